@@ -0,0 +1,130 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/sixban6/ghinstall"
+)
+
+func runAttest(args []string) int {
+	if len(args) == 0 {
+		fmt.Fprintf(os.Stderr, "usage: %s attest sign|verify [flags] [config-file]\n", os.Args[0])
+		return 2
+	}
+
+	switch args[0] {
+	case "sign":
+		return attestSign(args[1:])
+	case "verify":
+		return attestVerify(args[1:])
+	default:
+		fmt.Fprintf(os.Stderr, "unknown attest command %q\n", args[0])
+		return 2
+	}
+}
+
+// attestSign signs the manifests of existing installs, e.g. after attest.key
+// was configured or the key was rotated.
+func attestSign(args []string) int {
+	fs := flag.NewFlagSet("attest sign", flag.ExitOnError)
+	configFile := fs.String("config", "", "Path to configuration file")
+	key := fs.String("key", "", "Private key signing the manifests (default: attest.key of the configuration)")
+	format := fs.String("format", "", "Signature format: ssh or minisign (default: attest.format of the configuration)")
+	timeout := fs.Duration("timeout", time.Minute, "Timeout for signing")
+	fs.Parse(args)
+
+	cfg, err := loadConfigArg(fs, *configFile)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to load configuration: %v\n", err)
+		return 1
+	}
+	if *key == "" {
+		*key = cfg.Attest.Key
+	}
+	if *format == "" {
+		*format = cfg.Attest.Format
+	}
+	if *key == "" {
+		fmt.Fprintln(os.Stderr, "No signing key: pass -key or configure attest.key")
+		return 2
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), *timeout)
+	defer cancel()
+
+	results, err := ghinstall.Attest(ctx, cfg, *format, *key)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%v\n", err)
+		return 2
+	}
+	failed := 0
+	for _, res := range results {
+		if res.Err != nil {
+			fmt.Printf("%s (%s): error: %v\n", res.Repo.DisplayName(), res.Repo.OutputDir, res.Err)
+			failed++
+			continue
+		}
+		fmt.Printf("%s (%s): signed %s\n", res.Repo.DisplayName(), res.Repo.OutputDir, res.Signature)
+	}
+	if failed > 0 {
+		return 1
+	}
+	return 0
+}
+
+// attestVerify checks the manifest signatures and the files they cover, as
+// any other tool could with ssh-keygen or minisign and sha256sum.
+func attestVerify(args []string) int {
+	fs := flag.NewFlagSet("attest verify", flag.ExitOnError)
+	configFile := fs.String("config", "", "Path to configuration file")
+	pub := fs.String("pub", "", "Public key the manifests must be signed with (required)")
+	format := fs.String("format", "", "Signature format: ssh or minisign (default: attest.format of the configuration)")
+	timeout := fs.Duration("timeout", 5*time.Minute, "Timeout for verifying")
+	fs.Parse(args)
+
+	cfg, err := loadConfigArg(fs, *configFile)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to load configuration: %v\n", err)
+		return 1
+	}
+	if *pub == "" {
+		fmt.Fprintln(os.Stderr, "-pub is required")
+		return 2
+	}
+	if *format == "" {
+		*format = cfg.Attest.Format
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), *timeout)
+	defer cancel()
+
+	results, err := ghinstall.VerifyAttestations(ctx, cfg, *format, *pub)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%v\n", err)
+		return 2
+	}
+	failed := 0
+	for _, res := range results {
+		switch {
+		case res.Err != nil:
+			fmt.Printf("%s (%s): error: %v\n", res.Repo.DisplayName(), res.Repo.OutputDir, res.Err)
+			failed++
+		case len(res.Problems) > 0:
+			fmt.Printf("%s (%s): %d files differ from the signed manifest\n", res.Repo.DisplayName(), res.Repo.OutputDir, len(res.Problems))
+			for _, p := range res.Problems {
+				fmt.Printf("  %-8s %s\n", p.Kind, p.Path)
+			}
+			failed++
+		default:
+			fmt.Printf("%s (%s): ok\n", res.Repo.DisplayName(), res.Repo.OutputDir)
+		}
+	}
+	if failed > 0 {
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,261 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"time"
+
+	"github.com/sixban6/ghinstall"
+	"github.com/sixban6/ghinstall/internal/buildinfo"
+	"github.com/sixban6/ghinstall/internal/cache"
+	"github.com/sixban6/ghinstall/internal/neterr"
+	"github.com/sixban6/ghinstall/internal/release"
+	"github.com/sixban6/ghinstall/internal/shim"
+	"github.com/sixban6/ghinstall/internal/state"
+)
+
+// finding is the outcome of one doctor check.
+type finding struct {
+	ok     bool
+	check  string
+	detail string
+}
+
+func runDoctor(args []string) int {
+	fs := flag.NewFlagSet("doctor", flag.ExitOnError)
+	configFile := fs.String("config", "", "Path to configuration file")
+	timeout := fs.Duration("timeout", 10*time.Second, "Timeout of each network check")
+	fs.Parse(args)
+
+	cfg, err := loadConfigArg(fs, *configFile)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to load configuration: %v\n", err)
+		return 1
+	}
+
+	client := &http.Client{Timeout: *timeout}
+	findings := checkGitHub(client)
+	findings = append(findings, checkMirror(client, cfg))
+	findings = append(findings, checkTools()...)
+	findings = append(findings, checkDirs(cfg)...)
+	findings = append(findings, checkBinDir(cfg)...)
+
+	failed := 0
+	for _, f := range findings {
+		status := "OK  "
+		if !f.ok {
+			status = "FAIL"
+			failed++
+		}
+		fmt.Printf("[%s] %s: %s\n", status, f.check, f.detail)
+	}
+
+	if failed > 0 {
+		fmt.Printf("\n%d problem(s) found\n", failed)
+		return 1
+	}
+	return 0
+}
+
+// checkGitHub checks that the GitHub API answers and, when a token is set,
+// that GitHub accepts it.
+func checkGitHub(client *http.Client) []finding {
+	req, err := http.NewRequest(http.MethodGet, release.APIURL+"/rate_limit", nil)
+	if err != nil {
+		return []finding{{check: "GitHub API", detail: err.Error()}}
+	}
+	req.Header.Set("User-Agent", "ghinstall/1.0")
+	token := release.Token()
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return []finding{{check: "GitHub API", detail: fmt.Sprintf("%s is unreachable: %v; check your network and proxy settings (HTTPS_PROXY), or set mirror_url", release.APIURL, err)}}
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode == http.StatusUnauthorized {
+		return []finding{
+			{ok: true, check: "GitHub API", detail: release.APIURL + " is reachable"},
+			{check: "token", detail: "GitHub rejected the token in GITHUB_TOKEN/GH_TOKEN; it is invalid or expired, create a new one or unset it"},
+		}
+	}
+
+	var findings []finding
+	remaining, limit := resp.Header.Get("X-RateLimit-Remaining"), resp.Header.Get("X-RateLimit-Limit")
+	switch {
+	case remaining == "0":
+		detail := "the API rate limit is exhausted"
+		if reset, err := strconv.ParseInt(resp.Header.Get("X-RateLimit-Reset"), 10, 64); err == nil {
+			detail += " until " + time.Unix(reset, 0).Format(time.Kitchen)
+		}
+		if token == "" {
+			detail += "; set GITHUB_TOKEN to get a higher limit"
+		}
+		findings = append(findings, finding{check: "GitHub API", detail: detail})
+	case resp.StatusCode != http.StatusOK:
+		findings = append(findings, finding{check: "GitHub API", detail: fmt.Sprintf("%s returned status %d", release.APIURL, resp.StatusCode)})
+	default:
+		findings = append(findings, finding{ok: true, check: "GitHub API", detail: fmt.Sprintf("%s is reachable, %s of %s requests left", release.APIURL, remaining, limit)})
+	}
+
+	switch {
+	case token == "":
+		findings = append(findings, finding{ok: true, check: "token", detail: "not set; anonymous requests are limited to 60 per hour, set GITHUB_TOKEN to raise the limit"})
+	case resp.StatusCode != http.StatusOK:
+		// The token could not be checked.
+	case resp.Header.Values("X-OAuth-Scopes") == nil:
+		findings = append(findings, finding{ok: true, check: "token", detail: "valid (fine-grained or app token)"})
+	default:
+		scopes := resp.Header.Get("X-OAuth-Scopes")
+		if scopes == "" {
+			scopes = "none; private repositories need the repo scope"
+		}
+		findings = append(findings, finding{ok: true, check: "token", detail: "valid, scopes: " + scopes})
+	}
+	return findings
+}
+
+// checkMirror checks that the configured mirror answers.
+func checkMirror(client *http.Client, cfg *ghinstall.Config) finding {
+	mirror := cfg.MirrorBase()
+	if mirror == "" {
+		return finding{ok: true, check: "mirror_url", detail: "not configured"}
+	}
+	if _, err := probe(client, mirror); err != nil {
+		return finding{check: "mirror_url", detail: err.Error()}
+	}
+	return finding{ok: true, check: "mirror_url", detail: mirror + " is reachable"}
+}
+
+// probe sends a HEAD request to url and returns how long the answer took.
+// Any answer but a server error counts: mirrors need not serve their root.
+func probe(client *http.Client, url string) (time.Duration, error) {
+	req, err := http.NewRequest(http.MethodHead, url, nil)
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("User-Agent", "ghinstall/1.0")
+
+	start := time.Now()
+	resp, err := client.Do(req)
+	if err != nil {
+		return 0, fmt.Errorf("%s is unreachable: %s", url, neterr.Describe(err))
+	}
+	resp.Body.Close()
+	if resp.StatusCode >= http.StatusInternalServerError {
+		return 0, fmt.Errorf("%s returned status %d", url, resp.StatusCode)
+	}
+	return time.Since(start), nil
+}
+
+// checkTools reports the system extraction tools. They are optional: the Go
+// implementation extracts every supported format.
+func checkTools() []finding {
+	if !slices.Contains(buildinfo.Read("").Extractors, "system") {
+		return []finding{{ok: true, check: "tar/unzip", detail: "not used; this build extracts archives in Go"}}
+	}
+
+	var findings []finding
+	for _, tool := range []string{"tar", "unzip"} {
+		if path, err := exec.LookPath(tool); err == nil {
+			findings = append(findings, finding{ok: true, check: tool, detail: path})
+		} else {
+			findings = append(findings, finding{ok: true, check: tool, detail: "not found; archives are extracted in Go"})
+		}
+	}
+	return findings
+}
+
+// checkDirs checks that ghinstall can write the directories it installs to
+// and keeps its cache and state in.
+func checkDirs(cfg *ghinstall.Config) []finding {
+	var findings []finding
+	if cfg.CacheDir == "" {
+		findings = append(findings, finding{ok: true, check: "cache_dir", detail: "not configured"})
+	} else {
+		findings = append(findings, checkWritable("cache_dir", cache.ResolveDir(cfg.CacheDir)))
+	}
+	findings = append(findings, checkWritable("state", filepath.Dir(state.IndexPath())))
+	for _, repo := range cfg.Github {
+		findings = append(findings, checkWritable("output_dir of "+repo.DisplayName(), repo.OutputDir))
+	}
+	return findings
+}
+
+// checkWritable checks that files can be created in dir or, when dir does not
+// exist yet, in its nearest existing parent.
+func checkWritable(check, dir string) finding {
+	existing := dir
+	for {
+		fi, err := os.Stat(existing)
+		if err == nil {
+			if !fi.IsDir() {
+				return finding{check: check, detail: existing + " is not a directory"}
+			}
+			break
+		}
+		parent := filepath.Dir(existing)
+		if parent == existing {
+			return finding{check: check, detail: fmt.Sprintf("%s cannot be created: %v", dir, err)}
+		}
+		existing = parent
+	}
+
+	f, err := os.CreateTemp(existing, ".ghinstall-doctor-*")
+	if err != nil {
+		return finding{check: check, detail: fmt.Sprintf("%s is not writable; fix its permissions or choose another directory", existing)}
+	}
+	f.Close()
+	os.Remove(f.Name())
+
+	if existing != dir {
+		return finding{ok: true, check: check, detail: dir + " does not exist yet; it can be created"}
+	}
+	return finding{ok: true, check: check, detail: dir}
+}
+
+func checkBinDir(cfg *ghinstall.Config) []finding {
+	if cfg.BinDir == "" {
+		return []finding{{ok: true, check: "bin_dir", detail: "not configured"}}
+	}
+
+	var findings []finding
+	if fi, err := os.Stat(cfg.BinDir); err != nil || !fi.IsDir() {
+		findings = append(findings, finding{ok: true, check: "bin_dir", detail: cfg.BinDir + " does not exist yet; it is created on the next install"})
+	} else {
+		findings = append(findings, finding{ok: true, check: "bin_dir", detail: cfg.BinDir})
+	}
+
+	if shim.OnPath(cfg.BinDir) {
+		findings = append(findings, finding{ok: true, check: "PATH", detail: cfg.BinDir + " is on PATH"})
+	} else {
+		findings = append(findings, finding{check: "PATH", detail: cfg.BinDir + " is not on PATH; add it to your shell profile"})
+	}
+	return findings
+}
+
+// loadConfigArg loads the configuration named by the -config flag or the first
+// positional argument of fs, or else the project and global config files.
+func loadConfigArg(fs *flag.FlagSet, configFile string) (*ghinstall.Config, error) {
+	if configFile == "" {
+		if fs.NArg() == 0 {
+			cfg, _, err := ghinstall.LoadDiscoveredConfig(".")
+			if errors.Is(err, ghinstall.ErrNoConfig) {
+				return nil, fmt.Errorf("usage: %s %s [flags] <config-file>", os.Args[0], fs.Name())
+			}
+			return cfg, err
+		}
+		configFile = fs.Arg(0)
+	}
+	return ghinstall.LoadConfig(configFile)
+}
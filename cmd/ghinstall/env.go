@@ -0,0 +1,48 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/sixban6/ghinstall/internal/shim"
+)
+
+func runEnv(args []string) int {
+	fs := flag.NewFlagSet("env", flag.ExitOnError)
+	configFile := fs.String("config", "", "Path to configuration file (its bin_dir is used)")
+	shell := fs.String("shell", shim.DetectShell(), "Shell to emit code for: "+strings.Join(shim.Shells, ", "))
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: %s env [flags] [config-file]\n\n", os.Args[0])
+		fmt.Fprintf(fs.Output(), "Prints shell code putting the managed bin dir on PATH, e.g.\n")
+		fmt.Fprintf(fs.Output(), "  eval \"$(ghinstall env)\"                       # bash, zsh\n")
+		fmt.Fprintf(fs.Output(), "  ghinstall env -shell fish | source            # fish\n")
+		fmt.Fprintf(fs.Output(), "  ghinstall env -shell powershell | Invoke-Expression\n\n")
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+
+	// Without a config, the bin dir of "ghinstall get" and "tools".
+	binDir := shim.DefaultDir()
+	if *configFile != "" || fs.NArg() > 0 {
+		cfg, err := loadConfigArg(fs, *configFile)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Failed to load configuration: %v\n", err)
+			return 1
+		}
+		if cfg.BinDir == "" {
+			fmt.Fprintln(os.Stderr, "The configuration sets no bin_dir, so installs link no executables to put on PATH; set bin_dir")
+			return 1
+		}
+		binDir = cfg.BinDir
+	}
+
+	snippet, err := shim.EnvSnippet(*shell, binDir)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	fmt.Print(snippet)
+	return 0
+}
@@ -0,0 +1,23 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestEnv_NoBinDir(t *testing.T) {
+	config := writeConfig(t, t.TempDir())
+	if rc := runEnv([]string{"-shell", "bash", config}); rc != 1 {
+		t.Errorf("env of a config without bin_dir exited with %d, want 1", rc)
+	}
+
+	withBinDir := filepath.Join(t.TempDir(), "config.yaml")
+	content := "bin_dir: " + filepath.ToSlash(t.TempDir()) + "\ngithub:\n  - url: https://github.com/owner/app\n    output_dir: " + filepath.ToSlash(t.TempDir()) + "\n"
+	if err := os.WriteFile(withBinDir, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if rc := runEnv([]string{"-shell", "bash", withBinDir}); rc != 0 {
+		t.Errorf("env of a config with bin_dir exited with %d, want 0", rc)
+	}
+}
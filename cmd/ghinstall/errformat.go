@@ -0,0 +1,109 @@
+package main
+
+import (
+	"cmp"
+	"errors"
+	"fmt"
+	log "github.com/sixban6/ghinstall/internal/logger"
+	"os"
+
+	"github.com/sixban6/ghinstall"
+	"github.com/sixban6/ghinstall/internal/actions"
+	"github.com/sixban6/ghinstall/internal/config"
+	"github.com/sixban6/ghinstall/internal/neterr"
+)
+
+// defaultErrorFormat annotates errors when running in GitHub Actions.
+func defaultErrorFormat() string {
+	if actions.Enabled() {
+		return "github"
+	}
+	return "text"
+}
+
+// validErrorFormat reports whether format is a supported -error-format.
+func validErrorFormat(format string) bool {
+	return format == "text" || format == "line" || format == "github"
+}
+
+// reportError prints err in the selected -error-format: "text" logs it after
+// prefix, "line" prints a single "file:line: message" line for problem
+// matchers and "github" emits a workflow error annotation. Errors of the
+// config file, or of a repository declared in it, point at their line.
+// Network failures are described briefly, with a hint how to fix them. Every
+// repository that failed to resolve, or to install with continue_on_error, is
+// reported on its own.
+func reportError(format, cfgPath, prefix string, err error) {
+	var resolveErr *ghinstall.ResolveError
+	if errors.As(err, &resolveErr) {
+		for _, repoErr := range resolveErr.Errs {
+			reportError(format, cfgPath, prefix, repoErr)
+		}
+		return
+	}
+	var batchErr *ghinstall.BatchError
+	if errors.As(err, &batchErr) {
+		for _, repoErr := range batchErr.Failed() {
+			reportError(format, cfgPath, prefix, repoErr)
+		}
+		return
+	}
+
+	msg, hint := describe(err)
+	if format == "text" {
+		log.Error("%s: %s", prefix, msg)
+		if hint != "" {
+			log.Info("Hint: %s", hint)
+		}
+		return
+	}
+
+	line := 0
+	var cfgErr *ghinstall.ConfigError
+	var installErr *ghinstall.InstallError
+	switch {
+	case errors.As(err, &cfgErr):
+		// With a global and a project config, the error may be in either.
+		cfgPath, line = cmp.Or(cfgErr.File, cfgPath), cfgErr.Line
+	case errors.As(err, &installErr):
+		line = config.RepoLine(cfgPath, installErr.Repo)
+	}
+
+	if format == "github" {
+		if hint != "" {
+			msg += "\nHint: " + hint
+		}
+		actions.Error(cfgPath, line, msg)
+		return
+	}
+	location := cfgPath
+	if line > 0 {
+		location = fmt.Sprintf("%s:%d", cfgPath, line)
+	}
+	fmt.Fprintf(os.Stderr, "%s: %s\n", location, errorText(err))
+}
+
+// describe returns the message reporting err and, for network failures, a
+// hint how to fix them. Network failures are reduced to what went wrong
+// instead of the chain of errors wrapping it.
+func describe(err error) (msg, hint string) {
+	if neterr.Classify(err) == neterr.Unknown {
+		return err.Error(), ""
+	}
+	msg = neterr.Describe(err)
+	var installErr *ghinstall.InstallError
+	if errors.As(err, &installErr) {
+		msg = fmt.Sprintf("failed to install %s: %s", installErr.Repo.DisplayName(), msg)
+	}
+	return msg, neterr.Hint(err)
+}
+
+// errorText returns err described on a single line, for per-repository
+// results.
+func errorText(err error) string {
+	msg, hint := describe(err)
+	if hint == "" {
+		return msg
+	}
+	return fmt.Sprintf("%s (%s)", msg, hint)
+}
@@ -0,0 +1,100 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/sixban6/ghinstall"
+	"github.com/sixban6/ghinstall/internal/fleet"
+	log "github.com/sixban6/ghinstall/internal/logger"
+)
+
+// runFleet installs the repositories of a config on the hosts of a hosts
+// file over SSH, resolving and downloading on this machine.
+func runFleet(args []string) int {
+	fs := flag.NewFlagSet("fleet", flag.ExitOnError)
+	hostsFile := fs.String("hosts", "", "Path to the hosts file (required)")
+	configFile := fs.String("config", "", "Path to configuration file")
+	timeout := fs.Duration("timeout", 30*time.Minute, "Timeout for installing on all hosts")
+	parallel := fs.Int("parallel", 4, "Number of hosts to install on at the same time")
+	only := fs.String("only", "", "Comma-separated repositories to install (name, owner/repo or URL); all by default")
+	skip := fs.String("skip", "", "Comma-separated repositories not to install (name, owner/repo or URL)")
+	sshOptions := fs.String("ssh-options", "", "Comma-separated ssh -o options, such as StrictHostKeyChecking=accept-new")
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: %s fleet -hosts hosts.yaml [flags] [config-file]\n\n", os.Args[0])
+		fmt.Fprintf(fs.Output(), "Resolves the releases of the config and downloads their assets on this\n")
+		fmt.Fprintf(fs.Output(), "machine, once per platform, then copies them to every host over SSH and\n")
+		fmt.Fprintf(fs.Output(), "extracts them into the output_dir of each repository there.\n\n")
+		fs.PrintDefaults()
+	}
+	logLevel := logLevelFlag(fs)
+	fs.Parse(args)
+	log.SetLevel(*logLevel)
+
+	if *hostsFile == "" {
+		fs.Usage()
+		return 1
+	}
+	hosts, err := fleet.LoadHosts(*hostsFile)
+	if err != nil {
+		log.Error("%v", err)
+		return 1
+	}
+	cfg, err := loadConfigArg(fs, *configFile)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to load configuration: %v\n", err)
+		return 1
+	}
+	if *only != "" || *skip != "" {
+		if cfg.Github, err = cfg.Select(splitList(*only), splitList(*skip)); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 1
+		}
+	}
+	// The output directories are paths on the hosts, where the extraction
+	// usually runs as root.
+	if err := ghinstall.CheckOutputDirs(cfg); err != nil {
+		log.Error("%v", err)
+		return 1
+	}
+	if cfg.CacheDir == "" {
+		// The assets are copied to the hosts from the cache.
+		cfg.CacheDir = "user"
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), *timeout)
+	defer cancel()
+
+	fetch := func(ctx context.Context, platform string) ([]ghinstall.PrefetchResult, error) {
+		return ghinstall.PrefetchPlatform(ctx, cfg, platform)
+	}
+	results := fleet.Install(ctx, hosts, fleet.SSH{Options: splitList(*sshOptions)}, fetch, *parallel)
+
+	failed := 0
+	for _, res := range results {
+		if res.Failed() {
+			failed++
+		}
+		if res.Err != nil {
+			fmt.Printf("%s: error: %s\n", res.Host.Name, errorText(res.Err))
+			continue
+		}
+		fmt.Printf("%s (%s):\n", res.Host.Name, res.Platform)
+		for _, r := range res.Repos {
+			if r.Err != nil {
+				fmt.Printf("  %s: error: %s\n", r.Repo.DisplayName(), errorText(r.Err))
+			} else {
+				fmt.Printf("  %s: installed %s %s\n", r.Repo.DisplayName(), r.Tag, r.Asset)
+			}
+		}
+	}
+
+	if failed > 0 {
+		log.Error("Installation failed on %d of %d hosts", failed, len(results))
+		return 1
+	}
+	return 0
+}
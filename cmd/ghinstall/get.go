@@ -0,0 +1,116 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"time"
+
+	"github.com/sixban6/ghinstall"
+	"github.com/sixban6/ghinstall/internal/cache"
+	"github.com/sixban6/ghinstall/internal/catalog"
+	"github.com/sixban6/ghinstall/internal/config"
+	log "github.com/sixban6/ghinstall/internal/logger"
+	"github.com/sixban6/ghinstall/internal/shim"
+)
+
+func runGet(args []string) int {
+	fs := flag.NewFlagSet("get", flag.ExitOnError)
+	outputDir := fs.String("o", "", "Output directory (default ~/.ghinstall/tools/<name>)")
+	binDir := fs.String("bin-dir", shim.DefaultDir(), "Directory receiving shims for the tool's executables (empty to disable)")
+	mirror := fs.String("mirror", "", "GitHub mirror URL or preset name (see 'mirrors')")
+	timeout := fs.Duration("timeout", 5*time.Minute, "Timeout for installation")
+	list := fs.Bool("list", false, "List the tools in the catalog")
+	catalogURL := fs.String("catalog-url", "", "https URL or path of a catalog overriding the built-in one (default $GHINSTALL_CATALOG_URL, then catalog_url of the config)")
+	dangerous := fs.Bool("allow-dangerous-dir", false, "Allow an output directory that is /, the home directory or a system directory")
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: %s get [flags] <tool>\n\n", os.Args[0])
+		fs.PrintDefaults()
+	}
+	logLevel := logLevelFlag(fs)
+	fs.Parse(args)
+	log.SetLevel(*logLevel)
+
+	ctx, cancel := context.WithTimeout(context.Background(), *timeout)
+	defer cancel()
+
+	if *catalogURL == "" {
+		*catalogURL = os.Getenv("GHINSTALL_CATALOG_URL")
+	}
+	if *catalogURL == "" {
+		cfg, _, err := ghinstall.LoadDiscoveredConfig(".")
+		if err != nil && !errors.Is(err, ghinstall.ErrNoConfig) {
+			log.Error("Failed to load configuration: %v", err)
+			return 1
+		}
+		if cfg != nil {
+			*catalogURL = cfg.CatalogURL
+		}
+	}
+
+	cat, err := catalog.Load(ctx, *catalogURL, cache.UserDir())
+	if err != nil {
+		log.Error("Failed to load catalog: %v", err)
+		return 1
+	}
+
+	if *list {
+		for _, name := range cat.Names() {
+			tool, _ := cat.Lookup(name)
+			pinned := ""
+			if tool.Version != "" {
+				pinned = " @ " + tool.Version
+			}
+			fmt.Printf("%-12s %s (%s%s)\n", name, tool.Description, tool.Repo, pinned)
+		}
+		return 0
+	}
+
+	if fs.NArg() != 1 {
+		fs.Usage()
+		return 1
+	}
+
+	name := fs.Arg(0)
+	tool, ok := cat.Lookup(name)
+	if !ok {
+		log.Error("Unknown tool %q; run '%s get -list' to see the catalog", name, os.Args[0])
+		return 1
+	}
+
+	if *outputDir == "" {
+		*outputDir = filepath.Join(filepath.Dir(shim.DefaultDir()), "tools", name)
+	}
+
+	repo, err := tool.RepoFor(runtime.GOOS, runtime.GOARCH, *outputDir)
+	if err != nil {
+		log.Error("%v", err)
+		return 1
+	}
+
+	cfg := &ghinstall.Config{
+		Github:            []ghinstall.Repo{repo},
+		BinDir:            *binDir,
+		AllowDangerousDir: *dangerous,
+	}
+	if _, ok := config.LookupMirror(*mirror); ok {
+		cfg.Mirror = *mirror
+	} else {
+		cfg.MirrorURL = *mirror
+	}
+	if err := cfg.Validate(); err != nil {
+		log.Error("%v", err)
+		return 1
+	}
+
+	if err := ghinstall.InstallWithConfig(ctx, cfg); err != nil {
+		log.Error("Installation failed: %v", err)
+		return 1
+	}
+	log.Success("Installed %s into %s", name, *outputDir)
+	return 0
+}
@@ -0,0 +1,26 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"strings"
+	"testing"
+
+	log "github.com/sixban6/ghinstall/internal/logger"
+)
+
+func TestGet_InvalidMirror(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	t.Setenv("USERPROFILE", os.Getenv("HOME"))
+	t.Setenv("GHINSTALL_CATALOG_URL", "")
+	var logs bytes.Buffer
+	log.SetWriter(&logs)
+	defer log.SetOutput(os.Stderr)
+
+	if rc := runGet([]string{"-bin-dir", "", "-mirror", "ghfast.top", "gh"}); rc != 1 {
+		t.Errorf("get with a mirror without scheme exited with %d, want 1", rc)
+	}
+	if !strings.Contains(logs.String(), "mirror_url must be") {
+		t.Errorf("logs = %q, want the mirror refused before installing", logs.String())
+	}
+}
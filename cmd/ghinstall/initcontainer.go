@@ -0,0 +1,190 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/sixban6/ghinstall"
+	log "github.com/sixban6/ghinstall/internal/logger"
+	"github.com/sixban6/ghinstall/internal/tmpdir"
+)
+
+// Exit codes of init-container, which Kubernetes reports as the termination
+// reason of the container.
+const (
+	initExitFailed     = 1 // the install failed
+	initExitInvalid    = 2 // the environment variables are invalid
+	initExitNoWritable = 3 // none of the output directories is writable
+)
+
+// defaultInitOutputDirs are tried in order when GHINSTALL_OUTPUT_DIR is unset:
+// the mount point commonly given to the emptyDir volume shared with the
+// application containers, then a directory of /tmp.
+var defaultInitOutputDirs = []string{"/tools", "/tmp/ghinstall"}
+
+// runInitContainer installs the single repository described by environment
+// variables, for Kubernetes init containers injecting release binaries into
+// the volumes of a pod.
+func runInitContainer(args []string) int {
+	fs := flag.NewFlagSet("init-container", flag.ExitOnError)
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: %s init-container\n\n", os.Args[0])
+		fmt.Fprintf(fs.Output(), "Installs one repository configured by environment variables, without a config file:\n\n")
+		fmt.Fprintf(fs.Output(), "  GHINSTALL_REPO           repository, owner/name or URL (required)\n")
+		fmt.Fprintf(fs.Output(), "  GHINSTALL_VERSION        release tag; the latest stable release by default\n")
+		fmt.Fprintf(fs.Output(), "  GHINSTALL_ASSET_PATTERN  regular expression selecting the asset\n")
+		fmt.Fprintf(fs.Output(), "  GHINSTALL_SHA256         expected digest of the asset\n")
+		fmt.Fprintf(fs.Output(), "  GHINSTALL_OUTPUT_DIR     directories separated by %c; the first writable one is used (default %s)\n",
+			os.PathListSeparator, strings.Join(defaultInitOutputDirs, string(os.PathListSeparator)))
+		fmt.Fprintf(fs.Output(), "  GHINSTALL_MIRROR         mirror preset or URL; a cluster Service URL is used for every download\n")
+		fmt.Fprintf(fs.Output(), "  GHINSTALL_TIMEOUT        timeout of the install (default 5m)\n")
+		fmt.Fprintf(fs.Output(), "  GHINSTALL_LOG_LEVEL      minimum level of the logs (default warn)\n\n")
+		fmt.Fprintf(fs.Output(), "Exits with %d when the install fails, %d when the variables are invalid and\n", initExitFailed, initExitInvalid)
+		fmt.Fprintf(fs.Output(), "%d when no output directory is writable.\n", initExitNoWritable)
+	}
+	fs.Parse(args)
+	if fs.NArg() > 0 {
+		fs.Usage()
+		return initExitInvalid
+	}
+
+	level := log.LevelWarn
+	if env := os.Getenv("GHINSTALL_LOG_LEVEL"); env != "" {
+		var err error
+		if level, err = log.ParseLevel(env); err != nil {
+			log.Error("GHINSTALL_LOG_LEVEL: %v", err)
+			return initExitInvalid
+		}
+	}
+	log.SetLevel(level)
+
+	ref, err := ghinstall.ParseRepoRef(os.Getenv("GHINSTALL_REPO"))
+	if err != nil {
+		log.Error("GHINSTALL_REPO: %v", err)
+		return initExitInvalid
+	}
+	timeout := 5 * time.Minute
+	if env := os.Getenv("GHINSTALL_TIMEOUT"); env != "" {
+		if timeout, err = time.ParseDuration(env); err != nil {
+			log.Error("GHINSTALL_TIMEOUT: %v", err)
+			return initExitInvalid
+		}
+	}
+
+	dirs := defaultInitOutputDirs
+	if env := os.Getenv("GHINSTALL_OUTPUT_DIR"); env != "" {
+		dirs = filepath.SplitList(env)
+	}
+	outputDir := firstWritable(dirs)
+	if outputDir == "" {
+		log.Error("None of the output directories %v is writable; mount a volume at one of them or set GHINSTALL_OUTPUT_DIR", dirs)
+		return initExitNoWritable
+	}
+	// Pods with a read-only root filesystem often have no writable /tmp;
+	// downloads are then spooled next to the output directory.
+	if !writable(tmpdir.Dir()) {
+		spool := filepath.Join(outputDir, ".ghinstall-tmp")
+		if err := os.MkdirAll(spool, 0o700); err != nil {
+			log.Error("No writable temporary directory: %v", err)
+			return initExitNoWritable
+		}
+		defer os.RemoveAll(spool)
+		os.Setenv("TMPDIR", spool)
+	}
+
+	var opts []ghinstall.RepoOption
+	if v := os.Getenv("GHINSTALL_VERSION"); v != "" {
+		opts = append(opts, ghinstall.WithVersion(v))
+	}
+	if p := os.Getenv("GHINSTALL_ASSET_PATTERN"); p != "" {
+		opts = append(opts, ghinstall.WithPattern(p))
+	}
+	if d := os.Getenv("GHINSTALL_SHA256"); d != "" {
+		opts = append(opts, ghinstall.WithSHA256(d))
+	}
+	b := ghinstall.NewConfig().AddRepo(ref.HTMLURL(), outputDir, opts...)
+	mirror := os.Getenv("GHINSTALL_MIRROR")
+	if mirror != "" {
+		b.WithMirror(mirror)
+	}
+	cfg, err := b.Build()
+	if err != nil {
+		log.Error("%v", err)
+		return initExitInvalid
+	}
+
+	var tag string
+	options := []ghinstall.Option{
+		ghinstall.WithMiddleware(ghinstall.MiddlewareFunc(func(ctx context.Context, step ghinstall.InstallStep) error {
+			if step.Stage == ghinstall.AfterExtract {
+				tag = step.Result.Tag
+			}
+			return nil
+		})),
+	}
+	if clusterService(mirror) {
+		// An in-cluster mirror is closer than GitHub and reachable when egress
+		// is not; GitHub is left to the API lookups.
+		base := strings.TrimSuffix(mirror, "/")
+		options = append(options, ghinstall.WithURLRewriter(func(assetURL string) string {
+			return base + "/" + assetURL
+		}))
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	if err := ghinstall.InstallWithOptions(ctx, cfg, nil, options...); err != nil {
+		log.Error("Failed to install %s: %s", ref, errorText(err))
+		return initExitFailed
+	}
+	fmt.Printf("Installed %s %s into %s\n", ref, tag, outputDir)
+	return 0
+}
+
+// firstWritable returns the first of dirs that exists or can be created and
+// accepts new files, or "".
+func firstWritable(dirs []string) string {
+	for _, dir := range dirs {
+		if dir == "" {
+			continue
+		}
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			log.Debug("Output directory %s is unusable: %v", dir, err)
+			continue
+		}
+		if writable(dir) {
+			return dir
+		}
+		log.Debug("Output directory %s is not writable", dir)
+	}
+	return ""
+}
+
+// writable reports whether a file can be created in dir.
+func writable(dir string) bool {
+	f, err := os.CreateTemp(dir, ".ghinstall-probe-*")
+	if err != nil {
+		return false
+	}
+	f.Close()
+	os.Remove(f.Name())
+	return true
+}
+
+// clusterService reports whether mirror is the URL of a Kubernetes Service,
+// such as http://ghmirror.tools.svc.cluster.local or http://ghmirror.
+func clusterService(mirror string) bool {
+	u, err := url.Parse(mirror)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") {
+		return false
+	}
+	host := u.Hostname()
+	return !strings.Contains(host, ".") || strings.HasSuffix(host, ".svc") || strings.HasSuffix(host, ".svc.cluster.local")
+}
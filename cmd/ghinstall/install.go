@@ -0,0 +1,337 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	log "github.com/sixban6/ghinstall/internal/logger"
+	"io"
+	"os"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/sixban6/ghinstall"
+	"github.com/sixban6/ghinstall/internal/actions"
+	"github.com/sixban6/ghinstall/internal/progress"
+)
+
+// extraOptions are added to the options of install and reinstall; tests set
+// them to install from fakes.
+var extraOptions []ghinstall.Option
+
+// runInstall is the classic "ghinstall [flags] <config-file>" install, also
+// available as "ghinstall install".
+func runInstall(args []string) int {
+	fs := flag.NewFlagSet("install", flag.ExitOnError)
+	var (
+		configFile = fs.String("config", "", "Path to configuration file")
+		timeout    = fs.Duration("timeout", 5*time.Minute, "Timeout for installation")
+		verbose    = fs.Bool("verbose", false, "Timestamp the logs and write the informational ones to stdout")
+		debug      = fs.Bool("debug", false, "Same as -log-level debug")
+		version    = fs.Bool("version", false, "Show version information")
+		lockWait   = fs.Duration("lock-timeout", 0, "How long to wait for another ghinstall holding the same output directory (default from config, 5m)")
+		refresh    = fs.Bool("force-refresh", false, "Reinstall assets that were replaced upstream without a new tag")
+		update     = fs.Bool("update", false, "Only install repositories with a release newer than the installed tag, or not installed yet, and print the upgrades")
+		force      = fs.Bool("force", false, "Re-download and re-extract everything, up-to-date repositories too, removing the files of previous installs first")
+		only       = fs.String("only", "", "Comma-separated repositories to install (name, owner/repo or URL); all by default")
+		skip       = fs.String("skip", "", "Comma-separated repositories not to install (name, owner/repo or URL)")
+		toolCache  = fs.Bool("tool-cache", false, "Install into the GitHub Actions tool cache ($RUNNER_TOOL_CACHE/<name>/<version>/<arch>) instead of output_dir")
+		ghActions  = fs.Bool("github-actions", actions.Enabled(), "Emit workflow notices and add installed executables to $GITHUB_PATH (default when GITHUB_ACTIONS=true)")
+		errFormat  = fs.String("error-format", defaultErrorFormat(), "Error output: text, line (file:line: message) or github (annotations, default when GITHUB_ACTIONS=true)")
+		parallel   = fs.Int("parallel", 1, "Number of repositories to install at the same time")
+		events     = fs.String("events", "", "Write machine-readable events to stdout instead of progress: jsonl (logs go to stderr)")
+		strict     = fs.Bool("strict-assets", false, "Fail when an asset_pattern matches several assets instead of using the first (default from config)")
+		dangerous  = fs.Bool("allow-dangerous-dir", false, "Allow an output_dir that is /, the home directory or a system directory (default from config)")
+		keepGoing  = fs.Bool("continue-on-error", false, "Install every repository that can be installed when others fail (default from config)")
+		summary    = fs.String("summary", "", "Write a summary of upgraded, skipped and failed repositories to this file (- for stdout)")
+		summaryFmt = fs.String("summary-format", "", "Summary format: text or markdown (default markdown for .md files, text otherwise)")
+		dryRun     = fs.Bool("dry-run", false, "Resolve the repositories and print what would be downloaded without installing anything")
+		yes        = fs.Bool("yes", false, "Download without asking for confirmation above -confirm-over")
+		confirm    = fs.Int64("confirm-over", 500, "Ask for confirmation on a terminal before downloading more than this many MB (0 never asks)")
+		major      = fs.Bool("accept-major", false, "Upgrade repositories with hold_on_major to new major versions without asking")
+		check      = fs.Bool("check", false, "Like -dry-run, and exit with -changed-exit-code (2 by default) when repositories are not up to date")
+		changedRC  = fs.Int("changed-exit-code", 0, "Exit with this code instead of 0 when repositories were installed or, with -check, would be")
+	)
+	logLevel := logLevelFlag(fs)
+	fs.Parse(args)
+
+	if !validErrorFormat(*errFormat) {
+		log.Error("-error-format must be text, line or github")
+		return 1
+	}
+	if *events != "" && *events != "jsonl" {
+		log.Error("-events must be jsonl")
+		return 1
+	}
+	if *summaryFmt == "" {
+		*summaryFmt = "text"
+		if strings.HasSuffix(*summary, ".md") {
+			*summaryFmt = "markdown"
+		}
+	}
+	if *summaryFmt != "text" && *summaryFmt != "markdown" {
+		log.Error("-summary-format must be text or markdown")
+		return 1
+	}
+
+	if *version {
+		return runVersion(nil)
+	}
+	if *check {
+		*dryRun = true
+		if flagSet(fs, "changed-exit-code") && *changedRC == 0 {
+			log.Error("-check cannot exit with -changed-exit-code 0: it would not tell pending changes apart")
+			return 1
+		}
+		if *changedRC == 0 {
+			*changedRC = 2
+		}
+	}
+	if *changedRC == 1 || *changedRC < 0 || *changedRC > 125 {
+		log.Error("-changed-exit-code must be between 2 and 125, 1 being the exit code of failures")
+		return 1
+	}
+
+	if *configFile == "" && fs.NArg() > 0 {
+		*configFile = fs.Arg(0)
+	}
+
+	if !*verbose {
+		log.SetOutput(os.Stderr)
+		log.SetFlags(0)
+	}
+	if *debug {
+		*logLevel = log.LevelDebug
+	}
+	log.SetLevel(*logLevel)
+	if *events != "" {
+		// stdout carries only the events.
+		log.SetOutput(os.Stderr)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), *timeout)
+	defer cancel()
+
+	var cfg *ghinstall.Config
+	var err error
+	if *configFile != "" {
+		log.Info("Loading configuration from %s", *configFile)
+		cfg, err = ghinstall.LoadConfig(*configFile)
+	} else {
+		var files []string
+		cfg, files, err = ghinstall.LoadDiscoveredConfig(".")
+		if errors.Is(err, ghinstall.ErrNoConfig) {
+			log.Error("Usage: %s [flags] <config-file>\n", os.Args[0])
+			log.Error("   or: %s -config <config-file>\n", os.Args[0])
+			log.Error("   or: %s inside a project with a %s, or with %s\n", os.Args[0], ghinstall.ProjectConfigFile, ghinstall.GlobalConfigPath())
+			fs.PrintDefaults()
+			return 1
+		}
+		if len(files) > 0 {
+			log.Info("Loading configuration from %s", strings.Join(files, " and "))
+			*configFile = files[len(files)-1]
+		}
+	}
+	if err != nil {
+		reportError(*errFormat, *configFile, "Failed to load configuration", err)
+		return 1
+	}
+
+	if *lockWait > 0 {
+		cfg.LockTimeout = *lockWait
+	}
+	if *strict {
+		cfg.StrictAssets = true
+	}
+	if *dangerous {
+		cfg.AllowDangerousDir = true
+	}
+	if *keepGoing {
+		cfg.ContinueOnError = true
+	}
+
+	if *only != "" || *skip != "" {
+		if cfg.Github, err = cfg.Select(splitList(*only), splitList(*skip)); err != nil {
+			log.Error("%v", err)
+			return 1
+		}
+	}
+
+	log.Info("Found %d repositories to install", len(cfg.Github))
+
+	if mirror := cfg.MirrorBase(); mirror != "" {
+		log.Info("Using GitHub mirror: %s", mirror)
+	}
+
+	opts := []ghinstall.Option{ghinstall.WithForceRefresh(*refresh), ghinstall.WithForce(*force), ghinstall.WithUpdate(*update), ghinstall.WithGitHubActions(*ghActions)}
+	if *toolCache {
+		root := actions.ToolCacheRoot()
+		if root == "" {
+			log.Error("-tool-cache requires RUNNER_TOOL_CACHE")
+			return 1
+		}
+		opts = append(opts, ghinstall.WithToolCache(root))
+	}
+
+	if *parallel > 1 {
+		opts = append(opts, ghinstall.WithParallel(*parallel))
+	}
+	// The planned downloads are printed with the results, or with the logs
+	// when stdout carries events.
+	planOut := io.Writer(os.Stdout)
+	var progs []ghinstall.Progress
+	switch {
+	case *events != "":
+		ev := progress.NewEvents(os.Stdout, cfg.Github)
+		defer ev.Close()
+		planOut = os.Stderr
+		opts = append(opts, ghinstall.WithMiddleware(ev))
+		progs = append(progs, ev)
+	case *parallel > 1:
+		// A terminal shows a live line per repository below the logs; other
+		// output gets a plain log line for every status change and the
+		// results in config order at the end.
+		if progress.IsTerminal(os.Stdout) {
+			term := progress.NewTerminal(os.Stdout, cfg.Github)
+			defer term.Close()
+			log.SetWriter(term)
+			planOut = term
+			progs = append(progs, term)
+		} else {
+			plain := progress.NewPlain(os.Stdout, cfg.Github)
+			defer plain.Close()
+			progs = append(progs, plain)
+		}
+	}
+	var report *progress.Report
+	if (*summary != "" || *update) && !*dryRun {
+		report = progress.NewReport(cfg.Github)
+		opts = append(opts, ghinstall.WithMiddleware(report))
+		progs = append(progs, report)
+	}
+	switch len(progs) {
+	case 0:
+	case 1:
+		opts = append(opts, ghinstall.WithProgress(progs[0]))
+	default:
+		opts = append(opts, ghinstall.WithProgress(progress.Multi(progs...)))
+	}
+
+	changes := &changeTracker{}
+	opts = append(opts, ghinstall.WithMiddleware(changes),
+		ghinstall.WithPlan(changes.plan(planReporter(planOut, *confirm*1024*1024, *yes))), ghinstall.WithDryRun(*dryRun))
+	if !*dryRun || *major {
+		opts = append(opts, ghinstall.WithMajorApproval(majorApprover(*major)))
+	}
+
+	log.Info("Starting installation...")
+
+	start := time.Now()
+	err = ghinstall.InstallWithOptions(ctx, cfg, nil, append(opts, extraOptions...)...)
+	if report != nil && *summary != "" {
+		writeSummary(report, *summary, *summaryFmt, planOut)
+	}
+	if report != nil && *update && *events == "" {
+		fmt.Fprintln(planOut)
+		report.WriteUpgrades(planOut)
+	}
+	if err != nil {
+		var batchErr *ghinstall.BatchError
+		if errors.As(err, &batchErr) && !*dryRun {
+			log.Warn("Installed %d of %d repositories", len(batchErr.Succeeded()), len(batchErr.Results))
+		}
+		reportError(*errFormat, *configFile, "Installation failed", err)
+		return 1
+	}
+
+	if *dryRun {
+		log.Info("Dry run: nothing was downloaded or installed")
+		if changes.pending.Load() {
+			return *changedRC
+		}
+		return 0
+	}
+	duration := time.Since(start)
+	log.Info("Installation completed successfully in %v", duration)
+	if changes.installed.Load() {
+		return *changedRC
+	}
+	log.Info("Nothing changed: every repository is up to date")
+	return 0
+}
+
+// flagSet reports whether the flag name was given on the command line.
+func flagSet(fs *flag.FlagSet, name string) bool {
+	set := false
+	fs.Visit(func(f *flag.Flag) {
+		if f.Name == name {
+			set = true
+		}
+	})
+	return set
+}
+
+// changeTracker tells whether an install changed anything, for -check and
+// -changed-exit-code: whether a repository was extracted, and whether the plan
+// had repositories that are not up to date.
+type changeTracker struct {
+	installed, pending atomic.Bool
+}
+
+func (c *changeTracker) Intercept(ctx context.Context, step ghinstall.InstallStep) error {
+	if step.Stage == ghinstall.AfterExtract {
+		c.installed.Store(true)
+	}
+	return nil
+}
+
+// plan wraps the WithPlan function next to record pending changes.
+func (c *changeTracker) plan(next func(context.Context, *ghinstall.DownloadPlan) error) func(context.Context, *ghinstall.DownloadPlan) error {
+	return func(ctx context.Context, plan *ghinstall.DownloadPlan) error {
+		for _, d := range plan.Downloads {
+			if !d.UpToDate {
+				c.pending.Store(true)
+			}
+		}
+		return next(ctx, plan)
+	}
+}
+
+// writeSummary writes report in format to the file path or, for "-", to w.
+// A summary that cannot be written is logged without failing the run.
+func writeSummary(report *progress.Report, path, format string, w io.Writer) {
+	write := report.WriteText
+	if format == "markdown" {
+		write = report.WriteMarkdown
+	}
+	if path == "-" {
+		fmt.Fprintln(w)
+		write(w)
+		return
+	}
+	f, err := os.Create(path)
+	if err != nil {
+		log.Warn("Failed to write summary: %v", err)
+		return
+	}
+	err = write(f)
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		log.Warn("Failed to write summary: %v", err)
+	}
+}
+
+// splitList splits a comma-separated flag value, dropping empty items.
+func splitList(s string) []string {
+	var items []string
+	for _, item := range strings.Split(s, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
@@ -0,0 +1,59 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// writeConfig writes a configuration installing owner/app into dir.
+func writeConfig(t *testing.T, dir string) string {
+	t.Helper()
+	file := filepath.Join(t.TempDir(), "config.yaml")
+	content := "github:\n  - url: https://github.com/owner/app\n    output_dir: " + filepath.ToSlash(dir) + "\n"
+	if err := os.WriteFile(file, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return file
+}
+
+func TestInstall_ChangedExitCode(t *testing.T) {
+	fixtureServer(t)
+	config := writeConfig(t, t.TempDir())
+
+	if rc := runInstall([]string{"-changed-exit-code", "3", config}); rc != 3 {
+		t.Errorf("changed install exited with %d, want 3", rc)
+	}
+	if rc := runInstall([]string{"-changed-exit-code", "3", config}); rc != 0 {
+		t.Errorf("unchanged install exited with %d, want 0", rc)
+	}
+	if rc := runInstall([]string{"-check", config}); rc != 0 {
+		t.Errorf("-check of an up-to-date install exited with %d, want 0", rc)
+	}
+}
+
+func TestInstall_Check(t *testing.T) {
+	fixtureServer(t)
+	dir := t.TempDir()
+	config := writeConfig(t, dir)
+
+	tests := []struct {
+		name string
+		args []string
+		want int
+	}{
+		{name: "default code", args: []string{"-check", config}, want: 2},
+		{name: "changed exit code", args: []string{"-check", "-changed-exit-code", "4", config}, want: 4},
+		{name: "changed exit code 0", args: []string{"-check", "-changed-exit-code", "0", config}, want: 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if rc := runInstall(tt.args); rc != tt.want {
+				t.Errorf("install %v exited with %d, want %d", tt.args, rc, tt.want)
+			}
+		})
+	}
+	if _, err := os.Stat(filepath.Join(dir, "app")); !os.IsNotExist(err) {
+		t.Errorf("-check installed app: %v", err)
+	}
+}
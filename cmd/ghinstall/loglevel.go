@@ -0,0 +1,19 @@
+package main
+
+import (
+	"flag"
+	"os"
+
+	log "github.com/sixban6/ghinstall/internal/logger"
+)
+
+// logLevelFlag adds -log-level to fs, defaulting to $GHINSTALL_LOG_LEVEL and
+// else info. Call log.SetLevel with the result once fs is parsed.
+func logLevelFlag(fs *flag.FlagSet) *log.Level {
+	level := log.LevelInfo
+	if env, err := log.ParseLevel(os.Getenv("GHINSTALL_LOG_LEVEL")); err == nil {
+		level = env
+	}
+	fs.TextVar(&level, "log-level", level, "Minimum level of the logs: debug (which traces every API request and download), info, warn or error; $GHINSTALL_LOG_LEVEL sets the default")
+	return &level
+}
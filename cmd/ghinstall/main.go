@@ -1,71 +1,40 @@
 package main
 
-import (
-	"context"
-	"flag"
-	"fmt"
-	log "github.com/sixban6/ghinstall/internal/logger"
-	"os"
-	"time"
-
-	"github.com/sixban6/ghinstall"
-)
+import "os"
 
 var appVersion = "dev"
 
-func main() {
-	var (
-		configFile = flag.String("config", "", "Path to configuration file")
-		timeout    = flag.Duration("timeout", 5*time.Minute, "Timeout for installation")
-		verbose    = flag.Bool("verbose", true, "Enable verbose logging")
-		version    = flag.Bool("version", false, "Show version information")
-	)
-	flag.Parse()
-
-	if *version {
-		log.Info("ghinstall version %s\n", appVersion)
-		fmt.Println("A tool for automatically downloading GitHub releases")
-		return
-	}
+// commands are selected by the first argument; any other invocation is the
+// classic "ghinstall [flags] <config-file>" install.
+var commands = map[string]func(args []string) int{
+	"attest":           runAttest,
+	"doctor":           runDoctor,
+	"env":              runEnv,
+	"fleet":            runFleet,
+	"get":              runGet,
+	"init-container":   runInitContainer,
+	"install":          runInstall,
+	"migrate-config":   runMigrateConfig,
+	"mirror-sync":      runMirrorSync,
+	"mirrors":          runMirrors,
+	"pin":              runPin,
+	"prefetch":         runPrefetch,
+	"reinstall":        runReinstall,
+	"release-manifest": runReleaseManifest,
+	"state":            runState,
+	"status":           runStatus,
+	"tools":            runTools,
+	"unpin":            runUnpin,
+	"verify":           runVerify,
+	"version":          runVersion,
+}
 
-	if *configFile == "" {
-		if len(flag.Args()) > 0 {
-			*configFile = flag.Args()[0]
-		} else {
-			log.Error("Usage: %s [flags] <config-file>\n", os.Args[0])
-			log.Error("   or: %s -config <config-file>\n", os.Args[0])
-			flag.PrintDefaults()
-			os.Exit(1)
+func main() {
+	if len(os.Args) > 1 {
+		if cmd, ok := commands[os.Args[1]]; ok {
+			os.Exit(cmd(os.Args[2:]))
 		}
 	}
 
-	if !*verbose {
-		log.SetOutput(os.Stderr)
-		log.SetFlags(0)
-	}
-
-	ctx, cancel := context.WithTimeout(context.Background(), *timeout)
-	defer cancel()
-
-	log.Info("Loading configuration from %s", *configFile)
-	cfg, err := ghinstall.LoadConfig(*configFile)
-	if err != nil {
-		log.Error("Failed to load configuration: %v", err)
-	}
-
-	log.Info("Found %d repositories to install", len(cfg.Github))
-
-	if cfg.MirrorURL != "" {
-		log.Info("Using GitHub mirror: %s", cfg.MirrorURL)
-	}
-
-	log.Info("Starting installation...")
-
-	start := time.Now()
-	if err := ghinstall.InstallWithConfig(ctx, cfg); err != nil {
-		log.Error("Installation failed: %v", err)
-	}
-
-	duration := time.Since(start)
-	log.Info("Installation completed successfully in %v", duration)
+	os.Exit(runInstall(os.Args[1:]))
 }
@@ -0,0 +1,39 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/sixban6/ghinstall"
+)
+
+// runMigrateConfig rewrites config files to the current schema version.
+func runMigrateConfig(args []string) int {
+	fs := flag.NewFlagSet("migrate-config", flag.ExitOnError)
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: %s migrate-config <config-file>...\n\n", os.Args[0])
+		fmt.Fprintf(fs.Output(), "Rewrites config files to schema version %d, keeping their comments.\n", ghinstall.ConfigSchemaVersion)
+		fmt.Fprintf(fs.Output(), "Files of older versions keep loading without migration.\n")
+	}
+	fs.Parse(args)
+	if fs.NArg() == 0 {
+		fs.Usage()
+		return 2
+	}
+
+	status := 0
+	for _, path := range fs.Args() {
+		changed, err := ghinstall.MigrateConfig(path)
+		switch {
+		case err != nil:
+			fmt.Fprintf(os.Stderr, "Failed to migrate %s: %v\n", path, err)
+			status = 1
+		case changed:
+			fmt.Printf("Migrated %s to version %d\n", path, ghinstall.ConfigSchemaVersion)
+		default:
+			fmt.Printf("%s is already at version %d\n", path, ghinstall.ConfigSchemaVersion)
+		}
+	}
+	return status
+}
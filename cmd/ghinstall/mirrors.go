@@ -0,0 +1,34 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"net/http"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"github.com/sixban6/ghinstall/internal/config"
+)
+
+// runMirrors lists the mirror presets and checks that each of them answers.
+func runMirrors(args []string) int {
+	fs := flag.NewFlagSet("mirrors", flag.ExitOnError)
+	timeout := fs.Duration("timeout", 5*time.Second, "Timeout of each health check")
+	fs.Parse(args)
+
+	client := &http.Client{Timeout: *timeout}
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "NAME\tURL\tHEALTH\tDESCRIPTION")
+	for _, p := range config.MirrorPresets {
+		health := "down"
+		if latency, err := probe(client, p.URL); err == nil {
+			health = fmt.Sprintf("ok (%dms)", latency.Milliseconds())
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", p.Name, p.URL, health, p.Description)
+	}
+	w.Flush()
+
+	fmt.Println("\nSelect one with \"mirror: <name>\" in the config file; ghinstall never picks one by itself.")
+	return 0
+}
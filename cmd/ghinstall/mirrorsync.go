@@ -0,0 +1,57 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/sixban6/ghinstall"
+)
+
+func runMirrorSync(args []string) int {
+	fs := flag.NewFlagSet("mirror-sync", flag.ExitOnError)
+	configFile := fs.String("config", "", "Path to configuration file")
+	timeout := fs.Duration("timeout", 30*time.Minute, "Timeout for mirroring all repositories")
+	dest := fs.String("to", "", "Mirror destination: a directory or an http(s) URL accepting PUT (token from $GHINSTALL_MIRROR_TOKEN)")
+	fs.Parse(args)
+
+	if *dest == "" {
+		fmt.Fprintf(os.Stderr, "usage: %s mirror-sync -to <directory-or-url> [flags] <config-file>\n", os.Args[0])
+		return 2
+	}
+
+	cfg, err := loadConfigArg(fs, *configFile)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to load configuration: %v\n", err)
+		return 1
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), *timeout)
+	defer cancel()
+
+	results, err := ghinstall.MirrorSync(ctx, cfg, *dest)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to open mirror: %v\n", err)
+		return 1
+	}
+
+	failed := 0
+	for _, res := range results {
+		switch {
+		case res.Err != nil:
+			fmt.Printf("%s: error: %s\n", res.Repo.DisplayName(), errorText(res.Err))
+			failed++
+		case res.Uploaded:
+			fmt.Printf("%s: uploaded %s %s\n", res.Repo.DisplayName(), res.Tag, res.Asset)
+		default:
+			fmt.Printf("%s: %s %s already mirrored\n", res.Repo.DisplayName(), res.Tag, res.Asset)
+		}
+	}
+
+	if failed > 0 {
+		return 1
+	}
+	return 0
+}
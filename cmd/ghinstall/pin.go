@@ -0,0 +1,76 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/sixban6/ghinstall"
+)
+
+func runPin(args []string) int {
+	fs := flag.NewFlagSet("pin", flag.ExitOnError)
+	configFile := fs.String("config", "", "Path to configuration file")
+	timeout := fs.Duration("timeout", 2*time.Minute, "Timeout for resolving the latest releases")
+	fs.Parse(args)
+
+	rest := fs.Args()
+	if *configFile == "" && len(rest) > 0 {
+		*configFile, rest = rest[0], rest[1:]
+	}
+	if *configFile == "" || len(rest) == 0 || len(rest) > 2 {
+		fmt.Fprintf(os.Stderr, "usage: %s pin <config-file> <repository> [tag]\n", os.Args[0])
+		return 2
+	}
+	var tag string
+	if len(rest) == 2 {
+		tag = rest[1]
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), *timeout)
+	defer cancel()
+
+	pinned, err := ghinstall.Pin(ctx, *configFile, rest[0], tag)
+	for _, res := range pinned {
+		if res.Previous != "" && res.Previous != res.Tag {
+			fmt.Printf("Pinned %s to %s (was %s)\n", res.Repo.DisplayName(), res.Tag, res.Previous)
+		} else {
+			fmt.Printf("Pinned %s to %s\n", res.Repo.DisplayName(), res.Tag)
+		}
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to pin: %v\n", err)
+		return 1
+	}
+	return 0
+}
+
+func runUnpin(args []string) int {
+	fs := flag.NewFlagSet("unpin", flag.ExitOnError)
+	configFile := fs.String("config", "", "Path to configuration file")
+	fs.Parse(args)
+
+	rest := fs.Args()
+	if *configFile == "" && len(rest) > 0 {
+		*configFile, rest = rest[0], rest[1:]
+	}
+	if *configFile == "" || len(rest) != 1 {
+		fmt.Fprintf(os.Stderr, "usage: %s unpin <config-file> <repository>\n", os.Args[0])
+		return 2
+	}
+
+	unpinned, err := ghinstall.Unpin(*configFile, rest[0])
+	for _, res := range unpinned {
+		fmt.Printf("Unpinned %s (was %s)\n", res.Repo.DisplayName(), res.Previous)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to unpin: %v\n", err)
+		return 1
+	}
+	if len(unpinned) == 0 {
+		fmt.Printf("%s is not pinned\n", rest[0])
+	}
+	return 0
+}
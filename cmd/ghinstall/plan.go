@@ -0,0 +1,90 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/sixban6/ghinstall"
+	log "github.com/sixban6/ghinstall/internal/logger"
+	"github.com/sixban6/ghinstall/internal/progress"
+)
+
+// errDeclined aborts an install whose downloads the user did not confirm.
+var errDeclined = errors.New("download declined")
+
+// planReporter prints the downloads of an install to w and, unless yes is set,
+// asks for confirmation when they exceed confirmOver bytes (0 never asks).
+// Without a terminal to ask on, the install goes on with a warning.
+func planReporter(w io.Writer, confirmOver int64, yes bool) func(context.Context, *ghinstall.DownloadPlan) error {
+	return func(ctx context.Context, plan *ghinstall.DownloadPlan) error {
+		printPlan(w, plan)
+
+		total := plan.Total()
+		if yes || confirmOver <= 0 || total <= confirmOver {
+			return nil
+		}
+		if !progress.IsTerminal(os.Stdin) {
+			log.Warn("Downloading %s, more than -confirm-over; pass -yes to confirm such downloads", formatMB(total))
+			return nil
+		}
+		fmt.Fprintf(os.Stderr, "Download %s? [y/N] ", formatMB(total))
+		answer, _ := bufio.NewReader(os.Stdin).ReadString('\n')
+		switch strings.ToLower(strings.TrimSpace(answer)) {
+		case "y", "yes":
+			return nil
+		}
+		return errDeclined
+	}
+}
+
+// majorApprover approves the upgrades to new major versions that
+// hold_on_major holds: all of them when acceptAll is set, otherwise those the
+// user confirms on a terminal. Without a terminal the repositories stay held.
+func majorApprover(acceptAll bool) func(context.Context, ghinstall.MajorUpgrade) (bool, error) {
+	return func(ctx context.Context, u ghinstall.MajorUpgrade) (bool, error) {
+		if acceptAll {
+			return true, nil
+		}
+		if !progress.IsTerminal(os.Stdin) {
+			return false, nil
+		}
+		fmt.Fprintf(os.Stderr, "Upgrade %s from %s to the new major version %s? [y/N] ", u.Repo.DisplayName(), u.From, u.To)
+		answer, _ := bufio.NewReader(os.Stdin).ReadString('\n')
+		switch strings.ToLower(strings.TrimSpace(answer)) {
+		case "y", "yes":
+			return true, nil
+		}
+		return false, nil
+	}
+}
+
+// printPlan writes a line per download and their total.
+func printPlan(w io.Writer, plan *ghinstall.DownloadPlan) {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "REPOSITORY\tRELEASE\tASSET\tSIZE")
+	for _, d := range plan.Downloads {
+		size := "-"
+		if d.Size > 0 {
+			size = formatMB(d.Size)
+		}
+		switch {
+		case d.UpToDate:
+			size += " (up to date)"
+		case d.Cached:
+			size += " (cached)"
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\n", d.Repo.DisplayName(), d.Tag, d.Asset, size)
+	}
+	tw.Flush()
+	fmt.Fprintf(w, "Total download: %s for %d repositories\n", formatMB(plan.Total()), len(plan.Downloads))
+}
+
+func formatMB(n int64) string {
+	return fmt.Sprintf("%.2f MB", float64(n)/(1024*1024))
+}
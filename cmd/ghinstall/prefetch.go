@@ -0,0 +1,62 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/sixban6/ghinstall"
+	log "github.com/sixban6/ghinstall/internal/logger"
+)
+
+func runPrefetch(args []string) int {
+	fs := flag.NewFlagSet("prefetch", flag.ExitOnError)
+	configFile := fs.String("config", "", "Path to configuration file")
+	timeout := fs.Duration("timeout", 30*time.Minute, "Timeout for downloading all assets")
+	only := fs.String("only", "", "Comma-separated repositories to prefetch (name, owner/repo or URL); all by default")
+	skip := fs.String("skip", "", "Comma-separated repositories not to prefetch (name, owner/repo or URL)")
+	logLevel := logLevelFlag(fs)
+	fs.Parse(args)
+	log.SetLevel(*logLevel)
+
+	cfg, err := loadConfigArg(fs, *configFile)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to load configuration: %v\n", err)
+		return 1
+	}
+	if *only != "" || *skip != "" {
+		if cfg.Github, err = cfg.Select(splitList(*only), splitList(*skip)); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 1
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), *timeout)
+	defer cancel()
+
+	results, err := ghinstall.Prefetch(ctx, cfg, nil)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to prefetch: %v\n", err)
+		return 1
+	}
+
+	failed := 0
+	for _, res := range results {
+		switch {
+		case res.Err != nil:
+			fmt.Printf("%s: error: %s\n", res.Repo.DisplayName(), errorText(res.Err))
+			failed++
+		case res.Cached:
+			fmt.Printf("%s: %s %s already cached\n", res.Repo.DisplayName(), res.Tag, res.Asset)
+		default:
+			fmt.Printf("%s: cached %s %s (sha256 %s)\n", res.Repo.DisplayName(), res.Tag, res.Asset, res.SHA256)
+		}
+	}
+
+	if failed > 0 {
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,102 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/sixban6/ghinstall"
+	"github.com/sixban6/ghinstall/internal/state"
+)
+
+func runReinstall(args []string) int {
+	fs := flag.NewFlagSet("reinstall", flag.ExitOnError)
+	all := fs.Bool("all", false, "Reinstall every recorded install at its recorded version")
+	configFile := fs.String("config", "", "Optional configuration file supplying mirror, cache and bin_dir settings")
+	timeout := fs.Duration("timeout", 30*time.Minute, "Timeout for reinstalling")
+	fs.Parse(args)
+
+	if !*all {
+		fmt.Fprintf(os.Stderr, "usage: %s reinstall -all [-config file]\n", os.Args[0])
+		return 2
+	}
+
+	cfg := &ghinstall.Config{}
+	if *configFile != "" {
+		loaded, err := ghinstall.LoadConfig(*configFile)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Failed to load configuration: %v\n", err)
+			return 1
+		}
+		cfg = loaded
+	}
+
+	exp, err := state.ExportAll()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to read state: %v\n", err)
+		return 1
+	}
+
+	// Replay exactly what was recorded: the same tag, asset and digest.
+	cfg.Github = nil
+	var replayed []state.Install
+	for _, in := range exp.Installs {
+		if !strings.HasPrefix(in.Repo, "https://github.com/") {
+			fmt.Fprintf(os.Stderr, "Skipping %s: only GitHub repositories can be replayed\n", in.Repo)
+			continue
+		}
+		cfg.Github = append(cfg.Github, ghinstall.Repo{
+			URL:          in.Repo,
+			Name:         in.Name,
+			OutputDir:    in.OutputDir,
+			Version:      in.Tag,
+			AssetPattern: "^" + regexp.QuoteMeta(in.Asset) + "$",
+			SHA256:       in.SHA256,
+		})
+		replayed = append(replayed, in)
+	}
+	if len(cfg.Github) == 0 {
+		fmt.Println("Nothing to reinstall")
+		return 0
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), *timeout)
+	defer cancel()
+
+	// The records were made elsewhere, typically on another machine; only
+	// forcing the install tells whether their files are actually here.
+	opts := append([]ghinstall.Option{ghinstall.WithForce(true)}, extraOptions...)
+	if err := ghinstall.InstallWithOptions(ctx, cfg, nil, opts...); err != nil {
+		fmt.Fprintf(os.Stderr, "Reinstall failed: %v\n", err)
+		return 1
+	}
+
+	// The replay pinned every install to its tag; keep the original pins on record.
+	for _, in := range replayed {
+		if err := restoreVersion(in); err != nil {
+			fmt.Fprintf(os.Stderr, "Failed to update state of %s: %v\n", in.OutputDir, err)
+			return 1
+		}
+	}
+
+	fmt.Printf("Reinstalled %d repositories\n", len(replayed))
+	return 0
+}
+
+func restoreVersion(in state.Install) error {
+	f, err := state.Load(in.OutputDir)
+	if err != nil {
+		return err
+	}
+	rec, ok := f.Get(in.Repo)
+	if !ok {
+		return nil
+	}
+	rec.Version = in.Version
+	f.Put(rec)
+	return f.Save()
+}
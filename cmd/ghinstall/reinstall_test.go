@@ -0,0 +1,81 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/sixban6/ghinstall"
+	"github.com/sixban6/ghinstall/ghinstalltest"
+	"github.com/sixban6/ghinstall/internal/state"
+)
+
+// fixtureServer publishes owner/app v1.0.0 and makes the installs of the
+// commands use it.
+func fixtureServer(t *testing.T) []byte {
+	t.Helper()
+	t.Setenv("HOME", t.TempDir())
+	t.Setenv("USERPROFILE", os.Getenv("HOME"))
+
+	archive := ghinstalltest.TarGz(map[string]string{"app": "v1"})
+	server := ghinstalltest.NewServer(t)
+	server.Publish("owner", "app", ghinstall.Release{TagName: "v1.0.0"}, map[string][]byte{"app.tar.gz": archive})
+	extraOptions = []ghinstall.Option{ghinstall.WithGitHubAPI(server.URL)}
+	t.Cleanup(func() { extraOptions = nil })
+	return archive
+}
+
+func TestReinstall_Imported(t *testing.T) {
+	archive := fixtureServer(t)
+	sum := sha256.Sum256(archive)
+
+	// The export of another machine, imported into an empty output_dir.
+	dir := t.TempDir()
+	exp := state.Export{Installs: []state.Install{{OutputDir: dir, Record: state.Record{
+		Repo: "https://github.com/owner/app", Tag: "v1.0.0", Asset: "app.tar.gz", SHA256: hex.EncodeToString(sum[:]),
+	}}}}
+	data, err := json.Marshal(exp)
+	if err != nil {
+		t.Fatal(err)
+	}
+	file := filepath.Join(t.TempDir(), "installs.json")
+	if err := os.WriteFile(file, data, 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	if rc := runState([]string{"import", file}); rc != 0 {
+		t.Fatalf("state import exited with %d", rc)
+	}
+	if rc := runReinstall([]string{"-all"}); rc != 0 {
+		t.Fatalf("reinstall -all exited with %d", rc)
+	}
+	if got, err := os.ReadFile(filepath.Join(dir, "app")); err != nil || string(got) != "v1" {
+		t.Errorf("installed app = %q, %v, want the imported release extracted", got, err)
+	}
+}
+
+func TestStateImport_DangerousDir(t *testing.T) {
+	fixtureServer(t)
+
+	exp := state.Export{Installs: []state.Install{{OutputDir: os.Getenv("HOME"), Record: state.Record{
+		Repo: "https://github.com/owner/app", Tag: "v1.0.0", Asset: "app.tar.gz",
+	}}}}
+	data, err := json.Marshal(exp)
+	if err != nil {
+		t.Fatal(err)
+	}
+	file := filepath.Join(t.TempDir(), "installs.json")
+	if err := os.WriteFile(file, data, 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	if rc := runState([]string{"import", file}); rc != 1 {
+		t.Errorf("state import of the home directory exited with %d, want 1", rc)
+	}
+	if dirs, err := state.Dirs(); err != nil || len(dirs) != 0 {
+		t.Errorf("registered output dirs = %v, %v, want none", dirs, err)
+	}
+}
@@ -0,0 +1,89 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/sixban6/ghinstall/internal/artifacts"
+	"github.com/sixban6/ghinstall/internal/buildinfo"
+	log "github.com/sixban6/ghinstall/internal/logger"
+)
+
+// runReleaseManifest writes the checksum file, and optionally a JSON
+// manifest, of ghinstall's own release binaries.
+func runReleaseManifest(args []string) int {
+	fs := flag.NewFlagSet("release-manifest", flag.ExitOnError)
+	output := fs.String("o", "-", "Checksum file to write (- for standard output)")
+	jsonFile := fs.String("json", "", "Also write a JSON manifest of the artifacts to this file")
+	version := fs.String("version", "", "Release version recorded in the manifest (default this binary's version)")
+	commit := fs.String("commit", "", "Release commit recorded in the manifest (default this binary's commit)")
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: %s release-manifest [flags] <file-or-directory>...\n\n", os.Args[0])
+		fmt.Fprintf(fs.Output(), "Writes the sha256sum-style checksums of release artifacts, descending into\n")
+		fmt.Fprintf(fs.Output(), "directories, for publishing next to them.\n\n")
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+
+	if fs.NArg() == 0 {
+		fs.Usage()
+		return 2
+	}
+
+	// Outputs written into an artifact directory by a previous run are not
+	// artifacts themselves.
+	var skip []string
+	for _, out := range []string{*output, *jsonFile} {
+		if out != "" && out != "-" {
+			skip = append(skip, filepath.Base(out))
+		}
+	}
+	list, err := artifacts.Collect(fs.Args(), skip...)
+	if err != nil {
+		log.Error("%v", err)
+		return 1
+	}
+	if len(list) == 0 {
+		log.Error("No artifacts found in %v", fs.Args())
+		return 1
+	}
+
+	if err := writeOutput(*output, func(w io.Writer) error { return artifacts.WriteChecksums(w, list) }); err != nil {
+		log.Error("Failed to write checksums: %v", err)
+		return 1
+	}
+	if *jsonFile != "" {
+		info := buildinfo.Read(appVersion)
+		m := artifacts.Manifest{Version: *version, Commit: *commit, Artifacts: list}
+		if m.Version == "" {
+			m.Version = info.Version
+		}
+		if m.Commit == "" {
+			m.Commit = info.Commit
+		}
+		if err := writeOutput(*jsonFile, func(w io.Writer) error { return artifacts.WriteManifest(w, m) }); err != nil {
+			log.Error("Failed to write manifest: %v", err)
+			return 1
+		}
+	}
+	return 0
+}
+
+// writeOutput writes to the file at path, or to standard output for "-".
+func writeOutput(path string, write func(io.Writer) error) error {
+	if path == "-" {
+		return write(os.Stdout)
+	}
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := write(f); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
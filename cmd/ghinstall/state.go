@@ -0,0 +1,89 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/sixban6/ghinstall"
+	"github.com/sixban6/ghinstall/internal/state"
+)
+
+func runState(args []string) int {
+	if len(args) == 0 {
+		fmt.Fprintf(os.Stderr, "usage: %s state export|import [-allow-dangerous-dir] [file]\n", os.Args[0])
+		return 2
+	}
+
+	switch args[0] {
+	case "export":
+		return stateExport()
+	case "import":
+		return stateImport(args[1:])
+	default:
+		fmt.Fprintf(os.Stderr, "unknown state command %q\n", args[0])
+		return 2
+	}
+}
+
+func stateExport() int {
+	exp, err := state.ExportAll()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to export state: %v\n", err)
+		return 1
+	}
+
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(exp); err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to export state: %v\n", err)
+		return 1
+	}
+	return 0
+}
+
+// stateImport records the installs of an export (read from a file or stdin)
+// without installing anything; "ghinstall reinstall -all" then replays them.
+// The export may come from anywhere, so its output directories are checked
+// like those of a configuration before any is created.
+func stateImport(args []string) int {
+	fs := flag.NewFlagSet("state import", flag.ExitOnError)
+	dangerous := fs.Bool("allow-dangerous-dir", false, "Allow output directories that are /, the home directory or a system directory")
+	fs.Parse(args)
+
+	var in io.Reader = os.Stdin
+	if file := fs.Arg(0); file != "" && file != "-" {
+		f, err := os.Open(file)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Failed to import state: %v\n", err)
+			return 1
+		}
+		defer f.Close()
+		in = f
+	}
+
+	var exp state.Export
+	if err := json.NewDecoder(in).Decode(&exp); err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to parse state export: %v\n", err)
+		return 1
+	}
+
+	cfg := &ghinstall.Config{AllowDangerousDir: *dangerous}
+	for _, in := range exp.Installs {
+		cfg.Github = append(cfg.Github, ghinstall.Repo{URL: in.Repo, OutputDir: in.OutputDir})
+	}
+	if err := ghinstall.CheckOutputDirs(cfg); err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to import state: %v\n", err)
+		return 1
+	}
+	if err := state.Import(context.Background(), &exp); err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to import state: %v\n", err)
+		return 1
+	}
+
+	fmt.Printf("Imported %d installs; run \"%s reinstall -all\" to install them\n", len(exp.Installs), os.Args[0])
+	return 0
+}
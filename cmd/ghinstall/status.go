@@ -0,0 +1,102 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"github.com/sixban6/ghinstall"
+)
+
+func runStatus(args []string) int {
+	fs := flag.NewFlagSet("status", flag.ExitOnError)
+	configFile := fs.String("config", "", "Path to configuration file")
+	timeout := fs.Duration("timeout", 5*time.Minute, "Timeout for checking and reinstalling")
+	reinstall := fs.Bool("reinstall-yanked", false, "Reinstall the newest available release of repositories whose installed release was yanked")
+	fs.Parse(args)
+
+	cfg, err := loadConfigArg(fs, *configFile)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to load configuration: %v\n", err)
+		return 1
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), *timeout)
+	defer cancel()
+
+	statuses := ghinstall.Status(ctx, cfg)
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "REPOSITORY\tOUTPUT\tINSTALLED\tLATEST\tSIZE\tSTATUS")
+	var yanked []ghinstall.Repo
+	failed, replaced := 0, 0
+	for _, st := range statuses {
+		installed := "-"
+		if st.Installed != nil {
+			installed = st.Installed.Tag
+		}
+		latest := st.Latest
+		if latest == "" {
+			latest = "-"
+		}
+		size := "-"
+		if st.Remote != nil && st.Remote.Size >= 0 {
+			size = fmt.Sprintf("%.2f MB", float64(st.Remote.Size)/(1024*1024))
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\n", st.Repo.DisplayName(), st.Repo.OutputDir, installed, latest, size, describeStatus(st))
+
+		if st.Yanked && st.Repo.Version == "" {
+			yanked = append(yanked, st.Repo)
+		}
+		if st.Err != nil && !st.Yanked {
+			failed++
+		}
+		if st.Replaced != "" {
+			replaced++
+		}
+	}
+	w.Flush()
+
+	if len(yanked) > 0 && *reinstall {
+		fmt.Printf("\nReinstalling %d repositories with yanked releases\n", len(yanked))
+		fixCfg := *cfg
+		fixCfg.Github = yanked
+		if err := ghinstall.InstallWithConfig(ctx, &fixCfg); err != nil {
+			fmt.Fprintf(os.Stderr, "Reinstall failed: %v\n", err)
+			return 1
+		}
+	} else if len(yanked) > 0 {
+		fmt.Println("\nRun with -reinstall-yanked to replace yanked releases with the newest available one.")
+	}
+
+	if replaced > 0 {
+		fmt.Printf("\n%d assets were replaced upstream without a new tag. Check why, then run ghinstall -force-refresh to install them.\n", replaced)
+	}
+
+	if failed > 0 || replaced > 0 {
+		return 1
+	}
+	return 0
+}
+
+func describeStatus(st ghinstall.RepoStatus) string {
+	switch {
+	case st.Yanked && st.Repo.Version != "":
+		return "yanked (pinned; update version)"
+	case st.Yanked:
+		return "yanked"
+	case st.Err != nil:
+		return "error: " + errorText(st.Err)
+	case st.Replaced != "":
+		return "replaced upstream (" + st.Replaced + ")"
+	case st.Installed == nil:
+		return "not installed"
+	case st.UpToDate():
+		return "up to date"
+	default:
+		return "update available"
+	}
+}
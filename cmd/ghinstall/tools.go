@@ -0,0 +1,126 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"runtime"
+	"time"
+
+	"github.com/sixban6/ghinstall"
+	"github.com/sixban6/ghinstall/internal/cache"
+	"github.com/sixban6/ghinstall/internal/catalog"
+	"github.com/sixban6/ghinstall/internal/config"
+	log "github.com/sixban6/ghinstall/internal/logger"
+	"github.com/sixban6/ghinstall/internal/shim"
+)
+
+// runTools installs the tools listed in an asdf-style .tool-versions or a
+// tools.yaml file, by catalog name or owner/repo, at their listed versions.
+func runTools(args []string) int {
+	fs := flag.NewFlagSet("tools", flag.ExitOnError)
+	outputDir := fs.String("o", filepath.Join(filepath.Dir(shim.DefaultDir()), "tools"), "Directory receiving a subdirectory per tool")
+	binDir := fs.String("bin-dir", shim.DefaultDir(), "Directory receiving shims for the tools' executables (empty to disable)")
+	mirror := fs.String("mirror", "", "GitHub mirror URL or preset name (see 'mirrors')")
+	timeout := fs.Duration("timeout", 10*time.Minute, "Timeout for installation")
+	parallel := fs.Int("parallel", 1, "Number of tools to install at the same time")
+	catalogURL := fs.String("catalog-url", os.Getenv("GHINSTALL_CATALOG_URL"), "URL or path of a catalog overriding the built-in one (default $GHINSTALL_CATALOG_URL)")
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: %s tools [flags] [file]\n\n", os.Args[0])
+		fmt.Fprintf(fs.Output(), "Installs the tools of an asdf/mise .tool-versions or a tools.yaml file, the\n")
+		fmt.Fprintf(fs.Output(), "nearest one in the current directory or its parents by default.\n\n")
+		fs.PrintDefaults()
+	}
+	logLevel := logLevelFlag(fs)
+	fs.Parse(args)
+	log.SetLevel(*logLevel)
+
+	var file string
+	switch fs.NArg() {
+	case 0:
+		if file = findToolVersions("."); file == "" {
+			log.Error("No %s or %s found in the current directory or its parents", catalog.ToolVersionsFiles[0], catalog.ToolVersionsFiles[1])
+			return 1
+		}
+	case 1:
+		file = fs.Arg(0)
+	default:
+		fs.Usage()
+		return 1
+	}
+
+	data, err := os.ReadFile(file)
+	if err != nil {
+		log.Error("Failed to read tool versions: %v", err)
+		return 1
+	}
+	tools, err := catalog.ParseToolVersions(file, data)
+	if err != nil {
+		log.Error("%v", err)
+		return 1
+	}
+	if len(tools) == 0 {
+		fmt.Printf("%s lists no tools\n", file)
+		return 0
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), *timeout)
+	defer cancel()
+
+	cat, err := catalog.Load(ctx, *catalogURL, cache.UserDir())
+	if err != nil {
+		log.Error("Failed to load catalog: %v", err)
+		return 1
+	}
+
+	cfg := &ghinstall.Config{BinDir: *binDir}
+	if _, ok := config.LookupMirror(*mirror); ok {
+		cfg.Mirror = *mirror
+	} else {
+		cfg.MirrorURL = *mirror
+	}
+	for _, tv := range tools {
+		repo, err := cat.RepoFor(tv, runtime.GOOS, runtime.GOARCH, filepath.Join(*outputDir, path.Base(tv.Tool)))
+		if err != nil {
+			log.Error("%s:%d: %v", file, tv.Line, err)
+			return 1
+		}
+		cfg.Github = append(cfg.Github, repo)
+	}
+
+	log.Info("Installing %d tools from %s", len(cfg.Github), file)
+	var opts []ghinstall.Option
+	if *parallel > 1 {
+		opts = append(opts, ghinstall.WithParallel(*parallel))
+	}
+	if err := ghinstall.InstallWithOptions(ctx, cfg, nil, opts...); err != nil {
+		log.Error("Installation failed: %v", err)
+		return 1
+	}
+	log.Success("Installed %d tools into %s", len(cfg.Github), *outputDir)
+	return 0
+}
+
+// findToolVersions returns the tool version file in dir or its nearest
+// ancestor, "" when there is none.
+func findToolVersions(dir string) string {
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return ""
+	}
+	for {
+		for _, name := range catalog.ToolVersionsFiles {
+			if info, err := os.Stat(filepath.Join(dir, name)); err == nil && info.Mode().IsRegular() {
+				return filepath.Join(dir, name)
+			}
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return ""
+		}
+		dir = parent
+	}
+}
@@ -0,0 +1,63 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/sixban6/ghinstall"
+)
+
+func runVerify(args []string) int {
+	fs := flag.NewFlagSet("verify", flag.ExitOnError)
+	configFile := fs.String("config", "", "Path to configuration file")
+	timeout := fs.Duration("timeout", 5*time.Minute, "Timeout for verifying and repairing")
+	repair := fs.Bool("repair", false, "Re-extract modified and missing files from the cached archive")
+	fs.Parse(args)
+
+	cfg, err := loadConfigArg(fs, *configFile)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to load configuration: %v\n", err)
+		return 1
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), *timeout)
+	defer cancel()
+
+	failed := 0
+	for _, res := range ghinstall.Verify(ctx, cfg, *repair) {
+		switch {
+		case len(res.Problems) == 0 && res.Err == nil:
+			fmt.Printf("%s (%s): ok\n", res.Repo.DisplayName(), res.Repo.OutputDir)
+			continue
+		case len(res.Problems) == 0:
+			fmt.Printf("%s (%s): error: %v\n", res.Repo.DisplayName(), res.Repo.OutputDir, res.Err)
+			failed++
+			continue
+		}
+
+		fmt.Printf("%s (%s): %d damaged files\n", res.Repo.DisplayName(), res.Repo.OutputDir, len(res.Problems))
+		for _, p := range res.Problems {
+			fmt.Printf("  %-8s %s\n", p.Kind, p.Path)
+		}
+		switch {
+		case res.Repaired:
+			fmt.Println("  repaired from the cached archive")
+		case res.Err != nil:
+			fmt.Printf("  repair failed: %v\n", res.Err)
+			failed++
+		default:
+			failed++
+		}
+	}
+
+	if failed > 0 {
+		if !*repair {
+			fmt.Println("\nRun with -repair to restore damaged files from the cached archive.")
+		}
+		return 1
+	}
+	return 0
+}
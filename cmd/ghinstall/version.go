@@ -0,0 +1,13 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/sixban6/ghinstall/internal/buildinfo"
+)
+
+// runVersion prints the version with the build details useful in bug reports.
+func runVersion(args []string) int {
+	fmt.Print(buildinfo.Read(appVersion))
+	return 0
+}
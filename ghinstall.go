@@ -2,10 +2,22 @@ package ghinstall
 
 import (
 	"context"
+	"fmt"
+	"io"
+	"io/fs"
+	"regexp"
+	"strings"
 
+	"github.com/sixban6/ghinstall/internal/attest"
 	"github.com/sixban6/ghinstall/internal/config"
+	"github.com/sixban6/ghinstall/internal/downloader"
+	"github.com/sixban6/ghinstall/internal/extractor"
 	"github.com/sixban6/ghinstall/internal/installer"
+	"github.com/sixban6/ghinstall/internal/mirror"
+	"github.com/sixban6/ghinstall/internal/progress"
+	"github.com/sixban6/ghinstall/internal/provider"
 	"github.com/sixban6/ghinstall/internal/release"
+	"github.com/sixban6/ghinstall/internal/state"
 )
 
 // Install provides a one-click entry point: loads configuration from the specified
@@ -37,22 +49,562 @@ func InstallWithConfigAndFilter(ctx context.Context, cfg *Config, filter AssetFi
 	return installer.New(nil, nil, nil).Install(ctx, cfg, filter)
 }
 
+// InstallWithOptions installs using a pre-loaded configuration, an asset filter
+// (nil selects the default filter) and installer options.
+func InstallWithOptions(ctx context.Context, cfg *Config, filter AssetFilter, opts ...Option) error {
+	if filter == nil {
+		filter = DefaultAssetFilter()
+	}
+	return installer.New(nil, nil, nil, opts...).Install(ctx, cfg, filter)
+}
+
+// Update loads the configuration from cfgPath and installs only the
+// repositories whose release differs from the tag recorded in their output
+// directory, or that are not installed yet. It returns the upgrades in config
+// order, including those made before an error.
+func Update(ctx context.Context, cfgPath string) ([]Upgrade, error) {
+	cfg, err := config.Load(cfgPath)
+	if err != nil {
+		return nil, err
+	}
+	report := progress.NewReport(cfg.Github)
+	err = installer.New(nil, nil, nil, installer.WithUpdate(true), installer.WithMiddleware(report), installer.WithProgress(report)).
+		Install(ctx, cfg, DefaultAssetFilter())
+	var upgrades []Upgrade
+	for _, l := range report.Lines() {
+		if l.Outcome == progress.Upgraded {
+			upgrades = append(upgrades, Upgrade{Repo: l.Repo, From: l.From, To: l.To})
+		}
+	}
+	return upgrades, err
+}
+
+// Upgrade is a repository Update installed a new release of.
+type Upgrade struct {
+	Repo Repo
+	// From is the tag installed before, "" when the repository was not
+	// installed, and To the tag installed now.
+	From, To string
+}
+
+// Status re-resolves every repository of cfg and compares the result with what
+// is recorded as installed, detecting updates and yanked releases.
+func Status(ctx context.Context, cfg *Config) []RepoStatus {
+	return installer.New(nil, nil, nil).Status(ctx, cfg)
+}
+
+// RepoStatus exports the per-repository status for library usage.
+type RepoStatus = installer.RepoStatus
+
+// Verify re-hashes the installed files of every repository of cfg against the
+// manifest recorded at install time. With repair, damaged files are restored
+// from the cached archive.
+func Verify(ctx context.Context, cfg *Config, repair bool) []VerifyResult {
+	return installer.New(nil, nil, nil).Verify(ctx, cfg, repair)
+}
+
+// VerifyResult exports the per-repository verification result for library usage.
+type VerifyResult = installer.VerifyResult
+
+// Attest signs the manifest recorded for every repository of cfg with the
+// private key at key, in format "ssh" (the default) or "minisign".
+func Attest(ctx context.Context, cfg *Config, format, key string) ([]AttestResult, error) {
+	f, err := attest.ParseFormat(format)
+	if err != nil {
+		return nil, err
+	}
+	return installer.New(nil, nil, nil).Attest(ctx, cfg, f, key), nil
+}
+
+// VerifyAttestations checks the manifest signature of every repository of cfg
+// against the public key at pub, then the installed files against the manifest.
+func VerifyAttestations(ctx context.Context, cfg *Config, format, pub string) ([]AttestResult, error) {
+	f, err := attest.ParseFormat(format)
+	if err != nil {
+		return nil, err
+	}
+	return installer.New(nil, nil, nil).VerifyAttestations(ctx, cfg, f, pub), nil
+}
+
+// AttestResult exports the per-repository attestation result for library usage.
+type AttestResult = installer.AttestResult
+
+// MirrorSync uploads the asset an install would select for every repository
+// of cfg, with its checksum, into dest: a directory or an http(s) URL accepting
+// PUT requests. The result can then be served as mirror_url.
+func MirrorSync(ctx context.Context, cfg *Config, dest string) ([]MirrorResult, error) {
+	store, err := mirror.Open(dest)
+	if err != nil {
+		return nil, err
+	}
+	return installer.New(nil, nil, nil).MirrorSync(ctx, cfg, store), nil
+}
+
+// MirrorResult exports the per-repository mirroring result for library usage.
+type MirrorResult = installer.MirrorResult
+
+// Prefetch downloads the asset an install would select for every repository
+// of cfg into the download cache, without extracting anything. cfg must set
+// cache_dir. A nil filter selects the default asset filter.
+func Prefetch(ctx context.Context, cfg *Config, filter AssetFilter) ([]PrefetchResult, error) {
+	if filter == nil {
+		filter = DefaultAssetFilter()
+	}
+	return installer.New(nil, nil, nil).Prefetch(ctx, cfg, filter)
+}
+
+// PrefetchPlatform is Prefetch for installs on platform, a GOOS/GOARCH such as
+// "linux/arm64", rather than this host: it caches the assets such an install
+// would download, e.g. to copy them to other machines.
+func PrefetchPlatform(ctx context.Context, cfg *Config, platform string) ([]PrefetchResult, error) {
+	goos, goarch, _ := strings.Cut(platform, "/")
+	return installer.New(nil, nil, nil, installer.WithPlatform(platform)).Prefetch(ctx, cfg, release.ByPlatform(goos, goarch))
+}
+
+// PrefetchResult exports the per-repository prefetch result for library usage.
+type PrefetchResult = installer.PrefetchResult
+
+// ResolveOptions select what Resolve resolves. The zero value selects the
+// latest stable release and the asset an install on this platform would.
+type ResolveOptions struct {
+	// Version pins a release tag.
+	Version string
+	// Channel is "stable" (the default), "prerelease" or "nightly".
+	Channel string
+	// AssetPattern is a regular expression selecting the asset by name; it
+	// replaces Filter. Its {tag}, {version}, {os} and {arch} placeholders are
+	// expanded as in config files.
+	AssetPattern string
+	// Filter selects the asset; nil selects DefaultAssetFilter.
+	Filter AssetFilter
+	// Mirror is the name of a mirror preset or a mirror URL, used when GitHub
+	// is not reachable directly.
+	Mirror string
+}
+
+// Resolve reports what installing repoURL would install: the release tag, the
+// chosen asset, the URL it would be downloaded from after deciding between
+// GitHub and the mirror, and its expected digest. Nothing is downloaded, so
+// tools can present the result for approval first. opts may be nil.
+func Resolve(ctx context.Context, repoURL string, opts *ResolveOptions) (*ResolvedRelease, error) {
+	if opts == nil {
+		opts = &ResolveOptions{}
+	}
+	if _, _, err := config.ParseRepoURL(repoURL); err != nil {
+		return nil, err
+	}
+	if opts.Version != "" && opts.Channel != "" {
+		return nil, fmt.Errorf("version and channel are mutually exclusive")
+	}
+	switch opts.Channel {
+	case "", "stable", "prerelease", "nightly":
+	default:
+		return nil, fmt.Errorf("channel must be stable, prerelease or nightly")
+	}
+	if opts.AssetPattern != "" {
+		if _, err := regexp.Compile(opts.AssetPattern); err != nil {
+			return nil, fmt.Errorf("invalid asset pattern: %w", err)
+		}
+	}
+
+	cfg := mirrorConfig(opts.Mirror)
+	repo := Repo{URL: strings.TrimSuffix(repoURL, "/"), Version: opts.Version, Channel: opts.Channel, AssetPattern: opts.AssetPattern}
+
+	filter := opts.Filter
+	if filter == nil {
+		filter = DefaultAssetFilter()
+	}
+	return installer.New(nil, nil, nil).Resolve(ctx, cfg, repo, filter)
+}
+
+// ResolvedRelease exports the result of Resolve for library usage.
+type ResolvedRelease = installer.ResolvedRelease
+
+// Pin sets the version of the repositories of the config file at cfgPath
+// matching selector (a name, "owner/repo" or URL) to tag or, when tag is "",
+// to the release an install would select right now, freezing what later
+// installs install. The file keeps its comments.
+func Pin(ctx context.Context, cfgPath, selector, tag string) ([]PinResult, error) {
+	cfg, err := config.Load(cfgPath)
+	if err != nil {
+		return nil, err
+	}
+	return installer.New(nil, nil, nil).Pin(ctx, cfgPath, cfg, selector, tag)
+}
+
+// Unpin removes the version of the repositories of the config file at cfgPath
+// matching selector, so they follow their latest release again.
+func Unpin(cfgPath, selector string) ([]PinResult, error) {
+	cfg, err := config.Load(cfgPath)
+	if err != nil {
+		return nil, err
+	}
+	return installer.Unpin(cfgPath, cfg, selector)
+}
+
+// ConfigSchemaVersion is the newest config schema version, which
+// MigrateConfig rewrites config files to.
+const ConfigSchemaVersion = config.SchemaVersion
+
+// MigrateConfig rewrites the config file at cfgPath to ConfigSchemaVersion,
+// keeping its comments, and reports whether it changed. Files of older
+// versions keep loading without it.
+func MigrateConfig(cfgPath string) (bool, error) {
+	return config.Migrate(cfgPath)
+}
+
+// PinResult exports the per-repository result of Pin and Unpin for library usage.
+type PinResult = installer.PinResult
+
+// mirrorConfig returns a config using mirror, the name of a mirror preset or
+// a mirror URL.
+func mirrorConfig(mirror string) *Config {
+	cfg := &Config{}
+	if _, ok := config.LookupMirror(mirror); ok {
+		cfg.Mirror = mirror
+	} else {
+		cfg.MirrorURL = strings.TrimSuffix(mirror, "/")
+	}
+	return cfg
+}
+
+// DownloadOptions configure DownloadToFile. The zero value downloads without
+// a mirror and without checking the digest.
+type DownloadOptions struct {
+	// Mirror is the name of a mirror preset or a mirror URL, used for GitHub
+	// URLs when GitHub is not reachable directly.
+	Mirror string
+	// SHA256 is the hex digest the content must have.
+	SHA256 string
+}
+
+// DownloadToFile downloads url into path with the mirror fallback, timeouts
+// and redirect policy of installs. The content is streamed to path.part,
+// synced to disk and renamed, so path never holds a partial or unverified
+// download. opts may be nil; options such as WithURLRewriter apply as well.
+func DownloadToFile(ctx context.Context, url, path string, opts *DownloadOptions, options ...Option) (int64, error) {
+	if opts == nil {
+		opts = &DownloadOptions{}
+	}
+	return installer.New(nil, nil, nil, options...).DownloadToFile(ctx, mirrorConfig(opts.Mirror), url, path, opts.SHA256)
+}
+
+// AssetMetadata exports the HEAD metadata of an asset reported by Status.
+type AssetMetadata = downloader.Metadata
+
+// InstallRecord exports the recorded install of a repository.
+type InstallRecord = state.Record
+
+// Option exports the installer option type for library usage.
+type Option = installer.Option
+
+// InstallResult exports the per-repository install result for library usage.
+type InstallResult = installer.InstallResult
+
+// ExtractSummary counts the files, directories and symlinks an install
+// extracted and lists the top-level entries, as InstallResult.Extracted.
+type ExtractSummary = installer.ExtractSummary
+
+// DownloadStats describes the retries of a download, as InstallResult.Download.
+type DownloadStats = installer.DownloadStats
+
+// DownloadError is the error of a download that failed after its retries; it
+// carries their DownloadStats.
+type DownloadError = installer.DownloadError
+
+// PostProcessor exports the post-extraction processor interface for library usage.
+type PostProcessor = installer.PostProcessor
+
+// PostProcessorFunc adapts a function to the PostProcessor interface.
+type PostProcessorFunc = installer.PostProcessorFunc
+
+// WithPostProcessors registers processors run, in order, after each repository is extracted.
+func WithPostProcessors(processors ...PostProcessor) Option {
+	return installer.WithPostProcessors(processors...)
+}
+
+// Middleware exports the install middleware interface for library usage.
+type Middleware = installer.Middleware
+
+// MiddlewareFunc adapts a function to the Middleware interface.
+type MiddlewareFunc = installer.MiddlewareFunc
+
+// InstallStep exports the description of an install passed to Middleware.
+type InstallStep = installer.Step
+
+// InstallStage exports the stages at which Middleware runs.
+type InstallStage = installer.Stage
+
+const (
+	BeforeResolve  = installer.BeforeResolve
+	AfterResolve   = installer.AfterResolve
+	BeforeDownload = installer.BeforeDownload
+	AfterExtract   = installer.AfterExtract
+)
+
+// WithMiddleware registers middleware run, in order, at every stage of each
+// repository's install; an error stops that install.
+func WithMiddleware(middleware ...Middleware) Option {
+	return installer.WithMiddleware(middleware...)
+}
+
+// WithForceRefresh reinstalls assets that upstream replaced under the
+// installed tag instead of only warning about them.
+func WithForceRefresh(force bool) Option {
+	return installer.WithForceRefresh(force)
+}
+
+// WithForce re-downloads and re-extracts every repository regardless of caches
+// and recorded state, up-to-date ones too, removing the files of the previous
+// install first.
+func WithForce(force bool) Option {
+	return installer.WithForce(force)
+}
+
+// WithUpdate installs only the repositories whose release differs from the
+// tag recorded in their output directory, or that are not installed yet.
+func WithUpdate(update bool) Option {
+	return installer.WithUpdate(update)
+}
+
+// WithToolCache installs every repository into the GitHub Actions tool cache
+// rooted at root ($RUNNER_TOOL_CACHE) as <name>/<version>/<arch>.
+func WithToolCache(root string) Option {
+	return installer.WithToolCache(root)
+}
+
+// WithGitHubActions reports installs as workflow notices and adds their
+// executables to GITHUB_PATH.
+func WithGitHubActions(enabled bool) Option {
+	return installer.WithGitHubActions(enabled)
+}
+
+// WithURLRewriter downloads every asset from the URL fn returns for its GitHub
+// URL, for URL schemes mirror_url cannot express such as corporate proxies.
+// Returning the URL unchanged keeps the usual GitHub or mirror choice.
+func WithURLRewriter(fn func(assetURL string) string) Option {
+	return installer.WithURLRewriter(fn)
+}
+
+// WithParallel installs up to n repositories at the same time; the first
+// failure cancels the others.
+func WithParallel(n int) Option {
+	return installer.WithParallel(n)
+}
+
+// DownloadPlan lists the assets an install is about to download, as given to
+// the WithPlan function.
+type DownloadPlan = installer.DownloadPlan
+
+// PlannedDownload is one asset of a DownloadPlan.
+type PlannedDownload = installer.PlannedDownload
+
+// WithPlan calls fn with the downloads of every install once all repositories
+// are resolved; an error from fn aborts the install before any download.
+func WithPlan(fn func(ctx context.Context, plan *DownloadPlan) error) Option {
+	return installer.WithPlan(fn)
+}
+
+// WithDryRun resolves the repositories and reports their downloads to the
+// WithPlan function without installing anything.
+func WithDryRun(dryRun bool) Option {
+	return installer.WithDryRun(dryRun)
+}
+
+// MajorUpgrade is an upgrade to a new major version held by hold_on_major
+// unless the WithMajorApproval function approves it.
+type MajorUpgrade = installer.MajorUpgrade
+
+// WithMajorApproval asks fn, one repository at a time, whether a repository
+// with hold_on_major may upgrade to a new major version instead of being held
+// at its installed one.
+func WithMajorApproval(fn func(ctx context.Context, u MajorUpgrade) (bool, error)) Option {
+	return installer.WithMajorApproval(fn)
+}
+
+// Progress receives the status and download progress of every install.
+type Progress = installer.Progress
+
+// WithProgress reports the progress of every install to p.
+func WithProgress(p Progress) Option {
+	return installer.WithProgress(p)
+}
+
+// ExtractEvent exports the per-entry extraction event for library usage.
+type ExtractEvent = extractor.Event
+
+// WithExtractEvents registers fn to receive an event for every file extracted,
+// with its size and progress, e.g. to render a live file list in a GUI.
+func WithExtractEvents(fn func(repo Repo, ev ExtractEvent)) Option {
+	return installer.WithExtractEvents(fn)
+}
+
+// ExtractFS is a writable destination for ExtractToFS.
+type ExtractFS = extractor.WritableFS
+
+// ExtractOptions tune how ExtractToFS unpacks archives.
+type ExtractOptions = extractor.Options
+
+// ExtractToFS extracts the tar.gz or zip archive read from src into dst
+// without touching the local disk, e.g. into NewMemFS, object storage or
+// another archive.
+func ExtractToFS(src io.Reader, dst ExtractFS, opts ExtractOptions) error {
+	return extractor.ExtractFS(src, dst, opts)
+}
+
+// MemFS is an in-memory ExtractFS that can be read back as an fs.FS.
+type MemFS = extractor.MemFS
+
+// NewMemFS returns an empty MemFS.
+func NewMemFS() *MemFS {
+	return extractor.NewMemFS()
+}
+
+// ConfigError is returned by LoadConfig with the file and, when known, the
+// line of the problem.
+type ConfigError = config.Error
+
+// InstallError is returned by the Install functions with the repository that
+// failed to install.
+type InstallError = installer.RepoError
+
+// ResolveError is returned by the Install functions when several repositories
+// cannot be resolved; it holds an InstallError for each and nothing was
+// installed.
+type ResolveError = installer.ResolveError
+
+// BatchError is returned by the Install functions when repositories fail with
+// Config.ContinueOnError; it holds the Result of every repository, installed
+// or not.
+type BatchError = installer.BatchError
+
+// Result is the outcome of installing one repository, as held by a
+// BatchError.
+type Result = installer.Result
+
 // Config exports the internal config structure for library usage.
 type Config = config.Config
 
 // Repo exports the internal repo structure for library usage.
 type Repo = config.Repo
 
+// SignatureOptions select the gpg or cosign signature a repository's asset
+// must carry to be installed.
+type SignatureOptions = config.SignatureOptions
+
+// RepoDefaults are the settings of a Config's defaults block, inherited by
+// repositories loaded from a config file that do not set them.
+type RepoDefaults = config.RepoDefaults
+
 // LoadConfig loads and validates a configuration file.
 func LoadConfig(cfgPath string) (*Config, error) {
 	return config.Load(cfgPath)
 }
 
+// LoadConfigFS loads and validates the configuration file at path in fsys,
+// such as a manifest embedded in the application with go:embed.
+func LoadConfigFS(fsys fs.FS, path string) (*Config, error) {
+	return config.LoadFS(fsys, path)
+}
+
+// LoadDiscoveredConfig loads the config applying to dir when none is given
+// explicitly: the project's ProjectConfigFile, found in dir or its nearest
+// ancestor, merged over the per-user config at GlobalConfigPath. It returns
+// the files it loaded, or ErrNoConfig when neither exists.
+func LoadDiscoveredConfig(dir string) (*Config, []string, error) {
+	return config.LoadDiscovered(dir)
+}
+
+// ConfigBuilder builds a Config in Go with the validation and normalization
+// of LoadConfig, reporting the first invalid step from Build.
+type ConfigBuilder = config.Builder
+
+// RepoOption sets an optional field of a repository added with
+// ConfigBuilder.AddRepo.
+type RepoOption = config.RepoOption
+
+// NewConfig returns a ConfigBuilder of an empty config:
+//
+//	cfg, err := ghinstall.NewConfig().
+//		AddRepo("https://github.com/cli/cli", "/opt/gh", ghinstall.WithPattern(`linux_amd64\.tar\.gz$`)).
+//		WithMirror("ghproxy").
+//		Build()
+func NewConfig() *ConfigBuilder {
+	return config.NewBuilder()
+}
+
+// WithPattern selects the asset of a repository with a regular expression.
+func WithPattern(pattern string) RepoOption {
+	return config.WithPattern(pattern)
+}
+
+// WithVersion pins the release tag of a repository.
+func WithVersion(version string) RepoOption {
+	return config.WithVersion(version)
+}
+
+// WithChannel selects the release channel of a repository.
+func WithChannel(channel string) RepoOption {
+	return config.WithChannel(channel)
+}
+
+// WithName sets the short alias of a repository.
+func WithName(name string) RepoOption {
+	return config.WithName(name)
+}
+
+// WithSHA256 sets the expected digest of the asset of a repository.
+func WithSHA256(digest string) RepoOption {
+	return config.WithSHA256(digest)
+}
+
+// WithExcludeTags excludes the release tags matching patterns from a
+// repository's latest release.
+func WithExcludeTags(patterns ...string) RepoOption {
+	return config.WithExcludeTags(patterns...)
+}
+
+// WithConstraint installs the latest release whose version satisfies a
+// constraint such as ">=1.4.0 <2.0.0" or "~1.6".
+func WithConstraint(c string) RepoOption {
+	return config.WithConstraint(c)
+}
+
+// WithPrefer chooses among several assets for the platform: "smallest",
+// "largest" or "name:<substring>".
+func WithPrefer(pref string) RepoOption {
+	return config.WithPrefer(pref)
+}
+
+// ProjectConfigFile is the name of the config file projects declare their
+// tools in.
+const ProjectConfigFile = config.ProjectFileName
+
+// GlobalConfigPath returns the path of the per-user config file.
+func GlobalConfigPath() string {
+	return config.GlobalPath()
+}
+
+// ErrNoConfig is returned by LoadDiscoveredConfig when no config file applies.
+var ErrNoConfig = config.ErrNoConfig
+
 // ParseRepoURL parses a GitHub repository URL into owner and repository name.
+//
+// Deprecated: use ParseRepoRef, which also parses repositories on other hosts.
 func ParseRepoURL(repoURL string) (owner, repo string, err error) {
 	return config.ParseRepoURL(repoURL)
 }
 
+// RepoRef identifies a repository by Host, Owner and Name, and formats its
+// String ("owner/name" on github.com), HTMLURL and APIBaseURL.
+type RepoRef = config.RepoRef
+
+// ParseRepoRef parses a repository URL, or "owner/name" for github.com and
+// "host/owner/name" for other hosts, into a RepoRef.
+func ParseRepoRef(s string) (RepoRef, error) {
+	return config.ParseRepoRef(s)
+}
+
 // AssetFilter exports the asset filter function type for library usage.
 type AssetFilter = release.AssetFilter
 
@@ -85,11 +637,122 @@ func CombinedFilter(filters ...AssetFilter) AssetFilter {
 }
 
 // BySize creates a filter that selects the largest or smallest asset.
+//
+// Deprecated: BySize ignores the platform; use PreferringFilter with
+// "smallest" or "largest" around a platform filter instead.
 func BySize(largest bool) AssetFilter {
 	return release.BySize(largest)
 }
 
+// PreferringFilter creates a filter applying filter to the assets ordered by
+// pref, "smallest", "largest" or "name:<substring>", so it selects the
+// preferred one among those it accepts, like the prefer setting of a repository.
+func PreferringFilter(pref string, filter AssetFilter) AssetFilter {
+	return release.Preferring(pref, filter)
+}
+
 // CustomFilter creates a filter from a user-defined function.
 func CustomFilter(fn func([]Asset) (*Asset, error)) AssetFilter {
 	return release.Custom(fn)
 }
+
+// Release exports the release structure for library usage.
+type Release = release.Release
+
+// Finder looks up the releases of repositories; the default one asks the
+// GitHub API. Package ghinstalltest provides fakes of it and the interfaces
+// below for tests.
+type Finder = release.Finder
+
+// ReleasePolicy decides which release a Finder considers the latest.
+type ReleasePolicy = release.Policy
+
+// ErrReleaseNotFound is returned by a Finder for a tag without a release.
+var ErrReleaseNotFound = release.ErrNotFound
+
+// ErrDangerousOutputDir is returned by installs into a filesystem root, the
+// home directory or a system directory, unless Config.AllowDangerousDir is set.
+var ErrDangerousOutputDir = installer.ErrDangerousOutputDir
+
+// CheckOutputDirs returns an error wrapping ErrDangerousOutputDir when a
+// repository of cfg would be installed into a filesystem root, the home
+// directory or a system directory, unless Config.AllowDangerousDir is set.
+func CheckOutputDirs(cfg *Config) error {
+	return installer.CheckOutputDirs(cfg)
+}
+
+// ErrAmbiguousAsset is returned with Config.StrictAssets when an asset_pattern
+// matches several assets of a release.
+var ErrAmbiguousAsset = installer.ErrAmbiguousAsset
+
+// Downloader downloads assets.
+type Downloader = downloader.Client
+
+// HTTPDownloader is the default Downloader. Installs apply the timeouts,
+// redirect policy and HTTP cache of the config through its methods, so
+// wrappers should embed a *HTTPDownloader to keep them.
+type HTTPDownloader = downloader.HTTPClient
+
+// NewDownloader returns the default Downloader, for wrapping with tracing,
+// authentication or another storage before passing it to NewInstaller or
+// WithDownloader.
+func NewDownloader() *HTTPDownloader {
+	return downloader.NewHTTPClient()
+}
+
+// Extractor unpacks a downloaded asset into a directory.
+type Extractor = extractor.Extractor
+
+// NewExtractor returns the default Extractor, supporting tar.gz, zip and the
+// other archive formats installs handle, for wrapping.
+func NewExtractor() Extractor {
+	return extractor.New()
+}
+
+// Installer installs the repositories of configs with a Finder, a Downloader
+// and an Extractor.
+type Installer = installer.Installer
+
+// NewInstaller returns an Installer using f, d and e; nil selects the default
+// implementation of each. Unlike the default extractor, a non-nil e is used
+// whatever the extractor setting of the config.
+func NewInstaller(f Finder, d Downloader, e Extractor, opts ...Option) *Installer {
+	return installer.New(f, d, e, opts...)
+}
+
+// WithFinder makes installs look up releases with f.
+func WithFinder(f Finder) Option {
+	return installer.WithFinder(f)
+}
+
+// WithDownloader makes installs download assets with d.
+func WithDownloader(d Downloader) Option {
+	return installer.WithDownloader(d)
+}
+
+// WithExtractor makes installs extract assets with e; the extractor setting
+// of the config is then ignored.
+func WithExtractor(e Extractor) Option {
+	return installer.WithExtractor(e)
+}
+
+// WithGitHubAPI makes installs look up releases with the GitHub API served at
+// baseURL instead of api.github.com, e.g. a GitHub Enterprise server.
+func WithGitHubAPI(baseURL string) Option {
+	return installer.WithFinder(release.NewGitHubClientWithURL(baseURL))
+}
+
+// Provider exports the source provider interface. Implementations resolve and
+// download releases for repositories whose config sets provider to their name.
+type Provider = provider.Provider
+
+// RegisterProvider makes a source provider available under name.
+func RegisterProvider(name string, p Provider) {
+	provider.Register(name, p)
+}
+
+// NewExecProvider returns a provider backed by an external executable speaking
+// the JSON-over-stdio protocol described in the README.
+func NewExecProvider(command ...string) Provider {
+	return provider.NewExec(command...)
+}
@@ -0,0 +1,138 @@
+// Package ghinstalltest helps programs embedding ghinstall test their installs
+// without the network: fakes of the Finder, Downloader and Extractor, a
+// fixture GitHub server, and builders for the archives they serve.
+//
+// The fakes are passed to the installs with ghinstall.WithFinder,
+// ghinstall.WithDownloader and ghinstall.WithExtractor; the server with
+// ghinstall.WithGitHubAPI.
+package ghinstalltest
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"path"
+	"sync"
+
+	"github.com/sixban6/ghinstall"
+	"github.com/sixban6/ghinstall/internal/release"
+)
+
+// Finder is a ghinstall.Finder serving fixed releases.
+type Finder struct {
+	// Releases are the releases of every "owner/repo", newest first.
+	Releases map[string][]ghinstall.Release
+	// Err, when set, fails every lookup.
+	Err error
+}
+
+// Latest returns the first release of owner/repo the policy allows: drafts
+// are skipped, as are prereleases on the stable channel and excluded tags.
+func (f *Finder) Latest(ctx context.Context, owner, repo string, policy ghinstall.ReleasePolicy) (*ghinstall.Release, error) {
+	if f.Err != nil {
+		return nil, f.Err
+	}
+	for _, rel := range f.Releases[owner+"/"+repo] {
+		if rel.Draft || rel.Prerelease && (policy.Channel == "" || policy.Channel == release.ChannelStable) || excluded(rel.TagName, policy.ExcludeTags) || !policy.InRange(rel.TagName) {
+			continue
+		}
+		return &rel, nil
+	}
+	return nil, fmt.Errorf("no %s releases found for %s/%s", policy, owner, repo)
+}
+
+// ByTag returns the release of owner/repo tagged tag.
+func (f *Finder) ByTag(ctx context.Context, owner, repo, tag string) (*ghinstall.Release, error) {
+	if f.Err != nil {
+		return nil, f.Err
+	}
+	for _, rel := range f.Releases[owner+"/"+repo] {
+		if rel.TagName == tag {
+			return &rel, nil
+		}
+	}
+	return nil, fmt.Errorf("%w: %s for %s/%s", ghinstall.ErrReleaseNotFound, tag, owner, repo)
+}
+
+func excluded(tag string, patterns []string) bool {
+	for _, pattern := range patterns {
+		if ok, _ := path.Match(pattern, tag); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// Downloader is a ghinstall.Downloader serving fixed content by URL and
+// recording the URLs it was asked for.
+type Downloader struct {
+	// Files maps URLs to their content; other URLs fail to download.
+	Files map[string][]byte
+	// Err, when set, fails every download.
+	Err error
+
+	mu   sync.Mutex
+	urls []string
+}
+
+func (d *Downloader) Download(ctx context.Context, url string) (io.ReadCloser, error) {
+	d.mu.Lock()
+	d.urls = append(d.urls, url)
+	d.mu.Unlock()
+
+	if d.Err != nil {
+		return nil, d.Err
+	}
+	content, ok := d.Files[url]
+	if !ok {
+		return nil, fmt.Errorf("failed to download %s: not found", url)
+	}
+	return io.NopCloser(bytes.NewReader(content)), nil
+}
+
+// URLs returns the URLs downloaded so far, in order.
+func (d *Downloader) URLs() []string {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return append([]string(nil), d.urls...)
+}
+
+// Extractor is a ghinstall.Extractor that records the content it is given
+// instead of unpacking it.
+type Extractor struct {
+	// Err, when set, fails every extraction.
+	Err error
+
+	mu        sync.Mutex
+	extracted map[string][]byte
+}
+
+func (e *Extractor) Extract(src io.Reader, dst string) error {
+	content, err := io.ReadAll(src)
+	if err != nil {
+		return err
+	}
+	if e.Err != nil {
+		return e.Err
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if e.extracted == nil {
+		e.extracted = make(map[string][]byte)
+	}
+	e.extracted[dst] = content
+	return nil
+}
+
+// Extracted returns the content extracted into each output directory.
+func (e *Extractor) Extracted() map[string][]byte {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	extracted := make(map[string][]byte, len(e.extracted))
+	for dst, content := range e.extracted {
+		extracted[dst] = content
+	}
+	return extracted
+}
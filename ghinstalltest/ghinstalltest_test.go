@@ -0,0 +1,116 @@
+package ghinstalltest_test
+
+import (
+	"context"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"github.com/sixban6/ghinstall"
+	"github.com/sixban6/ghinstall/ghinstalltest"
+)
+
+func TestServer(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	t.Setenv("USERPROFILE", os.Getenv("HOME"))
+
+	server := ghinstalltest.NewServer(t)
+	server.Publish("owner", "app", ghinstall.Release{TagName: "v1.0.0"}, map[string][]byte{
+		"app_linux_amd64.tar.gz": ghinstalltest.TarGz(map[string]string{"app": "v1"}),
+	})
+	server.Publish("owner", "app", ghinstall.Release{TagName: "v1.1.0"}, map[string][]byte{
+		"app_linux_amd64.tar.gz": ghinstalltest.TarGz(map[string]string{"app": "v1.1"}),
+	})
+
+	dir := t.TempDir()
+	cfg := &ghinstall.Config{Github: []ghinstall.Repo{{URL: "https://github.com/owner/app", OutputDir: dir}}}
+	err := ghinstall.InstallWithOptions(context.Background(), cfg, ghinstall.ByNamePattern("linux_amd64"), ghinstall.WithGitHubAPI(server.URL))
+	if err != nil {
+		t.Fatalf("InstallWithOptions() error = %v", err)
+	}
+
+	got, err := os.ReadFile(filepath.Join(dir, "app"))
+	if err != nil || string(got) != "v1.1" {
+		t.Errorf("installed app = %q, %v, want the latest release", got, err)
+	}
+	if !slices.Contains(server.Requests(), "GET /owner/app/releases/download/v1.1.0/app_linux_amd64.tar.gz") {
+		t.Errorf("Requests() = %v, want the asset download", server.Requests())
+	}
+}
+
+func TestFakes(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	t.Setenv("USERPROFILE", os.Getenv("HOME"))
+
+	assetURL := "https://github.com/owner/app/releases/download/v2.0.0-rc1/app.zip"
+	finder := &ghinstalltest.Finder{Releases: map[string][]ghinstall.Release{
+		"owner/app": {
+			{TagName: "v2.0.0-rc1", Prerelease: true, Assets: []ghinstall.Asset{{Name: "app.zip", URL: assetURL}}},
+			{TagName: "v1.0.0", Assets: []ghinstall.Asset{{Name: "app.zip", URL: assetURL}}},
+		},
+	}}
+	downloader := &ghinstalltest.Downloader{Files: map[string][]byte{assetURL: []byte("archive")}}
+	extractor := &ghinstalltest.Extractor{}
+
+	dir := t.TempDir()
+	cfg := &ghinstall.Config{Github: []ghinstall.Repo{{URL: "https://github.com/owner/app", OutputDir: dir, Version: "v2.0.0-rc1"}}}
+	err := ghinstall.InstallWithOptions(context.Background(), cfg, ghinstall.ByNamePattern(".zip"),
+		ghinstall.WithFinder(finder), ghinstall.WithDownloader(downloader), ghinstall.WithExtractor(extractor))
+	if err != nil {
+		t.Fatalf("InstallWithOptions() error = %v", err)
+	}
+	if got := string(extractor.Extracted()[dir]); got != "archive" {
+		t.Errorf("Extracted() = %q, want the downloaded asset", got)
+	}
+	if got := downloader.URLs(); len(got) != 1 || got[0] != assetURL {
+		t.Errorf("URLs() = %v, want [%s]", got, assetURL)
+	}
+
+	rel, err := finder.Latest(context.Background(), "owner", "app", ghinstall.ReleasePolicy{})
+	if err != nil || rel.TagName != "v1.0.0" {
+		t.Errorf("Latest() = %v, %v, want the stable release", rel, err)
+	}
+	if _, err := finder.ByTag(context.Background(), "owner", "app", "v3.0.0"); !errors.Is(err, ghinstall.ErrReleaseNotFound) {
+		t.Errorf("ByTag() error = %v, want %v", err, ghinstall.ErrReleaseNotFound)
+	}
+}
+
+// countingDownloader wraps the default downloader the way applications add
+// tracing to it.
+type countingDownloader struct {
+	*ghinstall.HTTPDownloader
+	downloads int
+}
+
+func (d *countingDownloader) Download(ctx context.Context, url string) (io.ReadCloser, error) {
+	d.downloads++
+	return d.HTTPDownloader.Download(ctx, url)
+}
+
+func TestNewInstaller_WrappedDefaults(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	t.Setenv("USERPROFILE", os.Getenv("HOME"))
+
+	server := ghinstalltest.NewServer(t)
+	server.Publish("owner", "app", ghinstall.Release{TagName: "v1.0.0"}, map[string][]byte{
+		"app_linux_amd64.tar.gz": ghinstalltest.TarGz(map[string]string{"app": "v1"}),
+	})
+
+	downloader := &countingDownloader{HTTPDownloader: ghinstall.NewDownloader()}
+	inst := ghinstall.NewInstaller(nil, downloader, ghinstall.NewExtractor(), ghinstall.WithGitHubAPI(server.URL))
+
+	dir := t.TempDir()
+	cfg := &ghinstall.Config{Github: []ghinstall.Repo{{URL: "https://github.com/owner/app", OutputDir: dir}}}
+	if err := inst.Install(context.Background(), cfg, ghinstall.ByNamePattern("linux_amd64")); err != nil {
+		t.Fatalf("Install() error = %v", err)
+	}
+	if downloader.downloads != 1 {
+		t.Errorf("downloads = %d, want 1", downloader.downloads)
+	}
+	if got, err := os.ReadFile(filepath.Join(dir, "app")); err != nil || string(got) != "v1" {
+		t.Errorf("installed app = %q, %v", got, err)
+	}
+}
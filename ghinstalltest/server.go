@@ -0,0 +1,134 @@
+package ghinstalltest
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"maps"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/sixban6/ghinstall"
+)
+
+// Server is a fixture GitHub server: it answers the release endpoints of the
+// REST API and serves the assets of the releases published to it. Pass its
+// URL to ghinstall.WithGitHubAPI.
+type Server struct {
+	*httptest.Server
+
+	mu       sync.Mutex
+	releases map[string][]ghinstall.Release
+	files    map[string][]byte
+	requests []string
+}
+
+// NewServer starts a Server, which is closed when tb finishes.
+func NewServer(tb testing.TB) *Server {
+	s := &Server{releases: make(map[string][]ghinstall.Release), files: make(map[string][]byte)}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /repos/{owner}/{repo}/releases", s.serveReleases)
+	mux.HandleFunc("GET /repos/{owner}/{repo}/releases/tags/{tag}", s.serveTag)
+	mux.HandleFunc("GET /{owner}/{repo}/releases/download/{tag}/{name}", s.serveAsset)
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.mu.Lock()
+		s.requests = append(s.requests, r.Method+" "+r.URL.Path)
+		s.mu.Unlock()
+		mux.ServeHTTP(w, r)
+	}))
+	tb.Cleanup(s.Close)
+	return s
+}
+
+// Publish adds rel as the newest release of owner/repo, with an asset for
+// each of files, and returns it. The assets are served by s and carry their
+// size and digest like the assets of GitHub.
+func (s *Server) Publish(owner, repo string, rel ghinstall.Release, files map[string][]byte) ghinstall.Release {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	rel.Assets = slices.Clone(rel.Assets)
+	for _, name := range slices.Sorted(maps.Keys(files)) {
+		path := fmt.Sprintf("/%s/%s/releases/download/%s/%s", owner, repo, rel.TagName, name)
+		sum := sha256.Sum256(files[name])
+		s.files[path] = files[name]
+		rel.Assets = append(rel.Assets, ghinstall.Asset{
+			Name:        name,
+			URL:         s.URL + path,
+			ContentType: "application/octet-stream",
+			Size:        int64(len(files[name])),
+			Digest:      "sha256:" + hex.EncodeToString(sum[:]),
+		})
+	}
+	if rel.PublishedAt.IsZero() {
+		rel.PublishedAt = time.Now().UTC()
+	}
+
+	key := owner + "/" + repo
+	s.releases[key] = append([]ghinstall.Release{rel}, s.releases[key]...)
+	return rel
+}
+
+// Requests returns the method and path of every request served so far.
+func (s *Server) Requests() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return slices.Clone(s.requests)
+}
+
+func (s *Server) serveReleases(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	releases, ok := s.releases[r.PathValue("owner")+"/"+r.PathValue("repo")]
+	s.mu.Unlock()
+	if !ok {
+		notFound(w)
+		return
+	}
+	writeJSON(w, releases)
+}
+
+func (s *Server) serveTag(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	releases := s.releases[r.PathValue("owner")+"/"+r.PathValue("repo")]
+	s.mu.Unlock()
+	for _, rel := range releases {
+		if rel.TagName == r.PathValue("tag") {
+			writeJSON(w, rel)
+			return
+		}
+	}
+	notFound(w)
+}
+
+func (s *Server) serveAsset(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	content, ok := s.files[r.URL.Path]
+	s.mu.Unlock()
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+	sum := sha256.Sum256(content)
+	w.Header().Set("ETag", `"`+hex.EncodeToString(sum[:])+`"`)
+	w.Header().Set("Content-Type", "application/octet-stream")
+	http.ServeContent(w, r, "", time.Time{}, bytes.NewReader(content))
+}
+
+// notFound answers like the GitHub API for unknown repositories and tags.
+func notFound(w http.ResponseWriter) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusNotFound)
+	fmt.Fprint(w, `{"message":"Not Found"}`)
+}
+
+func writeJSON(w http.ResponseWriter, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(v)
+}
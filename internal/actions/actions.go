@@ -0,0 +1,101 @@
+// Package actions integrates with GitHub Actions runners: workflow commands,
+// the PATH file and the runner tool cache.
+package actions
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+)
+
+// Enabled reports whether ghinstall runs in a GitHub Actions job.
+func Enabled() bool {
+	return os.Getenv("GITHUB_ACTIONS") == "true"
+}
+
+// Output receives workflow commands; tests replace it.
+var Output io.Writer = os.Stdout
+
+// Notice emits a notice annotation shown in the workflow run summary.
+func Notice(msg string) {
+	fmt.Fprintf(Output, "::notice::%s\n", escape(msg))
+}
+
+// Error emits an error annotation. With a file, GitHub shows it on that file
+// and, when line is not 0, on that line.
+func Error(file string, line int, msg string) {
+	var props []string
+	if file != "" {
+		props = append(props, "file="+escapeProperty(file))
+		if line > 0 {
+			props = append(props, fmt.Sprintf("line=%d", line))
+		}
+	}
+	cmd := "::error"
+	if len(props) > 0 {
+		cmd += " " + strings.Join(props, ",")
+	}
+	fmt.Fprintf(Output, "%s::%s\n", cmd, escape(msg))
+}
+
+// escape encodes the characters that would end a workflow command message.
+func escape(s string) string {
+	return strings.NewReplacer("%", "%25", "\r", "%0D", "\n", "%0A").Replace(s)
+}
+
+// escapeProperty encodes the characters that would end a workflow command property.
+func escapeProperty(s string) string {
+	return strings.NewReplacer("%", "%25", "\r", "%0D", "\n", "%0A", ":", "%3A", ",", "%2C").Replace(s)
+}
+
+// AddPath prepends dir to PATH for the following steps of the job by
+// appending it to the file named by GITHUB_PATH.
+func AddPath(dir string) error {
+	path := os.Getenv("GITHUB_PATH")
+	if path == "" {
+		return fmt.Errorf("GITHUB_PATH is not set")
+	}
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to open GITHUB_PATH: %w", err)
+	}
+	defer f.Close()
+	if _, err := fmt.Fprintln(f, dir); err != nil {
+		return fmt.Errorf("failed to write GITHUB_PATH: %w", err)
+	}
+	return nil
+}
+
+// ToolCacheRoot returns the runner tool cache directory, "" outside runners.
+func ToolCacheRoot() string {
+	return os.Getenv("RUNNER_TOOL_CACHE")
+}
+
+// ToolCacheDir returns the directory of version of tool in the tool cache
+// rooted at root, laid out like actions/tool-cache: <tool>/<version>/<arch>
+// with the version stripped of its "v" prefix.
+func ToolCacheDir(root, tool, version string) string {
+	version = strings.TrimPrefix(version, "v")
+	return filepath.Join(root, tool, version, arch())
+}
+
+// MarkComplete writes the marker telling actions/tool-cache that the tool in
+// dir is completely installed.
+func MarkComplete(dir string) error {
+	return os.WriteFile(dir+".complete", nil, 0644)
+}
+
+// arch returns the architecture name used by the tool cache.
+func arch() string {
+	switch runtime.GOARCH {
+	case "amd64":
+		return "x64"
+	case "386":
+		return "x86"
+	default:
+		return runtime.GOARCH
+	}
+}
@@ -0,0 +1,60 @@
+package actions
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+)
+
+func TestNotice(t *testing.T) {
+	var buf bytes.Buffer
+	Output = &buf
+	defer func() { Output = os.Stdout }()
+
+	Notice("Installed gh\n100% done")
+	if got, want := buf.String(), "::notice::Installed gh%0A100%25 done\n"; got != want {
+		t.Errorf("Notice() wrote %q, want %q", got, want)
+	}
+}
+
+func TestError(t *testing.T) {
+	var buf bytes.Buffer
+	Output = &buf
+	defer func() { Output = os.Stdout }()
+
+	Error("conf/tools,ci.yaml", 12, "invalid config")
+	Error("", 0, "failed")
+	want := "::error file=conf/tools%2Cci.yaml,line=12::invalid config\n::error::failed\n"
+	if got := buf.String(); got != want {
+		t.Errorf("Error() wrote %q, want %q", got, want)
+	}
+}
+
+func TestAddPath(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "path")
+	t.Setenv("GITHUB_PATH", path)
+
+	AddPath("/opt/a/bin")
+	AddPath("/opt/b")
+	data, err := os.ReadFile(path)
+	if err != nil || string(data) != "/opt/a/bin\n/opt/b\n" {
+		t.Errorf("GITHUB_PATH = %q, %v", data, err)
+	}
+
+	t.Setenv("GITHUB_PATH", "")
+	if err := AddPath("/opt/c"); err == nil {
+		t.Error("AddPath() without GITHUB_PATH should fail")
+	}
+}
+
+func TestToolCacheDir(t *testing.T) {
+	want := filepath.Join("/cache", "gh", "2.40.0", map[string]string{"amd64": "x64", "386": "x86"}[runtime.GOARCH])
+	if runtime.GOARCH != "amd64" && runtime.GOARCH != "386" {
+		want = filepath.Join("/cache", "gh", "2.40.0", runtime.GOARCH)
+	}
+	if got := ToolCacheDir("/cache", "gh", "v2.40.0"); got != want {
+		t.Errorf("ToolCacheDir() = %s, want %s", got, want)
+	}
+}
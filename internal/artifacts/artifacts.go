@@ -0,0 +1,124 @@
+// Package artifacts describes the binaries of a ghinstall release, for the
+// checksum file and manifest published next to them.
+package artifacts
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// Prefix starts the names of release binaries, which continue with
+// "<os>-<arch>" and end in .exe on Windows.
+const Prefix = "ghinstall-"
+
+// Artifact is one release file.
+type Artifact struct {
+	Name   string `json:"name"`
+	Size   int64  `json:"size"`
+	SHA256 string `json:"sha256"`
+	// OS and Arch are parsed from binary names; empty for other files.
+	OS   string `json:"os,omitempty"`
+	Arch string `json:"arch,omitempty"`
+}
+
+// Manifest describes a release and its artifacts.
+type Manifest struct {
+	Version   string     `json:"version"`
+	Commit    string     `json:"commit,omitempty"`
+	Artifacts []Artifact `json:"artifacts"`
+}
+
+// Collect hashes the files at paths, descending into directories, ordered by
+// name. Files named in skip, such as the outputs of a previous run, are left
+// out; two files with the same name are an error since they would be
+// published under one name.
+func Collect(paths []string, skip ...string) ([]Artifact, error) {
+	var files []string
+	for _, p := range paths {
+		err := filepath.WalkDir(p, func(path string, d os.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			if d.Type().IsRegular() && !slices.Contains(skip, d.Name()) {
+				files = append(files, path)
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to list artifacts: %w", err)
+		}
+	}
+
+	seen := make(map[string]string, len(files))
+	list := make([]Artifact, 0, len(files))
+	for _, path := range files {
+		name := filepath.Base(path)
+		if prev, ok := seen[name]; ok {
+			return nil, fmt.Errorf("artifacts %s and %s have the same name", prev, path)
+		}
+		seen[name] = path
+
+		a, err := hashFile(path)
+		if err != nil {
+			return nil, err
+		}
+		a.OS, a.Arch = Platform(name)
+		list = append(list, a)
+	}
+	slices.SortFunc(list, func(a, b Artifact) int { return strings.Compare(a.Name, b.Name) })
+	return list, nil
+}
+
+func hashFile(path string) (Artifact, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return Artifact{}, fmt.Errorf("failed to open artifact: %w", err)
+	}
+	defer f.Close()
+
+	h := sha256.New()
+	n, err := io.Copy(h, f)
+	if err != nil {
+		return Artifact{}, fmt.Errorf("failed to hash %s: %w", path, err)
+	}
+	return Artifact{Name: filepath.Base(path), Size: n, SHA256: hex.EncodeToString(h.Sum(nil))}, nil
+}
+
+// Platform returns the GOOS and GOARCH of a release binary named
+// "ghinstall-<os>-<arch>[.exe]", empty strings for other names.
+func Platform(name string) (goos, goarch string) {
+	rest, ok := strings.CutPrefix(name, Prefix)
+	if !ok {
+		return "", ""
+	}
+	rest = strings.TrimSuffix(rest, ".exe")
+	goos, goarch, ok = strings.Cut(rest, "-")
+	if !ok || goos == "" || goarch == "" || strings.ContainsAny(goarch, "-.") {
+		return "", ""
+	}
+	return goos, goarch
+}
+
+// WriteChecksums writes list in the format "sha256sum -c" verifies.
+func WriteChecksums(w io.Writer, list []Artifact) error {
+	for _, a := range list {
+		if _, err := fmt.Fprintf(w, "%s  %s\n", a.SHA256, a.Name); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// WriteManifest writes m as indented JSON.
+func WriteManifest(w io.Writer, m Manifest) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(m)
+}
@@ -0,0 +1,76 @@
+package artifacts
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestPlatform(t *testing.T) {
+	tests := []struct{ name, goos, goarch string }{
+		{"ghinstall-linux-amd64", "linux", "amd64"},
+		{"ghinstall-windows-arm64.exe", "windows", "arm64"},
+		{"ghinstall-linux", "", ""},
+		{"ghinstall-linux-amd64.tar.gz", "", ""},
+		{"checksums.txt", "", ""},
+	}
+	for _, tt := range tests {
+		if goos, goarch := Platform(tt.name); goos != tt.goos || goarch != tt.goarch {
+			t.Errorf("Platform(%q) = %q, %q, want %q, %q", tt.name, goos, goarch, tt.goos, tt.goarch)
+		}
+	}
+}
+
+func TestCollect(t *testing.T) {
+	dir := t.TempDir()
+	write := func(rel, content string) {
+		t.Helper()
+		path := filepath.Join(dir, rel)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write("ghinstall-linux-amd64/ghinstall-linux-amd64", "linux")
+	write("ghinstall-windows-amd64/ghinstall-windows-amd64.exe", "windows")
+	write("checksums.txt", "stale")
+
+	list, err := Collect([]string{dir}, "checksums.txt")
+	if err != nil {
+		t.Fatalf("Collect() error = %v", err)
+	}
+	if len(list) != 2 || list[0].Name != "ghinstall-linux-amd64" || list[1].Name != "ghinstall-windows-amd64.exe" {
+		t.Fatalf("Collect() = %+v", list)
+	}
+	if list[1].OS != "windows" || list[1].Arch != "amd64" || list[1].Size != int64(len("windows")) {
+		t.Errorf("Collect()[1] = %+v", list[1])
+	}
+
+	var sums bytes.Buffer
+	if err := WriteChecksums(&sums, list); err != nil {
+		t.Fatal(err)
+	}
+	want := "caf90169eefa5f807d577486b9f795ab86ae2983c5c20806cff959117e90af18  ghinstall-linux-amd64\n"
+	if !strings.HasPrefix(sums.String(), want) {
+		t.Errorf("WriteChecksums() = %q, want it to start with %q", sums.String(), want)
+	}
+
+	var out bytes.Buffer
+	if err := WriteManifest(&out, Manifest{Version: "v1.2.3", Artifacts: list}); err != nil {
+		t.Fatal(err)
+	}
+	var m Manifest
+	if err := json.Unmarshal(out.Bytes(), &m); err != nil || m.Version != "v1.2.3" || len(m.Artifacts) != 2 {
+		t.Errorf("WriteManifest() = %s, %v", out.String(), err)
+	}
+
+	write("other/ghinstall-linux-amd64", "duplicate")
+	if _, err := Collect([]string{dir}); err == nil {
+		t.Error("Collect() with two artifacts of the same name should fail")
+	}
+}
@@ -0,0 +1,41 @@
+// Package attest signs install manifests with ssh-keygen or minisign, so
+// tooling other than ghinstall, such as configuration management or intrusion
+// detection, can check the installed files with the same standard tools.
+package attest
+
+import "fmt"
+
+// Format is the signature format of an attestation.
+type Format string
+
+const (
+	// SSH signs with an OpenSSH key using "ssh-keygen -Y sign".
+	SSH Format = "ssh"
+	// Minisign signs with a minisign secret key.
+	Minisign Format = "minisign"
+)
+
+// Namespace is the ssh signature namespace of manifest signatures; verifiers
+// must pass it to "ssh-keygen -Y verify -n".
+const Namespace = "ghinstall-manifest"
+
+// ParseFormat returns the format named s; "" is SSH.
+func ParseFormat(s string) (Format, error) {
+	switch Format(s) {
+	case "", SSH:
+		return SSH, nil
+	case Minisign:
+		return Minisign, nil
+	default:
+		return "", fmt.Errorf("unknown signature format %q, must be ssh or minisign", s)
+	}
+}
+
+// SignaturePath returns where the signature of the file at path is kept,
+// named like the tool itself names it.
+func SignaturePath(path string, f Format) string {
+	if f == Minisign {
+		return path + ".minisig"
+	}
+	return path + ".sig"
+}
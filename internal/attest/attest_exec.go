@@ -0,0 +1,141 @@
+//go:build !purego && !wasip1
+
+package attest
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/sixban6/ghinstall/internal/tmpdir"
+)
+
+// Sign signs the file at path with the private key at key and writes the
+// signature next to it. It returns the path of the signature.
+func Sign(ctx context.Context, f Format, key, path string) (string, error) {
+	sig := SignaturePath(path, f)
+	switch f {
+	case SSH:
+		// Signing stdin writes the signature to stdout, where ssh-keygen would
+		// otherwise ask before replacing an existing signature file.
+		in, err := os.Open(path)
+		if err != nil {
+			return "", err
+		}
+		defer in.Close()
+
+		var out, stderr bytes.Buffer
+		cmd := exec.CommandContext(ctx, "ssh-keygen", "-Y", "sign", "-f", key, "-n", Namespace)
+		cmd.Stdin, cmd.Stdout, cmd.Stderr = in, &out, &stderr
+		if err := cmd.Run(); err != nil {
+			return "", fmt.Errorf("ssh-keygen failed to sign %s: %w%s", path, err, stderrSuffix(&stderr))
+		}
+		if err := writeFile(sig, out.Bytes()); err != nil {
+			return "", fmt.Errorf("failed to write signature: %w", err)
+		}
+	case Minisign:
+		var stderr bytes.Buffer
+		cmd := exec.CommandContext(ctx, "minisign", "-S", "-s", key, "-m", path, "-x", sig)
+		cmd.Stderr = &stderr
+		if err := cmd.Run(); err != nil {
+			return "", fmt.Errorf("minisign failed to sign %s: %w%s", path, err, stderrSuffix(&stderr))
+		}
+	default:
+		return "", fmt.Errorf("unknown signature format %q", f)
+	}
+	return sig, nil
+}
+
+// Verify checks the signature next to the file at path against the public
+// key at pub: an OpenSSH public key for SSH, a minisign public key otherwise.
+func Verify(ctx context.Context, f Format, pub, path string) error {
+	sig := SignaturePath(path, f)
+	if _, err := os.Stat(sig); err != nil {
+		return fmt.Errorf("no signature: %w", err)
+	}
+
+	var stderr bytes.Buffer
+	var cmd *exec.Cmd
+	switch f {
+	case SSH:
+		signers, err := allowedSigners(pub)
+		if err != nil {
+			return err
+		}
+		defer os.Remove(signers)
+
+		in, err := os.Open(path)
+		if err != nil {
+			return err
+		}
+		defer in.Close()
+		cmd = exec.CommandContext(ctx, "ssh-keygen", "-Y", "verify", "-f", signers, "-I", "ghinstall", "-n", Namespace, "-s", sig)
+		cmd.Stdin = in
+	case Minisign:
+		cmd = exec.CommandContext(ctx, "minisign", "-V", "-q", "-p", pub, "-m", path, "-x", sig)
+	default:
+		return fmt.Errorf("unknown signature format %q", f)
+	}
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("bad signature %s: %w%s", sig, err, stderrSuffix(&stderr))
+	}
+	return nil
+}
+
+// allowedSigners writes a temporary allowed signers file trusting the public
+// key at pub for manifest signatures, as ssh-keygen verifies only against one.
+func allowedSigners(pub string) (string, error) {
+	data, err := os.ReadFile(pub)
+	if err != nil {
+		return "", fmt.Errorf("failed to read public key: %w", err)
+	}
+	key := strings.TrimSpace(string(data))
+	if key == "" || strings.Contains(key, "\n") {
+		return "", fmt.Errorf("%s must contain exactly one OpenSSH public key", pub)
+	}
+
+	f, err := os.CreateTemp(tmpdir.Dir(), "ghinstall-signers-*")
+	if err != nil {
+		return "", fmt.Errorf("failed to create allowed signers file: %w", err)
+	}
+	defer f.Close()
+	if _, err := fmt.Fprintf(f, "ghinstall namespaces=%q %s\n", Namespace, key); err != nil {
+		os.Remove(f.Name())
+		return "", fmt.Errorf("failed to write allowed signers file: %w", err)
+	}
+	return f.Name(), nil
+}
+
+// writeFile replaces path with data atomically, so a concurrent verifier never
+// reads a partial signature.
+func writeFile(path string, data []byte) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".sig-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	defer tmp.Close()
+
+	if _, err := tmp.Write(data); err != nil {
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Chmod(tmp.Name(), 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+func stderrSuffix(stderr *bytes.Buffer) string {
+	if msg := strings.TrimSpace(stderr.String()); msg != "" {
+		return ": " + msg
+	}
+	return ""
+}
@@ -0,0 +1,18 @@
+//go:build purego || wasip1
+
+package attest
+
+import (
+	"context"
+	"errors"
+)
+
+var errDisabled = errors.New("attestations cannot be signed or verified: this build of ghinstall (purego or wasip1) does not start processes")
+
+func Sign(ctx context.Context, f Format, key, path string) (string, error) {
+	return "", errDisabled
+}
+
+func Verify(ctx context.Context, f Format, pub, path string) error {
+	return errDisabled
+}
@@ -0,0 +1,73 @@
+//go:build !purego && !wasip1
+
+package attest
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+)
+
+func TestParseFormat(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    Format
+		wantErr bool
+	}{
+		{"", SSH, false},
+		{"ssh", SSH, false},
+		{"minisign", Minisign, false},
+		{"gpg", "", true},
+	}
+	for _, tt := range tests {
+		got, err := ParseFormat(tt.in)
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("ParseFormat(%q) = %q, %v; want %q, error %v", tt.in, got, err, tt.want, tt.wantErr)
+		}
+	}
+}
+
+func TestSignVerify_SSH(t *testing.T) {
+	if _, err := exec.LookPath("ssh-keygen"); err != nil {
+		t.Skip("ssh-keygen not available")
+	}
+	dir := t.TempDir()
+	key := filepath.Join(dir, "key")
+	if out, err := exec.Command("ssh-keygen", "-q", "-t", "ed25519", "-N", "", "-f", key).CombinedOutput(); err != nil {
+		t.Fatalf("ssh-keygen: %v: %s", err, out)
+	}
+	manifest := filepath.Join(dir, "manifest.json")
+	if err := os.WriteFile(manifest, []byte(`{"files":[]}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+
+	sig, err := Sign(ctx, SSH, key, manifest)
+	if err != nil {
+		t.Fatalf("Sign() error = %v", err)
+	}
+	if sig != manifest+".sig" {
+		t.Errorf("signature written to %s", sig)
+	}
+	// Signing again replaces the signature instead of prompting.
+	if _, err := Sign(ctx, SSH, key, manifest); err != nil {
+		t.Fatalf("second Sign() error = %v", err)
+	}
+	if err := Verify(ctx, SSH, key+".pub", manifest); err != nil {
+		t.Fatalf("Verify() error = %v", err)
+	}
+
+	if err := os.WriteFile(manifest, []byte(`{"files":[{"path":"evil"}]}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := Verify(ctx, SSH, key+".pub", manifest); err == nil {
+		t.Error("Verify() accepted a modified manifest")
+	}
+
+	os.Remove(sig)
+	if err := Verify(ctx, SSH, key+".pub", manifest); err == nil {
+		t.Error("Verify() accepted a manifest without signature")
+	}
+}
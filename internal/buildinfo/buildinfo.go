@@ -0,0 +1,109 @@
+// Package buildinfo describes how the running binary was built, for version
+// reports and bug reports.
+package buildinfo
+
+import (
+	"fmt"
+	"runtime"
+	"runtime/debug"
+	"strings"
+)
+
+// Info describes the running binary.
+type Info struct {
+	Version   string
+	Commit    string
+	Date      string
+	Modified  bool
+	GoVersion string
+	Platform  string
+	// Tags are the build tags, such as purego.
+	Tags []string
+	CGO  bool
+	// Extractors are the archive extraction backends compiled in.
+	Extractors []string
+}
+
+// commit and date are stamped by release builds, which are made with
+// -buildvcs=false so that they are reproducible from a source archive:
+//
+//	-ldflags "-X github.com/sixban6/ghinstall/internal/buildinfo.commit=<sha> -X github.com/sixban6/ghinstall/internal/buildinfo.date=<RFC 3339>"
+//
+// They take precedence over the VCS information of the Go toolchain.
+var commit, date string
+
+// Read returns the build information of the running binary. version is the
+// version stamped with -ldflags; "dev" falls back to the module version.
+func Read(version string) Info {
+	info := Info{
+		Version:    version,
+		Commit:     commit,
+		Date:       date,
+		GoVersion:  runtime.Version(),
+		Platform:   runtime.GOOS + "/" + runtime.GOARCH,
+		Extractors: extractors,
+	}
+
+	bi, ok := debug.ReadBuildInfo()
+	if !ok {
+		return info
+	}
+	if (info.Version == "" || info.Version == "dev") && bi.Main.Version != "" && bi.Main.Version != "(devel)" {
+		info.Version = bi.Main.Version
+	}
+	for _, s := range bi.Settings {
+		switch s.Key {
+		case "vcs.revision":
+			if commit == "" {
+				info.Commit = s.Value
+			}
+		case "vcs.time":
+			if date == "" {
+				info.Date = s.Value
+			}
+		case "vcs.modified":
+			info.Modified = commit == "" && s.Value == "true"
+		case "CGO_ENABLED":
+			info.CGO = s.Value == "1"
+		case "-tags":
+			info.Tags = strings.Split(s.Value, ",")
+		}
+	}
+	return info
+}
+
+// String formats the information as a multi-line report.
+func (i Info) String() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "ghinstall %s\n", i.Version)
+
+	commit := i.Commit
+	if commit == "" {
+		commit = "unknown"
+	} else if i.Modified {
+		commit += " (modified)"
+	}
+	fmt.Fprintf(&b, "  commit:     %s\n", commit)
+	if i.Date != "" {
+		fmt.Fprintf(&b, "  built:      %s\n", i.Date)
+	}
+	fmt.Fprintf(&b, "  go:         %s\n", i.GoVersion)
+	fmt.Fprintf(&b, "  platform:   %s\n", i.Platform)
+
+	features := []string{"cgo=" + onOff(i.CGO)}
+	for _, tag := range i.Tags {
+		if tag != "" {
+			features = append(features, tag)
+		}
+	}
+	fmt.Fprintf(&b, "  features:   %s\n", strings.Join(features, ", "))
+	fmt.Fprintf(&b, "  extractors: %s\n", strings.Join(i.Extractors, ", "))
+	return b.String()
+}
+
+func onOff(b bool) string {
+	if b {
+		return "on"
+	}
+	return "off"
+}
@@ -0,0 +1,51 @@
+package buildinfo
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestRead(t *testing.T) {
+	info := Read("v1.2.3")
+	if info.Version != "v1.2.3" {
+		t.Errorf("Version = %q, want the stamped version", info.Version)
+	}
+	if info.GoVersion == "" || info.Platform == "" || len(info.Extractors) == 0 {
+		t.Errorf("Read() = %+v", info)
+	}
+}
+
+func TestRead_Stamped(t *testing.T) {
+	defer func(c, d string) { commit, date = c, d }(commit, date)
+	commit, date = "0123456789abcdef", "2026-01-02T03:04:05Z"
+
+	info := Read("v1.2.3")
+	if info.Commit != commit || info.Date != date || info.Modified {
+		t.Errorf("Read() = %+v, want the stamped commit and date", info)
+	}
+}
+
+func TestInfo_String(t *testing.T) {
+	info := Info{
+		Version:    "v1.2.3",
+		Commit:     "abc123",
+		Modified:   true,
+		Date:       "2026-01-02T03:04:05Z",
+		GoVersion:  "go1.25.0",
+		Platform:   "linux/amd64",
+		Tags:       []string{"purego"},
+		Extractors: []string{"go"},
+	}
+	got := info.String()
+	for _, want := range []string{
+		"ghinstall v1.2.3\n",
+		"commit:     abc123 (modified)\n",
+		"built:      2026-01-02T03:04:05Z\n",
+		"features:   cgo=off, purego\n",
+		"extractors: go\n",
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("String() = %q, missing %q", got, want)
+		}
+	}
+}
@@ -0,0 +1,7 @@
+//go:build !purego && !wasip1
+
+package buildinfo
+
+// extractors lists the extraction backends: the Go implementation and the
+// system tools (tar, unzip, PowerShell) that regular builds may start.
+var extractors = []string{"go", "system"}
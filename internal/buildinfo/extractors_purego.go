@@ -0,0 +1,7 @@
+//go:build purego || wasip1
+
+package buildinfo
+
+// extractors lists the extraction backends: purego and wasip1 builds never
+// start processes, so only the Go implementation is available.
+var extractors = []string{"go"}
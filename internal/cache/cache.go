@@ -0,0 +1,282 @@
+// Package cache stores downloaded release assets by their SHA-256 digest so
+// repeated installs, and other users on the same host, can reuse them.
+//
+// Layout under the cache directory:
+//
+//	blobs/sha256/<digest>   asset content
+//	index/<sha256(key)>     digest of the content last stored for key
+//	locks/<sha256(key)>     lock file held while key is being fetched
+package cache
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"time"
+
+	"github.com/sixban6/ghinstall/internal/filelock"
+	"github.com/sixban6/ghinstall/internal/tmpdir"
+)
+
+const (
+	// SystemKeyword selects SystemDir when used as a configured cache dir.
+	SystemKeyword = "system"
+	// UserKeyword selects UserDir when used as a configured cache dir.
+	UserKeyword = "user"
+
+	defaultLockTimeout = 10 * time.Minute
+)
+
+// Cache is a content-addressed asset store.
+type Cache struct {
+	dir         string
+	shared      bool
+	lockTimeout time.Duration
+}
+
+// SystemDir returns the host-wide cache location shared by all users.
+func SystemDir() string {
+	if runtime.GOOS == "windows" {
+		base := os.Getenv("ProgramData")
+		if base == "" {
+			base = `C:\ProgramData`
+		}
+		return filepath.Join(base, "ghinstall", "cache")
+	}
+	return "/var/cache/ghinstall"
+}
+
+// UserDir returns the per-user cache location.
+func UserDir() string {
+	base, err := os.UserCacheDir()
+	if err != nil {
+		base = tmpdir.Dir()
+	}
+	return filepath.Join(base, "ghinstall")
+}
+
+// ResolveDir expands the "system" and "user" keywords of a configured cache dir.
+func ResolveDir(dir string) string {
+	switch strings.ToLower(dir) {
+	case SystemKeyword:
+		return SystemDir()
+	case UserKeyword:
+		return UserDir()
+	default:
+		return dir
+	}
+}
+
+// Open prepares a cache rooted at dir. When shared is true, directories are
+// created group-writable with the setgid bit and files group-readable so that
+// every member of the directory's group can use the cache.
+func Open(dir string, shared bool) (*Cache, error) {
+	c := &Cache{
+		dir:         dir,
+		shared:      shared,
+		lockTimeout: defaultLockTimeout,
+	}
+
+	for _, sub := range []string{c.dir, filepath.Join(c.dir, "blobs"), c.blobDir(), c.indexDir(), c.lockDir()} {
+		if err := c.mkdir(sub); err != nil {
+			return nil, fmt.Errorf("failed to prepare cache directory %s: %w", sub, err)
+		}
+	}
+	return c, nil
+}
+
+// OpenReadOnly returns the cache rooted at dir for Lookup and BlobPath only,
+// without creating any directory, e.g. for dry runs. A cache that does not
+// exist has no entries.
+func OpenReadOnly(dir string) *Cache {
+	return &Cache{dir: dir, lockTimeout: defaultLockTimeout}
+}
+
+// Dir returns the cache root directory.
+func (c *Cache) Dir() string {
+	return c.dir
+}
+
+// SetLockTimeout sets how long Fetch waits for a concurrent fetch of the same key.
+func (c *Cache) SetLockTimeout(d time.Duration) {
+	c.lockTimeout = d
+}
+
+// Lookup returns the digest stored for key, if its blob is still present.
+func (c *Cache) Lookup(key string) (string, bool) {
+	data, err := os.ReadFile(c.indexPath(key))
+	if err != nil {
+		return "", false
+	}
+	digest := strings.TrimSpace(string(data))
+	if _, err := os.Stat(c.BlobPath(digest)); err != nil {
+		return "", false
+	}
+	return digest, true
+}
+
+// Forget drops the index entry of key so the next Fetch downloads it again.
+// The blob itself stays, as other keys may refer to it.
+func (c *Cache) Forget(key string) error {
+	if err := os.Remove(c.indexPath(key)); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to drop cache index: %w", err)
+	}
+	return nil
+}
+
+// BlobPath returns the location of the content with the given digest.
+func (c *Cache) BlobPath(digest string) string {
+	return filepath.Join(c.blobDir(), digest)
+}
+
+// Fetch returns the cached content for key, calling download to populate the
+// cache on a miss. Concurrent fetches of the same key, from this or other
+// processes, are serialized so the asset is only downloaded once.
+func (c *Cache) Fetch(ctx context.Context, key string, download func() (io.ReadCloser, error)) (*os.File, string, error) {
+	if digest, ok := c.Lookup(key); ok {
+		if f, err := os.Open(c.BlobPath(digest)); err == nil {
+			return f, digest, nil
+		}
+	}
+
+	lock, err := filelock.Acquire(ctx, filepath.Join(c.lockDir(), hashKey(key)), c.lockTimeout)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to lock cache entry: %w", err)
+	}
+	defer lock.Release()
+
+	// Another process may have filled the entry while we waited.
+	if digest, ok := c.Lookup(key); ok {
+		if f, err := os.Open(c.BlobPath(digest)); err == nil {
+			return f, digest, nil
+		}
+	}
+
+	rc, err := download()
+	if err != nil {
+		return nil, "", err
+	}
+	defer rc.Close()
+
+	digest, err := c.Put(key, rc)
+	if err != nil {
+		return nil, "", err
+	}
+
+	f, err := os.Open(c.BlobPath(digest))
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to open cached blob: %w", err)
+	}
+	return f, digest, nil
+}
+
+// Put stores the content of r under key and returns its digest.
+func (c *Cache) Put(key string, r io.Reader) (string, error) {
+	tmp, err := os.CreateTemp(c.blobDir(), ".incoming-*")
+	if err != nil {
+		return "", fmt.Errorf("failed to create cache file: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	h := sha256.New()
+	if _, err := io.Copy(io.MultiWriter(tmp, h), r); err != nil {
+		tmp.Close()
+		return "", fmt.Errorf("failed to write cache file: %w", err)
+	}
+	// The index must never name a blob that a crash left incomplete.
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return "", fmt.Errorf("failed to write cache file: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return "", fmt.Errorf("failed to write cache file: %w", err)
+	}
+
+	digest := hex.EncodeToString(h.Sum(nil))
+	if err := os.Chmod(tmp.Name(), c.fileMode()); err != nil {
+		return "", fmt.Errorf("failed to set cache file permissions: %w", err)
+	}
+
+	blob := c.BlobPath(digest)
+	if _, err := os.Stat(blob); os.IsNotExist(err) {
+		if err := os.Rename(tmp.Name(), blob); err != nil {
+			return "", fmt.Errorf("failed to store cache file: %w", err)
+		}
+	}
+
+	if err := c.writeIndex(key, digest); err != nil {
+		return "", err
+	}
+	return digest, nil
+}
+
+func (c *Cache) writeIndex(key, digest string) error {
+	tmp, err := os.CreateTemp(c.indexDir(), ".incoming-*")
+	if err != nil {
+		return fmt.Errorf("failed to create cache index: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.WriteString(digest + "\n"); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write cache index: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to write cache index: %w", err)
+	}
+	if err := os.Chmod(tmp.Name(), c.fileMode()); err != nil {
+		return fmt.Errorf("failed to set cache index permissions: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), c.indexPath(key)); err != nil {
+		return fmt.Errorf("failed to store cache index: %w", err)
+	}
+	return nil
+}
+
+func (c *Cache) mkdir(dir string) error {
+	if err := os.MkdirAll(dir, c.dirMode()); err != nil {
+		return err
+	}
+	if !c.shared {
+		return nil
+	}
+	// MkdirAll is subject to the umask; shared caches need the exact bits.
+	// Ignore failures on directories owned by someone else, who already set them up.
+	if err := os.Chmod(dir, c.dirMode()); err != nil && !os.IsPermission(err) {
+		return err
+	}
+	return nil
+}
+
+func (c *Cache) dirMode() os.FileMode {
+	if c.shared {
+		return 0775 | os.ModeSetgid
+	}
+	return 0755
+}
+
+func (c *Cache) fileMode() os.FileMode {
+	if c.shared {
+		return 0664
+	}
+	return 0644
+}
+
+func (c *Cache) blobDir() string  { return filepath.Join(c.dir, "blobs", "sha256") }
+func (c *Cache) indexDir() string { return filepath.Join(c.dir, "index") }
+func (c *Cache) lockDir() string  { return filepath.Join(c.dir, "locks") }
+
+func (c *Cache) indexPath(key string) string {
+	return filepath.Join(c.indexDir(), hashKey(key))
+}
+
+func hashKey(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,184 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+)
+
+func TestCache_Fetch(t *testing.T) {
+	c, err := Open(t.TempDir(), false)
+	if err != nil {
+		t.Fatalf("Open() error = %v", err)
+	}
+
+	var calls int32
+	download := func() (io.ReadCloser, error) {
+		atomic.AddInt32(&calls, 1)
+		return io.NopCloser(strings.NewReader("asset content")), nil
+	}
+
+	for i := 0; i < 2; i++ {
+		f, digest, err := c.Fetch(context.Background(), "https://example.com/a.tar.gz", download)
+		if err != nil {
+			t.Fatalf("Fetch() error = %v", err)
+		}
+		content, _ := io.ReadAll(f)
+		f.Close()
+
+		if string(content) != "asset content" {
+			t.Errorf("Fetch() content = %q, want %q", content, "asset content")
+		}
+		if len(digest) != 64 {
+			t.Errorf("Fetch() digest = %q, want sha256 hex", digest)
+		}
+	}
+
+	if calls != 1 {
+		t.Errorf("download called %d times, want 1", calls)
+	}
+}
+
+func TestCache_Fetch_Concurrent(t *testing.T) {
+	c, err := Open(t.TempDir(), false)
+	if err != nil {
+		t.Fatalf("Open() error = %v", err)
+	}
+
+	var calls int32
+	download := func() (io.ReadCloser, error) {
+		atomic.AddInt32(&calls, 1)
+		return io.NopCloser(strings.NewReader("shared")), nil
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			f, _, err := c.Fetch(context.Background(), "key", download)
+			if err != nil {
+				t.Errorf("Fetch() error = %v", err)
+				return
+			}
+			f.Close()
+		}()
+	}
+	wg.Wait()
+
+	if calls != 1 {
+		t.Errorf("download called %d times, want 1", calls)
+	}
+}
+
+func TestCache_Fetch_DownloadError(t *testing.T) {
+	c, err := Open(t.TempDir(), false)
+	if err != nil {
+		t.Fatalf("Open() error = %v", err)
+	}
+
+	wantErr := errors.New("network down")
+	_, _, err = c.Fetch(context.Background(), "key", func() (io.ReadCloser, error) {
+		return nil, wantErr
+	})
+	if !errors.Is(err, wantErr) {
+		t.Errorf("Fetch() error = %v, want %v", err, wantErr)
+	}
+
+	if _, ok := c.Lookup("key"); ok {
+		t.Error("Lookup() found entry after failed download")
+	}
+}
+
+func TestCache_Put_Deduplicates(t *testing.T) {
+	dir := t.TempDir()
+	c, err := Open(dir, false)
+	if err != nil {
+		t.Fatalf("Open() error = %v", err)
+	}
+
+	d1, err := c.Put("mirror-url", strings.NewReader("same"))
+	if err != nil {
+		t.Fatalf("Put() error = %v", err)
+	}
+	d2, err := c.Put("direct-url", strings.NewReader("same"))
+	if err != nil {
+		t.Fatalf("Put() error = %v", err)
+	}
+	if d1 != d2 {
+		t.Errorf("Put() digests differ for identical content: %s vs %s", d1, d2)
+	}
+
+	blobs, _ := os.ReadDir(filepath.Join(dir, "blobs", "sha256"))
+	if len(blobs) != 1 {
+		t.Errorf("expected 1 blob, got %d", len(blobs))
+	}
+}
+
+func TestOpenReadOnly(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "cache")
+	if _, ok := OpenReadOnly(dir).Lookup("key"); ok {
+		t.Error("Lookup() found an entry in a missing cache")
+	}
+	if _, err := os.Stat(dir); !os.IsNotExist(err) {
+		t.Errorf("OpenReadOnly() created the cache: %v", err)
+	}
+
+	c, err := Open(dir, false)
+	if err != nil {
+		t.Fatalf("Open() error = %v", err)
+	}
+	digest, err := c.Put("key", strings.NewReader("content"))
+	if err != nil {
+		t.Fatalf("Put() error = %v", err)
+	}
+	if got, ok := OpenReadOnly(dir).Lookup("key"); !ok || got != digest {
+		t.Errorf("Lookup() = %s, %v, want %s", got, ok, digest)
+	}
+}
+
+func TestOpen_Shared(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "shared")
+	c, err := Open(dir, true)
+	if err != nil {
+		t.Fatalf("Open() error = %v", err)
+	}
+
+	fi, err := os.Stat(c.blobDir())
+	if err != nil {
+		t.Fatalf("Stat() error = %v", err)
+	}
+	if fi.Mode().Perm() != 0775 {
+		t.Errorf("shared blob dir mode = %v, want 0775", fi.Mode().Perm())
+	}
+
+	digest, err := c.Put("key", strings.NewReader("data"))
+	if err != nil {
+		t.Fatalf("Put() error = %v", err)
+	}
+	fi, err = os.Stat(c.BlobPath(digest))
+	if err != nil {
+		t.Fatalf("Stat() error = %v", err)
+	}
+	if fi.Mode().Perm() != 0664 {
+		t.Errorf("shared blob mode = %v, want 0664", fi.Mode().Perm())
+	}
+}
+
+func TestResolveDir(t *testing.T) {
+	if got := ResolveDir("system"); got != SystemDir() {
+		t.Errorf("ResolveDir(system) = %s, want %s", got, SystemDir())
+	}
+	if got := ResolveDir("user"); got != UserDir() {
+		t.Errorf("ResolveDir(user) = %s, want %s", got, UserDir())
+	}
+	if got := ResolveDir("/srv/cache"); got != "/srv/cache" {
+		t.Errorf("ResolveDir(/srv/cache) = %s, want /srv/cache", got)
+	}
+}
@@ -0,0 +1,114 @@
+// Package catalog provides known-good repository and asset settings for
+// popular tools, so they can be installed by name.
+package catalog
+
+import (
+	_ "embed"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/sixban6/ghinstall/internal/config"
+	"gopkg.in/yaml.v3"
+)
+
+//go:embed catalog.yaml
+var builtin []byte
+
+var sha256Hex = regexp.MustCompile(`^[0-9a-fA-F]{64}$`)
+
+// Tool is a catalog entry.
+type Tool struct {
+	Name        string `yaml:"-"`
+	Repo        string `yaml:"repo"`
+	Description string `yaml:"description"`
+	// Assets maps "GOOS/GOARCH" to an asset_pattern regular expression.
+	Assets map[string]string `yaml:"assets"`
+	// Version optionally pins the release tag.
+	Version string `yaml:"version,omitempty"`
+	// Checksums optionally maps "GOOS/GOARCH" to the asset's SHA-256 digest.
+	Checksums map[string]string `yaml:"checksums,omitempty"`
+}
+
+// Catalog is a set of tools indexed by name.
+type Catalog struct {
+	Tools map[string]Tool `yaml:"tools"`
+}
+
+// Builtin returns the catalog embedded in the binary.
+func Builtin() (*Catalog, error) {
+	return Parse(builtin)
+}
+
+// Parse decodes and validates a catalog document.
+func Parse(data []byte) (*Catalog, error) {
+	var c Catalog
+	if err := yaml.Unmarshal(data, &c); err != nil {
+		return nil, fmt.Errorf("failed to parse catalog: %w", err)
+	}
+
+	for name, tool := range c.Tools {
+		if tool.Repo == "" {
+			return nil, fmt.Errorf("catalog tool %q: repo is required", name)
+		}
+		for platform, pattern := range tool.Assets {
+			if _, err := regexp.Compile(pattern); err != nil {
+				return nil, fmt.Errorf("catalog tool %q: invalid pattern for %s: %w", name, platform, err)
+			}
+		}
+		for platform, sum := range tool.Checksums {
+			if !sha256Hex.MatchString(sum) {
+				return nil, fmt.Errorf("catalog tool %q: checksum for %s must be a sha256 hex digest", name, platform)
+			}
+		}
+		tool.Name = name
+		c.Tools[name] = tool
+	}
+	return &c, nil
+}
+
+// Lookup returns the tool called name.
+func (c *Catalog) Lookup(name string) (Tool, bool) {
+	t, ok := c.Tools[name]
+	return t, ok
+}
+
+// Names returns the tool names in sorted order.
+func (c *Catalog) Names() []string {
+	names := make([]string, 0, len(c.Tools))
+	for name := range c.Tools {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// RepoFor returns the repository configuration installing the tool for the
+// given platform into outputDir.
+func (t Tool) RepoFor(goos, goarch, outputDir string) (config.Repo, error) {
+	pattern, ok := t.Assets[goos+"/"+goarch]
+	if !ok {
+		return config.Repo{}, fmt.Errorf("%s has no known asset for %s/%s", t.Name, goos, goarch)
+	}
+	return config.Repo{
+		URL:          t.Repo,
+		OutputDir:    outputDir,
+		AssetPattern: pattern,
+		Version:      t.Version,
+		SHA256:       strings.ToLower(t.Checksums[goos+"/"+goarch]),
+	}, nil
+}
+
+// Merge returns a catalog containing the tools of c with those of override
+// added or replacing entries of the same name.
+func (c *Catalog) Merge(override *Catalog) *Catalog {
+	merged := &Catalog{Tools: make(map[string]Tool, len(c.Tools)+len(override.Tools))}
+	for name, tool := range c.Tools {
+		merged.Tools[name] = tool
+	}
+	for name, tool := range override.Tools {
+		merged.Tools[name] = tool
+	}
+	return merged
+}
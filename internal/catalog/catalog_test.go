@@ -0,0 +1,51 @@
+package catalog
+
+import (
+	"regexp"
+	"testing"
+)
+
+func TestBuiltin(t *testing.T) {
+	c, err := Builtin()
+	if err != nil {
+		t.Fatalf("Builtin() error = %v", err)
+	}
+
+	gh, ok := c.Lookup("gh")
+	if !ok {
+		t.Fatal("Builtin() has no gh entry")
+	}
+	if gh.Name != "gh" || gh.Repo != "https://github.com/cli/cli" {
+		t.Errorf("gh = %+v", gh)
+	}
+
+	repo, err := gh.RepoFor("linux", "amd64", "/opt/gh")
+	if err != nil {
+		t.Fatalf("RepoFor() error = %v", err)
+	}
+	if !regexp.MustCompile(repo.AssetPattern).MatchString("gh_2.40.0_linux_amd64.tar.gz") {
+		t.Errorf("gh linux/amd64 pattern %q does not match release asset", repo.AssetPattern)
+	}
+	if regexp.MustCompile(repo.AssetPattern).MatchString("gh_2.40.0_linux_amd64.rpm") {
+		t.Errorf("gh linux/amd64 pattern %q matches rpm package", repo.AssetPattern)
+	}
+
+	if _, err := gh.RepoFor("plan9", "386", "/opt/gh"); err == nil {
+		t.Error("RepoFor(plan9/386) should fail")
+	}
+}
+
+func TestParse_Invalid(t *testing.T) {
+	tests := map[string]string{
+		"missing repo":    "tools:\n  x:\n    assets: {linux/amd64: 'x'}\n",
+		"invalid pattern": "tools:\n  x:\n    repo: https://github.com/a/b\n    assets: {linux/amd64: '('}\n",
+		"invalid yaml":    "tools: [",
+	}
+	for name, doc := range tests {
+		t.Run(name, func(t *testing.T) {
+			if _, err := Parse([]byte(doc)); err == nil {
+				t.Error("Parse() should fail")
+			}
+		})
+	}
+}
@@ -0,0 +1,115 @@
+package catalog
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	log "github.com/sixban6/ghinstall/internal/logger"
+)
+
+// RemoteTTL is how long a fetched remote catalog is used before it is fetched again.
+const RemoteTTL = time.Hour
+
+// client fetches remote catalogs, refusing redirects to plain http.
+var client = &http.Client{
+	CheckRedirect: func(req *http.Request, via []*http.Request) error {
+		if req.URL.Scheme != "https" {
+			return fmt.Errorf("refusing redirect of catalog to %s", req.URL)
+		}
+		if len(via) >= 10 {
+			return fmt.Errorf("stopped after 10 redirects")
+		}
+		return nil
+	},
+}
+
+// Load returns the built-in catalog overridden by the catalog at source, which
+// may be an https URL or a local file path; plain http URLs are refused. Remote catalogs are cached in
+// cacheDir; a stale cached copy is used when the source cannot be reached.
+func Load(ctx context.Context, source, cacheDir string) (*Catalog, error) {
+	base, err := Builtin()
+	if err != nil {
+		return nil, err
+	}
+	if source == "" {
+		return base, nil
+	}
+
+	if strings.HasPrefix(source, "http://") {
+		return nil, fmt.Errorf("refusing to load catalog %s over plain http; use https", source)
+	}
+
+	var data []byte
+	if strings.HasPrefix(source, "https://") {
+		data, err = fetchCached(ctx, source, cacheDir)
+	} else {
+		data, err = os.ReadFile(source)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to load catalog %s: %w", source, err)
+	}
+
+	override, err := Parse(data)
+	if err != nil {
+		return nil, fmt.Errorf("catalog %s: %w", source, err)
+	}
+	return base.Merge(override), nil
+}
+
+func fetchCached(ctx context.Context, url, cacheDir string) ([]byte, error) {
+	sum := sha256.Sum256([]byte(url))
+	path := filepath.Join(cacheDir, "catalog", hex.EncodeToString(sum[:])+".yaml")
+
+	if fi, err := os.Stat(path); err == nil && time.Since(fi.ModTime()) < RemoteTTL {
+		return os.ReadFile(path)
+	}
+
+	data, err := fetch(ctx, url)
+	if err != nil {
+		if cached, readErr := os.ReadFile(path); readErr == nil {
+			log.Warn("Using cached catalog, failed to refresh %s: %v", url, err)
+			return cached, nil
+		}
+		return nil, err
+	}
+
+	if _, err := Parse(data); err != nil {
+		return nil, err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err == nil {
+		if err := os.WriteFile(path, data, 0644); err != nil {
+			log.Warn("Failed to cache catalog: %v", err)
+		}
+	}
+	return data, nil
+}
+
+func fetch(ctx context.Context, url string) ([]byte, error) {
+	ctx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("User-Agent", "ghinstall/1.0")
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("catalog server returned status %d", resp.StatusCode)
+	}
+	return io.ReadAll(io.LimitReader(resp.Body, 10<<20))
+}
@@ -0,0 +1,127 @@
+package catalog
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+const orgCatalog = `tools:
+  gh:
+    repo: https://github.com/cli/cli
+    version: v2.40.0
+    assets:
+      linux/amd64: '^gh_2\.40\.0_linux_amd64\.tar\.gz$'
+    checksums:
+      linux/amd64: 5B8D5A2C1E6F7A8B9C0D1E2F3A4B5C6D7E8F9A0B1C2D3E4F5A6B7C8D9E0F1A2B
+  deployer:
+    repo: https://github.com/acme/deployer
+    assets:
+      linux/amd64: 'linux'
+`
+
+// useClient makes Load fetch with c for the duration of the test.
+func useClient(t *testing.T, c *http.Client) {
+	saved := client
+	client = c
+	t.Cleanup(func() { client = saved })
+}
+
+func TestLoad_Remote(t *testing.T) {
+	requests := 0
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		w.Write([]byte(orgCatalog))
+	}))
+	defer server.Close()
+	useClient(t, server.Client())
+
+	cacheDir := t.TempDir()
+	c, err := Load(context.Background(), server.URL, cacheDir)
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+
+	gh, _ := c.Lookup("gh")
+	repo, err := gh.RepoFor("linux", "amd64", "/opt/gh")
+	if err != nil {
+		t.Fatalf("RepoFor() error = %v", err)
+	}
+	if repo.Version != "v2.40.0" {
+		t.Errorf("overridden gh version = %q, want v2.40.0", repo.Version)
+	}
+	if repo.SHA256 != "5b8d5a2c1e6f7a8b9c0d1e2f3a4b5c6d7e8f9a0b1c2d3e4f5a6b7c8d9e0f1a2b" {
+		t.Errorf("overridden gh checksum = %q", repo.SHA256)
+	}
+	if _, ok := c.Lookup("deployer"); !ok {
+		t.Error("remote-only tool missing from merged catalog")
+	}
+	if _, ok := c.Lookup("ripgrep"); !ok {
+		t.Error("built-in tool missing from merged catalog")
+	}
+
+	// A second load within the TTL is served from the cache.
+	if _, err := Load(context.Background(), server.URL, cacheDir); err != nil {
+		t.Fatalf("second Load() error = %v", err)
+	}
+	if requests != 1 {
+		t.Errorf("catalog fetched %d times, want 1", requests)
+	}
+}
+
+func TestLoad_RemoteStaleFallback(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(orgCatalog))
+	}))
+	useClient(t, server.Client())
+
+	cacheDir := t.TempDir()
+	url := server.URL
+	if _, err := Load(context.Background(), url, cacheDir); err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	server.Close()
+
+	// Age the cached copy past the TTL; the unreachable server forces the fallback.
+	matches, _ := filepath.Glob(filepath.Join(cacheDir, "catalog", "*.yaml"))
+	if len(matches) != 1 {
+		t.Fatalf("expected one cached catalog, got %v", matches)
+	}
+	old := time.Now().Add(-2 * RemoteTTL)
+	os.Chtimes(matches[0], old, old)
+
+	c, err := Load(context.Background(), url, cacheDir)
+	if err != nil {
+		t.Fatalf("Load() with unreachable server error = %v", err)
+	}
+	if _, ok := c.Lookup("deployer"); !ok {
+		t.Error("stale cached catalog was not used")
+	}
+}
+
+func TestLoad_LocalFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "catalog.yaml")
+	os.WriteFile(path, []byte(orgCatalog), 0644)
+
+	c, err := Load(context.Background(), path, t.TempDir())
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if _, ok := c.Lookup("deployer"); !ok {
+		t.Error("local catalog tool missing")
+	}
+
+	if _, err := Load(context.Background(), filepath.Join(t.TempDir(), "missing.yaml"), t.TempDir()); err == nil {
+		t.Error("Load() of missing file should fail")
+	}
+}
+
+func TestLoad_PlainHTTP(t *testing.T) {
+	if _, err := Load(context.Background(), "http://catalog.example/tools.yaml", t.TempDir()); err == nil {
+		t.Error("Load() accepted a plain http catalog")
+	}
+}
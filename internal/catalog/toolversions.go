@@ -0,0 +1,116 @@
+package catalog
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/sixban6/ghinstall/internal/config"
+	"gopkg.in/yaml.v3"
+)
+
+// ToolVersionsFiles are the names of the tool version files read by
+// ParseToolVersions: the asdf and mise format, and a YAML map of tool names
+// to versions.
+var ToolVersionsFiles = []string{".tool-versions", "tools.yaml"}
+
+// ToolVersion is a tool and the version of it a project wants.
+type ToolVersion struct {
+	// Tool is the name of a catalog tool or an "owner/repo" on GitHub.
+	Tool string
+	// Version is the release version or tag, "latest" for the latest release.
+	Version string
+	// Line is the line of the entry in its file.
+	Line int
+}
+
+// ParseToolVersions decodes a tool version file called name: a YAML map when
+// its name ends in .yaml or .yml, the asdf ".tool-versions" format otherwise,
+// whose lines name a tool and one or more versions, the first of which is
+// used, and whose comments start with "#".
+func ParseToolVersions(name string, data []byte) ([]ToolVersion, error) {
+	if ext := filepath.Ext(name); ext == ".yaml" || ext == ".yml" {
+		return parseToolsYAML(name, data)
+	}
+
+	var tools []ToolVersion
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for line := 1; scanner.Scan(); line++ {
+		text, _, _ := strings.Cut(scanner.Text(), "#")
+		fields := strings.Fields(text)
+		if len(fields) == 0 {
+			continue
+		}
+		if len(fields) < 2 {
+			return nil, fmt.Errorf("%s:%d: %s has no version", name, line, fields[0])
+		}
+		tools = append(tools, ToolVersion{Tool: fields[0], Version: fields[1], Line: line})
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", name, err)
+	}
+	return tools, nil
+}
+
+func parseToolsYAML(name string, data []byte) ([]ToolVersion, error) {
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", name, err)
+	}
+	if doc.Kind == 0 {
+		return nil, nil
+	}
+	root := doc.Content[0]
+	if root.Kind != yaml.MappingNode {
+		return nil, fmt.Errorf("%s: expected a map of tool names to versions", name)
+	}
+
+	var tools []ToolVersion
+	for i := 0; i+1 < len(root.Content); i += 2 {
+		key, value := root.Content[i], root.Content[i+1]
+		if value.Kind != yaml.ScalarNode || value.Value == "" {
+			return nil, fmt.Errorf("%s:%d: %s must map to a version", name, key.Line, key.Value)
+		}
+		tools = append(tools, ToolVersion{Tool: key.Value, Version: value.Value, Line: key.Line})
+	}
+	return tools, nil
+}
+
+// platformPattern selects the asset of a repository outside the catalog: the
+// archive naming the platform, such as "tool_1.0_linux_amd64.tar.gz".
+const platformPattern = `({os}.*{arch}|{arch}.*{os}).*\.(tar\.gz|tgz|zip)$`
+
+// RepoFor returns the repository configuration installing tv for the given
+// platform into outputDir: the catalog entry of the tool with its version, or
+// for an "owner/repo" the release asset naming the platform. "latest" follows
+// the latest release. Versions asdf resolves itself, such as "system",
+// "ref:..." and "path:...", are not supported.
+func (c *Catalog) RepoFor(tv ToolVersion, goos, goarch, outputDir string) (config.Repo, error) {
+	switch {
+	case tv.Version == "system" || strings.HasPrefix(tv.Version, "ref:") || strings.HasPrefix(tv.Version, "path:"):
+		return config.Repo{}, fmt.Errorf("%s: version %q is not a release", tv.Tool, tv.Version)
+	case tv.Version == "latest" || strings.HasPrefix(tv.Version, "latest:"):
+		tv.Version = ""
+	}
+
+	var repo config.Repo
+	if tool, ok := c.Lookup(tv.Tool); ok {
+		var err error
+		if repo, err = tool.RepoFor(goos, goarch, outputDir); err != nil {
+			return config.Repo{}, err
+		}
+		if tv.Version != tool.Version {
+			// The catalog checksums are those of its pinned version.
+			repo.SHA256 = ""
+		}
+	} else if owner, name, ok := strings.Cut(tv.Tool, "/"); ok && owner != "" && name != "" && !strings.Contains(name, "/") {
+		repo = config.Repo{URL: "https://github.com/" + tv.Tool, OutputDir: outputDir, AssetPattern: platformPattern}
+	} else {
+		return config.Repo{}, fmt.Errorf("unknown tool %q: use a catalog name or owner/repo", tv.Tool)
+	}
+	repo.Name = tv.Tool
+	repo.Version = tv.Version
+	return repo, nil
+}
@@ -0,0 +1,89 @@
+package catalog
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseToolVersions(t *testing.T) {
+	tests := []struct {
+		name    string
+		file    string
+		data    string
+		want    []ToolVersion
+		wantErr bool
+	}{
+		{
+			name: "tool-versions",
+			file: ".tool-versions",
+			data: "# tools\ngh 2.40.0 2.39.0\n\nBurntSushi/ripgrep latest # search\n",
+			want: []ToolVersion{
+				{Tool: "gh", Version: "2.40.0", Line: 2},
+				{Tool: "BurntSushi/ripgrep", Version: "latest", Line: 4},
+			},
+		},
+		{name: "tool-versions without version", file: ".tool-versions", data: "gh\n", wantErr: true},
+		{
+			name: "yaml",
+			file: "tools.yaml",
+			data: "gh: v2.40.0\nfd: latest\n",
+			want: []ToolVersion{
+				{Tool: "gh", Version: "v2.40.0", Line: 1},
+				{Tool: "fd", Version: "latest", Line: 2},
+			},
+		},
+		{name: "empty yaml", file: "tools.yaml", data: ""},
+		{name: "yaml list", file: "tools.yaml", data: "- gh\n", wantErr: true},
+		{name: "yaml without version", file: "tools.yaml", data: "gh: {}\n", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseToolVersions(tt.file, []byte(tt.data))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseToolVersions() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ParseToolVersions() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCatalog_RepoFor(t *testing.T) {
+	digest := strings.Repeat("a", 64)
+	c, err := Parse([]byte("tools:\n  gh:\n    repo: https://github.com/cli/cli\n    version: v2.40.0\n    assets: {linux/amd64: 'gh'}\n    checksums: {linux/amd64: " + digest + "}\n"))
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+
+	tests := []struct {
+		name       string
+		tv         ToolVersion
+		wantURL    string
+		wantVer    string
+		wantSHA256 string
+		wantErr    bool
+	}{
+		{name: "catalog pinned version", tv: ToolVersion{Tool: "gh", Version: "v2.40.0"}, wantURL: "https://github.com/cli/cli", wantVer: "v2.40.0", wantSHA256: digest},
+		{name: "catalog other version", tv: ToolVersion{Tool: "gh", Version: "2.41.0"}, wantURL: "https://github.com/cli/cli", wantVer: "2.41.0"},
+		{name: "catalog latest", tv: ToolVersion{Tool: "gh", Version: "latest"}, wantURL: "https://github.com/cli/cli"},
+		{name: "owner/repo", tv: ToolVersion{Tool: "sharkdp/fd", Version: "v9.0.0"}, wantURL: "https://github.com/sharkdp/fd", wantVer: "v9.0.0"},
+		{name: "unknown tool", tv: ToolVersion{Tool: "nodejs", Version: "20.0.0"}, wantErr: true},
+		{name: "system version", tv: ToolVersion{Tool: "gh", Version: "system"}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo, err := c.RepoFor(tt.tv, "linux", "amd64", "/opt/tool")
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("RepoFor() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			if repo.URL != tt.wantURL || repo.Version != tt.wantVer || repo.SHA256 != tt.wantSHA256 || repo.Name != tt.tv.Tool || repo.OutputDir != "/opt/tool" {
+				t.Errorf("RepoFor() = %+v", repo)
+			}
+		})
+	}
+}
@@ -0,0 +1,180 @@
+// Package completion finds shell completion scripts and man pages shipped in
+// release archives and installs them where shells and man(1) look for them.
+package completion
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"runtime"
+	"slices"
+	"strings"
+)
+
+// Item is a completion script or man page found in an extracted tree.
+type Item struct {
+	// Kind is "man", "bash", "zsh" or "fish".
+	Kind string
+	// Source is the file inside the extracted tree.
+	Source string
+	// Dest is where the file is installed.
+	Dest string
+}
+
+// Dirs are the per-user install locations.
+type Dirs struct {
+	Man  string
+	Bash string
+	Zsh  string
+	Fish string
+}
+
+var (
+	manPage = regexp.MustCompile(`\.([1-9])[a-z]*(\.gz)?$`)
+	manDir  = regexp.MustCompile(`^man([1-9][a-z]*)?$`)
+)
+
+// DefaultDirs returns the XDG-style per-user locations that man-db,
+// bash-completion, zsh (with fpath configured) and fish read from.
+func DefaultDirs() Dirs {
+	home, _ := os.UserHomeDir()
+
+	data := os.Getenv("XDG_DATA_HOME")
+	if data == "" {
+		data = filepath.Join(home, ".local", "share")
+	}
+	conf := os.Getenv("XDG_CONFIG_HOME")
+	if conf == "" {
+		conf = filepath.Join(home, ".config")
+	}
+
+	return Dirs{
+		Man:  filepath.Join(data, "man"),
+		Bash: filepath.Join(data, "bash-completion", "completions"),
+		Zsh:  filepath.Join(data, "zsh", "site-functions"),
+		Fish: filepath.Join(conf, "fish", "completions"),
+	}
+}
+
+// Find returns the completion scripts and man pages among files, the
+// slash-separated paths of the regular files a release extracted below root.
+// Other files below root, such as those of other releases sharing it, are
+// never considered.
+func Find(root string, files []string, dirs Dirs) []Item {
+	var items []Item
+	for _, rel := range files {
+		source := filepath.Join(root, filepath.FromSlash(rel))
+		info, err := os.Stat(source)
+		executable := err == nil && info.Mode().Perm()&0111 != 0
+		if item, ok := classify(rel, executable, dirs); ok {
+			item.Source = source
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// classify tells what the file rel is. Names ending in a section number are
+// man pages only below a man directory or when not executable, as versioned
+// executables such as tool-v1.2.3 or python3.9 end the same way.
+func classify(rel string, executable bool, dirs Dirs) (Item, bool) {
+	name := pathBase(rel)
+	parent := strings.ToLower(strings.TrimSuffix(rel, name))
+
+	switch {
+	case strings.HasSuffix(name, ".fish"):
+		return Item{Kind: "fish", Dest: filepath.Join(dirs.Fish, name)}, true
+	case strings.HasSuffix(name, ".zsh"):
+		return Item{Kind: "zsh", Dest: filepath.Join(dirs.Zsh, "_"+strings.TrimPrefix(strings.TrimSuffix(name, ".zsh"), "_"))}, true
+	case strings.HasPrefix(name, "_") && strings.Contains(parent, "zsh"):
+		return Item{Kind: "zsh", Dest: filepath.Join(dirs.Zsh, name)}, true
+	case strings.HasSuffix(name, ".bash"):
+		return Item{Kind: "bash", Dest: filepath.Join(dirs.Bash, strings.TrimSuffix(name, ".bash"))}, true
+	case strings.HasSuffix(name, ".bash-completion"):
+		return Item{Kind: "bash", Dest: filepath.Join(dirs.Bash, strings.TrimSuffix(name, ".bash-completion"))}, true
+	case !strings.Contains(name, ".") && strings.Contains(parent, "bash"):
+		// e.g. completions/bash/gh
+		return Item{Kind: "bash", Dest: filepath.Join(dirs.Bash, name)}, true
+	}
+
+	if m := manPage.FindStringSubmatch(name); m != nil && !strings.Contains(name, ".so.") && (inManDir(parent) || !executable) {
+		return Item{Kind: "man", Dest: filepath.Join(dirs.Man, "man"+m[1], name)}, true
+	}
+	return Item{}, false
+}
+
+// Install copies the items to their destinations and returns the installed
+// paths. An existing destination is replaced only when it is listed in owned,
+// the files ghinstall installed before, or holds the same content; others,
+// such as the user's own completions, are left alone and returned as skipped.
+func Install(items []Item, owned []string) (installed, skipped []string, err error) {
+	for _, item := range items {
+		if _, err := os.Lstat(item.Dest); err == nil && !slices.Contains(owned, item.Dest) && !sameContent(item.Source, item.Dest) {
+			skipped = append(skipped, item.Dest)
+			continue
+		}
+		if err := copyFile(item.Source, item.Dest); err != nil {
+			return installed, skipped, fmt.Errorf("failed to install %s: %w", item.Source, err)
+		}
+		installed = append(installed, item.Dest)
+	}
+	return installed, skipped, nil
+}
+
+// sameContent reports whether the files a and b hold the same bytes.
+func sameContent(a, b string) bool {
+	x, err := os.ReadFile(a)
+	if err != nil {
+		return false
+	}
+	y, err := os.ReadFile(b)
+	return err == nil && bytes.Equal(x, y)
+}
+
+// Supported reports whether completions are installed on this platform.
+func Supported() bool {
+	return runtime.GOOS != "windows"
+}
+
+func copyFile(src, dst string) error {
+	if err := os.MkdirAll(filepath.Dir(dst), 0755); err != nil {
+		return err
+	}
+
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	out, err := os.OpenFile(dst, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0644)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
+
+// inManDir reports whether the slash-separated directory dir is, or is
+// below, a man or man<N> directory.
+func inManDir(dir string) bool {
+	for _, elem := range strings.Split(dir, "/") {
+		if manDir.MatchString(elem) {
+			return true
+		}
+	}
+	return false
+}
+
+func pathBase(rel string) string {
+	if i := strings.LastIndex(rel, "/"); i >= 0 {
+		return rel[i+1:]
+	}
+	return rel
+}
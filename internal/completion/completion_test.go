@@ -0,0 +1,145 @@
+package completion
+
+import (
+	"os"
+	"path/filepath"
+	"sort"
+	"testing"
+)
+
+func TestFind(t *testing.T) {
+	root := t.TempDir()
+	files := []string{
+		"gh_2.40.0/share/man/man1/gh.1",
+		"gh_2.40.0/share/man/man1/gh-pr.1.gz",
+		"completions/rg.bash",
+		"completions/_rg",
+		"complete/zsh/_fzf",
+		"completions/bash/fzf",
+		"completions/fd.fish",
+		"bin/rg",
+		"lib/libfoo.so.1",
+		"README.md",
+	}
+	// Files of other releases sharing root are ignored.
+	for _, f := range append(files, "other.bash", "share/man/man8/other.8") {
+		path := filepath.Join(root, f)
+		os.MkdirAll(filepath.Dir(path), 0755)
+		os.WriteFile(path, []byte(f), 0644)
+	}
+
+	dirs := Dirs{Man: "/m", Bash: "/b", Zsh: "/z", Fish: "/f"}
+	items := Find(root, files, dirs)
+	for _, item := range items {
+		if _, err := os.Stat(item.Source); err != nil {
+			t.Errorf("Find() source %s: %v", item.Source, err)
+		}
+	}
+
+	var got []string
+	for _, item := range items {
+		got = append(got, item.Kind+" "+filepath.ToSlash(item.Dest))
+	}
+	sort.Strings(got)
+
+	want := []string{
+		"bash /b/fzf",
+		"bash /b/rg",
+		"fish /f/fd.fish",
+		"man /m/man1/gh-pr.1.gz",
+		"man /m/man1/gh.1",
+		"zsh /z/_fzf",
+	}
+	if len(got) != len(want) {
+		t.Fatalf("Find() = %v, want %v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("Find()[%d] = %s, want %s", i, got[i], want[i])
+		}
+	}
+}
+
+func TestClassify_ManPages(t *testing.T) {
+	dirs := Dirs{Man: "/m"}
+	tests := []struct {
+		rel        string
+		executable bool
+		want       string
+	}{
+		{rel: "share/man/man1/tool.1", want: "/m/man1/tool.1"},
+		{rel: "man/tool.8.gz", executable: true, want: "/m/man8/tool.8.gz"},
+		{rel: "doc/tool.1", want: "/m/man1/tool.1"},
+		// Versioned executables are not man pages.
+		{rel: "tool-v1.2.3", executable: true},
+		{rel: "bin/python3.9", executable: true},
+		{rel: "tool_1.2.3/tool-1.2.3", executable: true},
+		{rel: "lib/libfoo.so.1"},
+	}
+	for _, tt := range tests {
+		item, ok := classify(tt.rel, tt.executable, dirs)
+		if got := filepath.ToSlash(item.Dest); ok != (tt.want != "") || got != tt.want {
+			t.Errorf("classify(%s, %v) = %s, %v, want %q", tt.rel, tt.executable, got, ok, tt.want)
+		}
+	}
+}
+
+func TestInstall(t *testing.T) {
+	root := t.TempDir()
+	src := filepath.Join(root, "rg.bash")
+	os.WriteFile(src, []byte("complete -F _rg rg"), 0644)
+
+	dest := filepath.Join(t.TempDir(), "bash-completion", "completions", "rg")
+	installed, _, err := Install([]Item{{Kind: "bash", Source: src, Dest: dest}}, nil)
+	if err != nil {
+		t.Fatalf("Install() error = %v", err)
+	}
+	if len(installed) != 1 || installed[0] != dest {
+		t.Errorf("Install() = %v, want [%s]", installed, dest)
+	}
+
+	data, err := os.ReadFile(dest)
+	if err != nil || string(data) != "complete -F _rg rg" {
+		t.Errorf("installed content = %q, %v", data, err)
+	}
+}
+
+func TestInstall_Existing(t *testing.T) {
+	root := t.TempDir()
+	src := filepath.Join(root, "rg.bash")
+	os.WriteFile(src, []byte("complete -F _rg rg"), 0644)
+	dir := t.TempDir()
+
+	tests := []struct {
+		name     string
+		existing string
+		owned    bool
+		want     string
+		skipped  bool
+	}{
+		{name: "the user's", existing: "mine", want: "mine", skipped: true},
+		{name: "installed before", existing: "old", owned: true, want: "complete -F _rg rg"},
+		{name: "same content", existing: "complete -F _rg rg", want: "complete -F _rg rg"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dest := filepath.Join(dir, tt.name)
+			os.WriteFile(dest, []byte(tt.existing), 0644)
+			var owned []string
+			if tt.owned {
+				owned = []string{dest}
+			}
+
+			installed, skipped, err := Install([]Item{{Kind: "bash", Source: src, Dest: dest}}, owned)
+			if err != nil {
+				t.Fatalf("Install() error = %v", err)
+			}
+			if data, _ := os.ReadFile(dest); string(data) != tt.want {
+				t.Errorf("content = %q, want %q", data, tt.want)
+			}
+			if (len(skipped) == 1) != tt.skipped || (len(installed) == 1) == tt.skipped {
+				t.Errorf("Install() = %v installed, %v skipped", installed, skipped)
+			}
+		})
+	}
+}
@@ -0,0 +1,145 @@
+package config
+
+import (
+	"fmt"
+	"slices"
+)
+
+// Builder builds a Config in Go. Every step is validated as it is taken, with
+// the checks Load applies to config files, and Build normalizes the result
+// like Load does, so generated and loaded configs behave the same.
+type Builder struct {
+	cfg Config
+	err error
+}
+
+// RepoOption sets an optional field of a repository added with AddRepo.
+type RepoOption func(*Repo)
+
+// WithPattern selects the asset with a regular expression, as asset_pattern.
+func WithPattern(pattern string) RepoOption {
+	return func(r *Repo) { r.AssetPattern = pattern }
+}
+
+// WithVersion pins the release tag, as version.
+func WithVersion(version string) RepoOption {
+	return func(r *Repo) { r.Version = version }
+}
+
+// WithChannel selects the release channel, as channel.
+func WithChannel(channel string) RepoOption {
+	return func(r *Repo) { r.Channel = channel }
+}
+
+// WithConstraint installs the latest release satisfying a version
+// constraint, as constraint.
+func WithConstraint(c string) RepoOption {
+	return func(r *Repo) { r.Constraint = c }
+}
+
+// WithName sets the short alias of the repository, as name.
+func WithName(name string) RepoOption {
+	return func(r *Repo) { r.Name = name }
+}
+
+// WithSHA256 sets the expected digest of the asset, as sha256.
+func WithSHA256(digest string) RepoOption {
+	return func(r *Repo) { r.SHA256 = digest }
+}
+
+// WithExcludeTags excludes release tags matching patterns, as exclude_tags.
+func WithExcludeTags(patterns ...string) RepoOption {
+	return func(r *Repo) { r.ExcludeTags = append(r.ExcludeTags, patterns...) }
+}
+
+// WithPrefer sets which of several assets for the platform is installed, as prefer.
+func WithPrefer(pref string) RepoOption {
+	return func(r *Repo) { r.Prefer = pref }
+}
+
+// NewBuilder returns a Builder of an empty config.
+func NewBuilder() *Builder {
+	return &Builder{}
+}
+
+// AddRepo adds the repository at url, installed into outputDir.
+func (b *Builder) AddRepo(url, outputDir string, opts ...RepoOption) *Builder {
+	repo := Repo{URL: url, OutputDir: outputDir}
+	for _, opt := range opts {
+		opt(&repo)
+	}
+	return b.apply(func(c *Config) { c.Github = append(c.Github, repo) })
+}
+
+// WithMirror sets the mirror used when GitHub is not reachable directly: the
+// name of one of the MirrorPresets, as mirror, or a URL, as mirror_url.
+func (b *Builder) WithMirror(mirror string) *Builder {
+	return b.apply(func(c *Config) {
+		if _, ok := LookupMirror(mirror); ok {
+			c.Mirror, c.MirrorURL = mirror, ""
+		} else {
+			c.Mirror, c.MirrorURL = "", mirror
+		}
+	})
+}
+
+// WithCacheDir enables the download cache in dir, as cache_dir.
+func (b *Builder) WithCacheDir(dir string) *Builder {
+	return b.apply(func(c *Config) { c.CacheDir = dir })
+}
+
+// WithBinDir links the executables of every repository into dir, as bin_dir.
+func (b *Builder) WithBinDir(dir string) *Builder {
+	return b.apply(func(c *Config) { c.BinDir = dir })
+}
+
+// With applies fn to the config for the settings without a dedicated method,
+// validating the result like the other steps.
+func (b *Builder) With(fn func(*Config)) *Builder {
+	return b.apply(fn)
+}
+
+// Err returns the first error of the steps taken so far.
+func (b *Builder) Err() error {
+	return b.err
+}
+
+// Build returns the config, or the first error of the steps taken. The
+// Builder can be used further; later steps do not change the returned config.
+func (b *Builder) Build() (*Config, error) {
+	if b.err != nil {
+		return nil, b.err
+	}
+	cfg := b.cfg.clone()
+	cfg.applyAliases()
+	if err := cfg.validate(); err != nil {
+		return nil, fmt.Errorf("invalid config: %w", err)
+	}
+	cfg.normalize()
+	return cfg, nil
+}
+
+// apply applies fn to a copy of the config and keeps it when it validates,
+// though no repository may have been added yet. After an error, steps are
+// skipped so the first error is the one reported.
+func (b *Builder) apply(fn func(*Config)) *Builder {
+	if b.err != nil {
+		return b
+	}
+	cfg := b.cfg.clone()
+	fn(cfg)
+	if err := cfg.validateSettings(); err != nil {
+		b.err = fmt.Errorf("invalid config: %w", err)
+		return b
+	}
+	b.cfg = *cfg
+	return b
+}
+
+// clone returns a copy of c whose repository list can be changed without
+// changing c's.
+func (c *Config) clone() *Config {
+	cfg := *c
+	cfg.Github = slices.Clone(c.Github)
+	return &cfg
+}
@@ -0,0 +1,59 @@
+package config
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestBuilder(t *testing.T) {
+	b := NewBuilder().
+		WithMirror("ghproxy").
+		AddRepo("https://github.com/cli/cli/", "/opt/gh/", WithPattern(`linux_amd64\.tar\.gz$`), WithVersion("v2.40.0"), WithName("gh")).
+		AddRepo("https://github.com/owner/tool", "/opt/tool", WithSHA256(strings.Repeat("A", 64)))
+	cfg, err := b.Build()
+	if err != nil {
+		t.Fatalf("Build() error = %v", err)
+	}
+	if cfg.Mirror != "ghproxy" || cfg.MirrorURL != "" {
+		t.Errorf("mirror = %q, mirror_url = %q", cfg.Mirror, cfg.MirrorURL)
+	}
+	gh := cfg.Github[0]
+	if gh.URL != "https://github.com/cli/cli" || gh.OutputDir != filepath.Clean("/opt/gh") || gh.Version != "v2.40.0" || gh.Name != "gh" || gh.AssetPattern == "" {
+		t.Errorf("repo = %+v, want normalized like Load", gh)
+	}
+	if cfg.Github[1].SHA256 != strings.Repeat("a", 64) {
+		t.Errorf("sha256 = %q, want lower case", cfg.Github[1].SHA256)
+	}
+
+	b.AddRepo("https://github.com/owner/more", "/opt/more")
+	if len(cfg.Github) != 2 {
+		t.Error("steps after Build() changed the built config")
+	}
+
+	if _, err := NewBuilder().WithMirror("https://mirror.example").Build(); err == nil {
+		t.Error("Build() without repositories should fail")
+	}
+}
+
+func TestBuilder_Invalid(t *testing.T) {
+	tests := map[string]*Builder{
+		"not github":       NewBuilder().AddRepo("https://example.com/owner/tool", "/opt/tool"),
+		"no output dir":    NewBuilder().AddRepo("https://github.com/owner/tool", ""),
+		"invalid pattern":  NewBuilder().AddRepo("https://github.com/owner/tool", "/opt/tool", WithPattern("(")),
+		"channel version":  NewBuilder().AddRepo("https://github.com/owner/tool", "/opt/tool", WithChannel("nightly"), WithVersion("v1")),
+		"duplicate":        NewBuilder().AddRepo("https://github.com/owner/tool", "/opt/tool").AddRepo("https://github.com/owner/tool/", "/opt/tool/"),
+		"invalid settings": NewBuilder().With(func(c *Config) { c.ZipDuplicates = "newest" }),
+	}
+	for name, b := range tests {
+		t.Run(name, func(t *testing.T) {
+			if b.Err() == nil {
+				t.Fatal("Err() should report the invalid step")
+			}
+			// Later valid steps keep the first error.
+			if _, err := b.AddRepo("https://github.com/owner/other", "/opt/other").Build(); err != b.Err() {
+				t.Errorf("Build() error = %v, want %v", err, b.Err())
+			}
+		})
+	}
+}
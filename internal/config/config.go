@@ -1,91 +1,938 @@
 package config
 
 import (
+	"errors"
 	"fmt"
+	"io/fs"
+	"net/url"
 	"os"
+	"path"
 	"path/filepath"
+	"regexp"
+	"slices"
+	"strconv"
 	"strings"
+	"time"
 
+	"github.com/sixban6/ghinstall/internal/constraint"
 	"gopkg.in/yaml.v3"
 )
 
 type Config struct {
 	Github    []Repo `yaml:"github"`
 	MirrorURL string `yaml:"mirror_url"`
+	// Mirror selects one of the MirrorPresets by name instead of a MirrorURL.
+	Mirror string `yaml:"mirror"`
+	// MirrorOptions work around mirrors that misbehave with HTTP/2 or
+	// compress already-compressed assets again.
+	MirrorOptions MirrorOptions `yaml:"mirror_options"`
+	// AllowInsecureRedirects follows redirects of https downloads to plain
+	// http, which are refused by default.
+	AllowInsecureRedirects bool `yaml:"allow_insecure_redirects"`
+	// CacheDir enables the download cache. Besides a path it accepts "user"
+	// for the per-user cache and "system" for the host-wide shared cache.
+	CacheDir string `yaml:"cache_dir"`
+	// CacheShared makes a custom CacheDir usable by every member of its group.
+	CacheShared bool `yaml:"cache_shared"`
+	// LockTimeout bounds how long an install waits for another process that
+	// holds the lock on the same output_dir or cache entry.
+	LockTimeout time.Duration `yaml:"lock_timeout"`
+	// Providers declares external executables that resolve and download
+	// releases for repositories selecting them with provider.
+	Providers []ProviderConfig `yaml:"providers"`
+	// PostProcessors run after every repository is extracted.
+	PostProcessors []Hook `yaml:"post_processors"`
+	// BinDir is a managed directory receiving a shim for every executable of
+	// every installed repository, so only it needs to be on PATH.
+	BinDir string `yaml:"bin_dir"`
+	// AssetTypePreference orders archive types such as ".zip" or ".tar.gz"
+	// from most to least preferred when a release offers several of them.
+	AssetTypePreference []string `yaml:"asset_type_preference"`
+	// ZipDuplicates decides which copy of a zip entry stored several times
+	// under the same path is extracted: "last-wins" (default), "first-wins"
+	// or "error".
+	ZipDuplicates string `yaml:"zip_duplicates"`
+	// DefaultFileMode and DefaultDirMode are the permissions given to archive
+	// entries stored with mode 0; 0644 and 0755 when unset.
+	DefaultFileMode os.FileMode `yaml:"default_file_mode"`
+	DefaultDirMode  os.FileMode `yaml:"default_dir_mode"`
+	// Extractor selects how archives are unpacked: "go" (default) with the
+	// built-in implementation or "system" with tar, unzip or PowerShell.
+	Extractor string `yaml:"extractor"`
+	// ExtractorIsolation runs the tools of the system extractor under
+	// "bwrap" or "unshare" on Linux.
+	ExtractorIsolation string `yaml:"extractor_isolation"`
+	// SandboxExtraction confines extraction on Linux with Landlock and seccomp
+	// to the output and temp directories, without exec or network access.
+	// Installs fail where the sandbox is unavailable.
+	SandboxExtraction bool `yaml:"sandbox_extraction"`
+	// Attest signs the manifest of installed files after every install.
+	Attest AttestOptions `yaml:"attest"`
+	// MaxRedirects bounds the redirects a download follows, e.g. through
+	// mirrors redirecting to each other; DefaultMaxRedirects when unset.
+	MaxRedirects int `yaml:"max_redirects"`
+	// Timeouts bound the phases of every download instead of its total
+	// duration.
+	Timeouts TimeoutOptions `yaml:"timeouts"`
+	// ExtractLimits bound what extracting one archive may produce, guarding
+	// against archive bombs.
+	ExtractLimits ExtractLimits `yaml:"extract_limits"`
+	// MaxOpenFiles and MaxConnections bound the output files and network
+	// connections open at once across all installs; a quarter of the open
+	// file limit each when unset.
+	MaxOpenFiles   int `yaml:"max_open_files"`
+	MaxConnections int `yaml:"max_connections"`
+	// KeepAliveIdleTimeout is how long idle connections are kept for the
+	// next downloads of a run; 90s when unset.
+	KeepAliveIdleTimeout time.Duration `yaml:"keepalive_idle_timeout"`
+	// StrictAssets fails installs whose asset_pattern matches several assets
+	// instead of picking the first of them.
+	StrictAssets bool `yaml:"strict_assets"`
+	// AllowDangerousDir allows an output_dir that is a filesystem root, the
+	// home directory itself or a system directory such as /usr or C:\Windows,
+	// which installs refuse by default.
+	AllowDangerousDir bool `yaml:"allow_dangerous_dir"`
+	// AllowRosetta installs the amd64 build of releases without an arm64
+	// asset on Apple Silicon, to run under Rosetta 2.
+	AllowRosetta bool `yaml:"allow_rosetta"`
+	// WindowsEmulation lists the architectures, "amd64" and "386", whose
+	// builds are installed in this order on Windows when a release has none
+	// for the host, to run under emulation: both on ARM devices, 386 on x64.
+	WindowsEmulation []string `yaml:"windows_emulation"`
+	// APIBudget limits the GitHub API requests of a run, to spare a token
+	// shared by many jobs; past it, releases resolve from the HTTP cache or
+	// fail. Unlimited when 0.
+	APIBudget int `yaml:"api_budget"`
+	// Defaults are inherited by every repository that does not set them.
+	Defaults RepoDefaults `yaml:"defaults"`
+	// ContinueOnError installs every repository that can be installed when
+	// others fail, instead of stopping at the first failure.
+	ContinueOnError bool `yaml:"continue_on_error"`
+	// CatalogURL is a catalog of tools for "ghinstall get" overriding the
+	// built-in one: an https URL or a local path.
+	CatalogURL string `yaml:"catalog_url"`
 }
 
+// RepoDefaults are the settings of the defaults block, which repositories
+// inherit unless they set their own.
+type RepoDefaults struct {
+	// OutputBaseDir gives a repository without output_dir the directory
+	// <output_base_dir>/<name>, after its name or else its repository name.
+	OutputBaseDir      string   `yaml:"output_base_dir"`
+	AssetPattern       string   `yaml:"asset_pattern"`
+	Prefer             string   `yaml:"prefer"`
+	Channel            string   `yaml:"channel"`
+	ExcludeTags        []string `yaml:"exclude_tags"`
+	InstallCompletions bool     `yaml:"install_completions"`
+	Delta              bool     `yaml:"delta"`
+	BinariesOnly       bool     `yaml:"binaries_only"`
+	HoldOnMajor        bool     `yaml:"hold_on_major"`
+}
+
+// AttestOptions select the key signing install manifests.
+type AttestOptions struct {
+	// Key is the private key: an OpenSSH key, or a minisign secret key
+	// without password for format minisign.
+	Key string `yaml:"key"`
+	// Format is "ssh" (default) or "minisign".
+	Format string `yaml:"format"`
+}
+
+// MirrorOptions adjust the HTTP transport used for the mirror.
+type MirrorOptions struct {
+	ForceHTTP1         bool `yaml:"force_http1"`
+	DisableCompression bool `yaml:"disable_compression"`
+	// SampleBytes, when positive, fetches that many bytes of every asset
+	// directly from GitHub and compares them with the beginning of the mirror's
+	// response before the rest is downloaded from the mirror.
+	SampleBytes int64 `yaml:"sample_bytes"`
+}
+
+// TimeoutOptions bound the phases of a download; unset ones keep their
+// defaults.
+type TimeoutOptions struct {
+	// Connect bounds establishing a connection (default 30s).
+	Connect time.Duration `yaml:"connect"`
+	// ResponseHeader bounds the wait for the response, including the TLS
+	// handshake and redirects (default 1m).
+	ResponseHeader time.Duration `yaml:"response_header"`
+	// Idle aborts a download that receives no data for this long (default 1m).
+	Idle time.Duration `yaml:"idle"`
+}
+
+// ExtractLimits bound the extraction of an archive by the built-in extractor;
+// unset ones keep their defaults.
+type ExtractLimits struct {
+	// MaxSize bounds the bytes extracted (default 32 GiB).
+	MaxSize int64 `yaml:"max_size"`
+	// MaxEntries bounds the number of entries (default 1048576).
+	MaxEntries int `yaml:"max_entries"`
+	// Timeout bounds the duration of the extraction (default 1h).
+	Timeout time.Duration `yaml:"timeout"`
+}
+
+// Hook is an external command run by ghinstall.
+type Hook struct {
+	Command []string `yaml:"command"`
+}
+
+// ProviderConfig declares an exec source provider.
+type ProviderConfig struct {
+	Name    string   `yaml:"name"`
+	Command []string `yaml:"command"`
+}
+
+// DefaultLockTimeout is used when LockTimeout is not configured.
+const DefaultLockTimeout = 5 * time.Minute
+
+// DefaultMaxRedirects is used when MaxRedirects is not configured.
+const DefaultMaxRedirects = 10
+
 type Repo struct {
 	URL       string `yaml:"url"`
 	OutputDir string `yaml:"output_dir"`
+	// Name is an optional short alias used in logs, reports and the state, and
+	// to select the repository on the command line.
+	Name string `yaml:"name,omitempty"`
+	// AssetPattern is a regular expression selecting the release asset by
+	// name. It takes precedence over the asset filter passed to the installer.
+	// The placeholders {tag}, {version}, {os} and {arch} are expanded for the
+	// release and the platform before matching.
+	AssetPattern string `yaml:"asset_pattern,omitempty"`
+	// Version pins the release tag to install instead of the latest stable release.
+	Version string `yaml:"version,omitempty"`
+	// Tag is an alias of Version. Loading moves it into Version.
+	Tag string `yaml:"tag,omitempty"`
+	// Channel selects which releases count as the latest one: "stable"
+	// (default), "prerelease" or "nightly" (most recently published).
+	Channel string `yaml:"channel,omitempty"`
+	// ExcludeTags are glob patterns such as "*-rc*" of release tags that are
+	// never selected as the latest release, even when marked stable.
+	ExcludeTags []string `yaml:"exclude_tags,omitempty"`
+	// SHA256 is the expected hex digest of the selected asset; installs fail on mismatch.
+	SHA256 string `yaml:"sha256,omitempty"`
+	// Provider selects a custom source provider instead of the GitHub API.
+	// URL is then passed to the provider as-is and need not be a GitHub URL.
+	Provider        string            `yaml:"provider,omitempty"`
+	ProviderOptions map[string]string `yaml:"provider_options,omitempty"`
+	// PostProcessors run after this repository is extracted, after the global ones.
+	PostProcessors []Hook `yaml:"post_processors,omitempty"`
+	// InstallCompletions copies shell completions and man pages found in the
+	// archive into the per-user completion and man directories.
+	InstallCompletions bool `yaml:"install_completions,omitempty"`
+	// Delta rebuilds new versions from the previously installed asset and a
+	// bsdiff patch when the release publishes one. It requires cache_dir.
+	Delta bool `yaml:"delta,omitempty"`
+	// BinariesOnly links only the executables named after the repository, or
+	// Binary, into bin_dir instead of every executable of the archive. The
+	// documentation and other files stay in output_dir.
+	BinariesOnly bool `yaml:"binaries_only,omitempty"`
+	// Binary is the name of the executable BinariesOnly links, when it is not
+	// named after the repository.
+	Binary string `yaml:"binary,omitempty"`
+	// Prefer chooses among several assets for the platform, such as full and
+	// minimal builds: "smallest", "largest" or "name:<substring>".
+	Prefer string `yaml:"prefer,omitempty"`
+	// Constraint installs the latest release whose version satisfies it,
+	// such as ">=1.4.0 <2.0.0" or "~1.6", to track a major version without
+	// taking breaking upgrades.
+	Constraint string `yaml:"constraint,omitempty"`
+	// HoldOnMajor keeps an installed repository at its version instead of
+	// upgrading it to a new major version (minor version before 1.0).
+	HoldOnMajor bool `yaml:"hold_on_major,omitempty"`
+	// HoldPatterns are regular expressions, matched case-insensitively
+	// against the tag, name and notes of a new release, that keep an
+	// installed repository at its version, e.g. "breaking".
+	HoldPatterns []string `yaml:"hold_patterns,omitempty"`
+	// Signature makes installs verify the asset against the signature
+	// published with it before extracting it.
+	Signature SignatureOptions `yaml:"signature,omitempty"`
+}
+
+// SignatureOptions select the signature the asset of a repository must carry.
+type SignatureOptions struct {
+	// Type is "cosign" or "gpg".
+	Type string `yaml:"type"`
+	// Key is the public key file: an OpenPGP key for gpg, a cosign public
+	// key for cosign signatures made with a key.
+	Key string `yaml:"key,omitempty"`
+	// Identity, a regular expression, and Issuer are the certificate identity
+	// and OIDC issuer of keyless cosign signatures.
+	Identity string `yaml:"identity,omitempty"`
+	Issuer   string `yaml:"issuer,omitempty"`
+	// Asset names the signature asset, "{asset}" standing for the name of
+	// the asset; the names the tool uses by default, such as
+	// "{asset}.sigstore.json" or "{asset}.asc", when empty.
+	Asset string `yaml:"asset,omitempty"`
+}
+
+func (s SignatureOptions) validate() error {
+	switch s.Type {
+	case "":
+		if s != (SignatureOptions{}) {
+			return fmt.Errorf("type is required")
+		}
+	case "gpg":
+		if s.Key == "" {
+			return fmt.Errorf("gpg requires key")
+		}
+	case "cosign":
+		if s.Key == "" && (s.Identity == "" || s.Issuer == "") {
+			return fmt.Errorf("cosign requires key, or identity and issuer")
+		}
+		if _, err := regexp.Compile(s.Identity); err != nil {
+			return fmt.Errorf("invalid identity: %w", err)
+		}
+	default:
+		return fmt.Errorf("type must be cosign or gpg")
+	}
+	return nil
 }
 
 func Load(cfgPath string) (*Config, error) {
-	data, err := os.ReadFile(cfgPath)
-	if err != nil {
-		return nil, fmt.Errorf("failed to read config file %q: %w", cfgPath, err)
+	return loadFiles([]configFile{{path: cfgPath}})
+}
+
+// LoadFS loads the config file at path in fsys, such as a file embedded with
+// go:embed.
+func LoadFS(fsys fs.FS, path string) (*Config, error) {
+	return loadFiles([]configFile{{path: path, fsys: fsys}})
+}
+
+// configFile is a config file to load, from fsys when set; relative output
+// and bin directories of files with a base are resolved against it.
+type configFile struct {
+	path, base string
+	fsys       fs.FS
+}
+
+func (f configFile) read() ([]byte, error) {
+	if f.fsys != nil {
+		return fs.ReadFile(f.fsys, f.path)
 	}
+	return os.ReadFile(f.path)
+}
 
-	var cfg Config
-	if err := yaml.Unmarshal(data, &cfg); err != nil {
-		return nil, fmt.Errorf("failed to parse config file %q: %w", cfgPath, err)
+// loadFiles loads files, each merged over the ones before it: the settings a
+// file sets replace those of earlier files, and its github list replaces
+// theirs.
+func loadFiles(files []configFile) (*Config, error) {
+	var (
+		cfg      Config
+		repoDoc  *yaml.Node
+		repoFile string
+	)
+	for _, f := range files {
+		data, err := f.read()
+		if err != nil {
+			return nil, fmt.Errorf("failed to read config file %q: %w", f.path, err)
+		}
+
+		// Decoding through a node keeps the lines of the repositories for error reports.
+		var doc yaml.Node
+		err = yaml.Unmarshal(data, &doc)
+		if err == nil && doc.Kind != 0 {
+			if schemaErr := toV1(&doc); schemaErr != nil {
+				schemaErr.File = f.path
+				schemaErr.Err = fmt.Errorf("invalid config file %q: %w", f.path, schemaErr.Err)
+				return nil, schemaErr
+			}
+			err = doc.Decode(&cfg)
+		}
+		if err != nil {
+			return nil, &Error{File: f.path, Line: yamlErrorLine(err), Err: fmt.Errorf("failed to parse config file %q: %w", f.path, err)}
+		}
+		if repoLines(&doc) != nil {
+			repoDoc, repoFile = &doc, f.path
+			if f.base != "" {
+				for i := range cfg.Github {
+					cfg.Github[i].OutputDir = resolveAgainst(f.base, cfg.Github[i].OutputDir)
+				}
+			}
+		}
+		if f.base != "" && hasKey(&doc, "bin_dir") {
+			cfg.BinDir = resolveAgainst(f.base, cfg.BinDir)
+		}
+		if f.base != "" && hasKey(&doc, "defaults") {
+			cfg.Defaults.OutputBaseDir = resolveAgainst(f.base, cfg.Defaults.OutputBaseDir)
+		}
+	}
+	cfg.applyAliases()
+	if repoDoc != nil {
+		cfg.applyDefaults(repoDoc)
 	}
 
 	if err := cfg.validate(); err != nil {
-		return nil, fmt.Errorf("invalid config: %w", err)
+		cfgErr := &Error{File: files[len(files)-1].path, Err: fmt.Errorf("invalid config: %w", err)}
+		var repoErr *repoIndexError
+		if errors.As(err, &repoErr) && repoDoc != nil {
+			cfgErr.File = repoFile
+			if lines := repoLines(repoDoc); repoErr.index < len(lines) {
+				cfgErr.Line = lines[repoErr.index]
+			}
+		}
+		return nil, cfgErr
 	}
 
 	cfg.normalize()
 	return &cfg, nil
 }
 
+// Error is an error in a config file. Line is 0 when it is not tied to a line.
+type Error struct {
+	File string
+	Line int
+	Err  error
+}
+
+func (e *Error) Error() string {
+	return e.Err.Error()
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// repoIndexError is a validation error of the repository at index.
+type repoIndexError struct {
+	index int
+	err   error
+}
+
+func (e *repoIndexError) Error() string {
+	return fmt.Sprintf("repository at index %d: %v", e.index, e.err)
+}
+
+func (e *repoIndexError) Unwrap() error {
+	return e.err
+}
+
+func repoError(index int, format string, args ...any) error {
+	return &repoIndexError{index: index, err: fmt.Errorf(format, args...)}
+}
+
+var yamlLinePattern = regexp.MustCompile(`line (\d+):`)
+
+// yamlErrorLine returns the first line number mentioned by a YAML error.
+func yamlErrorLine(err error) int {
+	m := yamlLinePattern.FindStringSubmatch(err.Error())
+	if m == nil {
+		return 0
+	}
+	line, _ := strconv.Atoi(m[1])
+	return line
+}
+
+// RepoLine returns the line declaring repo in the config file at cfgPath, or 0
+// when it cannot be found, to point error reports at it.
+func RepoLine(cfgPath string, repo Repo) int {
+	data, err := os.ReadFile(cfgPath)
+	if err != nil {
+		return 0
+	}
+	var doc yaml.Node
+	var cfg Config
+	if yaml.Unmarshal(data, &doc) != nil || doc.Kind == 0 || doc.Decode(&cfg) != nil {
+		return 0
+	}
+	cfg.normalize()
+
+	lines := repoLines(&doc)
+	for i, r := range cfg.Github {
+		if i < len(lines) && r.URL == repo.URL && r.OutputDir == repo.OutputDir {
+			return lines[i]
+		}
+	}
+	return 0
+}
+
+// repoLines returns the line of every item of the github list of doc.
+func repoLines(doc *yaml.Node) []int {
+	if doc.Kind != yaml.DocumentNode || len(doc.Content) == 0 {
+		return nil
+	}
+	root := doc.Content[0]
+	if root.Kind != yaml.MappingNode {
+		return nil
+	}
+	for i := 0; i+1 < len(root.Content); i += 2 {
+		if root.Content[i].Value != "github" || root.Content[i+1].Kind != yaml.SequenceNode {
+			continue
+		}
+		var lines []int
+		for _, item := range root.Content[i+1].Content {
+			lines = append(lines, item.Line)
+		}
+		return lines
+	}
+	return nil
+}
+
+// Validate checks c as LoadConfig does and normalizes it the same way, for
+// configs assembled in code, e.g. from command-line flags.
+func (c *Config) Validate() error {
+	c.applyAliases()
+	if err := c.validate(); err != nil {
+		return fmt.Errorf("invalid config: %w", err)
+	}
+	c.normalize()
+	return nil
+}
+
 func (c *Config) validate() error {
 	if len(c.Github) == 0 {
 		return fmt.Errorf("no GitHub repositories configured")
 	}
+	return c.validateSettings()
+}
 
+// validateSettings validates c except for having repositories.
+func (c *Config) validateSettings() error {
+	// Checked first, as the repositories inheriting an invalid default would
+	// report it as theirs.
+	if err := c.Defaults.validate(); err != nil {
+		return fmt.Errorf("defaults: %w", err)
+	}
 	for i, repo := range c.Github {
+		for j, other := range c.Github[:i] {
+			if strings.TrimSuffix(other.URL, "/") == strings.TrimSuffix(repo.URL, "/") && filepath.Clean(other.OutputDir) == filepath.Clean(repo.OutputDir) {
+				return repoError(i, "%s is already installed to %s by the repository at index %d; use a separate output_dir per version", repo.URL, repo.OutputDir, j)
+			}
+		}
 		if repo.URL == "" {
-			return fmt.Errorf("repository at index %d: URL is required", i)
+			return repoError(i, "URL is required")
+		}
+		for j, other := range c.Github[:i] {
+			if repo.Name != "" && strings.EqualFold(other.Name, repo.Name) {
+				return repoError(i, "name %q is already used by the repository at index %d", repo.Name, j)
+			}
 		}
 		if repo.OutputDir == "" {
-			return fmt.Errorf("repository at index %d: output_dir is required", i)
+			return repoError(i, "output_dir is required")
+		}
+		if repo.Provider == "" && !strings.HasPrefix(repo.URL, "https://github.com/") {
+			return repoError(i, "URL must be a GitHub repository URL")
+		}
+		if err := validateHooks(repo.PostProcessors); err != nil {
+			return repoError(i, "%w", err)
+		}
+		if repo.AssetPattern != "" {
+			if _, err := regexp.Compile(repo.AssetPattern); err != nil {
+				return repoError(i, "invalid asset_pattern: %w", err)
+			}
+		}
+		if repo.SHA256 != "" && !sha256Hex.MatchString(repo.SHA256) {
+			return repoError(i, "sha256 must be 64 hex characters")
+		}
+		switch repo.Channel {
+		case "", "stable", "prerelease", "nightly":
+		default:
+			return repoError(i, "channel must be stable, prerelease or nightly")
+		}
+		if repo.Tag != "" {
+			return repoError(i, "tag is an alias of version; set only one of them")
+		}
+		if repo.Channel != "" && repo.Version != "" {
+			return repoError(i, "channel and version are mutually exclusive")
+		}
+		if repo.Constraint != "" {
+			if repo.Version != "" {
+				return repoError(i, "constraint and version are mutually exclusive")
+			}
+			if _, err := constraint.Parse(repo.Constraint); err != nil {
+				return repoError(i, "%w", err)
+			}
+		}
+		for _, pattern := range repo.ExcludeTags {
+			if _, err := path.Match(pattern, ""); err != nil {
+				return repoError(i, "invalid exclude_tags pattern %q: %w", pattern, err)
+			}
+		}
+		for _, pattern := range repo.HoldPatterns {
+			if _, err := regexp.Compile(pattern); err != nil {
+				return repoError(i, "invalid hold_patterns pattern %q: %w", pattern, err)
+			}
+		}
+		if repo.Delta && c.CacheDir == "" {
+			return repoError(i, "delta requires cache_dir")
+		}
+		if repo.BinariesOnly && c.BinDir == "" {
+			return repoError(i, "binaries_only requires bin_dir")
+		}
+		if repo.Binary != "" && !repo.BinariesOnly {
+			return repoError(i, "binary requires binaries_only")
+		}
+		if !validPrefer(repo.Prefer) {
+			return repoError(i, "prefer must be smallest, largest or name:<substring>")
+		}
+		if err := repo.Signature.validate(); err != nil {
+			return repoError(i, "signature: %w", err)
+		}
+	}
+
+	if err := validateHooks(c.PostProcessors); err != nil {
+		return err
+	}
+
+	for i, p := range c.Providers {
+		if p.Name == "" {
+			return fmt.Errorf("provider at index %d: name is required", i)
+		}
+		if len(p.Command) == 0 {
+			return fmt.Errorf("provider %q: command is required", p.Name)
+		}
+	}
+
+	for _, t := range c.AssetTypePreference {
+		if !slices.ContainsFunc(archiveTypes, func(s string) bool { return strings.EqualFold(s, t) }) {
+			return fmt.Errorf("asset_type_preference: unsupported archive type %q, supported types are %s", t, strings.Join(archiveTypes, ", "))
+		}
+	}
+
+	switch c.ZipDuplicates {
+	case "", "last-wins", "first-wins", "error":
+	default:
+		return fmt.Errorf("zip_duplicates must be last-wins, first-wins or error")
+	}
+
+	switch c.Extractor {
+	case "", "go", "system":
+	default:
+		return fmt.Errorf("extractor must be go or system")
+	}
+	switch c.ExtractorIsolation {
+	case "":
+	case "bwrap", "unshare":
+		if c.Extractor != "system" {
+			return fmt.Errorf("extractor_isolation requires extractor: system")
 		}
-		if !strings.HasPrefix(repo.URL, "https://github.com/") {
-			return fmt.Errorf("repository at index %d: URL must be a GitHub repository URL", i)
+	default:
+		return fmt.Errorf("extractor_isolation must be bwrap or unshare")
+	}
+	if c.SandboxExtraction && c.Extractor == "system" {
+		return fmt.Errorf("sandbox_extraction cannot be used with extractor: system, which starts programs")
+	}
+	switch c.Attest.Format {
+	case "", "ssh", "minisign":
+	default:
+		return fmt.Errorf("attest.format must be ssh or minisign")
+	}
+	if c.Attest.Format != "" && c.Attest.Key == "" {
+		return fmt.Errorf("attest.format requires attest.key")
+	}
+
+	if c.DefaultFileMode > os.ModePerm {
+		return fmt.Errorf("default_file_mode must be an octal permission such as 0644")
+	}
+	if c.DefaultDirMode > os.ModePerm {
+		return fmt.Errorf("default_dir_mode must be an octal permission such as 0755")
+	}
+
+	if c.LockTimeout < 0 {
+		return fmt.Errorf("lock_timeout must not be negative")
+	}
+
+	if c.Timeouts.Connect < 0 || c.Timeouts.ResponseHeader < 0 || c.Timeouts.Idle < 0 {
+		return fmt.Errorf("timeouts must not be negative")
+	}
+
+	if c.ExtractLimits.MaxSize < 0 || c.ExtractLimits.MaxEntries < 0 || c.ExtractLimits.Timeout < 0 {
+		return fmt.Errorf("extract_limits must not be negative")
+	}
+
+	if c.MaxOpenFiles < 0 || c.MaxConnections < 0 {
+		return fmt.Errorf("max_open_files and max_connections must not be negative")
+	}
+
+	for _, arch := range c.WindowsEmulation {
+		if arch != "amd64" && arch != "386" {
+			return fmt.Errorf("windows_emulation: unsupported architecture %q, supported are amd64 and 386", arch)
 		}
 	}
 
+	if c.KeepAliveIdleTimeout < 0 {
+		return fmt.Errorf("keepalive_idle_timeout must not be negative")
+	}
+
+	if c.MaxRedirects < 0 {
+		return fmt.Errorf("max_redirects must not be negative")
+	}
+
+	if c.APIBudget < 0 {
+		return fmt.Errorf("api_budget must not be negative")
+	}
+
+	if c.MirrorOptions.SampleBytes < 0 {
+		return fmt.Errorf("mirror_options.sample_bytes must not be negative")
+	}
+
+	if c.MirrorURL != "" {
+		if u, err := url.Parse(c.MirrorURL); err != nil || (u.Scheme != "https" && u.Scheme != "http") || u.Host == "" || u.RawQuery != "" || u.Fragment != "" {
+			return fmt.Errorf("mirror_url must be an http(s) URL such as https://ghfast.top, got %q", c.MirrorURL)
+		}
+	}
+
+	if c.Mirror != "" {
+		if c.MirrorURL != "" {
+			return fmt.Errorf("mirror and mirror_url are mutually exclusive")
+		}
+		if _, ok := LookupMirror(c.Mirror); !ok {
+			return fmt.Errorf("unknown mirror %q, known mirrors are %s", c.Mirror, mirrorNames())
+		}
+	}
+
+	if strings.HasPrefix(c.CatalogURL, "http://") {
+		return fmt.Errorf("catalog_url must use https")
+	}
+
 	return nil
 }
 
+// archiveTypes are the asset name suffixes of the archives ghinstall extracts.
+var archiveTypes = []string{".tar.gz", ".tgz", ".zip"}
+
+var sha256Hex = regexp.MustCompile(`^[0-9a-fA-F]{64}$`)
+
+func (d RepoDefaults) validate() error {
+	if d.AssetPattern != "" {
+		if _, err := regexp.Compile(d.AssetPattern); err != nil {
+			return fmt.Errorf("invalid asset_pattern: %w", err)
+		}
+	}
+	if !validPrefer(d.Prefer) {
+		return fmt.Errorf("prefer must be smallest, largest or name:<substring>")
+	}
+	switch d.Channel {
+	case "", "stable", "prerelease", "nightly":
+	default:
+		return fmt.Errorf("channel must be stable, prerelease or nightly")
+	}
+	for _, pattern := range d.ExcludeTags {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return fmt.Errorf("invalid exclude_tags pattern %q: %w", pattern, err)
+		}
+	}
+	return nil
+}
+
+// validPrefer reports whether p is empty or a valid prefer setting.
+func validPrefer(p string) bool {
+	return p == "" || p == "smallest" || p == "largest" || strings.HasPrefix(p, "name:") && p != "name:"
+}
+
+func validateHooks(hooks []Hook) error {
+	for i, h := range hooks {
+		if len(h.Command) == 0 {
+			return fmt.Errorf("post_processors[%d]: command is required", i)
+		}
+	}
+	return nil
+}
+
+// applyDefaults gives every repository of the github list of doc the
+// defaults for the keys it does not set, so a repository can still turn off
+// a boolean default.
+// applyAliases moves the tag of repositories into their version. A tag next
+// to a version is kept for validate to report.
+func (c *Config) applyAliases() {
+	for i := range c.Github {
+		if repo := &c.Github[i]; repo.Version == "" {
+			repo.Version, repo.Tag = repo.Tag, ""
+		}
+	}
+}
+
+func (c *Config) applyDefaults(doc *yaml.Node) {
+	d := c.Defaults
+	for i := range c.Github {
+		repo := &c.Github[i]
+		node := repoNode(doc, i)
+		unset := func(key string) bool {
+			if node == nil {
+				return false
+			}
+			for j := 0; j+1 < len(node.Content); j += 2 {
+				if node.Content[j].Value == key {
+					return false
+				}
+			}
+			return true
+		}
+		if d.OutputBaseDir != "" && unset("output_dir") {
+			name := repo.Name
+			if name == "" {
+				name = path.Base(strings.TrimSuffix(repo.URL, "/"))
+			}
+			repo.OutputDir = filepath.Join(d.OutputBaseDir, name)
+		}
+		if d.AssetPattern != "" && unset("asset_pattern") {
+			repo.AssetPattern = d.AssetPattern
+		}
+		if d.Prefer != "" && unset("prefer") {
+			repo.Prefer = d.Prefer
+		}
+		// A repository pinned to a version has no channel to inherit.
+		if d.Channel != "" && unset("channel") && repo.Version == "" {
+			repo.Channel = d.Channel
+		}
+		if len(d.ExcludeTags) > 0 && unset("exclude_tags") {
+			repo.ExcludeTags = d.ExcludeTags
+		}
+		if d.InstallCompletions && unset("install_completions") {
+			repo.InstallCompletions = true
+		}
+		if d.Delta && unset("delta") {
+			repo.Delta = true
+		}
+		if d.BinariesOnly && unset("binaries_only") {
+			repo.BinariesOnly = true
+		}
+		if d.HoldOnMajor && unset("hold_on_major") {
+			repo.HoldOnMajor = true
+		}
+	}
+}
+
 func (c *Config) normalize() {
 	for i := range c.Github {
 		c.Github[i].OutputDir = filepath.Clean(c.Github[i].OutputDir)
 		c.Github[i].URL = strings.TrimSuffix(c.Github[i].URL, "/")
+		c.Github[i].SHA256 = strings.ToLower(c.Github[i].SHA256)
+		if key := c.Github[i].Signature.Key; key != "" {
+			c.Github[i].Signature.Key = filepath.Clean(expandHome(key))
+		}
 	}
 
 	if c.MirrorURL != "" {
 		c.MirrorURL = strings.TrimSuffix(c.MirrorURL, "/")
 	}
+
+	if c.CacheDir != "" && !isCacheKeyword(c.CacheDir) {
+		c.CacheDir = filepath.Clean(expandHome(c.CacheDir))
+	}
+
+	if c.BinDir != "" {
+		c.BinDir = filepath.Clean(expandHome(c.BinDir))
+	}
+
+	if c.Attest.Key != "" {
+		c.Attest.Key = filepath.Clean(expandHome(c.Attest.Key))
+	}
+}
+
+// expandHome replaces a leading "~" with the user's home directory.
+func expandHome(path string) string {
+	if path != "~" && !strings.HasPrefix(path, "~/") && !strings.HasPrefix(path, `~\`) {
+		return path
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return path
+	}
+	return filepath.Join(home, path[1:])
+}
+
+func isCacheKeyword(dir string) bool {
+	return strings.EqualFold(dir, "system") || strings.EqualFold(dir, "user")
+}
+
+// GetLockTimeout returns the configured lock timeout or the default.
+func (c *Config) GetLockTimeout() time.Duration {
+	if c.LockTimeout > 0 {
+		return c.LockTimeout
+	}
+	return DefaultLockTimeout
+}
+
+// GetMaxRedirects returns the configured redirect limit or the default.
+func (c *Config) GetMaxRedirects() int {
+	if c.MaxRedirects > 0 {
+		return c.MaxRedirects
+	}
+	return DefaultMaxRedirects
+}
+
+// SharedCache reports whether the cache must be usable by multiple users.
+func (c *Config) SharedCache() bool {
+	return c.CacheShared || strings.EqualFold(c.CacheDir, "system")
 }
 
 func (c *Config) GetDownloadURL(repoURL, assetURL string) string {
+	if p, ok := LookupMirror(c.Mirror); ok {
+		return p.DownloadURL(assetURL)
+	}
 	if c.MirrorURL == "" {
 		return assetURL
 	}
 	return c.MirrorURL + "/" + assetURL
 }
 
+// MirrorBase returns the URL of the configured mirror, from mirror_url or the
+// mirror preset, or "" without a mirror.
+func (c *Config) MirrorBase() string {
+	if p, ok := LookupMirror(c.Mirror); ok {
+		return p.URL
+	}
+	return c.MirrorURL
+}
+
+// DisplayName returns the name of the repository for logs and reports: its
+// configured name, or its URL.
+func (r Repo) DisplayName() string {
+	if r.Name != "" {
+		return r.Name
+	}
+	return r.URL
+}
+
+// Matches reports whether selector designates the repository: its name, its
+// "owner/repo" or its URL, compared case-insensitively.
+func (r Repo) Matches(selector string) bool {
+	selector = strings.TrimSuffix(selector, "/")
+	if r.Name != "" && strings.EqualFold(selector, r.Name) {
+		return true
+	}
+	if strings.EqualFold(selector, strings.TrimSuffix(r.URL, "/")) {
+		return true
+	}
+	ref, err := r.Ref()
+	return err == nil && strings.EqualFold(selector, ref.String())
+}
+
+// Select returns the repositories matching one of only (all of them when only
+// is empty) and none of skip. A selector matching no repository is an error,
+// so typos do not silently select nothing.
+func (c *Config) Select(only, skip []string) ([]Repo, error) {
+	for _, selector := range append(append([]string(nil), only...), skip...) {
+		if !slices.ContainsFunc(c.Github, func(r Repo) bool { return r.Matches(selector) }) {
+			return nil, fmt.Errorf("no configured repository matches %q", selector)
+		}
+	}
+
+	var selected []Repo
+	for _, repo := range c.Github {
+		if len(only) > 0 && !slices.ContainsFunc(only, repo.Matches) {
+			continue
+		}
+		if slices.ContainsFunc(skip, repo.Matches) {
+			continue
+		}
+		selected = append(selected, repo)
+	}
+	return selected, nil
+}
+
+// ParseRepoURL returns the owner and name of a github.com repository URL.
+// ParseRepoRef also parses the URLs of other hosts.
 func ParseRepoURL(repoURL string) (owner, repo string, err error) {
 	if !strings.HasPrefix(repoURL, "https://github.com/") {
 		return "", "", fmt.Errorf("invalid GitHub URL: %s", repoURL)
 	}
 
-	path := strings.TrimPrefix(repoURL, "https://github.com/")
-	parts := strings.Split(path, "/")
-	if len(parts) < 2 {
+	ref, err := ParseRepoRef(repoURL)
+	if err != nil {
 		return "", "", fmt.Errorf("invalid GitHub URL format: %s", repoURL)
 	}
 
-	return parts[0], parts[1], nil
-}
\ No newline at end of file
+	return ref.Owner, ref.Name, nil
+}
@@ -1,10 +1,13 @@
 package config
 
 import (
+	"errors"
 	"os"
 	"path/filepath"
 	"reflect"
 	"testing"
+	"testing/fstest"
+	"time"
 )
 
 func TestLoad(t *testing.T) {
@@ -70,6 +73,508 @@ mirror_url: "https://ghfast.top"`,
 			},
 			wantErr: false,
 		},
+		{
+			name: "cache settings",
+			content: `github:
+  - url: "https://github.com/sixban6/singgen"
+    output_dir: "/root"
+cache_dir: "/var/cache/ghinstall/"
+cache_shared: true
+lock_timeout: 90s`,
+			want: &Config{
+				Github: []Repo{
+					{
+						URL:       "https://github.com/sixban6/singgen",
+						OutputDir: "/root",
+					},
+				},
+				CacheDir:    "/var/cache/ghinstall",
+				CacheShared: true,
+				LockTimeout: 90 * time.Second,
+			},
+			wantErr: false,
+		},
+		{
+			name: "custom provider",
+			content: `github:
+  - url: "corp://tools/deployer"
+    output_dir: "/opt/deployer"
+    provider: corp
+    provider_options:
+      channel: prod
+providers:
+  - name: corp
+    command: ["/usr/local/bin/corp-releases", "--json"]`,
+			want: &Config{
+				Github: []Repo{
+					{
+						URL:             "corp://tools/deployer",
+						OutputDir:       "/opt/deployer",
+						Provider:        "corp",
+						ProviderOptions: map[string]string{"channel": "prod"},
+					},
+				},
+				Providers: []ProviderConfig{
+					{Name: "corp", Command: []string{"/usr/local/bin/corp-releases", "--json"}},
+				},
+			},
+			wantErr: false,
+		},
+		{
+			name: "provider without command",
+			content: `github:
+  - url: "https://github.com/sixban6/singgen"
+    output_dir: "/root"
+providers:
+  - name: corp`,
+			want:    nil,
+			wantErr: true,
+		},
+		{
+			name: "post processors",
+			content: `github:
+  - url: "https://github.com/sixban6/singgen"
+    output_dir: "/root"
+    post_processors:
+      - command: ["codesign", "-s", "-", "singgen"]
+post_processors:
+  - command: ["./fix-perms.sh"]`,
+			want: &Config{
+				Github: []Repo{
+					{
+						URL:            "https://github.com/sixban6/singgen",
+						OutputDir:      "/root",
+						PostProcessors: []Hook{{Command: []string{"codesign", "-s", "-", "singgen"}}},
+					},
+				},
+				PostProcessors: []Hook{{Command: []string{"./fix-perms.sh"}}},
+			},
+			wantErr: false,
+		},
+		{
+			name: "post processor without command",
+			content: `github:
+  - url: "https://github.com/sixban6/singgen"
+    output_dir: "/root"
+    post_processors:
+      - command: []`,
+			want:    nil,
+			wantErr: true,
+		},
+		{
+			name: "invalid asset pattern",
+			content: `github:
+  - url: "https://github.com/sixban6/singgen"
+    output_dir: "/root"
+    asset_pattern: "linux_(amd64"`,
+			want:    nil,
+			wantErr: true,
+		},
+		{
+			name: "nightly channel",
+			content: `github:
+  - url: "https://github.com/sixban6/singgen"
+    output_dir: "/root"
+    channel: nightly`,
+			want: &Config{
+				Github: []Repo{{URL: "https://github.com/sixban6/singgen", OutputDir: "/root", Channel: "nightly"}},
+			},
+			wantErr: false,
+		},
+		{
+			name: "negative max_redirects",
+			content: `github:
+  - url: "https://github.com/sixban6/singgen"
+    output_dir: "/root"
+max_redirects: -1`,
+			want:    nil,
+			wantErr: true,
+		},
+		{
+			name: "negative idle timeout",
+			content: `github:
+  - url: "https://github.com/sixban6/singgen"
+    output_dir: "/root"
+timeouts:
+  idle: -1s`,
+			want:    nil,
+			wantErr: true,
+		},
+		{
+			name: "negative extract limit",
+			content: `github:
+  - url: "https://github.com/sixban6/singgen"
+    output_dir: "/root"
+extract_limits:
+  max_entries: -1`,
+			want:    nil,
+			wantErr: true,
+		},
+		{
+			name: "negative connection limit",
+			content: `github:
+  - url: "https://github.com/sixban6/singgen"
+    output_dir: "/root"
+max_connections: -1`,
+			want:    nil,
+			wantErr: true,
+		},
+		{
+			name: "negative keepalive idle timeout",
+			content: `github:
+  - url: "https://github.com/sixban6/singgen"
+    output_dir: "/root"
+keepalive_idle_timeout: -1s`,
+			want:    nil,
+			wantErr: true,
+		},
+		{
+			name: "unknown channel",
+			content: `github:
+  - url: "https://github.com/sixban6/singgen"
+    output_dir: "/root"
+    channel: beta`,
+			want:    nil,
+			wantErr: true,
+		},
+		{
+			name: "channel with version",
+			content: `github:
+  - url: "https://github.com/sixban6/singgen"
+    output_dir: "/root"
+    channel: prerelease
+    version: v1.0.0`,
+			want:    nil,
+			wantErr: true,
+		},
+		{
+			name: "tag as version",
+			content: `github:
+  - url: "https://github.com/sixban6/singgen"
+    output_dir: "/root"
+    tag: v1.0.0`,
+			want: &Config{
+				Github: []Repo{{URL: "https://github.com/sixban6/singgen", OutputDir: "/root", Version: "v1.0.0"}},
+			},
+			wantErr: false,
+		},
+		{
+			name: "tag with version",
+			content: `github:
+  - url: "https://github.com/sixban6/singgen"
+    output_dir: "/root"
+    tag: v1.0.0
+    version: v1.0.0`,
+			want:    nil,
+			wantErr: true,
+		},
+		{
+			name: "channel with tag",
+			content: `github:
+  - url: "https://github.com/sixban6/singgen"
+    output_dir: "/root"
+    channel: prerelease
+    tag: v1.0.0`,
+			want:    nil,
+			wantErr: true,
+		},
+		{
+			name: "invalid exclude_tags pattern",
+			content: `github:
+  - url: "https://github.com/sixban6/singgen"
+    output_dir: "/root"
+    exclude_tags: ["v1.[0-"]`,
+			want:    nil,
+			wantErr: true,
+		},
+		{
+			name: "binaries_only without bin_dir",
+			content: `github:
+  - url: "https://github.com/sixban6/singgen"
+    output_dir: "/root"
+    binaries_only: true`,
+			want:    nil,
+			wantErr: true,
+		},
+		{
+			name: "binary without binaries_only",
+			content: `github:
+  - url: "https://github.com/sixban6/singgen"
+    output_dir: "/root"
+    binary: singgen`,
+			want:    nil,
+			wantErr: true,
+		},
+		{
+			name: "unsupported windows_emulation",
+			content: `github:
+  - url: "https://github.com/sixban6/singgen"
+    output_dir: "/root"
+windows_emulation: [arm]`,
+			want:    nil,
+			wantErr: true,
+		},
+		{
+			name: "negative api_budget",
+			content: `github:
+  - url: "https://github.com/sixban6/singgen"
+    output_dir: "/root"
+api_budget: -1`,
+			want:    nil,
+			wantErr: true,
+		},
+		{
+			name: "mirror_url without scheme",
+			content: `github:
+  - url: "https://github.com/sixban6/singgen"
+    output_dir: "/root"
+mirror_url: "ghfast.top"`,
+			want:    nil,
+			wantErr: true,
+		},
+		{
+			name: "mirror_url of another scheme",
+			content: `github:
+  - url: "https://github.com/sixban6/singgen"
+    output_dir: "/root"
+mirror_url: "ftp://ghfast.top"`,
+			want:    nil,
+			wantErr: true,
+		},
+		{
+			name: "catalog_url",
+			content: `github:
+  - url: "https://github.com/sixban6/singgen"
+    output_dir: "/root"
+catalog_url: "https://tools.example.com/catalog.yaml"`,
+			want: &Config{
+				Github:     []Repo{{URL: "https://github.com/sixban6/singgen", OutputDir: "/root"}},
+				CatalogURL: "https://tools.example.com/catalog.yaml",
+			},
+		},
+		{
+			name: "plain http catalog_url",
+			content: `github:
+  - url: "https://github.com/sixban6/singgen"
+    output_dir: "/root"
+catalog_url: "http://tools.example.com/catalog.yaml"`,
+			want:    nil,
+			wantErr: true,
+		},
+		{
+			name: "unsupported prefer",
+			content: `github:
+  - url: "https://github.com/sixban6/singgen"
+    output_dir: "/root"
+    prefer: fastest`,
+			want:    nil,
+			wantErr: true,
+		},
+		{
+			name: "defaults",
+			content: `defaults:
+  output_base_dir: /opt
+  prefer: smallest
+  channel: prerelease
+  delta: true
+  hold_on_major: true
+cache_dir: /var/cache/ghinstall
+github:
+  - url: "https://github.com/sixban6/singgen"
+  - url: "https://github.com/owner/tool"
+    name: other
+    output_dir: /srv/tool
+    prefer: largest
+    version: v1.0.0
+    delta: false
+    hold_on_major: false`,
+			want: &Config{
+				Github: []Repo{
+					{URL: "https://github.com/sixban6/singgen", OutputDir: "/opt/singgen", Prefer: "smallest", Channel: "prerelease", Delta: true, HoldOnMajor: true},
+					{URL: "https://github.com/owner/tool", Name: "other", OutputDir: "/srv/tool", Prefer: "largest", Version: "v1.0.0"},
+				},
+				CacheDir: "/var/cache/ghinstall",
+				Defaults: RepoDefaults{OutputBaseDir: "/opt", Prefer: "smallest", Channel: "prerelease", Delta: true, HoldOnMajor: true},
+			},
+			wantErr: false,
+		},
+		{
+			name: "invalid default channel",
+			content: `defaults:
+  channel: beta
+github:
+  - url: "https://github.com/sixban6/singgen"
+    output_dir: "/root"`,
+			want:    nil,
+			wantErr: true,
+		},
+		{
+			name: "constraint with version",
+			content: `github:
+  - url: "https://github.com/sixban6/singgen"
+    output_dir: "/root"
+    constraint: "~1.6"
+    version: v1.6.0`,
+			want:    nil,
+			wantErr: true,
+		},
+		{
+			name: "invalid constraint",
+			content: `github:
+  - url: "https://github.com/sixban6/singgen"
+    output_dir: "/root"
+    constraint: ">=1.x"`,
+			want:    nil,
+			wantErr: true,
+		},
+		{
+			name: "invalid hold pattern",
+			content: `github:
+  - url: "https://github.com/sixban6/singgen"
+    output_dir: "/root"
+    hold_patterns: ["(breaking"]`,
+			want:    nil,
+			wantErr: true,
+		},
+		{
+			name: "continue on error",
+			content: `continue_on_error: true
+github:
+  - url: "https://github.com/sixban6/singgen"
+    output_dir: "/root"`,
+			want: &Config{
+				Github:          []Repo{{URL: "https://github.com/sixban6/singgen", OutputDir: "/root"}},
+				ContinueOnError: true,
+			},
+			wantErr: false,
+		},
+		{
+			name: "cosign signature",
+			content: `github:
+  - url: "https://github.com/sixban6/singgen"
+    output_dir: "/root"
+    signature:
+      type: cosign
+      identity: "^https://github.com/sixban6/singgen/"
+      issuer: https://token.actions.githubusercontent.com`,
+			want: &Config{
+				Github: []Repo{{URL: "https://github.com/sixban6/singgen", OutputDir: "/root", Signature: SignatureOptions{
+					Type:     "cosign",
+					Identity: "^https://github.com/sixban6/singgen/",
+					Issuer:   "https://token.actions.githubusercontent.com",
+				}}},
+			},
+			wantErr: false,
+		},
+		{
+			name: "gpg signature without key",
+			content: `github:
+  - url: "https://github.com/sixban6/singgen"
+    output_dir: "/root"
+    signature: {type: gpg}`,
+			want:    nil,
+			wantErr: true,
+		},
+		{
+			name: "signature without type",
+			content: `github:
+  - url: "https://github.com/sixban6/singgen"
+    output_dir: "/root"
+    signature: {key: /etc/keys/singgen.pub}`,
+			want:    nil,
+			wantErr: true,
+		},
+		{
+			name: "keyless cosign signature without issuer",
+			content: `github:
+  - url: "https://github.com/sixban6/singgen"
+    output_dir: "/root"
+    signature: {type: cosign, identity: "^https://github.com/"}`,
+			want:    nil,
+			wantErr: true,
+		},
+		{
+			name: "asset type preference",
+			content: `github:
+  - url: "https://github.com/sixban6/singgen"
+    output_dir: "/root"
+asset_type_preference: [".zip", ".tar.gz"]`,
+			want: &Config{
+				Github:              []Repo{{URL: "https://github.com/sixban6/singgen", OutputDir: "/root"}},
+				AssetTypePreference: []string{".zip", ".tar.gz"},
+			},
+			wantErr: false,
+		},
+		{
+			name: "unsupported asset type preference",
+			content: `github:
+  - url: "https://github.com/sixban6/singgen"
+    output_dir: "/root"
+asset_type_preference: [".tar.zst", ".tar.gz"]`,
+			want:    nil,
+			wantErr: true,
+		},
+		{
+			name: "unknown zip_duplicates policy",
+			content: `github:
+  - url: "https://github.com/sixban6/singgen"
+    output_dir: "/root"
+zip_duplicates: newest`,
+			want:    nil,
+			wantErr: true,
+		},
+		{
+			name: "default modes",
+			content: `github:
+  - url: "https://github.com/sixban6/singgen"
+    output_dir: "/root"
+default_file_mode: 0640
+default_dir_mode: 0750`,
+			want: &Config{
+				Github:          []Repo{{URL: "https://github.com/sixban6/singgen", OutputDir: "/root"}},
+				DefaultFileMode: 0640,
+				DefaultDirMode:  0750,
+			},
+			wantErr: false,
+		},
+		{
+			name: "decimal default mode",
+			content: `github:
+  - url: "https://github.com/sixban6/singgen"
+    output_dir: "/root"
+default_file_mode: 644`,
+			want:    nil,
+			wantErr: true,
+		},
+		{
+			name: "same repo side by side",
+			content: `github:
+  - url: "https://github.com/hashicorp/terraform"
+    output_dir: "/opt/terraform-1.5"
+    version: v1.5.7
+  - url: "https://github.com/hashicorp/terraform"
+    output_dir: "/opt/terraform-1.9"
+    version: v1.9.0`,
+			want: &Config{
+				Github: []Repo{
+					{URL: "https://github.com/hashicorp/terraform", OutputDir: "/opt/terraform-1.5", Version: "v1.5.7"},
+					{URL: "https://github.com/hashicorp/terraform", OutputDir: "/opt/terraform-1.9", Version: "v1.9.0"},
+				},
+			},
+			wantErr: false,
+		},
+		{
+			name: "same repo twice in one output_dir",
+			content: `github:
+  - url: "https://github.com/hashicorp/terraform"
+    output_dir: "/opt/terraform"
+    version: v1.5.7
+  - url: "https://github.com/hashicorp/terraform/"
+    output_dir: "/opt/terraform/"
+    version: v1.9.0`,
+			want:    nil,
+			wantErr: true,
+		},
 		{
 			name: "empty github list",
 			content: `github: []
@@ -99,6 +604,81 @@ mirror_url: "https://ghfast.top"`,
 			want:    nil,
 			wantErr: true,
 		},
+		{
+			name: "duplicate name",
+			content: `github:
+  - url: "https://github.com/sixban6/singgen"
+    output_dir: "/opt/a"
+    name: tool
+  - url: "https://github.com/sixban6/other"
+    output_dir: "/opt/b"
+    name: Tool`,
+			want:    nil,
+			wantErr: true,
+		},
+		{
+			name: "unknown mirror preset",
+			content: `github:
+  - url: "https://github.com/sixban6/singgen"
+    output_dir: "/root"
+mirror: fastgit`,
+			want:    nil,
+			wantErr: true,
+		},
+		{
+			name: "mirror preset and mirror_url",
+			content: `github:
+  - url: "https://github.com/sixban6/singgen"
+    output_dir: "/root"
+mirror: ghfast
+mirror_url: "https://ghfast.top"`,
+			want:    nil,
+			wantErr: true,
+		},
+		{
+			name: "mirror preset",
+			content: `github:
+  - url: "https://github.com/sixban6/singgen"
+    output_dir: "/root"
+mirror: tuna`,
+			want: &Config{
+				Github: []Repo{{URL: "https://github.com/sixban6/singgen", OutputDir: "/root"}},
+				Mirror: "tuna",
+			},
+		},
+		{
+			name: "attest key",
+			content: `github:
+  - url: "https://github.com/sixban6/singgen"
+    output_dir: "/root"
+attest:
+  key: /etc/ghinstall/attest_ed25519`,
+			want: &Config{
+				Github: []Repo{{URL: "https://github.com/sixban6/singgen", OutputDir: "/root"}},
+				Attest: AttestOptions{Key: "/etc/ghinstall/attest_ed25519"},
+			},
+		},
+		{
+			name: "attest format without key",
+			content: `github:
+  - url: "https://github.com/sixban6/singgen"
+    output_dir: "/root"
+attest:
+  format: minisign`,
+			want:    nil,
+			wantErr: true,
+		},
+		{
+			name: "unknown attest format",
+			content: `github:
+  - url: "https://github.com/sixban6/singgen"
+    output_dir: "/root"
+attest:
+  key: /etc/ghinstall/attest.key
+  format: gpg`,
+			want:    nil,
+			wantErr: true,
+		},
 	}
 
 	for _, tt := range tests {
@@ -118,6 +698,86 @@ mirror_url: "https://ghfast.top"`,
 	}
 }
 
+func TestLoadFS(t *testing.T) {
+	fsys := fstest.MapFS{
+		"tools/ghinstall.yaml": {Data: []byte(`github:
+  - url: "https://github.com/sixban6/singgen"
+    output_dir: "/opt/singgen"`)},
+	}
+	cfg, err := LoadFS(fsys, "tools/ghinstall.yaml")
+	if err != nil {
+		t.Fatalf("LoadFS() error = %v", err)
+	}
+	want := []Repo{{URL: "https://github.com/sixban6/singgen", OutputDir: "/opt/singgen"}}
+	if !reflect.DeepEqual(cfg.Github, want) {
+		t.Errorf("LoadFS() repos = %+v, want %+v", cfg.Github, want)
+	}
+
+	if _, err := LoadFS(fsys, "missing.yaml"); err == nil {
+		t.Error("LoadFS() of a missing file should fail")
+	}
+}
+
+func TestLoad_ErrorLine(t *testing.T) {
+	tests := []struct {
+		name     string
+		content  string
+		wantLine int
+	}{
+		{
+			name: "invalid repository",
+			content: `mirror_url: "https://ghfast.top"
+github:
+  - url: "https://github.com/owner/ok"
+    output_dir: "/opt/ok"
+  - url: "https://gitlab.com/owner/bad"
+    output_dir: "/opt/bad"`,
+			wantLine: 5,
+		},
+		{
+			name: "syntax error",
+			content: `github:
+  - url: "https://github.com/owner/ok"
+    output_dir: [`,
+			wantLine: 3,
+		},
+		{
+			name:     "not tied to a line",
+			content:  `lock_timeout: -1s`,
+			wantLine: 0,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := createTempConfigFile(t, tt.content)
+			_, err := Load(path)
+			var cfgErr *Error
+			if !errors.As(err, &cfgErr) {
+				t.Fatalf("Load() error = %v, want *Error", err)
+			}
+			if cfgErr.File != path || cfgErr.Line != tt.wantLine {
+				t.Errorf("Load() error at %s:%d, want %s:%d", cfgErr.File, cfgErr.Line, path, tt.wantLine)
+			}
+		})
+	}
+}
+
+func TestRepoLine(t *testing.T) {
+	path := createTempConfigFile(t, `github:
+  - url: "https://github.com/owner/a"
+    output_dir: "/opt/a"
+  - url: "https://github.com/owner/b/"
+    output_dir: "/opt/b/"`)
+
+	if got := RepoLine(path, Repo{URL: "https://github.com/owner/b", OutputDir: "/opt/b"}); got != 4 {
+		t.Errorf("RepoLine() = %d, want 4", got)
+	}
+	if got := RepoLine(path, Repo{URL: "https://github.com/owner/c", OutputDir: "/opt/c"}); got != 0 {
+		t.Errorf("RepoLine(unknown) = %d, want 0", got)
+	}
+}
+
 func TestConfig_GetDownloadURL(t *testing.T) {
 	tests := []struct {
 		name      string
@@ -135,6 +795,33 @@ func TestConfig_GetDownloadURL(t *testing.T) {
 			assetURL: "https://github.com/owner/repo/releases/download/v1.0.0/app.tar.gz",
 			want:     "https://ghfast.top/https://github.com/owner/repo/releases/download/v1.0.0/app.tar.gz",
 		},
+		{
+			name: "proxy preset",
+			config: &Config{
+				Mirror: "ghproxy",
+			},
+			repoURL:  "https://github.com/owner/repo",
+			assetURL: "https://github.com/owner/repo/releases/download/v1.0.0/app.tar.gz",
+			want:     "https://ghproxy.net/https://github.com/owner/repo/releases/download/v1.0.0/app.tar.gz",
+		},
+		{
+			name: "release layout preset",
+			config: &Config{
+				Mirror: "tuna",
+			},
+			repoURL:  "https://github.com/owner/repo",
+			assetURL: "https://github.com/owner/repo/releases/download/v1.0.0/app.tar.gz",
+			want:     "https://mirrors.tuna.tsinghua.edu.cn/github-release/owner/repo/v1.0.0/app.tar.gz",
+		},
+		{
+			name: "release layout preset with other URL",
+			config: &Config{
+				Mirror: "tuna",
+			},
+			repoURL:  "https://github.com/owner/repo",
+			assetURL: "https://example.com/app.tar.gz",
+			want:     "https://example.com/app.tar.gz",
+		},
 		{
 			name: "without mirror",
 			config: &Config{
@@ -156,6 +843,45 @@ func TestConfig_GetDownloadURL(t *testing.T) {
 	}
 }
 
+func TestConfig_SharedCache(t *testing.T) {
+	tests := []struct {
+		config *Config
+		want   bool
+	}{
+		{&Config{CacheDir: "system"}, true},
+		{&Config{CacheDir: "user"}, false},
+		{&Config{CacheDir: "/srv/cache", CacheShared: true}, true},
+		{&Config{CacheDir: "/srv/cache"}, false},
+	}
+
+	for _, tt := range tests {
+		if got := tt.config.SharedCache(); got != tt.want {
+			t.Errorf("Config{CacheDir: %q, CacheShared: %v}.SharedCache() = %v, want %v",
+				tt.config.CacheDir, tt.config.CacheShared, got, tt.want)
+		}
+	}
+}
+
+func TestLoad_ExpandsHome(t *testing.T) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		t.Skip("no home directory")
+	}
+
+	tmpFile := createTempConfigFile(t, `github:
+  - url: "https://github.com/sixban6/singgen"
+    output_dir: "/root"
+bin_dir: "~/.ghinstall/bin/"`)
+
+	cfg, err := Load(tmpFile)
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if want := filepath.Join(home, ".ghinstall", "bin"); cfg.BinDir != want {
+		t.Errorf("Load() BinDir = %s, want %s", cfg.BinDir, want)
+	}
+}
+
 func TestParseRepoURL(t *testing.T) {
 	tests := []struct {
 		name      string
@@ -218,6 +944,46 @@ func TestParseRepoURL(t *testing.T) {
 	}
 }
 
+func TestConfig_Select(t *testing.T) {
+	cfg := &Config{Github: []Repo{
+		{URL: "https://github.com/cli/cli", OutputDir: "/opt/gh", Name: "gh"},
+		{URL: "https://github.com/BurntSushi/ripgrep", OutputDir: "/opt/rg"},
+		{URL: "https://github.com/sharkdp/fd", OutputDir: "/opt/fd"},
+	}}
+
+	tests := []struct {
+		name    string
+		only    []string
+		skip    []string
+		want    []string
+		wantErr bool
+	}{
+		{name: "all", want: []string{"/opt/gh", "/opt/rg", "/opt/fd"}},
+		{name: "only by name and owner/repo", only: []string{"gh", "burntsushi/ripgrep"}, want: []string{"/opt/gh", "/opt/rg"}},
+		{name: "only by url", only: []string{"https://github.com/sharkdp/fd/"}, want: []string{"/opt/fd"}},
+		{name: "skip", skip: []string{"GH"}, want: []string{"/opt/rg", "/opt/fd"}},
+		{name: "only and skip", only: []string{"gh", "sharkdp/fd"}, skip: []string{"sharkdp/fd"}, want: []string{"/opt/gh"}},
+		{name: "unknown selector", only: []string{"cli"}, wantErr: true},
+		{name: "unknown skip", skip: []string{"owner/nope"}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := cfg.Select(tt.only, tt.skip)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Select() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			var dirs []string
+			for _, r := range got {
+				dirs = append(dirs, r.OutputDir)
+			}
+			if !tt.wantErr && !reflect.DeepEqual(dirs, tt.want) {
+				t.Errorf("Select() = %v, want %v", dirs, tt.want)
+			}
+		})
+	}
+}
+
 func createTempConfigFile(t *testing.T, content string) string {
 	tmpDir := t.TempDir()
 	tmpFile := filepath.Join(tmpDir, "config.yaml")
@@ -227,4 +993,21 @@ func createTempConfigFile(t *testing.T, content string) string {
 	}
 	
 	return tmpFile
-}
\ No newline at end of file
+}
+func TestConfig_Validate(t *testing.T) {
+	cfg := &Config{
+		Github:    []Repo{{URL: "https://github.com/owner/repo/", OutputDir: "/opt/repo/"}},
+		MirrorURL: "https://mirror.example/",
+	}
+	if err := cfg.Validate(); err != nil {
+		t.Fatalf("Validate() error = %v", err)
+	}
+	if got := cfg.GetDownloadURL(cfg.Github[0].URL, "https://github.com/a"); got != "https://mirror.example/https://github.com/a" {
+		t.Errorf("GetDownloadURL() = %s after Validate()", got)
+	}
+
+	cfg.MirrorURL = "mirror.example"
+	if err := cfg.Validate(); err == nil {
+		t.Error("Validate() accepted a mirror_url without scheme")
+	}
+}
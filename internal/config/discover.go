@@ -0,0 +1,117 @@
+package config
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+
+	"gopkg.in/yaml.v3"
+)
+
+// ProjectFileName is the name of the config file a project declares its
+// tools in, found in the working directory or one of its ancestors.
+const ProjectFileName = ".ghinstall.yaml"
+
+// ErrNoConfig is returned by LoadDiscovered when no config file applies.
+var ErrNoConfig = errors.New("no config file found")
+
+// GlobalPath returns the path of the per-user config file,
+// $XDG_CONFIG_HOME/ghinstall/config.yaml or ~/.config/ghinstall/config.yaml,
+// whether or not it exists.
+func GlobalPath() string {
+	dir := os.Getenv("XDG_CONFIG_HOME")
+	if dir == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return ""
+		}
+		dir = filepath.Join(home, ".config")
+	}
+	return filepath.Join(dir, "ghinstall", "config.yaml")
+}
+
+// Discover returns the config files applying to dir, like git does: the
+// global config when it exists, then the ProjectFileName nearest to dir.
+func Discover(dir string) []string {
+	var paths []string
+	if global := GlobalPath(); global != "" && isFile(global) {
+		paths = append(paths, global)
+	}
+	if project := findProject(dir); project != "" && (len(paths) == 0 || !sameFile(project, paths[0])) {
+		paths = append(paths, project)
+	}
+	return paths
+}
+
+// LoadDiscovered loads the config files Discover finds for dir, the project
+// file merged over the global one: the settings it sets replace the global
+// ones and its github list replaces the global list. Relative output_dir and
+// bin_dir of the project file are relative to its directory, so installs work
+// from anywhere in the project. It returns the files loaded, or ErrNoConfig.
+func LoadDiscovered(dir string) (*Config, []string, error) {
+	paths := Discover(dir)
+	if len(paths) == 0 {
+		return nil, nil, ErrNoConfig
+	}
+	files := make([]configFile, len(paths))
+	for i, path := range paths {
+		files[i] = configFile{path: path}
+		if filepath.Base(path) == ProjectFileName {
+			files[i].base = filepath.Dir(path)
+		}
+	}
+	cfg, err := loadFiles(files)
+	return cfg, paths, err
+}
+
+// findProject returns the ProjectFileName in dir or its nearest ancestor.
+func findProject(dir string) string {
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return ""
+	}
+	for {
+		path := filepath.Join(dir, ProjectFileName)
+		if isFile(path) {
+			return path
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return ""
+		}
+		dir = parent
+	}
+}
+
+func isFile(path string) bool {
+	info, err := os.Stat(path)
+	return err == nil && info.Mode().IsRegular()
+}
+
+func sameFile(a, b string) bool {
+	ia, errA := os.Stat(a)
+	ib, errB := os.Stat(b)
+	return errA == nil && errB == nil && os.SameFile(ia, ib)
+}
+
+// resolveAgainst returns path relative to base when it is relative.
+func resolveAgainst(base, path string) string {
+	if path == "" || filepath.IsAbs(path) || path == "~" || len(path) > 1 && path[0] == '~' && os.IsPathSeparator(path[1]) {
+		return path
+	}
+	return filepath.Join(base, path)
+}
+
+// hasKey reports whether the top-level mapping of doc sets key.
+func hasKey(doc *yaml.Node, key string) bool {
+	if doc.Kind != yaml.DocumentNode || len(doc.Content) == 0 || doc.Content[0].Kind != yaml.MappingNode {
+		return false
+	}
+	root := doc.Content[0]
+	for i := 0; i+1 < len(root.Content); i += 2 {
+		if root.Content[i].Value == key {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,80 @@
+package config
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestLoadDiscovered(t *testing.T) {
+	root := t.TempDir()
+	t.Setenv("XDG_CONFIG_HOME", filepath.Join(root, "config"))
+	project := filepath.Join(root, "project")
+	sub := filepath.Join(project, "src", "pkg")
+	if err := os.MkdirAll(sub, 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, _, err := LoadDiscovered(sub); !errors.Is(err, ErrNoConfig) {
+		t.Fatalf("LoadDiscovered() without config files error = %v, want ErrNoConfig", err)
+	}
+
+	write := func(path, content string) {
+		t.Helper()
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write(GlobalPath(), `mirror_url: "https://mirror.example"
+lock_timeout: 1m
+github:
+  - url: "https://github.com/owner/global"
+    output_dir: "/opt/global"
+`)
+
+	cfg, files, err := LoadDiscovered(sub)
+	if err != nil {
+		t.Fatalf("LoadDiscovered() error = %v", err)
+	}
+	if len(files) != 1 || len(cfg.Github) != 1 || cfg.Github[0].URL != "https://github.com/owner/global" {
+		t.Fatalf("LoadDiscovered() = %+v from %v, want the global config", cfg.Github, files)
+	}
+
+	write(filepath.Join(project, ProjectFileName), `lock_timeout: 2m
+bin_dir: .bin
+github:
+  - url: "https://github.com/owner/tool"
+    output_dir: "tools/tool"
+`)
+	cfg, files, err = LoadDiscovered(sub)
+	if err != nil {
+		t.Fatalf("LoadDiscovered() error = %v", err)
+	}
+	if len(files) != 2 || files[1] != filepath.Join(project, ProjectFileName) {
+		t.Errorf("files = %v, want the global and the project config", files)
+	}
+	if cfg.MirrorURL != "https://mirror.example" || cfg.LockTimeout.String() != "2m0s" {
+		t.Errorf("settings = %q, %v, want the global mirror and the project lock timeout", cfg.MirrorURL, cfg.LockTimeout)
+	}
+	if len(cfg.Github) != 1 || cfg.Github[0].OutputDir != filepath.Join(project, "tools", "tool") {
+		t.Errorf("repositories = %+v, want the project's, relative to it", cfg.Github)
+	}
+	if cfg.BinDir != filepath.Join(project, ".bin") {
+		t.Errorf("bin_dir = %q, want it relative to the project", cfg.BinDir)
+	}
+
+	write(filepath.Join(project, ProjectFileName), `github:
+  - url: "https://github.com/owner/tool"
+    output_dir: "tools/tool"
+    channel: beta
+`)
+	_, _, err = LoadDiscovered(sub)
+	var cfgErr *Error
+	if !errors.As(err, &cfgErr) || cfgErr.File != filepath.Join(project, ProjectFileName) || cfgErr.Line != 2 {
+		t.Errorf("LoadDiscovered() error = %#v, want it at line 2 of the project config", err)
+	}
+}
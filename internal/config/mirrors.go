@@ -0,0 +1,57 @@
+package config
+
+import (
+	"slices"
+	"strings"
+)
+
+// MirrorPreset is a public GitHub mirror selectable by name with mirror,
+// mainly for users in mainland China where github.com is slow or blocked.
+type MirrorPreset struct {
+	Name        string
+	URL         string
+	Description string
+	// ReleaseLayout marks mirrors serving assets as
+	// <URL>/<owner>/<repo>/<tag>/<asset> instead of proxying
+	// <URL>/<asset URL>. They carry only the projects they choose to mirror.
+	ReleaseLayout bool
+}
+
+// MirrorPresets are the known mirrors. They are never selected automatically.
+var MirrorPresets = []MirrorPreset{
+	{Name: "ghproxy", URL: "https://ghproxy.net", Description: "GitHub proxy (ghproxy.net)"},
+	{Name: "ghfast", URL: "https://ghfast.top", Description: "GitHub proxy (ghfast.top)"},
+	{Name: "tuna", URL: "https://mirrors.tuna.tsinghua.edu.cn/github-release", Description: "Tsinghua University release mirror, selected projects only", ReleaseLayout: true},
+}
+
+// LookupMirror returns the preset called name.
+func LookupMirror(name string) (MirrorPreset, bool) {
+	i := slices.IndexFunc(MirrorPresets, func(p MirrorPreset) bool { return strings.EqualFold(p.Name, name) })
+	if i < 0 {
+		return MirrorPreset{}, false
+	}
+	return MirrorPresets[i], true
+}
+
+// mirrorNames returns the names of the presets, for error messages.
+func mirrorNames() string {
+	names := make([]string, len(MirrorPresets))
+	for i, p := range MirrorPresets {
+		names[i] = p.Name
+	}
+	return strings.Join(names, ", ")
+}
+
+// DownloadURL returns the URL under which the mirror serves assetURL.
+func (p MirrorPreset) DownloadURL(assetURL string) string {
+	if !p.ReleaseLayout {
+		return p.URL + "/" + assetURL
+	}
+	// https://github.com/<owner>/<repo>/releases/download/<tag>/<asset>
+	rest, ok := strings.CutPrefix(assetURL, "https://github.com/")
+	parts := strings.SplitN(rest, "/", 6)
+	if !ok || len(parts) != 6 || parts[2] != "releases" || parts[3] != "download" {
+		return assetURL
+	}
+	return strings.Join([]string{p.URL, parts[0], parts[1], parts[4], parts[5]}, "/")
+}
@@ -0,0 +1,124 @@
+package config
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"gopkg.in/yaml.v3"
+)
+
+// SetVersion pins the repository at index of the github list of the config
+// file at cfgPath to version, or removes its pin when version is "", whether
+// set as version or tag. The rest of the file is kept, comments included,
+// though re-encoding normalizes its indentation. The file is replaced
+// atomically.
+func SetVersion(cfgPath string, index int, version string) error {
+	data, err := os.ReadFile(cfgPath)
+	if err != nil {
+		return fmt.Errorf("failed to read config file %q: %w", cfgPath, err)
+	}
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return fmt.Errorf("failed to parse config file %q: %w", cfgPath, err)
+	}
+
+	repo := repoNode(&doc, index)
+	if repo == nil {
+		return fmt.Errorf("config file %q has no repository at index %d", cfgPath, index)
+	}
+	// A tag, the alias of version, would conflict with the new pin.
+	setKey(repo, "tag", "")
+	setKey(repo, "version", version)
+
+	var buf bytes.Buffer
+	enc := yaml.NewEncoder(&buf)
+	enc.SetIndent(2)
+	if err := enc.Encode(&doc); err != nil {
+		return fmt.Errorf("failed to encode config file %q: %w", cfgPath, err)
+	}
+	enc.Close()
+	return replaceFile(cfgPath, buf.Bytes())
+}
+
+// repoNode returns the mapping of the repository at index of the github list
+// of doc, or nil.
+func repoNode(doc *yaml.Node, index int) *yaml.Node {
+	if doc.Kind != yaml.DocumentNode || len(doc.Content) == 0 {
+		return nil
+	}
+	root := doc.Content[0]
+	if root.Kind != yaml.MappingNode {
+		return nil
+	}
+	for i := 0; i+1 < len(root.Content); i += 2 {
+		list := root.Content[i+1]
+		if root.Content[i].Value != "github" || list.Kind != yaml.SequenceNode {
+			continue
+		}
+		if index < 0 || index >= len(list.Content) || list.Content[index].Kind != yaml.MappingNode {
+			return nil
+		}
+		return list.Content[index]
+	}
+	return nil
+}
+
+// setKey sets key of mapping to value, adding it after the url when missing,
+// or removes key when value is "".
+func setKey(mapping *yaml.Node, key, value string) {
+	for i := 0; i+1 < len(mapping.Content); i += 2 {
+		if mapping.Content[i].Value != key {
+			continue
+		}
+		if value == "" {
+			mapping.Content = append(mapping.Content[:i], mapping.Content[i+2:]...)
+			return
+		}
+		mapping.Content[i+1].SetString(value)
+		return
+	}
+	if value == "" {
+		return
+	}
+
+	var v yaml.Node
+	v.SetString(value)
+	pair := []*yaml.Node{{Kind: yaml.ScalarNode, Value: key}, &v}
+	at := len(mapping.Content)
+	for i := 0; i+1 < len(mapping.Content); i += 2 {
+		if mapping.Content[i].Value == "url" {
+			at = i + 2
+		}
+	}
+	mapping.Content = append(mapping.Content[:at], append(pair, mapping.Content[at:]...)...)
+}
+
+// replaceFile replaces path with data, keeping its permissions.
+func replaceFile(path string, data []byte) error {
+	info, err := os.Stat(path)
+	if err != nil {
+		return fmt.Errorf("failed to write config file %q: %w", path, err)
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".config-*")
+	if err != nil {
+		return fmt.Errorf("failed to write config file %q: %w", path, err)
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write config file %q: %w", path, err)
+	}
+	if err := tmp.Chmod(info.Mode().Perm()); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write config file %q: %w", path, err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to write config file %q: %w", path, err)
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return fmt.Errorf("failed to write config file %q: %w", path, err)
+	}
+	return nil
+}
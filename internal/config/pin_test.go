@@ -0,0 +1,58 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+func TestSetVersion(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "ghinstall.yaml")
+	content := `# tools of the build image
+github:
+  - url: "https://github.com/owner/tool"
+    output_dir: "/opt/tool" # keep in sync with the Dockerfile
+  - url: "https://github.com/owner/other"
+    output_dir: "/opt/other"
+    version: v0.9.0
+  - url: "https://github.com/owner/third"
+    output_dir: "/opt/third"
+    tag: v0.1.0
+`
+	if err := os.WriteFile(path, []byte(content), 0640); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := SetVersion(path, 0, "v1.2.0"); err != nil {
+		t.Fatalf("SetVersion() error = %v", err)
+	}
+	if err := SetVersion(path, 1, ""); err != nil {
+		t.Fatalf("SetVersion() error = %v", err)
+	}
+	if err := SetVersion(path, 2, "v0.2.0"); err != nil {
+		t.Fatalf("SetVersion() of a tag error = %v", err)
+	}
+	if err := SetVersion(path, 3, "v1.0.0"); err == nil {
+		t.Error("SetVersion() of a missing repository should fail")
+	}
+
+	cfg, err := Load(path)
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if cfg.Github[0].Version != "v1.2.0" || cfg.Github[1].Version != "" || cfg.Github[2].Version != "v0.2.0" {
+		t.Errorf("versions = %q, %q, %q, want v1.2.0, none and v0.2.0", cfg.Github[0].Version, cfg.Github[1].Version, cfg.Github[2].Version)
+	}
+
+	data, _ := os.ReadFile(path)
+	for _, comment := range []string{"# tools of the build image", "# keep in sync with the Dockerfile"} {
+		if !strings.Contains(string(data), comment) {
+			t.Errorf("comment %q lost:\n%s", comment, data)
+		}
+	}
+	if info, _ := os.Stat(path); runtime.GOOS != "windows" && info.Mode().Perm() != 0640 {
+		t.Errorf("mode = %v, want 0640", info.Mode().Perm())
+	}
+}
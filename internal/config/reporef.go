@@ -0,0 +1,79 @@
+package config
+
+import (
+	"fmt"
+	"strings"
+)
+
+// GitHubHost is the host of repositories on github.com, the default of
+// RepoRef.Host.
+const GitHubHost = "github.com"
+
+// RepoRef identifies a repository on a forge: github.com by default, or a
+// GitHub Enterprise server.
+type RepoRef struct {
+	// Host is the forge's host name; "" stands for GitHubHost.
+	Host  string
+	Owner string
+	Name  string
+}
+
+// ParseRepoRef parses a repository URL such as "https://github.com/cli/cli",
+// with or without a trailing slash, ".git" or further path, or the forms
+// RepoRef.String returns: "owner/name" on github.com and "host/owner/name"
+// elsewhere. The Host of refs on github.com is GitHubHost.
+func ParseRepoRef(s string) (RepoRef, error) {
+	rest, isURL := strings.CutPrefix(s, "https://")
+	parts := strings.Split(strings.TrimSuffix(rest, "/"), "/")
+
+	var ref RepoRef
+	switch {
+	case isURL && len(parts) >= 3:
+		ref = RepoRef{Host: parts[0], Owner: parts[1], Name: strings.TrimSuffix(parts[2], ".git")}
+	case !isURL && len(parts) == 2:
+		ref = RepoRef{Host: GitHubHost, Owner: parts[0], Name: parts[1]}
+	case !isURL && len(parts) == 3 && strings.Contains(parts[0], "."):
+		ref = RepoRef{Host: parts[0], Owner: parts[1], Name: parts[2]}
+	default:
+		return RepoRef{}, fmt.Errorf("invalid repository %q: want https://host/owner/name or owner/name", s)
+	}
+	if ref.Host == "" || ref.Owner == "" || ref.Name == "" {
+		return RepoRef{}, fmt.Errorf("invalid repository %q: want https://host/owner/name or owner/name", s)
+	}
+	return ref, nil
+}
+
+func (r RepoRef) host() string {
+	if r.Host == "" {
+		return GitHubHost
+	}
+	return r.Host
+}
+
+// String returns "owner/name" for repositories on github.com and
+// "host/owner/name" for others.
+func (r RepoRef) String() string {
+	if r.host() == GitHubHost {
+		return r.Owner + "/" + r.Name
+	}
+	return r.Host + "/" + r.Owner + "/" + r.Name
+}
+
+// HTMLURL returns the URL of the repository's web page, the form of Repo.URL.
+func (r RepoRef) HTMLURL() string {
+	return "https://" + r.host() + "/" + r.Owner + "/" + r.Name
+}
+
+// APIBaseURL returns the base URL of the REST API serving the repository:
+// api.github.com for github.com, and /api/v3 of GitHub Enterprise servers.
+func (r RepoRef) APIBaseURL() string {
+	if r.host() == GitHubHost {
+		return "https://api.github.com"
+	}
+	return "https://" + r.Host + "/api/v3"
+}
+
+// Ref returns the RepoRef of the repository's URL.
+func (r Repo) Ref() (RepoRef, error) {
+	return ParseRepoRef(r.URL)
+}
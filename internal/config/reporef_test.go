@@ -0,0 +1,48 @@
+package config
+
+import "testing"
+
+func TestParseRepoRef(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    RepoRef
+		str     string
+		html    string
+		api     string
+		wantErr bool
+	}{
+		{in: "https://github.com/cli/cli", want: RepoRef{"github.com", "cli", "cli"}, str: "cli/cli", html: "https://github.com/cli/cli", api: "https://api.github.com"},
+		{in: "https://github.com/cli/cli.git/", want: RepoRef{"github.com", "cli", "cli"}, str: "cli/cli", html: "https://github.com/cli/cli", api: "https://api.github.com"},
+		{in: "https://github.com/cli/cli/releases", want: RepoRef{"github.com", "cli", "cli"}, str: "cli/cli", html: "https://github.com/cli/cli", api: "https://api.github.com"},
+		{in: "cli/cli", want: RepoRef{"github.com", "cli", "cli"}, str: "cli/cli", html: "https://github.com/cli/cli", api: "https://api.github.com"},
+		{in: "https://ghe.example.com/team/tool", want: RepoRef{"ghe.example.com", "team", "tool"}, str: "ghe.example.com/team/tool", html: "https://ghe.example.com/team/tool", api: "https://ghe.example.com/api/v3"},
+		{in: "ghe.example.com/team/tool", want: RepoRef{"ghe.example.com", "team", "tool"}, str: "ghe.example.com/team/tool", html: "https://ghe.example.com/team/tool", api: "https://ghe.example.com/api/v3"},
+		{in: "https://github.com/cli", wantErr: true},
+		{in: "cli", wantErr: true},
+		{in: "a/b/c", wantErr: true},
+		{in: "", wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := ParseRepoRef(tt.in)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("ParseRepoRef(%q) error = %v, wantErr %v", tt.in, err, tt.wantErr)
+			continue
+		}
+		if tt.wantErr {
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("ParseRepoRef(%q) = %+v, want %+v", tt.in, got, tt.want)
+		}
+		if got.String() != tt.str || got.HTMLURL() != tt.html || got.APIBaseURL() != tt.api {
+			t.Errorf("ParseRepoRef(%q) = %s, %s, %s, want %s, %s, %s", tt.in, got, got.HTMLURL(), got.APIBaseURL(), tt.str, tt.html, tt.api)
+		}
+		if again, err := ParseRepoRef(got.String()); err != nil || again != got {
+			t.Errorf("ParseRepoRef(%q) = %+v, %v, want %+v", got.String(), again, err, got)
+		}
+	}
+
+	if ref := (RepoRef{Owner: "cli", Name: "cli"}); ref.String() != "cli/cli" || ref.APIBaseURL() != "https://api.github.com" {
+		t.Errorf("zero Host = %s, %s, want github.com", ref, ref.APIBaseURL())
+	}
+}
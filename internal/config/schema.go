@@ -0,0 +1,274 @@
+package config
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// SchemaVersion is the newest config schema. Version 1, the schema of files
+// without a version, keeps loading; version 2 groups the asset filters of a
+// repository under asset, its checks under verify, renames post_processors
+// to hooks and accepts a mirror URL as mirror:
+//
+//	version: 2
+//	mirror: https://ghfast.top
+//	github:
+//	  - url: https://github.com/cli/cli
+//	    output_dir: /opt/gh
+//	    asset: {pattern: '_linux_amd64\.tar\.gz$', prefer: smallest}
+//	    verify: {sha256: 5b8d..., signature: {type: gpg, key: gh.asc}}
+//	    hooks: [{command: [strip, gh]}]
+const SchemaVersion = 2
+
+// v2Groups maps the keys of a repository or defaults mapping in schema 2 that
+// group settings to the version 1 keys of the settings they hold.
+var v2Groups = []struct {
+	key    string
+	fields map[string]string
+}{
+	{"asset", map[string]string{"pattern": "asset_pattern", "prefer": "prefer"}},
+	{"verify", map[string]string{"sha256": "sha256", "signature": "signature"}},
+}
+
+// schemaVersion returns the schema version of doc, 1 when it has none.
+func schemaVersion(doc *yaml.Node) (int, *Error) {
+	root := rootMapping(doc)
+	if root == nil {
+		return 1, nil
+	}
+	for i := 0; i+1 < len(root.Content); i += 2 {
+		if root.Content[i].Value != "version" {
+			continue
+		}
+		value := root.Content[i+1]
+		v, err := strconv.Atoi(value.Value)
+		if err != nil || value.Kind != yaml.ScalarNode || v < 1 {
+			return 0, &Error{Line: value.Line, Err: fmt.Errorf("version must be 1 or 2")}
+		}
+		if v > SchemaVersion {
+			return 0, &Error{Line: value.Line, Err: fmt.Errorf("config version %d is newer than this ghinstall supports (%d); upgrade ghinstall", v, SchemaVersion)}
+		}
+		return v, nil
+	}
+	return 1, nil
+}
+
+// toV1 rewrites doc to schema 1 whatever its version. Its errors lack the
+// file.
+func toV1(doc *yaml.Node) *Error {
+	version, err := schemaVersion(doc)
+	if err != nil {
+		return err
+	}
+	if version == 2 {
+		return fromV2(doc)
+	}
+	return nil
+}
+
+// fromV2 rewrites doc in schema 2 to the keys of schema 1, which Config
+// decodes, keeping the lines of its nodes for error reports.
+func fromV2(doc *yaml.Node) *Error {
+	root := rootMapping(doc)
+	if root == nil {
+		return nil
+	}
+	for i := 0; i+1 < len(root.Content); i += 2 {
+		key, value := root.Content[i], root.Content[i+1]
+		switch key.Value {
+		case "hooks":
+			key.Value = "post_processors"
+		case "mirror":
+			if strings.Contains(value.Value, "://") {
+				key.Value = "mirror_url"
+			}
+		case "defaults":
+			if err := ungroup(value); err != nil {
+				return err
+			}
+		case "github":
+			if value.Kind != yaml.SequenceNode {
+				continue
+			}
+			for _, repo := range value.Content {
+				if err := ungroup(repo); err != nil {
+					return err
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// ungroup moves the settings of the groups of a schema 2 mapping up into it.
+func ungroup(mapping *yaml.Node) *Error {
+	if mapping.Kind != yaml.MappingNode {
+		return nil
+	}
+	var content []*yaml.Node
+	for i := 0; i+1 < len(mapping.Content); i += 2 {
+		key, value := mapping.Content[i], mapping.Content[i+1]
+		if key.Value == "hooks" {
+			key.Value = "post_processors"
+		}
+		fields := groupFields(key.Value)
+		if fields == nil {
+			content = append(content, key, value)
+			continue
+		}
+		if value.Kind != yaml.MappingNode {
+			return &Error{Line: value.Line, Err: fmt.Errorf("%s must be a mapping", key.Value)}
+		}
+		for j := 0; j+1 < len(value.Content); j += 2 {
+			field := value.Content[j]
+			name, ok := fields[field.Value]
+			if !ok {
+				return &Error{Line: field.Line, Err: fmt.Errorf("unknown %s setting %q", key.Value, field.Value)}
+			}
+			field.Value = name
+			content = append(content, field, value.Content[j+1])
+		}
+	}
+	mapping.Content = content
+	return nil
+}
+
+// groupFields returns the fields of the schema 2 group key, or nil.
+func groupFields(key string) map[string]string {
+	for _, g := range v2Groups {
+		if g.key == key {
+			return g.fields
+		}
+	}
+	return nil
+}
+
+// toV2 rewrites doc in schema 1 to schema 2.
+func toV2(doc *yaml.Node) {
+	root := rootMapping(doc)
+	if root == nil {
+		return
+	}
+	for i := 0; i+1 < len(root.Content); i += 2 {
+		key, value := root.Content[i], root.Content[i+1]
+		switch key.Value {
+		case "post_processors":
+			key.Value = "hooks"
+		case "mirror_url":
+			key.Value = "mirror"
+		case "defaults":
+			group(value)
+		case "github":
+			if value.Kind != yaml.SequenceNode {
+				continue
+			}
+			for _, repo := range value.Content {
+				group(repo)
+			}
+		}
+	}
+	for i := 0; i+1 < len(root.Content); i += 2 {
+		if root.Content[i].Value == "version" {
+			root.Content[i+1].Value = strconv.Itoa(SchemaVersion)
+			return
+		}
+	}
+	// The version goes first, under the comment heading the file.
+	key := &yaml.Node{Kind: yaml.ScalarNode, Value: "version"}
+	if len(root.Content) > 0 {
+		key.HeadComment, root.Content[0].HeadComment = root.Content[0].HeadComment, ""
+	}
+	v := &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!int", Value: strconv.Itoa(SchemaVersion)}
+	root.Content = append([]*yaml.Node{key, v}, root.Content...)
+}
+
+// group moves the settings of a schema 1 mapping into their schema 2 groups,
+// each placed where the first of its settings was.
+func group(mapping *yaml.Node) {
+	if mapping.Kind != yaml.MappingNode {
+		return
+	}
+	groups := map[string]*yaml.Node{}
+	var content []*yaml.Node
+	for i := 0; i+1 < len(mapping.Content); i += 2 {
+		key, value := mapping.Content[i], mapping.Content[i+1]
+		if key.Value == "post_processors" {
+			key.Value = "hooks"
+		}
+		g, field := groupOf(key.Value)
+		if g == "" {
+			content = append(content, key, value)
+			continue
+		}
+		node := groups[g]
+		if node == nil {
+			node = &yaml.Node{Kind: yaml.MappingNode}
+			groups[g] = node
+			content = append(content, &yaml.Node{Kind: yaml.ScalarNode, Value: g}, node)
+		}
+		key.Value = field
+		node.Content = append(node.Content, key, value)
+	}
+	mapping.Content = content
+}
+
+// groupOf returns the schema 2 group and field of the schema 1 key, or "".
+func groupOf(key string) (group, field string) {
+	for _, g := range v2Groups {
+		for f, name := range g.fields {
+			if name == key {
+				return g.key, f
+			}
+		}
+	}
+	return "", ""
+}
+
+// rootMapping returns the top-level mapping of doc, or nil.
+func rootMapping(doc *yaml.Node) *yaml.Node {
+	if doc.Kind != yaml.DocumentNode || len(doc.Content) == 0 || doc.Content[0].Kind != yaml.MappingNode {
+		return nil
+	}
+	return doc.Content[0]
+}
+
+// Migrate rewrites the config file at cfgPath to SchemaVersion, keeping its
+// comments, and reports whether it changed; files already at SchemaVersion
+// are left alone. Like SetVersion it normalizes the indentation and replaces
+// the file atomically.
+func Migrate(cfgPath string) (bool, error) {
+	data, err := os.ReadFile(cfgPath)
+	if err != nil {
+		return false, fmt.Errorf("failed to read config file %q: %w", cfgPath, err)
+	}
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return false, &Error{File: cfgPath, Line: yamlErrorLine(err), Err: fmt.Errorf("failed to parse config file %q: %w", cfgPath, err)}
+	}
+	version, verr := schemaVersion(&doc)
+	if verr != nil {
+		verr.File = cfgPath
+		return false, verr
+	}
+	if version == SchemaVersion {
+		return false, nil
+	}
+	if rootMapping(&doc) == nil {
+		return false, fmt.Errorf("config file %q is not a mapping", cfgPath)
+	}
+
+	toV2(&doc)
+	var buf bytes.Buffer
+	enc := yaml.NewEncoder(&buf)
+	enc.SetIndent(2)
+	if err := enc.Encode(&doc); err != nil {
+		return false, fmt.Errorf("failed to encode config file %q: %w", cfgPath, err)
+	}
+	enc.Close()
+	return true, replaceFile(cfgPath, buf.Bytes())
+}
@@ -0,0 +1,102 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestMigrate(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "ghinstall.yaml")
+	content := `mirror_url: "https://ghfast.top"
+defaults:
+  prefer: smallest
+github:
+  - url: "https://github.com/owner/tool"
+    output_dir: "/opt/tool"
+    asset_pattern: '_linux_amd64\.tar\.gz$' # the glibc build
+    sha256: "5b8d000000000000000000000000000000000000000000000000000000001a2b"
+    prefer: largest
+    post_processors:
+      - command: [strip, tool]
+`
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	before, err := Load(path)
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+
+	changed, err := Migrate(path)
+	if err != nil || !changed {
+		t.Fatalf("Migrate() = %v, %v, want true, nil", changed, err)
+	}
+	data, _ := os.ReadFile(path)
+	for _, want := range []string{"version: 2", "mirror: \"https://ghfast.top\"", "asset:\n      pattern:", "verify:\n      sha256:", "hooks:", "# the glibc build"} {
+		if !strings.Contains(string(data), want) {
+			t.Errorf("migrated file lacks %q:\n%s", want, data)
+		}
+	}
+
+	after, err := Load(path)
+	if err != nil {
+		t.Fatalf("Load() of the migrated file error = %v", err)
+	}
+	if !reflect.DeepEqual(after, before) {
+		t.Errorf("migrated config = %+v, want %+v", after, before)
+	}
+
+	if changed, err := Migrate(path); err != nil || changed {
+		t.Errorf("Migrate() of a version 2 file = %v, %v, want false, nil", changed, err)
+	}
+}
+
+func TestLoad_SchemaVersion(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		wantErr string
+	}{
+		{
+			name: "newer version",
+			content: `version: 3
+github:
+  - url: "https://github.com/owner/tool"
+    output_dir: "/opt/tool"`,
+			wantErr: "newer than this ghinstall supports",
+		},
+		{
+			name: "unknown asset setting",
+			content: `version: 2
+github:
+  - url: "https://github.com/owner/tool"
+    output_dir: "/opt/tool"
+    asset: {patern: tool}`,
+			wantErr: `unknown asset setting "patern"`,
+		},
+		{
+			name: "v1 keys ignore version 2 groups",
+			content: `github:
+  - url: "https://github.com/owner/tool"
+    output_dir: "/opt/tool"
+    asset: {pattern: "("}`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Load(createTempConfigFile(t, tt.content))
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("Load() error = %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("Load() error = %v, want it to mention %q", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,168 @@
+// Package constraint parses version constraints such as ">=1.4.0 <2.0.0" or
+// "~1.6" and checks release tags against them.
+package constraint
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"golang.org/x/mod/semver"
+)
+
+// Constraint is a set of alternative version ranges separated by "||", each
+// a list of comparisons that must all hold, separated by spaces or commas:
+//
+//	>=1.4.0 <2.0.0   at least 1.4.0 and below 2.0.0
+//	~1.6             1.6.x: at least 1.6.0 and below 1.7.0
+//	~1.6.2           at least 1.6.2 and below 1.7.0
+//	^1.2             1.x from 1.2.0 on: below 2.0.0 (0.2.x for ^0.2)
+//	1.6              1.6.x, like ~1.6
+//	!=1.5.3          any version but 1.5.3
+//
+// Versions may omit their minor and patch numbers and their "v".
+type Constraint struct {
+	text string
+	alts [][]comparison
+}
+
+type comparison struct {
+	op      string
+	version string // canonical, e.g. "v1.6.0"
+}
+
+// Parse parses a constraint.
+func Parse(s string) (Constraint, error) {
+	c := Constraint{text: strings.TrimSpace(s)}
+	if c.text == "" {
+		return Constraint{}, fmt.Errorf("empty version constraint")
+	}
+	for _, alt := range strings.Split(c.text, "||") {
+		var comps []comparison
+		for _, term := range strings.FieldsFunc(alt, func(r rune) bool { return r == ' ' || r == ',' }) {
+			parsed, err := parseTerm(term)
+			if err != nil {
+				return Constraint{}, fmt.Errorf("invalid version constraint %q: %w", s, err)
+			}
+			comps = append(comps, parsed...)
+		}
+		if len(comps) == 0 {
+			return Constraint{}, fmt.Errorf("invalid version constraint %q: empty range", s)
+		}
+		c.alts = append(c.alts, comps)
+	}
+	return c, nil
+}
+
+// parseTerm returns the comparisons of one term such as ">=1.4" or "~1.6".
+func parseTerm(term string) ([]comparison, error) {
+	op := ""
+	for _, prefix := range []string{">=", "<=", "!=", ">", "<", "=", "~", "^"} {
+		if strings.HasPrefix(term, prefix) {
+			op, term = prefix, term[len(prefix):]
+			break
+		}
+	}
+	v := "v" + strings.TrimPrefix(term, "v")
+	if !semver.IsValid(v) {
+		return nil, fmt.Errorf("%q is not a version", term)
+	}
+	nums := numbers(v)
+	lower := semver.Canonical(v)
+
+	switch op {
+	case "", "=":
+		if len(nums) == 3 {
+			return []comparison{{"=", lower}}, nil
+		}
+		// A partial version stands for all its releases.
+		return []comparison{{">=", lower}, {"<", bump(nums, len(nums)-1)}}, nil
+	case "~":
+		if len(nums) == 1 {
+			return []comparison{{">=", lower}, {"<", bump(nums, 0)}}, nil
+		}
+		return []comparison{{">=", lower}, {"<", bump(nums, 1)}}, nil
+	case "^":
+		// The first non-zero number is the one breaking changes bump.
+		i := 0
+		for i < len(nums)-1 && nums[i] == 0 {
+			i++
+		}
+		return []comparison{{">=", lower}, {"<", bump(nums, i)}}, nil
+	}
+	// "<=1.6" and ">1.6" compare against all of 1.6.x, not 1.6.0.
+	if len(nums) < 3 && op == "<=" {
+		return []comparison{{"<", bump(nums, len(nums)-1)}}, nil
+	}
+	if len(nums) < 3 && op == ">" {
+		return []comparison{{">=", bump(nums, len(nums)-1)}}, nil
+	}
+	return []comparison{{op, lower}}, nil
+}
+
+// numbers returns the major, minor and patch numbers given in v, a valid
+// semantic version such as "v1.6".
+func numbers(v string) []int {
+	v = strings.TrimPrefix(v, "v")
+	if i := strings.IndexAny(v, "-+"); i >= 0 {
+		v = v[:i]
+	}
+	var nums []int
+	for _, p := range strings.Split(v, ".") {
+		n, _ := strconv.Atoi(p)
+		nums = append(nums, n)
+	}
+	return nums
+}
+
+// bump returns the version incrementing nums[i] and zeroing what follows.
+func bump(nums []int, i int) string {
+	next := []int{0, 0, 0}
+	copy(next, nums[:i+1])
+	next[i]++
+	return fmt.Sprintf("v%d.%d.%d", next[0], next[1], next[2])
+}
+
+// Allows reports whether tag, with or without its "v", is a version c allows.
+// Tags that are not semantic versions are never allowed.
+func (c Constraint) Allows(tag string) bool {
+	v := "v" + strings.TrimPrefix(tag, "v")
+	if !semver.IsValid(v) {
+		return false
+	}
+	for _, alt := range c.alts {
+		if allowsAll(alt, v) {
+			return true
+		}
+	}
+	return false
+}
+
+func allowsAll(comps []comparison, v string) bool {
+	for _, comp := range comps {
+		cmp := semver.Compare(v, comp.version)
+		var ok bool
+		switch comp.op {
+		case "=":
+			ok = cmp == 0
+		case "!=":
+			ok = cmp != 0
+		case ">":
+			ok = cmp > 0
+		case ">=":
+			ok = cmp >= 0
+		case "<":
+			ok = cmp < 0
+		case "<=":
+			ok = cmp <= 0
+		}
+		if !ok {
+			return false
+		}
+	}
+	return true
+}
+
+func (c Constraint) String() string {
+	return c.text
+}
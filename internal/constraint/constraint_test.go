@@ -0,0 +1,50 @@
+package constraint
+
+import "testing"
+
+func TestConstraint_Allows(t *testing.T) {
+	tests := []struct {
+		constraint string
+		allowed    []string
+		refused    []string
+	}{
+		{">=1.4.0 <2.0.0", []string{"v1.4.0", "1.9.9", "v1.10.0"}, []string{"v1.3.9", "v2.0.0", "nightly"}},
+		{">=1.4, <2", []string{"v1.4.0", "v1.99.0"}, []string{"v2.0.0"}},
+		{"~1.6", []string{"v1.6.0", "v1.6.9"}, []string{"v1.5.9", "v1.7.0"}},
+		{"~1.6.2", []string{"v1.6.2", "v1.6.10"}, []string{"v1.6.1", "v1.7.0"}},
+		{"~1", []string{"v1.0.0", "v1.9.0"}, []string{"v2.0.0"}},
+		{"^1.2", []string{"v1.2.0", "v1.9.0"}, []string{"v1.1.9", "v2.0.0"}},
+		{"^0.2.3", []string{"v0.2.3", "v0.2.9"}, []string{"v0.3.0"}},
+		{"1.6", []string{"v1.6.0", "v1.6.4"}, []string{"v1.7.0"}},
+		{"=1.6.1", []string{"v1.6.1", "1.6.1"}, []string{"v1.6.2"}},
+		{"<=1.6", []string{"v1.6.9"}, []string{"v1.7.0"}},
+		{">1.6", []string{"v1.7.0"}, []string{"v1.6.9"}},
+		{">=1.0.0 !=1.5.3", []string{"v1.5.2"}, []string{"v1.5.3"}},
+		{"~1.6 || ~2.1", []string{"v1.6.1", "v2.1.0"}, []string{"v2.0.0"}},
+	}
+	for _, tt := range tests {
+		c, err := Parse(tt.constraint)
+		if err != nil {
+			t.Errorf("Parse(%q) error = %v", tt.constraint, err)
+			continue
+		}
+		for _, tag := range tt.allowed {
+			if !c.Allows(tag) {
+				t.Errorf("%q refuses %s", tt.constraint, tag)
+			}
+		}
+		for _, tag := range tt.refused {
+			if c.Allows(tag) {
+				t.Errorf("%q allows %s", tt.constraint, tag)
+			}
+		}
+	}
+}
+
+func TestParse_Invalid(t *testing.T) {
+	for _, s := range []string{"", ">=", "~x.y", "1.4 ||", ">>1.0"} {
+		if _, err := Parse(s); err == nil {
+			t.Errorf("Parse(%q) should fail", s)
+		}
+	}
+}
@@ -0,0 +1,118 @@
+// Package delta rebuilds release assets from a previously downloaded version
+// and a bsdiff patch, so large assets that change little between releases do
+// not have to be downloaded in full.
+package delta
+
+import (
+	"bufio"
+	"bytes"
+	"compress/bzip2"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+)
+
+const magic = "BSDIFF40"
+
+// ErrCorrupt is returned for patches that are not valid BSDIFF40 data or do
+// not fit the base they are applied to.
+var ErrCorrupt = errors.New("corrupt bsdiff patch")
+
+// PatchName returns the asset name under which a release publishes the patch
+// turning the asset of release tag from into asset.
+func PatchName(asset, from string) string {
+	return asset + ".from-" + from + ".bsdiff"
+}
+
+// Apply applies the BSDIFF40 patch to old and writes the result to w. It
+// returns the number of bytes written. size is the expected size of the
+// result; patches declaring another size are refused before anything is
+// written.
+func Apply(old io.ReaderAt, oldSize int64, patch []byte, size int64, w io.Writer) (int64, error) {
+	if len(patch) < 32 || string(patch[:8]) != magic {
+		return 0, fmt.Errorf("%w: bad header", ErrCorrupt)
+	}
+
+	ctrlLen := offtin(patch[8:16])
+	diffLen := offtin(patch[16:24])
+	newSize := offtin(patch[24:32])
+	if ctrlLen < 0 || diffLen < 0 || newSize < 0 || 32+ctrlLen+diffLen > int64(len(patch)) {
+		return 0, fmt.Errorf("%w: bad header", ErrCorrupt)
+	}
+	if newSize != size {
+		return 0, fmt.Errorf("%w: patch rebuilds %d bytes, expected %d", ErrCorrupt, newSize, size)
+	}
+
+	ctrl := bzip2.NewReader(bytes.NewReader(patch[32 : 32+ctrlLen]))
+	diff := bzip2.NewReader(bytes.NewReader(patch[32+ctrlLen : 32+ctrlLen+diffLen]))
+	extra := bzip2.NewReader(bytes.NewReader(patch[32+ctrlLen+diffLen:]))
+
+	out := bufio.NewWriter(w)
+	var (
+		newPos, oldPos int64
+		triple         [24]byte
+		buf            = make([]byte, 32*1024)
+		base           = make([]byte, 32*1024)
+	)
+	for newPos < newSize {
+		if _, err := io.ReadFull(ctrl, triple[:]); err != nil {
+			return newPos, fmt.Errorf("%w: %v", ErrCorrupt, err)
+		}
+		add, copyLen, seek := offtin(triple[0:8]), offtin(triple[8:16]), offtin(triple[16:24])
+		if add < 0 || copyLen < 0 || newPos+add+copyLen > newSize {
+			return newPos, fmt.Errorf("%w: control data out of range", ErrCorrupt)
+		}
+
+		// Bytes read from the diff block are added to the base at oldPos.
+		for add > 0 {
+			n := min(add, int64(len(buf)))
+			if _, err := io.ReadFull(diff, buf[:n]); err != nil {
+				return newPos, fmt.Errorf("%w: %v", ErrCorrupt, err)
+			}
+			if err := readBase(old, oldSize, oldPos, base[:n]); err != nil {
+				return newPos, err
+			}
+			for i := range n {
+				buf[i] += base[i]
+			}
+			if _, err := out.Write(buf[:n]); err != nil {
+				return newPos, err
+			}
+			add -= n
+			newPos += n
+			oldPos += n
+		}
+
+		// Bytes from the extra block are new content copied as-is.
+		if _, err := io.CopyN(out, extra, copyLen); err != nil {
+			return newPos, fmt.Errorf("%w: %v", ErrCorrupt, err)
+		}
+		newPos += copyLen
+		oldPos += seek
+	}
+
+	return newPos, out.Flush()
+}
+
+// readBase fills p with old content at off; positions outside of old read as zero.
+func readBase(old io.ReaderAt, oldSize, off int64, p []byte) error {
+	clear(p)
+	start, end := max(off, 0), min(off+int64(len(p)), oldSize)
+	if start >= end {
+		return nil
+	}
+	if _, err := old.ReadAt(p[start-off:end-off], start); err != nil && err != io.EOF {
+		return fmt.Errorf("failed to read base: %w", err)
+	}
+	return nil
+}
+
+// offtin decodes bsdiff's sign-magnitude little-endian 64-bit integers.
+func offtin(b []byte) int64 {
+	v := int64(binary.LittleEndian.Uint64(b) &^ (1 << 63))
+	if b[7]&0x80 != 0 {
+		return -v
+	}
+	return v
+}
@@ -0,0 +1,83 @@
+package delta
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"testing"
+)
+
+func TestApply(t *testing.T) {
+	old, err := os.ReadFile("testdata/old.bin")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want, err := os.ReadFile("testdata/new.bin")
+	if err != nil {
+		t.Fatal(err)
+	}
+	patch, err := os.ReadFile("testdata/old-to-new.bsdiff")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var out bytes.Buffer
+	n, err := Apply(bytes.NewReader(old), int64(len(old)), patch, int64(len(want)), &out)
+	if err != nil {
+		t.Fatalf("Apply() error = %v", err)
+	}
+	if n != int64(len(want)) || !bytes.Equal(out.Bytes(), want) {
+		t.Errorf("Apply() produced %d bytes that differ from the expected %d", n, len(want))
+	}
+}
+
+func TestApply_Corrupt(t *testing.T) {
+	patch, err := os.ReadFile("testdata/old-to-new.bsdiff")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name  string
+		patch []byte
+	}{
+		{name: "empty", patch: nil},
+		{name: "bad magic", patch: append([]byte("BSDIFF41"), patch[8:]...)},
+		{name: "truncated", patch: patch[:len(patch)/2]},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Apply(bytes.NewReader(nil), 0, tt.patch, 1, &bytes.Buffer{})
+			if !errors.Is(err, ErrCorrupt) {
+				t.Errorf("Apply() error = %v, want ErrCorrupt", err)
+			}
+		})
+	}
+}
+
+func TestApply_WrongSize(t *testing.T) {
+	old, err := os.ReadFile("testdata/old.bin")
+	if err != nil {
+		t.Fatal(err)
+	}
+	patch, err := os.ReadFile("testdata/old-to-new.bsdiff")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var out bytes.Buffer
+	n, err := Apply(bytes.NewReader(old), int64(len(old)), patch, 1<<40, &out)
+	if !errors.Is(err, ErrCorrupt) {
+		t.Errorf("Apply() error = %v, want ErrCorrupt", err)
+	}
+	if n != 0 || out.Len() != 0 {
+		t.Errorf("Apply() wrote %d bytes for a patch of the wrong size", out.Len())
+	}
+}
+
+func TestPatchName(t *testing.T) {
+	if got := PatchName("app_linux_amd64.tar.gz", "v1.2.0"); got != "app_linux_amd64.tar.gz.from-v1.2.0.bsdiff" {
+		t.Errorf("PatchName() = %s", got)
+	}
+}
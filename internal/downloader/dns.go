@@ -0,0 +1,181 @@
+package downloader
+
+import (
+	"context"
+	"maps"
+	"net"
+	"net/http"
+	neturl "net/url"
+	"slices"
+	"sync"
+	"time"
+)
+
+// dnsTTL is how long a client reuses the addresses it looked up for a host.
+// A run installing many assets from the same hosts resolves each once.
+const dnsTTL = 5 * time.Minute
+
+// githubDownloadHosts serve the assets github.com redirects release
+// downloads to.
+var githubDownloadHosts = []string{"objects.githubusercontent.com", "release-assets.githubusercontent.com"}
+
+// Preresolver is implemented by clients that can look up the hosts of URLs
+// ahead of downloading from them.
+type Preresolver interface {
+	Preresolve(ctx context.Context, urls []string)
+}
+
+// Preresolve looks up the hosts of urls in the background, so the downloads
+// of a run do not wait for DNS one by one. Downloads from github.com also
+// look up the hosts it redirects them to.
+func (c *HTTPClient) Preresolve(ctx context.Context, urls []string) {
+	hosts := make(map[string]bool)
+	for _, u := range urls {
+		parsed, err := neturl.Parse(u)
+		if err != nil || parsed.Hostname() == "" {
+			continue
+		}
+		hosts[parsed.Hostname()] = true
+		if parsed.Hostname() == "github.com" {
+			for _, host := range githubDownloadHosts {
+				hosts[host] = true
+			}
+		}
+	}
+	for host := range hosts {
+		go c.dns.lookup(ctx, host)
+	}
+}
+
+// dnsCache shares the lookups of a client between its connections.
+type dnsCache struct {
+	mu      sync.Mutex
+	entries map[string]*dnsEntry
+	// lookupHost is replaced by tests.
+	lookupHost func(ctx context.Context, host string) ([]string, error)
+}
+
+type dnsEntry struct {
+	ready   chan struct{}
+	addrs   []string
+	err     error
+	expires time.Time
+}
+
+// lookup returns the addresses of host, waiting for a lookup in progress
+// rather than starting another one. Failed lookups are not kept.
+func (d *dnsCache) lookup(ctx context.Context, host string) ([]string, error) {
+	d.mu.Lock()
+	if d.entries == nil {
+		d.entries = make(map[string]*dnsEntry)
+	}
+	e, ok := d.entries[host]
+	if !ok || e.expired() {
+		e = &dnsEntry{ready: make(chan struct{})}
+		d.entries[host] = e
+		go d.resolve(host, e)
+	}
+	d.mu.Unlock()
+
+	select {
+	case <-e.ready:
+		return e.addrs, e.err
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+func (d *dnsCache) resolve(host string, e *dnsEntry) {
+	// The lookup outlives the request that started it, as others may wait
+	// for it, but not forever.
+	ctx, cancel := context.WithTimeout(context.Background(), DefaultTimeouts.Connect)
+	defer cancel()
+	lookupHost := d.lookupHost
+	if lookupHost == nil {
+		lookupHost = net.DefaultResolver.LookupHost
+	}
+	e.addrs, e.err = lookupHost(ctx, host)
+	e.expires = time.Now().Add(dnsTTL)
+	close(e.ready)
+
+	if e.err != nil {
+		d.mu.Lock()
+		if d.entries[host] == e {
+			delete(d.entries, host)
+		}
+		d.mu.Unlock()
+	}
+}
+
+func (e *dnsEntry) expired() bool {
+	select {
+	case <-e.ready:
+		return time.Now().After(e.expires)
+	default:
+		return false
+	}
+}
+
+// dial connects to addr through the addresses cached for its host, trying
+// them in turn.
+func (d *dnsCache) dial(ctx context.Context, dialer *net.Dialer, network, addr string) (net.Conn, error) {
+	host, port, err := net.SplitHostPort(addr)
+	if err != nil || net.ParseIP(host) != nil {
+		return dialer.DialContext(ctx, network, addr)
+	}
+	addrs, err := d.lookup(ctx, host)
+	if err != nil {
+		return nil, &net.OpError{Op: "dial", Net: network, Err: err}
+	}
+
+	var firstErr error
+	for _, ip := range addrs {
+		conn, err := dialer.DialContext(ctx, network, net.JoinHostPort(ip, port))
+		if err == nil {
+			return conn, nil
+		}
+		if firstErr == nil {
+			firstErr = err
+		}
+		if ctx.Err() != nil {
+			break
+		}
+	}
+	if firstErr == nil {
+		firstErr = &net.OpError{Op: "dial", Net: network, Err: &net.DNSError{Err: "no addresses", Name: host, IsNotFound: true}}
+	}
+	return nil, firstErr
+}
+
+// DefaultKeepAliveIdleTimeout is how long idle connections are kept for
+// reuse by later downloads of a run.
+const DefaultKeepAliveIdleTimeout = 90 * time.Second
+
+// KeepAliveConfigurer is implemented by clients whose idle connections can be
+// kept for a configurable time.
+type KeepAliveConfigurer interface {
+	SetKeepAliveIdleTimeout(d time.Duration)
+}
+
+// SetKeepAliveIdleTimeout keeps idle connections for reuse for d;
+// DefaultKeepAliveIdleTimeout when d <= 0.
+func (c *HTTPClient) SetKeepAliveIdleTimeout(d time.Duration) {
+	if t := c.hostTransport(); t != nil {
+		t.setIdleConnTimeout(d)
+	}
+}
+
+// setIdleConnTimeout applies d to the base transport and the per-host ones.
+// Transports in use are only written to when d changes.
+func (t *hostTransport) setIdleConnTimeout(d time.Duration) {
+	if d <= 0 {
+		d = DefaultKeepAliveIdleTimeout
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	for _, tr := range append([]*http.Transport{t.base}, slices.Collect(maps.Values(t.hosts))...) {
+		if tr.IdleConnTimeout != d {
+			tr.IdleConnTimeout = d
+		}
+	}
+}
@@ -0,0 +1,118 @@
+package downloader
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestHTTPClient_Download_CachesDNS(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("asset"))
+	}))
+	defer server.Close()
+	u, _ := url.Parse(server.URL)
+
+	var lookups atomic.Int32
+	c := NewHTTPClient()
+	c.dns.lookupHost = func(ctx context.Context, host string) ([]string, error) {
+		lookups.Add(1)
+		if host != "assets.example" {
+			return nil, errors.New("unknown host")
+		}
+		return []string{"127.0.0.1"}, nil
+	}
+	c.Preresolve(context.Background(), []string{"http://assets.example:" + u.Port() + "/a"})
+
+	for _, name := range []string{"a", "b", "c"} {
+		// New connections, so every download dials.
+		c.client.CloseIdleConnections()
+		rc, err := c.Download(context.Background(), "http://assets.example:"+u.Port()+"/"+name)
+		if err != nil {
+			t.Fatalf("Download(%s) error = %v", name, err)
+		}
+		io.ReadAll(rc)
+		rc.Close()
+	}
+	if got := lookups.Load(); got != 1 {
+		t.Errorf("lookups = %d, want 1", got)
+	}
+}
+
+func TestDNSCache_FailuresNotCached(t *testing.T) {
+	var lookups atomic.Int32
+	d := &dnsCache{lookupHost: func(ctx context.Context, host string) ([]string, error) {
+		if lookups.Add(1) == 1 {
+			return nil, errors.New("temporary failure")
+		}
+		return []string{"192.0.2.1"}, nil
+	}}
+
+	if _, err := d.lookup(context.Background(), "host.example"); err == nil {
+		t.Fatal("first lookup() error = nil, want the failure")
+	}
+	// The failed entry is dropped right after it is reported.
+	time.Sleep(10 * time.Millisecond)
+	addrs, err := d.lookup(context.Background(), "host.example")
+	if err != nil || len(addrs) != 1 {
+		t.Fatalf("second lookup() = %v, %v, want the address", addrs, err)
+	}
+	d.lookup(context.Background(), "host.example")
+	if got := lookups.Load(); got != 2 {
+		t.Errorf("lookups = %d, want 2", got)
+	}
+}
+
+func TestPreresolve_GitHubDownloadHosts(t *testing.T) {
+	looked := make(chan string, 8)
+	c := NewHTTPClient()
+	c.dns.lookupHost = func(ctx context.Context, host string) ([]string, error) {
+		looked <- host
+		return []string{"192.0.2.1"}, nil
+	}
+	c.Preresolve(context.Background(), []string{
+		"https://github.com/owner/repo/releases/download/v1/app.tar.gz",
+		"https://github.com/owner/other/releases/download/v2/other.zip",
+		"not a url\x7f",
+	})
+
+	got := make(map[string]bool)
+	for range 3 {
+		select {
+		case host := <-looked:
+			got[host] = true
+		case <-time.After(5 * time.Second):
+			t.Fatalf("looked up %v, want 3 hosts", got)
+		}
+	}
+	for _, host := range append([]string{"github.com"}, githubDownloadHosts...) {
+		if !got[host] {
+			t.Errorf("%s not looked up, got %v", host, got)
+		}
+	}
+}
+
+func TestHTTPClient_SetKeepAliveIdleTimeout(t *testing.T) {
+	c := NewHTTPClient()
+	c.SetHostOptions("mirror.example", TransportOptions{ForceHTTP1: true})
+	c.SetKeepAliveIdleTimeout(5 * time.Second)
+
+	ht := c.hostTransport()
+	if got := ht.base.IdleConnTimeout; got != 5*time.Second {
+		t.Errorf("base IdleConnTimeout = %v, want 5s", got)
+	}
+	if got := ht.hosts["mirror.example"].IdleConnTimeout; got != 5*time.Second {
+		t.Errorf("mirror IdleConnTimeout = %v, want 5s", got)
+	}
+
+	c.SetKeepAliveIdleTimeout(0)
+	if got := ht.base.IdleConnTimeout; got != DefaultKeepAliveIdleTimeout {
+		t.Errorf("default IdleConnTimeout = %v, want %v", got, DefaultKeepAliveIdleTimeout)
+	}
+}
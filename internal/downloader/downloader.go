@@ -2,10 +2,22 @@ package downloader
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
+	neturl "net/url"
+	"os"
+	"sync"
+	"sync/atomic"
 	"time"
+
+	"github.com/sixban6/ghinstall/internal/delta"
+	"github.com/sixban6/ghinstall/internal/fdlimit"
+	"github.com/sixban6/ghinstall/internal/httpcache"
+	"github.com/sixban6/ghinstall/internal/httplog"
+	log "github.com/sixban6/ghinstall/internal/logger"
+	"github.com/sixban6/ghinstall/internal/neterr"
 )
 
 type Client interface {
@@ -14,74 +26,356 @@ type Client interface {
 
 type HTTPClient struct {
 	client *http.Client
+	// allowInsecureRedirects lets redirects downgrade from https to http.
+	allowInsecureRedirects atomic.Bool
+	// maxRedirects bounds the redirects of a request; DefaultMaxRedirects when 0.
+	maxRedirects atomic.Int64
+	// phaseTimeouts are set by SetTimeouts.
+	phaseTimeouts atomic.Pointer[Timeouts]
+	// dns caches the addresses of the hosts downloaded from.
+	dns dnsCache
 }
 
+// DefaultMaxRedirects is the redirect limit of clients without SetMaxRedirects.
+const DefaultMaxRedirects = 10
+
+// NewHTTPClient returns a client bounding downloads by DefaultTimeouts: a
+// download may take as long as it keeps receiving data.
 func NewHTTPClient() *HTTPClient {
-	return &HTTPClient{
-		client: &http.Client{
-			Timeout: 5 * time.Minute,
-			CheckRedirect: func(req *http.Request, via []*http.Request) error {
-				if len(via) > 10 {
-					return fmt.Errorf("too many redirects")
-				}
-				return nil
-			},
-		},
-	}
+	c := newHTTPClient(0)
+	c.SetTimeouts(DefaultTimeouts)
+	return c
 }
 
+// NewHTTPClientWithTimeout returns a client whose requests, including reading
+// the whole body, must complete within timeout.
 func NewHTTPClientWithTimeout(timeout time.Duration) *HTTPClient {
-	return &HTTPClient{
-		client: &http.Client{
-			Timeout: timeout,
-			CheckRedirect: func(req *http.Request, via []*http.Request) error {
-				if len(via) > 10 {
-					return fmt.Errorf("too many redirects")
-				}
-				return nil
-			},
-		},
+	return newHTTPClient(timeout)
+}
+
+func newHTTPClient(timeout time.Duration) *HTTPClient {
+	c := &HTTPClient{}
+	base := http.DefaultTransport.(*http.Transport).Clone()
+	base.DialContext = c.dialContext
+	fdlimit.Limit(base)
+	c.client = &http.Client{
+		Transport:     httpcache.New(httplog.New(newHostTransport(base), "download")),
+		Timeout:       timeout,
+		CheckRedirect: c.checkRedirect,
+	}
+	return c
+}
+
+// ErrInsecureRedirect is returned when an https request is redirected to
+// plain http and insecure redirects are not allowed.
+var ErrInsecureRedirect = errors.New("redirect from https to http refused")
+
+// ErrTooManyRedirects is returned when a request is redirected more often
+// than the redirect limit allows, typically by a redirect loop.
+var ErrTooManyRedirects = errors.New("too many redirects")
+
+func (c *HTTPClient) checkRedirect(req *http.Request, via []*http.Request) error {
+	limit := c.redirectLimit()
+	log.Debug("Redirect %d/%d: %s -> %s", len(via), limit, redact(via[len(via)-1].URL), redact(req.URL))
+	if len(via) > limit {
+		return fmt.Errorf("%w: stopped after %d redirects of %s (set max_redirects to follow more)", ErrTooManyRedirects, limit, redact(via[0].URL))
+	}
+	if req.URL.Scheme == "http" && !c.allowInsecureRedirects.Load() {
+		for _, prev := range via {
+			if prev.URL.Scheme == "https" {
+				return fmt.Errorf("%w: %s (set allow_insecure_redirects to follow it)", ErrInsecureRedirect, req.URL.Redacted())
+			}
+		}
+	}
+	return nil
+}
+
+// RedirectConfigurer is implemented by clients that refuse redirects from
+// https to plain http unless allowed.
+type RedirectConfigurer interface {
+	AllowInsecureRedirects(allow bool)
+}
+
+// AllowInsecureRedirects makes c follow redirects from https to plain http.
+func (c *HTTPClient) AllowInsecureRedirects(allow bool) {
+	c.allowInsecureRedirects.Store(allow)
+}
+
+// SetHTTPCache keeps small responses, such as checksum files, in dir so later
+// runs can revalidate them. Assets above httpcache.DefaultMaxBodySize are
+// always downloaded.
+func (c *HTTPClient) SetHTTPCache(dir string, shared bool) {
+	if t, ok := c.client.Transport.(*httpcache.Transport); ok {
+		t.SetDir(dir, shared)
+	}
+}
+
+// RedirectLimiter is implemented by clients whose redirect limit can be set.
+type RedirectLimiter interface {
+	SetMaxRedirects(n int)
+}
+
+// SetMaxRedirects makes c give up after n redirects; n <= 0 restores
+// DefaultMaxRedirects.
+func (c *HTTPClient) SetMaxRedirects(n int) {
+	c.maxRedirects.Store(int64(n))
+}
+
+func (c *HTTPClient) redirectLimit() int {
+	if n := c.maxRedirects.Load(); n > 0 {
+		return int(n)
 	}
+	return DefaultMaxRedirects
+}
+
+// redact returns u for logging, without its password and with the values of
+// its query parameters, which carry the signatures of signed URLs, replaced.
+func redact(u *neturl.URL) string {
+	return httplog.Redact(u)
 }
 
 func (c *HTTPClient) Download(ctx context.Context, url string) (io.ReadCloser, error) {
-	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+	return c.get(ctx, url, http.Header{"Accept": {"*/*"}})
+}
+
+// AssetClient is implemented by clients downloading release assets through
+// the GitHub API, which the assets of private repositories require: their
+// browser download URLs answer 404 to anything but a signed-in browser.
+type AssetClient interface {
+	DownloadAsset(ctx context.Context, apiURL, token string) (io.ReadCloser, error)
+}
+
+// DownloadAsset downloads the release asset at apiURL, its URL in the GitHub
+// API, authenticated with token. The API redirects to a signed storage URL,
+// which the token is not sent to.
+func (c *HTTPClient) DownloadAsset(ctx context.Context, apiURL, token string) (io.ReadCloser, error) {
+	return c.get(ctx, apiURL, http.Header{
+		"Accept":        {"application/octet-stream"},
+		"Authorization": {"Bearer " + token},
+	})
+}
+
+// get downloads url with header added to the request.
+func (c *HTTPClient) get(ctx context.Context, url string, header http.Header) (io.ReadCloser, error) {
+	req, wd, err := c.newRequest(ctx, "GET", url)
 	if err != nil {
-		return nil, fmt.Errorf("failed to create request for %s: %w", url, err)
+		return nil, err
+	}
+	for k, v := range header {
+		req.Header[k] = v
 	}
 
-	req.Header.Set("User-Agent", "ghinstall/1.0")
-	req.Header.Set("Accept", "*/*")
-
-	resp, err := c.client.Do(req)
+	resp, err := c.do(req, wd)
 	if err != nil {
+		wd.stop()
 		return nil, fmt.Errorf("failed to download %s: %w", url, err)
 	}
 
 	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
 		resp.Body.Close()
-		return nil, fmt.Errorf("download failed with status %d for %s", resp.StatusCode, url)
+		wd.stop()
+		return nil, neterr.Status(resp, fmt.Sprintf("download failed with status %d for %s", resp.StatusCode, url))
 	}
 
+	return c.guard(resp, url, wd), nil
+}
+
+// guard returns the body of resp, aborted by wd when no data arrives within
+// the idle timeout.
+func (c *HTTPClient) guard(resp *http.Response, url string, wd *watchdog) *responseWrapper {
+	idle := c.timeouts().Idle
+	wd.arm(idle)
 	return &responseWrapper{
 		ReadCloser: resp.Body,
 		url:        url,
-	}, nil
+		etag:       resp.Header.Get("ETag"),
+		wd:         wd,
+		idle:       idle,
+	}
 }
 
 type responseWrapper struct {
 	io.ReadCloser
-	url string
+	url  string
+	etag string
+	// wd aborts the download when it receives no data for idle.
+	wd   *watchdog
+	idle time.Duration
+}
+
+// ETag returns the entity tag the content was served with, if any.
+func (w *responseWrapper) ETag() string {
+	return w.etag
 }
 
 func (w *responseWrapper) Close() error {
-	return w.ReadCloser.Close()
+	err := w.ReadCloser.Close()
+	if w.wd != nil {
+		w.wd.stop()
+	}
+	return err
 }
 
 func (w *responseWrapper) Read(p []byte) (n int, err error) {
 	n, err = w.ReadCloser.Read(p)
+	if n > 0 && w.wd != nil {
+		w.wd.arm(w.idle)
+	}
 	if err != nil && err != io.EOF {
-		err = fmt.Errorf("failed to read from %s: %w", w.url, err)
+		err = fmt.Errorf("failed to read from %s: %w", w.url, w.wd.stalled(err, w.idle))
 	}
 	return n, err
-}
\ No newline at end of file
+}
+
+// DeltaClient is implemented by clients that can rebuild an asset from a
+// previously downloaded version of it and a bsdiff patch published with the
+// new release, instead of downloading the asset in full. Callers negotiate it
+// per asset and fall back to Download when it is unavailable or fails.
+type DeltaClient interface {
+	DownloadDelta(ctx context.Context, patchURL, base string, size int64, w io.Writer) (int64, error)
+}
+
+// maxPatchSize bounds patches, which are held in memory while being applied.
+const maxPatchSize = 256 << 20
+
+// DownloadDelta downloads the patch at patchURL, applies it to the file at
+// base and writes the rebuilt asset, which must be size bytes, to w.
+func (c *HTTPClient) DownloadDelta(ctx context.Context, patchURL, base string, size int64, w io.Writer) (int64, error) {
+	body, err := c.Download(ctx, patchURL)
+	if err != nil {
+		return 0, err
+	}
+	defer body.Close()
+
+	patch, err := io.ReadAll(io.LimitReader(body, maxPatchSize+1))
+	if err != nil {
+		return 0, err
+	}
+	if len(patch) > maxPatchSize {
+		return 0, fmt.Errorf("patch %s exceeds %d bytes", patchURL, maxPatchSize)
+	}
+
+	f, err := os.Open(base)
+	if err != nil {
+		return 0, fmt.Errorf("failed to open delta base: %w", err)
+	}
+	defer f.Close()
+
+	fi, err := f.Stat()
+	if err != nil {
+		return 0, fmt.Errorf("failed to open delta base: %w", err)
+	}
+
+	return delta.Apply(f, fi.Size(), patch, size, w)
+}
+
+// PrefixClient is implemented by clients that can download the beginning of
+// a URL without downloading all of it.
+type PrefixClient interface {
+	// DownloadPrefix returns the first n bytes of url, or all of it when it
+	// is shorter.
+	DownloadPrefix(ctx context.Context, url string, n int64) ([]byte, error)
+}
+
+func (c *HTTPClient) DownloadPrefix(ctx context.Context, url string, n int64) ([]byte, error) {
+	req, wd, err := c.newRequest(ctx, "GET", url)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Range", fmt.Sprintf("bytes=0-%d", n-1))
+
+	resp, err := c.do(req, wd)
+	if err != nil {
+		wd.stop()
+		return nil, fmt.Errorf("failed to download %s: %w", url, err)
+	}
+	body := c.guard(resp, url, wd)
+	defer body.Close()
+
+	// Servers ignoring the range answer 200 with the whole content, of which
+	// only the prefix is read before the connection is dropped.
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusPartialContent {
+		return nil, neterr.Status(resp, fmt.Sprintf("download failed with status %d for %s", resp.StatusCode, url))
+	}
+	return io.ReadAll(io.LimitReader(body, n))
+}
+
+// TransportOptions work around hosts, typically mirrors, that misbehave with
+// the default HTTP transport.
+type TransportOptions struct {
+	// ForceHTTP1 disables HTTP/2.
+	ForceHTTP1 bool
+	// DisableCompression stops requesting gzip responses, so servers that
+	// would compress already-compressed assets again send them unchanged.
+	DisableCompression bool
+}
+
+// HostConfigurer is implemented by clients whose transport can be tuned per host.
+type HostConfigurer interface {
+	SetHostOptions(host string, opts TransportOptions)
+}
+
+// SetHostOptions applies opts to every request sent to host ("name" or "name:port").
+func (c *HTTPClient) SetHostOptions(host string, opts TransportOptions) {
+	if t := c.hostTransport(); t != nil {
+		t.set(host, opts)
+	}
+}
+
+// hostTransport returns the transport under the cache and the request log,
+// nil when tests replaced it.
+func (c *HTTPClient) hostTransport() *hostTransport {
+	tr := c.client.Transport
+	if cached, ok := tr.(*httpcache.Transport); ok {
+		tr = cached.Base
+	}
+	if logged, ok := tr.(*httplog.Transport); ok {
+		tr = logged.Base
+	}
+	t, _ := tr.(*hostTransport)
+	return t
+}
+
+// hostTransport dispatches requests to a transport configured for their host.
+type hostTransport struct {
+	base  *http.Transport
+	mu    sync.Mutex
+	hosts map[string]*http.Transport
+}
+
+// newHostTransport returns a transport deriving per-host transports from base,
+// or from http.DefaultTransport when base is nil.
+func newHostTransport(base *http.Transport) *hostTransport {
+	if base == nil {
+		base = http.DefaultTransport.(*http.Transport)
+	}
+	return &hostTransport{base: base, hosts: make(map[string]*http.Transport)}
+}
+
+func (t *hostTransport) set(host string, opts TransportOptions) {
+	tr := t.base.Clone()
+	tr.DisableCompression = opts.DisableCompression
+	if opts.ForceHTTP1 {
+		tr.Protocols = new(http.Protocols)
+		tr.Protocols.SetHTTP1(true)
+		if tr.TLSClientConfig != nil {
+			// Do not offer h2 in ALPN either, or the server may pick it.
+			tr.TLSClientConfig = tr.TLSClientConfig.Clone()
+			tr.TLSClientConfig.NextProtos = []string{"http/1.1"}
+		}
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.hosts[host] = tr
+}
+
+func (t *hostTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	t.mu.Lock()
+	tr, ok := t.hosts[req.URL.Host]
+	t.mu.Unlock()
+	if !ok {
+		tr = t.base
+	}
+	return tr.RoundTrip(req)
+}
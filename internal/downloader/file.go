@@ -0,0 +1,71 @@
+package downloader
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/sixban6/ghinstall/internal/fdlimit"
+)
+
+// PartSuffix is appended to the path of a file while it is being downloaded.
+const PartSuffix = ".part"
+
+// DownloadToFile downloads url into path like WriteFile and returns the number
+// of bytes written.
+func (c *HTTPClient) DownloadToFile(ctx context.Context, url, path string) (int64, error) {
+	body, err := c.Download(ctx, url)
+	if err != nil {
+		return 0, err
+	}
+	defer body.Close()
+	return WriteFile(path, body)
+}
+
+// WriteFile streams r into path+PartSuffix, syncs it to disk and renames it
+// to path, so path only ever holds complete content, even after a crash. The
+// partial file is removed when reading r fails.
+func WriteFile(path string, r io.Reader) (int64, error) {
+	dir := filepath.Dir(path)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return 0, fmt.Errorf("failed to create directory %s: %w", dir, err)
+	}
+
+	part := path + PartSuffix
+	defer fdlimit.File()()
+	f, err := os.OpenFile(part, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0644)
+	if err != nil {
+		return 0, fmt.Errorf("failed to create %s: %w", part, err)
+	}
+	n, err := io.Copy(f, r)
+	if err == nil {
+		err = f.Sync()
+	}
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		os.Remove(part)
+		return 0, fmt.Errorf("failed to write %s: %w", path, err)
+	}
+
+	if err := os.Rename(part, path); err != nil {
+		os.Remove(part)
+		return 0, fmt.Errorf("failed to write %s: %w", path, err)
+	}
+	syncDir(dir)
+	return n, nil
+}
+
+// syncDir makes a rename in dir durable. Not every platform can sync a
+// directory; the file itself is already on disk then.
+func syncDir(dir string) {
+	d, err := os.Open(dir)
+	if err != nil {
+		return
+	}
+	d.Sync()
+	d.Close()
+}
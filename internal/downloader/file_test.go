@@ -0,0 +1,73 @@
+package downloader
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+type failingReader struct{}
+
+func (failingReader) Read([]byte) (int, error) { return 0, errors.New("connection reset") }
+
+func TestWriteFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "sub", "app.tar.gz")
+
+	n, err := WriteFile(path, strings.NewReader("content"))
+	if err != nil {
+		t.Fatalf("WriteFile() error = %v", err)
+	}
+	if n != 7 {
+		t.Errorf("WriteFile() = %d bytes, want 7", n)
+	}
+	if data, _ := os.ReadFile(path); string(data) != "content" {
+		t.Errorf("file = %q, want %q", data, "content")
+	}
+	if _, err := os.Stat(path + PartSuffix); !os.IsNotExist(err) {
+		t.Errorf("partial file left behind: %v", err)
+	}
+
+	// A failed download leaves the previous file untouched.
+	if _, err := WriteFile(path, io.MultiReader(strings.NewReader("new"), failingReader{})); err == nil {
+		t.Fatal("WriteFile() with a failing reader succeeded")
+	}
+	if data, _ := os.ReadFile(path); string(data) != "content" {
+		t.Errorf("file = %q after a failed download, want %q", data, "content")
+	}
+	if _, err := os.Stat(path + PartSuffix); !os.IsNotExist(err) {
+		t.Errorf("partial file left behind: %v", err)
+	}
+}
+
+func TestHTTPClient_DownloadToFile(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/missing" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Write([]byte("content"))
+	}))
+	defer server.Close()
+
+	path := filepath.Join(t.TempDir(), "app.tar.gz")
+	client := NewHTTPClient()
+	if _, err := client.DownloadToFile(context.Background(), server.URL+"/missing", path); err == nil {
+		t.Fatal("DownloadToFile() of a missing file succeeded")
+	}
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Errorf("failed download created %s", path)
+	}
+
+	if _, err := client.DownloadToFile(context.Background(), server.URL, path); err != nil {
+		t.Fatalf("DownloadToFile() error = %v", err)
+	}
+	if data, _ := os.ReadFile(path); string(data) != "content" {
+		t.Errorf("file = %q, want %q", data, "content")
+	}
+}
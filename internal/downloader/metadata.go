@@ -0,0 +1,137 @@
+package downloader
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/sixban6/ghinstall/internal/neterr"
+)
+
+// Metadata describes a remote asset as reported by a HEAD request.
+type Metadata struct {
+	URL          string    `json:"url"`
+	Size         int64     `json:"size"`
+	ETag         string    `json:"etag,omitempty"`
+	LastModified string    `json:"last_modified,omitempty"`
+	CheckedAt    time.Time `json:"checked_at"`
+}
+
+// MetadataClient is implemented by clients that can describe an asset without
+// downloading it.
+type MetadataClient interface {
+	// Head returns the current metadata of url. When prev is the metadata
+	// previously returned for url, the request is conditional and prev is
+	// returned (with a new CheckedAt) if the asset did not change.
+	Head(ctx context.Context, url string, prev *Metadata) (*Metadata, error)
+}
+
+func (c *HTTPClient) Head(ctx context.Context, url string, prev *Metadata) (*Metadata, error) {
+	req, wd, err := c.newRequest(ctx, http.MethodHead, url)
+	if err != nil {
+		return nil, err
+	}
+	defer wd.stop()
+	if prev != nil && prev.URL == url {
+		if prev.ETag != "" {
+			req.Header.Set("If-None-Match", prev.ETag)
+		}
+		if prev.LastModified != "" {
+			req.Header.Set("If-Modified-Since", prev.LastModified)
+		}
+	}
+
+	resp, err := c.do(req, wd)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check %s: %w", url, err)
+	}
+	resp.Body.Close()
+
+	switch {
+	case resp.StatusCode == http.StatusNotModified && prev != nil:
+		m := *prev
+		m.CheckedAt = time.Now().UTC()
+		return &m, nil
+	case resp.StatusCode < 200 || resp.StatusCode >= 300:
+		return nil, neterr.Status(resp, fmt.Sprintf("check failed with status %d for %s", resp.StatusCode, url))
+	}
+
+	return &Metadata{
+		URL:          url,
+		Size:         resp.ContentLength,
+		ETag:         resp.Header.Get("ETag"),
+		LastModified: resp.Header.Get("Last-Modified"),
+		CheckedAt:    time.Now().UTC(),
+	}, nil
+}
+
+// MetadataCache persists asset metadata between runs so HEAD requests can be
+// made conditional.
+type MetadataCache struct {
+	path    string
+	entries map[string]Metadata
+	dirty   bool
+}
+
+// MetadataCacheFile is the name of the metadata cache in a cache directory.
+const MetadataCacheFile = "metadata.json"
+
+// OpenMetadataCache loads the metadata cache stored in dir. A missing or
+// unreadable cache starts empty.
+func OpenMetadataCache(dir string) *MetadataCache {
+	c := &MetadataCache{path: filepath.Join(dir, MetadataCacheFile), entries: map[string]Metadata{}}
+	if data, err := os.ReadFile(c.path); err == nil {
+		json.Unmarshal(data, &c.entries)
+	}
+	return c
+}
+
+// Get returns the cached metadata of url.
+func (c *MetadataCache) Get(url string) (*Metadata, bool) {
+	m, ok := c.entries[url]
+	if !ok {
+		return nil, false
+	}
+	return &m, true
+}
+
+// Put stores m, replacing earlier metadata of the same URL.
+func (c *MetadataCache) Put(m Metadata) {
+	c.entries[m.URL] = m
+	c.dirty = true
+}
+
+// Save writes the cache atomically if it was modified.
+func (c *MetadataCache) Save() error {
+	if !c.dirty {
+		return nil
+	}
+	data, err := json.MarshalIndent(c.entries, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode metadata cache: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(c.path), 0755); err != nil {
+		return fmt.Errorf("failed to create metadata cache directory: %w", err)
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(c.path), ".metadata-*")
+	if err != nil {
+		return fmt.Errorf("failed to write metadata cache: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write metadata cache: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to write metadata cache: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), c.path); err != nil {
+		return fmt.Errorf("failed to write metadata cache: %w", err)
+	}
+	return nil
+}
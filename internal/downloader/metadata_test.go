@@ -0,0 +1,70 @@
+package downloader
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestHTTPClient_Head(t *testing.T) {
+	etag := `"v1"`
+	var conditional int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodHead {
+			t.Errorf("method = %s, want HEAD", r.Method)
+		}
+		if r.Header.Get("If-None-Match") == etag {
+			conditional++
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		w.Header().Set("ETag", etag)
+		w.Header().Set("Content-Length", "1234")
+	}))
+	defer server.Close()
+
+	client := NewHTTPClient()
+	first, err := client.Head(context.Background(), server.URL, nil)
+	if err != nil {
+		t.Fatalf("Head() error = %v", err)
+	}
+	if first.Size != 1234 || first.ETag != etag {
+		t.Errorf("Head() = %+v", first)
+	}
+
+	again, err := client.Head(context.Background(), server.URL, first)
+	if err != nil {
+		t.Fatalf("conditional Head() error = %v", err)
+	}
+	if conditional != 1 || again.Size != 1234 || again.ETag != etag {
+		t.Errorf("conditional Head() = %+v after %d conditional requests", again, conditional)
+	}
+
+	etag = `"v2"`
+	changed, err := client.Head(context.Background(), server.URL, first)
+	if err != nil {
+		t.Fatalf("Head() of a changed asset error = %v", err)
+	}
+	if changed.ETag != `"v2"` {
+		t.Errorf("Head() of a changed asset = %+v", changed)
+	}
+}
+
+func TestMetadataCache(t *testing.T) {
+	dir := t.TempDir()
+
+	c := OpenMetadataCache(dir)
+	if _, ok := c.Get("https://example.com/a"); ok {
+		t.Fatal("empty cache returned an entry")
+	}
+	c.Put(Metadata{URL: "https://example.com/a", Size: 7, ETag: `"x"`})
+	if err := c.Save(); err != nil {
+		t.Fatalf("Save() error = %v", err)
+	}
+
+	m, ok := OpenMetadataCache(dir).Get("https://example.com/a")
+	if !ok || m.Size != 7 || m.ETag != `"x"` {
+		t.Errorf("reloaded entry = %+v, %v", m, ok)
+	}
+}
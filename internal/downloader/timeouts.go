@@ -0,0 +1,150 @@
+package downloader
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	neturl "net/url"
+	"os"
+	"sync/atomic"
+	"time"
+)
+
+// Timeouts bound the phases of a download rather than its total duration, so
+// large assets on slow links can finish while hung connections still abort.
+type Timeouts struct {
+	// Connect bounds establishing a TCP connection.
+	Connect time.Duration
+	// Header bounds the wait for the response headers, including connecting,
+	// the TLS handshake and following redirects.
+	Header time.Duration
+	// Idle aborts a download that receives no data for this long.
+	Idle time.Duration
+}
+
+// DefaultTimeouts are the timeouts of NewHTTPClient.
+var DefaultTimeouts = Timeouts{
+	Connect: 30 * time.Second,
+	Header:  time.Minute,
+	Idle:    time.Minute,
+}
+
+// ErrStalled is returned when a download receives no response or no data
+// within its timeouts. It is a timeout, like a deadline of the connection.
+var ErrStalled = fmt.Errorf("download stalled: %w", os.ErrDeadlineExceeded)
+
+// TimeoutConfigurer is implemented by clients whose timeouts can be set.
+type TimeoutConfigurer interface {
+	SetTimeouts(t Timeouts)
+}
+
+// SetTimeouts replaces the timeouts of c; zero fields keep their
+// DefaultTimeouts value.
+func (c *HTTPClient) SetTimeouts(t Timeouts) {
+	if t.Connect <= 0 {
+		t.Connect = DefaultTimeouts.Connect
+	}
+	if t.Header <= 0 {
+		t.Header = DefaultTimeouts.Header
+	}
+	if t.Idle <= 0 {
+		t.Idle = DefaultTimeouts.Idle
+	}
+	c.phaseTimeouts.Store(&t)
+}
+
+// timeouts returns the phase timeouts of c; zero when c only has the overall
+// timeout of NewHTTPClientWithTimeout.
+func (c *HTTPClient) timeouts() Timeouts {
+	if t := c.phaseTimeouts.Load(); t != nil {
+		return *t
+	}
+	return Timeouts{}
+}
+
+// dialContext dials with the current connect timeout, through the addresses
+// c cached for the host.
+func (c *HTTPClient) dialContext(ctx context.Context, network, addr string) (net.Conn, error) {
+	timeout := c.timeouts().Connect
+	if timeout <= 0 {
+		timeout = DefaultTimeouts.Connect
+	}
+	d := net.Dialer{Timeout: timeout, KeepAlive: 30 * time.Second}
+	return c.dns.dial(ctx, &d, network, addr)
+}
+
+// newRequest returns a request to url whose context the returned watchdog
+// cancels when the request stalls.
+func (c *HTTPClient) newRequest(ctx context.Context, method, url string) (*http.Request, *watchdog, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	req, err := http.NewRequestWithContext(ctx, method, url, nil)
+	if err != nil {
+		cancel()
+		return nil, nil, fmt.Errorf("failed to create request for %s: %w", url, err)
+	}
+	req.Header.Set("User-Agent", "ghinstall/1.0")
+	return req, &watchdog{cancel: cancel}, nil
+}
+
+// do sends req, giving up when its response headers do not arrive within the
+// header timeout.
+func (c *HTTPClient) do(req *http.Request, wd *watchdog) (*http.Response, error) {
+	t := c.timeouts()
+	wd.arm(t.Header)
+	resp, err := c.client.Do(req)
+	wd.disarm()
+	if err != nil && wd.expired.Load() {
+		err = &neturl.Error{
+			Op:  req.Method,
+			URL: req.URL.String(),
+			Err: fmt.Errorf("%w: no response within %s", ErrStalled, t.Header),
+		}
+	}
+	return resp, err
+}
+
+// watchdog cancels a request when its timer expires. Only the goroutine
+// making the request arms and disarms it.
+type watchdog struct {
+	cancel  context.CancelFunc
+	timer   *time.Timer
+	expired atomic.Bool
+}
+
+// arm (re)starts the timer; d <= 0 leaves the request unguarded.
+func (w *watchdog) arm(d time.Duration) {
+	if d <= 0 {
+		return
+	}
+	if w.timer == nil {
+		w.timer = time.AfterFunc(d, func() {
+			w.expired.Store(true)
+			w.cancel()
+		})
+		return
+	}
+	w.timer.Reset(d)
+}
+
+func (w *watchdog) disarm() {
+	if w.timer != nil {
+		w.timer.Stop()
+	}
+}
+
+// stop disarms the watchdog and releases the request context.
+func (w *watchdog) stop() {
+	w.disarm()
+	w.cancel()
+}
+
+// stalled returns the error of a body read, explaining it when the watchdog
+// aborted the read.
+func (w *watchdog) stalled(err error, idle time.Duration) error {
+	if w == nil || !w.expired.Load() || errors.Is(err, ErrStalled) {
+		return err
+	}
+	return fmt.Errorf("%w: no data for %s", ErrStalled, idle)
+}
@@ -0,0 +1,93 @@
+package downloader
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/sixban6/ghinstall/internal/neterr"
+)
+
+func TestHTTPClient_Download_Timeouts(t *testing.T) {
+	tests := []struct {
+		name    string
+		handler func(w http.ResponseWriter, hang <-chan struct{})
+		wantErr bool
+	}{
+		{
+			name: "slow but steady download finishes",
+			handler: func(w http.ResponseWriter, hang <-chan struct{}) {
+				for range 10 {
+					w.Write([]byte("chunk"))
+					w.(http.Flusher).Flush()
+					time.Sleep(50 * time.Millisecond)
+				}
+			},
+		},
+		{
+			name: "no response headers",
+			handler: func(w http.ResponseWriter, hang <-chan struct{}) {
+				<-hang
+			},
+			wantErr: true,
+		},
+		{
+			name: "body stops arriving",
+			handler: func(w http.ResponseWriter, hang <-chan struct{}) {
+				w.Write([]byte("chunk"))
+				w.(http.Flusher).Flush()
+				<-hang
+			},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			hang := make(chan struct{})
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				tt.handler(w, hang)
+			}))
+			defer server.Close()
+			defer close(hang)
+
+			client := NewHTTPClient()
+			client.SetTimeouts(Timeouts{Header: 200 * time.Millisecond, Idle: 200 * time.Millisecond})
+
+			reader, err := client.Download(context.Background(), server.URL)
+			if err == nil {
+				_, err = io.ReadAll(reader)
+				reader.Close()
+			}
+			if !tt.wantErr {
+				if err != nil {
+					t.Fatalf("Download() error = %v", err)
+				}
+				return
+			}
+			if !errors.Is(err, ErrStalled) {
+				t.Fatalf("Download() error = %v, want %v", err, ErrStalled)
+			}
+			if kind := neterr.Classify(err); kind != neterr.Timeout {
+				t.Errorf("Classify() = %v, want %v", kind, neterr.Timeout)
+			}
+		})
+	}
+}
+
+func TestHTTPClient_SetTimeouts_Defaults(t *testing.T) {
+	client := NewHTTPClient()
+	client.SetTimeouts(Timeouts{Idle: 5 * time.Minute})
+
+	want := Timeouts{Connect: DefaultTimeouts.Connect, Header: DefaultTimeouts.Header, Idle: 5 * time.Minute}
+	if got := client.timeouts(); got != want {
+		t.Errorf("timeouts() = %+v, want %+v", got, want)
+	}
+	if client.client.Timeout != 0 {
+		t.Errorf("NewHTTPClient() caps the whole download at %v", client.client.Timeout)
+	}
+}
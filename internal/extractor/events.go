@@ -0,0 +1,66 @@
+package extractor
+
+import "io"
+
+// Event reports the extraction of one archive entry. A regular file produces
+// an event when it is started, after every chunk written and when it is
+// complete; directories and symlinks produce a single completed event.
+type Event struct {
+	// Name is the slash-separated path relative to the extraction root.
+	Name string
+	// Size is the size announced by the archive, 0 for directories and symlinks.
+	Size int64
+	// Written counts the bytes of the entry extracted so far.
+	Written int64
+	// Done is set on the last event of the entry.
+	Done bool
+}
+
+// EventFunc receives extraction events. It is called synchronously from the
+// extracting goroutine, so it should return quickly.
+type EventFunc func(Event)
+
+// EventExtractor is implemented by extractors able to report per-entry events.
+type EventExtractor interface {
+	ExtractWithEvents(src io.Reader, dst string, fn EventFunc) error
+}
+
+// entry reports an entry that is extracted at once.
+func (fn EventFunc) entry(name string) {
+	if fn == nil {
+		return
+	}
+	if name, ok := entryName(name); ok {
+		fn(Event{Name: name, Done: true})
+	}
+}
+
+// track reports the extraction of an entry whose content is read from r.
+func (fn EventFunc) track(name string, size int64, r io.Reader) io.Reader {
+	if fn == nil {
+		return r
+	}
+	name, _ = entryName(name)
+	ev := &eventReader{r: r, fn: fn, ev: Event{Name: name, Size: size}}
+	fn(ev.ev)
+	return ev
+}
+
+type eventReader struct {
+	r  io.Reader
+	fn EventFunc
+	ev Event
+}
+
+func (er *eventReader) Read(p []byte) (int, error) {
+	n, err := er.r.Read(p)
+	if er.ev.Done {
+		return n, err
+	}
+	er.ev.Written += int64(n)
+	er.ev.Done = err == io.EOF
+	if n > 0 || er.ev.Done {
+		er.fn(er.ev)
+	}
+	return n, err
+}
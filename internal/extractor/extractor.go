@@ -3,6 +3,7 @@ package extractor
 import (
 	"archive/tar"
 	"archive/zip"
+	"bufio"
 	"bytes"
 	"compress/gzip"
 	"fmt"
@@ -12,6 +13,9 @@ import (
 	"path/filepath"
 	"strings"
 	"time"
+
+	"github.com/sixban6/ghinstall/internal/fdlimit"
+	"github.com/sixban6/ghinstall/internal/tmpdir"
 )
 
 type Extractor interface {
@@ -20,6 +24,8 @@ type Extractor interface {
 
 type MultiExtractor struct {
 	cacheFirst bool // true = 先落盘再解压
+	events     EventFunc
+	opts       Options
 }
 
 func (m MultiExtractor) WithCache() *MultiExtractor {
@@ -72,18 +78,33 @@ func (e *MultiExtractor) Extract(src io.Reader, dst string) error {
 		return fmt.Errorf("failed to detect archive format: %w", err)
 	}
 
+	b := e.opts.Limits.start()
 	switch format {
 	case "tar.gz", "tgz":
-		return e.extractTarGz(tmp, dst)
+		return e.extractTarGz(tmp, dst, b)
 	case "zip":
-		return e.extractZip(tmp, dst)
+		return e.extractZip(tmp, dst, b)
 	default:
 		return fmt.Errorf("unsupported archive format: %s", format)
 	}
 }
 
+// ExtractWithEvents extracts like Extract, reporting every entry to fn.
+func (e *MultiExtractor) ExtractWithEvents(src io.Reader, dst string, fn EventFunc) error {
+	c := *e
+	c.events = fn
+	return c.Extract(src, dst)
+}
+
+// SetOptions changes how the following extractions unpack archives.
+func (e *MultiExtractor) SetOptions(opts Options) {
+	if e.opts != opts {
+		e.opts = opts
+	}
+}
+
 func writeToTemp(r io.Reader) (*os.File, error) {
-	tmp, err := os.CreateTemp("", "extract-*.tmp")
+	tmp, err := os.CreateTemp(tmpdir.Dir(), "extract-*.tmp")
 	if err != nil {
 		return nil, err
 	}
@@ -116,7 +137,7 @@ func detectFormat(r io.ReaderAt, size int64) (string, error) {
 	return "", fmt.Errorf("unknown archive format")
 }
 
-func (e *MultiExtractor) extractTarEntry(reader *tar.Reader, header *tar.Header, dst string) error {
+func (e *MultiExtractor) extractTarEntry(reader io.Reader, header *tar.Header, dst string) error {
 	path := filepath.Join(dst, header.Name)
 	cleanedDst := filepath.Clean(dst)
 	cleanedPath := filepath.Clean(path)
@@ -134,31 +155,34 @@ func (e *MultiExtractor) extractTarEntry(reader *tar.Reader, header *tar.Header,
 
 	switch header.Typeflag {
 	case tar.TypeDir:
-		return os.MkdirAll(path, os.FileMode(header.Mode))
+		e.events.entry(header.Name)
+		return os.MkdirAll(path, e.opts.dirMode(tarMode(header.Mode)))
 	case tar.TypeReg:
-		return e.extractFile(reader, path, os.FileMode(header.Mode))
+		return e.extractFile(e.events.track(header.Name, header.Size, reader), path, e.opts.fileMode(tarMode(header.Mode)))
 	case tar.TypeSymlink:
-		linkTarget := header.Linkname
-		if !strings.HasPrefix(filepath.Join(dst, linkTarget), dst) {
-			return fmt.Errorf("invalid symlink target: %s", linkTarget)
+		if err := checkSymlink(header.Linkname, cleanedPath, cleanedDst); err != nil {
+			return err
 		}
-		return os.Symlink(linkTarget, path)
+		e.events.entry(header.Name)
+		return os.Symlink(header.Linkname, path)
 	default:
 		return nil
 	}
 }
 
 // 解压 tar.gz
-func (e *MultiExtractor) extractTarGz(f *os.File, dst string) error {
+func (e *MultiExtractor) extractTarGz(f *os.File, dst string, b *budget) error {
 	if _, err := f.Seek(0, io.SeekStart); err != nil {
 		return fmt.Errorf("seek tar.gz file: %w", err)
 	}
 
-	gzr, err := gzip.NewReader(f)
+	gzr, err := openGzip(f)
 	if err != nil {
-		return fmt.Errorf("create gzip reader: %w", err)
+		return err
+	}
+	if isZip(gzr) {
+		return e.extractGzippedZip(gzr, dst, b)
 	}
-	defer gzr.Close()
 
 	tr := tar.NewReader(gzr)
 	for {
@@ -169,7 +193,10 @@ func (e *MultiExtractor) extractTarGz(f *os.File, dst string) error {
 		if err != nil {
 			return fmt.Errorf("read tar entry: %w", err)
 		}
-		if err := e.extractTarEntry(tr, hdr, dst); err != nil {
+		if err := b.entry(); err != nil {
+			return err
+		}
+		if err := e.extractTarEntry(b.reader(tr), hdr, dst); err != nil {
 			return fmt.Errorf("extract tar entry %s: %w", hdr.Name, err)
 		}
 	}
@@ -177,7 +204,7 @@ func (e *MultiExtractor) extractTarGz(f *os.File, dst string) error {
 }
 
 // 解压 zip
-func (e *MultiExtractor) extractZip(f *os.File, dst string) error {
+func (e *MultiExtractor) extractZip(f *os.File, dst string, b *budget) error {
 	if _, err := f.Seek(0, io.SeekStart); err != nil {
 		return fmt.Errorf("seek zip file: %w", err)
 	}
@@ -192,15 +219,22 @@ func (e *MultiExtractor) extractZip(f *os.File, dst string) error {
 		return fmt.Errorf("create zip reader: %w", err)
 	}
 
-	for _, file := range zr.File {
-		if err := e.extractZipEntry(file, dst); err != nil {
+	files, err := zipEntries(zr.File, e.opts.Duplicates)
+	if err != nil {
+		return err
+	}
+	for _, file := range files {
+		if err := b.entry(); err != nil {
+			return err
+		}
+		if err := e.extractZipEntry(file, dst, b); err != nil {
 			return fmt.Errorf("extract zip entry %s: %w", file.Name, err)
 		}
 	}
 	return nil
 }
 
-func (e *MultiExtractor) extractZipEntry(file *zip.File, dst string) error {
+func (e *MultiExtractor) extractZipEntry(file *zip.File, dst string, b *budget) error {
 	path := filepath.Join(dst, file.Name)
 	cleanedDst := filepath.Clean(dst)
 	cleanedPath := filepath.Clean(path)
@@ -217,7 +251,8 @@ func (e *MultiExtractor) extractZipEntry(file *zip.File, dst string) error {
 	}
 
 	if file.FileInfo().IsDir() {
-		return os.MkdirAll(path, file.FileInfo().Mode())
+		e.events.entry(file.Name)
+		return os.MkdirAll(path, e.opts.dirMode(file.FileInfo().Mode()))
 	}
 
 	fileReader, err := file.Open()
@@ -226,7 +261,7 @@ func (e *MultiExtractor) extractZipEntry(file *zip.File, dst string) error {
 	}
 	defer fileReader.Close()
 
-	return e.extractFile(fileReader, path, file.FileInfo().Mode())
+	return e.extractFile(e.events.track(file.Name, int64(file.UncompressedSize64), b.reader(fileReader)), path, e.opts.fileMode(file.FileInfo().Mode()))
 }
 
 func (e *MultiExtractor) extractFile(reader io.Reader, path string, mode os.FileMode) error {
@@ -234,6 +269,7 @@ func (e *MultiExtractor) extractFile(reader io.Reader, path string, mode os.File
 		return fmt.Errorf("failed to create directory for file %s: %w", path, err)
 	}
 
+	defer fdlimit.File()()
 	outFile, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, mode)
 	if err != nil {
 		return fmt.Errorf("failed to create file %s: %w", path, err)
@@ -269,3 +305,64 @@ func (e *ZipExtractor) Extract(src io.Reader, dst string) error {
 	optimized := NewOptimized()
 	return optimized.Extract(src, dst)
 }
+
+// maxGzipLayers bounds how many gzip layers openGzip peels off.
+const maxGzipLayers = 3
+
+// openGzip decompresses r. Some mirrors gzip already compressed assets again
+// without a Content-Encoding the HTTP client would undo; the extra layers are
+// detected by the gzip magic at the start of the decompressed data and peeled
+// off as well, with a warning.
+func openGzip(r io.Reader) (*bufio.Reader, error) {
+	br, layers, err := peelGzip(r)
+	if layers > 1 {
+		log.Warn("Archive is gzip-compressed %d times, probably by a mirror; decompressed all layers", layers)
+	}
+	return br, err
+}
+
+// peelGzip decompresses every gzip layer of r and reports how many there were.
+func peelGzip(r io.Reader) (*bufio.Reader, int, error) {
+	br := bufio.NewReader(r)
+	for layer := 1; layer <= maxGzipLayers; layer++ {
+		gzr, err := gzip.NewReader(br)
+		if err != nil {
+			return nil, layer, fmt.Errorf("create gzip reader: %w", err)
+		}
+		br = bufio.NewReader(gzr)
+
+		if magic, _ := br.Peek(2); !bytes.Equal(magic, []byte{0x1f, 0x8b}) {
+			return br, layer, nil
+		}
+	}
+	return nil, maxGzipLayers, fmt.Errorf("archive is nested in more than %d gzip layers", maxGzipLayers)
+}
+
+// isZip reports whether br starts with a zip local file header.
+func isZip(br *bufio.Reader) bool {
+	magic, _ := br.Peek(4)
+	return bytes.Equal(magic, []byte{'P', 'K', 0x03, 0x04})
+}
+
+// extractGzippedZip extracts a zip archive a mirror delivered gzip-compressed.
+func (e *MultiExtractor) extractGzippedZip(r io.Reader, dst string, b *budget) error {
+	log.Warn("Archive is a gzip-compressed zip file, probably compressed by a mirror")
+	tmp, err := writeToTemp(b.stream(r))
+	if err != nil {
+		return fmt.Errorf("decompress zip file: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+	defer tmp.Close()
+
+	return e.extractZip(tmp, dst, b)
+}
+
+// checkSymlink fails when target, the target of a symlink at path, resolved
+// from the directory of the link, leaves dst. path and dst are clean.
+func checkSymlink(target, path, dst string) error {
+	resolved := filepath.Clean(filepath.Join(filepath.Dir(path), target))
+	if filepath.IsAbs(target) || (resolved != dst && !strings.HasPrefix(resolved, dst+string(os.PathSeparator))) {
+		return fmt.Errorf("invalid symlink target: %s", target)
+	}
+	return nil
+}
@@ -0,0 +1,281 @@
+package extractor
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+)
+
+func tarArchive(t *testing.T, name, content string) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	tw := tar.NewWriter(&buf)
+	if err := tw.WriteHeader(&tar.Header{Name: name, Mode: 0644, Size: int64(len(content)), Typeflag: tar.TypeReg}); err != nil {
+		t.Fatal(err)
+	}
+	tw.Write([]byte(content))
+	tw.Close()
+	return buf.Bytes()
+}
+
+func zipArchive(t *testing.T, name, content string) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	w, err := zw.Create(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	w.Write([]byte(content))
+	zw.Close()
+	return buf.Bytes()
+}
+
+func gzipped(data []byte) []byte {
+	var buf bytes.Buffer
+	gw := gzip.NewWriter(&buf)
+	gw.Write(data)
+	gw.Close()
+	return buf.Bytes()
+}
+
+func TestExtract_DoubleCompression(t *testing.T) {
+	tests := []struct {
+		name    string
+		archive []byte
+		wantErr bool
+	}{
+		{name: "tar.gz", archive: gzipped(tarArchive(t, "tool", "hello"))},
+		{name: "double gzip", archive: gzipped(gzipped(tarArchive(t, "tool", "hello")))},
+		{name: "gzipped zip", archive: gzipped(zipArchive(t, "tool", "hello"))},
+		{name: "too many layers", archive: gzipped(gzipped(gzipped(gzipped(tarArchive(t, "tool", "hello"))))), wantErr: true},
+	}
+
+	extractors := map[string]Extractor{
+		"legacy":    NewLegacy(),
+		"optimized": NewOptimized(),
+	}
+
+	for _, tt := range tests {
+		for name, ext := range extractors {
+			t.Run(tt.name+"/"+name, func(t *testing.T) {
+				dst := t.TempDir()
+				err := ext.Extract(bytes.NewReader(tt.archive), dst)
+				if (err != nil) != tt.wantErr {
+					t.Fatalf("Extract() error = %v, wantErr %v", err, tt.wantErr)
+				}
+				if tt.wantErr {
+					return
+				}
+
+				got, err := os.ReadFile(filepath.Join(dst, "tool"))
+				if err != nil || string(got) != "hello" {
+					t.Errorf("extracted tool = %q, %v", got, err)
+				}
+			})
+		}
+	}
+}
+
+func TestExtractWithEvents(t *testing.T) {
+	content := string(bytes.Repeat([]byte("x"), 100000))
+	archives := map[string][]byte{
+		"tar.gz": gzipped(tarArchive(t, "./bin/tool", content)),
+		"zip":    zipArchive(t, "bin/tool", content),
+	}
+	extractors := map[string]EventExtractor{
+		"legacy":    NewLegacy(),
+		"optimized": NewOptimized(),
+	}
+
+	for format, archive := range archives {
+		for name, ext := range extractors {
+			t.Run(format+"/"+name, func(t *testing.T) {
+				var events []Event
+				if err := ext.ExtractWithEvents(bytes.NewReader(archive), t.TempDir(), func(ev Event) {
+					events = append(events, ev)
+				}); err != nil {
+					t.Fatalf("ExtractWithEvents() error = %v", err)
+				}
+
+				if len(events) < 3 {
+					t.Fatalf("got %d events, want start, progress and done: %+v", len(events), events)
+				}
+				first, last := events[0], events[len(events)-1]
+				if first != (Event{Name: "bin/tool", Size: int64(len(content))}) {
+					t.Errorf("first event = %+v", first)
+				}
+				if last != (Event{Name: "bin/tool", Size: int64(len(content)), Written: int64(len(content)), Done: true}) {
+					t.Errorf("last event = %+v", last)
+				}
+				for _, ev := range events[:len(events)-1] {
+					if ev.Done {
+						t.Errorf("event before the last one is done: %+v", ev)
+					}
+				}
+			})
+		}
+	}
+}
+
+func TestExtract_ZipDuplicates(t *testing.T) {
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for _, entry := range []struct{ name, content string }{
+		{"tool", "first"},
+		{"README", "readme"},
+		{"./tool", "last"},
+	} {
+		w, err := zw.Create(entry.name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		w.Write([]byte(entry.content))
+	}
+	zw.Close()
+
+	tests := []struct {
+		policy  DuplicatePolicy
+		want    string
+		wantErr bool
+	}{
+		{policy: "", want: "last"},
+		{policy: DuplicateLastWins, want: "last"},
+		{policy: DuplicateFirstWins, want: "first"},
+		{policy: DuplicateError, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		extractors := map[string]interface {
+			Extractor
+			Configurer
+		}{
+			"legacy":    NewLegacy(),
+			"optimized": NewOptimized(),
+		}
+		for name, ext := range extractors {
+			t.Run(string(tt.policy)+"/"+name, func(t *testing.T) {
+				ext.SetOptions(Options{Duplicates: tt.policy})
+				dst := t.TempDir()
+				err := ext.Extract(bytes.NewReader(buf.Bytes()), dst)
+				if (err != nil) != tt.wantErr {
+					t.Fatalf("Extract() error = %v, wantErr %v", err, tt.wantErr)
+				}
+				if tt.wantErr {
+					return
+				}
+
+				got, err := os.ReadFile(filepath.Join(dst, "tool"))
+				if err != nil || string(got) != tt.want {
+					t.Errorf("extracted tool = %q, %v, want %q", got, err, tt.want)
+				}
+				if _, err := os.Stat(filepath.Join(dst, "README")); err != nil {
+					t.Errorf("README not extracted: %v", err)
+				}
+			})
+		}
+	}
+}
+
+func TestExtract_ZeroModes(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("permissions are not enforced on Windows")
+	}
+
+	var buf bytes.Buffer
+	tw := tar.NewWriter(&buf)
+	tw.WriteHeader(&tar.Header{Name: "share/", Mode: 0, Typeflag: tar.TypeDir})
+	tw.WriteHeader(&tar.Header{Name: "share/tool.conf", Mode: 0, Size: 2, Typeflag: tar.TypeReg})
+	tw.Write([]byte("ok"))
+	tw.Close()
+	archive := gzipped(buf.Bytes())
+
+	tests := []struct {
+		name              string
+		opts              Options
+		wantFile, wantDir os.FileMode
+	}{
+		// Compare only the owner bits, which no sane umask removes.
+		{name: "defaults", wantFile: 0600, wantDir: 0700},
+		{name: "configured", opts: Options{FileMode: 0400, DirMode: 0700}, wantFile: 0400, wantDir: 0700},
+	}
+
+	for _, tt := range tests {
+		extractors := map[string]interface {
+			Extractor
+			Configurer
+		}{
+			"legacy":    NewLegacy(),
+			"optimized": NewOptimized(),
+		}
+		for name, ext := range extractors {
+			t.Run(tt.name+"/"+name, func(t *testing.T) {
+				ext.SetOptions(tt.opts)
+				dst := t.TempDir()
+				if err := ext.Extract(bytes.NewReader(archive), dst); err != nil {
+					t.Fatalf("Extract() error = %v", err)
+				}
+
+				dir, err := os.Stat(filepath.Join(dst, "share"))
+				if err != nil {
+					t.Fatal(err)
+				}
+				file, err := os.Stat(filepath.Join(dst, "share", "tool.conf"))
+				if err != nil {
+					t.Fatal(err)
+				}
+				if got := file.Mode().Perm() & 0700; got != tt.wantFile {
+					t.Errorf("file owner mode = %o, want %o", got, tt.wantFile)
+				}
+				if got := dir.Mode().Perm() & 0700; got != tt.wantDir {
+					t.Errorf("dir owner mode = %o, want %o", got, tt.wantDir)
+				}
+			})
+		}
+	}
+}
+
+func TestExtract_Limits(t *testing.T) {
+	archive := tarSeed(seedEntry{name: "a", content: "1234"}, seedEntry{name: "b", content: "5678"})
+
+	tests := []struct {
+		name    string
+		limits  Limits
+		wantErr bool
+	}{
+		{name: "within limits", limits: Limits{MaxSize: 8, MaxEntries: 2}},
+		{name: "too large", limits: Limits{MaxSize: 7}, wantErr: true},
+		{name: "too many entries", limits: Limits{MaxEntries: 1}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		extractors := map[string]interface {
+			Extractor
+			Configurer
+		}{
+			"legacy":    NewLegacy(),
+			"optimized": NewOptimized(),
+		}
+		for name, ext := range extractors {
+			t.Run(tt.name+"/"+name, func(t *testing.T) {
+				ext.SetOptions(Options{Limits: tt.limits})
+				err := ext.Extract(bytes.NewReader(archive), t.TempDir())
+				if tt.wantErr != errors.Is(err, ErrLimitExceeded) || !tt.wantErr && err != nil {
+					t.Errorf("Extract() error = %v, want limit exceeded %v", err, tt.wantErr)
+				}
+			})
+		}
+		t.Run(tt.name+"/fs", func(t *testing.T) {
+			err := ExtractFS(bytes.NewReader(archive), NewMemFS(), Options{Limits: tt.limits})
+			if tt.wantErr != errors.Is(err, ErrLimitExceeded) || !tt.wantErr && err != nil {
+				t.Errorf("ExtractFS() error = %v, want limit exceeded %v", err, tt.wantErr)
+			}
+		})
+	}
+}
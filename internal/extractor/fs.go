@@ -0,0 +1,277 @@
+package extractor
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing/fstest"
+	"time"
+
+	"github.com/sixban6/ghinstall/internal/fdlimit"
+)
+
+// WritableFS is a destination for ExtractFS. Names are slash-separated paths
+// relative to its root, already checked to stay inside it. Implementations can
+// keep the entries in memory, upload them to object storage or write them into
+// another archive.
+type WritableFS interface {
+	MkdirAll(name string, perm fs.FileMode) error
+	// Create returns a writer for the content of a regular file; the file is
+	// complete once the writer is closed.
+	Create(name string, perm fs.FileMode) (io.WriteCloser, error)
+	Symlink(target, name string) error
+}
+
+// ExtractFS extracts the tar.gz or zip archive read from src into dst instead
+// of a local directory.
+func ExtractFS(src io.Reader, dst WritableFS, opts Options) error {
+	br := bufio.NewReader(src)
+	peek, err := br.Peek(4)
+	if err != nil {
+		return fmt.Errorf("failed to peek archive data: %w", err)
+	}
+
+	b := opts.Limits.start()
+	switch detectFormatFromBytes(peek) {
+	case "tar.gz":
+		gzr, err := openGzip(br)
+		if err != nil {
+			return err
+		}
+		if isZip(gzr) {
+			return extractZipFS(gzr, dst, opts, b)
+		}
+		return extractTarFS(gzr, dst, opts, b)
+	case "zip":
+		return extractZipFS(br, dst, opts, b)
+	default:
+		return fmt.Errorf("unsupported archive format")
+	}
+}
+
+// fsName returns the cleaned name of an archive entry, "" for the root, or an
+// error when it escapes the extraction root.
+func fsName(name string) (string, error) {
+	clean := path.Clean(strings.TrimPrefix(name, "/"))
+	if clean == "." {
+		return "", nil
+	}
+	if !fs.ValidPath(clean) {
+		return "", fmt.Errorf("invalid file path: %s", name)
+	}
+	return clean, nil
+}
+
+func extractTarFS(r io.Reader, dst WritableFS, opts Options, b *budget) error {
+	tr := tar.NewReader(r)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("failed to read tar entry: %w", err)
+		}
+
+		if err := b.entry(); err != nil {
+			return err
+		}
+		name, err := fsName(hdr.Name)
+		if err != nil {
+			return err
+		}
+		if name == "" {
+			continue
+		}
+
+		switch hdr.Typeflag {
+		case tar.TypeDir:
+			err = dst.MkdirAll(name, opts.dirMode(tarMode(hdr.Mode)))
+		case tar.TypeReg:
+			err = writeFS(dst, name, opts.fileMode(tarMode(hdr.Mode)), b.reader(tr))
+		case tar.TypeSymlink:
+			err = symlinkFS(dst, hdr.Linkname, name)
+		}
+		if err != nil {
+			return fmt.Errorf("failed to extract tar entry %s: %w", hdr.Name, err)
+		}
+	}
+}
+
+// extractZipFS reads the whole zip archive into memory, as zip requires seeking.
+func extractZipFS(r io.Reader, dst WritableFS, opts Options, b *budget) error {
+	var buf bytes.Buffer
+	if _, err := buf.ReadFrom(b.stream(r)); err != nil {
+		return fmt.Errorf("failed to read zip data: %w", err)
+	}
+	zr, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	if err != nil {
+		return fmt.Errorf("failed to create zip reader: %w", err)
+	}
+	files, err := zipEntries(zr.File, opts.Duplicates)
+	if err != nil {
+		return err
+	}
+
+	for _, file := range files {
+		if err := b.entry(); err != nil {
+			return err
+		}
+		name, err := fsName(file.Name)
+		if err != nil {
+			return err
+		}
+		if name == "" {
+			continue
+		}
+		if err := extractZipEntryFS(file, name, dst, opts, b); err != nil {
+			return fmt.Errorf("failed to extract zip entry %s: %w", file.Name, err)
+		}
+	}
+	return nil
+}
+
+func extractZipEntryFS(file *zip.File, name string, dst WritableFS, opts Options, b *budget) error {
+	if file.FileInfo().IsDir() {
+		return dst.MkdirAll(name, opts.dirMode(file.FileInfo().Mode()))
+	}
+	rc, err := file.Open()
+	if err != nil {
+		return fmt.Errorf("failed to open zip file entry: %w", err)
+	}
+	defer rc.Close()
+	return writeFS(dst, name, opts.fileMode(file.FileInfo().Mode()), b.reader(rc))
+}
+
+func writeFS(dst WritableFS, name string, perm fs.FileMode, r io.Reader) error {
+	w, err := dst.Create(name, perm)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(w, r); err != nil {
+		w.Close()
+		return err
+	}
+	return w.Close()
+}
+
+// symlinkFS creates a symlink after checking that target, resolved from the
+// directory of name, stays inside the extraction root.
+func symlinkFS(dst WritableFS, target, name string) error {
+	if path.IsAbs(target) || !fs.ValidPath(path.Join(path.Dir(name), target)) {
+		return fmt.Errorf("invalid symlink target: %s", target)
+	}
+	return dst.Symlink(target, name)
+}
+
+// DirFS returns a WritableFS creating entries under the local directory dir.
+func DirFS(dir string) WritableFS {
+	return dirFS(dir)
+}
+
+type dirFS string
+
+func (d dirFS) path(name string) string {
+	return filepath.Join(string(d), filepath.FromSlash(name))
+}
+
+func (d dirFS) MkdirAll(name string, perm fs.FileMode) error {
+	return os.MkdirAll(d.path(name), perm)
+}
+
+func (d dirFS) Create(name string, perm fs.FileMode) (io.WriteCloser, error) {
+	p := d.path(name)
+	if err := os.MkdirAll(filepath.Dir(p), 0755); err != nil {
+		return nil, err
+	}
+	release := fdlimit.File()
+	f, err := os.OpenFile(p, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, perm)
+	if err != nil {
+		release()
+		return nil, err
+	}
+	return fdlimit.WriteCloser(f, release), nil
+}
+
+func (d dirFS) Symlink(target, name string) error {
+	p := d.path(name)
+	if err := os.MkdirAll(filepath.Dir(p), 0755); err != nil {
+		return err
+	}
+	return os.Symlink(target, p)
+}
+
+// MemFS is an in-memory WritableFS. It is also a read-only fs.FS, so the
+// extracted entries can be read back with the io/fs functions.
+type MemFS struct {
+	mu    sync.Mutex
+	files fstest.MapFS
+}
+
+// NewMemFS returns an empty MemFS.
+func NewMemFS() *MemFS {
+	return &MemFS{files: fstest.MapFS{}}
+}
+
+func (m *MemFS) MkdirAll(name string, perm fs.FileMode) error {
+	m.put(name, &fstest.MapFile{Mode: fs.ModeDir | perm, ModTime: time.Now()})
+	return nil
+}
+
+func (m *MemFS) Create(name string, perm fs.FileMode) (io.WriteCloser, error) {
+	return &memFile{fs: m, name: name, perm: perm}, nil
+}
+
+func (m *MemFS) Symlink(target, name string) error {
+	m.put(name, &fstest.MapFile{Data: []byte(target), Mode: fs.ModeSymlink | 0777, ModTime: time.Now()})
+	return nil
+}
+
+// Open implements fs.FS.
+func (m *MemFS) Open(name string) (fs.File, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.files.Open(name)
+}
+
+// ReadLink implements fs.ReadLinkFS.
+func (m *MemFS) ReadLink(name string) (string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.files.ReadLink(name)
+}
+
+// Lstat implements fs.ReadLinkFS.
+func (m *MemFS) Lstat(name string) (fs.FileInfo, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.files.Lstat(name)
+}
+
+func (m *MemFS) put(name string, f *fstest.MapFile) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.files[name] = f
+}
+
+// memFile buffers the content of a MemFS file until it is closed.
+type memFile struct {
+	bytes.Buffer
+	fs   *MemFS
+	name string
+	perm fs.FileMode
+}
+
+func (f *memFile) Close() error {
+	f.fs.put(f.name, &fstest.MapFile{Data: f.Bytes(), Mode: f.perm, ModTime: time.Now()})
+	return nil
+}
@@ -0,0 +1,60 @@
+package extractor
+
+import (
+	"archive/tar"
+	"bytes"
+	"io/fs"
+	"testing"
+)
+
+func TestExtractFS(t *testing.T) {
+	var buf bytes.Buffer
+	tw := tar.NewWriter(&buf)
+	tw.WriteHeader(&tar.Header{Name: "./bin/", Mode: 0755, Typeflag: tar.TypeDir})
+	tw.WriteHeader(&tar.Header{Name: "./bin/tool", Mode: 0755, Size: 5, Typeflag: tar.TypeReg})
+	tw.Write([]byte("hello"))
+	tw.WriteHeader(&tar.Header{Name: "./tool", Linkname: "bin/tool", Typeflag: tar.TypeSymlink})
+	tw.Close()
+
+	archives := map[string][]byte{
+		"tar.gz": gzipped(buf.Bytes()),
+		"zip":    zipArchive(t, "bin/tool", "hello"),
+	}
+
+	for format, archive := range archives {
+		t.Run(format, func(t *testing.T) {
+			mem := NewMemFS()
+			if err := ExtractFS(bytes.NewReader(archive), mem, Options{}); err != nil {
+				t.Fatalf("ExtractFS() error = %v", err)
+			}
+			got, err := fs.ReadFile(mem, "bin/tool")
+			if err != nil || string(got) != "hello" {
+				t.Errorf("bin/tool = %q, %v", got, err)
+			}
+			if format == "tar.gz" {
+				if target, err := fs.ReadLink(mem, "tool"); err != nil || target != "bin/tool" {
+					t.Errorf("tool links to %q, %v", target, err)
+				}
+			}
+		})
+	}
+}
+
+func TestExtractFS_Traversal(t *testing.T) {
+	var buf bytes.Buffer
+	tw := tar.NewWriter(&buf)
+	tw.WriteHeader(&tar.Header{Name: "bin/link", Linkname: "../../etc/passwd", Typeflag: tar.TypeSymlink})
+	tw.Close()
+
+	tests := map[string][]byte{
+		"file":    gzipped(tarArchive(t, "../escape", "x")),
+		"symlink": gzipped(buf.Bytes()),
+	}
+	for name, archive := range tests {
+		t.Run(name, func(t *testing.T) {
+			if err := ExtractFS(bytes.NewReader(archive), NewMemFS(), Options{}); err == nil {
+				t.Error("ExtractFS() should reject entries escaping the root")
+			}
+		})
+	}
+}
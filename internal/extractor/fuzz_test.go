@@ -0,0 +1,142 @@
+package extractor
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"io/fs"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// fuzzLimits keep the extractions of the fuzz targets small and fast.
+var fuzzLimits = Limits{MaxSize: 1 << 20, MaxEntries: 64, Timeout: 10 * time.Second}
+
+// seedEntry is an entry of a seed archive; link makes it a symlink.
+type seedEntry struct {
+	name, content, link string
+}
+
+func tarSeed(entries ...seedEntry) []byte {
+	var buf bytes.Buffer
+	tw := tar.NewWriter(&buf)
+	for _, e := range entries {
+		hdr := &tar.Header{Name: e.name, Mode: 0644, Size: int64(len(e.content)), Typeflag: tar.TypeReg}
+		switch {
+		case e.link != "":
+			hdr.Typeflag, hdr.Linkname, hdr.Size = tar.TypeSymlink, e.link, 0
+		case strings.HasSuffix(e.name, "/"):
+			hdr.Typeflag, hdr.Mode = tar.TypeDir, 0755
+		}
+		tw.WriteHeader(hdr)
+		tw.Write([]byte(e.content))
+	}
+	tw.Close()
+	return gzipped(buf.Bytes())
+}
+
+func zipSeed(entries ...seedEntry) []byte {
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for _, e := range entries {
+		w, _ := zw.Create(e.name)
+		w.Write([]byte(e.content))
+	}
+	zw.Close()
+	return buf.Bytes()
+}
+
+// checkConfined fails when extracting into dst created anything in root
+// outside dst, or wrote more than the limits allow.
+func checkConfined(t *testing.T, root, dst string) {
+	t.Helper()
+	var size int64
+	filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil || path == root || strings.HasPrefix(dst, path+string(filepath.Separator)) {
+			return nil
+		}
+		if path != dst && !strings.HasPrefix(path, dst+string(filepath.Separator)) {
+			t.Fatalf("extraction created %s outside of %s", path, dst)
+		}
+		if info, err := d.Info(); err == nil && info.Mode().IsRegular() {
+			size += info.Size()
+		}
+		return nil
+	})
+	if size > fuzzLimits.MaxSize {
+		t.Fatalf("extraction wrote %d bytes, limit %d", size, fuzzLimits.MaxSize)
+	}
+}
+
+// fuzzExtract extracts data with every Go implementation, which must not
+// panic nor leave the destination, whether or not data is a valid archive.
+func fuzzExtract(t *testing.T, data []byte) {
+	for name, ext := range map[string]interface {
+		Extractor
+		Configurer
+	}{
+		"legacy":    NewLegacy(),
+		"optimized": NewOptimized(),
+	} {
+		root := t.TempDir()
+		// Escapes land inside root, where checkConfined finds them.
+		dst := filepath.Join(root, "a", "b", name)
+		ext.SetOptions(Options{Limits: fuzzLimits})
+		ext.Extract(bytes.NewReader(data), dst)
+		checkConfined(t, root, dst)
+	}
+
+	ExtractFS(bytes.NewReader(data), NewMemFS(), Options{Limits: fuzzLimits})
+}
+
+func FuzzDetectFormat(f *testing.F) {
+	f.Add([]byte{})
+	f.Add([]byte{0x1f, 0x8b})
+	f.Add([]byte("PK\x03\x04"))
+	f.Add([]byte("PK\x05\x06"))
+	f.Add(tarSeed(seedEntry{name: "app", content: "binary"}))
+	f.Add(zipSeed(seedEntry{name: "app", content: "binary"}))
+
+	f.Fuzz(func(t *testing.T, data []byte) {
+		format, err := detectFormat(bytes.NewReader(data), int64(len(data)))
+		if err != nil {
+			return
+		}
+		if got := detectFormatFromBytes(data[:4]); got != format {
+			t.Errorf("detectFormat() = %q, detectFormatFromBytes() = %q", format, got)
+		}
+	})
+}
+
+func FuzzExtractTarGz(f *testing.F) {
+	f.Add(tarSeed(seedEntry{name: "app", content: "binary"}))
+	f.Add(tarSeed(
+		seedEntry{name: "bin/"},
+		seedEntry{name: "bin/app", content: "binary"},
+		seedEntry{name: "app", link: "bin/app"},
+		seedEntry{name: "bin/lib", link: "../lib"},
+	))
+	f.Add(tarSeed(seedEntry{name: "../evil", content: "x"}))
+	f.Add(tarSeed(seedEntry{name: "/etc/evil", content: "x"}))
+	f.Add(tarSeed(seedEntry{name: "out", link: "/tmp"}, seedEntry{name: "out/evil", content: "x"}))
+	f.Add(tarSeed(seedEntry{name: "up", link: ".."}, seedEntry{name: "up/evil", content: "x"}))
+	f.Add(gzipped(tarSeed(seedEntry{name: "app", content: "binary"})))
+	f.Add(gzipped(zipSeed(seedEntry{name: "app", content: "binary"})))
+
+	f.Fuzz(fuzzExtract)
+}
+
+func FuzzExtractZip(f *testing.F) {
+	f.Add(zipSeed(seedEntry{name: "app", content: "binary"}))
+	f.Add(zipSeed(
+		seedEntry{name: "bin/"},
+		seedEntry{name: "bin/app", content: "binary"},
+	))
+	f.Add(zipSeed(seedEntry{name: "tool", content: "first"}, seedEntry{name: "./tool", content: "last"}))
+	f.Add(zipSeed(seedEntry{name: "../evil", content: "x"}))
+	f.Add(zipSeed(seedEntry{name: "big", content: strings.Repeat("0", 2<<20)}))
+
+	f.Fuzz(fuzzExtract)
+}
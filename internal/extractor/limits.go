@@ -0,0 +1,106 @@
+package extractor
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"time"
+)
+
+// Limits bound what extracting a single archive may do, so a hostile archive
+// (a zip or gzip bomb, millions of empty entries, a stream that never ends)
+// cannot exhaust the disk, the memory or the time of an install. Zero fields
+// select their DefaultLimits value. The system extractor ignores them.
+type Limits struct {
+	// MaxSize bounds the bytes written, summed over all entries. It also
+	// bounds the archive data held in memory or in temp files while
+	// extracting a zip archive.
+	MaxSize int64
+	// MaxEntries bounds the number of entries.
+	MaxEntries int
+	// Timeout bounds the duration of the extraction.
+	Timeout time.Duration
+}
+
+// DefaultLimits are generous enough for any real release asset.
+var DefaultLimits = Limits{
+	MaxSize:    32 << 30,
+	MaxEntries: 1 << 20,
+	Timeout:    time.Hour,
+}
+
+// ErrLimitExceeded is returned when an archive exceeds the Limits.
+var ErrLimitExceeded = errors.New("archive exceeds extraction limits")
+
+// budget tracks one extraction against its limits.
+type budget struct {
+	limits   Limits
+	deadline time.Time
+	written  int64
+	entries  int
+}
+
+// start returns the budget of an extraction starting now.
+func (l Limits) start() *budget {
+	if l.MaxSize <= 0 {
+		l.MaxSize = DefaultLimits.MaxSize
+	}
+	if l.MaxEntries <= 0 {
+		l.MaxEntries = DefaultLimits.MaxEntries
+	}
+	if l.Timeout <= 0 {
+		l.Timeout = DefaultLimits.Timeout
+	}
+	return &budget{limits: l, deadline: time.Now().Add(l.Timeout)}
+}
+
+// entry counts an archive entry.
+func (b *budget) entry() error {
+	if b.entries++; b.entries > b.limits.MaxEntries {
+		return fmt.Errorf("%w: more than %d entries", ErrLimitExceeded, b.limits.MaxEntries)
+	}
+	return b.check()
+}
+
+// check fails once the extraction ran out of time.
+func (b *budget) check() error {
+	if time.Now().After(b.deadline) {
+		return fmt.Errorf("%w: not done within %s", ErrLimitExceeded, b.limits.Timeout)
+	}
+	return nil
+}
+
+// reader returns r failing once the bytes read from it and every other
+// reader of b exceed MaxSize, or the time is up.
+func (b *budget) reader(r io.Reader) io.Reader {
+	return &budgetReader{r: r, b: b, n: &b.written}
+}
+
+// stream returns r failing once more than MaxSize bytes are read from it
+// alone, for data buffered before its entries are extracted.
+func (b *budget) stream(r io.Reader) io.Reader {
+	return &budgetReader{r: r, b: b, n: new(int64)}
+}
+
+type budgetReader struct {
+	r io.Reader
+	b *budget
+	n *int64
+}
+
+func (br *budgetReader) Read(p []byte) (int, error) {
+	if err := br.b.check(); err != nil {
+		return 0, err
+	}
+	// Read one byte beyond the limit to tell an archive of exactly MaxSize
+	// bytes from a larger one.
+	if left := br.b.limits.MaxSize - *br.n + 1; int64(len(p)) > left {
+		p = p[:left]
+	}
+	n, err := br.r.Read(p)
+	*br.n += int64(n)
+	if over := *br.n - br.b.limits.MaxSize; over > 0 {
+		return n - int(over), fmt.Errorf("%w: more than %d bytes", ErrLimitExceeded, br.b.limits.MaxSize)
+	}
+	return n, err
+}
@@ -5,17 +5,20 @@ import (
 	"archive/zip"
 	"bufio"
 	"bytes"
-	"compress/gzip"
 	"fmt"
 	"io"
 	"os"
 	"path/filepath"
 	"strings"
+
+	"github.com/sixban6/ghinstall/internal/fdlimit"
 )
 
 // OptimizedExtractor provides better performance by avoiding unnecessary memory copies
 type OptimizedExtractor struct {
 	bufferSize int
+	events     EventFunc
+	opts       Options
 }
 
 func NewOptimized() *OptimizedExtractor {
@@ -39,18 +42,33 @@ func (e *OptimizedExtractor) Extract(src io.Reader, dst string) error {
 	}
 
 	format := detectFormatFromBytes(peek)
-	
+	b := e.opts.Limits.start()
+
 	switch format {
 	case "tar.gz", "tgz":
-		return e.extractTarGzStream(bufferedSrc, dst)
+		return e.extractTarGzStream(bufferedSrc, dst, b)
 	case "zip":
 		// Zip requires seeking, so we need to read all data
-		return e.extractZipFromReader(bufferedSrc, dst)
+		return e.extractZipFromReader(bufferedSrc, dst, b)
 	default:
 		return fmt.Errorf("unsupported archive format")
 	}
 }
 
+// ExtractWithEvents extracts like Extract, reporting every entry to fn.
+func (e *OptimizedExtractor) ExtractWithEvents(src io.Reader, dst string, fn EventFunc) error {
+	c := *e
+	c.events = fn
+	return c.Extract(src, dst)
+}
+
+// SetOptions changes how the following extractions unpack archives.
+func (e *OptimizedExtractor) SetOptions(opts Options) {
+	if e.opts != opts {
+		e.opts = opts
+	}
+}
+
 func detectFormatFromBytes(data []byte) string {
 	if len(data) < 4 {
 		return ""
@@ -68,12 +86,14 @@ func detectFormatFromBytes(data []byte) string {
 }
 
 // Optimized tar.gz extraction using streaming
-func (e *OptimizedExtractor) extractTarGzStream(src io.Reader, dst string) error {
-	gzReader, err := gzip.NewReader(src)
+func (e *OptimizedExtractor) extractTarGzStream(src io.Reader, dst string, b *budget) error {
+	gzReader, err := openGzip(src)
 	if err != nil {
-		return fmt.Errorf("failed to create gzip reader: %w", err)
+		return err
+	}
+	if isZip(gzReader) {
+		return e.extractZipFromReader(gzReader, dst, b)
 	}
-	defer gzReader.Close()
 
 	tarReader := tar.NewReader(gzReader)
 
@@ -86,7 +106,10 @@ func (e *OptimizedExtractor) extractTarGzStream(src io.Reader, dst string) error
 			return fmt.Errorf("failed to read tar entry: %w", err)
 		}
 
-		if err := e.extractTarEntryOptimized(tarReader, header, dst); err != nil {
+		if err := b.entry(); err != nil {
+			return err
+		}
+		if err := e.extractTarEntryOptimized(b.reader(tarReader), header, dst); err != nil {
 			return fmt.Errorf("failed to extract tar entry %s: %w", header.Name, err)
 		}
 	}
@@ -94,7 +117,7 @@ func (e *OptimizedExtractor) extractTarGzStream(src io.Reader, dst string) error
 	return nil
 }
 
-func (e *OptimizedExtractor) extractTarEntryOptimized(reader *tar.Reader, header *tar.Header, dst string) error {
+func (e *OptimizedExtractor) extractTarEntryOptimized(reader io.Reader, header *tar.Header, dst string) error {
 	path := filepath.Join(dst, header.Name)
 	cleanedDst := filepath.Clean(dst)
 	cleanedPath := filepath.Clean(path)
@@ -112,15 +135,16 @@ func (e *OptimizedExtractor) extractTarEntryOptimized(reader *tar.Reader, header
 
 	switch header.Typeflag {
 	case tar.TypeDir:
-		return os.MkdirAll(path, os.FileMode(header.Mode))
+		e.events.entry(header.Name)
+		return os.MkdirAll(path, e.opts.dirMode(tarMode(header.Mode)))
 	case tar.TypeReg:
-		return e.extractFileOptimized(reader, path, os.FileMode(header.Mode))
+		return e.extractFileOptimized(e.events.track(header.Name, header.Size, reader), path, e.opts.fileMode(tarMode(header.Mode)))
 	case tar.TypeSymlink:
-		linkTarget := header.Linkname
-		if !strings.HasPrefix(filepath.Join(dst, linkTarget), dst) {
-			return fmt.Errorf("invalid symlink target: %s", linkTarget)
+		if err := checkSymlink(header.Linkname, cleanedPath, cleanedDst); err != nil {
+			return err
 		}
-		return os.Symlink(linkTarget, path)
+		e.events.entry(header.Name)
+		return os.Symlink(header.Linkname, path)
 	default:
 		return nil
 	}
@@ -132,6 +156,7 @@ func (e *OptimizedExtractor) extractFileOptimized(reader io.Reader, path string,
 		return fmt.Errorf("failed to create directory for file %s: %w", path, err)
 	}
 
+	defer fdlimit.File()()
 	outFile, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, mode)
 	if err != nil {
 		return fmt.Errorf("failed to create file %s: %w", path, err)
@@ -153,10 +178,10 @@ func (e *OptimizedExtractor) extractFileOptimized(reader io.Reader, path string,
 }
 
 // For ZIP files, we still need to read all data due to seeking requirements
-func (e *OptimizedExtractor) extractZipFromReader(src io.Reader, dst string) error {
+func (e *OptimizedExtractor) extractZipFromReader(src io.Reader, dst string, b *budget) error {
 	// Use bytes.Buffer for better memory management
 	var buf bytes.Buffer
-	_, err := buf.ReadFrom(src)
+	_, err := buf.ReadFrom(b.stream(src))
 	if err != nil {
 		return fmt.Errorf("failed to read zip data: %w", err)
 	}
@@ -166,8 +191,15 @@ func (e *OptimizedExtractor) extractZipFromReader(src io.Reader, dst string) err
 		return fmt.Errorf("failed to create zip reader: %w", err)
 	}
 
-	for _, file := range reader.File {
-		if err := e.extractZipEntryOptimized(file, dst); err != nil {
+	files, err := zipEntries(reader.File, e.opts.Duplicates)
+	if err != nil {
+		return err
+	}
+	for _, file := range files {
+		if err := b.entry(); err != nil {
+			return err
+		}
+		if err := e.extractZipEntryOptimized(file, dst, b); err != nil {
 			return fmt.Errorf("failed to extract zip entry %s: %w", file.Name, err)
 		}
 	}
@@ -175,7 +207,7 @@ func (e *OptimizedExtractor) extractZipFromReader(src io.Reader, dst string) err
 	return nil
 }
 
-func (e *OptimizedExtractor) extractZipEntryOptimized(file *zip.File, dst string) error {
+func (e *OptimizedExtractor) extractZipEntryOptimized(file *zip.File, dst string, b *budget) error {
 	path := filepath.Join(dst, file.Name)
 	cleanedDst := filepath.Clean(dst)
 	cleanedPath := filepath.Clean(path)
@@ -192,7 +224,8 @@ func (e *OptimizedExtractor) extractZipEntryOptimized(file *zip.File, dst string
 	}
 
 	if file.FileInfo().IsDir() {
-		return os.MkdirAll(path, file.FileInfo().Mode())
+		e.events.entry(file.Name)
+		return os.MkdirAll(path, e.opts.dirMode(file.FileInfo().Mode()))
 	}
 
 	fileReader, err := file.Open()
@@ -201,5 +234,5 @@ func (e *OptimizedExtractor) extractZipEntryOptimized(file *zip.File, dst string
 	}
 	defer fileReader.Close()
 
-	return e.extractFileOptimized(fileReader, path, file.FileInfo().Mode())
+	return e.extractFileOptimized(e.events.track(file.Name, int64(file.UncompressedSize64), b.reader(fileReader)), path, e.opts.fileMode(file.FileInfo().Mode()))
 }
\ No newline at end of file
@@ -0,0 +1,137 @@
+package extractor
+
+import (
+	"archive/zip"
+	"fmt"
+	log "github.com/sixban6/ghinstall/internal/logger"
+	"os"
+	"path"
+)
+
+// DuplicatePolicy decides which copy of a zip entry is extracted when the
+// archive contains the same path more than once.
+type DuplicatePolicy string
+
+const (
+	// DuplicateLastWins extracts the last copy, like unzip does. It is the default.
+	DuplicateLastWins DuplicatePolicy = "last-wins"
+	// DuplicateFirstWins extracts the first copy and skips the others.
+	DuplicateFirstWins DuplicatePolicy = "first-wins"
+	// DuplicateError fails the extraction.
+	DuplicateError DuplicatePolicy = "error"
+)
+
+// Permissions given to entries whose archive records none.
+const (
+	DefaultFileMode os.FileMode = 0644
+	DefaultDirMode  os.FileMode = 0755
+)
+
+// Options tune how archives are unpacked.
+type Options struct {
+	// Duplicates applies to zip entries sharing a path. Paths are compared
+	// exactly, so entries differing only by case are distinct.
+	Duplicates DuplicatePolicy
+	// FileMode and DirMode replace the permissions of files and directories
+	// stored with mode 0, which would otherwise be unreadable. Zero selects
+	// DefaultFileMode and DefaultDirMode.
+	FileMode os.FileMode
+	DirMode  os.FileMode
+	// Isolation sandboxes the tools started by the system extractor; the Go
+	// implementation ignores it.
+	Isolation Isolation
+	// Limits bound the size, entries and duration of an extraction.
+	Limits Limits
+}
+
+// Isolation selects how the system extractor isolates tar and unzip from the
+// rest of the system. Whatever the isolation, they run in the destination
+// directory with a scrubbed environment.
+type Isolation string
+
+const (
+	// IsolationNone runs the tools directly. It is the default.
+	IsolationNone Isolation = ""
+	// IsolationBwrap runs the tools in a bubblewrap sandbox on Linux, seeing a
+	// read-only root file system with only the destination writable and no
+	// network.
+	IsolationBwrap Isolation = "bwrap"
+	// IsolationUnshare runs the tools in new user, network, IPC and PID
+	// namespaces with unshare on Linux; the file system is not restricted.
+	IsolationUnshare Isolation = "unshare"
+)
+
+// fileMode returns the permissions to create a file stored with mode.
+func (o Options) fileMode(mode os.FileMode) os.FileMode {
+	return orDefault(mode, o.FileMode, DefaultFileMode)
+}
+
+// dirMode returns the permissions to create a directory stored with mode.
+func (o Options) dirMode(mode os.FileMode) os.FileMode {
+	return orDefault(mode, o.DirMode, DefaultDirMode)
+}
+
+func orDefault(mode, configured, def os.FileMode) os.FileMode {
+	if perm := mode & os.ModePerm; perm != 0 {
+		return perm
+	}
+	if configured != 0 {
+		return configured
+	}
+	return def
+}
+
+// tarMode converts the mode of a tar header, which may carry file type bits
+// or be garbage, to permissions; 0 when it is invalid.
+func tarMode(mode int64) os.FileMode {
+	if mode < 0 {
+		return 0
+	}
+	return os.FileMode(mode) & os.ModePerm
+}
+
+// Configurer is implemented by extractors whose behaviour can be tuned.
+// Setting the current options again changes nothing, so parallel installs
+// sharing a configured extractor may all apply the same options.
+type Configurer interface {
+	SetOptions(opts Options)
+}
+
+// zipEntries returns the entries of files to extract, resolving duplicated
+// paths according to policy and logging every duplicate found.
+func zipEntries(files []*zip.File, policy DuplicatePolicy) ([]*zip.File, error) {
+	count := make(map[string]int, len(files))
+	for _, f := range files {
+		if !f.FileInfo().IsDir() {
+			count[path.Clean(f.Name)]++
+		}
+	}
+
+	seen := make(map[string]int, len(files))
+	entries := make([]*zip.File, 0, len(files))
+	for _, f := range files {
+		name := path.Clean(f.Name)
+		n := count[name]
+		if n < 2 {
+			entries = append(entries, f)
+			continue
+		}
+		seen[name]++
+
+		switch policy {
+		case DuplicateError:
+			return nil, fmt.Errorf("zip entry %s occurs %d times", f.Name, n)
+		case DuplicateFirstWins:
+			if seen[name] == 1 {
+				log.Warn("Zip entry %s occurs %d times; extracting the first copy", f.Name, n)
+				entries = append(entries, f)
+			}
+		default:
+			if seen[name] == n {
+				log.Warn("Zip entry %s occurs %d times; extracting the last copy", f.Name, n)
+				entries = append(entries, f)
+			}
+		}
+	}
+	return entries, nil
+}
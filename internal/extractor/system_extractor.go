@@ -1,3 +1,5 @@
+//go:build !purego && !wasip1
+
 package extractor
 
 import (
@@ -5,18 +7,83 @@ import (
 	"io"
 	"os"
 	"os/exec"
+	"path/filepath"
 	"runtime"
+
+	"github.com/sixban6/ghinstall/internal/tmpdir"
 )
 
 // SystemExtractor uses system commands for better performance
 type SystemExtractor struct {
 	tempDir string
+	opts    Options
 }
 
 func NewSystem() *SystemExtractor {
 	return &SystemExtractor{}
 }
 
+// SetOptions changes how the following extractions run the system tools.
+func (e *SystemExtractor) SetOptions(opts Options) {
+	if e.opts != opts {
+		e.opts = opts
+	}
+}
+
+// command returns the command running the tool name with args to extract
+// into dst: in dst, with a scrubbed environment instead of the caller's, and
+// isolated as configured.
+func (e *SystemExtractor) command(dst, name string, args ...string) (*exec.Cmd, error) {
+	path, err := exec.LookPath(name)
+	if err != nil {
+		return nil, err
+	}
+	dst, err = filepath.Abs(dst)
+	if err != nil {
+		return nil, err
+	}
+
+	var cmd *exec.Cmd
+	switch e.opts.Isolation {
+	case IsolationNone:
+		cmd = exec.Command(path, args...)
+	case IsolationBwrap, IsolationUnshare:
+		if runtime.GOOS != "linux" {
+			return nil, fmt.Errorf("%s isolation is only supported on Linux", e.opts.Isolation)
+		}
+		wrapper, err := exec.LookPath(string(e.opts.Isolation))
+		if err != nil {
+			return nil, fmt.Errorf("%s isolation is configured but unavailable: %w", e.opts.Isolation, err)
+		}
+		cmd = exec.Command(wrapper, append(isolationArgs(e.opts.Isolation, dst), append([]string{path}, args...)...)...)
+	default:
+		return nil, fmt.Errorf("unknown isolation %q", e.opts.Isolation)
+	}
+
+	cmd.Dir = dst
+	cmd.Env = scrubbedEnv()
+	setProcAttr(cmd)
+	return cmd, nil
+}
+
+// isolationArgs returns the arguments of the isolation wrapper preceding the
+// command it runs.
+func isolationArgs(isolation Isolation, dst string) []string {
+	if isolation == IsolationBwrap {
+		return []string{
+			"--ro-bind", "/", "/",
+			"--bind", dst, dst,
+			"--dev", "/dev",
+			"--unshare-all",
+			"--die-with-parent",
+			"--new-session",
+			"--chdir", dst,
+			"--",
+		}
+	}
+	return []string{"--user", "--map-root-user", "--net", "--ipc", "--pid", "--fork", "--"}
+}
+
 func (e *SystemExtractor) Extract(src io.Reader, dst string) error {
 	if err := os.MkdirAll(dst, 0755); err != nil {
 		return fmt.Errorf("failed to create destination directory %s: %w", dst, err)
@@ -54,7 +121,7 @@ func (e *SystemExtractor) Extract(src io.Reader, dst string) error {
 }
 
 func (e *SystemExtractor) createTempFile(src io.Reader) (string, error) {
-	tempFile, err := os.CreateTemp("", "ghinstall-*.tmp")
+	tempFile, err := os.CreateTemp(tmpdir.Dir(), "ghinstall-*.tmp")
 	if err != nil {
 		return "", fmt.Errorf("failed to create temp file: %w", err)
 	}
@@ -89,7 +156,7 @@ func (e *SystemExtractor) detectFormatFromFile(filename string) (string, error)
 
 func (e *SystemExtractor) extractTarGzSystem(archivePath, dst string) error {
 	switch runtime.GOOS {
-	case "linux", "darwin":
+	case "linux", "darwin", "freebsd", "openbsd", "netbsd", "dragonfly", "illumos", "solaris":
 		return e.extractWithTar(archivePath, dst)
 	case "windows":
 		return e.extractWithPowerShell(archivePath, dst)
@@ -106,9 +173,28 @@ func (e *SystemExtractor) extractTarGzSystem(archivePath, dst string) error {
 	}
 }
 
+// tarCommand returns the tar of this platform able to extract tar.gz archives
+// and the flags keeping it from restoring the archived ownership, "" when
+// there is none.
+func tarCommand() (string, []string) {
+	switch runtime.GOOS {
+	case "openbsd":
+		// OpenBSD's tar has no long options and restores ownership only with -p.
+		return "tar", nil
+	case "illumos", "solaris":
+		// The native tar cannot decompress; GNU tar is usually installed as gtar.
+		if _, err := exec.LookPath("gtar"); err == nil {
+			return "gtar", []string{"--no-same-owner"}
+		}
+		return "", nil
+	}
+	return "tar", []string{"--no-same-owner"}
+}
+
 func (e *SystemExtractor) extractWithTar(archivePath, dst string) error {
 	// Check if tar command exists
-	if _, err := exec.LookPath("tar"); err != nil {
+	tar, ownership := tarCommand()
+	if _, err := exec.LookPath(tar); tar == "" || err != nil {
 		// Fallback to Go implementation
 		file, err := os.Open(archivePath)
 		if err != nil {
@@ -121,14 +207,15 @@ func (e *SystemExtractor) extractWithTar(archivePath, dst string) error {
 	}
 
 	// Use system tar command
-	cmd := exec.Command("tar", 
+	args := []string{
 		"-xzf", archivePath,  // extract gzip compressed tar
 		"-C", dst,            // change to directory
-		"--no-same-owner",    // don't try to restore ownership
-	)
-	
-	// Set process attributes (platform-specific implementation)
-	setProcAttr(cmd)
+	}
+	args = append(args, ownership...) // don't try to restore ownership
+	cmd, err := e.command(dst, tar, args...)
+	if err != nil {
+		return err
+	}
 	
 	// Capture both stdout and stderr
 	output, err := cmd.CombinedOutput()
@@ -155,7 +242,10 @@ func (e *SystemExtractor) extractWithPowerShell(archivePath, dst string) error {
 		}
 	`, archivePath, dst, archivePath, dst)
 
-	cmd := exec.Command("powershell", "-Command", script)
+	cmd, err := e.command(dst, "powershell", "-Command", script)
+	if err != nil {
+		return err
+	}
 	output, err := cmd.CombinedOutput()
 	if err != nil {
 		return fmt.Errorf("powershell extraction failed: %w, output: %s", err, string(output))
@@ -166,7 +256,8 @@ func (e *SystemExtractor) extractWithPowerShell(archivePath, dst string) error {
 
 func (e *SystemExtractor) extractZipSystem(archivePath, dst string) error {
 	switch runtime.GOOS {
-	case "linux":
+	case "linux", "freebsd", "openbsd", "netbsd", "dragonfly", "illumos", "solaris":
+		// unzip is an optional package on all of them.
 		return e.extractZipLinux(archivePath, dst)
 	case "darwin":
 		return e.extractZipDarwin(archivePath, dst)
@@ -199,7 +290,10 @@ func (e *SystemExtractor) extractZipWindows(archivePath, dst string) error {
 		}
 	`, archivePath, dst)
 
-	cmd := exec.Command("powershell", "-Command", script)
+	cmd, err := e.command(dst, "powershell", "-Command", script)
+	if err != nil {
+		return err
+	}
 	output, err := cmd.CombinedOutput()
 	if err != nil {
 		return fmt.Errorf("powershell zip extraction failed: %w, output: %s", err, string(output))
@@ -221,6 +315,12 @@ func NewSystemWithFallback() *SystemExtractorWithFallback {
 	}
 }
 
+// SetOptions changes how the following extractions unpack archives.
+func (e *SystemExtractorWithFallback) SetOptions(opts Options) {
+	e.system.SetOptions(opts)
+	e.fallback.SetOptions(opts)
+}
+
 func (e *SystemExtractorWithFallback) Extract(src io.Reader, dst string) error {
 	// Try system extractor first
 	err := e.system.Extract(src, dst)
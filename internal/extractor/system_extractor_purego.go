@@ -0,0 +1,43 @@
+//go:build purego || wasip1
+
+package extractor
+
+import "io"
+
+// SystemExtractor shells out to tar, unzip or PowerShell in regular builds.
+// purego and wasip1 builds never start processes, so it extracts with the Go
+// implementation instead.
+type SystemExtractor struct {
+	optimized *OptimizedExtractor
+}
+
+func NewSystem() *SystemExtractor {
+	return &SystemExtractor{optimized: NewOptimized()}
+}
+
+func (e *SystemExtractor) Extract(src io.Reader, dst string) error {
+	return e.optimized.Extract(src, dst)
+}
+
+// SetOptions changes how the following extractions unpack archives.
+func (e *SystemExtractor) SetOptions(opts Options) {
+	e.optimized.SetOptions(opts)
+}
+
+// SystemExtractorWithFallback is the Go implementation in purego and wasip1 builds.
+type SystemExtractorWithFallback struct {
+	system *SystemExtractor
+}
+
+func NewSystemWithFallback() *SystemExtractorWithFallback {
+	return &SystemExtractorWithFallback{system: NewSystem()}
+}
+
+func (e *SystemExtractorWithFallback) Extract(src io.Reader, dst string) error {
+	return e.system.Extract(src, dst)
+}
+
+// SetOptions changes how the following extractions unpack archives.
+func (e *SystemExtractorWithFallback) SetOptions(opts Options) {
+	e.system.SetOptions(opts)
+}
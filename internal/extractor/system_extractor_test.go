@@ -0,0 +1,59 @@
+//go:build !windows && !purego && !wasip1
+
+package extractor
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestSystemExtractor_Command(t *testing.T) {
+	t.Setenv("GHINSTALL_SECRET", "leaked")
+	dst := t.TempDir()
+
+	e := NewSystem()
+	cmd, err := e.command(dst, "sh", "-c", "true")
+	if err != nil {
+		t.Skip(err)
+	}
+	if cmd.Dir != dst {
+		t.Errorf("command dir = %s, want %s", cmd.Dir, dst)
+	}
+	if slices.ContainsFunc(cmd.Env, func(kv string) bool { return strings.HasPrefix(kv, "GHINSTALL_SECRET=") }) {
+		t.Errorf("command environment %v contains the caller's variables", cmd.Env)
+	}
+
+	e.SetOptions(Options{Isolation: "chroot"})
+	if _, err := e.command(dst, "sh"); err == nil {
+		t.Error("command() accepted an unknown isolation")
+	}
+}
+
+func TestSystemExtractor_Isolation(t *testing.T) {
+	for _, isolation := range []Isolation{IsolationBwrap, IsolationUnshare} {
+		t.Run(string(isolation), func(t *testing.T) {
+			dst := t.TempDir()
+			e := NewSystem()
+			e.SetOptions(Options{Isolation: isolation})
+
+			cmd, err := e.command(dst, "tar", "--version")
+			if err != nil {
+				t.Skip(err)
+			}
+			if err := cmd.Run(); err != nil {
+				t.Skipf("%s cannot create namespaces here: %v", isolation, err)
+			}
+
+			if err := e.Extract(bytes.NewReader(gzipped(tarArchive(t, "app", "binary"))), dst); err != nil {
+				t.Fatalf("Extract() error = %v", err)
+			}
+			if data, err := os.ReadFile(filepath.Join(dst, "app")); err != nil || string(data) != "binary" {
+				t.Errorf("extracted app = %q, %v", data, err)
+			}
+		})
+	}
+}
@@ -1,4 +1,4 @@
-//go:build !windows
+//go:build !windows && !purego && !wasip1
 
 package extractor
 
@@ -13,12 +13,26 @@ func setProcAttr(cmd *exec.Cmd) {
 	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
 }
 
+// scrubbedEnv returns the environment of the system tools: only what tar needs
+// to find gzip and its temp directory, in the C locale.
+func scrubbedEnv() []string {
+	env := []string{"LC_ALL=C"}
+	for _, key := range []string{"PATH", "TMPDIR"} {
+		if value, ok := os.LookupEnv(key); ok {
+			env = append(env, key+"="+value)
+		}
+	}
+	return env
+}
+
 func (e *SystemExtractor) extractZipLinux(archivePath, dst string) error {
 	// Try unzip command first
 	if _, err := exec.LookPath("unzip"); err == nil {
-		cmd := exec.Command("unzip", "-q", "-o", archivePath, "-d", dst)
-		setProcAttr(cmd)
-		
+		cmd, err := e.command(dst, "unzip", "-q", "-o", archivePath, "-d", dst)
+		if err != nil {
+			return err
+		}
+
 		output, err := cmd.CombinedOutput()
 		if err != nil {
 			return fmt.Errorf("unzip command failed: %w, output: %s", err, string(output))
@@ -39,9 +53,11 @@ func (e *SystemExtractor) extractZipLinux(archivePath, dst string) error {
 
 func (e *SystemExtractor) extractZipDarwin(archivePath, dst string) error {
 	// macOS has built-in unzip
-	cmd := exec.Command("unzip", "-q", "-o", archivePath, "-d", dst)
-	setProcAttr(cmd)
-	
+	cmd, err := e.command(dst, "unzip", "-q", "-o", archivePath, "-d", dst)
+	if err != nil {
+		return err
+	}
+
 	output, err := cmd.CombinedOutput()
 	if err != nil {
 		return fmt.Errorf("unzip command failed: %w, output: %s", err, string(output))
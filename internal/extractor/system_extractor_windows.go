@@ -1,13 +1,28 @@
-//go:build windows
+//go:build windows && !purego
 
 package extractor
 
-import "os/exec"
+import (
+	"os"
+	"os/exec"
+)
 
 func setProcAttr(cmd *exec.Cmd) {
 	// Windows doesn't support Setpgid, so we do nothing here
 }
 
+// scrubbedEnv returns the environment of PowerShell and tar: only the
+// variables Windows programs need to start and find their temp directory.
+func scrubbedEnv() []string {
+	var env []string
+	for _, key := range []string{"SystemRoot", "SystemDrive", "windir", "ComSpec", "Path", "PATHEXT", "TEMP", "TMP"} {
+		if value, ok := os.LookupEnv(key); ok {
+			env = append(env, key+"="+value)
+		}
+	}
+	return env
+}
+
 func (e *SystemExtractor) extractZipLinux(archivePath, dst string) error {
 	// This shouldn't be called on Windows, but provide fallback
 	return e.extractZipWindows(archivePath, dst)
@@ -0,0 +1,131 @@
+package extractor
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"fmt"
+	"io"
+	"io/fs"
+	"path"
+	"strings"
+)
+
+// Entry describes a file, directory or symlink stored in an archive.
+type Entry struct {
+	// Name is the slash-separated path relative to the extraction root.
+	Name string
+	// Mode holds the permission bits and the type (fs.ModeDir, fs.ModeSymlink).
+	Mode fs.FileMode
+	Size int64
+	// Link is the target of a symlink.
+	Link string
+}
+
+// Walk calls fn for every entry of the tar.gz or zip archive in r, in archive
+// order, without extracting anything. For regular files content streams the
+// entry's data; it is empty for other entries. Walk stops at the first error
+// returned by fn.
+func Walk(r io.ReaderAt, size int64, fn func(e Entry, content io.Reader) error) error {
+	magic := make([]byte, 4)
+	if _, err := r.ReadAt(magic, 0); err != nil {
+		return fmt.Errorf("failed to read file header: %w", err)
+	}
+
+	switch detectFormatFromBytes(magic) {
+	case "zip":
+		return walkZip(r, size, fn)
+	case "tar.gz":
+		br, _, err := peelGzip(io.NewSectionReader(r, 0, size))
+		if err != nil {
+			return err
+		}
+		if isZip(br) {
+			data, err := io.ReadAll(br)
+			if err != nil {
+				return fmt.Errorf("decompress zip file: %w", err)
+			}
+			return walkZip(bytes.NewReader(data), int64(len(data)), fn)
+		}
+		return walkTar(br, fn)
+	default:
+		return fmt.Errorf("unknown archive format")
+	}
+}
+
+func walkTar(r io.Reader, fn func(e Entry, content io.Reader) error) error {
+	tr := tar.NewReader(r)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("read tar entry: %w", err)
+		}
+
+		name, ok := entryName(hdr.Name)
+		if !ok {
+			continue
+		}
+		e := Entry{Name: name, Mode: fs.FileMode(hdr.Mode).Perm()}
+		switch hdr.Typeflag {
+		case tar.TypeDir:
+			e.Mode |= fs.ModeDir
+		case tar.TypeReg:
+			e.Size = hdr.Size
+		case tar.TypeSymlink:
+			e.Mode |= fs.ModeSymlink
+			e.Link = hdr.Linkname
+		default:
+			continue
+		}
+		if err := fn(e, tr); err != nil {
+			return err
+		}
+	}
+}
+
+func walkZip(r io.ReaderAt, size int64, fn func(e Entry, content io.Reader) error) error {
+	zr, err := zip.NewReader(r, size)
+	if err != nil {
+		return fmt.Errorf("create zip reader: %w", err)
+	}
+
+	for _, file := range zr.File {
+		name, ok := entryName(file.Name)
+		if !ok {
+			continue
+		}
+		info := file.FileInfo()
+		e := Entry{Name: name, Mode: info.Mode()}
+		if info.Mode().IsRegular() {
+			e.Size = int64(file.UncompressedSize64)
+		}
+
+		content, err := file.Open()
+		if err != nil {
+			return fmt.Errorf("failed to open zip file entry: %w", err)
+		}
+		if e.Mode&fs.ModeSymlink != 0 {
+			target, err := io.ReadAll(content)
+			if err != nil {
+				content.Close()
+				return fmt.Errorf("failed to read zip symlink %s: %w", file.Name, err)
+			}
+			e.Link = string(target)
+		}
+		err = fn(e, content)
+		content.Close()
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// entryName normalizes an archive path; it reports false for the root entry.
+func entryName(name string) (string, bool) {
+	name = strings.TrimPrefix(path.Clean("/"+name), "/")
+	return name, name != ""
+}
@@ -0,0 +1,174 @@
+// Package fdlimit bounds the file descriptors ghinstall holds at once. Large
+// archives extracted by parallel installs, each downloading over its own
+// connections, could otherwise exceed the per-process limit and fail with
+// "too many open files". Output files and network connections draw from two
+// process-wide pools sized from RLIMIT_NOFILE.
+package fdlimit
+
+import (
+	"cmp"
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"weak"
+)
+
+// maxDefault caps the default size of each pool.
+const maxDefault = 1024
+
+// Defaults returns the default sizes of the pools: a quarter of the soft
+// RLIMIT_NOFILE each, leaving the rest to everything else the process opens,
+// between 8 and 1024.
+func Defaults() (files, conns int) {
+	n := min(max(nofile()/4, 8), maxDefault)
+	return n, n
+}
+
+// semaphore is a pool of slots; a smaller or larger one replaces it when the
+// limits change, while the slots taken return to the pool they came from.
+type semaphore chan struct{}
+
+func (s semaphore) release() { <-s }
+
+var (
+	files, conns atomic.Pointer[semaphore]
+	// setMu serializes SetLimits.
+	setMu sync.Mutex
+)
+
+func init() {
+	SetLimits(0, 0)
+}
+
+// SetLimits sizes the pools of output files and network connections; zero
+// selects the Defaults. Setting the current limits again changes nothing.
+func SetLimits(maxFiles, maxConns int) {
+	setMu.Lock()
+	defer setMu.Unlock()
+	defFiles, defConns := Defaults()
+	resize(&files, cmp.Or(maxFiles, defFiles))
+	resize(&conns, cmp.Or(maxConns, defConns))
+}
+
+// Limits returns the current sizes of the pools.
+func Limits() (maxFiles, maxConns int) {
+	return cap(*files.Load()), cap(*conns.Load())
+}
+
+func resize(p *atomic.Pointer[semaphore], n int) {
+	if s := p.Load(); s != nil && cap(*s) == n {
+		return
+	}
+	s := make(semaphore, n)
+	p.Store(&s)
+}
+
+// File waits for a free slot to open an output file and returns the function
+// giving it back, to be called once the file is closed.
+func File() func() {
+	s := *files.Load()
+	s <- struct{}{}
+	return s.release
+}
+
+// WriteCloser returns a WriteCloser closing w and then calling release.
+func WriteCloser(w io.WriteCloser, release func()) io.WriteCloser {
+	return &fileWriter{WriteCloser: w, release: release}
+}
+
+type fileWriter struct {
+	io.WriteCloser
+	release func()
+	closed  sync.Once
+}
+
+func (w *fileWriter) Close() error {
+	err := w.WriteCloser.Close()
+	w.closed.Do(w.release)
+	return err
+}
+
+// transports are closed idle when the connection pool runs out.
+var (
+	transportsMu sync.Mutex
+	transports   []weak.Pointer[http.Transport]
+)
+
+// Limit makes every connection t dials take a slot of the connection pool
+// until it is closed. When the pool is exhausted, the idle keep-alive
+// connections of all limited transports are closed to free slots.
+func Limit(t *http.Transport) {
+	dial := t.DialContext
+	if dial == nil {
+		dial = (&net.Dialer{}).DialContext
+	}
+	t.DialContext = func(ctx context.Context, network, addr string) (net.Conn, error) {
+		release, err := conn(ctx)
+		if err != nil {
+			return nil, err
+		}
+		c, err := dial(ctx, network, addr)
+		if err != nil {
+			release()
+			return nil, err
+		}
+		return &limitedConn{Conn: c, release: release}, nil
+	}
+
+	transportsMu.Lock()
+	defer transportsMu.Unlock()
+	transports = append(transports, weak.Make(t))
+}
+
+// conn waits for a free connection slot.
+func conn(ctx context.Context) (func(), error) {
+	s := *conns.Load()
+	select {
+	case s <- struct{}{}:
+		return s.release, nil
+	default:
+	}
+
+	closeIdle()
+	select {
+	case s <- struct{}{}:
+		return s.release, nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// closeIdle closes the idle connections of the limited transports still in
+// use and forgets the others.
+func closeIdle() {
+	transportsMu.Lock()
+	live := transports[:0]
+	var idle []*http.Transport
+	for _, p := range transports {
+		if t := p.Value(); t != nil {
+			live = append(live, p)
+			idle = append(idle, t)
+		}
+	}
+	transports = live
+	transportsMu.Unlock()
+
+	for _, t := range idle {
+		t.CloseIdleConnections()
+	}
+}
+
+type limitedConn struct {
+	net.Conn
+	release func()
+	closed  sync.Once
+}
+
+func (c *limitedConn) Close() error {
+	err := c.Conn.Close()
+	c.closed.Do(c.release)
+	return err
+}
@@ -0,0 +1,99 @@
+package fdlimit
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestSetLimits(t *testing.T) {
+	defer SetLimits(0, 0)
+
+	SetLimits(3, 5)
+	if files, conns := Limits(); files != 3 || conns != 5 {
+		t.Fatalf("Limits() = %d, %d, want 3, 5", files, conns)
+	}
+	release := File()
+	// Resizing does not lose the slot taken from the old pool.
+	SetLimits(0, 0)
+	release()
+
+	defFiles, defConns := Defaults()
+	if files, conns := Limits(); files != defFiles || conns != defConns {
+		t.Errorf("Limits() = %d, %d, want the defaults %d, %d", files, conns, defFiles, defConns)
+	}
+	if defFiles < 8 || defFiles > maxDefault {
+		t.Errorf("Defaults() = %d, want between 8 and %d", defFiles, maxDefault)
+	}
+}
+
+func TestFile(t *testing.T) {
+	defer SetLimits(0, 0)
+	SetLimits(1, 0)
+
+	release := File()
+	acquired := make(chan func())
+	go func() { acquired <- File() }()
+	select {
+	case <-acquired:
+		t.Fatal("File() returned while the only slot was taken")
+	case <-time.After(50 * time.Millisecond):
+	}
+	release()
+	(<-acquired)()
+}
+
+func TestLimit(t *testing.T) {
+	defer SetLimits(0, 0)
+	SetLimits(0, 1)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, "ok")
+	}))
+	defer server.Close()
+	other := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, "ok")
+	}))
+	defer other.Close()
+
+	tr := http.DefaultTransport.(*http.Transport).Clone()
+	Limit(tr)
+	client := &http.Client{Transport: tr}
+
+	get := func(url string) error {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		req, _ := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+		resp, err := client.Do(req)
+		if err != nil {
+			return err
+		}
+		io.Copy(io.Discard, resp.Body)
+		return resp.Body.Close()
+	}
+
+	if err := get(server.URL); err != nil {
+		t.Fatalf("first request: %v", err)
+	}
+	// The idle keep-alive connection to server holds the only slot and is
+	// closed to connect to other.
+	if err := get(other.URL); err != nil {
+		t.Fatalf("second request: %v", err)
+	}
+
+	// A request needing a new connection while one is in use waits for it.
+	release, err := conn(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if _, err := conn(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("conn() error = %v, want %v", err, context.DeadlineExceeded)
+	}
+	release()
+}
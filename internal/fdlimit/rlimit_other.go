@@ -0,0 +1,9 @@
+//go:build !unix
+
+package fdlimit
+
+// nofile returns the descriptor budget of platforms without RLIMIT_NOFILE,
+// which do not limit handles as tightly.
+func nofile() int {
+	return maxDefault * 4
+}
@@ -0,0 +1,14 @@
+//go:build unix
+
+package fdlimit
+
+import "syscall"
+
+// nofile returns the soft limit of open file descriptors.
+func nofile() int {
+	var rl syscall.Rlimit
+	if err := syscall.Getrlimit(syscall.RLIMIT_NOFILE, &rl); err != nil || rl.Cur > 1<<20 {
+		return maxDefault * 4
+	}
+	return int(rl.Cur)
+}
@@ -0,0 +1,194 @@
+// Package filelock implements advisory lock files that work across processes
+// and users sharing a directory. A lock is a file created with O_EXCL; the
+// holder refreshes its modification time periodically so that locks left
+// behind by crashed processes can be detected and broken.
+package filelock
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+const (
+	// heartbeat is how often a held lock refreshes its modification time.
+	heartbeat = 10 * time.Second
+	// staleAfter is how long a lock may go without a heartbeat before it is
+	// considered abandoned.
+	staleAfter = 6 * heartbeat
+	// pollInterval is how often a waiting process retries the lock.
+	pollInterval = 200 * time.Millisecond
+)
+
+// ErrTimeout is returned when a lock could not be acquired in time.
+var ErrTimeout = errors.New("timed out waiting for lock")
+
+// Lock is a held lock file.
+type Lock struct {
+	path string
+	done chan struct{}
+	// info and content identify the file this holder created, so that a
+	// lock broken as stale and taken by another process is left alone.
+	info    os.FileInfo
+	content []byte
+}
+
+// Acquire creates the lock file at path, waiting up to timeout for another
+// holder to release it. A zero timeout fails immediately when the lock is held.
+func Acquire(ctx context.Context, path string, timeout time.Duration) (*Lock, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, fmt.Errorf("failed to create lock directory: %w", err)
+	}
+
+	deadline := time.Now().Add(timeout)
+	for {
+		l, err := tryCreate(path)
+		if err != nil {
+			return nil, err
+		}
+		if l != nil {
+			l.done = make(chan struct{})
+			go l.refresh(l.done)
+			return l, nil
+		}
+
+		if breakStale(path) {
+			continue
+		}
+
+		if !time.Now().Before(deadline) {
+			return nil, fmt.Errorf("%w %s (held by %s)", ErrTimeout, path, Owner(path))
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(pollInterval):
+		}
+	}
+}
+
+// Release removes the lock file unless another process took it over after
+// breaking it as stale. It is safe to call more than once.
+func (l *Lock) Release() error {
+	if l == nil || l.done == nil {
+		return nil
+	}
+	close(l.done)
+	l.done = nil
+	if !l.held() {
+		return nil
+	}
+	if err := os.Remove(l.path); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to remove lock %s: %w", l.path, err)
+	}
+	return nil
+}
+
+// Owner describes the process holding the lock at path, or "unknown".
+func Owner(path string) string {
+	data, err := os.ReadFile(path)
+	if err != nil || len(data) == 0 {
+		return "unknown"
+	}
+	return string(data)
+}
+
+// held reports whether the file at the lock path is still the one this
+// holder created.
+func (l *Lock) held() bool {
+	fi, err := os.Stat(l.path)
+	if err != nil || !os.SameFile(fi, l.info) {
+		return false
+	}
+	data, err := os.ReadFile(l.path)
+	return err == nil && bytes.Equal(data, l.content)
+}
+
+// tryCreate creates the lock file at path, returning nil when it exists.
+func tryCreate(path string) (*Lock, error) {
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0644)
+	if err != nil {
+		if os.IsExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to create lock %s: %w", path, err)
+	}
+	defer f.Close()
+
+	host, _ := os.Hostname()
+	content := []byte(fmt.Sprintf("pid %d on %s (%s)", os.Getpid(), host, token()))
+	if _, err := f.Write(content); err != nil {
+		os.Remove(path)
+		return nil, fmt.Errorf("failed to write lock %s: %w", path, err)
+	}
+	info, err := f.Stat()
+	if err != nil {
+		os.Remove(path)
+		return nil, fmt.Errorf("failed to write lock %s: %w", path, err)
+	}
+	return &Lock{path: path, info: info, content: content}, nil
+}
+
+// token returns a random string telling apart locks of the same process.
+func token() string {
+	b := make([]byte, 8)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// breakStale removes the lock at path if its holder stopped refreshing it.
+// The lock is first renamed to a name of its own, so that of several
+// processes breaking it only one succeeds, and a lock created or refreshed
+// in the meantime is never removed; it is checked again under the new name.
+func breakStale(path string) bool {
+	fi, err := os.Stat(path)
+	if err != nil {
+		// Released between our create attempt and now; retry right away.
+		return os.IsNotExist(err)
+	}
+	if time.Since(fi.ModTime()) < staleAfter {
+		return false
+	}
+
+	stale := path + ".stale-" + token()
+	if err := os.Rename(path, stale); err != nil {
+		// Another process broke it first.
+		return os.IsNotExist(err)
+	}
+	if fi, err := os.Stat(stale); err == nil && time.Since(fi.ModTime()) < staleAfter {
+		// Its holder refreshed it after all: put it back unless the lock
+		// was taken meanwhile, in which case the holder finds it lost.
+		if err := os.Link(stale, path); err == nil {
+			os.Remove(stale)
+			return false
+		}
+	}
+	os.Remove(stale)
+	return true
+}
+
+// refresh keeps the lock file fresh until done is closed or the lock is
+// lost; done is passed in because Release clears l.done.
+func (l *Lock) refresh(done <-chan struct{}) {
+	ticker := time.NewTicker(heartbeat)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-done:
+			return
+		case now := <-ticker.C:
+			if !l.held() {
+				return
+			}
+			_ = os.Chtimes(l.path, now, now)
+		}
+	}
+}
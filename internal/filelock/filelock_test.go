@@ -0,0 +1,130 @@
+package filelock
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestAcquire_Release(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "test.lock")
+
+	lock, err := Acquire(context.Background(), path, 0)
+	if err != nil {
+		t.Fatalf("Acquire() error = %v", err)
+	}
+
+	if _, err := Acquire(context.Background(), path, 0); !errors.Is(err, ErrTimeout) {
+		t.Errorf("second Acquire() error = %v, want ErrTimeout", err)
+	}
+
+	if err := lock.Release(); err != nil {
+		t.Fatalf("Release() error = %v", err)
+	}
+	if err := lock.Release(); err != nil {
+		t.Errorf("second Release() error = %v", err)
+	}
+
+	lock, err = Acquire(context.Background(), path, 0)
+	if err != nil {
+		t.Fatalf("Acquire() after release error = %v", err)
+	}
+	lock.Release()
+}
+
+func TestAcquire_WaitsForRelease(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "test.lock")
+
+	lock, err := Acquire(context.Background(), path, 0)
+	if err != nil {
+		t.Fatalf("Acquire() error = %v", err)
+	}
+	time.AfterFunc(300*time.Millisecond, func() { lock.Release() })
+
+	second, err := Acquire(context.Background(), path, 5*time.Second)
+	if err != nil {
+		t.Fatalf("waiting Acquire() error = %v", err)
+	}
+	second.Release()
+}
+
+func TestAcquire_BreaksStaleLock(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "test.lock")
+	if err := os.WriteFile(path, []byte("pid 1 on crashed"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	old := time.Now().Add(-2 * staleAfter)
+	if err := os.Chtimes(path, old, old); err != nil {
+		t.Fatal(err)
+	}
+
+	lock, err := Acquire(context.Background(), path, 0)
+	if err != nil {
+		t.Fatalf("Acquire() over stale lock error = %v", err)
+	}
+	lock.Release()
+
+	if entries, _ := os.ReadDir(filepath.Dir(path)); len(entries) != 0 {
+		t.Errorf("files left behind: %v", entries)
+	}
+}
+
+func TestBreakStale_Refreshed(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "test.lock")
+	lock, err := Acquire(context.Background(), path, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer lock.Release()
+
+	if breakStale(path) {
+		t.Error("breakStale() broke a fresh lock")
+	}
+	if !lock.held() {
+		t.Error("lock lost after breakStale()")
+	}
+}
+
+func TestRelease_TakenOver(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "test.lock")
+	lock, err := Acquire(context.Background(), path, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Another process broke the lock as stale and holds it now.
+	if err := os.Remove(path); err != nil {
+		t.Fatal(err)
+	}
+	other, err := Acquire(context.Background(), path, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer other.Release()
+
+	if err := lock.Release(); err != nil {
+		t.Fatalf("Release() error = %v", err)
+	}
+	if !other.held() {
+		t.Error("Release() removed the lock of another holder")
+	}
+}
+
+func TestAcquire_ContextCancel(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "test.lock")
+	lock, err := Acquire(context.Background(), path, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer lock.Release()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+
+	if _, err := Acquire(ctx, path, time.Minute); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Acquire() error = %v, want context deadline", err)
+	}
+}
@@ -0,0 +1,228 @@
+package fleet
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path"
+	"strings"
+	"sync"
+
+	"github.com/sixban6/ghinstall/internal/config"
+	"github.com/sixban6/ghinstall/internal/installer"
+	log "github.com/sixban6/ghinstall/internal/logger"
+)
+
+// Transport runs commands on hosts and copies files to them.
+type Transport interface {
+	// Run runs the shell command on h and returns its output.
+	Run(ctx context.Context, h Host, command string) ([]byte, error)
+	// Copy copies the local file to the path remote on h.
+	Copy(ctx context.Context, h Host, local, remote string) error
+}
+
+// Fetcher resolves the repositories of the config for platform and caches
+// their assets on this machine, like ghinstall.PrefetchPlatform.
+type Fetcher func(ctx context.Context, platform string) ([]installer.PrefetchResult, error)
+
+// HostResult is the outcome of the installs on one host.
+type HostResult struct {
+	Host     Host
+	Platform string
+	// Repos holds a result per repository installed on Host, in config order.
+	Repos []RepoResult
+	// Err is set when nothing could be installed, e.g. the host was
+	// unreachable or its releases could not be resolved.
+	Err error
+}
+
+// Failed reports whether anything failed on the host.
+func (r HostResult) Failed() bool {
+	if r.Err != nil {
+		return true
+	}
+	for _, repo := range r.Repos {
+		if repo.Err != nil {
+			return true
+		}
+	}
+	return false
+}
+
+// RepoResult is the outcome of installing one repository on a host.
+type RepoResult struct {
+	Repo  config.Repo
+	Tag   string
+	Asset string
+	Err   error
+}
+
+// Install installs on every host the assets fetch caches for its platform, on
+// up to parallel hosts at once, and returns the results in the order of hosts.
+// Each platform is fetched once. On a host, every asset is copied to /tmp and
+// extracted into the output_dir of its repository, which is a path on the
+// host; a repository failing does not stop the others.
+func Install(ctx context.Context, hosts []Host, t Transport, fetch Fetcher, parallel int) []HostResult {
+	if parallel < 1 {
+		parallel = 1
+	}
+	f := &fetcher{fetch: fetch, results: make(map[string]*fetched)}
+	results := make([]HostResult, len(hosts))
+	sem := make(chan struct{}, parallel)
+	var wg sync.WaitGroup
+	for n, h := range hosts {
+		wg.Go(func() {
+			sem <- struct{}{}
+			defer func() { <-sem }()
+			results[n] = installHost(ctx, h, t, f)
+		})
+	}
+	wg.Wait()
+	return results
+}
+
+// fetcher fetches each platform once for all hosts.
+type fetcher struct {
+	mu      sync.Mutex
+	fetch   Fetcher
+	results map[string]*fetched
+}
+
+type fetched struct {
+	results []installer.PrefetchResult
+	err     error
+}
+
+func (f *fetcher) get(ctx context.Context, platform string) ([]installer.PrefetchResult, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	r, ok := f.results[platform]
+	if !ok {
+		log.Info("Resolving releases for %s", platform)
+		r = &fetched{}
+		r.results, r.err = f.fetch(ctx, platform)
+		f.results[platform] = r
+	}
+	return r.results, r.err
+}
+
+func installHost(ctx context.Context, h Host, t Transport, f *fetcher) HostResult {
+	res := HostResult{Host: h, Platform: h.Platform}
+	// Asking for the platform checks that the host is reachable, before
+	// anything is fetched for it.
+	out, err := t.Run(ctx, h, "uname -sm")
+	if err != nil {
+		res.Err = fmt.Errorf("failed to connect: %w", err)
+		return res
+	}
+	if res.Platform == "" {
+		if res.Platform, err = parseUname(string(out)); err != nil {
+			res.Err = err
+			return res
+		}
+	}
+
+	fetched, err := f.get(ctx, res.Platform)
+	if err != nil {
+		res.Err = fmt.Errorf("failed to resolve releases for %s: %w", res.Platform, err)
+		return res
+	}
+	for _, pf := range fetched {
+		if !selected(pf.Repo, h.Only) {
+			continue
+		}
+		r := RepoResult{Repo: pf.Repo, Tag: pf.Tag, Asset: pf.Asset, Err: pf.Err}
+		if r.Err == nil {
+			r.Err = installAsset(ctx, h, t, pf)
+		}
+		if r.Err != nil {
+			log.Error("%s: failed to install %s: %v", h.Name, pf.Repo.DisplayName(), r.Err)
+		} else {
+			log.Info("%s: installed %s %s", h.Name, pf.Repo.DisplayName(), pf.Tag)
+		}
+		res.Repos = append(res.Repos, r)
+	}
+	return res
+}
+
+// selected reports whether repo is one of only, or only is empty.
+func selected(repo config.Repo, only []string) bool {
+	if len(only) == 0 {
+		return true
+	}
+	for _, selector := range only {
+		if repo.Matches(selector) {
+			return true
+		}
+	}
+	return false
+}
+
+// installAsset copies the cached asset of pf to h and extracts it there. The
+// copy goes to a directory only the SSH user can write to, so other users of
+// the host cannot plant a symlink or swap the asset before it is extracted.
+func installAsset(ctx context.Context, h Host, t Transport, pf installer.PrefetchResult) error {
+	if pf.Path == "" {
+		return errors.New("the asset was not cached")
+	}
+	tmpDir, err := remoteTempDir(ctx, h, t)
+	if err != nil {
+		return err
+	}
+	if err := t.Copy(ctx, h, pf.Path, path.Join(tmpDir, path.Base(pf.Asset))); err != nil {
+		t.Run(context.WithoutCancel(ctx), h, "rm -rf "+quote(tmpDir))
+		return fmt.Errorf("failed to copy %s: %w", pf.Asset, err)
+	}
+	if out, err := t.Run(ctx, h, installCommand(pf.Asset, tmpDir, pf.Repo.OutputDir)); err != nil {
+		if msg := strings.TrimSpace(string(out)); msg != "" {
+			err = fmt.Errorf("%w: %s", err, msg)
+		}
+		return fmt.Errorf("failed to extract %s: %w", pf.Asset, err)
+	}
+	return nil
+}
+
+// remoteTempDir creates a private temporary directory on h and returns its
+// path.
+func remoteTempDir(ctx context.Context, h Host, t Transport) (string, error) {
+	out, err := t.Run(ctx, h, "mktemp -d /tmp/ghinstall.XXXXXXXX")
+	if err != nil {
+		return "", fmt.Errorf("failed to create a temporary directory: %w", err)
+	}
+	dir := strings.TrimSpace(string(out))
+	if !path.IsAbs(dir) || strings.ContainsAny(dir, "\n\r") {
+		return "", fmt.Errorf("unexpected temporary directory %q", dir)
+	}
+	return dir, nil
+}
+
+// installCommand returns the shell command extracting the asset named asset,
+// copied into the temporary directory tmpDir, into dir and removing tmpDir.
+// Assets that are not archives are copied into dir as executables.
+func installCommand(asset, tmpDir, dir string) string {
+	src, dst := quote(path.Join(tmpDir, path.Base(asset))), quote(dir)
+	var extract string
+	switch name := strings.ToLower(asset); {
+	case strings.HasSuffix(name, ".tar.gz"), strings.HasSuffix(name, ".tgz"):
+		extract = "tar -xzf " + src + " -C " + dst
+	case strings.HasSuffix(name, ".tar.xz"), strings.HasSuffix(name, ".txz"):
+		extract = "tar -xJf " + src + " -C " + dst
+	case strings.HasSuffix(name, ".tar.bz2"), strings.HasSuffix(name, ".tbz2"):
+		extract = "tar -xjf " + src + " -C " + dst
+	case strings.HasSuffix(name, ".tar.zst"):
+		extract = "tar --zstd -xf " + src + " -C " + dst
+	case strings.HasSuffix(name, ".tar"):
+		extract = "tar -xf " + src + " -C " + dst
+	case strings.HasSuffix(name, ".zip"):
+		extract = "unzip -q -o " + src + " -d " + dst
+	default:
+		bin := quote(path.Join(dir, path.Base(asset)))
+		extract = "cp " + src + " " + bin + " && chmod 755 " + bin
+	}
+	return fmt.Sprintf("mkdir -p %s && %s; status=$?; rm -rf %s; exit $status", dst, extract, quote(tmpDir))
+}
+
+// quote quotes s for a POSIX shell.
+func quote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
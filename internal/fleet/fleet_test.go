@@ -0,0 +1,176 @@
+package fleet
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/sixban6/ghinstall/internal/config"
+	"github.com/sixban6/ghinstall/internal/installer"
+)
+
+// fakeTransport records the commands and copies of every host.
+type fakeTransport struct {
+	mu       sync.Mutex
+	uname    map[string]string
+	fail     map[string]error
+	commands map[string][]string
+}
+
+func (f *fakeTransport) Run(ctx context.Context, h Host, command string) ([]byte, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err := f.fail[h.Name]; err != nil {
+		return nil, err
+	}
+	if command == "uname -sm" {
+		return []byte(f.uname[h.Name] + "\n"), nil
+	}
+	f.commands[h.Name] = append(f.commands[h.Name], command)
+	if strings.HasPrefix(command, "mktemp ") {
+		return []byte("/tmp/ghinstall.Ab3dE5gH\n"), nil
+	}
+	return nil, nil
+}
+
+func (f *fakeTransport) Copy(ctx context.Context, h Host, local, remote string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.commands[h.Name] = append(f.commands[h.Name], "copy "+filepath.Base(local)+" "+remote)
+	return nil
+}
+
+func TestInstall(t *testing.T) {
+	gh := config.Repo{URL: "https://github.com/cli/cli", Name: "gh", OutputDir: "/opt/gh"}
+	jq := config.Repo{URL: "https://github.com/jqlang/jq", OutputDir: "/opt/jq"}
+	var fetched []string
+	fetch := func(ctx context.Context, platform string) ([]installer.PrefetchResult, error) {
+		fetched = append(fetched, platform)
+		arch := strings.Split(platform, "/")[1]
+		return []installer.PrefetchResult{
+			{Repo: gh, Tag: "v2.41.0", Asset: "gh_linux_" + arch + ".tar.gz", SHA256: "0123456789abcdef0123", Path: "/cache/gh-" + arch},
+			{Repo: jq, Tag: "jq-1.7.1", Asset: "jq-linux-" + arch, SHA256: "fedcba9876543210fedc", Path: "/cache/jq-" + arch},
+		}, nil
+	}
+	hosts := []Host{
+		{Name: "nas", Address: "admin@nas", Platform: "linux/arm64"},
+		{Name: "pi", Address: "pi@raspberrypi", Only: []string{"jqlang/jq"}},
+		{Name: "down", Address: "down", Platform: "linux/arm64"},
+	}
+	tr := &fakeTransport{
+		uname:    map[string]string{"nas": "Linux aarch64", "pi": "Linux aarch64"},
+		fail:     map[string]error{"down": errors.New("connection refused")},
+		commands: map[string][]string{},
+	}
+
+	results := Install(context.Background(), hosts, tr, fetch, 1)
+
+	if !reflect.DeepEqual(fetched, []string{"linux/arm64"}) {
+		t.Errorf("fetched %v, want linux/arm64 once", fetched)
+	}
+	if len(results) != 3 {
+		t.Fatalf("%d results, want 3", len(results))
+	}
+	if r := results[0]; r.Failed() || len(r.Repos) != 2 || r.Repos[0].Tag != "v2.41.0" {
+		t.Errorf("nas: %+v", r)
+	}
+	if r := results[1]; r.Failed() || r.Platform != "linux/arm64" || len(r.Repos) != 1 || r.Repos[0].Repo.URL != jq.URL {
+		t.Errorf("pi: %+v", r)
+	}
+	if r := results[2]; !r.Failed() || len(r.Repos) != 0 {
+		t.Errorf("down: %+v, want a failure", r)
+	}
+
+	want := []string{
+		"mktemp -d /tmp/ghinstall.XXXXXXXX",
+		"copy jq-arm64 /tmp/ghinstall.Ab3dE5gH/jq-linux-arm64",
+		"mkdir -p '/opt/jq' && cp '/tmp/ghinstall.Ab3dE5gH/jq-linux-arm64' '/opt/jq/jq-linux-arm64' && chmod 755 '/opt/jq/jq-linux-arm64'; status=$?; rm -rf '/tmp/ghinstall.Ab3dE5gH'; exit $status",
+	}
+	if got := tr.commands["pi"]; !reflect.DeepEqual(got, want) {
+		t.Errorf("pi commands =\n%s\nwant\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+}
+
+func TestInstallCommand(t *testing.T) {
+	tests := []struct {
+		asset, want string
+	}{
+		{"app.tar.gz", "tar -xzf '/tmp/a/app.tar.gz' -C '/opt/it'\\''s'"},
+		{"app.TGZ", "tar -xzf '/tmp/a/app.TGZ' -C '/opt/it'\\''s'"},
+		{"app.tar.xz", "tar -xJf '/tmp/a/app.tar.xz' -C '/opt/it'\\''s'"},
+		{"app.zip", "unzip -q -o '/tmp/a/app.zip' -d '/opt/it'\\''s'"},
+	}
+	for _, tt := range tests {
+		if got := installCommand(tt.asset, "/tmp/a", "/opt/it's"); !strings.Contains(got, tt.want) {
+			t.Errorf("installCommand(%q) = %s, want it to contain %s", tt.asset, got, tt.want)
+		}
+	}
+}
+
+func TestLoadHosts(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		want    []Host
+		wantErr bool
+	}{
+		{
+			name: "valid",
+			content: `hosts:
+  - name: nas
+    address: admin@nas.local
+    port: 2222
+    platform: linux/arm64
+  - address: pi@raspberrypi
+    only: [jq]`,
+			want: []Host{
+				{Name: "nas", Address: "admin@nas.local", Port: 2222, Platform: "linux/arm64"},
+				{Name: "pi@raspberrypi", Address: "pi@raspberrypi", Only: []string{"jq"}},
+			},
+		},
+		{name: "no hosts", content: `hosts: []`, wantErr: true},
+		{name: "missing address", content: "hosts:\n  - name: nas", wantErr: true},
+		{name: "duplicate name", content: "hosts:\n  - address: a\n    name: x\n  - address: b\n    name: x", wantErr: true},
+		{name: "windows", content: "hosts:\n  - address: a\n    platform: windows/amd64", wantErr: true},
+		{name: "invalid platform", content: "hosts:\n  - address: a\n    platform: linux", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "hosts.yaml")
+			if err := os.WriteFile(path, []byte(tt.content), 0o644); err != nil {
+				t.Fatal(err)
+			}
+			got, err := LoadHosts(path)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("LoadHosts() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("LoadHosts() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseUname(t *testing.T) {
+	tests := map[string]string{
+		"Linux x86_64":   "linux/amd64",
+		"Linux aarch64":  "linux/arm64",
+		"Linux armv7l":   "linux/arm",
+		"Darwin arm64":   "darwin/arm64",
+		"FreeBSD amd64":  "freebsd/amd64",
+		"Linux i686\r\n": "linux/386",
+	}
+	for out, want := range tests {
+		if got, err := parseUname(out); err != nil || got != want {
+			t.Errorf("parseUname(%q) = %q, %v, want %q", out, got, err, want)
+		}
+	}
+	if _, err := parseUname("MINGW64_NT-10.0"); err == nil {
+		t.Error("parseUname() accepted unexpected output")
+	}
+}
@@ -0,0 +1,117 @@
+// Package fleet installs the releases of a config on other machines over SSH:
+// releases are resolved and their assets downloaded on this machine, once
+// per platform, then copied to every host and extracted there.
+package fleet
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Host is a machine listed in a hosts file.
+type Host struct {
+	// Name labels the host in results; Address by default.
+	Name string `yaml:"name"`
+	// Address is the SSH destination, such as "admin@nas.local" or a Host of
+	// ~/.ssh/config.
+	Address string `yaml:"address"`
+	// Port is the SSH port; the one of the SSH configuration when 0.
+	Port int `yaml:"port"`
+	// Platform is the GOOS/GOARCH of the host, such as "linux/arm64"; it is
+	// asked with uname when unset.
+	Platform string `yaml:"platform"`
+	// Only lists the repositories to install on the host (name, owner/repo or
+	// URL); all of them when empty.
+	Only []string `yaml:"only"`
+}
+
+type hostsFile struct {
+	Hosts []Host `yaml:"hosts"`
+}
+
+// LoadHosts reads the hosts file at path:
+//
+//	hosts:
+//	  - name: nas
+//	    address: admin@nas.local
+//	    port: 2222
+//	    platform: linux/arm64
+//	  - address: pi@raspberrypi
+//	    only: [jq]
+func LoadHosts(path string) ([]Host, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read hosts file %q: %w", path, err)
+	}
+	var f hostsFile
+	if err := yaml.Unmarshal(data, &f); err != nil {
+		return nil, fmt.Errorf("failed to parse hosts file %q: %w", path, err)
+	}
+	if len(f.Hosts) == 0 {
+		return nil, fmt.Errorf("hosts file %q lists no hosts", path)
+	}
+	names := make(map[string]int)
+	for i := range f.Hosts {
+		h := &f.Hosts[i]
+		if h.Address == "" {
+			return nil, fmt.Errorf("hosts file %q: host at index %d: address is required", path, i)
+		}
+		if h.Name == "" {
+			h.Name = h.Address
+		}
+		if j, ok := names[h.Name]; ok {
+			return nil, fmt.Errorf("hosts file %q: host at index %d: name %q is already used by the host at index %d", path, i, h.Name, j)
+		}
+		names[h.Name] = i
+		if h.Port < 0 || h.Port > 65535 {
+			return nil, fmt.Errorf("hosts file %q: host %s: port must be between 1 and 65535", path, h.Name)
+		}
+		if h.Platform != "" {
+			if err := checkPlatform(h.Platform); err != nil {
+				return nil, fmt.Errorf("hosts file %q: host %s: %w", path, h.Name, err)
+			}
+		}
+	}
+	return f.Hosts, nil
+}
+
+// checkPlatform reports whether platform is a GOOS/GOARCH fleet installs
+// support: hosts need a POSIX shell, tar and unzip.
+func checkPlatform(platform string) error {
+	goos, goarch, ok := strings.Cut(platform, "/")
+	if !ok || goos == "" || goarch == "" {
+		return fmt.Errorf("platform %q must be GOOS/GOARCH, such as linux/amd64", platform)
+	}
+	if goos == "windows" {
+		return fmt.Errorf("platform %q is not supported: hosts need a POSIX shell", platform)
+	}
+	return nil
+}
+
+// parseUname returns the GOOS/GOARCH of the output of "uname -sm", such as
+// "Linux aarch64".
+func parseUname(out string) (string, error) {
+	fields := strings.Fields(out)
+	if len(fields) != 2 {
+		return "", fmt.Errorf("unexpected uname output %q", strings.TrimSpace(out))
+	}
+	goos := strings.ToLower(fields[0])
+	goarch := strings.ToLower(fields[1])
+	switch goarch {
+	case "x86_64", "amd64":
+		goarch = "amd64"
+	case "aarch64", "arm64":
+		goarch = "arm64"
+	case "i386", "i486", "i586", "i686":
+		goarch = "386"
+	default:
+		if strings.HasPrefix(goarch, "armv") {
+			goarch = "arm"
+		}
+	}
+	platform := goos + "/" + goarch
+	return platform, checkPlatform(platform)
+}
@@ -0,0 +1,59 @@
+//go:build !purego && !wasip1
+
+package fleet
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os/exec"
+	"strconv"
+	"strings"
+)
+
+// SSH runs commands and copies files with the ssh and scp commands, so the
+// keys, agent and host settings of the OpenSSH configuration apply. Commands
+// never prompt: hosts must accept a key or an agent.
+type SSH struct {
+	// Options are extra -o options, such as "StrictHostKeyChecking=accept-new".
+	Options []string
+}
+
+func (s SSH) Run(ctx context.Context, h Host, command string) ([]byte, error) {
+	args := append(s.args(h, "-p"), h.Address, command)
+	var stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, "ssh", args...)
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			err = fmt.Errorf("%w: %s", err, msg)
+		}
+		return out, fmt.Errorf("ssh %s: %w", h.Address, err)
+	}
+	return out, nil
+}
+
+func (s SSH) Copy(ctx context.Context, h Host, local, remote string) error {
+	args := append(s.args(h, "-P"), "-q", local, h.Address+":"+remote)
+	if out, err := exec.CommandContext(ctx, "scp", args...).CombinedOutput(); err != nil {
+		if msg := strings.TrimSpace(string(out)); msg != "" {
+			err = fmt.Errorf("%w: %s", err, msg)
+		}
+		return fmt.Errorf("scp to %s: %w", h.Address, err)
+	}
+	return nil
+}
+
+// args returns the options of ssh and scp for h, which name the port option
+// portFlag.
+func (s SSH) args(h Host, portFlag string) []string {
+	args := []string{"-o", "BatchMode=yes"}
+	for _, opt := range s.Options {
+		args = append(args, "-o", opt)
+	}
+	if h.Port != 0 {
+		args = append(args, portFlag, strconv.Itoa(h.Port))
+	}
+	return args
+}
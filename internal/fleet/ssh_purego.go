@@ -0,0 +1,24 @@
+//go:build purego || wasip1
+
+package fleet
+
+import (
+	"context"
+	"errors"
+)
+
+var errDisabled = errors.New("fleet installs are disabled: this build of ghinstall (purego or wasip1) does not start processes")
+
+// SSH would run commands and copy files with the ssh and scp commands, which
+// this build cannot start.
+type SSH struct {
+	Options []string
+}
+
+func (s SSH) Run(ctx context.Context, h Host, command string) ([]byte, error) {
+	return nil, errDisabled
+}
+
+func (s SSH) Copy(ctx context.Context, h Host, local, remote string) error {
+	return errDisabled
+}
@@ -0,0 +1,495 @@
+// Package httpcache implements a private HTTP cache following RFC 9111, so
+// repeated requests are answered from earlier responses while they are fresh
+// and revalidated with conditional requests once they are stale. Behind a
+// caching proxy the revalidations are usually answered by the proxy, so a
+// fleet of hosts installing the same releases rarely reaches GitHub.
+//
+// Only GET responses with status 200 and a known, small size are stored;
+// large downloads pass through untouched.
+package httpcache
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultMaxBodySize is the largest response body stored by default.
+const DefaultMaxBodySize = 1 << 20
+
+// Configurer is implemented by clients that keep their HTTP responses in a
+// Transport and can persist them in a directory.
+type Configurer interface {
+	// SetHTTPCache stores responses in dir, or only in memory when dir is "".
+	// A shared directory is readable by other users and only receives
+	// responses a shared cache may store.
+	SetHTTPCache(dir string, shared bool)
+}
+
+// Transport is an http.RoundTripper answering requests from stored responses.
+type Transport struct {
+	// Base sends the requests that cannot be answered from the cache;
+	// http.DefaultTransport when nil.
+	Base http.RoundTripper
+	// MaxBodySize limits the stored bodies; DefaultMaxBodySize when zero.
+	MaxBodySize int64
+
+	mu      sync.Mutex
+	dir     string
+	shared  bool
+	entries map[string]*entry
+	// now is replaced by tests.
+	now func() time.Time
+}
+
+// New returns a Transport sending requests with base.
+func New(base http.RoundTripper) *Transport {
+	return &Transport{Base: base}
+}
+
+// SetDir makes t persist responses in dir, shared with other users of the
+// directory when shared is set. Responses already stored in memory are kept.
+func (t *Transport) SetDir(dir string, shared bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.dir == dir && t.shared == shared {
+		return
+	}
+	t.dir, t.shared = dir, shared
+}
+
+type revalidateKey struct{}
+
+// Revalidate returns a context whose requests are revalidated with the
+// server even when a fresh response is stored, for callers that learnt the
+// stored response is outdated.
+func Revalidate(ctx context.Context) context.Context {
+	return context.WithValue(ctx, revalidateKey{}, true)
+}
+
+// entry is a stored response.
+type entry struct {
+	URL        string      `json:"url"`
+	StatusCode int         `json:"status"`
+	Header     http.Header `json:"header"`
+	Body       []byte      `json:"body"`
+	// Vary holds a digest of every request header the response varies on,
+	// so credentials never end up in the cache.
+	Vary     map[string]string `json:"vary,omitempty"`
+	StoredAt time.Time         `json:"stored_at"`
+}
+
+func (t *Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.Method != http.MethodGet && req.Method != http.MethodHead {
+		resp, err := t.base().RoundTrip(req)
+		if err == nil && resp.StatusCode < 400 {
+			// Unsafe methods invalidate what is stored for their target.
+			t.remove(req.URL.String())
+		}
+		return resp, err
+	}
+	if req.Method != http.MethodGet || req.Header.Get("Range") != "" || isConditional(req) {
+		return t.base().RoundTrip(req)
+	}
+
+	key := req.URL.String()
+	reqCC := parseCacheControl(req.Header)
+	e := t.lookup(key)
+	if e != nil && !e.matches(req) {
+		e = nil
+	}
+	revalidate, _ := req.Context().Value(revalidateKey{}).(bool)
+	if e != nil && !reqCC.has("no-cache") && !revalidate && t.fresh(e, reqCC) {
+		return e.response(req, t.age(e)), nil
+	}
+
+	out := req
+	if e != nil && e.hasValidator() {
+		out = req.Clone(req.Context())
+		if etag := e.Header.Get("ETag"); etag != "" {
+			out.Header.Set("If-None-Match", etag)
+		}
+		if lm := e.Header.Get("Last-Modified"); lm != "" {
+			out.Header.Set("If-Modified-Since", lm)
+		}
+	}
+
+	resp, err := t.base().RoundTrip(out)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode == http.StatusNotModified && out != req {
+		resp.Body.Close()
+		updated := e.revalidated(resp.Header, t.clock())
+		t.store(updated)
+		return updated.response(req, 0), nil
+	}
+	return t.maybeStore(req, reqCC, resp)
+}
+
+// maybeStore stores resp when it may be reused and returns it to the caller.
+func (t *Transport) maybeStore(req *http.Request, reqCC cacheControl, resp *http.Response) (*http.Response, error) {
+	key := req.URL.String()
+	if !t.storable(req, reqCC, resp) {
+		if resp.StatusCode != http.StatusNotModified {
+			t.remove(key)
+		}
+		return resp, nil
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response from %s: %w", req.URL.Redacted(), err)
+	}
+	resp.Body = io.NopCloser(bytes.NewReader(body))
+
+	t.store(&entry{
+		URL:        key,
+		StatusCode: resp.StatusCode,
+		Header:     resp.Header.Clone(),
+		Body:       body,
+		Vary:       varyDigests(req, resp.Header),
+		StoredAt:   t.clock(),
+	})
+	return resp, nil
+}
+
+// storable reports whether resp may be stored, following the rules for a
+// private cache or, when the directory is shared, a shared one.
+func (t *Transport) storable(req *http.Request, reqCC cacheControl, resp *http.Response) bool {
+	if resp.StatusCode != http.StatusOK || reqCC.has("no-store") {
+		return false
+	}
+	if resp.ContentLength < 0 || resp.ContentLength > t.maxBodySize() {
+		return false
+	}
+	cc := parseCacheControl(resp.Header)
+	if cc.has("no-store") || strings.TrimSpace(resp.Header.Get("Vary")) == "*" {
+		return false
+	}
+
+	t.mu.Lock()
+	shared := t.shared && t.dir != ""
+	t.mu.Unlock()
+	if shared {
+		if cc.has("private") {
+			return false
+		}
+		if req.Header.Get("Authorization") != "" && !cc.has("public") && !cc.has("s-maxage") && !cc.has("must-revalidate") {
+			return false
+		}
+	}
+
+	// A response that is never fresh and cannot be revalidated is of no use.
+	e := &entry{Header: resp.Header}
+	return e.hasValidator() || e.lifetime(shared) > 0
+}
+
+// fresh reports whether e may be used without asking the origin.
+func (t *Transport) fresh(e *entry, reqCC cacheControl) bool {
+	if parseCacheControl(e.Header).has("no-cache") {
+		return false
+	}
+	t.mu.Lock()
+	shared := t.shared && t.dir != ""
+	t.mu.Unlock()
+
+	lifetime := e.lifetime(shared)
+	if maxAge, ok := reqCC.seconds("max-age"); ok && maxAge < lifetime {
+		lifetime = maxAge
+	}
+	age := t.age(e)
+	if age < lifetime {
+		return true
+	}
+	// max-stale accepts stale responses, however stale without a value.
+	if v, ok := reqCC["max-stale"]; ok && !parseCacheControl(e.Header).has("must-revalidate") {
+		if v == "" {
+			return true
+		}
+		maxStale, _ := reqCC.seconds("max-stale")
+		return age-lifetime <= maxStale
+	}
+	return false
+}
+
+// age returns how old e is, including the time it spent in other caches.
+func (t *Transport) age(e *entry) time.Duration {
+	age := t.clock().Sub(e.StoredAt)
+	if n, err := strconv.Atoi(e.Header.Get("Age")); err == nil && n > 0 {
+		age += time.Duration(n) * time.Second
+	}
+	if age < 0 {
+		return 0
+	}
+	return age
+}
+
+func (t *Transport) lookup(key string) *entry {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if e, ok := t.entries[key]; ok {
+		return e
+	}
+	if t.dir == "" {
+		return nil
+	}
+	data, err := os.ReadFile(t.path(key))
+	if err != nil {
+		return nil
+	}
+	var e entry
+	if err := json.Unmarshal(data, &e); err != nil || e.URL != key {
+		return nil
+	}
+	t.remember(&e)
+	return &e
+}
+
+// store keeps e in memory and, best effort, in the directory.
+func (t *Transport) store(e *entry) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.remember(e)
+	if t.dir == "" {
+		return
+	}
+	data, err := json.Marshal(e)
+	if err != nil {
+		return
+	}
+	// The cache only saves requests; failing to write it is not an error.
+	_ = writeFile(t.path(e.URL), data, t.shared)
+}
+
+func (t *Transport) remove(key string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	delete(t.entries, key)
+	if t.dir != "" {
+		os.Remove(t.path(key))
+	}
+}
+
+func (t *Transport) remember(e *entry) {
+	if t.entries == nil {
+		t.entries = make(map[string]*entry)
+	}
+	t.entries[e.URL] = e
+}
+
+func (t *Transport) path(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return filepath.Join(t.dir, hex.EncodeToString(sum[:])+".json")
+}
+
+func (t *Transport) base() http.RoundTripper {
+	if t.Base == nil {
+		return http.DefaultTransport
+	}
+	return t.Base
+}
+
+func (t *Transport) maxBodySize() int64 {
+	if t.MaxBodySize > 0 {
+		return t.MaxBodySize
+	}
+	return DefaultMaxBodySize
+}
+
+func (t *Transport) clock() time.Time {
+	if t.now != nil {
+		return t.now()
+	}
+	return time.Now()
+}
+
+// lifetime returns how long after it was generated e stays fresh.
+func (e *entry) lifetime(shared bool) time.Duration {
+	cc := parseCacheControl(e.Header)
+	if shared {
+		if s, ok := cc.seconds("s-maxage"); ok {
+			return s
+		}
+	}
+	if s, ok := cc.seconds("max-age"); ok {
+		return s
+	}
+	if expires := e.Header.Get("Expires"); expires != "" {
+		exp, err := http.ParseTime(expires)
+		if err != nil {
+			return 0
+		}
+		date, err := http.ParseTime(e.Header.Get("Date"))
+		if err != nil {
+			date = e.StoredAt
+		}
+		return exp.Sub(date)
+	}
+	return 0
+}
+
+func (e *entry) hasValidator() bool {
+	return e.Header.Get("ETag") != "" || e.Header.Get("Last-Modified") != ""
+}
+
+// matches reports whether req selects the same representation as the request
+// e was stored for.
+func (e *entry) matches(req *http.Request) bool {
+	for name, digest := range e.Vary {
+		if headerDigest(req.Header.Values(name)) != digest {
+			return false
+		}
+	}
+	return true
+}
+
+// revalidated returns e updated with the header of a 304 response.
+func (e *entry) revalidated(h http.Header, now time.Time) *entry {
+	updated := *e
+	updated.Header = e.Header.Clone()
+	updated.Header.Del("Age")
+	for name, values := range h {
+		switch name {
+		case "Content-Length", "Content-Encoding", "Transfer-Encoding":
+			continue
+		}
+		updated.Header[name] = values
+	}
+	updated.StoredAt = now
+	return &updated
+}
+
+// response returns a response to req served from e.
+func (e *entry) response(req *http.Request, age time.Duration) *http.Response {
+	h := e.Header.Clone()
+	h.Set("Age", strconv.Itoa(int(age/time.Second)))
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", e.StatusCode, http.StatusText(e.StatusCode)),
+		StatusCode:    e.StatusCode,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        h,
+		Body:          io.NopCloser(bytes.NewReader(e.Body)),
+		ContentLength: int64(len(e.Body)),
+		Request:       req,
+	}
+}
+
+// varyDigests returns the digests of the request headers named by the Vary
+// header of a response.
+func varyDigests(req *http.Request, h http.Header) map[string]string {
+	var vary map[string]string
+	for _, v := range h.Values("Vary") {
+		for _, name := range strings.Split(v, ",") {
+			name = http.CanonicalHeaderKey(strings.TrimSpace(name))
+			if name == "" {
+				continue
+			}
+			if vary == nil {
+				vary = make(map[string]string)
+			}
+			vary[name] = headerDigest(req.Header.Values(name))
+		}
+	}
+	return vary
+}
+
+func headerDigest(values []string) string {
+	sum := sha256.Sum256([]byte(strings.Join(values, "\n")))
+	return hex.EncodeToString(sum[:])
+}
+
+// isConditional reports whether the caller made req conditional itself, in
+// which case it handles the answer.
+func isConditional(req *http.Request) bool {
+	for _, name := range []string{"If-None-Match", "If-Modified-Since", "If-Match", "If-Unmodified-Since", "If-Range"} {
+		if req.Header.Get(name) != "" {
+			return true
+		}
+	}
+	return false
+}
+
+// cacheControl holds the directives of Cache-Control headers.
+type cacheControl map[string]string
+
+func parseCacheControl(h http.Header) cacheControl {
+	cc := cacheControl{}
+	for _, v := range h.Values("Cache-Control") {
+		for _, directive := range strings.Split(v, ",") {
+			name, value, _ := strings.Cut(strings.TrimSpace(directive), "=")
+			if name == "" {
+				continue
+			}
+			cc[strings.ToLower(name)] = strings.Trim(value, `"`)
+		}
+	}
+	return cc
+}
+
+func (cc cacheControl) has(name string) bool {
+	_, ok := cc[name]
+	return ok
+}
+
+func (cc cacheControl) seconds(name string) (time.Duration, bool) {
+	v, ok := cc[name]
+	if !ok {
+		return 0, false
+	}
+	n, err := strconv.Atoi(v)
+	if err != nil || n < 0 {
+		return 0, true
+	}
+	return time.Duration(n) * time.Second, true
+}
+
+// writeFile writes data to path atomically. Files of a shared directory are
+// group-readable, like the download cache.
+func writeFile(path string, data []byte, shared bool) error {
+	dir := filepath.Dir(path)
+	if shared {
+		if err := os.MkdirAll(dir, 0775|os.ModeSetgid); err != nil {
+			return err
+		}
+		if err := os.Chmod(dir, 0775|os.ModeSetgid); err != nil && !os.IsPermission(err) {
+			return err
+		}
+	} else if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(dir, ".entry-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if shared {
+		if err := tmp.Chmod(0664); err != nil {
+			tmp.Close()
+			return err
+		}
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
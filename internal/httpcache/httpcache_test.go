@@ -0,0 +1,244 @@
+package httpcache
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// origin serves body with header and answers If-None-Match with 304.
+func origin(t *testing.T, header http.Header) (*httptest.Server, *atomic.Int32, *atomic.Int32) {
+	t.Helper()
+	var hits, notModified atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits.Add(1)
+		for name, values := range header {
+			w.Header()[name] = values
+		}
+		if etag := header.Get("ETag"); etag != "" && r.Header.Get("If-None-Match") == etag {
+			notModified.Add(1)
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		io.WriteString(w, "releases")
+	}))
+	t.Cleanup(server.Close)
+	return server, &hits, &notModified
+}
+
+func get(t *testing.T, client *http.Client, url string, header http.Header) string {
+	t.Helper()
+	req, err := http.NewRequest(http.MethodGet, url, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header = header.Clone()
+	if req.Header == nil {
+		req.Header = http.Header{}
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("status = %d", resp.StatusCode)
+	}
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(body)
+}
+
+func TestTransport(t *testing.T) {
+	tests := []struct {
+		name            string
+		header          http.Header
+		wantHits        int32
+		wantNotModified int32
+	}{
+		{
+			name:     "fresh responses are reused",
+			header:   http.Header{"Cache-Control": {"private, max-age=60"}, "Etag": {`"v1"`}},
+			wantHits: 1,
+		},
+		{
+			name:            "stale responses are revalidated",
+			header:          http.Header{"Cache-Control": {"max-age=0"}, "Etag": {`"v1"`}},
+			wantHits:        3,
+			wantNotModified: 2,
+		},
+		{
+			name:            "no-cache always revalidates",
+			header:          http.Header{"Cache-Control": {"no-cache, max-age=60"}, "Etag": {`"v1"`}},
+			wantHits:        3,
+			wantNotModified: 2,
+		},
+		{
+			name:     "no-store is never stored",
+			header:   http.Header{"Cache-Control": {"no-store"}, "Etag": {`"v1"`}},
+			wantHits: 3,
+		},
+		{
+			name:     "without freshness or validator",
+			wantHits: 3,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server, hits, notModified := origin(t, tt.header)
+			client := &http.Client{Transport: New(nil)}
+			for range 3 {
+				if got := get(t, client, server.URL, nil); got != "releases" {
+					t.Fatalf("body = %q", got)
+				}
+			}
+			if hits.Load() != tt.wantHits || notModified.Load() != tt.wantNotModified {
+				t.Errorf("origin saw %d requests (%d not modified), want %d (%d)", hits.Load(), notModified.Load(), tt.wantHits, tt.wantNotModified)
+			}
+		})
+	}
+}
+
+func TestTransport_Expiry(t *testing.T) {
+	server, hits, notModified := origin(t, http.Header{"Cache-Control": {"max-age=60"}, "Etag": {`"v1"`}, "Age": {"30"}})
+	now := time.Now()
+	tr := New(nil)
+	tr.now = func() time.Time { return now }
+	client := &http.Client{Transport: tr}
+
+	get(t, client, server.URL, nil)
+	now = now.Add(20 * time.Second)
+	get(t, client, server.URL, nil)
+	if hits.Load() != 1 {
+		t.Fatalf("origin saw %d requests within max-age", hits.Load())
+	}
+	// The 30 seconds the response spent in a proxy count towards its age.
+	now = now.Add(20 * time.Second)
+	get(t, client, server.URL, nil)
+	if hits.Load() != 2 || notModified.Load() != 1 {
+		t.Errorf("origin saw %d requests (%d not modified), want a revalidation", hits.Load(), notModified.Load())
+	}
+}
+
+func TestTransport_MaxStale(t *testing.T) {
+	server, hits, _ := origin(t, http.Header{"Cache-Control": {"max-age=60"}, "Etag": {`"v1"`}})
+	now := time.Now()
+	tr := New(nil)
+	tr.now = func() time.Time { return now }
+	client := &http.Client{Transport: tr}
+
+	get(t, client, server.URL, nil)
+	now = now.Add(90 * time.Second)
+	get(t, client, server.URL, http.Header{"Cache-Control": {"max-stale"}})
+	get(t, client, server.URL, http.Header{"Cache-Control": {"max-stale=60"}})
+	if hits.Load() != 1 {
+		t.Fatalf("origin saw %d requests, want stale responses accepted", hits.Load())
+	}
+	get(t, client, server.URL, http.Header{"Cache-Control": {"max-stale=10"}})
+	if hits.Load() != 2 {
+		t.Errorf("origin saw %d requests, want a revalidation beyond max-stale", hits.Load())
+	}
+}
+
+func TestTransport_Revalidate(t *testing.T) {
+	server, hits, notModified := origin(t, http.Header{"Cache-Control": {"max-age=60"}, "Etag": {`"v1"`}})
+	client := &http.Client{Transport: New(nil)}
+
+	get(t, client, server.URL, nil)
+	req, _ := http.NewRequestWithContext(Revalidate(context.Background()), http.MethodGet, server.URL, nil)
+	resp, err := client.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if hits.Load() != 2 || notModified.Load() != 1 {
+		t.Errorf("origin saw %d requests (%d not modified), want a revalidation of the fresh response", hits.Load(), notModified.Load())
+	}
+}
+
+func TestTransport_Vary(t *testing.T) {
+	server, hits, _ := origin(t, http.Header{"Cache-Control": {"max-age=60"}, "Vary": {"Accept, Authorization"}})
+	client := &http.Client{Transport: New(nil)}
+
+	get(t, client, server.URL, http.Header{"Authorization": {"Bearer a"}})
+	get(t, client, server.URL, http.Header{"Authorization": {"Bearer a"}})
+	if hits.Load() != 1 {
+		t.Fatalf("origin saw %d requests for the same representation", hits.Load())
+	}
+	get(t, client, server.URL, http.Header{"Authorization": {"Bearer b"}})
+	if hits.Load() != 2 {
+		t.Errorf("origin saw %d requests, a different Authorization must not be served from the cache", hits.Load())
+	}
+}
+
+func TestTransport_Dir(t *testing.T) {
+	dir := t.TempDir()
+	server, hits, notModified := origin(t, http.Header{"Cache-Control": {"private, max-age=0"}, "Etag": {`"v1"`}})
+
+	first := New(nil)
+	first.SetDir(dir, false)
+	get(t, &http.Client{Transport: first}, server.URL, nil)
+
+	// A later run revalidates the stored response instead of fetching it.
+	second := New(nil)
+	second.SetDir(dir, false)
+	if got := get(t, &http.Client{Transport: second}, server.URL, nil); got != "releases" {
+		t.Fatalf("body = %q", got)
+	}
+	if hits.Load() != 2 || notModified.Load() != 1 {
+		t.Errorf("origin saw %d requests (%d not modified), want a revalidation", hits.Load(), notModified.Load())
+	}
+}
+
+func TestTransport_SharedDir(t *testing.T) {
+	tests := []struct {
+		name      string
+		header    http.Header
+		auth      bool
+		wantReuse bool
+	}{
+		{name: "public", header: http.Header{"Cache-Control": {"public, max-age=60"}}, auth: true, wantReuse: true},
+		{name: "private", header: http.Header{"Cache-Control": {"private, max-age=60"}}},
+		{name: "authorized", header: http.Header{"Cache-Control": {"max-age=60"}}, auth: true},
+		{name: "anonymous", header: http.Header{"Cache-Control": {"max-age=60"}}, wantReuse: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server, hits, _ := origin(t, tt.header)
+			tr := New(nil)
+			tr.SetDir(t.TempDir(), true)
+			client := &http.Client{Transport: tr}
+
+			var header http.Header
+			if tt.auth {
+				header = http.Header{"Authorization": {"Bearer token"}}
+			}
+			get(t, client, server.URL, header)
+			get(t, client, server.URL, header)
+			if reused := hits.Load() == 1; reused != tt.wantReuse {
+				t.Errorf("reused = %v, want %v", reused, tt.wantReuse)
+			}
+		})
+	}
+}
+
+func TestTransport_LargeBodiesPassThrough(t *testing.T) {
+	server, hits, _ := origin(t, http.Header{"Cache-Control": {"max-age=60"}})
+	tr := New(nil)
+	tr.MaxBodySize = 4
+	client := &http.Client{Transport: tr}
+
+	get(t, client, server.URL, nil)
+	get(t, client, server.URL, nil)
+	if hits.Load() != 2 {
+		t.Errorf("origin saw %d requests, bodies above MaxBodySize must not be stored", hits.Load())
+	}
+}
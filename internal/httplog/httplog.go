@@ -0,0 +1,91 @@
+// Package httplog traces HTTP requests at the debug log level: method, URL,
+// status, duration and the rate limit headers, never credentials.
+package httplog
+
+import (
+	"fmt"
+	"net/http"
+	neturl "net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	log "github.com/sixban6/ghinstall/internal/logger"
+)
+
+// rateHeaders are the rate limit headers of GitHub and most forges, and
+// Retry-After sent with 429 and 503 responses.
+var rateHeaders = []string{
+	"X-RateLimit-Limit",
+	"X-RateLimit-Remaining",
+	"X-RateLimit-Used",
+	"X-RateLimit-Reset",
+	"X-RateLimit-Resource",
+	"Retry-After",
+}
+
+// Transport is an http.RoundTripper logging the requests it passes to Base.
+type Transport struct {
+	// Base performs the requests.
+	Base http.RoundTripper
+	// Kind names the requests in the log, such as "api" or "download".
+	Kind string
+}
+
+// New returns a Transport logging the requests of base as kind.
+func New(base http.RoundTripper, kind string) *Transport {
+	return &Transport{Base: base, Kind: kind}
+}
+
+func (t *Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if !log.Enabled(log.LevelDebug) {
+		return t.Base.RoundTrip(req)
+	}
+
+	start := time.Now()
+	resp, err := t.Base.RoundTrip(req)
+	elapsed := time.Since(start).Round(time.Millisecond)
+	if err != nil {
+		log.Debug("%s %s %s: %v after %s", t.Kind, req.Method, Redact(req.URL), err, elapsed)
+		return resp, err
+	}
+	log.Debug("%s %s %s: %s in %s%s", t.Kind, req.Method, Redact(req.URL), resp.Status, elapsed, rateLimit(resp.Header))
+	return resp, err
+}
+
+// rateLimit formats the rate limit headers of h, "" when there are none.
+func rateLimit(h http.Header) string {
+	var b strings.Builder
+	for _, name := range rateHeaders {
+		value := h.Get(name)
+		if value == "" {
+			continue
+		}
+		if name == "X-RateLimit-Reset" {
+			// Seconds since the epoch.
+			if sec, err := strconv.ParseInt(value, 10, 64); err == nil {
+				value = time.Unix(sec, 0).UTC().Format(time.RFC3339)
+			}
+		}
+		fmt.Fprintf(&b, " %s=%s", name, value)
+	}
+	if b.Len() == 0 {
+		return ""
+	}
+	return " (" + b.String()[1:] + ")"
+}
+
+// Redact returns u without its password and query values, which may carry
+// tokens or the signatures of pre-signed download URLs.
+func Redact(u *neturl.URL) string {
+	if u.RawQuery == "" {
+		return u.Redacted()
+	}
+	r := *u
+	query := r.Query()
+	for name := range query {
+		query[name] = []string{"REDACTED"}
+	}
+	r.RawQuery = query.Encode()
+	return r.Redacted()
+}
@@ -0,0 +1,59 @@
+package httplog
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+
+	log "github.com/sixban6/ghinstall/internal/logger"
+)
+
+func TestTransport_RoundTrip(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-RateLimit-Limit", "5000")
+		w.Header().Set("X-RateLimit-Remaining", "4999")
+		w.Header().Set("X-RateLimit-Reset", "1767225600")
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	var out bytes.Buffer
+	log.SetWriter(&out)
+	defer log.SetWriter(os.Stderr)
+	defer log.SetLevel(log.LevelInfo)
+
+	client := &http.Client{Transport: New(http.DefaultTransport, "api")}
+	get := func() {
+		t.Helper()
+		req, _ := http.NewRequest("GET", server.URL+"/repos/o/r?access_token=secret", nil)
+		req.Header.Set("Authorization", "Bearer secret")
+		resp, err := client.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+	}
+
+	get()
+	if out.Len() != 0 {
+		t.Errorf("logged %q below the debug level", out.String())
+	}
+
+	log.SetLevel(log.LevelDebug)
+	get()
+	got := out.String()
+	for _, want := range []string{
+		"api GET " + server.URL + "/repos/o/r?access_token=REDACTED: 200 OK in ",
+		"(X-RateLimit-Limit=5000 X-RateLimit-Remaining=4999 X-RateLimit-Reset=2026-01-01T00:00:00Z)",
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("log = %q, missing %q", got, want)
+		}
+	}
+	if strings.Contains(got, "secret") {
+		t.Errorf("log = %q, leaks credentials", got)
+	}
+}
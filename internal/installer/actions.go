@@ -0,0 +1,89 @@
+package installer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	log "github.com/sixban6/ghinstall/internal/logger"
+	"path/filepath"
+	"slices"
+
+	"github.com/sixban6/ghinstall/internal/actions"
+	"github.com/sixban6/ghinstall/internal/config"
+	"github.com/sixban6/ghinstall/internal/manifest"
+	"github.com/sixban6/ghinstall/internal/provider"
+)
+
+// WithToolCache installs every repository into the GitHub Actions tool cache
+// rooted at root instead of its output_dir, as <name>/<version>/<arch>, and
+// marks it complete so actions/tool-cache finds it.
+func WithToolCache(root string) Option {
+	return func(i *Installer) {
+		i.toolCache = root
+	}
+}
+
+// WithGitHubActions reports every install as a notice annotation and adds the
+// directories holding its executables to GITHUB_PATH for the following steps.
+func WithGitHubActions(enabled bool) Option {
+	return func(i *Installer) {
+		i.actions = enabled
+	}
+}
+
+// inToolCache resolves the release of repo and returns repo pinned to it and
+// installing into its tool cache directory.
+func (i *Installer) inToolCache(ctx context.Context, cfg *config.Config, repo config.Repo) (config.Repo, error) {
+	var src provider.Provider
+	if repo.Provider != "" {
+		var err error
+		if src, err = provider.ForRepo(cfg, repo); err != nil {
+			return repo, err
+		}
+	}
+	rel, err := i.resolve(ctx, repo, src)
+	if err != nil {
+		return repo, fmt.Errorf("failed to find latest release: %w", err)
+	}
+
+	repo.Version, repo.Channel, repo.Constraint = rel.TagName, "", ""
+	repo.OutputDir = actions.ToolCacheDir(i.toolCache, toolName(repo), rel.TagName)
+	return repo, nil
+}
+
+// toolName returns the name of repo in the tool cache: its configured name,
+// or the repository name.
+func toolName(repo config.Repo) string {
+	if repo.Name != "" {
+		return repo.Name
+	}
+	if _, name, err := config.ParseRepoURL(repo.URL); err == nil {
+		return name
+	}
+	return filepath.Base(repo.URL)
+}
+
+// reportToActions announces a finished install to the GitHub Actions job.
+func reportToActions(res InstallResult) error {
+	repo := res.Repo
+	repo.OutputDir = res.OutputDir
+	targets, err := repoExecutables(repo)
+	if err != nil && !errors.Is(err, manifest.ErrNoManifest) {
+		return err
+	}
+	var dirs []string
+	for _, target := range targets {
+		if dir := filepath.Dir(target); !slices.Contains(dirs, dir) {
+			dirs = append(dirs, dir)
+		}
+	}
+	for _, dir := range dirs {
+		if err := actions.AddPath(dir); err != nil {
+			log.Warn("Failed to add %s to PATH: %v", dir, err)
+			break
+		}
+	}
+
+	actions.Notice(fmt.Sprintf("Installed %s %s to %s", res.Repo.DisplayName(), res.Tag, res.OutputDir))
+	return nil
+}
@@ -0,0 +1,64 @@
+package installer
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/sixban6/ghinstall/internal/actions"
+	"github.com/sixban6/ghinstall/internal/config"
+	"github.com/sixban6/ghinstall/internal/extractor"
+	"github.com/sixban6/ghinstall/internal/release"
+)
+
+func TestInstaller_Install_GitHubActions(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("executables are detected by extension on Windows")
+	}
+	directReachable = func(context.Context) bool { return true }
+	defer func() { directReachable = PingGoogle }()
+
+	var notices bytes.Buffer
+	actions.Output = &notices
+	defer func() { actions.Output = os.Stdout }()
+	pathFile := filepath.Join(t.TempDir(), "github_path")
+	t.Setenv("GITHUB_PATH", pathFile)
+
+	rel := &release.Release{TagName: "v1.2.3", Assets: []release.Asset{
+		{Name: "app.tar.gz", URL: "https://github.com/owner/app/releases/download/v1.2.3/app.tar.gz"},
+	}}
+	cfg := &config.Config{Github: []config.Repo{
+		{URL: "https://github.com/owner/app", OutputDir: filepath.Join(t.TempDir(), "unused")},
+	}}
+	root := t.TempDir()
+
+	archive := modeTarGz(t, map[string]os.FileMode{"bin/tool": 0755})
+	inst := New(&mockFinder{release: rel}, &mockDownloader{content: archive}, extractor.NewLegacy(),
+		WithToolCache(root), WithGitHubActions(true))
+	if err := inst.Install(context.Background(), cfg, release.DefaultFilter()); err != nil {
+		t.Fatalf("Install() error = %v", err)
+	}
+
+	dir := actions.ToolCacheDir(root, "app", "v1.2.3")
+	if _, err := os.Stat(filepath.Join(dir, "bin", "tool")); err != nil {
+		t.Errorf("tool not installed into the tool cache: %v", err)
+	}
+	if _, err := os.Stat(dir + ".complete"); err != nil {
+		t.Errorf("tool cache entry not marked complete: %v", err)
+	}
+	if _, err := os.Stat(cfg.Github[0].OutputDir); !os.IsNotExist(err) {
+		t.Errorf("output_dir was used: %v", err)
+	}
+
+	data, _ := os.ReadFile(pathFile)
+	if got := strings.TrimSpace(string(data)); got != filepath.Join(dir, "bin") {
+		t.Errorf("GITHUB_PATH = %q, want %s", got, filepath.Join(dir, "bin"))
+	}
+	if !strings.HasPrefix(notices.String(), "::notice::Installed https://github.com/owner/app v1.2.3") {
+		t.Errorf("notices = %q", notices.String())
+	}
+}
@@ -0,0 +1,76 @@
+package installer
+
+import (
+	"context"
+	"fmt"
+	log "github.com/sixban6/ghinstall/internal/logger"
+
+	"github.com/sixban6/ghinstall/internal/attest"
+	"github.com/sixban6/ghinstall/internal/config"
+	"github.com/sixban6/ghinstall/internal/manifest"
+)
+
+// AttestResult reports the signing or checking of the manifest of one
+// installed repository.
+type AttestResult struct {
+	Repo config.Repo
+	// Signature is the path of the manifest signature.
+	Signature string
+	// Problems lists the files that no longer match the signed manifest.
+	Problems []manifest.Problem
+	// Err is set when the manifest could not be signed, or when its signature
+	// or the files could not be checked.
+	Err error
+}
+
+// Attest signs the recorded manifest of every repository with key, replacing
+// any previous signature.
+func (i *Installer) Attest(ctx context.Context, cfg *config.Config, format attest.Format, key string) []AttestResult {
+	results := make([]AttestResult, 0, len(cfg.Github))
+	for _, repo := range cfg.Github {
+		res := AttestResult{Repo: repo}
+		if _, res.Err = manifest.Load(repo.OutputDir, repo.URL); res.Err == nil {
+			res.Signature, res.Err = attest.Sign(ctx, format, key, manifest.Path(repo.OutputDir, repo.URL))
+		}
+		results = append(results, res)
+	}
+	return results
+}
+
+// VerifyAttestations checks the manifest signature of every repository against
+// the public key pub, then the installed files against the signed manifest.
+func (i *Installer) VerifyAttestations(ctx context.Context, cfg *config.Config, format attest.Format, pub string) []AttestResult {
+	results := make([]AttestResult, 0, len(cfg.Github))
+	for _, repo := range cfg.Github {
+		path := manifest.Path(repo.OutputDir, repo.URL)
+		res := AttestResult{Repo: repo, Signature: attest.SignaturePath(path, format)}
+		// The manifest is read only after its signature was checked, so a
+		// forged manifest cannot hide modified files.
+		if res.Err = attest.Verify(ctx, format, pub, path); res.Err == nil {
+			var m *manifest.Manifest
+			if m, res.Err = manifest.Load(repo.OutputDir, repo.URL); res.Err == nil {
+				res.Problems, res.Err = m.Verify(repo.OutputDir)
+			}
+		}
+		results = append(results, res)
+	}
+	return results
+}
+
+// signManifest signs the manifest just written for repo with the key of
+// cfg.Attest, when one is configured.
+func signManifest(ctx context.Context, cfg *config.Config, repo config.Repo) error {
+	if cfg.Attest.Key == "" {
+		return nil
+	}
+	format, err := attest.ParseFormat(cfg.Attest.Format)
+	if err != nil {
+		return err
+	}
+	sig, err := attest.Sign(ctx, format, cfg.Attest.Key, manifest.Path(repo.OutputDir, repo.URL))
+	if err != nil {
+		return fmt.Errorf("failed to sign manifest: %w", err)
+	}
+	log.Info("Signed the manifest of %s: %s", repo.DisplayName(), sig)
+	return nil
+}
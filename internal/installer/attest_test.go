@@ -0,0 +1,76 @@
+//go:build !purego && !wasip1
+
+package installer
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	"github.com/sixban6/ghinstall/internal/attest"
+	"github.com/sixban6/ghinstall/internal/config"
+	"github.com/sixban6/ghinstall/internal/extractor"
+	"github.com/sixban6/ghinstall/internal/manifest"
+	"github.com/sixban6/ghinstall/internal/release"
+)
+
+func TestInstaller_Install_Attest(t *testing.T) {
+	if _, err := exec.LookPath("ssh-keygen"); err != nil {
+		t.Skip("ssh-keygen not available")
+	}
+	keyDir := t.TempDir()
+	key := filepath.Join(keyDir, "attest")
+	if out, err := exec.Command("ssh-keygen", "-q", "-t", "ed25519", "-N", "", "-f", key).CombinedOutput(); err != nil {
+		t.Fatalf("ssh-keygen: %v: %s", err, out)
+	}
+
+	rel := &release.Release{TagName: "v1.0.0", Assets: []release.Asset{
+		{Name: "app.tar.gz", URL: "https://github.com/owner/repo/releases/download/v1.0.0/app.tar.gz"},
+	}}
+	dir := t.TempDir()
+	cfg := &config.Config{
+		Github: []config.Repo{{URL: "https://github.com/owner/repo", OutputDir: dir}},
+		Attest: config.AttestOptions{Key: key},
+	}
+	inst := New(&mockFinder{release: rel}, &mockDownloader{content: tarGz(t, map[string]string{"app": "binary"})}, extractor.NewLegacy())
+	if err := inst.Install(context.Background(), cfg, release.DefaultFilter()); err != nil {
+		t.Fatalf("Install() error = %v", err)
+	}
+
+	sig := attest.SignaturePath(manifest.Path(dir, cfg.Github[0].URL), attest.SSH)
+	if _, err := os.Stat(sig); err != nil {
+		t.Fatalf("install did not sign the manifest: %v", err)
+	}
+	if res := inst.VerifyAttestations(context.Background(), cfg, attest.SSH, key+".pub")[0]; res.Err != nil || len(res.Problems) != 0 {
+		t.Fatalf("VerifyAttestations() after install = %+v", res)
+	}
+
+	os.WriteFile(filepath.Join(dir, "app"), []byte("patched"), 0644)
+	res := inst.VerifyAttestations(context.Background(), cfg, attest.SSH, key+".pub")[0]
+	if res.Err != nil || len(res.Problems) != 1 || res.Problems[0].Path != "app" {
+		t.Errorf("VerifyAttestations() after tampering = %+v", res)
+	}
+
+	// A manifest rewritten to match the tampered files fails its signature.
+	m, err := manifest.Load(dir, cfg.Github[0].URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	m.Files = nil
+	if err := m.Save(dir); err != nil {
+		t.Fatal(err)
+	}
+	if res := inst.VerifyAttestations(context.Background(), cfg, attest.SSH, key+".pub")[0]; res.Err == nil {
+		t.Error("VerifyAttestations() accepted a rewritten manifest")
+	}
+
+	// attest sign re-signs the current manifest.
+	if res := inst.Attest(context.Background(), cfg, attest.SSH, key)[0]; res.Err != nil || res.Signature != sig {
+		t.Fatalf("Attest() = %+v", res)
+	}
+	if res := inst.VerifyAttestations(context.Background(), cfg, attest.SSH, key+".pub")[0]; res.Err != nil {
+		t.Errorf("VerifyAttestations() after Attest() = %+v", res)
+	}
+}
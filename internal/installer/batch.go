@@ -0,0 +1,126 @@
+package installer
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/sixban6/ghinstall/internal/config"
+	"github.com/sixban6/ghinstall/internal/release"
+)
+
+// Result is the outcome of installing one repository with continue_on_error.
+type Result struct {
+	// Repo is the repository as configured.
+	Repo config.Repo
+	// Tag is the release selected for Repo, "" when it failed to resolve.
+	Tag string
+	// Err is why Repo failed to install, nil when it was installed (or, in a
+	// dry run, resolved).
+	Err error
+}
+
+// BatchError is returned by Install when repositories fail with
+// continue_on_error, which installs the others anyway. Results holds the
+// outcome of every repository, in config order.
+type BatchError struct {
+	Results []Result
+}
+
+// Failed returns the failures of e, in config order.
+func (e *BatchError) Failed() []*RepoError {
+	var failed []*RepoError
+	for _, r := range e.Results {
+		if r.Err != nil {
+			failed = append(failed, &RepoError{Repo: r.Repo, Err: r.Err})
+		}
+	}
+	return failed
+}
+
+// Succeeded returns the repositories that were installed, in config order.
+func (e *BatchError) Succeeded() []config.Repo {
+	var repos []config.Repo
+	for _, r := range e.Results {
+		if r.Err == nil {
+			repos = append(repos, r.Repo)
+		}
+	}
+	return repos
+}
+
+func (e *BatchError) Error() string {
+	failed := e.Failed()
+	var b strings.Builder
+	fmt.Fprintf(&b, "failed to install %d of %d repositories:", len(failed), len(e.Results))
+	for _, err := range failed {
+		b.WriteString("\n  " + err.Error())
+	}
+	return b.String()
+}
+
+func (e *BatchError) Unwrap() []error {
+	failed := e.Failed()
+	errs := make([]error, len(failed))
+	for n, err := range failed {
+		errs[n] = err
+	}
+	return errs
+}
+
+// installEach installs every repository of cfg that resolves, whatever happens
+// to the others, and returns a *BatchError when any failed.
+func (i *Installer) installEach(ctx context.Context, cfg *config.Config, filter release.AssetFilter) error {
+	repos, errs := i.resolveEach(ctx, cfg, filter)
+	results := make([]Result, len(cfg.Github))
+	var (
+		ok    []*resolved
+		okAt  []int // the index in results of each of ok
+		fails int
+	)
+	for n, repo := range cfg.Github {
+		results[n] = Result{Repo: repo, Err: errs[n]}
+		if errs[n] != nil {
+			i.tracker(repo).done(errs[n])
+			fails++
+			continue
+		}
+		results[n].Tag = repos[n].rel.TagName
+		ok, okAt = append(ok, repos[n]), append(okAt, n)
+	}
+
+	if len(ok) > 0 {
+		proceed, err := i.checkPlan(ctx, cfg, ok)
+		if err != nil {
+			return err
+		}
+		if proceed {
+			i.preresolve(ctx, cfg, ok)
+			for n, err := range i.installOK(ctx, cfg, ok) {
+				if err != nil {
+					results[okAt[n]].Err = err
+					fails++
+				}
+			}
+		}
+	}
+	if fails == 0 {
+		return nil
+	}
+	return &BatchError{Results: results}
+}
+
+// installOK installs repos one after the other, or concurrently with
+// WithParallel, without stopping at failures, which it returns in the order
+// of repos.
+func (i *Installer) installOK(ctx context.Context, cfg *config.Config, repos []*resolved) []error {
+	if i.parallel > 1 {
+		return i.installConcurrently(ctx, cfg, repos, false)
+	}
+	errs := make([]error, len(repos))
+	for n, r := range repos {
+		errs[n] = i.installResolved(ctx, cfg, r)
+		i.tracker(r.entry).done(errs[n])
+	}
+	return errs
+}
@@ -0,0 +1,78 @@
+package installer
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/sixban6/ghinstall/internal/config"
+	"github.com/sixban6/ghinstall/internal/release"
+)
+
+func TestInstaller_Install_ContinueOnError(t *testing.T) {
+	directReachable = func(context.Context) bool { return true }
+	defer func() { directReachable = PingGoogle }()
+
+	rel := &release.Release{TagName: "v1.0.0", Assets: []release.Asset{
+		{Name: "app.tar.gz", URL: "https://github.com/owner/repo/releases/download/v1.0.0/app.tar.gz"},
+	}}
+
+	for _, parallel := range []int{1, 3} {
+		failDir := t.TempDir()
+		cfg := &config.Config{ContinueOnError: true, Github: []config.Repo{
+			{URL: "https://github.com/owner/repo", OutputDir: failDir},
+			{URL: "https://github.com/owner/repo", OutputDir: t.TempDir(), AssetPattern: "windows"},
+			{URL: "https://github.com/owner/repo", OutputDir: t.TempDir()},
+		}}
+
+		progress := newRecordedProgress()
+		inst := New(&mockFinder{release: rel}, &mockDownloader{content: "archive"}, dirExtractor{failDir: failDir}, WithParallel(parallel), WithProgress(progress))
+		err := inst.Install(context.Background(), cfg, release.DefaultFilter())
+
+		var batchErr *BatchError
+		if !errors.As(err, &batchErr) {
+			t.Fatalf("parallel %d: Install() error = %v, want a BatchError", parallel, err)
+		}
+		if len(batchErr.Results) != 3 {
+			t.Fatalf("parallel %d: %d results, want 3", parallel, len(batchErr.Results))
+		}
+		for n, want := range []struct {
+			tag    string
+			failed bool
+		}{{"v1.0.0", true}, {"", true}, {"v1.0.0", false}} {
+			r := batchErr.Results[n]
+			if r.Repo.OutputDir != cfg.Github[n].OutputDir || r.Tag != want.tag || (r.Err != nil) != want.failed {
+				t.Errorf("parallel %d: result %d = %+v, want tag %q, failed %v", parallel, n, r, want.tag, want.failed)
+			}
+		}
+		if got := batchErr.Succeeded(); len(got) != 1 || got[0].OutputDir != cfg.Github[2].OutputDir {
+			t.Errorf("parallel %d: Succeeded() = %v", parallel, got)
+		}
+		if got := batchErr.Failed(); len(got) != 2 {
+			t.Errorf("parallel %d: Failed() = %v, want 2 failures", parallel, got)
+		}
+		var repoErr *RepoError
+		if !errors.As(err, &repoErr) || repoErr.Repo.OutputDir != failDir {
+			t.Errorf("parallel %d: errors.As(RepoError) = %v, want the failure of %s", parallel, repoErr, failDir)
+		}
+		if err, ok := progress.done[cfg.Github[2].OutputDir]; !ok || err != nil {
+			t.Errorf("parallel %d: Done(%s) = %v, reported %v", parallel, cfg.Github[2].OutputDir, err, ok)
+		}
+	}
+}
+
+func TestInstaller_Install_ContinueOnErrorSucceeds(t *testing.T) {
+	directReachable = func(context.Context) bool { return true }
+	defer func() { directReachable = PingGoogle }()
+
+	rel := &release.Release{TagName: "v1.0.0", Assets: []release.Asset{
+		{Name: "app.tar.gz", URL: "https://github.com/owner/repo/releases/download/v1.0.0/app.tar.gz"},
+	}}
+	cfg := &config.Config{ContinueOnError: true, Github: []config.Repo{
+		{URL: "https://github.com/owner/repo", OutputDir: t.TempDir()},
+	}}
+	inst := New(&mockFinder{release: rel}, &mockDownloader{content: "archive"}, dirExtractor{})
+	if err := inst.Install(context.Background(), cfg, release.DefaultFilter()); err != nil {
+		t.Fatalf("Install() error = %v", err)
+	}
+}
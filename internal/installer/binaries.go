@@ -0,0 +1,61 @@
+package installer
+
+import (
+	"path/filepath"
+	"runtime"
+	"slices"
+	"strings"
+
+	"github.com/sixban6/ghinstall/internal/config"
+)
+
+// binaryNames returns the names the executables of repo are expected to have:
+// its configured binary, or its repository name and alias.
+func binaryNames(repo config.Repo) []string {
+	if repo.Binary != "" {
+		return []string{repo.Binary}
+	}
+	var names []string
+	if _, name, err := config.ParseRepoURL(repo.URL); err == nil {
+		names = append(names, name)
+	}
+	if repo.Name != "" && !slices.Contains(names, repo.Name) {
+		names = append(names, repo.Name)
+	}
+	return names
+}
+
+// repoBinaries returns the executables among targets named names. When none
+// is named exactly, executables whose name continues a name with a version or
+// platform, such as "tool-linux-amd64" or "tool_1.2.0", are returned instead.
+func repoBinaries(targets, names []string) []string {
+	var exact, prefixed []string
+	for _, target := range targets {
+		base := executableName(target)
+		for _, name := range names {
+			switch {
+			case strings.EqualFold(base, name) && (runtime.GOOS == "windows" || base == name):
+				exact = append(exact, target)
+			case len(base) > len(name) && strings.HasPrefix(base, name) && strings.ContainsRune("-_.", rune(base[len(name)])):
+				prefixed = append(prefixed, target)
+			default:
+				continue
+			}
+			break
+		}
+	}
+	if len(exact) > 0 {
+		return exact
+	}
+	return prefixed
+}
+
+// executableName returns the file name of path without the extensions that
+// make files executable on Windows.
+func executableName(path string) string {
+	base := filepath.Base(path)
+	if runtime.GOOS == "windows" {
+		base = strings.TrimSuffix(base, filepath.Ext(base))
+	}
+	return base
+}
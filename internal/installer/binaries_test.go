@@ -0,0 +1,69 @@
+package installer
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"runtime"
+	"testing"
+
+	"github.com/sixban6/ghinstall/internal/config"
+	"github.com/sixban6/ghinstall/internal/extractor"
+	"github.com/sixban6/ghinstall/internal/release"
+)
+
+func TestRepoBinaries(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("executable names carry extensions on Windows")
+	}
+
+	tests := []struct {
+		name    string
+		targets []string
+		names   []string
+		want    []string
+	}{
+		{name: "exact", targets: []string{"/o/tool/tool", "/o/tool/tool-helper", "/o/tool/install.sh"}, names: []string{"tool"}, want: []string{"/o/tool/tool"}},
+		{name: "platform suffix", targets: []string{"/o/tool-linux-amd64", "/o/toolbox"}, names: []string{"tool"}, want: []string{"/o/tool-linux-amd64"}},
+		{name: "alias", targets: []string{"/o/ripgrep/rg", "/o/ripgrep/complete.sh"}, names: []string{"ripgrep", "rg"}, want: []string{"/o/ripgrep/rg"}},
+		{name: "none", targets: []string{"/o/install.sh"}, names: []string{"tool"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := repoBinaries(tt.targets, tt.names); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("repoBinaries() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestInstaller_Install_BinariesOnly(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("symlink shims are not used on Windows")
+	}
+
+	mockRel := &release.Release{
+		TagName: "v1.0.0",
+		Assets:  []release.Asset{{Name: "app.tar.gz", URL: "https://github.com/owner/app/releases/download/v1.0.0/app.tar.gz"}},
+	}
+	outputDir := t.TempDir()
+	binDir := filepath.Join(t.TempDir(), "bin")
+	cfg := &config.Config{
+		Github: []config.Repo{{URL: "https://github.com/owner/app", OutputDir: outputDir, BinariesOnly: true}},
+		BinDir: binDir,
+	}
+
+	archive := modeTarGz(t, map[string]os.FileMode{"app_1.0/app": 0755, "app_1.0/scripts/setup.sh": 0755, "app_1.0/README.md": 0644})
+	installer := New(&mockFinder{release: mockRel}, &mockDownloader{content: archive}, extractor.NewLegacy())
+	if err := installer.Install(context.Background(), cfg, release.DefaultFilter()); err != nil {
+		t.Fatalf("Installer.Install() error = %v", err)
+	}
+
+	if target, err := os.Readlink(filepath.Join(binDir, "app")); err != nil || target != filepath.Join(outputDir, "app_1.0", "app") {
+		t.Errorf("shim target = %s, %v, want the app binary", target, err)
+	}
+	if _, err := os.Lstat(filepath.Join(binDir, "setup.sh")); err == nil {
+		t.Error("other executables should not be linked with binaries_only")
+	}
+}
@@ -0,0 +1,78 @@
+package installer
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"io"
+	"strings"
+
+	"github.com/sixban6/ghinstall/internal/release"
+)
+
+// expectation describes what downloaded content must look like. Zero values
+// disable the corresponding check.
+type expectation struct {
+	size   int64
+	sha256 string
+}
+
+// wrap returns rc verifying the expectation. The content is hashed even when
+// there is nothing to check, so its digest is known once it is read completely.
+func (e expectation) wrap(rc io.ReadCloser) *verifyReader {
+	return &verifyReader{ReadCloser: rc, hash: sha256.New(), want: e}
+}
+
+// untrusted returns e holding content from a mirror, which is untrusted, to
+// the GitHub API metadata of asset as well.
+func (e expectation) untrusted(asset *release.Asset) expectation {
+	e.size = asset.Size
+	if e.sha256 == "" {
+		e.sha256 = githubDigest(asset.Digest)
+	}
+	return e
+}
+
+// githubDigest returns the hex SHA-256 from a GitHub asset digest ("sha256:<hex>").
+func githubDigest(digest string) string {
+	if hexDigest, ok := strings.CutPrefix(digest, "sha256:"); ok {
+		return strings.ToLower(hexDigest)
+	}
+	return ""
+}
+
+// verifyReader hashes and counts the content read through it and, at EOF,
+// replaces io.EOF with an error when it does not match the expectation. Callers
+// (the cache, the extractor) therefore see a failed read instead of a complete
+// archive and never store or unpack unverified content.
+type verifyReader struct {
+	io.ReadCloser
+	hash   hash.Hash
+	read   int64
+	want   expectation
+	digest string
+}
+
+func (r *verifyReader) Read(p []byte) (int, error) {
+	n, err := r.ReadCloser.Read(p)
+	r.hash.Write(p[:n])
+	r.read += int64(n)
+	if err == io.EOF {
+		if r.want.size > 0 && r.read != r.want.size {
+			return n, fmt.Errorf("size mismatch: expected %d bytes, received %d", r.want.size, r.read)
+		}
+		got := hex.EncodeToString(r.hash.Sum(nil))
+		if r.want.sha256 != "" && got != r.want.sha256 {
+			return n, fmt.Errorf("checksum mismatch: expected sha256 %s, got %s", r.want.sha256, got)
+		}
+		r.digest = got
+	}
+	return n, err
+}
+
+// Digest returns the hex SHA-256 of the content once it was read completely
+// and verified, or "" before.
+func (r *verifyReader) Digest() string {
+	return r.digest
+}
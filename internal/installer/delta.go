@@ -0,0 +1,116 @@
+package installer
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	log "github.com/sixban6/ghinstall/internal/logger"
+	"io"
+	"os"
+
+	"github.com/sixban6/ghinstall/internal/cache"
+	"github.com/sixban6/ghinstall/internal/config"
+	"github.com/sixban6/ghinstall/internal/delta"
+	"github.com/sixban6/ghinstall/internal/downloader"
+	"github.com/sixban6/ghinstall/internal/release"
+	"github.com/sixban6/ghinstall/internal/state"
+	"github.com/sixban6/ghinstall/internal/tmpdir"
+)
+
+// deltaSource is a patch rebuilding the selected asset from a cached base.
+type deltaSource struct {
+	url  string
+	base string
+	from string
+}
+
+// deltaPatch returns the patch to rebuild asset from the previously installed
+// version, or nil when a full download is needed: delta is off, the
+// downloader cannot apply patches, nothing usable was installed before or the
+// release publishes no patch from that version.
+func (i *Installer) deltaPatch(cfg *config.Config, repo config.Repo, rel *release.Release, asset *release.Asset) *deltaSource {
+	if !repo.Delta || cfg.CacheDir == "" {
+		return nil
+	}
+	if _, ok := i.downloader.(downloader.DeltaClient); !ok {
+		return nil
+	}
+
+	st, err := state.Load(repo.OutputDir)
+	if err != nil {
+		return nil
+	}
+	prev, ok := st.Get(repo.URL)
+	if !ok || prev.SHA256 == "" || prev.Tag == rel.TagName {
+		return nil
+	}
+
+	c, err := cache.Open(cache.ResolveDir(cfg.CacheDir), cfg.SharedCache())
+	if err != nil {
+		return nil
+	}
+	base := c.BlobPath(prev.SHA256)
+	if _, err := os.Stat(base); err != nil {
+		return nil
+	}
+
+	name := delta.PatchName(asset.Name, prev.Tag)
+	for _, a := range rel.Assets {
+		if a.Name == name {
+			return &deltaSource{url: a.URL, base: base, from: prev.Tag}
+		}
+	}
+	return nil
+}
+
+// downloadDelta rebuilds asset from patch into a temporary file and checks it
+// against the expected digest before handing it out. The size must be known:
+// patches rebuilding another size are refused before writing anything.
+func (i *Installer) downloadDelta(ctx context.Context, patch *deltaSource, asset *release.Asset, want expectation) (io.ReadCloser, error) {
+	log.Info("Downloading delta from %s: %s", patch.from, patch.url)
+
+	if want.size <= 0 {
+		want.size = asset.Size
+	}
+	if want.sha256 == "" {
+		want.sha256 = githubDigest(asset.Digest)
+	}
+	if want.size <= 0 {
+		return nil, fmt.Errorf("no size to verify the rebuilt asset against")
+	}
+
+	tmp, err := os.CreateTemp(tmpdir.Dir(), "ghinstall-delta-*")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create temporary file: %w", err)
+	}
+
+	h := sha256.New()
+	n, err := i.downloader.(downloader.DeltaClient).DownloadDelta(ctx, patch.url, patch.base, want.size, io.MultiWriter(tmp, h))
+	if err == nil && want.size > 0 && n != want.size {
+		err = fmt.Errorf("size mismatch: expected %d bytes, rebuilt %d", want.size, n)
+	}
+	if got := hex.EncodeToString(h.Sum(nil)); err == nil && want.sha256 != "" && got != want.sha256 {
+		err = fmt.Errorf("checksum mismatch: expected sha256 %s, rebuilt %s", want.sha256, got)
+	}
+	if err == nil {
+		_, err = tmp.Seek(0, io.SeekStart)
+	}
+	if err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return nil, err
+	}
+	return &tempFile{File: tmp}, nil
+}
+
+// tempFile removes itself when closed.
+type tempFile struct {
+	*os.File
+}
+
+func (f *tempFile) Close() error {
+	err := f.File.Close()
+	os.Remove(f.Name())
+	return err
+}
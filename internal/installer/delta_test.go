@@ -0,0 +1,82 @@
+package installer
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+
+	"github.com/sixban6/ghinstall/internal/config"
+	"github.com/sixban6/ghinstall/internal/downloader"
+	"github.com/sixban6/ghinstall/internal/release"
+)
+
+func TestInstaller_Install_Delta(t *testing.T) {
+	directReachable = func(context.Context) bool { return true }
+	defer func() { directReachable = PingGoogle }()
+
+	files := map[string]string{
+		"/v1/app.bin":                        "../delta/testdata/old.bin",
+		"/v2/app.bin":                        "../delta/testdata/new.bin",
+		"/v2/app.bin.from-v1.bsdiff":         "../delta/testdata/old-to-new.bsdiff",
+		"/v2-corrupt/app.bin":                "../delta/testdata/new.bin",
+		"/v2-corrupt/app.bin.from-v1.bsdiff": "../delta/testdata/old.bin",
+	}
+	newContent, err := os.ReadFile(files["/v2/app.bin"])
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name         string
+		dir          string
+		wantFullHits int
+	}{
+		{name: "patch applied", dir: "v2", wantFullHits: 0},
+		{name: "corrupt patch falls back", dir: "v2-corrupt", wantFullHits: 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			hits := map[string]int{}
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				hits[r.URL.Path]++
+				http.ServeFile(w, r, files[r.URL.Path])
+			}))
+			defer server.Close()
+
+			asset := func(path string, size int) release.Asset {
+				return release.Asset{Name: path[len("/"+tt.dir+"/"):], URL: server.URL + path, Size: int64(size)}
+			}
+			cfg := &config.Config{
+				Github:   []config.Repo{{URL: "https://github.com/owner/repo", OutputDir: t.TempDir(), Delta: true}},
+				CacheDir: t.TempDir(),
+			}
+
+			v1 := &release.Release{TagName: "v1", Assets: []release.Asset{
+				{Name: "app.bin", URL: server.URL + "/v1/app.bin"},
+			}}
+			if err := New(&mockFinder{release: v1}, downloader.NewHTTPClient(), &readingExtractor{}).Install(context.Background(), cfg, release.ByRegex(`^app\.bin$`)); err != nil {
+				t.Fatalf("installing v1: %v", err)
+			}
+
+			v2 := &release.Release{TagName: "v2", Assets: []release.Asset{
+				asset("/"+tt.dir+"/app.bin", len(newContent)),
+				asset("/"+tt.dir+"/app.bin.from-v1.bsdiff", 0),
+			}}
+			ext := &readingExtractor{}
+			if err := New(&mockFinder{release: v2}, downloader.NewHTTPClient(), ext).Install(context.Background(), cfg, release.ByRegex(`^app\.bin$`)); err != nil {
+				t.Fatalf("installing v2: %v", err)
+			}
+
+			if !bytes.Equal(ext.content, newContent) {
+				t.Error("installed content differs from the new release")
+			}
+			if got := hits["/"+tt.dir+"/app.bin"]; got != tt.wantFullHits {
+				t.Errorf("full asset downloaded %d times, want %d", got, tt.wantFullHits)
+			}
+		})
+	}
+}
@@ -0,0 +1,43 @@
+package installer
+
+import (
+	"context"
+	"strings"
+
+	"github.com/sixban6/ghinstall/internal/config"
+	"github.com/sixban6/ghinstall/internal/downloader"
+	log "github.com/sixban6/ghinstall/internal/logger"
+)
+
+// DownloadToFile downloads url into path the way installs download assets:
+// through the URL rewriter, or for GitHub URLs through the mirror when GitHub
+// is not reachable directly, with the configured timeouts and redirect policy.
+// When sha256 is given the content must match it. path is only replaced by a
+// complete, verified download.
+func (i *Installer) DownloadToFile(ctx context.Context, cfg *config.Config, url, path, sha256 string) (int64, error) {
+	i.configureRedirects(cfg)
+	i.configureTimeouts(cfg)
+	i.configureHTTPCache(cfg)
+	configureFDLimits(cfg)
+
+	downloadURL, rewritten := i.rewrite(url)
+	switch {
+	case rewritten:
+		log.Info("Download URL rewritten to %s", downloadURL)
+	case strings.HasPrefix(url, "https://github.com/") && !directReachable(ctx):
+		downloadURL = cfg.GetDownloadURL("", url)
+		if downloadURL != url {
+			log.Info("Using mirror: %s", downloadURL)
+			i.configureMirror(cfg)
+		}
+	}
+
+	log.Info("Downloading %s", downloadURL)
+	rc, err := i.downloader.Download(ctx, downloadURL)
+	if err != nil {
+		return 0, err
+	}
+	body := expectation{sha256: strings.ToLower(sha256)}.wrap(rc)
+	defer body.Close()
+	return downloader.WriteFile(path, body)
+}
@@ -0,0 +1,65 @@
+package installer
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/sixban6/ghinstall/internal/config"
+)
+
+func TestInstaller_DownloadToFile(t *testing.T) {
+	const (
+		githubURL = "https://github.com/owner/repo/releases/download/v1.0.0/app.tar.gz"
+		otherURL  = "https://example.com/app.tar.gz"
+		// sha256 of "content".
+		digest = "ed7002b439e9ac845f22357d822bac1444730fbdb6016d3ec9432297b9ec9f73"
+	)
+	d := urlDownloader{
+		githubURL: "content",
+		otherURL:  "content",
+		"https://mirror.example.com/" + githubURL: "content",
+	}
+
+	tests := []struct {
+		name    string
+		direct  bool
+		url     string
+		sha256  string
+		wantErr bool
+	}{
+		{name: "direct", direct: true, url: githubURL, sha256: digest},
+		// urlDownloader fails on URLs it does not know, so these also check
+		// which URL was downloaded.
+		{name: "mirror", url: githubURL},
+		{name: "only GitHub URLs are mirrored", url: otherURL},
+		{name: "checksum mismatch", direct: true, url: githubURL, sha256: "ff", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			directReachable = func(context.Context) bool { return tt.direct }
+			defer func() { directReachable = PingGoogle }()
+
+			cfg := &config.Config{MirrorURL: "https://mirror.example.com"}
+			path := filepath.Join(t.TempDir(), "app.tar.gz")
+			inst := New(&mockFinder{}, d, &mockExtractor{})
+			_, err := inst.DownloadToFile(context.Background(), cfg, tt.url, path, tt.sha256)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("DownloadToFile() error = %v, wantErr %v", err, tt.wantErr)
+			}
+
+			data, readErr := os.ReadFile(path)
+			if tt.wantErr {
+				if readErr == nil {
+					t.Errorf("unverified download was written to %s", path)
+				}
+				return
+			}
+			if string(data) != "content" {
+				t.Errorf("file = %q, want %q", data, "content")
+			}
+		})
+	}
+}
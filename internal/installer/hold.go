@@ -0,0 +1,119 @@
+package installer
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/sixban6/ghinstall/internal/config"
+	log "github.com/sixban6/ghinstall/internal/logger"
+	"github.com/sixban6/ghinstall/internal/provider"
+	"github.com/sixban6/ghinstall/internal/release"
+	"github.com/sixban6/ghinstall/internal/state"
+	"golang.org/x/mod/semver"
+)
+
+// MajorUpgrade is an upgrade to a new major version that hold_on_major holds
+// unless the WithMajorApproval function approves it.
+type MajorUpgrade struct {
+	Repo config.Repo
+	// From is the installed tag and To the latest one.
+	From, To string
+}
+
+// WithMajorApproval makes installs ask fn before holding a repository with
+// hold_on_major at its installed version: when fn approves, the new major
+// version is installed. fn is called for one repository at a time; an error
+// from it fails the repository.
+func WithMajorApproval(fn func(ctx context.Context, u MajorUpgrade) (bool, error)) Option {
+	return func(i *Installer) {
+		i.approveMajor = fn
+	}
+}
+
+// hold returns the release to install instead of rel, the latest release of
+// repo: the installed one when the hold rules of repo keep it from upgrading,
+// rel otherwise. Pinned repositories install their version regardless.
+func (i *Installer) hold(ctx context.Context, repo config.Repo, src provider.Provider, rel *release.Release) (*release.Release, error) {
+	if repo.Version != "" || !repo.HoldOnMajor && len(repo.HoldPatterns) == 0 {
+		return rel, nil
+	}
+	installed := installedTag(repo)
+	if installed == "" || installed == rel.TagName {
+		return rel, nil
+	}
+	reason := holdReason(repo, installed, rel)
+	if reason == "" {
+		return rel, nil
+	}
+	if reason == reasonMajor && i.approveMajor != nil {
+		i.approvals.Lock()
+		ok, err := i.approveMajor(ctx, MajorUpgrade{Repo: repo, From: installed, To: rel.TagName})
+		i.approvals.Unlock()
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			log.Info("%s: upgrading from %s to the new major version %s as approved", repo.DisplayName(), installed, rel.TagName)
+			return rel, nil
+		}
+	}
+
+	log.Warn("%s: holding %s instead of upgrading to %s, which %s; pin %s to upgrade",
+		repo.DisplayName(), installed, rel.TagName, reason, rel.TagName)
+	held := repo
+	held.Version, held.Channel, held.Constraint = installed, "", ""
+	heldRel, err := i.resolve(ctx, held, src)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find held release %s: %w", installed, err)
+	}
+	return heldRel, nil
+}
+
+const reasonMajor = "is a new major version"
+
+// holdReason returns why the hold rules of repo keep the installed tag from
+// being upgraded to rel, or "".
+func holdReason(repo config.Repo, installed string, rel *release.Release) string {
+	if repo.HoldOnMajor && crossesMajor(installed, rel.TagName) {
+		return reasonMajor
+	}
+	for _, pattern := range repo.HoldPatterns {
+		re, err := regexp.Compile("(?i)" + pattern)
+		if err != nil {
+			continue
+		}
+		for _, text := range []string{rel.TagName, rel.Name, rel.Body} {
+			if re.MatchString(text) {
+				return fmt.Sprintf("matches hold pattern %q", pattern)
+			}
+		}
+	}
+	return ""
+}
+
+// crossesMajor reports whether to is a semantic version of a higher major
+// version than from, or of a higher minor version when from is below 1.0,
+// whose minor versions may break compatibility.
+func crossesMajor(from, to string) bool {
+	from, to = "v"+strings.TrimPrefix(from, "v"), "v"+strings.TrimPrefix(to, "v")
+	if !semver.IsValid(from) || !semver.IsValid(to) {
+		return false
+	}
+	if semver.Major(from) == "v0" {
+		return semver.Compare(semver.MajorMinor(to), semver.MajorMinor(from)) > 0
+	}
+	return semver.Compare(semver.Major(to), semver.Major(from)) > 0
+}
+
+// installedTag returns the tag of repo recorded in its output directory, ""
+// when it is not installed there or the state cannot be read.
+func installedTag(repo config.Repo) string {
+	st, err := state.Load(repo.OutputDir)
+	if err != nil {
+		return ""
+	}
+	rec, _ := st.Get(repo.URL)
+	return rec.Tag
+}
@@ -0,0 +1,86 @@
+package installer
+
+import (
+	"context"
+	"testing"
+
+	"github.com/sixban6/ghinstall/internal/config"
+	"github.com/sixban6/ghinstall/internal/release"
+	"github.com/sixban6/ghinstall/internal/state"
+)
+
+func TestInstaller_Hold(t *testing.T) {
+	finder := &listFinder{releases: []release.Release{
+		{TagName: "v2.0.0", Body: "Drops the old flags.", Assets: []release.Asset{{Name: "app.tar.gz"}}},
+		{TagName: "v1.5.0", Body: "BREAKING: renames the config file.", Assets: []release.Asset{{Name: "app.tar.gz"}}},
+		{TagName: "v1.4.0", Assets: []release.Asset{{Name: "app.tar.gz"}}},
+	}}
+
+	tests := []struct {
+		name      string
+		installed string
+		repo      config.Repo
+		approve   bool
+		wantTag   string
+	}{
+		{name: "no rules", installed: "v1.4.0", wantTag: "v2.0.0"},
+		{name: "hold on major", installed: "v1.4.0", repo: config.Repo{HoldOnMajor: true}, wantTag: "v1.4.0"},
+		{name: "approved major", installed: "v1.4.0", repo: config.Repo{HoldOnMajor: true}, approve: true, wantTag: "v2.0.0"},
+		{name: "same major", installed: "v2.0.0", repo: config.Repo{HoldOnMajor: true}, wantTag: "v2.0.0"},
+		{name: "not installed", repo: config.Repo{HoldOnMajor: true}, wantTag: "v2.0.0"},
+		{name: "notes match", installed: "v1.4.0", repo: config.Repo{HoldPatterns: []string{"old flags"}}, wantTag: "v1.4.0"},
+		{name: "notes differ", installed: "v1.4.0", repo: config.Repo{HoldPatterns: []string{"breaking"}}, wantTag: "v2.0.0"},
+		{name: "pinned", installed: "v1.4.0", repo: config.Repo{HoldOnMajor: true, Version: "v2.0.0"}, wantTag: "v2.0.0"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := tt.repo
+			repo.URL, repo.OutputDir = "https://github.com/owner/app", t.TempDir()
+			if tt.installed != "" {
+				st, _ := state.Load(repo.OutputDir)
+				st.Put(state.Record{Repo: repo.URL, Tag: tt.installed, Asset: "app.tar.gz"})
+				if err := st.Save(); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			cfg := &config.Config{Github: []config.Repo{repo}}
+			var asked []MajorUpgrade
+			approve := WithMajorApproval(func(ctx context.Context, u MajorUpgrade) (bool, error) {
+				asked = append(asked, u)
+				return tt.approve, nil
+			})
+			r, err := New(finder, nil, nil, approve).resolveRepo(context.Background(), cfg, repo, release.DefaultFilter())
+			if err != nil {
+				t.Fatalf("resolveRepo() error = %v", err)
+			}
+			if r.rel.TagName != tt.wantTag {
+				t.Errorf("resolved %s, want %s", r.rel.TagName, tt.wantTag)
+			}
+			if wantAsked := tt.repo.HoldOnMajor && tt.installed == "v1.4.0" && tt.repo.Version == ""; (len(asked) > 0) != wantAsked {
+				t.Errorf("asked for approval %v, want asked %v", asked, wantAsked)
+			} else if wantAsked && (asked[0].From != "v1.4.0" || asked[0].To != "v2.0.0") {
+				t.Errorf("asked to upgrade from %s to %s, want v1.4.0 to v2.0.0", asked[0].From, asked[0].To)
+			}
+		})
+	}
+}
+
+func TestCrossesMajor(t *testing.T) {
+	tests := []struct {
+		from, to string
+		want     bool
+	}{
+		{"v1.4.0", "v2.0.0", true},
+		{"v1.4.0", "v1.9.0", false},
+		{"1.4.0", "v2.0.0-rc1", true},
+		{"v0.3.1", "v0.4.0", true},
+		{"v0.3.1", "v0.3.2", false},
+		{"nightly", "v2.0.0", false},
+	}
+	for _, tt := range tests {
+		if got := crossesMajor(tt.from, tt.to); got != tt.want {
+			t.Errorf("crossesMajor(%s, %s) = %v, want %v", tt.from, tt.to, got, tt.want)
+		}
+	}
+}
@@ -2,50 +2,235 @@ package installer
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	log "github.com/sixban6/ghinstall/internal/logger"
+	"io"
 	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
 	"time"
 
+	"github.com/sixban6/ghinstall/internal/actions"
+	"github.com/sixban6/ghinstall/internal/cache"
+	"github.com/sixban6/ghinstall/internal/completion"
 	"github.com/sixban6/ghinstall/internal/config"
 	"github.com/sixban6/ghinstall/internal/downloader"
 	"github.com/sixban6/ghinstall/internal/extractor"
+	"github.com/sixban6/ghinstall/internal/fdlimit"
+	"github.com/sixban6/ghinstall/internal/filelock"
+	"github.com/sixban6/ghinstall/internal/httpcache"
+	"github.com/sixban6/ghinstall/internal/manifest"
+	"github.com/sixban6/ghinstall/internal/neterr"
+	"github.com/sixban6/ghinstall/internal/provider"
 	"github.com/sixban6/ghinstall/internal/release"
+	"github.com/sixban6/ghinstall/internal/sandbox"
+	"github.com/sixban6/ghinstall/internal/shim"
+	"github.com/sixban6/ghinstall/internal/state"
+	"github.com/sixban6/ghinstall/internal/tmpdir"
 )
 
+// LockFileName is the advisory lock file created in an output directory
+// while it is being installed into.
+const LockFileName = ".ghinstall.lock"
+
 type Installer struct {
 	finder     release.Finder
 	downloader downloader.Client
 	extractor  extractor.Extractor
+	processors []PostProcessor
+	middleware []Middleware
+	events     func(config.Repo, extractor.Event)
+	// forceRefresh reinstalls assets replaced upstream under the installed tag.
+	forceRefresh bool
+	// force re-downloads and re-extracts everything, see WithForce.
+	force bool
+	// update skips repositories still at their installed tag, see WithUpdate.
+	update bool
+	// toolCache is the GitHub Actions tool cache root to install into, see WithToolCache.
+	toolCache string
+	// actions reports installs to the GitHub Actions job.
+	actions bool
+	// defaultExtractor is set when New chose the extractor, which the
+	// extractor setting of the config may then replace.
+	defaultExtractor bool
+	// parallel is the number of repositories installed at once, see WithParallel.
+	parallel int
+	progress Progress
+	// rewriteURL rewrites download URLs, see WithURLRewriter.
+	rewriteURL func(string) string
+	// plan is given the downloads of an install, see WithPlan.
+	plan func(context.Context, *DownloadPlan) error
+	// dryRun stops installs once they are planned, see WithDryRun.
+	dryRun bool
+	// platform is the GOOS/GOARCH Prefetch selects assets for, see WithPlatform.
+	platform string
+	// approveMajor is asked before holding a major upgrade, see
+	// WithMajorApproval; approvals serializes the questions.
+	approveMajor func(context.Context, MajorUpgrade) (bool, error)
+	approvals    sync.Mutex
+	// callbacks serializes the middleware and the Progress while repositories
+	// are resolved concurrently without WithParallel.
+	callbacks sync.Mutex
+}
+
+// Option customizes an Installer.
+type Option func(*Installer)
+
+// WithPostProcessors appends processors run after each repository is extracted.
+func WithPostProcessors(processors ...PostProcessor) Option {
+	return func(i *Installer) {
+		i.processors = append(i.processors, processors...)
+	}
+}
+
+// WithExtractEvents registers fn to receive an event for every archive entry
+// extracted, for rendering live file lists. Extractors that cannot report
+// entries (such as the system extractor) extract without events.
+func WithExtractEvents(fn func(repo config.Repo, ev extractor.Event)) Option {
+	return func(i *Installer) {
+		i.events = fn
+	}
+}
+
+// WithForceRefresh makes installs replace an asset that upstream overwrote
+// under the installed tag. Without it such a change is only warned about and
+// the installed copy is kept.
+func WithForceRefresh(force bool) Option {
+	return func(i *Installer) {
+		i.forceRefresh = force
+	}
+}
+
+// WithForce makes installs bypass everything that would reuse earlier work:
+// up-to-date repositories are installed again, the asset is downloaded in full
+// even when cached or patchable, assets replaced upstream are reinstalled, and
+// the files of the previous install are removed before extracting, so a
+// manually broken install is deployed cleanly.
+func WithForce(force bool) Option {
+	return func(i *Installer) {
+		i.force = force
+	}
+}
+
+// WithURLRewriter makes installs download an asset from the URL fn returns for
+// its GitHub URL, e.g. to build the URLs of a corporate proxy. Returning the
+// URL unchanged (or "") keeps the usual choice between GitHub and the mirror.
+// Rewritten downloads are verified against the GitHub metadata like mirrored
+// ones.
+func WithURLRewriter(fn func(assetURL string) string) Option {
+	return func(i *Installer) {
+		i.rewriteURL = fn
+	}
+}
+
+// WithFinder makes the Installer look up releases with f instead of the
+// Finder given to New, e.g. a fake in the tests of library users.
+func WithFinder(f release.Finder) Option {
+	return func(i *Installer) {
+		i.finder = f
+	}
+}
+
+// WithDownloader makes the Installer download assets with d instead of the
+// client given to New.
+func WithDownloader(d downloader.Client) Option {
+	return func(i *Installer) {
+		i.downloader = d
+	}
 }
 
-func New(f release.Finder, d downloader.Client, e extractor.Extractor) *Installer {
+// WithExtractor makes the Installer extract assets with e instead of the
+// extractor given to New. Like one given to New, e is not replaced by the
+// extractor setting of the config.
+func WithExtractor(e extractor.Extractor) Option {
+	return func(i *Installer) {
+		i.extractor = e
+		i.defaultExtractor = false
+	}
+}
+
+func New(f release.Finder, d downloader.Client, e extractor.Extractor, opts ...Option) *Installer {
 	if f == nil {
 		f = release.NewGitHubClient()
 	}
 	if d == nil {
 		d = downloader.NewHTTPClient()
 	}
+	defaultExtractor := e == nil
 	if e == nil {
 		e = extractor.New()
 	}
 
-	return &Installer{
-		finder:     f,
-		downloader: d,
-		extractor:  e,
+	i := &Installer{
+		finder:           f,
+		downloader:       d,
+		extractor:        e,
+		defaultExtractor: defaultExtractor,
+	}
+	for _, opt := range opts {
+		opt(i)
 	}
+	return i
 }
 
+// Install installs every repository of cfg. All of them are resolved first,
+// concurrently, so a config with several broken entries reports them all at
+// once, before anything is downloaded.
 func (i *Installer) Install(ctx context.Context, cfg *config.Config, filter release.AssetFilter) error {
-	for _, repo := range cfg.Github {
-		if err := i.installRepo(ctx, cfg, repo, filter); err != nil {
-			return fmt.Errorf("failed to install %s: %w", repo.URL, err)
+	if i.toolCache == "" {
+		if err := CheckOutputDirs(cfg); err != nil {
+			return err
+		}
+	}
+	i.configureHTTPCache(cfg)
+	i.configureAPIBudget(cfg)
+	configureFDLimits(cfg)
+	if cfg.ContinueOnError {
+		return i.installEach(ctx, cfg, filter)
+	}
+	repos, err := i.resolveAll(ctx, cfg, filter)
+	if err != nil {
+		return err
+	}
+	if ok, err := i.checkPlan(ctx, cfg, repos); !ok {
+		return err
+	}
+	i.preresolve(ctx, cfg, repos)
+	if i.parallel > 1 {
+		return i.installParallel(ctx, cfg, repos)
+	}
+	for _, r := range repos {
+		err := i.installResolved(ctx, cfg, r)
+		i.tracker(r.entry).done(err)
+		if err != nil {
+			return &RepoError{Repo: r.entry, Err: err}
 		}
 	}
 	return nil
 }
 
+// RepoError is returned by Install when a repository fails to install.
+type RepoError struct {
+	Repo config.Repo
+	Err  error
+}
+
+func (e *RepoError) Error() string {
+	return fmt.Sprintf("failed to install %s: %v", e.Repo.DisplayName(), e.Err)
+}
+
+func (e *RepoError) Unwrap() error {
+	return e.Err
+}
+
+// directReachable decides whether GitHub is downloaded from directly or through
+// the mirror; tests replace it.
+var directReachable = PingGoogle
+
 func PingGoogle(ctx context.Context) bool {
 	// 如果外部没传超时，自己补一个 3s 的默认超时，防止阻塞
 	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
@@ -62,60 +247,818 @@ func PingGoogle(ctx context.Context) bool {
 }
 
 func (i *Installer) installRepo(ctx context.Context, cfg *config.Config, repo config.Repo, filter release.AssetFilter) error {
-	log.Info("Installing %s to %s", repo.URL, repo.OutputDir)
+	r, err := i.resolveRepo(ctx, cfg, repo, filter)
+	if err != nil {
+		return err
+	}
+	return i.installResolved(ctx, cfg, r)
+}
 
-	owner, repoName, err := config.ParseRepoURL(repo.URL)
+// resolved is a repository whose release and asset were selected.
+type resolved struct {
+	// entry is the repository as configured; repo may differ from it, e.g.
+	// when installing into the tool cache.
+	entry config.Repo
+	repo  config.Repo
+	src   provider.Provider
+	rel   *release.Release
+	asset *release.Asset
+	// filter selected asset; it selects one again when asset is gone.
+	filter release.AssetFilter
+}
+
+// resolveRepo selects the release and the asset of repo.
+func (i *Installer) resolveRepo(ctx context.Context, cfg *config.Config, repo config.Repo, filter release.AssetFilter) (*resolved, error) {
+	r := &resolved{entry: repo}
+	i.tracker(repo).status("resolving")
+	if err := i.intercept(ctx, Step{Stage: BeforeResolve, Repo: repo}); err != nil {
+		return nil, err
+	}
+	if i.toolCache != "" {
+		var err error
+		if repo, err = i.inToolCache(ctx, cfg, repo); err != nil {
+			return nil, err
+		}
+	}
+
+	var src provider.Provider
+	if repo.Provider != "" {
+		var err error
+		if src, err = provider.ForRepo(cfg, repo); err != nil {
+			return nil, err
+		}
+	}
+
+	rel, err := i.resolve(ctx, repo, src)
 	if err != nil {
-		return fmt.Errorf("failed to parse repository URL: %w", err)
+		return nil, fmt.Errorf("failed to find latest release: %w", err)
+	}
+	if rel, err = i.hold(ctx, repo, src, rel); err != nil {
+		return nil, err
+	}
+
+	log.Info("Found release %s of %s", rel.TagName, repo.DisplayName())
+	if err := i.intercept(ctx, Step{Stage: AfterResolve, Repo: repo, Release: rel}); err != nil {
+		return nil, err
 	}
 
-	log.Info("Finding latest stable release for %s/%s", owner, repoName)
-	rel, err := i.finder.LatestStable(ctx, owner, repoName)
+	asset, err := selectAsset(cfg, repo, rel, filter)
 	if err != nil {
-		return fmt.Errorf("failed to find latest release: %w", err)
+		return nil, fmt.Errorf("no suitable asset found in release %s: %w", rel.TagName, err)
 	}
+	r.repo, r.src, r.rel, r.asset, r.filter = repo, src, rel, asset, filter
+	return r, nil
+}
 
-	log.Info("Found release: %s", rel.TagName)
+// installResolved downloads and extracts the asset selected for r. When the
+// asset is gone, typically because the maintainers replaced it shortly after
+// tagging, the release is resolved again, bypassing cached API responses, and
+// the asset selected anew is installed instead.
+func (i *Installer) installResolved(ctx context.Context, cfg *config.Config, r *resolved) error {
+	err := i.installAsset(ctx, cfg, r)
+	if r.src != nil || neterr.Classify(err) != neterr.NotFound {
+		return err
+	}
+
+	log.Warn("%s: asset %s of %s was not found, resolving the release again", r.entry.DisplayName(), r.asset.Name, r.rel.TagName)
+	again, rerr := i.resolveRepo(httpcache.Revalidate(ctx), cfg, r.entry, r.filter)
+	if rerr != nil {
+		return fmt.Errorf("%w (resolving the release again failed: %v)", err, rerr)
+	}
+	if again.rel.TagName != r.rel.TagName || again.asset.URL != r.asset.URL {
+		log.Info("%s: selected asset %s of %s instead", r.entry.DisplayName(), again.asset.Name, again.rel.TagName)
+	}
+	return i.installAsset(ctx, cfg, again)
+}
 
-	asset, err := filter(rel.Assets)
+// installAsset downloads and extracts the asset selected for r.
+func (i *Installer) installAsset(ctx context.Context, cfg *config.Config, r *resolved) error {
+	repo, src, rel, asset := r.repo, r.src, r.rel, r.asset
+	track := i.tracker(r.entry)
+
+	log.Info("Installing %s to %s", repo.DisplayName(), repo.OutputDir)
+
+	lock, err := acquireLock(ctx, filepath.Join(repo.OutputDir, LockFileName), cfg.GetLockTimeout())
 	if err != nil {
-		return fmt.Errorf("no suitable asset found in release %s: %w", rel.TagName, err)
+		return fmt.Errorf("failed to lock output directory: %w", err)
+	}
+	defer lock.Release()
+
+	if i.unchanged(repo, rel) {
+		return i.keepInstalled(cfg, repo, rel, asset)
 	}
 
 	log.Info("Selected asset: %s (%.2f MB)", asset.Name, float64(asset.Size)/(1024*1024))
-	downloadURL := ""
-	if PingGoogle(context.Background()) {
+	track.status("downloading " + rel.TagName)
+	if err := i.intercept(ctx, Step{Stage: BeforeDownload, Repo: repo, Release: rel, Asset: asset}); err != nil {
+		return err
+	}
+
+	var (
+		cacheKey = asset.URL
+		want     = expectation{sha256: repo.SHA256}
+		download func() (io.ReadCloser, error)
+		source   string
+		etag     string
+		replaced bool
+		stats    DownloadStats
+	)
+	if src != nil {
+		cacheKey = providerCacheKey(repo, rel, asset)
+		download = func() (io.ReadCloser, error) {
+			log.Info("Downloading %s via provider %s", asset.Name, repo.Provider)
+			return src.Download(ctx, *asset)
+		}
+	} else {
+		var downloadURL string
+		downloadURL, want = i.downloadSource(cfg, repo, asset)
+
+		reason := i.upstreamReplaced(ctx, repo, rel, asset, downloadURL)
+		replaced = reason != ""
+		if replaced && !i.force {
+			if !i.forceRefresh {
+				log.Warn("%s: asset %s of %s was replaced upstream without a new tag (%s); keeping the installed copy, use -force-refresh to install the new one",
+					repo.DisplayName(), asset.Name, rel.TagName, reason)
+				return nil
+			}
+			log.Warn("%s: asset %s of %s was replaced upstream without a new tag (%s); reinstalling it", repo.DisplayName(), asset.Name, rel.TagName, reason)
+			if err := forgetCached(cfg, cacheKey); err != nil {
+				return err
+			}
+		}
+		source = downloadURL
+		sources := i.downloadSources(cfg, repo, asset, downloadURL)
+		if downloadURL == asset.URL && len(sources) > 1 {
+			// The mirror may be retried in the middle of the download.
+			want = want.untrusted(asset)
+		}
+
+		var patch *deltaSource
+		if !i.force {
+			patch = i.deltaPatch(cfg, repo, rel, asset)
+		}
+		if patch != nil {
+			if rewritten, ok := i.rewrite(patch.url); ok {
+				patch.url = rewritten
+			} else if downloadURL != asset.URL {
+				patch.url = cfg.GetDownloadURL(repo.URL, patch.url)
+			}
+		}
+
+		download = func() (io.ReadCloser, error) {
+			if patch != nil {
+				rc, err := i.downloadDelta(ctx, patch, asset, want)
+				if err == nil {
+					return rc, nil
+				}
+				log.Warn("Delta download of %s failed, downloading it in full: %v", asset.Name, err)
+			}
+			return i.downloadRetrying(ctx, cfg, asset, sources, &stats)
+		}
+	}
+
+	if !i.force && !replaced && upToDate(repo, rel, asset) {
+		return i.keepInstalled(cfg, repo, rel, asset)
+	}
+
+	if i.force {
+		if err := forgetCached(cfg, cacheKey); err != nil {
+			return err
+		}
+	}
+
+	// The digest is computed while the download streams into the cache or the
+	// extractor, so the archive never has to be read again to hash it.
+	var streamed *verifyReader
+	reader, digest, err := i.fetch(ctx, cfg, cacheKey, asset, func() (io.ReadCloser, error) {
+		rc, err := download()
+		if err != nil {
+			return nil, err
+		}
+		if e, ok := rc.(etagged); ok {
+			etag = e.ETag()
+		}
+		streamed = want.wrap(track.counting(rc, asset.Size))
+		return streamed, nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed to download asset: %w", err)
+	}
+	defer reader.Close()
+
+	// A cache hit was verified when it was stored, but possibly against other expectations.
+	if digest != "" && repo.SHA256 != "" && digest != repo.SHA256 {
+		return fmt.Errorf("checksum mismatch for %s: expected sha256 %s, got %s", asset.Name, repo.SHA256, digest)
+	}
+
+	// The manifest of extracted files is built from the archive afterwards:
+	// cached archives are read again from the cache, others are spooled.
+	archive, cached := reader.(*os.File)
+	var input io.Reader = reader
+	if !cached {
+		spool, err := os.CreateTemp(tmpdir.Dir(), "ghinstall-archive-*")
+		if err != nil {
+			return fmt.Errorf("failed to create temporary file: %w", err)
+		}
+		defer os.Remove(spool.Name())
+		defer spool.Close()
+		archive, input = spool, io.TeeReader(reader, spool)
+	}
+
+	if repo.Signature.Type != "" {
+		// Nothing is extracted before the signature of the whole asset was checked.
+		if !cached {
+			if _, err := io.Copy(io.Discard, input); err != nil {
+				return fmt.Errorf("failed to download asset: %w", err)
+			}
+			if _, err := archive.Seek(0, io.SeekStart); err != nil {
+				return fmt.Errorf("failed to read archive: %w", err)
+			}
+			input = archive
+		}
+		if err := i.verifySignature(ctx, cfg, repo, src, rel, asset, archive.Name()); err != nil {
+			return err
+		}
+	}
+
+	if i.force {
+		if err := removeInstalled(repo); err != nil {
+			return err
+		}
+	}
+
+	log.Info("Extracting to %s", repo.OutputDir)
+	track.status("extracting " + rel.TagName)
+	if err := i.extract(cfg, input, repo); err != nil {
+		return fmt.Errorf("failed to extract archive: %w", err)
+	}
+	if !cached {
+		// Extractors may stop before the end of the archive; spool the rest too.
+		if _, err := io.Copy(io.Discard, input); err != nil {
+			return fmt.Errorf("failed to read archive: %w", err)
+		}
+		digest = streamed.Digest()
+	}
+	if digest == "" {
+		digest = repo.SHA256
+	}
+	m, err := writeManifest(repo, rel.TagName, archive)
+	var summary ExtractSummary
+	if err != nil {
+		log.Warn("Failed to record the files of %s; verify will not cover them: %v", repo.DisplayName(), err)
+	} else {
+		summary = summarize(m)
+		log.Info("Extracted %s", summary)
+		if err := signManifest(ctx, cfg, repo); err != nil {
+			log.Warn("Failed to attest the files of %s: %v", repo.DisplayName(), err)
+		}
+	}
+
+	res := InstallResult{
+		Repo:      repo,
+		Tag:       rel.TagName,
+		Asset:     *asset,
+		OutputDir: repo.OutputDir,
+		SHA256:    digest,
+		Release:   *rel,
+		Extracted: summary,
+		Download:  stats,
+	}
+	if err := i.intercept(ctx, Step{Stage: AfterExtract, Repo: repo, Release: rel, Asset: asset, Result: &res}); err != nil {
+		return err
+	}
+	track.status("finishing " + rel.TagName)
+	if err := i.runPostProcessors(ctx, cfg, res); err != nil {
+		return err
+	}
+
+	if cfg.BinDir != "" {
+		if err := linkExecutables(cfg.BinDir, repo, shimSuffix(cfg, repo)); err != nil {
+			return err
+		}
+	}
+
+	var completions []string
+	if repo.InstallCompletions {
+		completions = installCompletions(repo, m)
+	}
+
+	rec := state.Record{Tag: rel.TagName, Asset: asset.Name, SHA256: digest, Source: source, ETag: etag, Completions: completions}
+	if err := recordInstall(ctx, repo, rec); err != nil {
+		return err
+	}
+
+	if i.toolCache != "" {
+		if err := actions.MarkComplete(repo.OutputDir); err != nil {
+			return err
+		}
+	}
+	if i.actions {
+		if err := reportToActions(res); err != nil {
+			return err
+		}
+	}
+
+	log.Info("Successfully installed %s %s to %s", repo.DisplayName(), rel.TagName, repo.OutputDir)
+	return nil
+}
+
+// providerCacheKey returns the cache key of an asset downloaded by a provider,
+// whose URL need not identify it.
+func providerCacheKey(repo config.Repo, rel *release.Release, asset *release.Asset) string {
+	return fmt.Sprintf("provider:%s:%s@%s/%s", repo.Provider, repo.URL, rel.TagName, asset.Name)
+}
+
+// downloadSource returns the URL to download asset from, directly when GitHub
+// is reachable and through the mirror otherwise, and what its content must
+// look like.
+func (i *Installer) downloadSource(cfg *config.Config, repo config.Repo, asset *release.Asset) (string, expectation) {
+	want := expectation{sha256: repo.SHA256}
+	i.configureRedirects(cfg)
+	i.configureTimeouts(cfg)
+
+	downloadURL, rewritten := i.rewrite(asset.URL)
+	switch {
+	case rewritten:
+		log.Info("Download URL rewritten to %s", downloadURL)
+	case directReachable(context.Background()):
 		log.Info("google is available")
 		downloadURL = asset.URL
-	} else {
+	default:
 		log.Info("google is unavailable")
 		downloadURL = cfg.GetDownloadURL(repo.URL, asset.URL)
 	}
 
 	if downloadURL != asset.URL {
-		log.Info("Using mirror: %s", downloadURL)
+		if !rewritten {
+			log.Info("Using mirror: %s", downloadURL)
+			i.configureMirror(cfg)
+		}
+		want = want.untrusted(asset)
+	}
+	return downloadURL, want
+}
+
+// rewrite applies the URL rewriter to url and reports whether it changed it.
+func (i *Installer) rewrite(url string) (string, bool) {
+	if i.rewriteURL == nil {
+		return url, false
+	}
+	rewritten := i.rewriteURL(url)
+	if rewritten == "" || rewritten == url {
+		return url, false
 	}
+	return rewritten, true
+}
 
+// downloadFrom downloads asset from downloadURL. Downloads from the mirror are
+// sampled against GitHub when mirror_options.sample_bytes is set, and assets
+// of private repositories fall back to the GitHub API.
+func (i *Installer) downloadFrom(ctx context.Context, cfg *config.Config, asset *release.Asset, downloadURL string) (io.ReadCloser, error) {
 	log.Info("Downloading %s", downloadURL)
-	reader, err := i.downloader.Download(ctx, downloadURL)
+	rc, err := i.downloader.Download(ctx, downloadURL)
+	if err == nil && downloadURL != asset.URL && cfg.MirrorOptions.SampleBytes > 0 {
+		return i.sampleMirror(ctx, asset, rc, cfg.MirrorOptions.SampleBytes)
+	}
+	if err != nil && downloadURL == asset.URL {
+		return i.downloadPrivate(ctx, asset, err)
+	}
+	return rc, err
+}
+
+// configureRedirects allows the downloader to follow redirects from https to
+// plain http when the config opts in, and applies the redirect limit.
+func (i *Installer) configureRedirects(cfg *config.Config) {
+	rc, ok := i.downloader.(downloader.RedirectConfigurer)
+	if ok {
+		rc.AllowInsecureRedirects(cfg.AllowInsecureRedirects)
+	} else if cfg.AllowInsecureRedirects {
+		log.Warn("allow_insecure_redirects is not supported by the configured downloader")
+	}
+	if rl, ok := i.downloader.(downloader.RedirectLimiter); ok {
+		rl.SetMaxRedirects(cfg.GetMaxRedirects())
+	} else if cfg.MaxRedirects > 0 {
+		log.Warn("max_redirects is not supported by the configured downloader")
+	}
+}
+
+// configureHTTPCache keeps the responses of the finder and the downloader in
+// the http directory of the configured cache directory, or only in memory
+// without one.
+func (i *Installer) configureHTTPCache(cfg *config.Config) {
+	dir := ""
+	if cfg.CacheDir != "" {
+		dir = filepath.Join(cache.ResolveDir(cfg.CacheDir), "http")
+	}
+	for _, client := range []any{i.finder, i.downloader} {
+		if c, ok := client.(httpcache.Configurer); ok {
+			c.SetHTTPCache(dir, cfg.SharedCache())
+		}
+	}
+}
+
+// configureAPIBudget starts counting the API requests of the run against the
+// configured budget.
+func (i *Installer) configureAPIBudget(cfg *config.Config) {
+	if bc, ok := i.finder.(release.BudgetConfigurer); ok {
+		bc.SetAPIBudget(cfg.APIBudget)
+	} else if cfg.APIBudget > 0 {
+		log.Warn("api_budget is not supported by the configured release finder")
+	}
+}
+
+// configureFDLimits sizes the process-wide pools of open output files and
+// network connections.
+func configureFDLimits(cfg *config.Config) {
+	fdlimit.SetLimits(cfg.MaxOpenFiles, cfg.MaxConnections)
+}
+
+// configureTimeouts applies the configured download timeouts.
+func (i *Installer) configureTimeouts(cfg *config.Config) {
+	t := downloader.Timeouts{
+		Connect: cfg.Timeouts.Connect,
+		Header:  cfg.Timeouts.ResponseHeader,
+		Idle:    cfg.Timeouts.Idle,
+	}
+	if tc, ok := i.downloader.(downloader.TimeoutConfigurer); ok {
+		tc.SetTimeouts(t)
+	} else if t != (downloader.Timeouts{}) {
+		log.Warn("timeouts are not supported by the configured downloader")
+	}
+
+	if kc, ok := i.downloader.(downloader.KeepAliveConfigurer); ok {
+		kc.SetKeepAliveIdleTimeout(cfg.KeepAliveIdleTimeout)
+	} else if cfg.KeepAliveIdleTimeout != 0 {
+		log.Warn("keepalive_idle_timeout is not supported by the configured downloader")
+	}
+}
+
+// preresolve has the downloader look up the hosts the assets of repos may be
+// downloaded from, directly or through the mirror, while the first downloads
+// start.
+func (i *Installer) preresolve(ctx context.Context, cfg *config.Config, repos []*resolved) {
+	pr, ok := i.downloader.(downloader.Preresolver)
+	if !ok {
+		return
+	}
+	var urls []string
+	for _, r := range repos {
+		if u, rewritten := i.rewrite(r.asset.URL); rewritten {
+			urls = append(urls, u)
+			continue
+		}
+		urls = append(urls, r.asset.URL, cfg.GetDownloadURL(r.repo.URL, r.asset.URL))
+	}
+	pr.Preresolve(ctx, urls)
+}
+
+// configureMirror applies the configured mirror transport options to the downloader.
+func (i *Installer) configureMirror(cfg *config.Config) {
+	opts := downloader.TransportOptions{
+		ForceHTTP1:         cfg.MirrorOptions.ForceHTTP1,
+		DisableCompression: cfg.MirrorOptions.DisableCompression,
+	}
+	if opts == (downloader.TransportOptions{}) {
+		return
+	}
+
+	hc, ok := i.downloader.(downloader.HostConfigurer)
+	if !ok {
+		log.Warn("mirror_options are not supported by the configured downloader")
+		return
+	}
+	u, err := url.Parse(cfg.MirrorBase())
+	if err != nil || u.Host == "" {
+		return
+	}
+	hc.SetHostOptions(u.Host, opts)
+}
+
+// extractorFor returns the extractor for cfg: the one given to New or, when
+// New chose the default and cfg selects "system", the system tools.
+func (i *Installer) extractorFor(cfg *config.Config) extractor.Extractor {
+	if cfg.Extractor != "system" {
+		return i.extractor
+	}
+	if !i.defaultExtractor {
+		log.Warn("extractor: system is ignored in favour of the extractor the installer was created with")
+		return i.extractor
+	}
+	return extractor.NewSystemWithFallback()
+}
+
+// configureExtractor applies the configured extraction options to ext.
+func configureExtractor(cfg *config.Config, ext extractor.Extractor) {
+	opts := extractor.Options{
+		Duplicates: extractor.DuplicatePolicy(cfg.ZipDuplicates),
+		FileMode:   cfg.DefaultFileMode,
+		DirMode:    cfg.DefaultDirMode,
+		Isolation:  extractor.Isolation(cfg.ExtractorIsolation),
+		Limits: extractor.Limits{
+			MaxSize:    cfg.ExtractLimits.MaxSize,
+			MaxEntries: cfg.ExtractLimits.MaxEntries,
+			Timeout:    cfg.ExtractLimits.Timeout,
+		},
+	}
+	if c, ok := ext.(extractor.Configurer); ok {
+		c.SetOptions(opts)
+	} else if opts != (extractor.Options{}) {
+		log.Warn("Extraction options are not supported by the configured extractor")
+	}
+}
+
+// removeInstalled deletes the files recorded for the previous install of repo.
+func removeInstalled(repo config.Repo) error {
+	m, err := manifest.Load(repo.OutputDir, repo.URL)
+	if errors.Is(err, manifest.ErrNoManifest) {
+		return nil
+	}
 	if err != nil {
-		return fmt.Errorf("failed to download asset: %w", err)
+		return err
 	}
-	defer reader.Close()
+	log.Info("Removing %d files of the previous install of %s", len(m.Files), repo.DisplayName())
+	return m.Remove(repo.OutputDir)
+}
 
-	log.Info("Extracting to %s", repo.OutputDir)
-	if err := i.extractor.Extract(reader, repo.OutputDir); err != nil {
-		return fmt.Errorf("failed to extract archive: %w", err)
+// writeManifest records the files extracted from archive for verification
+// and returns their manifest.
+func writeManifest(repo config.Repo, tag string, archive *os.File) (*manifest.Manifest, error) {
+	fi, err := archive.Stat()
+	if err != nil {
+		return nil, err
+	}
+	m, err := manifest.FromArchive(repo.URL, tag, archive, fi.Size())
+	if err != nil {
+		return nil, err
+	}
+	m.Stamp(repo.OutputDir)
+	return m, m.Save(repo.OutputDir)
+}
+
+// recordInstall updates the state file of the repository's output directory
+// and registers the directory in the per-user index of installs.
+func recordInstall(ctx context.Context, repo config.Repo, rec state.Record) error {
+	st, err := state.Load(repo.OutputDir)
+	if err != nil {
+		return err
+	}
+	// Cache hits are not served with an ETag; keep the one of the same content.
+	if prev, ok := st.Get(repo.URL); ok && rec.ETag == "" && rec.Source == prev.Source && rec.SHA256 == prev.SHA256 {
+		rec.ETag = prev.ETag
+	}
+	rec.Repo = repo.URL
+	rec.Name = repo.Name
+	rec.Version = repo.Version
+	rec.InstalledAt = time.Now().UTC()
+	st.Put(rec)
+	if err := st.Save(); err != nil {
+		return err
+	}
+
+	if err := state.Register(ctx, repo.OutputDir); err != nil {
+		log.Warn("Failed to register %s in %s: %v", repo.OutputDir, state.IndexPath(), err)
+	}
+	return nil
+}
+
+// extract extracts src into the output directory of repo, reporting the
+// entries to the registered event handler when the extractor supports it.
+// With sandbox_extraction, the extractor can only write below the output
+// directory and the temp directory, and cannot start programs or connect.
+func (i *Installer) extract(cfg *config.Config, src io.Reader, repo config.Repo) error {
+	ext := i.extractorFor(cfg)
+	configureExtractor(cfg, ext)
+	run := func() error {
+		ee, ok := ext.(extractor.EventExtractor)
+		if i.events == nil || !ok {
+			return ext.Extract(src, repo.OutputDir)
+		}
+		return ee.ExtractWithEvents(src, repo.OutputDir, func(ev extractor.Event) {
+			i.events(repo, ev)
+		})
+	}
+	if !cfg.SandboxExtraction {
+		return run()
+	}
+
+	if err := os.MkdirAll(repo.OutputDir, 0755); err != nil {
+		return fmt.Errorf("failed to create destination directory %s: %w", repo.OutputDir, err)
+	}
+	return sandbox.Run([]string{repo.OutputDir, tmpdir.Dir()}, run)
+}
+
+// shimSuffix returns the suffix for the shims of repo. When the same repository
+// is installed several times side by side, the pinned entries are linked as
+// "<name>-<version>" so they do not replace each other's shims.
+func shimSuffix(cfg *config.Config, repo config.Repo) string {
+	if repo.Version == "" {
+		return ""
+	}
+	for _, other := range cfg.Github {
+		if other.URL == repo.URL && other.OutputDir != repo.OutputDir {
+			return "-" + repo.Version
+		}
+	}
+	return ""
+}
+
+// linkExecutables creates shims in binDir for the executables the install of
+// repo extracted, only its own binaries with binaries_only. Shims of other
+// ghinstall output directories are replaced; the user's own links and files
+// are kept with a warning, without failing the install.
+func linkExecutables(binDir string, repo config.Repo, suffix string) error {
+	targets, err := repoExecutables(repo)
+	if errors.Is(err, manifest.ErrNoManifest) {
+		log.Warn("The files of %s in %s are unknown, not linking them into %s", repo.DisplayName(), repo.OutputDir, binDir)
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if repo.BinariesOnly {
+		names := binaryNames(repo)
+		if targets = repoBinaries(targets, names); len(targets) == 0 {
+			log.Warn("No executable named %s found in %s to link into %s", strings.Join(names, " or "), repo.OutputDir, binDir)
+			return nil
+		}
+	}
+	if len(targets) == 0 {
+		log.Warn("No executables found in %s to link into %s", repo.OutputDir, binDir)
+		return nil
 	}
 
-	log.Info("Successfully installed %s %s to %s", repo.URL, rel.TagName, repo.OutputDir)
+	managed, err := state.Dirs()
+	if err != nil {
+		return fmt.Errorf("failed to read the output directories of ghinstall: %w", err)
+	}
+	created, conflicts, err := shim.LinkWithSuffix(binDir, targets, append(managed, repo.OutputDir), suffix)
+	for _, path := range created {
+		log.Info("Linked %s", path)
+	}
+	for _, conflict := range conflicts {
+		log.Warn("Not linking %s: %v", repo.DisplayName(), conflict)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to link executables: %w", err)
+	}
 	return nil
 }
 
+// repoExecutables returns the executables among the files of the install of
+// repo, as listed in its manifest. Output directories may be shared with
+// other releases and the user's own files, which are not considered.
+func repoExecutables(repo config.Repo) ([]string, error) {
+	m, err := manifest.Load(repo.OutputDir, repo.URL)
+	if err != nil {
+		return nil, err
+	}
+	var found []string
+	for _, f := range m.Files {
+		if !f.Mode.IsRegular() {
+			continue
+		}
+		path := filepath.Join(repo.OutputDir, filepath.FromSlash(f.Path))
+		info, err := os.Stat(path)
+		if err != nil {
+			continue
+		}
+		if shim.IsExecutable(path, info) {
+			found = append(found, path)
+		}
+	}
+	return found, nil
+}
+
+// installCompletions copies the completion scripts and man pages among the
+// files of m, the manifest of the install of repo, into the per-user shell and
+// man directories, and returns the copied paths. Only files of the release are
+// considered, as output directories may be shared with other releases and the
+// user's own files. Files there that the previous install did not copy are
+// kept, and failures only warn: the release is installed by now.
+func installCompletions(repo config.Repo, m *manifest.Manifest) []string {
+	if !completion.Supported() {
+		log.Warn("Installing completions is not supported on this platform")
+		return nil
+	}
+	if m == nil {
+		log.Warn("The files of %s are unknown; its completions are not installed", repo.DisplayName())
+		return nil
+	}
+
+	var owned []string
+	if st, err := state.Load(repo.OutputDir); err == nil {
+		prev, _ := st.Get(repo.URL)
+		owned = prev.Completions
+	}
+
+	var files []string
+	for _, f := range m.Files {
+		if f.Mode.IsRegular() {
+			files = append(files, f.Path)
+		}
+	}
+	installed, skipped, err := completion.Install(completion.Find(repo.OutputDir, files, completion.DefaultDirs()), owned)
+	for _, path := range installed {
+		log.Info("Installed %s", path)
+	}
+	for _, path := range skipped {
+		log.Warn("Not replacing %s, which ghinstall did not install", path)
+	}
+	if err != nil {
+		log.Warn("Failed to install the completions of %s: %v", repo.DisplayName(), err)
+	}
+	return installed
+}
+
+// acquireLock takes the lock file at path, logging who holds it while waiting.
+func acquireLock(ctx context.Context, path string, timeout time.Duration) (*filelock.Lock, error) {
+	lock, err := filelock.Acquire(ctx, path, 0)
+	if err == nil {
+		return lock, nil
+	}
+	if !errors.Is(err, filelock.ErrTimeout) {
+		return nil, err
+	}
+
+	log.Warn("Waiting up to %v for lock %s held by %s", timeout, path, filelock.Owner(path))
+	return filelock.Acquire(ctx, path, timeout)
+}
+
+// resolve finds the release to install, from the repository's provider when it has one.
+func (i *Installer) resolve(ctx context.Context, repo config.Repo, src provider.Provider) (*release.Release, error) {
+	if src != nil {
+		log.Info("Resolving %s with provider %s", repo.URL, repo.Provider)
+		return src.Resolve(ctx, repo)
+	}
+
+	owner, repoName, err := config.ParseRepoURL(repo.URL)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse repository URL: %w", err)
+	}
+
+	if repo.Version != "" {
+		log.Info("Finding release %s for %s/%s", repo.Version, owner, repoName)
+		rel, err := i.finder.ByTag(ctx, owner, repoName, repo.Version)
+		if alt := alternateTag(repo.Version); errors.Is(err, release.ErrNotFound) && alt != "" {
+			// Versions copied from other tools often lack or add the "v" of the tag.
+			if altRel, altErr := i.finder.ByTag(ctx, owner, repoName, alt); altErr == nil {
+				log.Info("Found release %s of %s/%s for version %s", alt, owner, repoName, repo.Version)
+				return altRel, nil
+			}
+		}
+		return rel, err
+	}
+
+	policy := release.Policy{Channel: release.Channel(repo.Channel), ExcludeTags: repo.ExcludeTags, Constraint: repo.Constraint}
+	log.Info("Finding latest %s release for %s/%s", policy, owner, repoName)
+	return i.finder.Latest(ctx, owner, repoName, policy)
+}
+
+// alternateTag returns version with its "v" prefix removed, or added when it
+// starts with a digit; "" for other versions.
+func alternateTag(version string) string {
+	switch {
+	case len(version) > 1 && version[0] == 'v' && version[1] >= '0' && version[1] <= '9':
+		return version[1:]
+	case version != "" && version[0] >= '0' && version[0] <= '9':
+		return "v" + version
+	}
+	return ""
+}
+
+// fetch returns the asset content, going through the download cache when one is configured.
+// Cache entries are keyed by the original asset URL rather than the download URL so that
+// mirrored and direct downloads of the same asset share an entry.
+// The returned digest is empty when the content is streamed without caching.
+func (i *Installer) fetch(ctx context.Context, cfg *config.Config, key string, asset *release.Asset, download func() (io.ReadCloser, error)) (io.ReadCloser, string, error) {
+	if cfg.CacheDir == "" {
+		rc, err := download()
+		return rc, "", err
+	}
+
+	c, err := cache.Open(cache.ResolveDir(cfg.CacheDir), cfg.SharedCache())
+	if err != nil {
+		return nil, "", err
+	}
+	c.SetLockTimeout(cfg.GetLockTimeout())
+
+	if digest, ok := c.Lookup(key); ok {
+		log.Info("Using cached %s (sha256 %s)", asset.Name, digest)
+	}
+
+	f, digest, err := c.Fetch(ctx, key, download)
+	if err != nil {
+		return nil, "", err
+	}
+	return f, digest, nil
+}
+
 func (i *Installer) InstallRepo(ctx context.Context, cfg *config.Config, repoURL, outputDir string, filter release.AssetFilter) error {
 	repo := config.Repo{
 		URL:       repoURL,
 		OutputDir: outputDir,
 	}
+	i.configureHTTPCache(cfg)
+	configureFDLimits(cfg)
 	return i.installRepo(ctx, cfg, repo, filter)
 }
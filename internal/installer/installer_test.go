@@ -3,26 +3,64 @@ package installer
 import (
 	"context"
 	"errors"
+	"fmt"
 	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"runtime"
+	"slices"
 	"strings"
 	"testing"
 	"time"
 
+	"github.com/sixban6/ghinstall/internal/cache"
 	"github.com/sixban6/ghinstall/internal/config"
 	"github.com/sixban6/ghinstall/internal/downloader"
 	"github.com/sixban6/ghinstall/internal/extractor"
+	"github.com/sixban6/ghinstall/internal/filelock"
+	"github.com/sixban6/ghinstall/internal/neterr"
+	"github.com/sixban6/ghinstall/internal/provider"
 	"github.com/sixban6/ghinstall/internal/release"
+	"github.com/sixban6/ghinstall/internal/sandbox"
+	"github.com/sixban6/ghinstall/internal/state"
 )
 
+func TestMain(m *testing.M) {
+	// Keep installs made by tests out of the real per-user index.
+	home, err := os.MkdirTemp("", "ghinstall-home-*")
+	if err != nil {
+		panic(err)
+	}
+	os.Setenv("HOME", home)
+	os.Setenv("USERPROFILE", home)
+	os.Setenv("XDG_CACHE_HOME", filepath.Join(home, ".cache"))
+	retryDelay = func(int, error) time.Duration { return 0 }
+
+	code := m.Run()
+	os.RemoveAll(home)
+	os.Exit(code)
+}
+
 type mockFinder struct {
 	release *release.Release
 	err     error
 }
 
-func (m *mockFinder) LatestStable(ctx context.Context, owner, repo string) (*release.Release, error) {
+func (m *mockFinder) Latest(ctx context.Context, owner, repo string, policy release.Policy) (*release.Release, error) {
+	if m.err != nil {
+		return nil, m.err
+	}
+	return m.release, nil
+}
+
+func (m *mockFinder) ByTag(ctx context.Context, owner, repo, tag string) (*release.Release, error) {
 	if m.err != nil {
 		return nil, m.err
 	}
+	if m.release.TagName != tag {
+		return nil, fmt.Errorf("%w: %s", release.ErrNotFound, tag)
+	}
 	return m.release, nil
 }
 
@@ -99,6 +137,7 @@ func TestInstaller_Install(t *testing.T) {
 			},
 		},
 	}
+	dir := t.TempDir()
 
 	tests := []struct {
 		name           string
@@ -115,7 +154,7 @@ func TestInstaller_Install(t *testing.T) {
 				Github: []config.Repo{
 					{
 						URL:       "https://github.com/owner/repo",
-						OutputDir: "/tmp/test",
+						OutputDir: dir,
 					},
 				},
 			},
@@ -123,7 +162,7 @@ func TestInstaller_Install(t *testing.T) {
 			downloader:     &mockDownloader{content: "test content"},
 			extractor:      &mockExtractor{},
 			wantErr:        false,
-			expectedExtDir: "/tmp/test",
+			expectedExtDir: dir,
 		},
 		{
 			name: "finder error",
@@ -191,7 +230,7 @@ func TestInstaller_InstallRepo(t *testing.T) {
 			{
 				Name: "app.tar.gz",
 				URL:  "https://github.com/owner/repo/releases/download/v1.0.0/app.tar.gz",
-				Size: 1024,
+				Size: 12, // len("test content"): mirrored content is checked against it
 			},
 		},
 	}
@@ -200,6 +239,7 @@ func TestInstaller_InstallRepo(t *testing.T) {
 		MirrorURL: "https://ghfast.top",
 	}
 
+	dir := t.TempDir()
 	mockExt := &mockExtractor{}
 	installer := New(
 		&mockFinder{release: mockRel},
@@ -211,7 +251,7 @@ func TestInstaller_InstallRepo(t *testing.T) {
 		context.Background(),
 		cfg,
 		"https://github.com/owner/repo",
-		"/tmp/single",
+		dir,
 		release.DefaultFilter(),
 	)
 
@@ -219,8 +259,8 @@ func TestInstaller_InstallRepo(t *testing.T) {
 		t.Errorf("Installer.InstallRepo() error = %v", err)
 	}
 
-	if mockExt.extractedTo != "/tmp/single" {
-		t.Errorf("Expected extraction to /tmp/single, got %s", mockExt.extractedTo)
+	if mockExt.extractedTo != dir {
+		t.Errorf("Expected extraction to %s, got %s", dir, mockExt.extractedTo)
 	}
 }
 
@@ -231,7 +271,7 @@ func TestInstaller_Install_WithMirror(t *testing.T) {
 			{
 				Name: "app.tar.gz",
 				URL:  "https://github.com/owner/repo/releases/download/v1.0.0/app.tar.gz",
-				Size: 1024,
+				Size: 12, // len("test content"): mirrored content is checked against it
 			},
 		},
 	}
@@ -271,15 +311,16 @@ func TestInstaller_Install_MultipleRepos(t *testing.T) {
 		},
 	}
 
+	dir1, dir2 := t.TempDir(), t.TempDir()
 	cfg := &config.Config{
 		Github: []config.Repo{
 			{
 				URL:       "https://github.com/owner1/repo1",
-				OutputDir: "/tmp/repo1",
+				OutputDir: dir1,
 			},
 			{
 				URL:       "https://github.com/owner2/repo2",
-				OutputDir: "/tmp/repo2",
+				OutputDir: dir2,
 			},
 		},
 	}
@@ -302,7 +343,7 @@ func TestInstaller_Install_MultipleRepos(t *testing.T) {
 		t.Errorf("Installer.Install() with multiple repos error = %v", err)
 	}
 
-	expectedCalls := []string{"/tmp/repo1", "/tmp/repo2"}
+	expectedCalls := []string{dir1, dir2}
 	if len(extractorCalls) != len(expectedCalls) {
 		t.Errorf("Expected %d extractor calls, got %d", len(expectedCalls), len(extractorCalls))
 		return
@@ -359,7 +400,11 @@ type slowMockFinder struct {
 	delay time.Duration
 }
 
-func (s *slowMockFinder) LatestStable(ctx context.Context, owner, repo string) (*release.Release, error) {
+func (s *slowMockFinder) ByTag(ctx context.Context, owner, repo, tag string) (*release.Release, error) {
+	return s.Latest(ctx, owner, repo, release.Policy{})
+}
+
+func (s *slowMockFinder) Latest(ctx context.Context, owner, repo string, policy release.Policy) (*release.Release, error) {
 	select {
 	case <-time.After(s.delay):
 		return &release.Release{
@@ -371,4 +416,957 @@ func (s *slowMockFinder) LatestStable(ctx context.Context, owner, repo string) (
 	case <-ctx.Done():
 		return nil, ctx.Err()
 	}
-}
\ No newline at end of file
+}
+type countingDownloader struct {
+	content string
+	calls   int
+}
+
+func (m *countingDownloader) Download(ctx context.Context, url string) (io.ReadCloser, error) {
+	m.calls++
+	return io.NopCloser(strings.NewReader(m.content)), nil
+}
+
+func TestInstaller_Install_WithCache(t *testing.T) {
+	mockRel := &release.Release{
+		TagName: "v1.0.0",
+		Assets: []release.Asset{
+			{
+				Name: "app.tar.gz",
+				URL:  "https://github.com/owner/repo/releases/download/v1.0.0/app.tar.gz",
+				Size: 1024,
+			},
+		},
+	}
+
+	cfg := &config.Config{
+		Github: []config.Repo{
+			{
+				URL:       "https://github.com/owner/repo",
+				OutputDir: "/tmp/test",
+			},
+		},
+		CacheDir: t.TempDir(),
+	}
+
+	down := &countingDownloader{content: "test content"}
+	installer := New(&mockFinder{release: mockRel}, down, &mockExtractor{})
+
+	for i := 0; i < 2; i++ {
+		// A second install into the same directory would be skipped as up to date.
+		cfg.Github[0].OutputDir = t.TempDir()
+		if err := installer.Install(context.Background(), cfg, release.DefaultFilter()); err != nil {
+			t.Fatalf("Installer.Install() run %d error = %v", i, err)
+		}
+	}
+
+	if down.calls != 1 {
+		t.Errorf("Expected 1 download with cache enabled, got %d", down.calls)
+	}
+}
+
+func TestInstaller_Install_OutputDirLocked(t *testing.T) {
+	mockRel := &release.Release{
+		TagName: "v1.0.0",
+		Assets: []release.Asset{
+			{Name: "app.tar.gz", URL: "https://github.com/owner/repo/releases/download/v1.0.0/app.tar.gz"},
+		},
+	}
+
+	outputDir := t.TempDir()
+	cfg := &config.Config{
+		Github: []config.Repo{
+			{URL: "https://github.com/owner/repo", OutputDir: outputDir},
+		},
+		LockTimeout: 300 * time.Millisecond,
+	}
+
+	held, err := filelock.Acquire(context.Background(), filepath.Join(outputDir, LockFileName), 0)
+	if err != nil {
+		t.Fatalf("failed to take lock: %v", err)
+	}
+
+	mockExt := &mockExtractor{}
+	installer := New(&mockFinder{release: mockRel}, &mockDownloader{content: "test content"}, mockExt)
+
+	err = installer.Install(context.Background(), cfg, release.DefaultFilter())
+	if !errors.Is(err, filelock.ErrTimeout) {
+		t.Errorf("Installer.Install() error = %v, want lock timeout", err)
+	}
+	if mockExt.extractedTo != "" {
+		t.Error("Extractor ran while output directory was locked")
+	}
+
+	held.Release()
+	if err := installer.Install(context.Background(), cfg, release.DefaultFilter()); err != nil {
+		t.Errorf("Installer.Install() after release error = %v", err)
+	}
+}
+
+type fakeProvider struct {
+	downloaded string
+}
+
+func (p *fakeProvider) Resolve(ctx context.Context, repo config.Repo) (*release.Release, error) {
+	return &release.Release{
+		TagName: "2024.1",
+		Assets:  []release.Asset{{Name: "tool.tar.gz", URL: "corp://tool/2024.1"}},
+	}, nil
+}
+
+func (p *fakeProvider) Download(ctx context.Context, asset release.Asset) (io.ReadCloser, error) {
+	p.downloaded = asset.URL
+	return io.NopCloser(strings.NewReader("content")), nil
+}
+
+func TestInstaller_Install_WithProvider(t *testing.T) {
+	src := &fakeProvider{}
+	provider.Register("installer-test", src)
+
+	cfg := &config.Config{
+		Github: []config.Repo{
+			{URL: "corp://tool", OutputDir: t.TempDir(), Provider: "installer-test"},
+		},
+	}
+
+	mockDown := &countingDownloader{}
+	mockExt := &mockExtractor{}
+	installer := New(&mockFinder{err: errors.New("finder must not be used")}, mockDown, mockExt)
+
+	if err := installer.Install(context.Background(), cfg, release.DefaultFilter()); err != nil {
+		t.Fatalf("Installer.Install() error = %v", err)
+	}
+	if src.downloaded != "corp://tool/2024.1" {
+		t.Errorf("provider downloaded %q, want corp://tool/2024.1", src.downloaded)
+	}
+	if mockDown.calls != 0 {
+		t.Errorf("HTTP downloader used %d times for provider repo", mockDown.calls)
+	}
+	if mockExt.extractedTo != cfg.Github[0].OutputDir {
+		t.Errorf("Expected extraction to %s, got %s", cfg.Github[0].OutputDir, mockExt.extractedTo)
+	}
+}
+
+func TestInstaller_Install_PostProcessors(t *testing.T) {
+	mockRel := &release.Release{
+		TagName: "v1.0.0",
+		Assets: []release.Asset{
+			{Name: "app.tar.gz", URL: "https://github.com/owner/repo/releases/download/v1.0.0/app.tar.gz"},
+		},
+	}
+
+	outputDir := t.TempDir()
+	cfg := &config.Config{
+		Github: []config.Repo{
+			{URL: "https://github.com/owner/repo", OutputDir: outputDir},
+		},
+	}
+
+	var order []string
+	record := func(name string) PostProcessor {
+		return PostProcessorFunc(func(ctx context.Context, dir string, res InstallResult) error {
+			if dir != outputDir {
+				t.Errorf("post-processor %s got dir %s, want %s", name, dir, outputDir)
+			}
+			if res.Tag != "v1.0.0" || res.Asset.Name != "app.tar.gz" {
+				t.Errorf("post-processor %s got result %+v", name, res)
+			}
+			order = append(order, name)
+			return nil
+		})
+	}
+
+	installer := New(&mockFinder{release: mockRel}, &mockDownloader{content: "test content"}, &mockExtractor{},
+		WithPostProcessors(record("first"), record("second")))
+
+	if err := installer.Install(context.Background(), cfg, release.DefaultFilter()); err != nil {
+		t.Fatalf("Installer.Install() error = %v", err)
+	}
+	if strings.Join(order, ",") != "first,second" {
+		t.Errorf("post-processor order = %v, want [first second]", order)
+	}
+
+	failing := PostProcessorFunc(func(ctx context.Context, dir string, res InstallResult) error {
+		return errors.New("codesign failed")
+	})
+	installer = New(&mockFinder{release: mockRel}, &mockDownloader{content: "test content"}, &mockExtractor{},
+		WithPostProcessors(failing))
+	cfg.Github[0].OutputDir = t.TempDir()
+	if err := installer.Install(context.Background(), cfg, release.DefaultFilter()); err == nil {
+		t.Error("Installer.Install() should fail when a post-processor fails")
+	}
+}
+
+func TestExpandHookArgs(t *testing.T) {
+	res := InstallResult{
+		Repo:      config.Repo{URL: "https://github.com/owner/tool"},
+		Tag:       "v1.2.0",
+		Asset:     release.Asset{Name: "tool.tar.gz", Size: 42},
+		OutputDir: "/opt/tool",
+		Release: release.Release{
+			TagName:     "v1.2.0",
+			HTMLURL:     "https://github.com/owner/tool/releases/tag/v1.2.0",
+			PublishedAt: time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC),
+		},
+	}
+
+	got := expandHookArgs([]string{"ln", "-sfn", "{output_dir}", "/opt/tool-{version}", "{unknown}"}, res)
+	want := []string{"ln", "-sfn", "/opt/tool", "/opt/tool-1.2.0", "{unknown}"}
+	if !slices.Equal(got, want) {
+		t.Errorf("expandHookArgs() = %v, want %v", got, want)
+	}
+
+	env := hookEnv(res)
+	for _, v := range []string{
+		"GHINSTALL_TAG=v1.2.0",
+		"GHINSTALL_VERSION=1.2.0",
+		"GHINSTALL_RELEASE_URL=https://github.com/owner/tool/releases/tag/v1.2.0",
+		"GHINSTALL_PUBLISHED_AT=2024-05-01T12:00:00Z",
+		"GHINSTALL_ASSET_SIZE=42",
+		"GHINSTALL_OUTPUT_DIR=/opt/tool",
+	} {
+		if !slices.Contains(env, v) {
+			t.Errorf("hookEnv() = %v, want %s", env, v)
+		}
+	}
+}
+
+func TestInstaller_Install_ResultDigest(t *testing.T) {
+	mockRel := &release.Release{
+		TagName: "v1.0.0",
+		Assets: []release.Asset{
+			{Name: "app.tar.gz", URL: "https://github.com/owner/repo/releases/download/v1.0.0/app.tar.gz"},
+		},
+	}
+	// sha256("test content")
+	const want = "6ae8a75555209fd6c44157c0aed8016e763ff435a19cf186f76863140143ff72"
+
+	tests := []struct {
+		name     string
+		cacheDir string
+	}{
+		{name: "streamed", cacheDir: ""},
+		{name: "cached", cacheDir: t.TempDir()},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &config.Config{
+				Github:   []config.Repo{{URL: "https://github.com/owner/repo", OutputDir: t.TempDir()}},
+				CacheDir: tt.cacheDir,
+			}
+			var got string
+			installer := New(&mockFinder{release: mockRel}, &mockDownloader{content: "test content"}, &mockExtractor{},
+				WithPostProcessors(PostProcessorFunc(func(ctx context.Context, dir string, res InstallResult) error {
+					got = res.SHA256
+					return nil
+				})))
+			if err := installer.Install(context.Background(), cfg, release.DefaultFilter()); err != nil {
+				t.Fatalf("Installer.Install() error = %v", err)
+			}
+			if got != want {
+				t.Errorf("InstallResult.SHA256 = %q, want %q", got, want)
+			}
+		})
+	}
+}
+
+func TestInstaller_Install_BinDir(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("symlink shims are not used on Windows")
+	}
+
+	mockRel := &release.Release{
+		TagName: "v1.0.0",
+		Assets: []release.Asset{
+			{Name: "app.tar.gz", URL: "https://github.com/owner/repo/releases/download/v1.0.0/app.tar.gz"},
+		},
+	}
+
+	outputDir := t.TempDir()
+	binDir := filepath.Join(t.TempDir(), "bin")
+	cfg := &config.Config{
+		Github: []config.Repo{
+			{URL: "https://github.com/owner/repo", OutputDir: outputDir},
+		},
+		BinDir: binDir,
+	}
+
+	archive := modeTarGz(t, map[string]os.FileMode{"app/app": 0755, "app/LICENSE": 0644})
+	installer := New(&mockFinder{release: mockRel}, &mockDownloader{content: archive}, extractor.NewLegacy())
+
+	if err := installer.Install(context.Background(), cfg, release.DefaultFilter()); err != nil {
+		t.Fatalf("Installer.Install() error = %v", err)
+	}
+
+	target, err := os.Readlink(filepath.Join(binDir, "app"))
+	if err != nil {
+		t.Fatalf("expected shim for app: %v", err)
+	}
+	if target != filepath.Join(outputDir, "app", "app") {
+		t.Errorf("shim target = %s, want %s", target, filepath.Join(outputDir, "app", "app"))
+	}
+	if _, err := os.Lstat(filepath.Join(binDir, "LICENSE")); err == nil {
+		t.Error("non-executable LICENSE should not be linked")
+	}
+}
+
+func TestInstaller_Install_BinDirConflict(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("symlink shims are not used on Windows")
+	}
+
+	mockRel := &release.Release{TagName: "v1.0.0", Assets: []release.Asset{
+		{Name: "app.tar.gz", URL: "https://github.com/owner/repo/releases/download/v1.0.0/app.tar.gz"},
+	}}
+	binDir := t.TempDir()
+	cfg := &config.Config{
+		Github: []config.Repo{{URL: "https://github.com/owner/repo", OutputDir: t.TempDir()}},
+		BinDir: binDir,
+	}
+	// The user's own app is in the way of the shim.
+	if err := os.WriteFile(filepath.Join(binDir, "app"), []byte("mine"), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	archive := modeTarGz(t, map[string]os.FileMode{"app": 0755})
+	if err := New(&mockFinder{release: mockRel}, &mockDownloader{content: archive}, extractor.NewLegacy()).Install(context.Background(), cfg, release.DefaultFilter()); err != nil {
+		t.Fatalf("Installer.Install() error = %v", err)
+	}
+
+	if got, _ := os.ReadFile(filepath.Join(binDir, "app")); string(got) != "mine" {
+		t.Errorf("bin_dir app = %q, want the user's file kept", got)
+	}
+	st, err := state.Load(cfg.Github[0].OutputDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rec, ok := st.Get(cfg.Github[0].URL); !ok || rec.Tag != "v1.0.0" {
+		t.Errorf("recorded install = %+v, %v, want v1.0.0", rec, ok)
+	}
+}
+
+func TestInstaller_Install_SideBySide(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("symlink shims are not used on Windows")
+	}
+
+	asset := func(tag string) []release.Asset {
+		return []release.Asset{{Name: "app.tar.gz", URL: "https://github.com/owner/repo/releases/download/" + tag + "/app.tar.gz"}}
+	}
+	finder := &listFinder{releases: []release.Release{
+		{TagName: "v2.0.0", Assets: asset("v2.0.0")},
+		{TagName: "v1.0.0", Assets: asset("v1.0.0")},
+	}}
+
+	binDir := filepath.Join(t.TempDir(), "bin")
+	cfg := &config.Config{
+		Github: []config.Repo{
+			{URL: "https://github.com/owner/repo", OutputDir: t.TempDir(), Version: "v1.0.0"},
+			{URL: "https://github.com/owner/repo", OutputDir: t.TempDir(), Version: "v2.0.0"},
+			{URL: "https://github.com/owner/repo", OutputDir: t.TempDir()},
+		},
+		BinDir: binDir,
+	}
+
+	archive := modeTarGz(t, map[string]os.FileMode{"app": 0755})
+	if err := New(finder, &mockDownloader{content: archive}, extractor.NewLegacy()).Install(context.Background(), cfg, release.DefaultFilter()); err != nil {
+		t.Fatalf("Installer.Install() error = %v", err)
+	}
+
+	for name, repo := range map[string]config.Repo{"app-v1.0.0": cfg.Github[0], "app-v2.0.0": cfg.Github[1], "app": cfg.Github[2]} {
+		target, err := os.Readlink(filepath.Join(binDir, name))
+		if err != nil {
+			t.Errorf("expected shim %s: %v", name, err)
+			continue
+		}
+		if target != filepath.Join(repo.OutputDir, "app") {
+			t.Errorf("shim %s target = %s, want %s", name, target, filepath.Join(repo.OutputDir, "app"))
+		}
+
+		st, err := state.Load(repo.OutputDir)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if rec, ok := st.Get(repo.URL); !ok || rec.Version != repo.Version {
+			t.Errorf("state of %s = %+v, want version %q", repo.OutputDir, rec, repo.Version)
+		}
+	}
+}
+
+func TestInstaller_Install_Completions(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("completions are not installed on Windows")
+	}
+
+	data := t.TempDir()
+	t.Setenv("XDG_DATA_HOME", data)
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+
+	mockRel := &release.Release{
+		TagName: "v1.0.0",
+		Assets: []release.Asset{
+			{Name: "app.tar.gz", URL: "https://github.com/owner/repo/releases/download/v1.0.0/app.tar.gz"},
+		},
+	}
+	cfg := &config.Config{
+		Github: []config.Repo{
+			{URL: "https://github.com/owner/repo", OutputDir: t.TempDir(), InstallCompletions: true},
+		},
+	}
+	// A file of another release sharing the output directory.
+	if err := os.WriteFile(filepath.Join(cfg.Github[0].OutputDir, "other.bash"), nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	// The user's own man page of app.
+	manPage := filepath.Join(data, "man", "man1", "app.1")
+	if err := os.MkdirAll(filepath.Dir(manPage), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(manPage, []byte("mine"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	archive := tarGz(t, map[string]string{"app": "binary", "doc/app.1": "man page", "complete/app.bash": "complete"})
+	installer := New(&mockFinder{release: mockRel}, &mockDownloader{content: archive}, extractor.NewLegacy())
+
+	if err := installer.Install(context.Background(), cfg, release.DefaultFilter()); err != nil {
+		t.Fatalf("Installer.Install() error = %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(data, "bash-completion", "completions", "other")); err == nil {
+		t.Error("a completion of another release was installed")
+	}
+
+	bash := filepath.Join(data, "bash-completion", "completions", "app")
+	if _, err := os.Stat(bash); err != nil {
+		t.Errorf("expected %s to be installed: %v", bash, err)
+	}
+	if got, _ := os.ReadFile(manPage); string(got) != "mine" {
+		t.Errorf("man page = %q, want the user's kept", got)
+	}
+
+	st, err := state.Load(cfg.Github[0].OutputDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rec, _ := st.Get(cfg.Github[0].URL); len(rec.Completions) != 1 || rec.Completions[0] != bash {
+		t.Errorf("recorded completions = %v, want [%s]", rec.Completions, bash)
+	}
+}
+
+type recordingDownloader struct {
+	urls []string
+}
+
+func (m *recordingDownloader) Download(ctx context.Context, url string) (io.ReadCloser, error) {
+	m.urls = append(m.urls, url)
+	return io.NopCloser(strings.NewReader("content")), nil
+}
+
+func TestInstaller_Install_AssetPattern(t *testing.T) {
+	mockRel := &release.Release{
+		TagName: "v1.0.0",
+		Assets: []release.Asset{
+			{Name: "app_darwin_arm64.tar.gz", URL: "https://example.com/darwin"},
+			{Name: "app_linux_amd64.tar.gz", URL: "https://example.com/linux"},
+		},
+	}
+	cfg := &config.Config{
+		Github: []config.Repo{
+			{URL: "https://github.com/owner/repo", OutputDir: t.TempDir(), AssetPattern: `_linux_amd64\.tar\.gz$`},
+		},
+		MirrorURL: "https://example.com",
+	}
+
+	down := &recordingDownloader{}
+	installer := New(&mockFinder{release: mockRel}, down, &mockExtractor{})
+	if err := installer.Install(context.Background(), cfg, release.DefaultFilter()); err != nil {
+		t.Fatalf("Installer.Install() error = %v", err)
+	}
+	if len(down.urls) != 1 || !strings.HasSuffix(down.urls[0], "https://example.com/linux") {
+		t.Errorf("downloaded %v, want the linux asset", down.urls)
+	}
+}
+
+// preresolvingDownloader records the URLs it is asked to preresolve and the
+// keep-alive timeout it is given.
+type preresolvingDownloader struct {
+	recordingDownloader
+	preresolved []string
+	keepAlive   time.Duration
+}
+
+func (m *preresolvingDownloader) Preresolve(ctx context.Context, urls []string) {
+	m.preresolved = append(m.preresolved, urls...)
+}
+
+func (m *preresolvingDownloader) SetKeepAliveIdleTimeout(d time.Duration) {
+	m.keepAlive = d
+}
+
+func TestInstaller_Install_Preresolve(t *testing.T) {
+	const assetURL = "https://github.com/owner/repo/releases/download/v1.0.0/app.tar.gz"
+	mockRel := &release.Release{
+		TagName: "v1.0.0",
+		Assets:  []release.Asset{{Name: "app.tar.gz", URL: assetURL}},
+	}
+	cfg := &config.Config{
+		Github:               []config.Repo{{URL: "https://github.com/owner/repo", OutputDir: t.TempDir()}},
+		MirrorURL:            "https://mirror.example",
+		KeepAliveIdleTimeout: 30 * time.Second,
+	}
+
+	down := &preresolvingDownloader{}
+	installer := New(&mockFinder{release: mockRel}, down, &mockExtractor{})
+	if err := installer.Install(context.Background(), cfg, release.DefaultFilter()); err != nil {
+		t.Fatalf("Installer.Install() error = %v", err)
+	}
+	want := []string{assetURL, cfg.GetDownloadURL("https://github.com/owner/repo", assetURL)}
+	if !slices.Equal(down.preresolved, want) {
+		t.Errorf("preresolved %v, want %v", down.preresolved, want)
+	}
+	if down.keepAlive != 30*time.Second {
+		t.Errorf("keep-alive idle timeout = %v, want 30s", down.keepAlive)
+	}
+}
+
+// sequenceFinder returns its releases in turn, the last one repeatedly.
+type sequenceFinder struct {
+	releases []*release.Release
+	calls    int
+}
+
+func (m *sequenceFinder) Latest(ctx context.Context, owner, repo string, policy release.Policy) (*release.Release, error) {
+	rel := m.releases[min(m.calls, len(m.releases)-1)]
+	m.calls++
+	return rel, nil
+}
+
+func (m *sequenceFinder) ByTag(ctx context.Context, owner, repo, tag string) (*release.Release, error) {
+	return m.Latest(ctx, owner, repo, release.Policy{})
+}
+
+// notFoundDownloader answers 404 for the URLs in gone.
+type notFoundDownloader struct {
+	recordingDownloader
+	gone map[string]bool
+}
+
+func (m *notFoundDownloader) Download(ctx context.Context, url string) (io.ReadCloser, error) {
+	if m.gone[url] {
+		m.urls = append(m.urls, url)
+		return nil, &neterr.StatusError{URL: url, Code: http.StatusNotFound}
+	}
+	return m.recordingDownloader.Download(ctx, url)
+}
+
+func TestInstaller_Install_AssetGone(t *testing.T) {
+	directReachable = func(context.Context) bool { return true }
+	defer func() { directReachable = PingGoogle }()
+
+	const (
+		oldURL = "https://github.com/owner/repo/releases/download/v1.0.0/app-old.tar.gz"
+		newURL = "https://github.com/owner/repo/releases/download/v1.0.0/app.tar.gz"
+	)
+	stale := &release.Release{TagName: "v1.0.0", Assets: []release.Asset{{Name: "app-old.tar.gz", URL: oldURL}}}
+	fresh := &release.Release{TagName: "v1.0.0", Assets: []release.Asset{{Name: "app.tar.gz", URL: newURL}}}
+
+	tests := []struct {
+		name     string
+		releases []*release.Release
+		wantURLs []string
+		wantErr  bool
+	}{
+		{
+			name:     "asset replaced after resolution",
+			releases: []*release.Release{stale, fresh},
+			wantURLs: []string{oldURL, newURL},
+		},
+		{
+			name:     "asset still gone",
+			releases: []*release.Release{stale},
+			wantURLs: []string{oldURL, oldURL},
+			wantErr:  true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &config.Config{
+				Github: []config.Repo{{URL: "https://github.com/owner/repo", OutputDir: t.TempDir()}},
+			}
+			finder := &sequenceFinder{releases: tt.releases}
+			down := &notFoundDownloader{gone: map[string]bool{oldURL: true}}
+			err := New(finder, down, &mockExtractor{}).Install(context.Background(), cfg, release.DefaultFilter())
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Installer.Install() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !slices.Equal(down.urls, tt.wantURLs) {
+				t.Errorf("downloaded %v, want %v", down.urls, tt.wantURLs)
+			}
+			if finder.calls != 2 {
+				t.Errorf("resolved %d times, want 2", finder.calls)
+			}
+		})
+	}
+}
+
+type readingExtractor struct {
+	content []byte
+}
+
+func (m *readingExtractor) Extract(src io.Reader, dst string) error {
+	data, err := io.ReadAll(src)
+	m.content = data
+	return err
+}
+
+func TestInstaller_Install_VersionAndChecksum(t *testing.T) {
+	mockRel := &release.Release{
+		TagName: "v1.2.0",
+		Assets: []release.Asset{
+			{Name: "app.tar.gz", URL: "https://github.com/owner/repo/releases/download/v1.2.0/app.tar.gz"},
+		},
+	}
+	// sha256("test content")
+	const digest = "6ae8a75555209fd6c44157c0aed8016e763ff435a19cf186f76863140143ff72"
+
+	tests := []struct {
+		name     string
+		repo     config.Repo
+		cacheDir string
+		wantErr  bool
+	}{
+		{name: "pinned version", repo: config.Repo{Version: "v1.2.0"}},
+		{name: "version without v", repo: config.Repo{Version: "1.2.0"}},
+		{name: "unknown version", repo: config.Repo{Version: "v9.9.9"}, wantErr: true},
+		{name: "checksum match", repo: config.Repo{SHA256: digest}},
+		{name: "checksum mismatch", repo: config.Repo{SHA256: strings.Repeat("0", 64)}, wantErr: true},
+		{name: "cached checksum match", repo: config.Repo{SHA256: digest}, cacheDir: t.TempDir()},
+		{name: "cached checksum mismatch", repo: config.Repo{SHA256: strings.Repeat("0", 64)}, cacheDir: t.TempDir(), wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := tt.repo
+			repo.URL = "https://github.com/owner/repo"
+			repo.OutputDir = t.TempDir()
+			cfg := &config.Config{Github: []config.Repo{repo}, CacheDir: tt.cacheDir}
+
+			installer := New(&mockFinder{release: mockRel}, &mockDownloader{content: "test content"}, &readingExtractor{})
+			err := installer.Install(context.Background(), cfg, release.DefaultFilter())
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Installer.Install() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestInstaller_Install_MirrorVerification(t *testing.T) {
+	directReachable = func(context.Context) bool { return false }
+	defer func() { directReachable = PingGoogle }()
+
+	// sha256("test content")
+	const digest = "6ae8a75555209fd6c44157c0aed8016e763ff435a19cf186f76863140143ff72"
+
+	tests := []struct {
+		name     string
+		asset    release.Asset
+		mirror   string
+		cacheDir string
+		wantErr  bool
+	}{
+		{name: "matching size and digest", asset: release.Asset{Size: 12, Digest: "sha256:" + digest}, mirror: "https://mirror.example"},
+		{name: "size only", asset: release.Asset{Size: 12}, mirror: "https://mirror.example"},
+		{name: "size mismatch", asset: release.Asset{Size: 99}, mirror: "https://mirror.example", wantErr: true},
+		{name: "digest mismatch", asset: release.Asset{Size: 12, Digest: "sha256:" + strings.Repeat("0", 64)}, mirror: "https://mirror.example", wantErr: true},
+		{name: "digest mismatch is not cached", asset: release.Asset{Size: 12, Digest: "sha256:" + strings.Repeat("0", 64)}, mirror: "https://mirror.example", cacheDir: t.TempDir(), wantErr: true},
+		{name: "no mirror configured", asset: release.Asset{Size: 99}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			asset := tt.asset
+			asset.Name = "app.tar.gz"
+			asset.URL = "https://github.com/owner/repo/releases/download/v1.0.0/app.tar.gz"
+			cfg := &config.Config{
+				Github:    []config.Repo{{URL: "https://github.com/owner/repo", OutputDir: t.TempDir()}},
+				MirrorURL: tt.mirror,
+				CacheDir:  tt.cacheDir,
+			}
+
+			installer := New(&mockFinder{release: &release.Release{TagName: "v1.0.0", Assets: []release.Asset{asset}}},
+				&mockDownloader{content: "test content"}, &readingExtractor{})
+			err := installer.Install(context.Background(), cfg, release.DefaultFilter())
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Installer.Install() error = %v, wantErr %v", err, tt.wantErr)
+			}
+
+			if tt.cacheDir != "" {
+				c, err := cache.Open(tt.cacheDir, false)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if _, ok := c.Lookup(asset.URL); ok {
+					t.Error("content failing verification was cached")
+				}
+			}
+		})
+	}
+}
+
+// urlDownloader serves different content per URL, like a mirror that does not
+// serve what GitHub does.
+type urlDownloader map[string]string
+
+func (m urlDownloader) Download(ctx context.Context, url string) (io.ReadCloser, error) {
+	content, ok := m[url]
+	if !ok {
+		return nil, fmt.Errorf("unexpected download of %s", url)
+	}
+	return io.NopCloser(strings.NewReader(content)), nil
+}
+
+func TestInstaller_Install_MirrorSampling(t *testing.T) {
+	directReachable = func(context.Context) bool { return false }
+	defer func() { directReachable = PingGoogle }()
+
+	const assetURL = "https://github.com/owner/repo/releases/download/v1.0.0/app.tar.gz"
+	tests := []struct {
+		name    string
+		mirror  string
+		wantErr bool
+	}{
+		{name: "matching prefix", mirror: "test content"},
+		{name: "injected content", mirror: "evil content", wantErr: true},
+		{name: "truncated content", mirror: "test", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &config.Config{
+				Github:        []config.Repo{{URL: "https://github.com/owner/repo", OutputDir: t.TempDir()}},
+				MirrorURL:     "https://mirror.example",
+				MirrorOptions: config.MirrorOptions{SampleBytes: 8},
+			}
+			down := urlDownloader{
+				assetURL:                             "test content",
+				"https://mirror.example/" + assetURL: tt.mirror,
+			}
+			ext := &readingExtractor{}
+			rel := &release.Release{TagName: "v1.0.0", Assets: []release.Asset{{Name: "app.tar.gz", URL: assetURL}}}
+
+			err := New(&mockFinder{release: rel}, down, ext).Install(context.Background(), cfg, release.DefaultFilter())
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Installer.Install() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && string(ext.content) != "test content" {
+				t.Errorf("extracted content = %q, want the whole mirrored asset", ext.content)
+			}
+		})
+	}
+}
+
+type hostConfigDownloader struct {
+	mockDownloader
+	hosts map[string]downloader.TransportOptions
+}
+
+func (m *hostConfigDownloader) SetHostOptions(host string, opts downloader.TransportOptions) {
+	m.hosts[host] = opts
+}
+
+func TestInstaller_Install_MirrorOptions(t *testing.T) {
+	directReachable = func(context.Context) bool { return false }
+	defer func() { directReachable = PingGoogle }()
+
+	mockRel := &release.Release{
+		TagName: "v1.0.0",
+		Assets:  []release.Asset{{Name: "app.tar.gz", URL: "https://github.com/owner/repo/releases/download/v1.0.0/app.tar.gz"}},
+	}
+	cfg := &config.Config{
+		Github:        []config.Repo{{URL: "https://github.com/owner/repo", OutputDir: t.TempDir()}},
+		MirrorURL:     "https://mirror.example:8443",
+		MirrorOptions: config.MirrorOptions{ForceHTTP1: true, DisableCompression: true},
+	}
+
+	down := &hostConfigDownloader{mockDownloader: mockDownloader{content: "test content"}, hosts: map[string]downloader.TransportOptions{}}
+	if err := New(&mockFinder{release: mockRel}, down, &mockExtractor{}).Install(context.Background(), cfg, release.DefaultFilter()); err != nil {
+		t.Fatalf("Installer.Install() error = %v", err)
+	}
+
+	want := downloader.TransportOptions{ForceHTTP1: true, DisableCompression: true}
+	if got := down.hosts["mirror.example:8443"]; got != want {
+		t.Errorf("mirror transport options = %+v, want %+v", got, want)
+	}
+}
+
+func TestInstaller_Install_SandboxExtraction(t *testing.T) {
+	directReachable = func(context.Context) bool { return true }
+	defer func() { directReachable = PingGoogle }()
+
+	rel := &release.Release{TagName: "v1.0.0", Assets: []release.Asset{
+		{Name: "app.tar.gz", URL: "https://github.com/owner/repo/releases/download/v1.0.0/app.tar.gz"},
+	}}
+	cfg := &config.Config{
+		Github:            []config.Repo{{URL: "https://github.com/owner/repo", OutputDir: t.TempDir()}},
+		SandboxExtraction: true,
+	}
+
+	installer := New(&mockFinder{release: rel}, &mockDownloader{content: tarGz(t, map[string]string{"app": "binary"})}, extractor.NewLegacy())
+	err := installer.Install(context.Background(), cfg, release.DefaultFilter())
+	if errors.Is(err, sandbox.ErrUnsupported) {
+		t.Skip(err)
+	}
+	if err != nil {
+		t.Fatalf("Install() error = %v", err)
+	}
+	if data, err := os.ReadFile(filepath.Join(cfg.Github[0].OutputDir, "app")); err != nil || string(data) != "binary" {
+		t.Errorf("extracted app = %q, %v", data, err)
+	}
+}
+
+func TestInstaller_Install_ExtractEvents(t *testing.T) {
+	rel := &release.Release{TagName: "v1.0.0", Assets: []release.Asset{
+		{Name: "app.tar.gz", URL: "https://github.com/owner/repo/releases/download/v1.0.0/app.tar.gz"},
+	}}
+	cfg := &config.Config{Github: []config.Repo{{URL: "https://github.com/owner/repo", OutputDir: t.TempDir()}}}
+
+	done := map[string]bool{}
+	installer := New(&mockFinder{release: rel}, &mockDownloader{content: tarGz(t, map[string]string{"app": "binary", "LICENSE": "MIT"})}, extractor.NewLegacy(),
+		WithExtractEvents(func(repo config.Repo, ev extractor.Event) {
+			if repo.URL != cfg.Github[0].URL {
+				t.Errorf("event for repo %s", repo.URL)
+			}
+			if ev.Done {
+				done[ev.Name] = true
+			}
+		}))
+	if err := installer.Install(context.Background(), cfg, release.DefaultFilter()); err != nil {
+		t.Fatalf("Install() error = %v", err)
+	}
+
+	if !done["app"] || !done["LICENSE"] || len(done) != 2 {
+		t.Errorf("completed entries = %v, want app and LICENSE", done)
+	}
+}
+
+func TestInstaller_Install_Force(t *testing.T) {
+	rel := &release.Release{TagName: "v1.0.0", Assets: []release.Asset{
+		{Name: "app.tar.gz", URL: "https://github.com/owner/repo/releases/download/v1.0.0/app.tar.gz"},
+	}}
+	dir := t.TempDir()
+	cfg := &config.Config{
+		Github:   []config.Repo{{URL: "https://github.com/owner/repo", OutputDir: dir}},
+		CacheDir: t.TempDir(),
+	}
+	down := &countingDownloader{content: tarGz(t, map[string]string{"app": "binary"})}
+
+	if err := New(&mockFinder{release: rel}, down, extractor.NewLegacy()).Install(context.Background(), cfg, release.DefaultFilter()); err != nil {
+		t.Fatalf("Install() error = %v", err)
+	}
+
+	// Break the install in a way extraction cannot overwrite.
+	os.Remove(filepath.Join(dir, "app"))
+	os.MkdirAll(filepath.Join(dir, "app", "junk"), 0755)
+
+	// The manifest shows the broken file, which is installed again without
+	// removing what is in the way.
+	if err := New(&mockFinder{release: rel}, down, extractor.NewLegacy()).Install(context.Background(), cfg, release.DefaultFilter()); err == nil {
+		t.Fatal("Install() without force extracted over the broken install")
+	}
+	if fi, err := os.Stat(filepath.Join(dir, "app")); err != nil || !fi.IsDir() {
+		t.Fatalf("Install() without force removed the broken install: %v", err)
+	}
+	if err := New(&mockFinder{release: rel}, down, extractor.NewLegacy(), WithForce(true)).Install(context.Background(), cfg, release.DefaultFilter()); err != nil {
+		t.Fatalf("Install() with force error = %v", err)
+	}
+
+	if got, err := os.ReadFile(filepath.Join(dir, "app")); err != nil || string(got) != "binary" {
+		t.Errorf("app = %q, %v", got, err)
+	}
+	if down.calls != 2 {
+		t.Errorf("downloads = %d, want 2 (force bypasses the cache)", down.calls)
+	}
+}
+
+func TestInstaller_Install_RecordsName(t *testing.T) {
+	rel := &release.Release{TagName: "v1.0.0", Assets: []release.Asset{
+		{Name: "app.tar.gz", URL: "https://github.com/owner/repo/releases/download/v1.0.0/app.tar.gz"},
+	}}
+	repo := config.Repo{URL: "https://github.com/owner/repo", OutputDir: t.TempDir(), Name: "app"}
+
+	var got string
+	installer := New(&mockFinder{release: rel}, &mockDownloader{content: "test content"}, &mockExtractor{},
+		WithPostProcessors(PostProcessorFunc(func(ctx context.Context, dir string, res InstallResult) error {
+			got = res.Repo.DisplayName()
+			return nil
+		})))
+	if err := installer.Install(context.Background(), &config.Config{Github: []config.Repo{repo}}, release.DefaultFilter()); err != nil {
+		t.Fatalf("Install() error = %v", err)
+	}
+	if got != "app" {
+		t.Errorf("InstallResult repo name = %q, want app", got)
+	}
+
+	st, err := state.Load(repo.OutputDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rec, ok := st.Get(repo.URL); !ok || rec.Name != "app" {
+		t.Errorf("state record = %+v, want name app", rec)
+	}
+}
+
+func TestInstaller_Install_URLRewriter(t *testing.T) {
+	directReachable = func(context.Context) bool { return true }
+	defer func() { directReachable = PingGoogle }()
+
+	const assetURL = "https://github.com/owner/repo/releases/download/v1.0.0/app.tar.gz"
+	const proxied = "https://artifacts.corp.example/github/owner/repo/v1.0.0/app.tar.gz"
+
+	tests := []struct {
+		name     string
+		rewriter func(string) string
+		size     int64
+		want     string
+		wantErr  bool
+	}{
+		{
+			name:     "rewritten",
+			rewriter: func(string) string { return proxied },
+			size:     7,
+			want:     proxied,
+		},
+		{
+			name:     "unchanged keeps direct download",
+			rewriter: func(u string) string { return u },
+			size:     7,
+			want:     assetURL,
+		},
+		{
+			name:     "rewritten downloads are verified",
+			rewriter: func(string) string { return proxied },
+			size:     8,
+			wantErr:  true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rel := &release.Release{TagName: "v1.0.0", Assets: []release.Asset{{Name: "app.tar.gz", URL: assetURL, Size: tt.size}}}
+			cfg := &config.Config{Github: []config.Repo{{URL: "https://github.com/owner/repo", OutputDir: t.TempDir()}}}
+			d := &recordingDownloader{}
+			inst := New(&mockFinder{release: rel}, d, &readingExtractor{}, WithURLRewriter(tt.rewriter))
+
+			err := inst.Install(context.Background(), cfg, release.DefaultFilter())
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Install() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && (len(d.urls) != 1 || d.urls[0] != tt.want) {
+				t.Errorf("downloaded %v, want %s", d.urls, tt.want)
+			}
+		})
+	}
+}
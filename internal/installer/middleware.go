@@ -0,0 +1,86 @@
+package installer
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/sixban6/ghinstall/internal/config"
+	"github.com/sixban6/ghinstall/internal/release"
+)
+
+// Stage is a point in the install of a repository at which middleware runs.
+type Stage int
+
+const (
+	// BeforeResolve runs before the release of the repository is looked up.
+	BeforeResolve Stage = iota
+	// AfterResolve runs once the release is known; Step.Release is set.
+	AfterResolve
+	// BeforeDownload runs once the asset is selected, before it is downloaded
+	// or taken from the cache; Step.Asset is set.
+	BeforeDownload
+	// AfterExtract runs once the asset is extracted, before post-processors;
+	// Step.Result is set.
+	AfterExtract
+)
+
+func (s Stage) String() string {
+	switch s {
+	case BeforeResolve:
+		return "before-resolve"
+	case AfterResolve:
+		return "after-resolve"
+	case BeforeDownload:
+		return "before-download"
+	case AfterExtract:
+		return "after-extract"
+	default:
+		return fmt.Sprintf("stage %d", int(s))
+	}
+}
+
+// Step describes the install of a repository at a Stage. Fields not known yet
+// at that stage are nil.
+type Step struct {
+	Stage   Stage
+	Repo    config.Repo
+	Release *release.Release
+	Asset   *release.Asset
+	Result  *InstallResult
+}
+
+// Middleware intercepts the install of every repository at each Stage, e.g.
+// to enforce deny-lists, wait for approval or write an audit log. Middleware
+// runs in registration order; an error stops the install of that repository.
+type Middleware interface {
+	Intercept(ctx context.Context, step Step) error
+}
+
+// MiddlewareFunc adapts a function to the Middleware interface.
+type MiddlewareFunc func(ctx context.Context, step Step) error
+
+func (f MiddlewareFunc) Intercept(ctx context.Context, step Step) error {
+	return f(ctx, step)
+}
+
+// WithMiddleware appends middleware run at every stage of each install.
+func WithMiddleware(middleware ...Middleware) Option {
+	return func(i *Installer) {
+		i.middleware = append(i.middleware, middleware...)
+	}
+}
+
+func (i *Installer) intercept(ctx context.Context, step Step) error {
+	if len(i.middleware) == 0 {
+		return nil
+	}
+	if unlock := i.serialize(); unlock != nil {
+		defer unlock()
+	}
+	for _, m := range i.middleware {
+		if err := m.Intercept(ctx, step); err != nil {
+			return fmt.Errorf("install stopped %s: %w", step.Stage, err)
+		}
+	}
+	return nil
+}
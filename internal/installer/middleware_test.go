@@ -0,0 +1,70 @@
+package installer
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/sixban6/ghinstall/internal/config"
+	"github.com/sixban6/ghinstall/internal/release"
+)
+
+func TestInstaller_Install_Middleware(t *testing.T) {
+	directReachable = func(context.Context) bool { return true }
+	defer func() { directReachable = PingGoogle }()
+
+	rel := &release.Release{TagName: "v1.0.0", Assets: []release.Asset{
+		{Name: "app.tar.gz", URL: "https://github.com/owner/repo/releases/download/v1.0.0/app.tar.gz"},
+	}}
+	errDenied := errors.New("denied")
+
+	tests := []struct {
+		name       string
+		denyAt     Stage
+		wantStages []Stage
+		wantErr    bool
+		wantDone   bool
+	}{
+		{name: "all stages", denyAt: -1, wantStages: []Stage{BeforeResolve, AfterResolve, BeforeDownload, AfterExtract}, wantDone: true},
+		{name: "deny after resolve", denyAt: AfterResolve, wantStages: []Stage{BeforeResolve, AfterResolve}, wantErr: true},
+		{name: "deny before download", denyAt: BeforeDownload, wantStages: []Stage{BeforeResolve, AfterResolve, BeforeDownload}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &config.Config{Github: []config.Repo{{URL: "https://github.com/owner/repo", OutputDir: t.TempDir()}}}
+
+			var stages []Stage
+			var result *InstallResult
+			mw := MiddlewareFunc(func(ctx context.Context, step Step) error {
+				stages = append(stages, step.Stage)
+				if step.Stage >= AfterResolve && step.Release.TagName != "v1.0.0" {
+					t.Errorf("%s: release = %v", step.Stage, step.Release)
+				}
+				if step.Stage == AfterExtract {
+					result = step.Result
+				}
+				if step.Stage == tt.denyAt {
+					return errDenied
+				}
+				return nil
+			})
+			down := &countingDownloader{content: "test content"}
+
+			err := New(&mockFinder{release: rel}, down, &mockExtractor{}, WithMiddleware(mw)).Install(context.Background(), cfg, release.DefaultFilter())
+			if (err != nil) != tt.wantErr || tt.wantErr && !errors.Is(err, errDenied) {
+				t.Fatalf("Installer.Install() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(stages, tt.wantStages) {
+				t.Errorf("stages = %v, want %v", stages, tt.wantStages)
+			}
+			if tt.denyAt == BeforeDownload && down.calls != 0 {
+				t.Errorf("asset downloaded %d times after being denied", down.calls)
+			}
+			if tt.wantDone && (result == nil || result.Tag != "v1.0.0") {
+				t.Errorf("after-extract result = %v", result)
+			}
+		})
+	}
+}
@@ -0,0 +1,118 @@
+package installer
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	log "github.com/sixban6/ghinstall/internal/logger"
+	"time"
+
+	"github.com/sixban6/ghinstall/internal/config"
+	"github.com/sixban6/ghinstall/internal/mirror"
+	"github.com/sixban6/ghinstall/internal/release"
+)
+
+// MirrorResult reports the mirroring of the asset selected for one repository.
+type MirrorResult struct {
+	Repo  config.Repo
+	Tag   string
+	Asset string
+	// Uploaded is false when the mirror already had the asset.
+	Uploaded bool
+	// Err is set when the asset could not be mirrored.
+	Err error
+}
+
+// MirrorSync resolves every repository like an install would and uploads the
+// selected asset, its checksum file and an index entry into store, laid out
+// so that the store's URL works as mirror_url. Assets the store already holds
+// with the expected digest are not downloaded again.
+func (i *Installer) MirrorSync(ctx context.Context, cfg *config.Config, store mirror.Store) []MirrorResult {
+	i.configureRedirects(cfg)
+	i.configureTimeouts(cfg)
+	i.configureHTTPCache(cfg)
+	i.configureAPIBudget(cfg)
+	configureFDLimits(cfg)
+	idx, err := mirror.LoadIndex(ctx, store)
+	if err != nil {
+		log.Warn("Starting a new mirror index: %v", err)
+		idx = &mirror.Index{}
+	}
+
+	results := make([]MirrorResult, 0, len(cfg.Github))
+	for _, repo := range cfg.Github {
+		res := MirrorResult{Repo: repo}
+		res.Err = i.mirrorRepo(ctx, cfg, repo, store, idx, &res)
+		if res.Err != nil {
+			log.Error("Failed to mirror %s: %v", repo.DisplayName(), res.Err)
+		}
+		results = append(results, res)
+	}
+
+	if err := idx.Save(ctx, store); err != nil {
+		log.Warn("%v", err)
+	}
+	return results
+}
+
+func (i *Installer) mirrorRepo(ctx context.Context, cfg *config.Config, repo config.Repo, store mirror.Store, idx *mirror.Index, res *MirrorResult) error {
+	if repo.Provider != "" {
+		return errors.New("repositories resolved by a provider cannot be mirrored")
+	}
+
+	rel, err := i.resolve(ctx, repo, nil)
+	if err != nil {
+		return fmt.Errorf("failed to find latest release: %w", err)
+	}
+	asset, err := selectAsset(cfg, repo, rel, release.DefaultFilter())
+	if err != nil {
+		return fmt.Errorf("failed to find suitable asset: %w", err)
+	}
+	res.Tag, res.Asset = rel.TagName, asset.Name
+
+	want := expectation{size: asset.Size, sha256: repo.SHA256}
+	if want.sha256 == "" {
+		want.sha256 = githubDigest(asset.Digest)
+	}
+
+	// Release assets are immutable unless replaced upstream, which a known
+	// digest detects; without one, any mirrored copy is kept.
+	if sum, err := store.Get(ctx, mirror.ChecksumKey(asset.URL)); err == nil {
+		if have := mirror.ParseChecksum(sum); want.sha256 == "" || have == want.sha256 {
+			log.Info("%s %s is already mirrored", repo.DisplayName(), asset.Name)
+			return nil
+		}
+	}
+
+	log.Info("Mirroring %s %s", repo.DisplayName(), asset.Name)
+	rc, err := i.downloader.Download(ctx, asset.URL)
+	if err != nil {
+		return fmt.Errorf("failed to download asset: %w", err)
+	}
+	defer rc.Close()
+
+	vr := want.wrap(rc)
+	if err := store.Put(ctx, mirror.Key(asset.URL), vr); err != nil {
+		return err
+	}
+	digest := vr.Digest()
+	if digest == "" {
+		return errors.New("the upload finished before the asset was read completely")
+	}
+	if err := store.Put(ctx, mirror.ChecksumKey(asset.URL), bytes.NewReader(mirror.Checksum(digest, asset.Name))); err != nil {
+		return err
+	}
+
+	idx.Put(mirror.Entry{
+		Repo:     repo.URL,
+		Tag:      rel.TagName,
+		Asset:    asset.Name,
+		URL:      asset.URL,
+		Size:     vr.read,
+		SHA256:   digest,
+		SyncedAt: time.Now().UTC(),
+	})
+	res.Uploaded = true
+	return nil
+}
@@ -0,0 +1,64 @@
+package installer
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/sixban6/ghinstall/internal/config"
+	"github.com/sixban6/ghinstall/internal/mirror"
+	"github.com/sixban6/ghinstall/internal/release"
+)
+
+func TestInstaller_MirrorSync(t *testing.T) {
+	assetURL := "https://github.com/test/repo/releases/download/v1.0.0/app.tar.gz"
+	finder := &mockFinder{release: &release.Release{
+		TagName: "v1.0.0",
+		Assets: []release.Asset{
+			{Name: "app.tar.gz", URL: assetURL, Size: 12},
+		},
+	}}
+	dl := &countingDownloader{content: "test content"}
+	inst := New(finder, dl, &mockExtractor{})
+
+	cfg := &config.Config{Github: []config.Repo{
+		{URL: "https://github.com/test/repo", OutputDir: t.TempDir()},
+	}}
+	dir := t.TempDir()
+	store := mirror.DirStore(dir)
+
+	results := inst.MirrorSync(context.Background(), cfg, store)
+	if len(results) != 1 || results[0].Err != nil || !results[0].Uploaded {
+		t.Fatalf("MirrorSync() = %+v", results)
+	}
+
+	got, err := store.Get(context.Background(), mirror.Key(assetURL))
+	if err != nil || string(got) != "test content" {
+		t.Errorf("mirrored asset = %q, %v", got, err)
+	}
+	sum, err := store.Get(context.Background(), mirror.ChecksumKey(assetURL))
+	if err != nil || mirror.ParseChecksum(sum) != "6ae8a75555209fd6c44157c0aed8016e763ff435a19cf186f76863140143ff72" {
+		t.Errorf("checksum file = %q, %v", sum, err)
+	}
+	idx, err := mirror.LoadIndex(context.Background(), store)
+	if err != nil || len(idx.Assets) != 1 || idx.Assets[0].Tag != "v1.0.0" {
+		t.Errorf("index = %+v, %v", idx, err)
+	}
+
+	results = inst.MirrorSync(context.Background(), cfg, store)
+	if results[0].Err != nil || results[0].Uploaded || dl.calls != 1 {
+		t.Errorf("second MirrorSync() = %+v after %d downloads, want the asset kept", results, dl.calls)
+	}
+
+	// A truncated download must not replace the mirrored copy.
+	os.Remove(filepath.Join(dir, filepath.FromSlash(mirror.ChecksumKey(assetURL))))
+	dl.content = "test"
+	results = inst.MirrorSync(context.Background(), cfg, store)
+	if results[0].Err == nil {
+		t.Error("MirrorSync() should fail for a truncated download")
+	}
+	if got, _ := store.Get(context.Background(), mirror.Key(assetURL)); string(got) != "test content" {
+		t.Errorf("mirrored asset = %q after a failed sync", got)
+	}
+}
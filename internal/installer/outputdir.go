@@ -0,0 +1,101 @@
+package installer
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+
+	"github.com/sixban6/ghinstall/internal/config"
+)
+
+// ErrDangerousOutputDir is returned by Install for an output_dir whose
+// contents removing or replacing would break the system or lose user data,
+// unless the config sets allow_dangerous_dir.
+var ErrDangerousOutputDir = errors.New("dangerous output_dir")
+
+// systemDirs are directories of the operating system no repository should be
+// extracted into directly.
+var systemDirs = map[string][]string{
+	"linux":   {"/bin", "/boot", "/dev", "/etc", "/home", "/lib", "/lib32", "/lib64", "/opt", "/proc", "/root", "/run", "/sbin", "/srv", "/sys", "/tmp", "/usr", "/usr/bin", "/usr/lib", "/usr/local", "/usr/sbin", "/var"},
+	"darwin":  {"/Applications", "/bin", "/Library", "/System", "/Users", "/etc", "/opt", "/private", "/sbin", "/tmp", "/usr", "/usr/bin", "/usr/lib", "/usr/local", "/usr/sbin", "/var"},
+	"freebsd": {"/bin", "/boot", "/dev", "/etc", "/home", "/lib", "/root", "/sbin", "/tmp", "/usr", "/usr/bin", "/usr/lib", "/usr/local", "/usr/sbin", "/var"},
+}
+
+// CheckOutputDirs refuses the repositories of cfg whose output_dir is a
+// filesystem root, the home directory itself or a system directory, where the
+// removal of previously installed files and forced reinstalls would do the
+// most damage. Install checks them itself; installs that bypass it, such as
+// those of fleet, call it first.
+func CheckOutputDirs(cfg *config.Config) error {
+	if cfg.AllowDangerousDir {
+		return nil
+	}
+	for _, repo := range cfg.Github {
+		if reason := dangerousDir(repo.OutputDir); reason != "" {
+			return &RepoError{Repo: repo, Err: fmt.Errorf("%w %s: it is %s; use a subdirectory or set allow_dangerous_dir", ErrDangerousOutputDir, repo.OutputDir, reason)}
+		}
+	}
+	return nil
+}
+
+// dangerousDir describes why dir is dangerous to install into, "" when it is
+// not.
+func dangerousDir(dir string) string {
+	if dir == "" {
+		return ""
+	}
+	dir = canonicalDir(dir)
+	if filepath.Dir(dir) == dir {
+		return "the filesystem root"
+	}
+	if home, err := os.UserHomeDir(); err == nil && sameDir(dir, canonicalDir(home)) {
+		return "the home directory"
+	}
+	for _, sys := range osSystemDirs() {
+		if sameDir(dir, canonicalDir(sys)) {
+			return "a system directory"
+		}
+	}
+	return ""
+}
+
+// osSystemDirs returns the system directories of the running OS.
+func osSystemDirs() []string {
+	if runtime.GOOS != "windows" {
+		return systemDirs[runtime.GOOS]
+	}
+	var dirs []string
+	for _, env := range []string{"SystemRoot", "ProgramFiles", "ProgramFiles(x86)", "ProgramData"} {
+		if dir := os.Getenv(env); dir != "" {
+			dirs = append(dirs, dir)
+		}
+	}
+	if drive := os.Getenv("SystemDrive"); drive != "" {
+		dirs = append(dirs, drive+`\Users`)
+	}
+	return dirs
+}
+
+// canonicalDir returns dir absolute, with symlinks resolved when it exists,
+// so "/var/.." and links to the root are recognized.
+func canonicalDir(dir string) string {
+	if abs, err := filepath.Abs(dir); err == nil {
+		dir = abs
+	}
+	if resolved, err := filepath.EvalSymlinks(dir); err == nil {
+		dir = resolved
+	}
+	return filepath.Clean(dir)
+}
+
+// sameDir compares directories case-insensitively where the filesystem
+// usually is.
+func sameDir(a, b string) bool {
+	if runtime.GOOS == "windows" || runtime.GOOS == "darwin" {
+		return strings.EqualFold(a, b)
+	}
+	return a == b
+}
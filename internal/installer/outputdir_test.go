@@ -0,0 +1,67 @@
+package installer
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/sixban6/ghinstall/internal/config"
+	"github.com/sixban6/ghinstall/internal/release"
+)
+
+func TestDangerousDir(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("USERPROFILE", home)
+
+	root := string(filepath.Separator)
+	if runtime.GOOS == "windows" {
+		root = os.Getenv("SystemDrive") + `\`
+	}
+	type dirCase struct {
+		dir  string
+		want bool
+	}
+	tests := []dirCase{
+		{dir: root, want: true},
+		{dir: home, want: true},
+		{dir: filepath.Join(home, "tool", ".."), want: true},
+		{dir: filepath.Join(home, "tool")},
+		{dir: t.TempDir()},
+	}
+	if runtime.GOOS == "linux" {
+		tests = append(tests, dirCase{dir: "/usr/", want: true}, dirCase{dir: "/usr/local/bin"})
+	}
+	for _, tt := range tests {
+		if got := dangerousDir(tt.dir) != ""; got != tt.want {
+			t.Errorf("dangerousDir(%q) = %q, want dangerous %v", tt.dir, dangerousDir(tt.dir), tt.want)
+		}
+	}
+}
+
+func TestInstaller_Install_DangerousOutputDir(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("USERPROFILE", home)
+
+	mockRel := &release.Release{
+		TagName: "v1.0.0",
+		Assets:  []release.Asset{{Name: "app.tar.gz", URL: "https://github.com/owner/repo/releases/download/v1.0.0/app.tar.gz"}},
+	}
+	cfg := &config.Config{Github: []config.Repo{{URL: "https://github.com/owner/repo", OutputDir: home}}}
+
+	extractor := &readingExtractor{}
+	installer := New(&mockFinder{release: mockRel}, &mockDownloader{content: "test content"}, extractor)
+	err := installer.Install(context.Background(), cfg, release.DefaultFilter())
+	if !errors.Is(err, ErrDangerousOutputDir) {
+		t.Fatalf("Install() error = %v, want %v", err, ErrDangerousOutputDir)
+	}
+
+	cfg.AllowDangerousDir = true
+	if err := installer.Install(context.Background(), cfg, release.DefaultFilter()); err != nil {
+		t.Errorf("Install() with allow_dangerous_dir error = %v", err)
+	}
+}
@@ -0,0 +1,73 @@
+package installer
+
+import (
+	"context"
+	"fmt"
+	log "github.com/sixban6/ghinstall/internal/logger"
+
+	"github.com/sixban6/ghinstall/internal/cache"
+	"github.com/sixban6/ghinstall/internal/config"
+	"github.com/sixban6/ghinstall/internal/downloader"
+	"github.com/sixban6/ghinstall/internal/release"
+	"github.com/sixban6/ghinstall/internal/state"
+)
+
+// etagged is implemented by downloads that know the ETag they were served with.
+type etagged interface {
+	ETag() string
+}
+
+// replacedReason compares the recorded install with the asset now published
+// under the same tag and describes how it changed, or returns "" when it did
+// not change or that cannot be told. The GitHub digest is preferred; the ETag
+// is only comparable when the asset is checked at the URL it was installed from.
+func replacedReason(rec *state.Record, asset *release.Asset, url string, remote *downloader.Metadata) string {
+	if rec == nil || rec.Asset != asset.Name {
+		return ""
+	}
+	if digest := githubDigest(asset.Digest); digest != "" && rec.SHA256 != "" {
+		if digest != rec.SHA256 {
+			return fmt.Sprintf("sha256 %s, installed %s", digest, rec.SHA256)
+		}
+		return ""
+	}
+	if remote != nil && remote.ETag != "" && rec.ETag != "" && rec.Source == url && remote.ETag != rec.ETag {
+		return fmt.Sprintf("ETag %s, installed %s", remote.ETag, rec.ETag)
+	}
+	return ""
+}
+
+// upstreamReplaced checks whether the asset of the installed tag was replaced
+// upstream since it was installed from url.
+func (i *Installer) upstreamReplaced(ctx context.Context, repo config.Repo, rel *release.Release, asset *release.Asset, url string) string {
+	st, err := state.Load(repo.OutputDir)
+	if err != nil {
+		return ""
+	}
+	rec, ok := st.Get(repo.URL)
+	if !ok || rec.Tag != rel.TagName {
+		return ""
+	}
+
+	var remote *downloader.Metadata
+	if githubDigest(asset.Digest) == "" && rec.ETag != "" && rec.Source == url {
+		if mc, ok := i.downloader.(downloader.MetadataClient); ok {
+			if remote, err = mc.Head(ctx, url, nil); err != nil {
+				log.Warn("Failed to check %s: %v", url, err)
+			}
+		}
+	}
+	return replacedReason(&rec, asset, url, remote)
+}
+
+// forgetCached drops key from the download cache so it is fetched again.
+func forgetCached(cfg *config.Config, key string) error {
+	if cfg.CacheDir == "" {
+		return nil
+	}
+	c, err := cache.Open(cache.ResolveDir(cfg.CacheDir), cfg.SharedCache())
+	if err != nil {
+		return err
+	}
+	return c.Forget(key)
+}
@@ -0,0 +1,79 @@
+package installer
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/sixban6/ghinstall/internal/config"
+	"github.com/sixban6/ghinstall/internal/downloader"
+	"github.com/sixban6/ghinstall/internal/release"
+)
+
+func TestInstaller_Install_UpstreamReplaced(t *testing.T) {
+	directReachable = func(context.Context) bool { return true }
+	defer func() { directReachable = PingGoogle }()
+
+	digest := func(content string) string {
+		sum := sha256.Sum256([]byte(content))
+		return "sha256:" + hex.EncodeToString(sum[:])
+	}
+
+	tests := []struct {
+		name         string
+		useDigest    bool
+		forceRefresh bool
+		wantContent  string
+	}{
+		{name: "etag changed, kept", wantContent: ""},
+		{name: "etag changed, refreshed", forceRefresh: true, wantContent: "rebuilt"},
+		{name: "digest changed, kept", useDigest: true, wantContent: ""},
+		{name: "digest changed, refreshed", useDigest: true, forceRefresh: true, wantContent: "rebuilt"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			content := "original"
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("ETag", `"`+content+`"`)
+				w.Write([]byte(content))
+			}))
+			defer server.Close()
+
+			asset := release.Asset{Name: "app.tar.gz", URL: server.URL + "/app.tar.gz"}
+			if tt.useDigest {
+				asset.Digest = digest(content)
+			}
+			rel := &release.Release{TagName: "v1.0.0", Assets: []release.Asset{asset}}
+			cfg := &config.Config{
+				Github:   []config.Repo{{URL: "https://github.com/owner/repo", OutputDir: t.TempDir()}},
+				CacheDir: t.TempDir(),
+			}
+
+			if err := New(&mockFinder{release: rel}, downloader.NewHTTPClient(), &readingExtractor{}).Install(context.Background(), cfg, release.DefaultFilter()); err != nil {
+				t.Fatalf("first Install() error = %v", err)
+			}
+
+			content = "rebuilt"
+			if tt.useDigest {
+				rel.Assets[0].Digest = digest(content)
+			}
+			ext := &readingExtractor{}
+			inst := New(&mockFinder{release: rel}, downloader.NewHTTPClient(), ext, WithForceRefresh(tt.forceRefresh))
+			if err := inst.Install(context.Background(), cfg, release.DefaultFilter()); err != nil {
+				t.Fatalf("second Install() error = %v", err)
+			}
+			if string(ext.content) != tt.wantContent {
+				t.Errorf("installed content = %q, want %q", ext.content, tt.wantContent)
+			}
+
+			st := New(&mockFinder{release: rel}, downloader.NewHTTPClient(), nil).Status(context.Background(), cfg)[0]
+			if wantReplaced := !tt.forceRefresh; (st.Replaced != "") != wantReplaced {
+				t.Errorf("Status().Replaced = %q, want replaced %v", st.Replaced, wantReplaced)
+			}
+		})
+	}
+}
@@ -0,0 +1,262 @@
+package installer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+
+	"github.com/sixban6/ghinstall/internal/config"
+	"github.com/sixban6/ghinstall/internal/release"
+)
+
+// WithParallel installs up to n repositories at the same time. Middleware,
+// post-processors and the Progress must then be safe for concurrent use. The
+// first failure cancels the installs still running, unless the config sets
+// continue_on_error. Without it, repositories
+// are still resolved concurrently, but the middleware and the Progress are
+// never called concurrently.
+func WithParallel(n int) Option {
+	return func(i *Installer) {
+		i.parallel = n
+	}
+}
+
+// Progress follows the installs of an Installer, e.g. to render a progress
+// line per repository. Repositories are the entries of the config, as given
+// to Install; with WithParallel, several are reported concurrently.
+type Progress interface {
+	// Status reports what the install of repo is doing, e.g. "resolving".
+	Status(repo config.Repo, status string)
+	// Downloaded reports that n bytes of the asset of repo were downloaded out
+	// of total, which is 0 when unknown.
+	Downloaded(repo config.Repo, n, total int64)
+	// Done reports that the install of repo finished, failing with err.
+	Done(repo config.Repo, err error)
+}
+
+// WithProgress reports the progress of every install to p.
+func WithProgress(p Progress) Option {
+	return func(i *Installer) {
+		i.progress = p
+	}
+}
+
+// maxResolving bounds the repositories resolved at the same time.
+const maxResolving = 8
+
+// ResolveError is returned by Install when several repositories cannot be
+// resolved. Install resolves every repository before downloading anything,
+// so it reports all of them at once and installs none.
+type ResolveError struct {
+	Errs []*RepoError
+}
+
+func (e *ResolveError) Error() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "failed to resolve %d repositories:", len(e.Errs))
+	for _, err := range e.Errs {
+		b.WriteString("\n  " + err.Error())
+	}
+	return b.String()
+}
+
+func (e *ResolveError) Unwrap() []error {
+	errs := make([]error, len(e.Errs))
+	for n, err := range e.Errs {
+		errs[n] = err
+	}
+	return errs
+}
+
+// ErrNotInstalled is reported to the Progress for repositories that resolved
+// but were not installed because others did not.
+var ErrNotInstalled = errors.New("not installed, other repositories failed to resolve")
+
+// resolveAll resolves every repository of cfg concurrently. When any fails,
+// it returns the failure, or a *ResolveError with all of them.
+func (i *Installer) resolveAll(ctx context.Context, cfg *config.Config, filter release.AssetFilter) ([]*resolved, error) {
+	repos, errs := i.resolveEach(ctx, cfg, filter)
+
+	var failed []*RepoError
+	for n, err := range errs {
+		if err != nil {
+			failed = append(failed, &RepoError{Repo: cfg.Github[n], Err: err})
+		}
+	}
+	if len(failed) == 0 {
+		return repos, nil
+	}
+
+	for n, repo := range cfg.Github {
+		if errs[n] != nil {
+			i.tracker(repo).done(errs[n])
+		} else {
+			i.tracker(repo).done(ErrNotInstalled)
+		}
+	}
+	if len(failed) == 1 {
+		return nil, failed[0]
+	}
+	return nil, &ResolveError{Errs: failed}
+}
+
+// resolveEach resolves every repository of cfg concurrently, returning the
+// resolved repositories and the failures in config order.
+func (i *Installer) resolveEach(ctx context.Context, cfg *config.Config, filter release.AssetFilter) ([]*resolved, []error) {
+	var (
+		repos = make([]*resolved, len(cfg.Github))
+		errs  = make([]error, len(cfg.Github))
+		sem   = make(chan struct{}, maxResolving)
+		wg    sync.WaitGroup
+	)
+	for n, repo := range cfg.Github {
+		wg.Go(func() {
+			sem <- struct{}{}
+			defer func() { <-sem }()
+			repos[n], errs[n] = i.resolveRepo(ctx, cfg, repo, filter)
+		})
+	}
+	wg.Wait()
+	return repos, errs
+}
+
+// installParallel installs the resolved repositories with up to i.parallel
+// running at once. The first failure cancels the others; of the failures, the
+// one of the repository listed first in the config is returned, so the same
+// failures report the same error however the installs were scheduled.
+func (i *Installer) installParallel(ctx context.Context, cfg *config.Config, repos []*resolved) error {
+	errs := i.installConcurrently(ctx, cfg, repos, true)
+
+	var cancelled error
+	for n, err := range errs {
+		switch {
+		case err == nil:
+		case errors.Is(err, context.Canceled) && ctx.Err() == nil:
+			// Cancelled because another install failed.
+			if cancelled == nil {
+				cancelled = &RepoError{Repo: repos[n].entry, Err: err}
+			}
+		default:
+			return &RepoError{Repo: repos[n].entry, Err: err}
+		}
+	}
+	return cancelled
+}
+
+// installConcurrently installs repos with up to i.parallel running at once and
+// returns their failures in the order of repos. With cancelOnFailure, the
+// first failure cancels the installs still running or waiting.
+func (i *Installer) installConcurrently(ctx context.Context, cfg *config.Config, repos []*resolved, cancelOnFailure bool) []error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	// Shared clients are configured before the installs start, which then only
+	// apply the same settings again.
+	i.configureRedirects(cfg)
+	i.configureTimeouts(cfg)
+	configureExtractor(cfg, i.extractorFor(cfg))
+
+	var (
+		sem  = make(chan struct{}, i.parallel)
+		wg   sync.WaitGroup
+		errs = make([]error, len(repos))
+	)
+	for n, r := range repos {
+		wg.Go(func() {
+			select {
+			case sem <- struct{}{}:
+				defer func() { <-sem }()
+			case <-ctx.Done():
+				i.tracker(r.entry).done(ctx.Err())
+				errs[n] = ctx.Err()
+				return
+			}
+
+			err := i.installResolved(ctx, cfg, r)
+			i.tracker(r.entry).done(err)
+			if err != nil {
+				errs[n] = err
+				if cancelOnFailure {
+					cancel()
+				}
+			}
+		})
+	}
+	wg.Wait()
+	return errs
+}
+
+// serialize locks the callbacks of an Installer without WithParallel and
+// returns the function unlocking them, or nil when they may run concurrently.
+func (i *Installer) serialize() func() {
+	if i.parallel > 1 {
+		return nil
+	}
+	i.callbacks.Lock()
+	return i.callbacks.Unlock
+}
+
+// tracker reports the progress of one repository to the Progress, if any.
+type tracker struct {
+	progress Progress
+	repo     config.Repo
+	i        *Installer
+}
+
+func (i *Installer) tracker(repo config.Repo) tracker {
+	return tracker{progress: i.progress, repo: repo, i: i}
+}
+
+func (t tracker) status(status string) {
+	if t.progress != nil {
+		defer t.lock()()
+		t.progress.Status(t.repo, status)
+	}
+}
+
+func (t tracker) downloaded(n, total int64) {
+	defer t.lock()()
+	t.progress.Downloaded(t.repo, n, total)
+}
+
+func (t tracker) done(err error) {
+	if t.progress != nil {
+		defer t.lock()()
+		t.progress.Done(t.repo, err)
+	}
+}
+
+// lock serializes a Progress call when needed and returns its unlock.
+func (t tracker) lock() func() {
+	if unlock := t.i.serialize(); unlock != nil {
+		return unlock
+	}
+	return func() {}
+}
+
+// counting returns rc reporting every read to the Progress.
+func (t tracker) counting(rc io.ReadCloser, total int64) io.ReadCloser {
+	if t.progress == nil {
+		return rc
+	}
+	return &countingReader{ReadCloser: rc, t: t, total: total}
+}
+
+type countingReader struct {
+	io.ReadCloser
+	t     tracker
+	n     int64
+	total int64
+}
+
+func (r *countingReader) Read(p []byte) (int, error) {
+	n, err := r.ReadCloser.Read(p)
+	if n > 0 {
+		r.n += int64(n)
+		r.t.downloaded(r.n, r.total)
+	}
+	return n, err
+}
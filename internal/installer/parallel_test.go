@@ -0,0 +1,231 @@
+package installer
+
+import (
+	"context"
+	"errors"
+	"io"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/sixban6/ghinstall/internal/config"
+	"github.com/sixban6/ghinstall/internal/extractor"
+	"github.com/sixban6/ghinstall/internal/release"
+)
+
+// gateDownloader blocks every download until as many are running at once.
+type gateDownloader struct {
+	content string
+	wait    int
+	mu      sync.Mutex
+	running int
+	open    chan struct{}
+}
+
+func (g *gateDownloader) Download(ctx context.Context, url string) (io.ReadCloser, error) {
+	g.mu.Lock()
+	if g.running++; g.running == g.wait {
+		close(g.open)
+	}
+	g.mu.Unlock()
+
+	select {
+	case <-g.open:
+		return io.NopCloser(strings.NewReader(g.content)), nil
+	case <-time.After(5 * time.Second):
+		return nil, errors.New("downloads did not run in parallel")
+	}
+}
+
+type recordedProgress struct {
+	mu         sync.Mutex
+	statuses   map[string][]string
+	downloaded map[string]int64
+	done       map[string]error
+}
+
+func newRecordedProgress() *recordedProgress {
+	return &recordedProgress{statuses: map[string][]string{}, downloaded: map[string]int64{}, done: map[string]error{}}
+}
+
+func (p *recordedProgress) Status(repo config.Repo, status string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.statuses[repo.OutputDir] = append(p.statuses[repo.OutputDir], status)
+}
+
+func (p *recordedProgress) Downloaded(repo config.Repo, n, total int64) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.downloaded[repo.OutputDir] = n
+}
+
+func (p *recordedProgress) Done(repo config.Repo, err error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.done[repo.OutputDir] = err
+}
+
+// dirExtractor fails to extract into the directory failDir.
+type dirExtractor struct {
+	failDir string
+}
+
+func (e dirExtractor) Extract(src io.Reader, dst string) error {
+	if dst == e.failDir {
+		return errors.New("disk full")
+	}
+	_, err := io.Copy(io.Discard, src)
+	return err
+}
+
+func TestInstaller_Install_Parallel(t *testing.T) {
+	directReachable = func(context.Context) bool { return true }
+	defer func() { directReachable = PingGoogle }()
+
+	rel := &release.Release{TagName: "v1.0.0", Assets: []release.Asset{
+		{Name: "app.tar.gz", URL: "https://github.com/owner/repo/releases/download/v1.0.0/app.tar.gz", Size: 7},
+	}}
+	cfg := &config.Config{Github: []config.Repo{
+		{URL: "https://github.com/owner/repo", OutputDir: t.TempDir()},
+		{URL: "https://github.com/owner/repo", OutputDir: t.TempDir()},
+		{URL: "https://github.com/owner/repo", OutputDir: t.TempDir()},
+	}}
+
+	progress := newRecordedProgress()
+	d := &gateDownloader{content: "archive", wait: 3, open: make(chan struct{})}
+	inst := New(&mockFinder{release: rel}, d, dirExtractor{}, WithParallel(3), WithProgress(progress))
+	if err := inst.Install(context.Background(), cfg, release.DefaultFilter()); err != nil {
+		t.Fatalf("Install() error = %v", err)
+	}
+
+	for _, repo := range cfg.Github {
+		if err, ok := progress.done[repo.OutputDir]; !ok || err != nil {
+			t.Errorf("Done(%s) = %v, reported %v", repo.OutputDir, err, ok)
+		}
+		if got := progress.downloaded[repo.OutputDir]; got != 7 {
+			t.Errorf("Downloaded(%s) = %d, want 7", repo.OutputDir, got)
+		}
+		if got := progress.statuses[repo.OutputDir]; len(got) == 0 || got[0] != "resolving" {
+			t.Errorf("statuses of %s = %v", repo.OutputDir, got)
+		}
+	}
+}
+
+func TestInstaller_Install_ParallelFailure(t *testing.T) {
+	directReachable = func(context.Context) bool { return true }
+	defer func() { directReachable = PingGoogle }()
+
+	rel := &release.Release{TagName: "v1.0.0", Assets: []release.Asset{
+		{Name: "app.tar.gz", URL: "https://github.com/owner/repo/releases/download/v1.0.0/app.tar.gz"},
+	}}
+	failDir := t.TempDir()
+	cfg := &config.Config{Github: []config.Repo{
+		{URL: "https://github.com/owner/repo", OutputDir: t.TempDir()},
+		{URL: "https://github.com/owner/repo", OutputDir: failDir},
+	}}
+
+	progress := newRecordedProgress()
+	inst := New(&mockFinder{release: rel}, &mockDownloader{content: "archive"}, dirExtractor{failDir: failDir}, WithParallel(2), WithProgress(progress))
+	err := inst.Install(context.Background(), cfg, release.DefaultFilter())
+
+	var repoErr *RepoError
+	if !errors.As(err, &repoErr) || repoErr.Repo.OutputDir != failDir {
+		t.Fatalf("Install() error = %v, want RepoError of %s", err, failDir)
+	}
+	if progress.done[failDir] == nil {
+		t.Errorf("Done(%s) did not report the failure", failDir)
+	}
+	if len(progress.done) != 2 {
+		t.Errorf("Done reported for %d repositories, want 2", len(progress.done))
+	}
+}
+
+// failingExtractor fails every extraction, the one into slowDir last.
+type failingExtractor struct {
+	slowDir string
+}
+
+func (e failingExtractor) Extract(src io.Reader, dst string) error {
+	if dst == e.slowDir {
+		time.Sleep(100 * time.Millisecond)
+	}
+	return errors.New("disk full")
+}
+
+func TestInstaller_Install_ParallelFailureOrder(t *testing.T) {
+	directReachable = func(context.Context) bool { return true }
+	defer func() { directReachable = PingGoogle }()
+
+	rel := &release.Release{TagName: "v1.0.0", Assets: []release.Asset{
+		{Name: "app.tar.gz", URL: "https://github.com/owner/repo/releases/download/v1.0.0/app.tar.gz"},
+	}}
+	slowDir := t.TempDir()
+	cfg := &config.Config{Github: []config.Repo{
+		{URL: "https://github.com/owner/repo", OutputDir: slowDir},
+		{URL: "https://github.com/owner/repo", OutputDir: t.TempDir()},
+	}}
+
+	// The second repository fails first, but the first one is reported.
+	d := &gateDownloader{content: "archive", wait: 2, open: make(chan struct{})}
+	inst := New(&mockFinder{release: rel}, d, failingExtractor{slowDir: slowDir}, WithParallel(2))
+	err := inst.Install(context.Background(), cfg, release.DefaultFilter())
+
+	var repoErr *RepoError
+	if !errors.As(err, &repoErr) || repoErr.Repo.OutputDir != slowDir {
+		t.Fatalf("Install() error = %v, want RepoError of %s", err, slowDir)
+	}
+}
+
+var _ extractor.Extractor = dirExtractor{}
+
+// gateFinder blocks every lookup until as many are running at once.
+type gateFinder struct {
+	mockFinder
+	gate *gateDownloader
+}
+
+func (g *gateFinder) Latest(ctx context.Context, owner, repo string, policy release.Policy) (*release.Release, error) {
+	if _, err := g.gate.Download(ctx, ""); err != nil {
+		return nil, errors.New("releases were not resolved concurrently")
+	}
+	return g.mockFinder.Latest(ctx, owner, repo, policy)
+}
+
+func TestInstaller_Install_ResolvesFirst(t *testing.T) {
+	directReachable = func(context.Context) bool { return true }
+	defer func() { directReachable = PingGoogle }()
+
+	rel := &release.Release{TagName: "v1.0.0", Assets: []release.Asset{
+		{Name: "app.tar.gz", URL: "https://github.com/owner/repo/releases/download/v1.0.0/app.tar.gz"},
+	}}
+	good := t.TempDir()
+	cfg := &config.Config{Github: []config.Repo{
+		{URL: "https://github.com/owner/repo", OutputDir: good},
+		{URL: "https://github.com/owner/repo", OutputDir: t.TempDir(), AssetPattern: "windows"},
+		{URL: "https://github.com/owner/repo", OutputDir: t.TempDir(), AssetPattern: "darwin"},
+	}}
+
+	progress := newRecordedProgress()
+	f := &gateFinder{mockFinder: mockFinder{release: rel}, gate: &gateDownloader{wait: 3, open: make(chan struct{})}}
+	d := &recordingDownloader{}
+	inst := New(f, d, &mockExtractor{}, WithProgress(progress))
+	err := inst.Install(context.Background(), cfg, release.DefaultFilter())
+
+	var resolveErr *ResolveError
+	if !errors.As(err, &resolveErr) || len(resolveErr.Errs) != 2 {
+		t.Fatalf("Install() error = %v, want a ResolveError of 2 repositories", err)
+	}
+	for n, repoErr := range resolveErr.Errs {
+		if want := cfg.Github[n+1]; repoErr.Repo.OutputDir != want.OutputDir {
+			t.Errorf("error %d is for %s, want %s", n, repoErr.Repo.OutputDir, want.OutputDir)
+		}
+	}
+	if len(d.urls) != 0 {
+		t.Errorf("downloaded %v although resolving failed", d.urls)
+	}
+	if !errors.Is(progress.done[good], ErrNotInstalled) {
+		t.Errorf("Done(%s) = %v, want %v", good, progress.done[good], ErrNotInstalled)
+	}
+}
@@ -0,0 +1,106 @@
+package installer
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/sixban6/ghinstall/internal/config"
+	"github.com/sixban6/ghinstall/internal/release"
+)
+
+// PinResult is a repository whose version Pin or Unpin changed.
+type PinResult struct {
+	Repo config.Repo
+	// Previous is the version the repository was pinned to before, "" when
+	// it followed its latest release.
+	Previous string
+	// Tag is the version it is pinned to now, "" after Unpin.
+	Tag string
+}
+
+// Pin pins the repositories of cfg, loaded from cfgPath, that match selector
+// (a name, "owner/repo" or URL) to tag by setting their version in the file.
+// Without a tag each is pinned to the release an install would select right
+// now. Repositories following a channel or a constraint cannot be pinned.
+func (i *Installer) Pin(ctx context.Context, cfgPath string, cfg *config.Config, selector, tag string) ([]PinResult, error) {
+	indexes, err := matching(cfg, selector)
+	if err != nil {
+		return nil, err
+	}
+
+	var pinned []PinResult
+	for _, n := range indexes {
+		repo := cfg.Github[n]
+		if repo.Channel != "" {
+			return pinned, fmt.Errorf("%s follows the %s channel; remove its channel to pin it", repo.DisplayName(), repo.Channel)
+		}
+		if repo.Constraint != "" {
+			return pinned, fmt.Errorf("%s follows the constraint %q; remove its constraint to pin it", repo.DisplayName(), repo.Constraint)
+		}
+
+		want := tag
+		if want == "" {
+			// Pinning is how held repositories are upgraded.
+			latest := repo
+			latest.Version, latest.HoldOnMajor, latest.HoldPatterns = "", false, nil
+			res, err := i.resolveTag(ctx, cfg, latest)
+			if err != nil {
+				return pinned, fmt.Errorf("failed to resolve %s: %w", repo.DisplayName(), err)
+			}
+			want = res
+		}
+		if err := config.SetVersion(cfgPath, n, want); err != nil {
+			return pinned, err
+		}
+		pinned = append(pinned, PinResult{Repo: repo, Previous: repo.Version, Tag: want})
+	}
+	return pinned, nil
+}
+
+// Unpin removes the version of the repositories of cfg, loaded from cfgPath,
+// that match selector, so they follow their latest release again.
+func Unpin(cfgPath string, cfg *config.Config, selector string) ([]PinResult, error) {
+	indexes, err := matching(cfg, selector)
+	if err != nil {
+		return nil, err
+	}
+
+	var unpinned []PinResult
+	for _, n := range indexes {
+		repo := cfg.Github[n]
+		if repo.Version == "" {
+			continue
+		}
+		if err := config.SetVersion(cfgPath, n, ""); err != nil {
+			return unpinned, err
+		}
+		unpinned = append(unpinned, PinResult{Repo: repo, Previous: repo.Version})
+	}
+	return unpinned, nil
+}
+
+// matching returns the indexes of the repositories of cfg matching selector.
+func matching(cfg *config.Config, selector string) ([]int, error) {
+	var indexes []int
+	for n, repo := range cfg.Github {
+		if repo.Matches(selector) {
+			indexes = append(indexes, n)
+		}
+	}
+	if len(indexes) == 0 {
+		return nil, fmt.Errorf("no configured repository matches %q", selector)
+	}
+	return indexes, nil
+}
+
+// resolveTag returns the tag of the release an install of repo would select,
+// checking that it offers an asset to install.
+func (i *Installer) resolveTag(ctx context.Context, cfg *config.Config, repo config.Repo) (string, error) {
+	i.configureHTTPCache(cfg)
+	configureFDLimits(cfg)
+	r, err := i.resolveRepo(ctx, cfg, repo, release.DefaultFilter())
+	if err != nil {
+		return "", err
+	}
+	return r.rel.TagName, nil
+}
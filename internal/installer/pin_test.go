@@ -0,0 +1,62 @@
+package installer
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/sixban6/ghinstall/internal/config"
+	"github.com/sixban6/ghinstall/internal/release"
+)
+
+func TestInstaller_Pin(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "ghinstall.yaml")
+	content := `github:
+  - url: "https://github.com/owner/tool"
+    output_dir: "` + filepath.ToSlash(filepath.Join(dir, "tool")) + `"
+  - url: "https://github.com/owner/nightly"
+    output_dir: "` + filepath.ToSlash(filepath.Join(dir, "nightly")) + `"
+    channel: nightly
+`
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	load := func() *config.Config {
+		cfg, err := config.Load(path)
+		if err != nil {
+			t.Fatalf("config.Load() error = %v", err)
+		}
+		return cfg
+	}
+
+	rel := &release.Release{TagName: "v2.0.0", Assets: []release.Asset{{Name: "tool.tar.gz", URL: "https://example.com/tool.tar.gz"}}}
+	inst := New(&mockFinder{release: rel}, &mockDownloader{}, &mockExtractor{})
+
+	pinned, err := inst.Pin(context.Background(), path, load(), "owner/tool", "")
+	if err != nil {
+		t.Fatalf("Pin() error = %v", err)
+	}
+	if len(pinned) != 1 || pinned[0].Tag != "v2.0.0" {
+		t.Fatalf("Pin() = %+v, want owner/tool pinned to the latest release", pinned)
+	}
+	if got := load().Github[0].Version; got != "v2.0.0" {
+		t.Errorf("version = %q, want v2.0.0", got)
+	}
+
+	if _, err := inst.Pin(context.Background(), path, load(), "owner/nightly", "v1.0.0"); err == nil {
+		t.Error("Pin() of a repository following a channel should fail")
+	}
+	if _, err := inst.Pin(context.Background(), path, load(), "owner/missing", "v1.0.0"); err == nil {
+		t.Error("Pin() of an unknown repository should fail")
+	}
+
+	unpinned, err := Unpin(path, load(), "owner/tool")
+	if err != nil || len(unpinned) != 1 || unpinned[0].Previous != "v2.0.0" {
+		t.Fatalf("Unpin() = %+v, %v, want owner/tool unpinned from v2.0.0", unpinned, err)
+	}
+	if got := load().Github[0].Version; got != "" {
+		t.Errorf("version after Unpin() = %q, want none", got)
+	}
+}
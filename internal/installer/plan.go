@@ -0,0 +1,112 @@
+package installer
+
+import (
+	"context"
+	"errors"
+
+	"github.com/sixban6/ghinstall/internal/cache"
+	"github.com/sixban6/ghinstall/internal/config"
+)
+
+// PlannedDownload is the asset selected for a repository, about to be
+// downloaded.
+type PlannedDownload struct {
+	Repo  config.Repo
+	Tag   string
+	Asset string
+	// Size is the size the release reports, 0 when unknown.
+	Size int64
+	// Cached is set when cache_dir holds the asset, which is then not
+	// downloaded again.
+	Cached bool
+	// UpToDate is set when the asset of Tag is already installed; the
+	// repository is then skipped.
+	UpToDate bool
+}
+
+// DownloadPlan lists the downloads of an install, in the order of the config.
+type DownloadPlan struct {
+	Downloads []PlannedDownload
+}
+
+// Total returns the bytes the install downloads: the sizes of the assets
+// that are neither cached nor up to date. Delta downloads may need less.
+func (p *DownloadPlan) Total() int64 {
+	var total int64
+	for _, d := range p.Downloads {
+		if !d.Cached && !d.UpToDate {
+			total += d.Size
+		}
+	}
+	return total
+}
+
+// WithPlan calls fn with the downloads of every Install once all
+// repositories are resolved, before anything is downloaded. An error from fn
+// aborts the install, e.g. when the user declines to download that much.
+func WithPlan(fn func(ctx context.Context, plan *DownloadPlan) error) Option {
+	return func(i *Installer) {
+		i.plan = fn
+	}
+}
+
+// WithDryRun makes Install resolve the repositories and report the
+// downloads to the WithPlan function, without downloading or installing
+// anything.
+func WithDryRun(dryRun bool) Option {
+	return func(i *Installer) {
+		i.dryRun = dryRun
+	}
+}
+
+// errDryRun is reported to the Progress for the repositories of a dry run.
+var errDryRun = errors.New("not installed, dry run")
+
+// checkPlan reports the downloads of repos to the WithPlan function. It
+// returns whether the install goes on.
+func (i *Installer) checkPlan(ctx context.Context, cfg *config.Config, repos []*resolved) (bool, error) {
+	if i.plan == nil && !i.dryRun {
+		return true, nil
+	}
+
+	var err error
+	if i.plan != nil {
+		err = i.plan(ctx, i.planDownloads(cfg, repos))
+	}
+	if err == nil && !i.dryRun {
+		return true, nil
+	}
+	for _, r := range repos {
+		if err != nil {
+			i.tracker(r.entry).done(err)
+		} else {
+			i.tracker(r.entry).done(errDryRun)
+		}
+	}
+	return false, err
+}
+
+// planDownloads lists the assets selected for repos. Without force, those
+// already installed are marked up to date.
+func (i *Installer) planDownloads(cfg *config.Config, repos []*resolved) *DownloadPlan {
+	var c *cache.Cache
+	if cfg.CacheDir != "" {
+		// Planning only looks, as dry runs must not create the cache.
+		c = cache.OpenReadOnly(cache.ResolveDir(cfg.CacheDir))
+	}
+
+	plan := &DownloadPlan{Downloads: make([]PlannedDownload, 0, len(repos))}
+	for _, r := range repos {
+		d := PlannedDownload{Repo: r.entry, Tag: r.rel.TagName, Asset: r.asset.Name, Size: r.asset.Size}
+		d.UpToDate = !i.force && upToDate(r.repo, r.rel, r.asset) || i.unchanged(r.repo, r.rel)
+		if c != nil {
+			key := r.asset.URL
+			if r.src != nil {
+				key = providerCacheKey(r.repo, r.rel, r.asset)
+			}
+			_, d.Cached = c.Lookup(key)
+		}
+		plan.Downloads = append(plan.Downloads, d)
+	}
+	return plan
+}
@@ -0,0 +1,69 @@
+package installer
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/sixban6/ghinstall/internal/config"
+	"github.com/sixban6/ghinstall/internal/release"
+)
+
+func TestInstaller_Install_Plan(t *testing.T) {
+	rel := &release.Release{
+		TagName: "v1.0.0",
+		Assets: []release.Asset{{
+			Name: "app.tar.gz",
+			URL:  "https://github.com/owner/repo/releases/download/v1.0.0/app.tar.gz",
+			Size: 1024,
+		}},
+	}
+	cfg := &config.Config{
+		Github:   []config.Repo{{URL: "https://github.com/owner/repo", OutputDir: t.TempDir()}},
+		CacheDir: filepath.Join(t.TempDir(), "cache"),
+	}
+
+	var plans []*DownloadPlan
+	record := WithPlan(func(ctx context.Context, plan *DownloadPlan) error {
+		plans = append(plans, plan)
+		return nil
+	})
+	down := &countingDownloader{content: "test content"}
+	ctx := context.Background()
+
+	if err := New(&mockFinder{release: rel}, down, &mockExtractor{}, record, WithDryRun(true)).Install(ctx, cfg, release.DefaultFilter()); err != nil {
+		t.Fatalf("Install() dry run error = %v", err)
+	}
+	if down.calls != 0 {
+		t.Fatalf("dry run downloaded %d times", down.calls)
+	}
+	if _, err := os.Stat(cfg.CacheDir); !os.IsNotExist(err) {
+		t.Errorf("dry run created the cache directory: %v", err)
+	}
+	if len(plans) != 1 || len(plans[0].Downloads) != 1 || plans[0].Total() != 1024 {
+		t.Fatalf("dry run plans = %+v, want one download of 1024 bytes", plans)
+	}
+	if d := plans[0].Downloads[0]; d.Tag != "v1.0.0" || d.Asset != "app.tar.gz" || d.Cached {
+		t.Errorf("planned download = %+v", d)
+	}
+
+	if err := New(&mockFinder{release: rel}, down, &mockExtractor{}, record).Install(ctx, cfg, release.DefaultFilter()); err != nil {
+		t.Fatalf("Install() error = %v", err)
+	}
+	if down.calls != 1 || len(plans) != 2 {
+		t.Fatalf("install downloaded %d times with %d plans", down.calls, len(plans))
+	}
+
+	declined := errors.New("declined")
+	decline := WithPlan(func(ctx context.Context, plan *DownloadPlan) error {
+		if !plan.Downloads[0].Cached || plan.Total() != 0 {
+			t.Errorf("plan of a cached asset = %+v", plan.Downloads)
+		}
+		return declined
+	})
+	if err := New(&mockFinder{release: rel}, down, &mockExtractor{}, decline).Install(ctx, cfg, release.DefaultFilter()); !errors.Is(err, declined) {
+		t.Errorf("Install() error = %v, want the plan's error", err)
+	}
+}
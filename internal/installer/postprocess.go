@@ -0,0 +1,130 @@
+package installer
+
+import (
+	"context"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/sixban6/ghinstall/internal/config"
+	"github.com/sixban6/ghinstall/internal/release"
+)
+
+// InstallResult describes a repository that has been extracted into its output directory.
+type InstallResult struct {
+	Repo      config.Repo
+	Tag       string
+	Asset     release.Asset
+	OutputDir string
+	// SHA256 is the hex digest of the installed archive.
+	SHA256 string
+	// Release is the selected release.
+	Release release.Release
+	// Extracted summarizes the extracted files; it is zero when they could
+	// not be listed.
+	Extracted ExtractSummary
+	// Download records the retries of the download; it is zero for cached
+	// assets and downloads that succeeded at once.
+	Download DownloadStats
+}
+
+// hookVar is a piece of install metadata passed to hook commands.
+type hookVar struct {
+	name, value string
+}
+
+// hookVars returns the metadata of res passed to hook commands, as the
+// GHINSTALL_<NAME> environment variables and the {name} placeholders of their
+// arguments.
+func hookVars(res InstallResult) []hookVar {
+	var published string
+	if !res.Release.PublishedAt.IsZero() {
+		published = res.Release.PublishedAt.UTC().Format(time.RFC3339)
+	}
+	return []hookVar{
+		{"repo_url", res.Repo.URL},
+		{"repo_name", res.Repo.DisplayName()},
+		{"tag", res.Tag},
+		{"version", strings.TrimPrefix(res.Tag, "v")},
+		{"release_name", res.Release.Name},
+		{"release_url", res.Release.HTMLURL},
+		{"published_at", published},
+		{"prerelease", strconv.FormatBool(res.Release.Prerelease)},
+		{"asset_name", res.Asset.Name},
+		{"asset_url", res.Asset.URL},
+		{"asset_size", strconv.FormatInt(res.Asset.Size, 10)},
+		{"asset_content_type", res.Asset.ContentType},
+		{"asset_sha256", res.SHA256},
+		{"output_dir", res.OutputDir},
+	}
+}
+
+// hookEnv returns the environment variables of hookVars.
+func hookEnv(res InstallResult) []string {
+	vars := hookVars(res)
+	env := make([]string, len(vars))
+	for n, v := range vars {
+		env[n] = "GHINSTALL_" + strings.ToUpper(v.name) + "=" + v.value
+	}
+	return env
+}
+
+// expandHookArgs replaces the {name} placeholders of hookVars in args, so
+// hooks can stamp renamed files and symlinks with the installed version.
+func expandHookArgs(args []string, res InstallResult) []string {
+	vars := hookVars(res)
+	pairs := make([]string, 0, 2*len(vars))
+	for _, v := range vars {
+		pairs = append(pairs, "{"+v.name+"}", v.value)
+	}
+	r := strings.NewReplacer(pairs...)
+	expanded := make([]string, len(args))
+	for n, arg := range args {
+		expanded[n] = r.Replace(arg)
+	}
+	return expanded
+}
+
+// PostProcessor runs after extraction, e.g. to re-sign binaries, apply patches
+// or generate shims. Processors run in registration order; the first error
+// fails the install of that repository.
+type PostProcessor interface {
+	Process(ctx context.Context, dir string, res InstallResult) error
+}
+
+// PostProcessorFunc adapts a function to the PostProcessor interface.
+type PostProcessorFunc func(ctx context.Context, dir string, res InstallResult) error
+
+func (f PostProcessorFunc) Process(ctx context.Context, dir string, res InstallResult) error {
+	return f(ctx, dir, res)
+}
+
+// ExecPostProcessor runs an external command in the extracted directory. The
+// install result and the selected release are passed through GHINSTALL_*
+// environment variables and {name} placeholders in the arguments. In
+// purego and wasip1 builds, which never start processes, it always fails.
+type ExecPostProcessor struct {
+	Command []string
+}
+
+// postProcessors returns the registered processors followed by the exec hooks
+// configured globally and for repo.
+func (i *Installer) postProcessors(cfg *config.Config, repo config.Repo) []PostProcessor {
+	chain := append([]PostProcessor(nil), i.processors...)
+	for _, hook := range cfg.PostProcessors {
+		chain = append(chain, ExecPostProcessor{Command: hook.Command})
+	}
+	for _, hook := range repo.PostProcessors {
+		chain = append(chain, ExecPostProcessor{Command: hook.Command})
+	}
+	return chain
+}
+
+func (i *Installer) runPostProcessors(ctx context.Context, cfg *config.Config, res InstallResult) error {
+	for _, p := range i.postProcessors(cfg, res.Repo) {
+		if err := p.Process(ctx, res.OutputDir, res); err != nil {
+			return err
+		}
+	}
+	return nil
+}
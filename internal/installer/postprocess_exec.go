@@ -0,0 +1,32 @@
+//go:build !purego && !wasip1
+
+package installer
+
+import (
+	"context"
+	"fmt"
+	log "github.com/sixban6/ghinstall/internal/logger"
+	"os"
+	"os/exec"
+	"strings"
+)
+
+func (p ExecPostProcessor) Process(ctx context.Context, dir string, res InstallResult) error {
+	if len(p.Command) == 0 {
+		return fmt.Errorf("post-processor command is empty")
+	}
+
+	args := expandHookArgs(p.Command, res)
+	cmd := exec.CommandContext(ctx, args[0], args[1:]...)
+	cmd.Dir = dir
+	cmd.Env = append(os.Environ(), hookEnv(res)...)
+
+	output, err := cmd.CombinedOutput()
+	if out := strings.TrimSpace(string(output)); out != "" {
+		log.Info("[%s] %s", p.Command[0], out)
+	}
+	if err != nil {
+		return fmt.Errorf("post-processor %s failed: %w", strings.Join(p.Command, " "), err)
+	}
+	return nil
+}
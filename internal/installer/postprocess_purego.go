@@ -0,0 +1,13 @@
+//go:build purego || wasip1
+
+package installer
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+func (p ExecPostProcessor) Process(ctx context.Context, dir string, res InstallResult) error {
+	return fmt.Errorf("post-processor %s cannot run: this build of ghinstall (purego or wasip1) does not start processes", strings.Join(p.Command, " "))
+}
@@ -0,0 +1,123 @@
+package installer
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
+	log "github.com/sixban6/ghinstall/internal/logger"
+	"io"
+
+	"github.com/sixban6/ghinstall/internal/cache"
+	"github.com/sixban6/ghinstall/internal/config"
+	"github.com/sixban6/ghinstall/internal/provider"
+	"github.com/sixban6/ghinstall/internal/release"
+)
+
+// PrefetchResult reports the caching of the asset selected for one repository.
+type PrefetchResult struct {
+	Repo  config.Repo
+	Tag   string
+	Asset string
+	// SHA256 is the digest of the cached asset.
+	SHA256 string
+	// Path is the cached asset.
+	Path string
+	// Cached is set when the asset was already in the cache.
+	Cached bool
+	// Err is set when the asset could not be cached.
+	Err error
+}
+
+// WithPlatform makes Prefetch select the assets for platform, a GOOS/GOARCH
+// such as "linux/arm64", instead of this host, e.g. to copy them to other
+// machines. The filter given to Prefetch must select for platform as well.
+func WithPlatform(platform string) Option {
+	return func(i *Installer) {
+		i.platform = platform
+	}
+}
+
+// Prefetch resolves every repository like an install would and downloads the
+// selected asset into the download cache, without extracting it or touching
+// the output directories, so later installs from the same cache need no
+// download. It requires cache_dir.
+func (i *Installer) Prefetch(ctx context.Context, cfg *config.Config, filter release.AssetFilter) ([]PrefetchResult, error) {
+	if cfg.CacheDir == "" {
+		return nil, errors.New("prefetching requires cache_dir")
+	}
+	c, err := cache.Open(cache.ResolveDir(cfg.CacheDir), cfg.SharedCache())
+	if err != nil {
+		return nil, err
+	}
+	c.SetLockTimeout(cfg.GetLockTimeout())
+	i.configureHTTPCache(cfg)
+	i.configureAPIBudget(cfg)
+	configureFDLimits(cfg)
+
+	results := make([]PrefetchResult, 0, len(cfg.Github))
+	for _, repo := range cfg.Github {
+		res := PrefetchResult{Repo: repo}
+		if res.Err = i.prefetchRepo(ctx, cfg, c, repo, filter, &res); res.Err != nil {
+			log.Error("Failed to prefetch %s: %v", repo.DisplayName(), res.Err)
+		}
+		results = append(results, res)
+	}
+	return results, nil
+}
+
+func (i *Installer) prefetchRepo(ctx context.Context, cfg *config.Config, c *cache.Cache, repo config.Repo, filter release.AssetFilter, res *PrefetchResult) error {
+	var src provider.Provider
+	if repo.Provider != "" {
+		var err error
+		if src, err = provider.ForRepo(cfg, repo); err != nil {
+			return err
+		}
+	}
+
+	rel, err := i.resolve(ctx, repo, src)
+	if err != nil {
+		return fmt.Errorf("failed to find latest release: %w", err)
+	}
+	asset, err := selectAssetOn(cfg, repo, rel, filter, cmp.Or(i.platform, hostPlatform))
+	if err != nil {
+		return fmt.Errorf("no suitable asset found in release %s: %w", rel.TagName, err)
+	}
+	res.Tag, res.Asset = rel.TagName, asset.Name
+
+	cacheKey := asset.URL
+	want := expectation{sha256: repo.SHA256}
+	var download func() (io.ReadCloser, error)
+	if src != nil {
+		cacheKey = providerCacheKey(repo, rel, asset)
+		download = func() (io.ReadCloser, error) {
+			log.Info("Downloading %s via provider %s", asset.Name, repo.Provider)
+			return src.Download(ctx, *asset)
+		}
+	} else {
+		var downloadURL string
+		downloadURL, want = i.downloadSource(cfg, repo, asset)
+		download = func() (io.ReadCloser, error) {
+			return i.downloadFrom(ctx, cfg, asset, downloadURL)
+		}
+	}
+
+	_, res.Cached = c.Lookup(cacheKey)
+	f, digest, err := c.Fetch(ctx, cacheKey, func() (io.ReadCloser, error) {
+		rc, err := download()
+		if err != nil {
+			return nil, err
+		}
+		return want.wrap(rc), nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed to download asset: %w", err)
+	}
+	f.Close()
+
+	if repo.SHA256 != "" && digest != repo.SHA256 {
+		return fmt.Errorf("checksum mismatch for %s: expected sha256 %s, got %s", asset.Name, repo.SHA256, digest)
+	}
+	res.SHA256, res.Path = digest, c.BlobPath(digest)
+	return nil
+}
@@ -0,0 +1,96 @@
+package installer
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/sixban6/ghinstall/internal/config"
+	"github.com/sixban6/ghinstall/internal/release"
+)
+
+func TestInstaller_Prefetch(t *testing.T) {
+	directReachable = func(context.Context) bool { return true }
+	defer func() { directReachable = PingGoogle }()
+
+	mockRel := &release.Release{
+		TagName: "v1.0.0",
+		Assets: []release.Asset{
+			{Name: "app.tar.gz", URL: "https://github.com/owner/repo/releases/download/v1.0.0/app.tar.gz", Size: 12},
+		},
+	}
+	outputDir := filepath.Join(t.TempDir(), "out")
+	cfg := &config.Config{
+		Github:   []config.Repo{{URL: "https://github.com/owner/repo", OutputDir: outputDir}},
+		CacheDir: t.TempDir(),
+	}
+
+	down := &countingDownloader{content: "test content"}
+	ext := &mockExtractor{}
+	inst := New(&mockFinder{release: mockRel}, down, ext)
+
+	results, err := inst.Prefetch(context.Background(), cfg, release.DefaultFilter())
+	if err != nil {
+		t.Fatalf("Prefetch() error = %v", err)
+	}
+	if len(results) != 1 || results[0].Err != nil || results[0].Cached || results[0].SHA256 != "6ae8a75555209fd6c44157c0aed8016e763ff435a19cf186f76863140143ff72" {
+		t.Fatalf("Prefetch() = %+v", results)
+	}
+	if ext.extractedTo != "" {
+		t.Errorf("Prefetch() extracted to %s", ext.extractedTo)
+	}
+	if _, err := os.Stat(outputDir); !os.IsNotExist(err) {
+		t.Errorf("Prefetch() touched the output directory: %v", err)
+	}
+
+	results, _ = inst.Prefetch(context.Background(), cfg, release.DefaultFilter())
+	if !results[0].Cached {
+		t.Errorf("second Prefetch() = %+v, want cached", results)
+	}
+	if err := inst.Install(context.Background(), cfg, release.DefaultFilter()); err != nil {
+		t.Fatalf("Install() error = %v", err)
+	}
+	if down.calls != 1 {
+		t.Errorf("got %d downloads, want the prefetched asset reused", down.calls)
+	}
+
+	cfg.CacheDir = ""
+	if _, err := inst.Prefetch(context.Background(), cfg, release.DefaultFilter()); err == nil {
+		t.Error("Prefetch() without cache_dir should fail")
+	}
+}
+
+func TestInstaller_Prefetch_Platform(t *testing.T) {
+	directReachable = func(context.Context) bool { return true }
+	defer func() { directReachable = PingGoogle }()
+
+	mockRel := &release.Release{
+		TagName: "v1.0.0",
+		Assets: []release.Asset{
+			{Name: "app_linux_amd64.tar.gz", URL: "https://github.com/owner/repo/releases/download/v1.0.0/app_linux_amd64.tar.gz"},
+			{Name: "app_freebsd_riscv64.tar.gz", URL: "https://github.com/owner/repo/releases/download/v1.0.0/app_freebsd_riscv64.tar.gz"},
+		},
+	}
+	cfg := &config.Config{
+		Github: []config.Repo{
+			{URL: "https://github.com/owner/repo", OutputDir: t.TempDir()},
+			{URL: "https://github.com/owner/other", OutputDir: t.TempDir(), AssetPattern: `_{os}_{arch}\.tar\.gz$`},
+		},
+		CacheDir: t.TempDir(),
+	}
+
+	inst := New(&mockFinder{release: mockRel}, &mockDownloader{content: "archive"}, nil, WithPlatform("freebsd/riscv64"))
+	results, err := inst.Prefetch(context.Background(), cfg, release.ByPlatform("freebsd", "riscv64"))
+	if err != nil {
+		t.Fatalf("Prefetch() error = %v", err)
+	}
+	for _, res := range results {
+		if res.Err != nil || res.Asset != "app_freebsd_riscv64.tar.gz" {
+			t.Errorf("Prefetch() of %s = %+v, want the freebsd/riscv64 asset", res.Repo.URL, res)
+		}
+		if _, err := os.Stat(res.Path); err != nil {
+			t.Errorf("cached asset: %v", err)
+		}
+	}
+}
@@ -0,0 +1,39 @@
+package installer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+
+	"github.com/sixban6/ghinstall/internal/downloader"
+	log "github.com/sixban6/ghinstall/internal/logger"
+	"github.com/sixban6/ghinstall/internal/neterr"
+	"github.com/sixban6/ghinstall/internal/release"
+)
+
+// downloadPrivate downloads asset through the GitHub API with the token when
+// err, from downloading its browser download URL, is a 404: the answer for the
+// assets of private repositories. Other errors are returned as they are.
+func (i *Installer) downloadPrivate(ctx context.Context, asset *release.Asset, err error) (io.ReadCloser, error) {
+	var status *neterr.StatusError
+	if !errors.As(err, &status) || status.Code != http.StatusNotFound || asset.APIURL == "" {
+		return nil, err
+	}
+	ac, ok := i.downloader.(downloader.AssetClient)
+	if !ok {
+		return nil, err
+	}
+	token := release.Token()
+	if token == "" {
+		return nil, fmt.Errorf("%w (set GITHUB_TOKEN to download the assets of private repositories)", err)
+	}
+
+	log.Info("%s is not public, downloading it through the GitHub API", asset.Name)
+	rc, apiErr := ac.DownloadAsset(ctx, asset.APIURL, token)
+	if apiErr != nil {
+		return nil, fmt.Errorf("failed to download %s through the GitHub API: %w", asset.Name, apiErr)
+	}
+	return rc, nil
+}
@@ -0,0 +1,56 @@
+package installer
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/sixban6/ghinstall/internal/config"
+	"github.com/sixban6/ghinstall/internal/downloader"
+	"github.com/sixban6/ghinstall/internal/release"
+)
+
+func TestInstaller_Install_PrivateAsset(t *testing.T) {
+	directReachable = func(context.Context) bool { return true }
+	defer func() { directReachable = PingGoogle }()
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/owner/repo/releases/download/v1.0.0/app.tar.gz", http.NotFound)
+	mux.HandleFunc("/api/repos/owner/repo/releases/assets/7", func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer secret" || r.Header.Get("Accept") != "application/octet-stream" {
+			http.NotFound(w, r)
+			return
+		}
+		http.Redirect(w, r, "/storage/app.tar.gz?signature=x", http.StatusFound)
+	})
+	mux.HandleFunc("/storage/app.tar.gz", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("private archive"))
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	rel := &release.Release{TagName: "v1.0.0", Assets: []release.Asset{{
+		Name:   "app.tar.gz",
+		URL:    server.URL + "/owner/repo/releases/download/v1.0.0/app.tar.gz",
+		APIURL: server.URL + "/api/repos/owner/repo/releases/assets/7",
+	}}}
+	cfg := &config.Config{Github: []config.Repo{{URL: "https://github.com/owner/repo", OutputDir: t.TempDir()}}}
+
+	t.Setenv("GITHUB_TOKEN", "")
+	t.Setenv("GH_TOKEN", "")
+	err := New(&mockFinder{release: rel}, downloader.NewHTTPClient(), &readingExtractor{}).Install(context.Background(), cfg, release.DefaultFilter())
+	if err == nil || !strings.Contains(err.Error(), "GITHUB_TOKEN") {
+		t.Errorf("Install() without a token error = %v, want a hint to set GITHUB_TOKEN", err)
+	}
+
+	t.Setenv("GITHUB_TOKEN", "secret")
+	ext := &readingExtractor{}
+	if err := New(&mockFinder{release: rel}, downloader.NewHTTPClient(), ext).Install(context.Background(), cfg, release.DefaultFilter()); err != nil {
+		t.Fatalf("Install() error = %v", err)
+	}
+	if string(ext.content) != "private archive" {
+		t.Errorf("installed content = %q, want the private archive", ext.content)
+	}
+}
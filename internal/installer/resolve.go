@@ -0,0 +1,66 @@
+package installer
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/sixban6/ghinstall/internal/config"
+	"github.com/sixban6/ghinstall/internal/provider"
+	"github.com/sixban6/ghinstall/internal/release"
+)
+
+// ResolvedRelease describes what installing a repository would install.
+type ResolvedRelease struct {
+	Repo    config.Repo
+	Tag     string
+	Release *release.Release
+	Asset   release.Asset
+	// DownloadURL is where the asset would be downloaded from: its GitHub URL
+	// or, when GitHub is not reachable directly, the mirror.
+	DownloadURL string
+	// Mirrored is set when DownloadURL is a mirror.
+	Mirrored bool
+	// SHA256 is the hex digest the asset must have: the configured sha256 or
+	// the digest GitHub reports, "" when neither is known.
+	SHA256 string
+}
+
+// Resolve looks up the release and selects the asset an install of repo
+// would, and decides where it would be downloaded from, without downloading.
+func (i *Installer) Resolve(ctx context.Context, cfg *config.Config, repo config.Repo, filter release.AssetFilter) (*ResolvedRelease, error) {
+	i.configureHTTPCache(cfg)
+	configureFDLimits(cfg)
+	var src provider.Provider
+	if repo.Provider != "" {
+		var err error
+		if src, err = provider.ForRepo(cfg, repo); err != nil {
+			return nil, err
+		}
+	}
+
+	rel, err := i.resolve(ctx, repo, src)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find latest release: %w", err)
+	}
+	asset, err := selectAsset(cfg, repo, rel, filter)
+	if err != nil {
+		return nil, fmt.Errorf("no suitable asset found in release %s: %w", rel.TagName, err)
+	}
+
+	res := &ResolvedRelease{
+		Repo:        repo,
+		Tag:         rel.TagName,
+		Release:     rel,
+		Asset:       *asset,
+		DownloadURL: asset.URL,
+		SHA256:      repo.SHA256,
+	}
+	if src == nil {
+		res.DownloadURL, _ = i.downloadSource(cfg, repo, asset)
+		res.Mirrored = res.DownloadURL != asset.URL
+	}
+	if res.SHA256 == "" {
+		res.SHA256 = githubDigest(asset.Digest)
+	}
+	return res, nil
+}
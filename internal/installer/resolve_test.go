@@ -0,0 +1,72 @@
+package installer
+
+import (
+	"context"
+	"testing"
+
+	"github.com/sixban6/ghinstall/internal/config"
+	"github.com/sixban6/ghinstall/internal/release"
+)
+
+func TestInstaller_Resolve(t *testing.T) {
+	const assetURL = "https://github.com/owner/repo/releases/download/v1.0.0/app.tar.gz"
+	const digest = "0123456789abcdef0123456789abcdef0123456789abcdef0123456789abcdef"
+	rel := &release.Release{TagName: "v1.0.0", Assets: []release.Asset{
+		{Name: "app.tar.gz", URL: assetURL, Size: 42, Digest: "sha256:" + digest},
+	}}
+
+	tests := []struct {
+		name         string
+		direct       bool
+		repo         config.Repo
+		wantURL      string
+		wantMirrored bool
+		wantSHA256   string
+	}{
+		{
+			name:       "direct",
+			direct:     true,
+			repo:       config.Repo{URL: "https://github.com/owner/repo"},
+			wantURL:    assetURL,
+			wantSHA256: digest,
+		},
+		{
+			name:         "mirror",
+			repo:         config.Repo{URL: "https://github.com/owner/repo"},
+			wantURL:      "https://mirror.example.com/" + assetURL,
+			wantMirrored: true,
+			wantSHA256:   digest,
+		},
+		{
+			name:       "configured digest wins",
+			direct:     true,
+			repo:       config.Repo{URL: "https://github.com/owner/repo", SHA256: "ff"},
+			wantURL:    assetURL,
+			wantSHA256: "ff",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			directReachable = func(context.Context) bool { return tt.direct }
+			defer func() { directReachable = PingGoogle }()
+
+			cfg := &config.Config{MirrorURL: "https://mirror.example.com"}
+			// Resolving must not download anything.
+			inst := New(&mockFinder{release: rel}, urlDownloader{}, &mockExtractor{})
+			got, err := inst.Resolve(context.Background(), cfg, tt.repo, release.DefaultFilter())
+			if err != nil {
+				t.Fatalf("Resolve() error = %v", err)
+			}
+			if got.Tag != "v1.0.0" || got.Asset.Name != "app.tar.gz" {
+				t.Errorf("Resolve() = %s %s", got.Tag, got.Asset.Name)
+			}
+			if got.DownloadURL != tt.wantURL || got.Mirrored != tt.wantMirrored {
+				t.Errorf("DownloadURL = %s (mirrored %v), want %s (mirrored %v)", got.DownloadURL, got.Mirrored, tt.wantURL, tt.wantMirrored)
+			}
+			if got.SHA256 != tt.wantSHA256 {
+				t.Errorf("SHA256 = %s, want %s", got.SHA256, tt.wantSHA256)
+			}
+		})
+	}
+}
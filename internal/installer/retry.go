@@ -0,0 +1,274 @@
+package installer
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"net/url"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/sixban6/ghinstall/internal/config"
+	"github.com/sixban6/ghinstall/internal/downloader"
+	log "github.com/sixban6/ghinstall/internal/logger"
+	"github.com/sixban6/ghinstall/internal/neterr"
+	"github.com/sixban6/ghinstall/internal/release"
+)
+
+// maxDownloadAttempts bounds the attempts to download an asset.
+const maxDownloadAttempts = 3
+
+// maxRetryAfter bounds how long a Retry-After header can delay a retry.
+const maxRetryAfter = time.Minute
+
+// retryDelay returns how long to wait before the retry following attempt.
+// Tests replace it.
+var retryDelay = defaultRetryDelay
+
+// defaultRetryDelay waits after attempt, which failed with err, as long as a
+// rate-limiting server asked, and otherwise a second longer each time.
+func defaultRetryDelay(attempt int, err error) time.Duration {
+	var statusErr *neterr.StatusError
+	if errors.As(err, &statusErr) && statusErr.RetryAfter > 0 {
+		return min(statusErr.RetryAfter, maxRetryAfter)
+	}
+	return time.Duration(attempt) * time.Second
+}
+
+// DownloadStats describes how the asset of a repository was downloaded, to
+// tell flaky mirrors from slow ones.
+type DownloadStats struct {
+	// Retries is the number of attempts after the first.
+	Retries int
+	// Sources lists where the asset was downloaded from, in the order first
+	// tried: "direct" for GitHub, the host of a mirror or rewritten URL.
+	Sources []string
+	// WastedBytes counts the bytes of failed attempts, and those downloaded
+	// again to resume after them.
+	WastedBytes int64
+}
+
+// String describes the retries, such as "2 retries via direct, ghfast.top,
+// 1.50 MB wasted", or returns "" without retries.
+func (s DownloadStats) String() string {
+	if s.Retries == 0 {
+		return ""
+	}
+	retries := "1 retry"
+	if s.Retries > 1 {
+		retries = fmt.Sprintf("%d retries", s.Retries)
+	}
+	return fmt.Sprintf("%s via %s, %.2f MB wasted", retries, strings.Join(s.Sources, ", "), float64(s.WastedBytes)/(1024*1024))
+}
+
+// DownloadError is the error of a download that failed, after retries.
+type DownloadError struct {
+	Stats DownloadStats
+	Err   error
+}
+
+func (e *DownloadError) Error() string {
+	if s := e.Stats.String(); s != "" {
+		return fmt.Sprintf("%v (%s)", e.Err, s)
+	}
+	return e.Err.Error()
+}
+
+func (e *DownloadError) Unwrap() error {
+	return e.Err
+}
+
+// downloadSources returns the URLs to download asset from in turn: first
+// downloadURL, chosen by downloadSource, then GitHub when it is the mirror or
+// the mirror when it is GitHub. Rewritten URLs are not second-guessed.
+func (i *Installer) downloadSources(cfg *config.Config, repo config.Repo, asset *release.Asset, downloadURL string) []string {
+	if _, rewritten := i.rewrite(asset.URL); rewritten {
+		return []string{downloadURL}
+	}
+	if downloadURL != asset.URL {
+		return []string{downloadURL, asset.URL}
+	}
+	if mirrored := cfg.GetDownloadURL(repo.URL, asset.URL); mirrored != asset.URL {
+		return []string{downloadURL, mirrored}
+	}
+	return []string{downloadURL}
+}
+
+// downloadRetrying downloads asset from sources, retrying failures up to
+// maxDownloadAttempts times and recording them in stats.
+func (i *Installer) downloadRetrying(ctx context.Context, cfg *config.Config, asset *release.Asset, sources []string, stats *DownloadStats) (io.ReadCloser, error) {
+	r := &retryReader{
+		ctx:     ctx,
+		asset:   asset,
+		sources: sources,
+		stats:   stats,
+		prefix:  sha256.New(),
+		open: func(url string) (io.ReadCloser, error) {
+			return i.downloadFrom(ctx, cfg, asset, url)
+		},
+	}
+	if err := r.next(nil); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// retryReader reads an asset, reopening it when a read fails midway so the
+// consumer only sees a failure once the retries are exhausted. An attempt
+// that made progress is resumed from the same source; otherwise the next
+// source is tried. Either way the asset is read again from its first byte,
+// and the part already handed to the consumer must come out the same: a
+// source serving other content fails the download rather than being spliced
+// onto what was read from another one.
+type retryReader struct {
+	ctx     context.Context
+	asset   *release.Asset
+	sources []string
+	open    func(url string) (io.ReadCloser, error)
+	stats   *DownloadStats
+
+	rc       io.ReadCloser
+	attempt  int
+	source   int       // index of the source of the current attempt
+	start    int64     // bytes handed to the consumer before it
+	read     int64     // bytes handed to the consumer
+	received int64     // bytes received over all attempts
+	prefix   hash.Hash // digest of the bytes handed to the consumer
+}
+
+// next opens the next attempt after the failure err, nil for the first.
+func (r *retryReader) next(err error) error {
+	for {
+		if r.attempt > 0 {
+			if r.attempt >= maxDownloadAttempts || !retryable(r.ctx, err) {
+				return r.fail(err)
+			}
+			wait := retryDelay(r.attempt, err)
+			log.Warn("Failed to download %s (%v); retrying in %s", r.asset.Name, err, wait)
+			select {
+			case <-time.After(wait):
+			case <-r.ctx.Done():
+				return r.fail(r.ctx.Err())
+			}
+			r.stats.Retries++
+			if r.read == r.start {
+				r.source = (r.source + 1) % len(r.sources)
+			}
+		}
+		source := r.sources[r.source]
+		r.attempt++
+		r.start = r.read
+		if label := sourceLabel(r.asset, source); !slices.Contains(r.stats.Sources, label) {
+			r.stats.Sources = append(r.stats.Sources, label)
+		}
+
+		rc, oerr := r.open(source)
+		if oerr != nil {
+			err = oerr
+			continue
+		}
+		if r.read > 0 {
+			if serr := r.skip(rc, source); serr != nil {
+				rc.Close()
+				err = serr
+				continue
+			}
+		}
+		r.rc = rc
+		r.stats.WastedBytes = r.received - r.read
+		return nil
+	}
+}
+
+// skip reads the part of rc, reopened from source, that was already handed
+// to the consumer, and checks that it is what the consumer got.
+func (r *retryReader) skip(rc io.Reader, source string) error {
+	h := sha256.New()
+	n, err := io.CopyN(h, rc, r.read)
+	r.received += n
+	if err != nil {
+		return err
+	}
+	if !bytes.Equal(h.Sum(nil), r.prefix.Sum(nil)) {
+		return mismatchError{fmt.Errorf("%s serves other content of %s than the attempt it resumes, in the first %d bytes", sourceLabel(r.asset, source), r.asset.Name, r.read)}
+	}
+	return nil
+}
+
+// fail returns the DownloadError ending the download with err. Everything
+// received was wasted.
+func (r *retryReader) fail(err error) error {
+	r.stats.WastedBytes = r.received
+	return &DownloadError{Stats: *r.stats, Err: err}
+}
+
+func (r *retryReader) Read(p []byte) (int, error) {
+	for {
+		n, err := r.rc.Read(p)
+		r.prefix.Write(p[:n])
+		r.read += int64(n)
+		r.received += int64(n)
+		if err == nil || err == io.EOF {
+			return n, err
+		}
+		r.rc.Close()
+		if nerr := r.next(err); nerr != nil {
+			r.rc = errReader{nerr}
+			return n, nerr
+		}
+		if n > 0 {
+			return n, nil
+		}
+	}
+}
+
+func (r *retryReader) Close() error {
+	return r.rc.Close()
+}
+
+// ETag returns the entity tag the current attempt was served with.
+func (r *retryReader) ETag() string {
+	if e, ok := r.rc.(etagged); ok {
+		return e.ETag()
+	}
+	return ""
+}
+
+// errReader fails every read with err once a retryReader gave up.
+type errReader struct{ err error }
+
+func (e errReader) Read([]byte) (int, error) { return 0, e.err }
+func (e errReader) Close() error             { return nil }
+
+// retryable reports whether a download failing with err may succeed when
+// tried again: not when it was cancelled, refused, not found or tampered with.
+func retryable(ctx context.Context, err error) bool {
+	if ctx.Err() != nil || errors.Is(err, downloader.ErrInsecureRedirect) || errors.Is(err, downloader.ErrTooManyRedirects) {
+		return false
+	}
+	if errors.As(err, new(mismatchError)) {
+		return false
+	}
+	var statusErr *neterr.StatusError
+	if errors.As(err, &statusErr) {
+		return statusErr.RateLimited || statusErr.Code >= 500 || statusErr.Code == 408
+	}
+	return neterr.Classify(err) != neterr.TLS
+}
+
+// sourceLabel names the source of downloadURL: "direct" for the GitHub URL
+// of asset, the host otherwise.
+func sourceLabel(asset *release.Asset, downloadURL string) string {
+	if downloadURL == asset.URL {
+		return "direct"
+	}
+	if u, err := url.Parse(downloadURL); err == nil && u.Host != "" {
+		return u.Host
+	}
+	return downloadURL
+}
@@ -0,0 +1,168 @@
+package installer
+
+import (
+	"context"
+	"errors"
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/sixban6/ghinstall/internal/config"
+	"github.com/sixban6/ghinstall/internal/neterr"
+	"github.com/sixban6/ghinstall/internal/release"
+)
+
+// flakyDownloader serves content from every URL but those in other, failing
+// the first reads of the URLs in fail after after bytes.
+type flakyDownloader struct {
+	content string
+	other   map[string]string
+	after   int
+	fail    map[string]int
+}
+
+func (f *flakyDownloader) Download(ctx context.Context, url string) (io.ReadCloser, error) {
+	content, ok := f.other[url]
+	if !ok {
+		content = f.content
+	}
+	if f.fail[url] == 0 {
+		return io.NopCloser(strings.NewReader(content)), nil
+	}
+	f.fail[url]--
+	return io.NopCloser(io.MultiReader(strings.NewReader(content[:f.after]), errReader{errors.New("connection reset by peer")})), nil
+}
+
+func TestInstaller_Install_Retry(t *testing.T) {
+	directReachable = func(context.Context) bool { return true }
+	defer func() { directReachable = PingGoogle }()
+
+	const assetURL = "https://github.com/owner/repo/releases/download/v1.0.0/app.tar.gz"
+	const mirrorURL = "https://mirror.example/" + assetURL
+	rel := &release.Release{TagName: "v1.0.0", Assets: []release.Asset{{Name: "app.tar.gz", URL: assetURL}}}
+
+	tests := []struct {
+		name      string
+		fail      map[string]int
+		other     map[string]string
+		wantStats DownloadStats
+		wantErr   bool
+	}{
+		{name: "no failure", fail: map[string]int{}, wantStats: DownloadStats{Sources: []string{"direct"}}},
+		{
+			name:      "resumes from the same source",
+			fail:      map[string]int{assetURL: 1},
+			wantStats: DownloadStats{Retries: 1, Sources: []string{"direct"}, WastedBytes: 4},
+		},
+		{
+			name:      "falls back to the mirror without progress",
+			fail:      map[string]int{assetURL: 2},
+			wantStats: DownloadStats{Retries: 2, Sources: []string{"direct", "mirror.example"}, WastedBytes: 8},
+		},
+		{
+			name:      "exhausted",
+			fail:      map[string]int{assetURL: 2, mirrorURL: 1},
+			wantStats: DownloadStats{Retries: 2, Sources: []string{"direct", "mirror.example"}, WastedBytes: 12},
+			wantErr:   true,
+		},
+		{
+			// Splicing the mirror onto the start from GitHub would extract "testMIRROR content".
+			name:      "mirror serves other content",
+			fail:      map[string]int{assetURL: 2},
+			other:     map[string]string{mirrorURL: "MIRROR content"},
+			wantStats: DownloadStats{Retries: 2, Sources: []string{"direct", "mirror.example"}, WastedBytes: 12},
+			wantErr:   true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &config.Config{
+				Github:    []config.Repo{{URL: "https://github.com/owner/repo", OutputDir: t.TempDir()}},
+				MirrorURL: "https://mirror.example",
+			}
+			down := &flakyDownloader{content: "test content", other: tt.other, after: 4, fail: tt.fail}
+			ext := &readingExtractor{}
+			var got DownloadStats
+			mw := MiddlewareFunc(func(ctx context.Context, step Step) error {
+				if step.Stage == AfterExtract {
+					got = step.Result.Download
+				}
+				return nil
+			})
+
+			err := New(&mockFinder{release: rel}, down, ext, WithMiddleware(mw)).Install(context.Background(), cfg, release.DefaultFilter())
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Install() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				var dlErr *DownloadError
+				if !errors.As(err, &dlErr) {
+					t.Fatalf("Install() error = %v, want a DownloadError", err)
+				}
+				got = dlErr.Stats
+				if tt.other != nil && !errors.As(err, new(mismatchError)) {
+					t.Errorf("Install() error = %v, want a content mismatch", err)
+				}
+			} else if string(ext.content) != "test content" {
+				t.Errorf("extracted %q, want the whole asset", ext.content)
+			}
+			if !reflect.DeepEqual(got, tt.wantStats) {
+				t.Errorf("download stats = %#v, want %#v", got, tt.wantStats)
+			}
+		})
+	}
+}
+
+func TestRetryable(t *testing.T) {
+	ctx := context.Background()
+	cancelled, cancel := context.WithCancel(ctx)
+	cancel()
+
+	tests := []struct {
+		name string
+		ctx  context.Context
+		err  error
+		want bool
+	}{
+		{"reset", ctx, errors.New("connection reset by peer"), true},
+		{"server error", ctx, &neterr.StatusError{Code: 502}, true},
+		{"rate limited", ctx, &neterr.StatusError{Code: 429, RateLimited: true}, true},
+		{"not found", ctx, &neterr.StatusError{Code: 404}, false},
+		{"mirror mismatch", ctx, mismatchError{errors.New("mirror served different content")}, false},
+		{"cancelled", cancelled, errors.New("connection reset by peer"), false},
+	}
+	for _, tt := range tests {
+		if got := retryable(tt.ctx, tt.err); got != tt.want {
+			t.Errorf("%s: retryable() = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestRetryDelay(t *testing.T) {
+	// TestMain disables the delays of the other tests.
+	delay := retryDelay
+	defer func() { retryDelay = delay }()
+	retryDelay = defaultRetryDelay
+
+	if got := retryDelay(2, errors.New("connection reset by peer")); got != 2*time.Second {
+		t.Errorf("retryDelay() = %s, want 2s", got)
+	}
+	if got := retryDelay(1, &neterr.StatusError{Code: 429, RetryAfter: 30 * time.Second}); got != 30*time.Second {
+		t.Errorf("retryDelay() = %s, want the 30s of Retry-After", got)
+	}
+	if got := retryDelay(1, &neterr.StatusError{Code: 429, RetryAfter: time.Hour}); got != maxRetryAfter {
+		t.Errorf("retryDelay() = %s, want at most %s", got, maxRetryAfter)
+	}
+}
+
+func TestDownloadStats_String(t *testing.T) {
+	if got := (DownloadStats{Sources: []string{"direct"}}).String(); got != "" {
+		t.Errorf("String() = %q without retries, want empty", got)
+	}
+	stats := DownloadStats{Retries: 2, Sources: []string{"direct", "ghfast.top"}, WastedBytes: 3 << 19}
+	if got, want := stats.String(), "2 retries via direct, ghfast.top, 1.50 MB wasted"; got != want {
+		t.Errorf("String() = %q, want %q", got, want)
+	}
+}
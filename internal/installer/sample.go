@@ -0,0 +1,76 @@
+package installer
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+
+	"github.com/sixban6/ghinstall/internal/downloader"
+	log "github.com/sixban6/ghinstall/internal/logger"
+	"github.com/sixban6/ghinstall/internal/release"
+)
+
+// sampleMirror compares the beginning of rc, the download of asset from the
+// mirror, with the first n bytes of asset fetched directly from GitHub. A
+// mirror serving other content is caught before the rest of the asset is
+// downloaded from it. The returned reader yields the whole mirrored content.
+func (i *Installer) sampleMirror(ctx context.Context, asset *release.Asset, rc io.ReadCloser, n int64) (io.ReadCloser, error) {
+	direct, err := i.downloadPrefix(ctx, asset.URL, n)
+	if err != nil {
+		rc.Close()
+		return nil, fmt.Errorf("failed to sample %s from GitHub: %w", asset.Name, err)
+	}
+
+	mirrored := make([]byte, len(direct))
+	if _, err := io.ReadFull(rc, mirrored); err != nil {
+		rc.Close()
+		return nil, mismatchError{fmt.Errorf("mirror sent less of %s than GitHub: %w", asset.Name, err)}
+	}
+	if !bytes.Equal(mirrored, direct) {
+		rc.Close()
+		return nil, mismatchError{fmt.Errorf("mirror content of %s differs from GitHub in the first %d bytes", asset.Name, len(direct))}
+	}
+
+	log.Info("The first %d bytes of %s from the mirror match GitHub", len(direct), asset.Name)
+	return &prefixedReader{ReadCloser: rc, r: io.MultiReader(bytes.NewReader(mirrored), rc)}, nil
+}
+
+// mismatchError is returned for mirror content that does not match GitHub,
+// which is not retried: the mirror is not trusted to have recovered.
+type mismatchError struct{ error }
+
+func (e mismatchError) Unwrap() error { return e.error }
+
+// downloadPrefix returns the first n bytes of url, with a range request when
+// the downloader supports them.
+func (i *Installer) downloadPrefix(ctx context.Context, url string, n int64) ([]byte, error) {
+	if pc, ok := i.downloader.(downloader.PrefixClient); ok {
+		return pc.DownloadPrefix(ctx, url, n)
+	}
+	rc, err := i.downloader.Download(ctx, url)
+	if err != nil {
+		return nil, err
+	}
+	defer rc.Close()
+	return io.ReadAll(io.LimitReader(rc, n))
+}
+
+// prefixedReader reads r, the already consumed prefix of ReadCloser followed
+// by its remaining content.
+type prefixedReader struct {
+	io.ReadCloser
+	r io.Reader
+}
+
+func (p *prefixedReader) Read(b []byte) (int, error) {
+	return p.r.Read(b)
+}
+
+// ETag returns the ETag of the underlying download, if known.
+func (p *prefixedReader) ETag() string {
+	if e, ok := p.ReadCloser.(etagged); ok {
+		return e.ETag()
+	}
+	return ""
+}
@@ -0,0 +1,108 @@
+package installer
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/sixban6/ghinstall/internal/config"
+	log "github.com/sixban6/ghinstall/internal/logger"
+	"github.com/sixban6/ghinstall/internal/provider"
+	"github.com/sixban6/ghinstall/internal/release"
+	"github.com/sixban6/ghinstall/internal/signature"
+	"github.com/sixban6/ghinstall/internal/tmpdir"
+)
+
+// maxSignatureSize bounds the download of signatures, certificates and
+// Sigstore bundles, which are a few kilobytes.
+const maxSignatureSize = 1 << 20
+
+// verifySignature checks the file at path, holding asset of rel, against the
+// signature published with it, as the signature of repo requires. Assets
+// without a signature are refused.
+func (i *Installer) verifySignature(ctx context.Context, cfg *config.Config, repo config.Repo, src provider.Provider, rel *release.Release, asset *release.Asset, path string) error {
+	opts := repo.Signature
+	candidates := signature.Candidates(opts, asset.Name)
+	sigAsset := findAssetNamed(rel.Assets, candidates)
+	if sigAsset == nil {
+		return fmt.Errorf("release %s has no signature of %s (looked for %v)", rel.TagName, asset.Name, candidates)
+	}
+	sig, err := i.downloadSidecar(ctx, cfg, repo, src, sigAsset)
+	if err != nil {
+		return fmt.Errorf("failed to download signature: %w", err)
+	}
+	defer os.Remove(sig)
+
+	// The temporary file of the signature does not keep its name.
+	bundle := opts.Type == "cosign" && signature.IsBundle(sigAsset.Name)
+	cert := ""
+	if opts.Type == "cosign" && opts.Key == "" && !bundle {
+		certAsset := findAssetNamed(rel.Assets, signature.Certificates(asset.Name, sigAsset.Name))
+		if certAsset == nil {
+			return fmt.Errorf("release %s has no certificate for keyless signature %s", rel.TagName, sigAsset.Name)
+		}
+		if cert, err = i.downloadSidecar(ctx, cfg, repo, src, certAsset); err != nil {
+			return fmt.Errorf("failed to download certificate: %w", err)
+		}
+		defer os.Remove(cert)
+	}
+
+	if err := signature.Verify(ctx, opts, path, sig, bundle, cert); err != nil {
+		return fmt.Errorf("bad %s signature %s: %w", opts.Type, sigAsset.Name, err)
+	}
+	log.Info("Verified %s signature %s", opts.Type, sigAsset.Name)
+	return nil
+}
+
+// findAssetNamed returns the first of names found among assets.
+func findAssetNamed(assets []release.Asset, names []string) *release.Asset {
+	for _, name := range names {
+		for n := range assets {
+			if assets[n].Name == name {
+				return &assets[n]
+			}
+		}
+	}
+	return nil
+}
+
+// downloadSidecar downloads asset, a small file published next to the asset
+// of repo, to a temporary file and returns its path. It comes from where the
+// asset itself does: the provider of repo, or GitHub or its mirror.
+func (i *Installer) downloadSidecar(ctx context.Context, cfg *config.Config, repo config.Repo, src provider.Provider, asset *release.Asset) (string, error) {
+	var rc io.ReadCloser
+	var err error
+	if src != nil {
+		rc, err = src.Download(ctx, *asset)
+	} else {
+		// The configured sha256 is the one of the asset, not of its sidecars.
+		repo.SHA256 = ""
+		downloadURL, want := i.downloadSource(cfg, repo, asset)
+		if rc, err = i.downloadFrom(ctx, cfg, asset, downloadURL); err == nil {
+			rc = want.wrap(rc)
+		}
+	}
+	if err != nil {
+		return "", err
+	}
+	defer rc.Close()
+
+	f, err := os.CreateTemp(tmpdir.Dir(), "ghinstall-sig-*")
+	if err != nil {
+		return "", fmt.Errorf("failed to create temporary file: %w", err)
+	}
+	defer f.Close()
+	n, err := io.Copy(f, io.LimitReader(rc, maxSignatureSize+1))
+	if err == nil && n > maxSignatureSize {
+		err = fmt.Errorf("%s is larger than %d bytes", asset.Name, maxSignatureSize)
+	}
+	if err == nil {
+		err = f.Close()
+	}
+	if err != nil {
+		os.Remove(f.Name())
+		return "", err
+	}
+	return f.Name(), nil
+}
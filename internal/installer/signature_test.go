@@ -0,0 +1,50 @@
+package installer
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/sixban6/ghinstall/internal/config"
+	"github.com/sixban6/ghinstall/internal/extractor"
+	"github.com/sixban6/ghinstall/internal/release"
+)
+
+func TestInstaller_Install_Signature(t *testing.T) {
+	asset := release.Asset{Name: "app.tar.gz", URL: "https://github.com/owner/repo/releases/download/v1.0.0/app.tar.gz"}
+	sig := release.Asset{Name: "app.tar.gz.asc", URL: asset.URL + ".asc"}
+	key := filepath.Join(t.TempDir(), "missing.asc")
+
+	tests := []struct {
+		name    string
+		assets  []release.Asset
+		wantErr string
+	}{
+		{name: "unsigned", assets: []release.Asset{asset}, wantErr: "has no signature of app.tar.gz"},
+		// Whether gpg is missing or rejects the key, the asset is refused.
+		{name: "bad signature", assets: []release.Asset{asset, sig}, wantErr: "bad gpg signature app.tar.gz.asc"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			cfg := &config.Config{Github: []config.Repo{{
+				URL:       "https://github.com/owner/repo",
+				OutputDir: dir,
+				Signature: config.SignatureOptions{Type: "gpg", Key: key},
+			}}}
+			rel := &release.Release{TagName: "v1.0.0", Assets: tt.assets}
+			archive := tarGz(t, map[string]string{"app": "binary"})
+			inst := New(&mockFinder{release: rel}, &mockDownloader{content: archive}, extractor.NewLegacy())
+
+			err := inst.Install(context.Background(), cfg, release.DefaultFilter())
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("Install() error = %v, want %q", err, tt.wantErr)
+			}
+			if _, err := os.Stat(filepath.Join(dir, "app")); !os.IsNotExist(err) {
+				t.Errorf("the unverified asset was extracted: %v", err)
+			}
+		})
+	}
+}
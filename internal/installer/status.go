@@ -0,0 +1,275 @@
+package installer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	log "github.com/sixban6/ghinstall/internal/logger"
+	"runtime"
+	"strings"
+	"sync"
+
+	"github.com/sixban6/ghinstall/internal/cache"
+	"github.com/sixban6/ghinstall/internal/config"
+	"github.com/sixban6/ghinstall/internal/downloader"
+	"github.com/sixban6/ghinstall/internal/provider"
+	"github.com/sixban6/ghinstall/internal/release"
+	"github.com/sixban6/ghinstall/internal/state"
+)
+
+// RepoStatus compares what is installed for a repository with its current releases.
+type RepoStatus struct {
+	Repo config.Repo
+	// Installed is the recorded install, nil when the repository was never installed.
+	Installed *state.Record
+	// Latest is the tag an install would select now.
+	Latest string
+	// Asset is the name of the asset an install would select now.
+	Asset string
+	// Remote is the asset's current metadata from a HEAD request, nil when
+	// it could not be checked.
+	Remote *downloader.Metadata
+	// Yanked reports that the installed tag is no longer published upstream.
+	Yanked bool
+	// Replaced describes how the asset of the installed tag changed upstream
+	// since it was installed, without a new tag; "" when it did not.
+	Replaced string
+	// Err is set when the repository could not be checked.
+	Err error
+
+	asset *release.Asset
+}
+
+// UpToDate reports whether the installed tag is still published and is the
+// one an install would select.
+func (s RepoStatus) UpToDate() bool {
+	return s.Err == nil && s.Installed != nil && !s.Yanked && s.Replaced == "" && s.Installed.Tag == s.Latest
+}
+
+// Status re-resolves every configured repository and compares the result with
+// the recorded installs. It never trusts the state file alone: a tag that was
+// installed but has since been removed upstream is reported as yanked.
+//
+// The selected asset is checked with a HEAD request, made conditional with the
+// ETag cached from the previous check, so sizes are exact without downloading.
+func (i *Installer) Status(ctx context.Context, cfg *config.Config) []RepoStatus {
+	meta := openMetadataCache(cfg)
+	i.configureRedirects(cfg)
+	i.configureTimeouts(cfg)
+	i.configureHTTPCache(cfg)
+	i.configureAPIBudget(cfg)
+	configureFDLimits(cfg)
+	direct := sync.OnceValue(func() bool { return directReachable(ctx) })
+
+	statuses := make([]RepoStatus, 0, len(cfg.Github))
+	for _, repo := range cfg.Github {
+		st := i.repoStatus(ctx, cfg, repo)
+		if st.asset != nil && repo.Provider == "" {
+			url := i.assetURL(cfg, repo, st.asset.URL, direct())
+			st.Remote = i.assetMetadata(ctx, meta, url)
+			if st.Installed != nil && st.Installed.Tag == st.Latest {
+				st.Replaced = replacedReason(st.Installed, st.asset, url, st.Remote)
+			}
+		}
+		statuses = append(statuses, st)
+	}
+
+	if err := meta.Save(); err != nil {
+		log.Warn("Failed to save asset metadata: %v", err)
+	}
+	return statuses
+}
+
+// openMetadataCache opens the asset metadata cache in the configured cache
+// directory, or the per-user one.
+func openMetadataCache(cfg *config.Config) *downloader.MetadataCache {
+	dir := cache.UserDir()
+	if cfg.CacheDir != "" {
+		dir = cache.ResolveDir(cfg.CacheDir)
+	}
+	return downloader.OpenMetadataCache(dir)
+}
+
+// assetURL returns the URL an install would download the asset from.
+func (i *Installer) assetURL(cfg *config.Config, repo config.Repo, url string, direct bool) string {
+	if rewritten, ok := i.rewrite(url); ok {
+		return rewritten
+	}
+	if direct {
+		return url
+	}
+	return cfg.GetDownloadURL(repo.URL, url)
+}
+
+// assetMetadata checks url with a HEAD request when the downloader supports
+// it, updating meta. It returns nil when the asset could not be checked.
+func (i *Installer) assetMetadata(ctx context.Context, meta *downloader.MetadataCache, url string) *downloader.Metadata {
+	mc, ok := i.downloader.(downloader.MetadataClient)
+	if !ok {
+		return nil
+	}
+	prev, _ := meta.Get(url)
+	m, err := mc.Head(ctx, url, prev)
+	if err != nil {
+		log.Warn("Failed to check %s: %v", url, err)
+		return nil
+	}
+	meta.Put(*m)
+	return m
+}
+
+// selectAsset picks the asset of rel to install for repo: the one matching its
+// asset_pattern, or the one chosen by filter, among the assets ordered by its
+// prefer setting and the configured asset_type_preference. Without prefer, a
+// pattern matching several assets picks the first with a warning, or fails
+// with strict_assets. When no asset fits
+// the host, the builds it can also run are tried: amd64 on Apple Silicon with
+// allow_rosetta, those listed in windows_emulation on Windows, and Linux
+// builds on Android.
+func selectAsset(cfg *config.Config, repo config.Repo, rel *release.Release, filter release.AssetFilter) (*release.Asset, error) {
+	return selectAssetOn(cfg, repo, rel, filter, hostPlatform)
+}
+
+// selectAssetOn is selectAsset for an install on host, a GOOS/GOARCH, with
+// filter selecting assets for it.
+func selectAssetOn(cfg *config.Config, repo config.Repo, rel *release.Release, filter release.AssetFilter, host string) (*release.Asset, error) {
+	asset, err := selectAssetFor(cfg, repo, rel, filter, host)
+	if err == nil || errors.Is(err, ErrAmbiguousAsset) {
+		return asset, err
+	}
+
+	runner, platforms := compatible(cfg, host)
+	for _, platform := range platforms {
+		fallback := filter
+		if repo.AssetPattern == "" {
+			goos, goarch, _ := strings.Cut(platform, "/")
+			fallback = release.ByPlatform(goos, goarch)
+		}
+		if compat, ferr := selectAssetFor(cfg, repo, rel, fallback, platform); ferr == nil {
+			log.Warn("%s: release %s has no %s asset; installing the %s build %s to run under %s",
+				repo.DisplayName(), rel.TagName, host, platform, compat.Name, runner)
+			return compat, nil
+		}
+	}
+	return nil, err
+}
+
+// hostPlatform is the GOOS/GOARCH assets are selected for; tests replace it.
+var hostPlatform = runtime.GOOS + "/" + runtime.GOARCH
+
+// compatible returns what lets host run builds for other platforms, and the
+// platforms cfg allows installing for it, in order of preference.
+func compatible(cfg *config.Config, host string) (string, []string) {
+	goos, goarch, _ := strings.Cut(host, "/")
+	switch {
+	case goos == "darwin" && goarch == "arm64" && cfg.AllowRosetta:
+		return "Rosetta 2", []string{"darwin/amd64"}
+	case goos == "windows" && (goarch == "arm64" || goarch == "amd64"):
+		var platforms []string
+		for _, arch := range cfg.WindowsEmulation {
+			// x64 Windows runs 386 builds only.
+			if arch != goarch && (goarch == "arm64" || arch == "386") {
+				platforms = append(platforms, "windows/"+arch)
+			}
+		}
+		return "Windows emulation", platforms
+	case goos == "android":
+		// Static Linux builds, which most Go and Rust projects ship, run on
+		// Android and in Termux.
+		return "Android", []string{"linux/" + goarch}
+	}
+	return "", nil
+}
+
+// selectAssetFor selects the asset of rel for platform, a GOOS/GOARCH which
+// only asset patterns use.
+func selectAssetFor(cfg *config.Config, repo config.Repo, rel *release.Release, filter release.AssetFilter, platform string) (*release.Asset, error) {
+	// On 32-bit ARM the builds for the host's variant come first, each group
+	// in the order of prefer, then of archive type preference.
+	assets := release.PreferTypes(rel.Assets, cfg.AssetTypePreference)
+	assets = release.PreferArmVariant(release.Prefer(assets, repo.Prefer), release.HostArmVariant())
+	goos, goarch, _ := strings.Cut(platform, "/")
+	if repo.AssetPattern == "" {
+		// Filters need not look at the platform; prefer must not pick the
+		// smallest build of another one.
+		if repo.Prefer != "" {
+			if own := release.ForPlatform(assets, goos, goarch); len(own) > 0 {
+				assets = own
+			}
+		}
+		return filter(assets)
+	}
+
+	pattern := release.ExpandPatternFor(repo.AssetPattern, rel.TagName, goos, goarch)
+	asset, err := release.ByRegex(pattern)(assets)
+	if err != nil {
+		return nil, err
+	}
+	// prefer settles which of several matches is installed.
+	if matches := release.MatchRegex(pattern, assets); len(matches) > 1 && repo.Prefer == "" {
+		names := make([]string, len(matches))
+		for n, m := range matches {
+			names[n] = m.Name
+		}
+		if cfg.StrictAssets {
+			return nil, fmt.Errorf("%w: asset_pattern %q matches %d assets: %s", ErrAmbiguousAsset, repo.AssetPattern, len(matches), strings.Join(names, ", "))
+		}
+		log.Warn("%s: asset_pattern %q matches %d assets of %s (%s); using %s, make the pattern more specific or set strict_assets to fail instead",
+			repo.DisplayName(), repo.AssetPattern, len(matches), rel.TagName, strings.Join(names, ", "), asset.Name)
+	}
+	return asset, nil
+}
+
+// ErrAmbiguousAsset is returned with strict_assets when an asset_pattern
+// matches more than one asset of a release.
+var ErrAmbiguousAsset = errors.New("ambiguous asset_pattern")
+
+func (i *Installer) repoStatus(ctx context.Context, cfg *config.Config, repo config.Repo) RepoStatus {
+	st := RepoStatus{Repo: repo}
+
+	recorded, err := state.Load(repo.OutputDir)
+	if err != nil {
+		st.Err = err
+		return st
+	}
+	if rec, ok := recorded.Get(repo.URL); ok {
+		st.Installed = &rec
+	}
+
+	var src provider.Provider
+	if repo.Provider != "" {
+		if src, err = provider.ForRepo(cfg, repo); err != nil {
+			st.Err = err
+			return st
+		}
+	}
+
+	rel, err := i.resolve(ctx, repo, src)
+	if err != nil {
+		// A pinned version that vanished is itself a yanked install.
+		st.Yanked = errors.Is(err, release.ErrNotFound) && st.Installed != nil && st.Installed.Tag == repo.Version
+		st.Err = fmt.Errorf("failed to find latest release: %w", err)
+		return st
+	}
+	st.Latest = rel.TagName
+
+	if asset, err := selectAsset(cfg, repo, rel, release.DefaultFilter()); err == nil {
+		st.Asset, st.asset = asset.Name, asset
+	}
+
+	if st.Installed == nil || st.Installed.Tag == rel.TagName || src != nil {
+		return st
+	}
+
+	owner, name, err := config.ParseRepoURL(repo.URL)
+	if err != nil {
+		st.Err = err
+		return st
+	}
+	if _, err := i.finder.ByTag(ctx, owner, name, st.Installed.Tag); errors.Is(err, release.ErrNotFound) {
+		st.Yanked = true
+	} else if err != nil {
+		st.Err = fmt.Errorf("failed to look up installed release %s: %w", st.Installed.Tag, err)
+	}
+	return st
+}
@@ -0,0 +1,284 @@
+package installer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/sixban6/ghinstall/internal/config"
+	"github.com/sixban6/ghinstall/internal/release"
+	"github.com/sixban6/ghinstall/internal/state"
+)
+
+// listFinder serves a fixed list of published releases.
+type listFinder struct {
+	releases []release.Release
+}
+
+func (f *listFinder) Latest(ctx context.Context, owner, repo string, policy release.Policy) (*release.Release, error) {
+	return &f.releases[0], nil
+}
+
+func (f *listFinder) ByTag(ctx context.Context, owner, repo, tag string) (*release.Release, error) {
+	for i := range f.releases {
+		if f.releases[i].TagName == tag {
+			return &f.releases[i], nil
+		}
+	}
+	return nil, fmt.Errorf("%w: %s", release.ErrNotFound, tag)
+}
+
+func TestInstaller_Status(t *testing.T) {
+	finder := &listFinder{releases: []release.Release{{TagName: "v1.2.0"}, {TagName: "v1.1.0"}}}
+
+	tests := []struct {
+		name         string
+		installed    string
+		version      string
+		wantLatest   string
+		wantYanked   bool
+		wantUpToDate bool
+		wantErr      bool
+	}{
+		{name: "not installed", wantLatest: "v1.2.0"},
+		{name: "up to date", installed: "v1.2.0", wantLatest: "v1.2.0", wantUpToDate: true},
+		{name: "update available", installed: "v1.1.0", wantLatest: "v1.2.0"},
+		{name: "yanked", installed: "v1.1.1", wantLatest: "v1.2.0", wantYanked: true},
+		{name: "pinned and yanked", installed: "v1.1.1", version: "v1.1.1", wantYanked: true, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := config.Repo{URL: "https://github.com/owner/repo", OutputDir: t.TempDir(), Version: tt.version}
+			if tt.installed != "" {
+				f, _ := state.Load(repo.OutputDir)
+				f.Put(state.Record{Repo: repo.URL, Tag: tt.installed, InstalledAt: time.Now()})
+				if err := f.Save(); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			got := New(finder, nil, nil).Status(context.Background(), &config.Config{Github: []config.Repo{repo}})
+			if len(got) != 1 {
+				t.Fatalf("Status() returned %d entries", len(got))
+			}
+			st := got[0]
+			if (st.Err != nil) != tt.wantErr {
+				t.Errorf("Status() error = %v, wantErr %v", st.Err, tt.wantErr)
+			}
+			if st.Latest != tt.wantLatest || st.Yanked != tt.wantYanked || st.UpToDate() != tt.wantUpToDate {
+				t.Errorf("Status() = latest %q, yanked %v, up to date %v", st.Latest, st.Yanked, st.UpToDate())
+			}
+		})
+	}
+}
+
+func TestInstaller_Status_AssetMetadata(t *testing.T) {
+	directReachable = func(context.Context) bool { return true }
+	defer func() { directReachable = PingGoogle }()
+
+	var heads int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		heads++
+		if r.Header.Get("If-None-Match") == `"abc"` {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		w.Header().Set("ETag", `"abc"`)
+		w.Header().Set("Content-Length", "4096")
+	}))
+	defer server.Close()
+
+	finder := &listFinder{releases: []release.Release{{TagName: "v1.2.0", Assets: []release.Asset{
+		{Name: "app_linux_amd64.tar.gz", URL: server.URL + "/app_linux_amd64.tar.gz"},
+	}}}}
+	cfg := &config.Config{
+		Github:   []config.Repo{{URL: "https://github.com/owner/repo", OutputDir: t.TempDir(), AssetPattern: `linux_amd64`}},
+		CacheDir: t.TempDir(),
+	}
+
+	for run := 0; run < 2; run++ {
+		st := New(finder, nil, nil).Status(context.Background(), cfg)[0]
+		if st.Asset != "app_linux_amd64.tar.gz" {
+			t.Errorf("run %d: Asset = %q", run, st.Asset)
+		}
+		if st.Remote == nil || st.Remote.Size != 4096 || st.Remote.ETag != `"abc"` {
+			t.Errorf("run %d: Remote = %+v", run, st.Remote)
+		}
+	}
+	if heads != 2 {
+		t.Errorf("server saw %d HEAD requests, want 2", heads)
+	}
+}
+
+func TestSelectAsset_Ambiguous(t *testing.T) {
+	rel := &release.Release{
+		TagName: "v1.0.0",
+		Assets: []release.Asset{
+			{Name: "tool-x86_64-unknown-linux-gnu.tar.gz", Size: 3000},
+			{Name: "tool-x86_64-unknown-linux-musl.tar.gz", Size: 2000},
+			{Name: "tool-aarch64-apple-darwin.tar.gz", Size: 1000},
+		},
+	}
+	tests := []struct {
+		name    string
+		pattern string
+		strict  bool
+		prefer  string
+		want    string
+		wantErr bool
+	}{
+		{name: "single match", pattern: `linux-musl`, strict: true, want: "tool-x86_64-unknown-linux-musl.tar.gz"},
+		{name: "first of several", pattern: `linux`, want: "tool-x86_64-unknown-linux-gnu.tar.gz"},
+		{name: "several with strict_assets", pattern: `linux`, strict: true, wantErr: true},
+		{name: "smallest of several", pattern: `linux`, strict: true, prefer: "smallest", want: "tool-x86_64-unknown-linux-musl.tar.gz"},
+		{name: "named of several", pattern: `x86_64`, strict: true, prefer: "name:GNU", want: "tool-x86_64-unknown-linux-gnu.tar.gz"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &config.Config{StrictAssets: tt.strict}
+			repo := config.Repo{URL: "https://github.com/owner/tool", AssetPattern: tt.pattern, Prefer: tt.prefer}
+			got, err := selectAsset(cfg, repo, rel, release.DefaultFilter())
+			if tt.wantErr {
+				if !errors.Is(err, ErrAmbiguousAsset) {
+					t.Fatalf("selectAsset() error = %v, want ErrAmbiguousAsset", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("selectAsset() error = %v", err)
+			}
+			if got.Name != tt.want {
+				t.Errorf("selectAsset() = %s, want %s", got.Name, tt.want)
+			}
+		})
+	}
+}
+
+func TestSelectAsset_Rosetta(t *testing.T) {
+	hostPlatform = "darwin/arm64"
+	defer func() { hostPlatform = runtime.GOOS + "/" + runtime.GOARCH }()
+
+	rel := &release.Release{
+		TagName: "v1.0.0",
+		Assets: []release.Asset{
+			{Name: "tool_linux_amd64.tar.gz"},
+			{Name: "tool_darwin_x86_64.tar.gz"},
+		},
+	}
+	repo := config.Repo{URL: "https://github.com/owner/tool"}
+
+	if _, err := selectAsset(&config.Config{}, repo, rel, release.ByArch("arm64")); err == nil {
+		t.Error("selectAsset() without allow_rosetta should fail")
+	}
+	got, err := selectAsset(&config.Config{AllowRosetta: true}, repo, rel, release.ByArch("arm64"))
+	if err != nil || got.Name != "tool_darwin_x86_64.tar.gz" {
+		t.Errorf("selectAsset() with allow_rosetta = %v, %v, want the darwin amd64 build", got, err)
+	}
+
+	rel.Assets = append(rel.Assets, release.Asset{Name: "tool_darwin_arm64.tar.gz"})
+	got, err = selectAsset(&config.Config{AllowRosetta: true}, repo, rel, release.ByArch("arm64"))
+	if err != nil || got.Name != "tool_darwin_arm64.tar.gz" {
+		t.Errorf("selectAsset() = %v, %v, want the arm64 build when there is one", got, err)
+	}
+}
+
+func TestSelectAsset_WindowsEmulation(t *testing.T) {
+	defer func() { hostPlatform = runtime.GOOS + "/" + runtime.GOARCH }()
+
+	rel := &release.Release{
+		TagName: "v1.0.0",
+		Assets: []release.Asset{
+			{Name: "tool_windows_386.zip"},
+			{Name: "tool_windows_x64.zip"},
+		},
+	}
+	repo := config.Repo{URL: "https://github.com/owner/tool", AssetPattern: `windows_{arch}\.zip$`}
+
+	tests := []struct {
+		name      string
+		host      string
+		emulation []string
+		want      string
+	}{
+		{name: "arm64 without emulation", host: "windows/arm64"},
+		{name: "arm64 prefers amd64", host: "windows/arm64", emulation: []string{"amd64", "386"}, want: "tool_windows_x64.zip"},
+		{name: "arm64 with 386 only", host: "windows/arm64", emulation: []string{"386"}, want: "tool_windows_386.zip"},
+		{name: "x64 matches directly", host: "windows/amd64", emulation: []string{"386"}, want: "tool_windows_x64.zip"},
+		{name: "other platforms ignore it", host: "linux/arm64", emulation: []string{"amd64"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			hostPlatform = tt.host
+			got, err := selectAsset(&config.Config{WindowsEmulation: tt.emulation}, repo, rel, release.DefaultFilter())
+			if tt.want == "" {
+				if err == nil {
+					t.Errorf("selectAsset() = %s, want an error", got.Name)
+				}
+				return
+			}
+			if err != nil || got.Name != tt.want {
+				t.Errorf("selectAsset() = %v, %v, want %s", got, err, tt.want)
+			}
+		})
+	}
+}
+
+func TestSelectAsset_Android(t *testing.T) {
+	defer func() { hostPlatform = runtime.GOOS + "/" + runtime.GOARCH }()
+	hostPlatform = "android/arm64"
+
+	tests := []struct {
+		name   string
+		assets []string
+		want   string
+	}{
+		{name: "android build", assets: []string{"tool-linux-arm64.tar.gz", "tool-android-arm64.tar.gz"}, want: "tool-android-arm64.tar.gz"},
+		{name: "linux fallback", assets: []string{"tool-linux-amd64.tar.gz", "tool-linux-arm64.tar.gz"}, want: "tool-linux-arm64.tar.gz"},
+		{name: "no arm64 build", assets: []string{"tool-linux-amd64.tar.gz", "tool-darwin-arm64.tar.gz"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rel := &release.Release{TagName: "v1.0.0"}
+			for _, name := range tt.assets {
+				rel.Assets = append(rel.Assets, release.Asset{Name: name})
+			}
+			repo := config.Repo{URL: "https://github.com/owner/tool"}
+			got, err := selectAsset(&config.Config{}, repo, rel, release.ByPlatform("android", "arm64"))
+			if tt.want == "" {
+				if err == nil {
+					t.Errorf("selectAsset() = %s, want an error", got.Name)
+				}
+				return
+			}
+			if err != nil || got.Name != tt.want {
+				t.Errorf("selectAsset() = %v, %v, want %s", got, err, tt.want)
+			}
+		})
+	}
+}
+
+func TestSelectAsset_PreferPlatform(t *testing.T) {
+	defer func() { hostPlatform = runtime.GOOS + "/" + runtime.GOARCH }()
+	hostPlatform = "linux/amd64"
+
+	rel := &release.Release{
+		TagName: "v1.0.0",
+		Assets: []release.Asset{
+			{Name: "tool-darwin-arm64.tar.gz", Size: 100},
+			{Name: "tool-full-linux-amd64.tar.gz", Size: 3000},
+			{Name: "tool-linux-amd64.tar.gz", Size: 2000},
+		},
+	}
+	repo := config.Repo{URL: "https://github.com/owner/tool", Prefer: "smallest"}
+	got, err := selectAsset(&config.Config{}, repo, rel, release.DefaultFilter())
+	if err != nil || got.Name != "tool-linux-amd64.tar.gz" {
+		t.Errorf("selectAsset() = %v, %v, want the smallest linux/amd64 build", got, err)
+	}
+}
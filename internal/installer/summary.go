@@ -0,0 +1,68 @@
+package installer
+
+import (
+	"fmt"
+	"io/fs"
+	"path"
+	"slices"
+	"strings"
+
+	"github.com/sixban6/ghinstall/internal/manifest"
+)
+
+// maxTopLevel bounds the top-level entries listed in the install log.
+const maxTopLevel = 5
+
+// ExtractSummary describes what an archive extracted, so an archive that
+// exploded into an unexpected layout is noticed right away.
+type ExtractSummary struct {
+	Files    int
+	Dirs     int
+	Symlinks int
+	// Bytes is the total size of the extracted regular files.
+	Bytes int64
+	// TopLevel lists the entries at the root of the output directory, sorted,
+	// directories with a trailing "/".
+	TopLevel []string
+}
+
+// summarize returns the summary of the files of m. Directories are counted
+// when they hold extracted files or symlinks; empty ones are not recorded.
+func summarize(m *manifest.Manifest) ExtractSummary {
+	var s ExtractSummary
+	dirs := make(map[string]bool)
+	top := make(map[string]bool)
+	for _, f := range m.Files {
+		if f.Mode&fs.ModeSymlink != 0 {
+			s.Symlinks++
+		} else {
+			s.Files++
+			s.Bytes += f.Size
+		}
+		for dir := path.Dir(f.Path); dir != "."; dir = path.Dir(dir) {
+			dirs[dir] = true
+		}
+		if first, _, nested := strings.Cut(f.Path, "/"); nested {
+			top[first+"/"] = true
+		} else {
+			top[first] = true
+		}
+	}
+	s.Dirs = len(dirs)
+	for name := range top {
+		s.TopLevel = append(s.TopLevel, name)
+	}
+	slices.Sort(s.TopLevel)
+	return s
+}
+
+// String formats s for the install log, listing the first few top-level
+// entries.
+func (s ExtractSummary) String() string {
+	top := strings.Join(s.TopLevel[:min(len(s.TopLevel), maxTopLevel)], ", ")
+	if more := len(s.TopLevel) - maxTopLevel; more > 0 {
+		top += fmt.Sprintf(" and %d more", more)
+	}
+	return fmt.Sprintf("%d files, %d directories, %d symlinks, %.1f MB; top level: %s",
+		s.Files, s.Dirs, s.Symlinks, float64(s.Bytes)/(1024*1024), top)
+}
@@ -0,0 +1,28 @@
+package installer
+
+import (
+	"io/fs"
+	"reflect"
+	"testing"
+
+	"github.com/sixban6/ghinstall/internal/manifest"
+)
+
+func TestSummarize(t *testing.T) {
+	m := &manifest.Manifest{Files: []manifest.File{
+		{Path: "app_1.0/bin/app", Mode: 0755, Size: 1024},
+		{Path: "app_1.0/bin/app-latest", Mode: fs.ModeSymlink | 0777, Link: "app"},
+		{Path: "app_1.0/share/doc/README", Mode: 0644, Size: 100},
+		{Path: "LICENSE", Mode: 0644, Size: 10},
+	}}
+	got := summarize(m)
+	want := ExtractSummary{Files: 3, Dirs: 4, Symlinks: 1, Bytes: 1134, TopLevel: []string{"LICENSE", "app_1.0/"}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("summarize() = %+v, want %+v", got, want)
+	}
+
+	want.TopLevel = []string{"a", "b", "c", "d", "e", "f", "g"}
+	if got, wantStr := want.String(), "3 files, 4 directories, 1 symlinks, 0.0 MB; top level: a, b, c, d, e and 2 more"; got != wantStr {
+		t.Errorf("String() = %q, want %q", got, wantStr)
+	}
+}
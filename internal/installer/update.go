@@ -0,0 +1,24 @@
+package installer
+
+import (
+	"github.com/sixban6/ghinstall/internal/config"
+	"github.com/sixban6/ghinstall/internal/release"
+)
+
+// WithUpdate makes installs only download and extract the repositories whose
+// resolved release differs from the tag recorded in their output directory,
+// and those not installed yet. Unlike the up-to-date check of every install,
+// a repository at the recorded tag is kept without looking further, even when
+// another asset is selected now or upstream replaced it. WithForce overrides
+// it.
+func WithUpdate(update bool) Option {
+	return func(i *Installer) {
+		i.update = update
+	}
+}
+
+// unchanged reports whether an update keeps repo, whose release rel is
+// already installed and whose files are in place.
+func (i *Installer) unchanged(repo config.Repo, rel *release.Release) bool {
+	return i.update && !i.force && installedTag(repo) == rel.TagName && intact(repo, rel.TagName)
+}
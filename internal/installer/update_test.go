@@ -0,0 +1,64 @@
+package installer
+
+import (
+	"context"
+	"testing"
+
+	"github.com/sixban6/ghinstall/internal/config"
+	"github.com/sixban6/ghinstall/internal/release"
+	"github.com/sixban6/ghinstall/internal/state"
+)
+
+func TestInstaller_Install_Update(t *testing.T) {
+	directReachable = func(context.Context) bool { return true }
+	defer func() { directReachable = PingGoogle }()
+
+	rel := func(tag string) *release.Release {
+		return &release.Release{TagName: tag, Assets: []release.Asset{
+			{Name: "app.tar.gz", URL: "https://github.com/owner/repo/releases/download/" + tag + "/app.tar.gz"},
+		}}
+	}
+
+	tests := []struct {
+		name     string
+		recorded string
+		tag      string
+		opts     []Option
+		wantDLs  int
+	}{
+		{name: "not installed", tag: "v1.0.0", wantDLs: 1},
+		// Another asset than the recorded one is reinstalled by plain installs only.
+		{name: "same tag", recorded: "v1.0.0", tag: "v1.0.0", wantDLs: 0},
+		{name: "new release", recorded: "v1.0.0", tag: "v1.1.0", wantDLs: 1},
+		{name: "forced", recorded: "v1.0.0", tag: "v1.0.0", opts: []Option{WithForce(true)}, wantDLs: 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &config.Config{Github: []config.Repo{{URL: "https://github.com/owner/repo", OutputDir: t.TempDir()}}}
+			if tt.recorded != "" {
+				st, _ := state.Load(cfg.Github[0].OutputDir)
+				st.Put(state.Record{Repo: cfg.Github[0].URL, Tag: tt.recorded, Asset: "app-old.tar.gz"})
+				if err := st.Save(); err != nil {
+					t.Fatal(err)
+				}
+			}
+			down := &countingDownloader{content: "test content"}
+			var plan *DownloadPlan
+			opts := append(tt.opts, WithUpdate(true), WithPlan(func(ctx context.Context, p *DownloadPlan) error {
+				plan = p
+				return nil
+			}))
+
+			inst := New(&mockFinder{release: rel(tt.tag)}, down, &mockExtractor{}, opts...)
+			if err := inst.Install(context.Background(), cfg, release.DefaultFilter()); err != nil {
+				t.Fatalf("Install() error = %v", err)
+			}
+			if down.calls != tt.wantDLs {
+				t.Errorf("%d downloads, want %d", down.calls, tt.wantDLs)
+			}
+			if got := plan.Downloads[0].UpToDate; got != (tt.wantDLs == 0) {
+				t.Errorf("planned UpToDate = %v, want %v", got, tt.wantDLs == 0)
+			}
+		})
+	}
+}
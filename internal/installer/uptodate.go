@@ -0,0 +1,67 @@
+package installer
+
+import (
+	"errors"
+
+	"github.com/sixban6/ghinstall/internal/config"
+	log "github.com/sixban6/ghinstall/internal/logger"
+	"github.com/sixban6/ghinstall/internal/manifest"
+	"github.com/sixban6/ghinstall/internal/release"
+	"github.com/sixban6/ghinstall/internal/state"
+)
+
+// upToDate reports whether the state file of the output directory of repo
+// records the install of asset of rel, whose files are still in place, which
+// then need not be installed again.
+func upToDate(repo config.Repo, rel *release.Release, asset *release.Asset) bool {
+	st, err := state.Load(repo.OutputDir)
+	if err != nil {
+		return false
+	}
+	rec, ok := st.Get(repo.URL)
+	if !ok || rec.Tag != rel.TagName || rec.Asset != asset.Name {
+		return false
+	}
+	// A sha256 pin changed since the install is checked against a new download.
+	if repo.SHA256 != "" && rec.SHA256 != repo.SHA256 {
+		return false
+	}
+	return intact(repo, rel.TagName)
+}
+
+// intact reports whether the files recorded in the manifest of repo at tag
+// are unchanged. Only files whose size or modification time differ from the
+// manifest are hashed; ghinstall verify hashes them all. Assets whose files cannot be listed have no manifest and are
+// trusted; so are state records imported from another machine, which
+// ghinstall reinstall therefore installs with WithForce.
+func intact(repo config.Repo, tag string) bool {
+	m, err := manifest.Load(repo.OutputDir, repo.URL)
+	if errors.Is(err, manifest.ErrNoManifest) {
+		return true
+	}
+	if err != nil || m.Tag != tag {
+		return false
+	}
+	problems, err := m.Changed(repo.OutputDir)
+	if err != nil || len(problems) > 0 {
+		log.Info("%s: %d files changed since the install of %s, installing it again", repo.DisplayName(), len(problems), tag)
+		return false
+	}
+	return true
+}
+
+// keepInstalled skips the install of an up-to-date repository. Its
+// executables are still linked into bin_dir and added to the PATH of GitHub
+// Actions jobs, which do not keep them from run to run.
+func (i *Installer) keepInstalled(cfg *config.Config, repo config.Repo, rel *release.Release, asset *release.Asset) error {
+	log.Info("%s is up to date at %s, skipping it; use -force to reinstall it", repo.DisplayName(), rel.TagName)
+	if cfg.BinDir != "" {
+		if err := linkExecutables(cfg.BinDir, repo, shimSuffix(cfg, repo)); err != nil {
+			return err
+		}
+	}
+	if i.actions {
+		return reportToActions(InstallResult{Repo: repo, Tag: rel.TagName, Asset: *asset, OutputDir: repo.OutputDir, Release: *rel})
+	}
+	return nil
+}
@@ -0,0 +1,90 @@
+package installer
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/sixban6/ghinstall/internal/config"
+	"github.com/sixban6/ghinstall/internal/extractor"
+	"github.com/sixban6/ghinstall/internal/release"
+)
+
+func TestInstaller_Install_UpToDate(t *testing.T) {
+	directReachable = func(context.Context) bool { return true }
+	defer func() { directReachable = PingGoogle }()
+
+	rel := func(tag string) *release.Release {
+		return &release.Release{TagName: tag, Assets: []release.Asset{
+			{Name: "app.tar.gz", URL: "https://github.com/owner/repo/releases/download/" + tag + "/app.tar.gz"},
+		}}
+	}
+	cfg := &config.Config{Github: []config.Repo{{URL: "https://github.com/owner/repo", OutputDir: t.TempDir()}}}
+	down := &countingDownloader{content: "test content"}
+
+	tests := []struct {
+		name    string
+		tag     string
+		opts    []Option
+		wantDLs int
+	}{
+		{name: "first install", tag: "v1.0.0", wantDLs: 1},
+		{name: "up to date", tag: "v1.0.0", wantDLs: 1},
+		{name: "forced", tag: "v1.0.0", opts: []Option{WithForce(true)}, wantDLs: 2},
+		{name: "new release", tag: "v1.1.0", wantDLs: 3},
+	}
+	for _, tt := range tests {
+		var plan *DownloadPlan
+		opts := append(tt.opts, WithPlan(func(ctx context.Context, p *DownloadPlan) error {
+			plan = p
+			return nil
+		}))
+		inst := New(&mockFinder{release: rel(tt.tag)}, down, &mockExtractor{}, opts...)
+		if err := inst.Install(context.Background(), cfg, release.DefaultFilter()); err != nil {
+			t.Fatalf("%s: Install() error = %v", tt.name, err)
+		}
+		if down.calls != tt.wantDLs {
+			t.Errorf("%s: %d downloads, want %d", tt.name, down.calls, tt.wantDLs)
+		}
+		if upToDate := tt.name == "up to date"; plan.Downloads[0].UpToDate != upToDate {
+			t.Errorf("%s: planned UpToDate = %v, want %v", tt.name, plan.Downloads[0].UpToDate, upToDate)
+		}
+	}
+}
+
+func TestInstaller_Install_UpToDateFilesChanged(t *testing.T) {
+	directReachable = func(context.Context) bool { return true }
+	defer func() { directReachable = PingGoogle }()
+
+	rel := &release.Release{TagName: "v1.0.0", Assets: []release.Asset{
+		{Name: "app.tar.gz", URL: "https://github.com/owner/repo/releases/download/v1.0.0/app.tar.gz"},
+	}}
+	dir := t.TempDir()
+	cfg := &config.Config{Github: []config.Repo{{URL: "https://github.com/owner/repo", OutputDir: dir}}}
+	down := &countingDownloader{content: tarGz(t, map[string]string{"app": "binary"})}
+	install := func() {
+		t.Helper()
+		if err := New(&mockFinder{release: rel}, down, extractor.NewLegacy()).Install(context.Background(), cfg, release.DefaultFilter()); err != nil {
+			t.Fatalf("Install() error = %v", err)
+		}
+	}
+
+	install()
+	install()
+	if down.calls != 1 {
+		t.Fatalf("%d downloads, want the second install skipped", down.calls)
+	}
+
+	// A recorded file removed by hand is restored.
+	if err := os.Remove(filepath.Join(dir, "app")); err != nil {
+		t.Fatal(err)
+	}
+	install()
+	if down.calls != 2 {
+		t.Errorf("%d downloads, want the install with a missing file repeated", down.calls)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "app")); err != nil {
+		t.Errorf("app was not restored: %v", err)
+	}
+}
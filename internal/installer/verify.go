@@ -0,0 +1,96 @@
+package installer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/sixban6/ghinstall/internal/cache"
+	"github.com/sixban6/ghinstall/internal/config"
+	"github.com/sixban6/ghinstall/internal/manifest"
+	"github.com/sixban6/ghinstall/internal/state"
+)
+
+// VerifyResult reports the integrity of one installed repository.
+type VerifyResult struct {
+	Repo config.Repo
+	// Problems lists the files that were modified or removed since the install.
+	Problems []manifest.Problem
+	// Repaired is set when the problems were fixed from the cached archive.
+	Repaired bool
+	// Err is set when the repository could not be verified or repaired.
+	Err error
+}
+
+// Verify re-hashes the installed files of every repository against the
+// manifest recorded at install time. With repair, damaged files are
+// re-extracted from the cached archive; nothing is downloaded.
+func (i *Installer) Verify(ctx context.Context, cfg *config.Config, repair bool) []VerifyResult {
+	results := make([]VerifyResult, 0, len(cfg.Github))
+	for _, repo := range cfg.Github {
+		results = append(results, verifyRepo(ctx, cfg, repo, repair))
+	}
+	return results
+}
+
+func verifyRepo(ctx context.Context, cfg *config.Config, repo config.Repo, repair bool) VerifyResult {
+	res := VerifyResult{Repo: repo}
+
+	m, err := manifest.Load(repo.OutputDir, repo.URL)
+	if err != nil {
+		res.Err = err
+		return res
+	}
+	if res.Problems, res.Err = m.Verify(repo.OutputDir); res.Err != nil || len(res.Problems) == 0 || !repair {
+		return res
+	}
+
+	lock, err := acquireLock(ctx, filepath.Join(repo.OutputDir, LockFileName), cfg.GetLockTimeout())
+	if err != nil {
+		res.Err = fmt.Errorf("failed to lock output directory: %w", err)
+		return res
+	}
+	defer lock.Release()
+
+	if res.Err = repairFromCache(cfg, repo, m, res.Problems); res.Err == nil {
+		res.Repaired = true
+	}
+	return res
+}
+
+func repairFromCache(cfg *config.Config, repo config.Repo, m *manifest.Manifest, problems []manifest.Problem) error {
+	st, err := state.Load(repo.OutputDir)
+	if err != nil {
+		return err
+	}
+	rec, ok := st.Get(repo.URL)
+	if !ok || rec.SHA256 == "" || rec.Tag != m.Tag {
+		return errors.New("the installed archive is unknown; reinstall to repair")
+	}
+	if cfg.CacheDir == "" {
+		return errors.New("repairing needs the archive from cache_dir; reinstall to repair")
+	}
+
+	c, err := cache.Open(cache.ResolveDir(cfg.CacheDir), cfg.SharedCache())
+	if err != nil {
+		return err
+	}
+	f, err := os.Open(c.BlobPath(rec.SHA256))
+	if err != nil {
+		return fmt.Errorf("archive %s is no longer cached; reinstall to repair", rec.Asset)
+	}
+	defer f.Close()
+
+	fi, err := f.Stat()
+	if err != nil {
+		return err
+	}
+
+	paths := make([]string, 0, len(problems))
+	for _, p := range problems {
+		paths = append(paths, p.Path)
+	}
+	return manifest.Repair(repo.OutputDir, f, fi.Size(), paths)
+}
@@ -0,0 +1,106 @@
+package installer
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/sixban6/ghinstall/internal/config"
+	"github.com/sixban6/ghinstall/internal/extractor"
+	"github.com/sixban6/ghinstall/internal/manifest"
+	"github.com/sixban6/ghinstall/internal/release"
+)
+
+func tarGz(t *testing.T, files map[string]string) string {
+	t.Helper()
+	var buf bytes.Buffer
+	gw := gzip.NewWriter(&buf)
+	tw := tar.NewWriter(gw)
+	for name, content := range files {
+		if err := tw.WriteHeader(&tar.Header{Name: name, Mode: 0644, Size: int64(len(content)), Typeflag: tar.TypeReg}); err != nil {
+			t.Fatal(err)
+		}
+		tw.Write([]byte(content))
+	}
+	tw.Close()
+	gw.Close()
+	return buf.String()
+}
+
+// modeTarGz returns a tar.gz archive of files with the given modes.
+func modeTarGz(t *testing.T, files map[string]os.FileMode) string {
+	t.Helper()
+	var buf bytes.Buffer
+	gw := gzip.NewWriter(&buf)
+	tw := tar.NewWriter(gw)
+	for name, mode := range files {
+		if err := tw.WriteHeader(&tar.Header{Name: name, Mode: int64(mode), Size: 1, Typeflag: tar.TypeReg}); err != nil {
+			t.Fatal(err)
+		}
+		tw.Write([]byte("x"))
+	}
+	tw.Close()
+	gw.Close()
+	return buf.String()
+}
+
+func TestInstaller_Verify(t *testing.T) {
+	rel := &release.Release{TagName: "v1.0.0", Assets: []release.Asset{
+		{Name: "app.tar.gz", URL: "https://github.com/owner/repo/releases/download/v1.0.0/app.tar.gz"},
+	}}
+	archive := tarGz(t, map[string]string{"app": "binary", "LICENSE": "MIT"})
+
+	tests := []struct {
+		name         string
+		cacheDir     string
+		repair       bool
+		wantProblems []manifest.Problem
+		wantRepaired bool
+		wantErr      bool
+	}{
+		{name: "report only", cacheDir: t.TempDir(), wantProblems: []manifest.Problem{{Path: "app", Kind: "modified"}}},
+		{name: "repair from cache", cacheDir: t.TempDir(), repair: true, wantProblems: []manifest.Problem{{Path: "app", Kind: "modified"}}, wantRepaired: true},
+		{name: "repair without cache", repair: true, wantProblems: []manifest.Problem{{Path: "app", Kind: "modified"}}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			cfg := &config.Config{
+				Github:   []config.Repo{{URL: "https://github.com/owner/repo", OutputDir: dir}},
+				CacheDir: tt.cacheDir,
+			}
+			inst := New(&mockFinder{release: rel}, &mockDownloader{content: archive}, extractor.NewLegacy())
+			if err := inst.Install(context.Background(), cfg, release.DefaultFilter()); err != nil {
+				t.Fatalf("Install() error = %v", err)
+			}
+
+			if res := inst.Verify(context.Background(), cfg, false); len(res[0].Problems) != 0 || res[0].Err != nil {
+				t.Fatalf("Verify() after install = %+v", res[0])
+			}
+
+			os.WriteFile(filepath.Join(dir, "app"), []byte("patched"), 0644)
+
+			res := inst.Verify(context.Background(), cfg, tt.repair)[0]
+			if len(res.Problems) != len(tt.wantProblems) || (len(res.Problems) > 0 && res.Problems[0] != tt.wantProblems[0]) {
+				t.Errorf("Problems = %v, want %v", res.Problems, tt.wantProblems)
+			}
+			if res.Repaired != tt.wantRepaired {
+				t.Errorf("Repaired = %v, want %v", res.Repaired, tt.wantRepaired)
+			}
+			if (res.Err != nil) != tt.wantErr {
+				t.Errorf("Err = %v, wantErr %v", res.Err, tt.wantErr)
+			}
+
+			if tt.wantRepaired {
+				if got, _ := os.ReadFile(filepath.Join(dir, "app")); string(got) != "binary" {
+					t.Errorf("repaired app = %q, want %q", got, "binary")
+				}
+			}
+		})
+	}
+}
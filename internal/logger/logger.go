@@ -1,8 +1,12 @@
 package logger
 
 import (
+	"fmt"
+	"io"
 	"log"
 	"os"
+	"strings"
+	"sync/atomic"
 )
 
 const (
@@ -10,6 +14,7 @@ const (
 	ColorCyan   = "\033[0;36m" // 青色 - 一般信息
 	ColorRed    = "\033[0;31m" // 红色 - 错误信息
 	ColorYellow = "\033[0;33m" // 黄色 - 警告信息
+	ColorGray   = "\033[0;90m" // 灰色 - 调试信息
 )
 
 var (
@@ -17,25 +22,114 @@ var (
 	infoLogger = log.New(os.Stdout, "", log.LstdFlags)
 	// 错误输出logger - 用于ERROR和WARN级别
 	errorLogger = log.New(os.Stderr, "", log.LstdFlags)
+	// 输出日志的最低级别，默认为LevelInfo
+	level atomic.Int32
 )
 
+// Level 日志级别，低于当前级别的日志不会输出
+type Level int32
+
+const (
+	LevelDebug Level = iota - 1
+	LevelInfo
+	LevelWarn
+	LevelError
+)
+
+var levelNames = []string{"debug", "info", "warn", "error"}
+
+func (l Level) String() string {
+	if l < LevelDebug || l > LevelError {
+		return fmt.Sprintf("Level(%d)", int32(l))
+	}
+	return levelNames[l-LevelDebug]
+}
+
+// ParseLevel 解析debug、info、warn或error（不区分大小写，warning同warn）
+func ParseLevel(s string) (Level, error) {
+	name := strings.ToLower(s)
+	if name == "warning" {
+		name = "warn"
+	}
+	for i, n := range levelNames {
+		if n == name {
+			return Level(i) + LevelDebug, nil
+		}
+	}
+	return LevelInfo, fmt.Errorf("unknown log level %q: must be debug, info, warn or error", s)
+}
+
+// MarshalText 实现encoding.TextMarshaler，以便用作flag.TextVar
+func (l Level) MarshalText() ([]byte, error) {
+	return []byte(l.String()), nil
+}
+
+// UnmarshalText 实现encoding.TextUnmarshaler，接受ParseLevel的取值
+func (l *Level) UnmarshalText(text []byte) error {
+	parsed, err := ParseLevel(string(text))
+	if err != nil {
+		return err
+	}
+	*l = parsed
+	return nil
+}
+
+// SetLevel 设置输出日志的最低级别
+func SetLevel(l Level) {
+	level.Store(int32(l))
+}
+
+// Enabled 判断该级别的日志是否会输出，用于跳过代价较高的调试信息
+func Enabled(l Level) bool {
+	return l >= Level(level.Load())
+}
+
 // Info 输出信息级别日志到stdout
 func Info(format string, v ...interface{}) {
+	if !Enabled(LevelInfo) {
+		return
+	}
 	infoLogger.Printf("%s[INFO] "+format+"%s", append([]interface{}{ColorCyan}, append(v, ColorReset)...)...)
 }
 
 // Error 输出错误级别日志到stderr
 func Error(format string, v ...interface{}) {
+	if !Enabled(LevelError) {
+		return
+	}
 	errorLogger.Printf("%s[ERROR] "+format+"%s", append([]interface{}{ColorRed}, append(v, ColorReset)...)...)
 }
 
 // Warn 输出警告级别日志到stderr
 func Warn(format string, v ...interface{}) {
+	if !Enabled(LevelWarn) {
+		return
+	}
 	errorLogger.Printf("%s[WARN] "+format+"%s", append([]interface{}{ColorYellow}, append(v, ColorReset)...)...)
 }
 
-// Success 输出成功信息（用绿色）
+// Debug 在启用调试时输出调试级别日志到stderr
+func Debug(format string, v ...interface{}) {
+	if !Enabled(LevelDebug) {
+		return
+	}
+	errorLogger.Printf("%s[DEBUG] "+format+"%s", append([]interface{}{ColorGray}, append(v, ColorReset)...)...)
+}
+
+// SetDebug 启用或关闭调试级别日志，即SetLevel(LevelDebug)或SetLevel(LevelInfo)
+func SetDebug(enabled bool) {
+	if enabled {
+		SetLevel(LevelDebug)
+	} else {
+		SetLevel(LevelInfo)
+	}
+}
+
+// Success 输出成功信息（用绿色），级别同Info
 func Success(format string, v ...interface{}) {
+	if !Enabled(LevelInfo) {
+		return
+	}
 	infoLogger.Printf("\033[0;32m[SUCCESS] "+format+"%s", append(v, ColorReset)...)
 }
 
@@ -44,6 +138,12 @@ func SetOutput(stderr *os.File) {
 	infoLogger.SetOutput(stderr)
 }
 
+// SetWriter 将所有级别的日志写入w，例如在进度界面上方输出
+func SetWriter(w io.Writer) {
+	errorLogger.SetOutput(w)
+	infoLogger.SetOutput(w)
+}
+
 func SetFlags(i int) {
 	errorLogger.SetFlags(i)
 	infoLogger.SetFlags(i)
@@ -0,0 +1,52 @@
+package logger
+
+import (
+	"bytes"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestParseLevel(t *testing.T) {
+	tests := []struct {
+		in   string
+		want Level
+	}{
+		{"debug", LevelDebug},
+		{"INFO", LevelInfo},
+		{"warning", LevelWarn},
+		{"error", LevelError},
+	}
+	for _, tt := range tests {
+		if got, err := ParseLevel(tt.in); err != nil || got != tt.want {
+			t.Errorf("ParseLevel(%q) = %v, %v, want %v", tt.in, got, err, tt.want)
+		}
+	}
+	if _, err := ParseLevel("trace"); err == nil {
+		t.Error("ParseLevel(trace) should fail")
+	}
+}
+
+func TestSetLevel(t *testing.T) {
+	var out bytes.Buffer
+	SetWriter(&out)
+	defer SetWriter(os.Stderr)
+	defer SetLevel(LevelInfo)
+
+	SetLevel(LevelWarn)
+	Debug("debug")
+	Info("info")
+	Success("success")
+	Warn("warn")
+	Error("error")
+
+	got := out.String()
+	for _, line := range []string{"debug", "info", "success"} {
+		if strings.Contains(got, "["+strings.ToUpper(line)+"]") {
+			t.Errorf("output %q contains %s below the warn level", got, line)
+		}
+	}
+	if !strings.Contains(got, "[WARN] warn") || !strings.Contains(got, "[ERROR] error") {
+		t.Errorf("output = %q, want the warning and the error", got)
+	}
+}
@@ -0,0 +1,248 @@
+// Package manifest records the files an install extracted, with their
+// digests, so installs can later be checked for modified or missing files and
+// repaired from the archive.
+package manifest
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/sixban6/ghinstall/internal/extractor"
+)
+
+// File is an extracted regular file or symlink.
+type File struct {
+	Path   string      `json:"path"`
+	Mode   fs.FileMode `json:"mode"`
+	Size   int64       `json:"size,omitempty"`
+	SHA256 string      `json:"sha256,omitempty"`
+	Link   string      `json:"link,omitempty"`
+	// ModTime is the modification time of the extracted file in Unix
+	// nanoseconds, recorded by Stamp; zero when unknown.
+	ModTime int64 `json:"mtime,omitempty"`
+}
+
+// Manifest lists the files extracted for one repository.
+type Manifest struct {
+	Repo  string `json:"repo"`
+	Tag   string `json:"tag"`
+	Files []File `json:"files"`
+}
+
+// Problem is a deviation of the installed files from the manifest.
+type Problem struct {
+	Path string
+	// Kind is "missing" or "modified".
+	Kind string
+}
+
+// FromArchive builds the manifest of the files the archive extracts.
+func FromArchive(repo, tag string, archive io.ReaderAt, size int64) (*Manifest, error) {
+	m := &Manifest{Repo: repo, Tag: tag, Files: []File{}}
+	err := extractor.Walk(archive, size, func(e extractor.Entry, content io.Reader) error {
+		switch {
+		case e.Mode&fs.ModeSymlink != 0:
+			m.Files = append(m.Files, File{Path: e.Name, Mode: e.Mode, Link: e.Link})
+		case e.Mode.IsRegular():
+			h := sha256.New()
+			n, err := io.Copy(h, content)
+			if err != nil {
+				return fmt.Errorf("failed to hash %s: %w", e.Name, err)
+			}
+			m.Files = append(m.Files, File{Path: e.Name, Mode: e.Mode, Size: n, SHA256: hex.EncodeToString(h.Sum(nil))})
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list archive: %w", err)
+	}
+	return m, nil
+}
+
+// Path returns where the manifest of repo is kept in dir.
+func Path(dir, repo string) string {
+	return filepath.Join(dir, ".ghinstall.manifest."+sanitize(repo)+".json")
+}
+
+var unsafeChars = regexp.MustCompile(`[^A-Za-z0-9._-]+`)
+
+func sanitize(repo string) string {
+	repo = strings.TrimPrefix(strings.TrimPrefix(repo, "https://"), "http://")
+	return strings.Trim(unsafeChars.ReplaceAllString(repo, "_"), "_")
+}
+
+// Save writes m into dir.
+func (m *Manifest) Save(dir string) error {
+	data, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode manifest: %w", err)
+	}
+	if err := os.WriteFile(Path(dir, m.Repo), append(data, '\n'), 0644); err != nil {
+		return fmt.Errorf("failed to write manifest: %w", err)
+	}
+	return nil
+}
+
+// ErrNoManifest is returned by Load when no manifest was recorded.
+var ErrNoManifest = errors.New("no manifest recorded")
+
+// Load reads the manifest of repo from dir.
+func Load(dir, repo string) (*Manifest, error) {
+	data, err := os.ReadFile(Path(dir, repo))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, ErrNoManifest
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read manifest: %w", err)
+	}
+
+	var m Manifest
+	if err := json.Unmarshal(data, &m); err != nil {
+		return nil, fmt.Errorf("failed to parse manifest %s: %w", Path(dir, repo), err)
+	}
+	return &m, nil
+}
+
+// Stamp records the modification times of the files of m as extracted below
+// dir, which lets Changed skip hashing the files that were left alone.
+func (m *Manifest) Stamp(dir string) {
+	for i, f := range m.Files {
+		if f.Mode&fs.ModeSymlink != 0 {
+			continue
+		}
+		fi, err := os.Lstat(filepath.Join(dir, filepath.FromSlash(f.Path)))
+		if err != nil || !fi.Mode().IsRegular() || fi.Size() != f.Size {
+			continue
+		}
+		m.Files[i].ModTime = fi.ModTime().UnixNano()
+	}
+}
+
+// Verify re-hashes the files of m below dir and reports those that are
+// missing or differ from the recorded content.
+func (m *Manifest) Verify(dir string) ([]Problem, error) {
+	return m.check(dir, false)
+}
+
+// Changed is the quick form of Verify: files whose size and modification
+// time are still those recorded by Stamp are taken as unchanged, and only
+// the others are re-hashed.
+func (m *Manifest) Changed(dir string) ([]Problem, error) {
+	return m.check(dir, true)
+}
+
+func (m *Manifest) check(dir string, quick bool) ([]Problem, error) {
+	var problems []Problem
+	for _, f := range m.Files {
+		ok, err := f.matches(filepath.Join(dir, filepath.FromSlash(f.Path)), quick)
+		if errors.Is(err, os.ErrNotExist) {
+			problems = append(problems, Problem{Path: f.Path, Kind: "missing"})
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		if !ok {
+			problems = append(problems, Problem{Path: f.Path, Kind: "modified"})
+		}
+	}
+	return problems, nil
+}
+
+func (f File) matches(path string, quick bool) (bool, error) {
+	fi, err := os.Lstat(path)
+	if err != nil {
+		return false, err
+	}
+
+	if f.Mode&fs.ModeSymlink != 0 {
+		if fi.Mode()&fs.ModeSymlink == 0 {
+			return false, nil
+		}
+		target, err := os.Readlink(path)
+		return err == nil && target == f.Link, err
+	}
+
+	if !fi.Mode().IsRegular() || fi.Size() != f.Size {
+		return false, nil
+	}
+	if quick && f.ModTime != 0 && fi.ModTime().Equal(time.Unix(0, f.ModTime)) {
+		return true, nil
+	}
+	file, err := os.Open(path)
+	if err != nil {
+		return false, err
+	}
+	defer file.Close()
+
+	h := sha256.New()
+	if _, err := io.Copy(h, file); err != nil {
+		return false, fmt.Errorf("failed to hash %s: %w", path, err)
+	}
+	return hex.EncodeToString(h.Sum(nil)) == f.SHA256, nil
+}
+
+// Remove deletes the files of m below dir, whatever they were changed into,
+// so the archive can be extracted again from scratch. Directories are kept.
+func (m *Manifest) Remove(dir string) error {
+	for _, f := range m.Files {
+		if err := os.RemoveAll(filepath.Join(dir, filepath.FromSlash(f.Path))); err != nil {
+			return fmt.Errorf("failed to remove %s: %w", f.Path, err)
+		}
+	}
+	return nil
+}
+
+// Repair re-extracts only the given paths from archive into dir.
+func Repair(dir string, archive io.ReaderAt, size int64, paths []string) error {
+	damaged := make(map[string]bool, len(paths))
+	for _, p := range paths {
+		damaged[p] = true
+	}
+
+	err := extractor.Walk(archive, size, func(e extractor.Entry, content io.Reader) error {
+		if !damaged[e.Name] {
+			return nil
+		}
+		delete(damaged, e.Name)
+
+		target := filepath.Join(dir, filepath.FromSlash(e.Name))
+		if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+			return err
+		}
+		if err := os.RemoveAll(target); err != nil {
+			return fmt.Errorf("failed to remove damaged %s: %w", target, err)
+		}
+
+		if e.Mode&fs.ModeSymlink != 0 {
+			return os.Symlink(e.Link, target)
+		}
+		out, err := os.OpenFile(target, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, e.Mode.Perm())
+		if err != nil {
+			return err
+		}
+		if _, err := io.Copy(out, content); err != nil {
+			out.Close()
+			return fmt.Errorf("failed to restore %s: %w", target, err)
+		}
+		return out.Close()
+	})
+	if err != nil {
+		return fmt.Errorf("failed to repair from archive: %w", err)
+	}
+
+	if len(damaged) > 0 {
+		return fmt.Errorf("%d damaged files are not in the archive", len(damaged))
+	}
+	return nil
+}
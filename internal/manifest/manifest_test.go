@@ -0,0 +1,151 @@
+package manifest
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/sixban6/ghinstall/internal/extractor"
+)
+
+func testArchive(t *testing.T) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	gw := gzip.NewWriter(&buf)
+	tw := tar.NewWriter(gw)
+	add := func(hdr *tar.Header, content string) {
+		hdr.Size = int64(len(content))
+		if err := tw.WriteHeader(hdr); err != nil {
+			t.Fatal(err)
+		}
+		tw.Write([]byte(content))
+	}
+	add(&tar.Header{Name: "./", Mode: 0755, Typeflag: tar.TypeDir}, "")
+	add(&tar.Header{Name: "./bin/tool", Mode: 0755, Typeflag: tar.TypeReg}, "#!/bin/sh\necho tool\n")
+	add(&tar.Header{Name: "./README.md", Mode: 0644, Typeflag: tar.TypeReg}, "# tool\n")
+	add(&tar.Header{Name: "./tool", Typeflag: tar.TypeSymlink, Linkname: "bin/tool"}, "")
+	tw.Close()
+	gw.Close()
+	return buf.Bytes()
+}
+
+func TestManifest_VerifyRepair(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("archive symlinks are not extracted on Windows")
+	}
+
+	archive := testArchive(t)
+	dir := t.TempDir()
+	if err := extractor.NewLegacy().Extract(bytes.NewReader(archive), dir); err != nil {
+		t.Fatal(err)
+	}
+
+	m, err := FromArchive("https://github.com/owner/tool", "v1.0.0", bytes.NewReader(archive), int64(len(archive)))
+	if err != nil {
+		t.Fatalf("FromArchive() error = %v", err)
+	}
+	if len(m.Files) != 3 {
+		t.Fatalf("FromArchive() = %+v, want 3 files", m.Files)
+	}
+	if err := m.Save(dir); err != nil {
+		t.Fatalf("Save() error = %v", err)
+	}
+	m, err = Load(dir, "https://github.com/owner/tool")
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+
+	if problems, err := m.Verify(dir); err != nil || len(problems) != 0 {
+		t.Fatalf("Verify() of a fresh install = %v, %v", problems, err)
+	}
+
+	os.WriteFile(filepath.Join(dir, "bin", "tool"), []byte("tampered"), 0755)
+	os.Remove(filepath.Join(dir, "README.md"))
+	os.WriteFile(filepath.Join(dir, "extra.txt"), []byte("not ours"), 0644)
+
+	problems, err := m.Verify(dir)
+	if err != nil {
+		t.Fatalf("Verify() error = %v", err)
+	}
+	want := []Problem{{Path: "bin/tool", Kind: "modified"}, {Path: "README.md", Kind: "missing"}}
+	if len(problems) != len(want) || problems[0] != want[0] || problems[1] != want[1] {
+		t.Fatalf("Verify() = %v, want %v", problems, want)
+	}
+
+	if err := Repair(dir, bytes.NewReader(archive), int64(len(archive)), []string{"bin/tool", "README.md"}); err != nil {
+		t.Fatalf("Repair() error = %v", err)
+	}
+	if problems, err := m.Verify(dir); err != nil || len(problems) != 0 {
+		t.Errorf("Verify() after Repair() = %v, %v", problems, err)
+	}
+	if fi, err := os.Stat(filepath.Join(dir, "bin", "tool")); err != nil || fi.Mode().Perm() != 0755 {
+		t.Errorf("repaired bin/tool mode = %v, %v", fi, err)
+	}
+}
+
+func TestManifest_Changed(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("archive symlinks are not extracted on Windows")
+	}
+
+	archive := testArchive(t)
+	dir := t.TempDir()
+	if err := extractor.NewLegacy().Extract(bytes.NewReader(archive), dir); err != nil {
+		t.Fatal(err)
+	}
+	m, err := FromArchive("https://github.com/owner/tool", "v1.0.0", bytes.NewReader(archive), int64(len(archive)))
+	if err != nil {
+		t.Fatalf("FromArchive() error = %v", err)
+	}
+	m.Stamp(dir)
+	if problems, err := m.Changed(dir); err != nil || len(problems) != 0 {
+		t.Fatalf("Changed() of a fresh install = %v, %v", problems, err)
+	}
+
+	// Same size and modification time: taken as unchanged without hashing.
+	readme := filepath.Join(dir, "README.md")
+	fi, err := os.Stat(readme)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(readme, []byte("# TOOL\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chtimes(readme, fi.ModTime(), fi.ModTime()); err != nil {
+		t.Fatal(err)
+	}
+	if problems, err := m.Changed(dir); err != nil || len(problems) != 0 {
+		t.Errorf("Changed() of a file with its recorded mtime = %v, %v", problems, err)
+	}
+	want := Problem{Path: "README.md", Kind: "modified"}
+	if problems, err := m.Verify(dir); err != nil || len(problems) != 1 || problems[0] != want {
+		t.Errorf("Verify() = %v, %v, want %v", problems, err, want)
+	}
+
+	// A new modification time has the file hashed.
+	later := fi.ModTime().Add(time.Minute)
+	if err := os.Chtimes(readme, later, later); err != nil {
+		t.Fatal(err)
+	}
+	if problems, err := m.Changed(dir); err != nil || len(problems) != 1 || problems[0] != want {
+		t.Errorf("Changed() of a touched file = %v, %v, want %v", problems, err, want)
+	}
+}
+
+func TestLoad_NoManifest(t *testing.T) {
+	if _, err := Load(t.TempDir(), "https://github.com/owner/tool"); err != ErrNoManifest {
+		t.Errorf("Load() error = %v, want ErrNoManifest", err)
+	}
+}
+
+func TestPath(t *testing.T) {
+	got := filepath.Base(Path("/opt", "https://github.com/owner/tool"))
+	if got != ".ghinstall.manifest.github.com_owner_tool.json" {
+		t.Errorf("Path() = %s", got)
+	}
+}
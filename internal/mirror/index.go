@@ -0,0 +1,87 @@
+package mirror
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"slices"
+	"strings"
+	"time"
+)
+
+// IndexKey is the key of the index listing every mirrored asset.
+const IndexKey = "ghinstall-mirror.json"
+
+// ChecksumKey returns the key of the sha256sum-style checksum file stored
+// next to the asset at assetURL.
+func ChecksumKey(assetURL string) string {
+	return Key(assetURL) + ".sha256"
+}
+
+// Checksum returns the content of the checksum file of an asset.
+func Checksum(sha256, name string) []byte {
+	return []byte(sha256 + "  " + name + "\n")
+}
+
+// ParseChecksum returns the digest from the content of a checksum file.
+func ParseChecksum(data []byte) string {
+	digest, _, _ := strings.Cut(strings.TrimSpace(string(data)), " ")
+	return strings.ToLower(digest)
+}
+
+// Entry describes one mirrored asset.
+type Entry struct {
+	Repo     string    `json:"repo"`
+	Tag      string    `json:"tag"`
+	Asset    string    `json:"asset"`
+	URL      string    `json:"url"`
+	Size     int64     `json:"size"`
+	SHA256   string    `json:"sha256"`
+	SyncedAt time.Time `json:"synced_at"`
+}
+
+// Index lists the mirrored assets, for humans and tooling browsing the mirror.
+type Index struct {
+	Assets []Entry `json:"assets"`
+}
+
+// LoadIndex reads the index of store; a store without one has an empty index.
+func LoadIndex(ctx context.Context, store Store) (*Index, error) {
+	data, err := store.Get(ctx, IndexKey)
+	if errors.Is(err, fs.ErrNotExist) {
+		return &Index{}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read mirror index: %w", err)
+	}
+
+	var idx Index
+	if err := json.Unmarshal(data, &idx); err != nil {
+		return nil, fmt.Errorf("failed to parse mirror index: %w", err)
+	}
+	return &idx, nil
+}
+
+// Put adds e to the index, replacing the entry of the same asset URL.
+func (x *Index) Put(e Entry) {
+	if i := slices.IndexFunc(x.Assets, func(old Entry) bool { return old.URL == e.URL }); i >= 0 {
+		x.Assets[i] = e
+		return
+	}
+	x.Assets = append(x.Assets, e)
+}
+
+// Save writes the index to store.
+func (x *Index) Save(ctx context.Context, store Store) error {
+	data, err := json.MarshalIndent(x, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := store.Put(ctx, IndexKey, bytes.NewReader(append(data, '\n'))); err != nil {
+		return fmt.Errorf("failed to write mirror index: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,131 @@
+// Package mirror publishes release assets into a storage layout that ghinstall
+// can use as mirror_url.
+package mirror
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// TokenEnv names the environment variable holding a bearer token sent with
+// the uploads to an HTTP store, e.g. a Google Cloud Storage access token.
+const TokenEnv = "GHINSTALL_MIRROR_TOKEN"
+
+// Store holds mirrored objects under slash-separated keys.
+type Store interface {
+	// Get returns the content of key, or an error wrapping fs.ErrNotExist.
+	Get(ctx context.Context, key string) ([]byte, error)
+	// Put stores the content read from r under key. A read error aborts the
+	// upload, leaving any previous content of key in place.
+	Put(ctx context.Context, key string, r io.Reader) error
+}
+
+// Key returns the key under which a mirror serves assetURL: mirror_url
+// requests are "<mirror_url>/<asset URL>", so the key is the asset URL itself.
+func Key(assetURL string) string {
+	return assetURL
+}
+
+// Open returns the store for dest: an http(s) URL accepting PUT requests, such
+// as a Google Cloud Storage bucket or a WebDAV share, or a local directory to
+// publish with a web server or to copy into a bucket with its own tools.
+func Open(dest string) (Store, error) {
+	if strings.HasPrefix(dest, "https://") || strings.HasPrefix(dest, "http://") {
+		return &HTTPStore{base: strings.TrimSuffix(dest, "/"), token: os.Getenv(TokenEnv), client: http.DefaultClient}, nil
+	}
+	dir := strings.TrimPrefix(dest, "file://")
+	if dir == "" {
+		return nil, fmt.Errorf("mirror destination is required")
+	}
+	return DirStore(dir), nil
+}
+
+// DirStore stores objects as files below a local directory.
+type DirStore string
+
+func (d DirStore) path(key string) string {
+	return filepath.Join(string(d), filepath.FromSlash(key))
+}
+
+func (d DirStore) Get(ctx context.Context, key string) ([]byte, error) {
+	return os.ReadFile(d.path(key))
+}
+
+func (d DirStore) Put(ctx context.Context, key string, r io.Reader) error {
+	path := d.path(key)
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("failed to create directory for %s: %w", key, err)
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".upload-*")
+	if err != nil {
+		return fmt.Errorf("failed to create temp file for %s: %w", key, err)
+	}
+	defer os.Remove(tmp.Name())
+	defer tmp.Close()
+
+	if _, err := io.Copy(tmp, r); err != nil {
+		return fmt.Errorf("failed to write %s: %w", key, err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to write %s: %w", key, err)
+	}
+	if err := os.Chmod(tmp.Name(), 0644); err != nil {
+		return fmt.Errorf("failed to write %s: %w", key, err)
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+// HTTPStore stores objects with GET and PUT requests below a base URL.
+type HTTPStore struct {
+	base   string
+	token  string
+	client *http.Client
+}
+
+func (s *HTTPStore) request(ctx context.Context, method, key string, body io.Reader) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, method, s.base+"/"+key, body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request for %s: %w", key, err)
+	}
+	req.Header.Set("User-Agent", "ghinstall/1.0")
+	if s.token != "" {
+		req.Header.Set("Authorization", "Bearer "+s.token)
+	}
+	return s.client.Do(req)
+}
+
+func (s *HTTPStore) Get(ctx context.Context, key string) ([]byte, error) {
+	resp, err := s.request(ctx, http.MethodGet, key, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get %s: %w", key, err)
+	}
+	defer resp.Body.Close()
+
+	switch {
+	case resp.StatusCode == http.StatusNotFound:
+		return nil, fmt.Errorf("%s: %w", key, fs.ErrNotExist)
+	case resp.StatusCode < 200 || resp.StatusCode >= 300:
+		return nil, fmt.Errorf("get %s failed with status %d", key, resp.StatusCode)
+	}
+	return io.ReadAll(resp.Body)
+}
+
+func (s *HTTPStore) Put(ctx context.Context, key string, r io.Reader) error {
+	resp, err := s.request(ctx, http.MethodPut, key, r)
+	if err != nil {
+		return fmt.Errorf("failed to upload %s: %w", key, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("upload of %s failed with status %d", key, resp.StatusCode)
+	}
+	return nil
+}
@@ -0,0 +1,79 @@
+package mirror
+
+import (
+	"context"
+	"errors"
+	"io"
+	"io/fs"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestStores(t *testing.T) {
+	var mu sync.Mutex
+	objects := map[string]string{}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		if r.Header.Get("Authorization") != "Bearer secret" {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		switch r.Method {
+		case http.MethodPut:
+			data, _ := io.ReadAll(r.Body)
+			objects[r.URL.Path] = string(data)
+		case http.MethodGet:
+			data, ok := objects[r.URL.Path]
+			if !ok {
+				w.WriteHeader(http.StatusNotFound)
+				return
+			}
+			io.WriteString(w, data)
+		}
+	}))
+	defer srv.Close()
+	t.Setenv(TokenEnv, "secret")
+
+	for name, dest := range map[string]string{"dir": t.TempDir(), "http": srv.URL + "/bucket/"} {
+		t.Run(name, func(t *testing.T) {
+			ctx := context.Background()
+			store, err := Open(dest)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if _, err := store.Get(ctx, "owner/tool.tar.gz"); !errors.Is(err, fs.ErrNotExist) {
+				t.Errorf("Get(missing) error = %v, want fs.ErrNotExist", err)
+			}
+			if err := store.Put(ctx, "owner/tool.tar.gz", strings.NewReader("archive")); err != nil {
+				t.Fatalf("Put() error = %v", err)
+			}
+			if got, err := store.Get(ctx, "owner/tool.tar.gz"); err != nil || string(got) != "archive" {
+				t.Errorf("Get() = %q, %v", got, err)
+			}
+
+			idx, err := LoadIndex(ctx, store)
+			if err != nil || len(idx.Assets) != 0 {
+				t.Fatalf("LoadIndex(empty) = %+v, %v", idx, err)
+			}
+			idx.Put(Entry{URL: "u", Tag: "v1"})
+			idx.Put(Entry{URL: "u", Tag: "v2"})
+			if err := idx.Save(ctx, store); err != nil {
+				t.Fatal(err)
+			}
+			if idx, err := LoadIndex(ctx, store); err != nil || len(idx.Assets) != 1 || idx.Assets[0].Tag != "v2" {
+				t.Errorf("LoadIndex() = %+v, %v", idx, err)
+			}
+		})
+	}
+}
+
+func TestParseChecksum(t *testing.T) {
+	if got := ParseChecksum(Checksum("ABC123", "tool.tar.gz")); got != "abc123" {
+		t.Errorf("ParseChecksum() = %q", got)
+	}
+}
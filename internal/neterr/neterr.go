@@ -0,0 +1,184 @@
+// Package neterr classifies failed network requests and suggests how to fix
+// them, so users see what went wrong instead of a chain of wrapped errors.
+package neterr
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	neturl "net/url"
+	"os"
+	"strconv"
+	"time"
+)
+
+// Kind is the class of a network failure.
+type Kind int
+
+const (
+	// Unknown is any error that is not a recognized network failure.
+	Unknown Kind = iota
+	DNS
+	TLS
+	Timeout
+	RateLimit
+	NotFound
+)
+
+func (k Kind) String() string {
+	switch k {
+	case DNS:
+		return "dns"
+	case TLS:
+		return "tls"
+	case Timeout:
+		return "timeout"
+	case RateLimit:
+		return "rate limit"
+	case NotFound:
+		return "not found"
+	default:
+		return "unknown"
+	}
+}
+
+// StatusError is returned for a response with an unexpected status code.
+type StatusError struct {
+	URL  string
+	Code int
+	// RateLimited is set when GitHub refused the request because the rate
+	// limit of the client is exhausted.
+	RateLimited bool
+	// RetryAfter is how long the server asked to wait before retrying, from
+	// its Retry-After header; 0 when it did not say.
+	RetryAfter time.Duration
+	msg        string
+}
+
+func (e *StatusError) Error() string {
+	return e.msg
+}
+
+// Status returns the error for resp, described by msg.
+func Status(resp *http.Response, msg string) error {
+	err := &StatusError{Code: resp.StatusCode, msg: msg}
+	if resp.Request != nil {
+		err.URL = resp.Request.URL.String()
+	}
+	err.RateLimited = resp.StatusCode == http.StatusTooManyRequests ||
+		resp.StatusCode == http.StatusForbidden && resp.Header.Get("X-RateLimit-Remaining") == "0"
+	err.RetryAfter = retryAfter(resp.Header.Get("Retry-After"), time.Now())
+	return err
+}
+
+// retryAfter parses a Retry-After header, in seconds or as an HTTP date.
+func retryAfter(header string, now time.Time) time.Duration {
+	if header == "" {
+		return 0
+	}
+	if secs, err := strconv.Atoi(header); err == nil {
+		return max(time.Duration(secs)*time.Second, 0)
+	}
+	if t, err := http.ParseTime(header); err == nil {
+		return max(t.Sub(now), 0)
+	}
+	return 0
+}
+
+// Classify returns the kind of network failure err is.
+func Classify(err error) Kind {
+	var statusErr *StatusError
+	if errors.As(err, &statusErr) {
+		switch {
+		case statusErr.RateLimited:
+			return RateLimit
+		case statusErr.Code == http.StatusNotFound:
+			return NotFound
+		}
+		return Unknown
+	}
+
+	var dnsErr *net.DNSError
+	if errors.As(err, &dnsErr) {
+		return DNS
+	}
+	if isTLS(err) {
+		return TLS
+	}
+	var netErr net.Error
+	if errors.Is(err, context.DeadlineExceeded) || errors.Is(err, os.ErrDeadlineExceeded) || errors.As(err, &netErr) && netErr.Timeout() {
+		return Timeout
+	}
+	return Unknown
+}
+
+func isTLS(err error) bool {
+	var verifyErr *tls.CertificateVerificationError
+	var recordErr tls.RecordHeaderError
+	var alertErr tls.AlertError
+	var authorityErr x509.UnknownAuthorityError
+	var hostnameErr x509.HostnameError
+	var invalidErr x509.CertificateInvalidError
+	return errors.As(err, &verifyErr) || errors.As(err, &recordErr) || errors.As(err, &alertErr) ||
+		errors.As(err, &authorityErr) || errors.As(err, &hostnameErr) || errors.As(err, &invalidErr)
+}
+
+// Describe returns a one-line description of the network failure err, without
+// the errors wrapping it. It returns err.Error() for Unknown errors.
+func Describe(err error) string {
+	switch Classify(err) {
+	case DNS:
+		var dnsErr *net.DNSError
+		errors.As(err, &dnsErr)
+		return fmt.Sprintf("cannot resolve host %s", dnsErr.Name)
+	case TLS:
+		return fmt.Sprintf("TLS connection to %s could not be verified", host(err))
+	case Timeout:
+		return fmt.Sprintf("request to %s timed out", host(err))
+	case RateLimit:
+		return "GitHub API rate limit exceeded"
+	case NotFound:
+		var statusErr *StatusError
+		errors.As(err, &statusErr)
+		return fmt.Sprintf("%s was not found", statusErr.URL)
+	default:
+		return err.Error()
+	}
+}
+
+// Hint suggests how to fix the network failure err; it is empty for Unknown
+// errors.
+func Hint(err error) string {
+	switch Classify(err) {
+	case DNS:
+		return "check your network connection and DNS settings, or set mirror_url to a mirror you can reach"
+	case TLS:
+		return "if a proxy inspects HTTPS traffic, add its CA certificate to the system trust store or point SSL_CERT_FILE at it; also check the system clock"
+	case Timeout:
+		return "check your network and proxy settings (HTTPS_PROXY), raise -timeout, or set mirror_url"
+	case RateLimit:
+		if os.Getenv("GITHUB_TOKEN") == "" && os.Getenv("GH_TOKEN") == "" {
+			return "set GITHUB_TOKEN to a GitHub token to raise the limit from 60 to 5000 requests per hour"
+		}
+		return "wait for the limit to reset, or set mirror_url to download through a mirror"
+	case NotFound:
+		return "check the repository URL, version and asset patterns; private repositories need GITHUB_TOKEN"
+	default:
+		return ""
+	}
+}
+
+// host returns the host of the request that failed with err, or "the server".
+func host(err error) string {
+	var urlErr *neturl.Error
+	if errors.As(err, &urlErr) {
+		if u, perr := neturl.Parse(urlErr.URL); perr == nil && u.Host != "" {
+			return u.Host
+		}
+	}
+	return "the server"
+}
@@ -0,0 +1,98 @@
+package neterr
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func get(t *testing.T, url string) error {
+	t.Helper()
+	resp, err := http.Get(url)
+	if err != nil {
+		return fmt.Errorf("failed to fetch: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("failed to fetch: %w", Status(resp, fmt.Sprintf("status %d", resp.StatusCode)))
+	}
+	return nil
+}
+
+func TestClassify(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/limited":
+			w.Header().Set("X-RateLimit-Remaining", "0")
+			w.WriteHeader(http.StatusForbidden)
+		case "/forbidden":
+			w.WriteHeader(http.StatusForbidden)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+	tlsServer := httptest.NewTLSServer(http.NotFoundHandler())
+	defer tlsServer.Close()
+
+	tests := []struct {
+		name string
+		err  error
+		want Kind
+	}{
+		{name: "not found", err: get(t, server.URL+"/missing"), want: NotFound},
+		{name: "rate limit", err: get(t, server.URL+"/limited"), want: RateLimit},
+		{name: "other status", err: get(t, server.URL+"/forbidden"), want: Unknown},
+		{name: "untrusted certificate", err: get(t, tlsServer.URL), want: TLS},
+		{name: "dns", err: fmt.Errorf("failed: %w", &net.DNSError{Err: "no such host", Name: "api.example.invalid"}), want: DNS},
+		{name: "deadline", err: fmt.Errorf("failed: %w", context.DeadlineExceeded), want: Timeout},
+		{name: "other", err: fmt.Errorf("failed to extract"), want: Unknown},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Classify(tt.err); got != tt.want {
+				t.Errorf("Classify(%v) = %v, want %v", tt.err, got, tt.want)
+			}
+			hint := Hint(tt.err)
+			if (hint == "") != (tt.want == Unknown) {
+				t.Errorf("Hint(%v) = %q", tt.err, hint)
+			}
+		})
+	}
+}
+
+func TestDescribe(t *testing.T) {
+	err := fmt.Errorf("failed to fetch releases: %w", &net.DNSError{Err: "no such host", Name: "api.github.com"})
+	if got := Describe(err); got != "cannot resolve host api.github.com" {
+		t.Errorf("Describe() = %q", got)
+	}
+
+	t.Setenv("GITHUB_TOKEN", "")
+	t.Setenv("GH_TOKEN", "")
+	limited := &StatusError{Code: http.StatusForbidden, RateLimited: true}
+	if got := Hint(limited); !strings.Contains(got, "GITHUB_TOKEN") {
+		t.Errorf("Hint() = %q, want a hint to set GITHUB_TOKEN", got)
+	}
+}
+
+func TestRetryAfter(t *testing.T) {
+	now := time.Date(2024, 1, 1, 8, 0, 0, 0, time.UTC)
+	tests := map[string]time.Duration{
+		"":                              0,
+		"30":                            30 * time.Second,
+		"-5":                            0,
+		"Mon, 01 Jan 2024 08:01:00 GMT": time.Minute,
+		"soon":                          0,
+	}
+	for header, want := range tests {
+		if got := retryAfter(header, now); got != want {
+			t.Errorf("retryAfter(%q) = %s, want %s", header, got, want)
+		}
+	}
+}
@@ -0,0 +1,231 @@
+package progress
+
+import (
+	"cmp"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/sixban6/ghinstall/internal/config"
+	"github.com/sixban6/ghinstall/internal/installer"
+)
+
+// Event is one line of the JSON event stream written by Events.
+type Event struct {
+	Time      time.Time `json:"time"`
+	Event     string    `json:"event"`
+	Repo      string    `json:"repo"`
+	Name      string    `json:"name,omitempty"`
+	OutputDir string    `json:"output_dir"`
+	Tag       string    `json:"tag,omitempty"`
+	Asset     string    `json:"asset,omitempty"`
+	Size      int64     `json:"size,omitempty"`
+	// Downloaded and Total are the bytes of a download_progress event; Speed
+	// is in bytes per second and ETA in seconds, both omitted while unknown.
+	Downloaded int64   `json:"downloaded,omitempty"`
+	Total      int64   `json:"total,omitempty"`
+	Speed      float64 `json:"speed,omitempty"`
+	ETA        float64 `json:"eta_seconds,omitempty"`
+	Error      string  `json:"error,omitempty"`
+	// Retries, Sources and WastedBytes describe the retries of the download
+	// in extract_done, error and summary events, omitted without retries.
+	Retries     int      `json:"retries,omitempty"`
+	Sources     []string `json:"sources,omitempty"`
+	WastedBytes int64    `json:"wasted_bytes,omitempty"`
+}
+
+// Event names, in the order an install emits them.
+const (
+	ResolveStarted   = "resolve_started"
+	ReleaseResolved  = "release_resolved"
+	AssetSelected    = "asset_selected"
+	DownloadProgress = "download_progress"
+	ExtractDone      = "extract_done"
+	InstallDone      = "install_done"
+	InstallError     = "error"
+	// Summary ends the stream with one event per repository, in config
+	// order, carrying its outcome.
+	Summary = "summary"
+)
+
+// eventInterval limits download_progress events per repository.
+const eventInterval = 250 * time.Millisecond
+
+// Events writes a JSON object per line for every state transition of every
+// install, for wrapper tools and GUIs rendering their own progress. Register
+// it both as installer.Middleware, for the stages, and as installer.Progress,
+// for downloads and the outcome. Events are written as they happen, which
+// interleaves the repositories of parallel installs; the summary written by
+// Close lists them in config order, with fields in a fixed order, so it can
+// be compared between runs.
+type Events struct {
+	mu        sync.Mutex
+	enc       *json.Encoder
+	downloads map[string]*download
+	repos     []config.Repo
+	results   map[string]*result
+	// now is replaced by tests.
+	now func() time.Time
+}
+
+type download struct {
+	meter    Meter
+	lastSent time.Time
+}
+
+// result is what the summary reports of a repository.
+type result struct {
+	tag, asset string
+	done       bool
+	err        error
+	download   installer.DownloadStats
+}
+
+// NewEvents returns Events writing to out and summarizing repos on Close.
+func NewEvents(out io.Writer, repos []config.Repo) *Events {
+	return &Events{
+		enc:       json.NewEncoder(out),
+		downloads: make(map[string]*download),
+		repos:     repos,
+		results:   make(map[string]*result),
+		now:       time.Now,
+	}
+}
+
+func (e *Events) Intercept(ctx context.Context, step installer.Step) error {
+	ev := Event{}
+	switch step.Stage {
+	case installer.BeforeResolve:
+		ev.Event = ResolveStarted
+	case installer.AfterResolve:
+		ev.Event, ev.Tag = ReleaseResolved, step.Release.TagName
+	case installer.BeforeDownload:
+		ev.Event, ev.Tag, ev.Asset, ev.Size = AssetSelected, step.Release.TagName, step.Asset.Name, step.Asset.Size
+	case installer.AfterExtract:
+		ev.Event, ev.Tag, ev.Asset = ExtractDone, step.Result.Tag, step.Result.Asset.Name
+		ev.setDownload(step.Result.Download)
+	default:
+		return nil
+	}
+	e.record(step.Repo, func(r *result) {
+		r.tag = cmp.Or(ev.Tag, r.tag)
+		r.asset = cmp.Or(ev.Asset, r.asset)
+		if step.Result != nil {
+			r.download = step.Result.Download
+		}
+	})
+	e.emit(step.Repo, ev)
+	return nil
+}
+
+func (e *Events) Status(repo config.Repo, status string) {}
+
+func (e *Events) Downloaded(repo config.Repo, n, total int64) {
+	e.mu.Lock()
+	d, ok := e.downloads[key(repo)]
+	if !ok {
+		d = &download{}
+		e.downloads[key(repo)] = d
+	}
+	now := e.now()
+	d.meter.Add(now, n)
+	if now.Sub(d.lastSent) < eventInterval && (total == 0 || n < total) {
+		e.mu.Unlock()
+		return
+	}
+	d.lastSent = now
+
+	ev := Event{Event: DownloadProgress, Downloaded: n, Total: total}
+	if speed, ok := d.meter.Speed(); ok {
+		ev.Speed = speed
+	}
+	if eta, ok := d.meter.ETA(total); ok {
+		ev.ETA = eta.Seconds()
+	}
+	e.mu.Unlock()
+	e.emit(repo, ev)
+}
+
+func (e *Events) Done(repo config.Repo, err error) {
+	e.mu.Lock()
+	delete(e.downloads, key(repo))
+	e.mu.Unlock()
+	stats, failed := failedDownload(err)
+	e.record(repo, func(r *result) {
+		r.done, r.err = true, err
+		if failed {
+			r.download = stats
+		}
+	})
+
+	if err != nil {
+		ev := Event{Event: InstallError, Error: err.Error()}
+		ev.setDownload(stats)
+		e.emit(repo, ev)
+		return
+	}
+	e.emit(repo, Event{Event: InstallDone})
+}
+
+// Close writes a summary event for every repository in config order.
+// Repositories that never finished are reported as not installed.
+func (e *Events) Close() error {
+	for _, repo := range e.repos {
+		ev := Event{Event: Summary, Error: "not installed"}
+		e.mu.Lock()
+		if r, ok := e.results[key(repo)]; ok {
+			ev.Tag, ev.Asset = r.tag, r.asset
+			ev.setDownload(r.download)
+			if r.done {
+				ev.Error = ""
+				if r.err != nil {
+					ev.Error = r.err.Error()
+				}
+			}
+		}
+		e.mu.Unlock()
+		e.emit(repo, ev)
+	}
+	return nil
+}
+
+// setDownload adds the retries of stats to ev.
+func (ev *Event) setDownload(stats installer.DownloadStats) {
+	if stats.Retries > 0 {
+		ev.Retries, ev.Sources, ev.WastedBytes = stats.Retries, stats.Sources, stats.WastedBytes
+	}
+}
+
+// failedDownload returns the retries of the download that failed with err,
+// and whether it was one.
+func failedDownload(err error) (installer.DownloadStats, bool) {
+	var dlErr *installer.DownloadError
+	if errors.As(err, &dlErr) {
+		return dlErr.Stats, true
+	}
+	return installer.DownloadStats{}, false
+}
+
+// record updates the result of repo.
+func (e *Events) record(repo config.Repo, fn func(*result)) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	r, ok := e.results[key(repo)]
+	if !ok {
+		r = &result{}
+		e.results[key(repo)] = r
+	}
+	fn(r)
+}
+
+func (e *Events) emit(repo config.Repo, ev Event) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	ev.Time = e.now().UTC()
+	ev.Repo, ev.Name, ev.OutputDir = repo.URL, repo.Name, repo.OutputDir
+	// A consumer that went away must not fail the installs.
+	_ = e.enc.Encode(ev)
+}
@@ -0,0 +1,107 @@
+package progress
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/sixban6/ghinstall/internal/config"
+	"github.com/sixban6/ghinstall/internal/installer"
+	"github.com/sixban6/ghinstall/internal/release"
+)
+
+func TestEvents(t *testing.T) {
+	var out bytes.Buffer
+	ev := NewEvents(&out, nil)
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	ev.now = func() time.Time { return now }
+
+	rel := &release.Release{TagName: "v1.0.0"}
+	asset := &release.Asset{Name: "app.tar.gz", Size: 4000}
+	ctx := context.Background()
+
+	ev.Intercept(ctx, installer.Step{Stage: installer.BeforeResolve, Repo: repoA})
+	ev.Intercept(ctx, installer.Step{Stage: installer.AfterResolve, Repo: repoA, Release: rel})
+	ev.Intercept(ctx, installer.Step{Stage: installer.BeforeDownload, Repo: repoA, Release: rel, Asset: asset})
+	ev.Downloaded(repoA, 0, 4000)
+	now = now.Add(100 * time.Millisecond)
+	ev.Downloaded(repoA, 100, 4000) // throttled
+	now = now.Add(900 * time.Millisecond)
+	ev.Downloaded(repoA, 1000, 4000)
+	now = now.Add(10 * time.Millisecond)
+	ev.Downloaded(repoA, 4000, 4000) // complete, never throttled
+	ev.Intercept(ctx, installer.Step{Stage: installer.AfterExtract, Repo: repoA, Release: rel, Asset: asset,
+		Result: &installer.InstallResult{Repo: repoA, Tag: "v1.0.0", Asset: *asset}})
+	ev.Done(repoA, nil)
+	ev.Done(repoB, errors.New("no suitable asset"))
+
+	var got []Event
+	dec := json.NewDecoder(&out)
+	for dec.More() {
+		var e Event
+		if err := dec.Decode(&e); err != nil {
+			t.Fatal(err)
+		}
+		got = append(got, e)
+	}
+
+	want := []Event{
+		{Event: ResolveStarted},
+		{Event: ReleaseResolved, Tag: "v1.0.0"},
+		{Event: AssetSelected, Tag: "v1.0.0", Asset: "app.tar.gz", Size: 4000},
+		{Event: DownloadProgress, Total: 4000},
+		{Event: DownloadProgress, Downloaded: 1000, Total: 4000, Speed: 1000, ETA: 3},
+		{Event: DownloadProgress, Downloaded: 4000, Total: 4000},
+		{Event: ExtractDone, Tag: "v1.0.0", Asset: "app.tar.gz"},
+		{Event: InstallDone},
+		{Event: InstallError, Error: "no suitable asset"},
+	}
+	if len(got) != len(want) {
+		t.Fatalf("got %d events, want %d: %+v", len(got), len(want), got)
+	}
+	for i := range want {
+		g := got[i]
+		if i < len(want)-1 && (g.Repo != repoA.URL || g.OutputDir != repoA.OutputDir) {
+			t.Errorf("event %d is for %s in %s", i, g.Repo, g.OutputDir)
+		}
+		if g.Time.IsZero() {
+			t.Errorf("event %d has no time", i)
+		}
+		g.Time, g.Repo, g.Name, g.OutputDir = time.Time{}, "", "", ""
+		// The speed of the final event depends on the merged samples.
+		if g.Event == DownloadProgress && g.Downloaded == g.Total {
+			g.Speed, g.ETA = 0, 0
+		}
+		if !reflect.DeepEqual(g, want[i]) {
+			t.Errorf("event %d = %+v, want %+v", i, g, want[i])
+		}
+	}
+}
+
+func TestEvents_Summary(t *testing.T) {
+	repoC := config.Repo{URL: "https://github.com/owner/c", OutputDir: "/opt/c"}
+	var out bytes.Buffer
+	ev := NewEvents(&out, []config.Repo{repoA, repoB, repoC})
+	ev.now = func() time.Time { return time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC) }
+
+	// Parallel installs finish in any order.
+	ev.Done(repoB, errors.New("no suitable asset"))
+	ev.Intercept(context.Background(), installer.Step{Stage: installer.AfterExtract, Repo: repoA,
+		Result: &installer.InstallResult{Repo: repoA, Tag: "v1.0.0", Asset: release.Asset{Name: "app.tar.gz"}}})
+	ev.Done(repoA, nil)
+	out.Reset()
+	ev.Close()
+
+	want := `{"time":"2024-01-01T00:00:00Z","event":"summary","repo":"https://github.com/owner/a","output_dir":"/opt/a","tag":"v1.0.0","asset":"app.tar.gz"}
+{"time":"2024-01-01T00:00:00Z","event":"summary","repo":"https://github.com/owner/b","name":"bee","output_dir":"/opt/b","error":"no suitable asset"}
+{"time":"2024-01-01T00:00:00Z","event":"summary","repo":"https://github.com/owner/c","output_dir":"/opt/c","error":"not installed"}
+`
+	if got := out.String(); got != want {
+		t.Errorf("summary =\n%s\nwant\n%s", strings.TrimSpace(got), strings.TrimSpace(want))
+	}
+}
@@ -0,0 +1,83 @@
+package progress
+
+import (
+	"fmt"
+	"time"
+)
+
+const (
+	// meterWindow is how far back the rolling speed looks, so it follows
+	// changes of the transfer rate without jumping with every read.
+	meterWindow = 5 * time.Second
+	// meterResolution merges samples closer together than this.
+	meterResolution = 100 * time.Millisecond
+	// minSpan is the shortest span of samples a speed is computed from.
+	minSpan = 500 * time.Millisecond
+)
+
+// Meter computes the rolling transfer speed of a download, and from it the
+// estimated time remaining, from samples of the bytes transferred so far.
+// The zero value is ready to use; it is not safe for concurrent use.
+type Meter struct {
+	samples []sample
+}
+
+type sample struct {
+	at time.Time
+	n  int64
+}
+
+// Add records that n bytes were transferred in total at time at.
+func (m *Meter) Add(at time.Time, n int64) {
+	if last := len(m.samples) - 1; last > 0 && at.Sub(m.samples[last-1].at) < meterResolution {
+		m.samples[last] = sample{at: at, n: n}
+	} else {
+		m.samples = append(m.samples, sample{at: at, n: n})
+	}
+
+	// Keep the newest sample older than the window, so the window is covered
+	// completely.
+	drop := 0
+	for drop+1 < len(m.samples) && at.Sub(m.samples[drop+1].at) >= meterWindow {
+		drop++
+	}
+	m.samples = m.samples[drop:]
+}
+
+// Speed returns the rolling transfer speed in bytes per second, and false
+// while too little was sampled to tell.
+func (m *Meter) Speed() (float64, bool) {
+	if len(m.samples) < 2 {
+		return 0, false
+	}
+	first, last := m.samples[0], m.samples[len(m.samples)-1]
+	span := last.at.Sub(first.at)
+	if span < minSpan {
+		return 0, false
+	}
+	return float64(last.n-first.n) / span.Seconds(), true
+}
+
+// ETA returns the estimated time until total bytes are transferred, and false
+// when it cannot be estimated: the total or the speed is unknown or stalled.
+func (m *Meter) ETA(total int64) (time.Duration, bool) {
+	speed, ok := m.Speed()
+	if !ok || speed <= 0 || total <= 0 {
+		return 0, false
+	}
+	left := total - m.samples[len(m.samples)-1].n
+	return time.Duration(float64(max(left, 0)) / speed * float64(time.Second)), true
+}
+
+// formatSpeed formats a speed in bytes per second for humans.
+func formatSpeed(speed float64) string {
+	if speed >= 1024*1024 {
+		return fmt.Sprintf("%.1f MB/s", speed/(1024*1024))
+	}
+	return fmt.Sprintf("%.0f kB/s", speed/1024)
+}
+
+// formatETA formats a remaining time for humans, in whole seconds.
+func formatETA(d time.Duration) string {
+	return "ETA " + max(d.Round(time.Second), time.Second).String()
+}
@@ -0,0 +1,76 @@
+package progress
+
+import (
+	"testing"
+	"time"
+)
+
+func TestMeter(t *testing.T) {
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	at := func(ms int) time.Time { return start.Add(time.Duration(ms) * time.Millisecond) }
+
+	tests := []struct {
+		name      string
+		samples   map[int]int64
+		total     int64
+		wantSpeed float64
+		wantOK    bool
+		wantETA   time.Duration
+		wantETAOK bool
+	}{
+		{name: "no samples"},
+		{name: "too short", samples: map[int]int64{0: 0, 200: 1000}},
+		{
+			name:    "steady",
+			samples: map[int]int64{0: 0, 500: 500, 1000: 1000},
+			total:   3000, wantSpeed: 1000, wantOK: true, wantETA: 2 * time.Second, wantETAOK: true,
+		},
+		{
+			name:      "unknown total",
+			samples:   map[int]int64{0: 0, 1000: 1000},
+			wantSpeed: 1000, wantOK: true,
+		},
+		{
+			name: "rolling window forgets the fast start",
+			// 10000 bytes in the first second, then 100 bytes per second.
+			samples: map[int]int64{0: 0, 1000: 10000, 2000: 10100, 3000: 10200, 4000: 10300, 5000: 10400, 6000: 10500, 7000: 10600},
+			total:   11000, wantSpeed: 100, wantOK: true, wantETA: 4 * time.Second, wantETAOK: true,
+		},
+		{
+			name:    "stalled",
+			samples: map[int]int64{0: 1000, 1000: 1000},
+			total:   3000, wantSpeed: 0, wantOK: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var m Meter
+			for ms := 0; ms <= 10000; ms++ {
+				if n, ok := tt.samples[ms]; ok {
+					m.Add(at(ms), n)
+				}
+			}
+
+			speed, ok := m.Speed()
+			if ok != tt.wantOK || speed != tt.wantSpeed {
+				t.Errorf("Speed() = %v, %v; want %v, %v", speed, ok, tt.wantSpeed, tt.wantOK)
+			}
+			eta, ok := m.ETA(tt.total)
+			if ok != tt.wantETAOK || eta != tt.wantETA {
+				t.Errorf("ETA() = %v, %v; want %v, %v", eta, ok, tt.wantETA, tt.wantETAOK)
+			}
+		})
+	}
+}
+
+func TestMeter_MergesCloseSamples(t *testing.T) {
+	start := time.Now()
+	var m Meter
+	for i := range 1000 {
+		m.Add(start.Add(time.Duration(i)*time.Millisecond), int64(i))
+	}
+	if len(m.samples) > 12 {
+		t.Errorf("kept %d samples for one second of reads", len(m.samples))
+	}
+}
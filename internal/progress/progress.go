@@ -0,0 +1,348 @@
+// Package progress reports installs as they run: as one live line per
+// repository and a summary line on a terminal, as plain log lines, or as a
+// JSON event stream for other programs.
+package progress
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sixban6/ghinstall/internal/config"
+	"github.com/sixban6/ghinstall/internal/installer"
+)
+
+// IsTerminal reports whether f is an interactive terminal able to redraw lines.
+func IsTerminal(f *os.File) bool {
+	if os.Getenv("TERM") == "dumb" {
+		return false
+	}
+	fi, err := f.Stat()
+	return err == nil && fi.Mode()&os.ModeCharDevice != 0
+}
+
+// redrawInterval limits how often download progress redraws the lines.
+const redrawInterval = 100 * time.Millisecond
+
+// Terminal draws the progress lines at the bottom of a terminal. Log output
+// written to it is printed above them, so logs of parallel installs do not
+// tear the lines apart. It implements installer.Progress.
+type Terminal struct {
+	mu    sync.Mutex
+	out   io.Writer
+	width int
+	lines []*line
+	byKey map[string]*line
+	// drawn is the number of lines currently on screen below the logs.
+	drawn    int
+	lastDraw time.Time
+	closed   bool
+	// now is replaced by tests.
+	now func() time.Time
+}
+
+type line struct {
+	name     string
+	status   string
+	done     int64
+	total    int64
+	finished bool
+	err      error
+	meter    Meter
+}
+
+// NewTerminal returns a Terminal drawing a line for each of repos on out.
+func NewTerminal(out io.Writer, repos []config.Repo) *Terminal {
+	t := &Terminal{out: out, width: width(), byKey: make(map[string]*line), now: time.Now}
+	for _, repo := range repos {
+		l := &line{name: label(repo), status: "waiting"}
+		t.lines = append(t.lines, l)
+		t.byKey[key(repo)] = l
+	}
+	return t
+}
+
+// key identifies repo: the config allows a repository URL only once per
+// output directory.
+func key(repo config.Repo) string {
+	return repo.URL + "\x00" + repo.OutputDir
+}
+
+// label names repo on its line: its name, or "owner/repo" as the full URL
+// would take up most of the line.
+func label(repo config.Repo) string {
+	if repo.Name != "" {
+		return repo.Name
+	}
+	if owner, name, err := config.ParseRepoURL(repo.URL); err == nil {
+		return owner + "/" + name
+	}
+	return repo.URL
+}
+
+// width returns the terminal width from COLUMNS; lines longer than the
+// terminal would wrap and break the redrawing.
+func width() int {
+	if n, err := strconv.Atoi(os.Getenv("COLUMNS")); err == nil && n > 20 {
+		return n
+	}
+	return 80
+}
+
+func (t *Terminal) Status(repo config.Repo, status string) {
+	t.update(repo, true, func(l *line) {
+		l.status = status
+	})
+}
+
+func (t *Terminal) Downloaded(repo config.Repo, n, total int64) {
+	t.update(repo, false, func(l *line) {
+		l.done, l.total = n, total
+		l.meter.Add(t.now(), n)
+	})
+}
+
+func (t *Terminal) Done(repo config.Repo, err error) {
+	t.update(repo, true, func(l *line) {
+		l.finished, l.err = true, err
+	})
+}
+
+func (t *Terminal) update(repo config.Repo, force bool, fn func(*line)) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	l, ok := t.byKey[key(repo)]
+	if !ok {
+		return
+	}
+	fn(l)
+	if force || t.now().Sub(t.lastDraw) >= redrawInterval {
+		t.redraw()
+	}
+}
+
+// Write prints p, complete log lines, above the progress lines.
+func (t *Terminal) Write(p []byte) (int, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.closed {
+		return t.out.Write(p)
+	}
+	var buf bytes.Buffer
+	t.clear(&buf)
+	buf.Write(p)
+	t.draw(&buf)
+	if _, err := t.out.Write(buf.Bytes()); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+// Close draws the final state of the lines and leaves them on screen; later
+// writes go straight to the terminal.
+func (t *Terminal) Close() error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if !t.closed {
+		t.redraw()
+		t.closed = true
+	}
+	return nil
+}
+
+func (t *Terminal) redraw() {
+	var buf bytes.Buffer
+	t.clear(&buf)
+	t.draw(&buf)
+	t.out.Write(buf.Bytes())
+}
+
+// clear moves the cursor up over the drawn lines, erasing them.
+func (t *Terminal) clear(buf *bytes.Buffer) {
+	for ; t.drawn > 0; t.drawn-- {
+		buf.WriteString("\033[1A\033[2K")
+	}
+	buf.WriteString("\r")
+}
+
+func (t *Terminal) draw(buf *bytes.Buffer) {
+	nameWidth := 0
+	for _, l := range t.lines {
+		nameWidth = max(nameWidth, len([]rune(l.name)))
+	}
+
+	var finished, failed int
+	var done, total int64
+	var speed float64
+	for _, l := range t.lines {
+		t.writeLine(buf, fmt.Sprintf("%-*s  %s", nameWidth, l.name, l.describe()))
+		if l.finished {
+			finished++
+			if l.err != nil && !errors.Is(l.err, context.Canceled) {
+				failed++
+			}
+		}
+		done += l.done
+		total += max(l.total, l.done)
+		if s, ok := l.meter.Speed(); ok && !l.finished {
+			speed += s
+		}
+	}
+
+	summary := fmt.Sprintf("%d/%d done", finished, len(t.lines))
+	if failed > 0 {
+		summary += fmt.Sprintf(", %d failed", failed)
+	}
+	if total > 0 {
+		summary += fmt.Sprintf(", %s of %s downloaded", formatBytes(done), formatBytes(total))
+	}
+	if speed > 0 {
+		summary += " at " + formatSpeed(speed)
+	}
+	t.writeLine(buf, summary)
+	t.lastDraw = t.now()
+}
+
+func (t *Terminal) writeLine(buf *bytes.Buffer, s string) {
+	if r := []rune(s); len(r) >= t.width {
+		s = string(r[:t.width-1])
+	}
+	buf.WriteString(s)
+	buf.WriteString("\n")
+	t.drawn++
+}
+
+func (l *line) describe() string {
+	switch {
+	case l.finished && errors.Is(l.err, context.Canceled):
+		return "cancelled"
+	case l.finished && l.err != nil:
+		return "failed: " + strings.ReplaceAll(l.err.Error(), "\n", " ")
+	case l.finished:
+		return "installed"
+	case l.total > 0:
+		return fmt.Sprintf("%s  %s / %s  %3d%%", l.status, formatBytes(l.done), formatBytes(l.total), l.done*100/max(l.total, l.done)) + l.rate()
+	case l.done > 0:
+		return fmt.Sprintf("%s  %s", l.status, formatBytes(l.done)) + l.rate()
+	default:
+		return l.status
+	}
+}
+
+// rate returns the speed and remaining time of a running download, if known.
+func (l *line) rate() string {
+	if l.done >= l.total && l.total > 0 {
+		return ""
+	}
+	speed, ok := l.meter.Speed()
+	if !ok {
+		return ""
+	}
+	s := "  " + formatSpeed(speed)
+	if eta, ok := l.meter.ETA(l.total); ok {
+		s += "  " + formatETA(eta)
+	}
+	return s
+}
+
+func formatBytes(n int64) string {
+	return fmt.Sprintf("%.1f MB", float64(n)/(1024*1024))
+}
+
+// Plain reports status changes as ordinary log lines naming the repository,
+// for output that is not a terminal. Download progress is not reported. The
+// lines of parallel installs interleave as they happen, so Close repeats the
+// outcome of every repository in config order, which stays the same between
+// runs with the same results.
+type Plain struct {
+	mu      sync.Mutex
+	out     io.Writer
+	repos   []config.Repo
+	results map[string]error
+}
+
+// NewPlain returns a Plain writing to out and summarizing repos on Close.
+func NewPlain(out io.Writer, repos []config.Repo) *Plain {
+	return &Plain{out: out, repos: repos, results: make(map[string]error)}
+}
+
+func (p *Plain) Status(repo config.Repo, status string) {
+	p.printf("%s: %s\n", repo.DisplayName(), status)
+}
+
+func (p *Plain) Downloaded(repo config.Repo, n, total int64) {}
+
+func (p *Plain) Done(repo config.Repo, err error) {
+	p.mu.Lock()
+	p.results[key(repo)] = err
+	p.mu.Unlock()
+	p.printf("%s: %s\n", repo.DisplayName(), outcome(err))
+}
+
+// Close prints the outcome of every repository in config order.
+func (p *Plain) Close() error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if len(p.repos) == 0 {
+		return nil
+	}
+	fmt.Fprintln(p.out, "Results:")
+	for _, repo := range p.repos {
+		result := "not installed"
+		if err, ok := p.results[key(repo)]; ok {
+			result = outcome(err)
+		}
+		fmt.Fprintf(p.out, "  %s: %s\n", repo.DisplayName(), result)
+	}
+	return nil
+}
+
+// outcome describes how an install finished.
+func outcome(err error) string {
+	switch {
+	case errors.Is(err, context.Canceled):
+		return "cancelled"
+	case err != nil:
+		return fmt.Sprintf("failed: %v", err)
+	default:
+		return "installed"
+	}
+}
+
+func (p *Plain) printf(format string, args ...any) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	fmt.Fprintf(p.out, format, args...)
+}
+
+// Multi reports to every one of ps, e.g. to a Terminal and a Report.
+func Multi(ps ...installer.Progress) installer.Progress {
+	return multi(ps)
+}
+
+type multi []installer.Progress
+
+func (m multi) Status(repo config.Repo, status string) {
+	for _, p := range m {
+		p.Status(repo, status)
+	}
+}
+
+func (m multi) Downloaded(repo config.Repo, n, total int64) {
+	for _, p := range m {
+		p.Downloaded(repo, n, total)
+	}
+}
+
+func (m multi) Done(repo config.Repo, err error) {
+	for _, p := range m {
+		p.Done(repo, err)
+	}
+}
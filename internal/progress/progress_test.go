@@ -0,0 +1,146 @@
+package progress
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/sixban6/ghinstall/internal/config"
+)
+
+var (
+	repoA = config.Repo{URL: "https://github.com/owner/a", OutputDir: "/opt/a"}
+	repoB = config.Repo{URL: "https://github.com/owner/b", OutputDir: "/opt/b", Name: "bee"}
+)
+
+// screen replays the cursor movements of out and returns the visible lines.
+func screen(out string) []string {
+	var lines []string
+	cur := 0
+	for len(out) > 0 {
+		switch {
+		case strings.HasPrefix(out, "\033[1A"):
+			cur--
+			out = out[4:]
+		case strings.HasPrefix(out, "\033[2K"):
+			lines[cur] = ""
+			out = out[4:]
+		case out[0] == '\r':
+			out = out[1:]
+		default:
+			end := strings.IndexAny(out, "\n\033")
+			if end < 0 {
+				end = len(out)
+			}
+			for cur >= len(lines) {
+				lines = append(lines, "")
+			}
+			lines[cur] += out[:end]
+			out = out[end:]
+			if strings.HasPrefix(out, "\n") {
+				cur++
+				out = out[1:]
+			}
+		}
+	}
+	return lines[:cur]
+}
+
+func TestTerminal(t *testing.T) {
+	var out bytes.Buffer
+	term := NewTerminal(&out, []config.Repo{repoA, repoB})
+
+	term.Status(repoA, "downloading v1.0.0")
+	term.Downloaded(repoA, 1<<20, 4<<20)
+	term.Write([]byte("log line\n"))
+	term.Done(repoB, errors.New("no suitable asset"))
+	term.Close()
+
+	want := []string{
+		"log line",
+		"owner/a  downloading v1.0.0  1.0 MB / 4.0 MB   25%",
+		"bee      failed: no suitable asset",
+		"1/2 done, 1 failed, 1.0 MB of 4.0 MB downloaded",
+	}
+	got := screen(out.String())
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("screen =\n%s\nwant\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+
+	// After Close, writes no longer move the lines.
+	out.Reset()
+	term.Write([]byte("after\n"))
+	if out.String() != "after\n" {
+		t.Errorf("write after Close = %q", out.String())
+	}
+}
+
+func TestTerminal_Truncates(t *testing.T) {
+	t.Setenv("COLUMNS", "30")
+	var out bytes.Buffer
+	term := NewTerminal(&out, []config.Repo{repoA})
+	term.Status(repoA, strings.Repeat("x", 100))
+	for _, line := range screen(out.String()) {
+		if len(line) >= 30 {
+			t.Errorf("line %q is not shorter than the terminal", line)
+		}
+	}
+}
+
+func TestPlain(t *testing.T) {
+	var out bytes.Buffer
+	p := NewPlain(&out, nil)
+	p.Status(repoA, "resolving")
+	p.Downloaded(repoA, 1, 2)
+	p.Done(repoA, nil)
+	p.Done(repoB, context.Canceled)
+	p.Close()
+
+	want := "https://github.com/owner/a: resolving\nhttps://github.com/owner/a: installed\nbee: cancelled\n"
+	if out.String() != want {
+		t.Errorf("output = %q, want %q", out.String(), want)
+	}
+}
+
+func TestPlain_ResultsInConfigOrder(t *testing.T) {
+	repoC := config.Repo{URL: "https://github.com/owner/c", OutputDir: "/c"}
+	var out bytes.Buffer
+	p := NewPlain(&out, []config.Repo{repoA, repoB, repoC})
+	// Parallel installs finish in any order.
+	p.Done(repoB, errors.New("disk full"))
+	p.Done(repoA, context.Canceled)
+	out.Reset()
+	p.Close()
+
+	want := "Results:\n" +
+		"  https://github.com/owner/a: cancelled\n" +
+		"  bee: failed: disk full\n" +
+		"  https://github.com/owner/c: not installed\n"
+	if out.String() != want {
+		t.Errorf("output = %q, want %q", out.String(), want)
+	}
+}
+
+func TestTerminal_SpeedAndETA(t *testing.T) {
+	var out bytes.Buffer
+	term := NewTerminal(&out, []config.Repo{repoA})
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	term.now = func() time.Time { return now }
+
+	term.Status(repoA, "downloading v1.0.0")
+	term.Downloaded(repoA, 0, 8<<20)
+	now = now.Add(2 * time.Second)
+	term.Downloaded(repoA, 2<<20, 8<<20)
+
+	got := screen(out.String())
+	want := []string{
+		"owner/a  downloading v1.0.0  2.0 MB / 8.0 MB   25%  1.0 MB/s  ETA 6s",
+		"0/1 done, 2.0 MB of 8.0 MB downloaded at 1.0 MB/s",
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("screen =\n%s\nwant\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+}
@@ -0,0 +1,266 @@
+package progress
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sixban6/ghinstall/internal/config"
+	"github.com/sixban6/ghinstall/internal/installer"
+	"github.com/sixban6/ghinstall/internal/state"
+)
+
+// Outcomes of a repository in a Report.
+const (
+	Upgraded = "upgraded"
+	Skipped  = "skipped"
+	Failed   = "failed"
+)
+
+// Report summarizes a batch run for people, e.g. to paste into a change
+// ticket or to send by cron mail: which repositories were upgraded, skipped
+// or failed, and why. Register it both as installer.Middleware and as
+// installer.Progress, like Events. The installed tags are read when it is
+// created, so create it before installing.
+type Report struct {
+	mu        sync.Mutex
+	repos     []config.Repo
+	installed map[string]string
+	results   map[string]*result
+	start     time.Time
+	// now and host are replaced by tests.
+	now  func() time.Time
+	host string
+}
+
+// NewReport returns a Report on repos, reading the tags installed before the
+// run from their state files.
+func NewReport(repos []config.Repo) *Report {
+	r := &Report{
+		repos:     repos,
+		installed: make(map[string]string),
+		results:   make(map[string]*result),
+		now:       time.Now,
+	}
+	r.start = r.now()
+	r.host, _ = os.Hostname()
+	for _, repo := range repos {
+		if st, err := state.Load(repo.OutputDir); err == nil {
+			if rec, ok := st.Get(repo.URL); ok {
+				r.installed[key(repo)] = rec.Tag
+			}
+		}
+	}
+	return r
+}
+
+func (r *Report) Intercept(ctx context.Context, step installer.Step) error {
+	var tag string
+	switch step.Stage {
+	case installer.AfterResolve:
+		tag = step.Release.TagName
+	case installer.AfterExtract:
+		tag = step.Result.Tag
+	default:
+		return nil
+	}
+	r.record(step.Repo, func(res *result) {
+		res.tag = cmp.Or(tag, res.tag)
+		if step.Result != nil {
+			res.download = step.Result.Download
+		}
+	})
+	return nil
+}
+
+func (r *Report) Status(repo config.Repo, status string) {}
+
+func (r *Report) Downloaded(repo config.Repo, n, total int64) {}
+
+func (r *Report) Done(repo config.Repo, err error) {
+	stats, failed := failedDownload(err)
+	r.record(repo, func(res *result) {
+		res.done, res.err = true, err
+		if failed {
+			res.download = stats
+		}
+	})
+}
+
+func (r *Report) record(repo config.Repo, fn func(*result)) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	res, ok := r.results[key(repo)]
+	if !ok {
+		res = &result{}
+		r.results[key(repo)] = res
+	}
+	fn(res)
+}
+
+// ReportLine is the outcome of one repository in a Report.
+type ReportLine struct {
+	Repo    config.Repo
+	Outcome string
+	// From is the tag installed before the run, "" when there was none, and
+	// To the tag of the run.
+	From, To string
+	// Reason tells why the repository was skipped or failed.
+	Reason string
+	// Download records the retries of the download, successful or not.
+	Download installer.DownloadStats
+}
+
+// Lines returns the outcome of every repository, in config order.
+// Repositories reinstalled at their installed tag, or not installed because
+// others failed, are skipped.
+func (r *Report) Lines() []ReportLine {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	lines := make([]ReportLine, len(r.repos))
+	for n, repo := range r.repos {
+		l := ReportLine{Repo: repo, Outcome: Skipped, From: r.installed[key(repo)], Reason: "not installed"}
+		res, ok := r.results[key(repo)]
+		if ok {
+			l.Download = res.download
+		}
+		switch {
+		case !ok || !res.done:
+		case errors.Is(res.err, installer.ErrNotInstalled):
+			l.Reason = res.err.Error()
+		case errors.Is(res.err, context.Canceled):
+			l.Reason = "cancelled"
+		case res.err != nil:
+			l.Outcome, l.Reason = Failed, res.err.Error()
+		case res.tag == "" || res.tag == l.From:
+			// Up-to-date repositories are skipped before they are extracted.
+			l.To, l.Reason = l.From, "already at "+l.From
+		default:
+			l.Outcome, l.To, l.Reason = Upgraded, res.tag, ""
+		}
+		lines[n] = l
+	}
+	return lines
+}
+
+// counts returns how many of lines were upgraded, skipped and failed.
+func counts(lines []ReportLine) (upgraded, skipped, failed int) {
+	for _, l := range lines {
+		switch l.Outcome {
+		case Upgraded:
+			upgraded++
+		case Skipped:
+			skipped++
+		case Failed:
+			failed++
+		}
+	}
+	return upgraded, skipped, failed
+}
+
+// heading names the run: where and when it ran, and for how long.
+func (r *Report) heading() string {
+	where := ""
+	if r.host != "" {
+		where = " on " + r.host
+	}
+	return fmt.Sprintf("ghinstall run%s at %s (took %s)", where, r.start.UTC().Format("2006-01-02 15:04 MST"), r.now().Sub(r.start).Round(time.Second))
+}
+
+// WriteText writes the report as plain text, grouping the repositories by
+// outcome.
+func (r *Report) WriteText(w io.Writer) error {
+	lines := r.Lines()
+	upgraded, skipped, failed := counts(lines)
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s\nUpgraded %d, skipped %d, failed %d.\n", r.heading(), upgraded, skipped, failed)
+	for _, outcome := range []string{Upgraded, Skipped, Failed} {
+		first := true
+		for _, l := range lines {
+			if l.Outcome != outcome {
+				continue
+			}
+			if first {
+				fmt.Fprintf(&b, "\n%s%s:\n", strings.ToUpper(outcome[:1]), outcome[1:])
+				first = false
+			}
+			switch {
+			case outcome == Failed:
+				// The error of a failed download describes its retries.
+				fmt.Fprintf(&b, "  %s: %s\n", label(l.Repo), l.Reason)
+			case outcome != Upgraded:
+				fmt.Fprintf(&b, "  %s: %s%s\n", label(l.Repo), l.Reason, retries(l))
+			case l.From == "":
+				fmt.Fprintf(&b, "  %s: %s (new)%s\n", label(l.Repo), l.To, retries(l))
+			default:
+				fmt.Fprintf(&b, "  %s: %s -> %s%s\n", label(l.Repo), l.From, l.To, retries(l))
+			}
+		}
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// WriteUpgrades writes the versions the run upgraded, for updates, or that
+// nothing needed to be.
+func (r *Report) WriteUpgrades(w io.Writer) error {
+	lines := r.Lines()
+	upgraded, _, _ := counts(lines)
+	var b strings.Builder
+	if upgraded == 0 {
+		fmt.Fprintf(&b, "No repository was upgraded.\n")
+	} else {
+		fmt.Fprintf(&b, "Upgraded %d of %d repositories:\n", upgraded, len(lines))
+	}
+	for _, l := range lines {
+		switch {
+		case l.Outcome != Upgraded:
+		case l.From == "":
+			fmt.Fprintf(&b, "  %s: %s (new)\n", label(l.Repo), l.To)
+		default:
+			fmt.Fprintf(&b, "  %s: %s -> %s\n", label(l.Repo), l.From, l.To)
+		}
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// WriteMarkdown writes the report as Markdown, with a table row per
+// repository in config order.
+func (r *Report) WriteMarkdown(w io.Writer) error {
+	lines := r.Lines()
+	upgraded, skipped, failed := counts(lines)
+	var b strings.Builder
+	fmt.Fprintf(&b, "## %s\n\nUpgraded %d, skipped %d, failed %d.\n\n", r.heading(), upgraded, skipped, failed)
+	b.WriteString("| Repository | Outcome | From | To | Details |\n")
+	b.WriteString("|------------|---------|------|----|---------|\n")
+	for _, l := range lines {
+		details := l.Reason
+		if l.Outcome != Failed && l.Download.Retries > 0 {
+			details = strings.TrimPrefix(details+"; "+l.Download.String(), "; ")
+		}
+		fmt.Fprintf(&b, "| %s | %s | %s | %s | %s |\n", cell(label(l.Repo)), l.Outcome, cell(l.From), cell(l.To), cell(details))
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// retries describes the retries of the download of l for a text line, or
+// returns "" without retries.
+func retries(l ReportLine) string {
+	if s := l.Download.String(); s != "" {
+		return " (" + s + ")"
+	}
+	return ""
+}
+
+// cell escapes s for a Markdown table cell, on a single line.
+func cell(s string) string {
+	return strings.Join(strings.Fields(strings.ReplaceAll(s, "|", `\|`)), " ")
+}
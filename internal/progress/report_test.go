@@ -0,0 +1,188 @@
+package progress
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/sixban6/ghinstall/internal/config"
+	"github.com/sixban6/ghinstall/internal/installer"
+	"github.com/sixban6/ghinstall/internal/release"
+	"github.com/sixban6/ghinstall/internal/state"
+)
+
+func TestReport(t *testing.T) {
+	repos := []config.Repo{
+		{URL: "https://github.com/owner/a", OutputDir: t.TempDir()},
+		{URL: "https://github.com/owner/b", OutputDir: t.TempDir(), Name: "bee"},
+		{URL: "https://github.com/owner/c", OutputDir: t.TempDir()},
+		{URL: "https://github.com/owner/d", OutputDir: t.TempDir()},
+		{URL: "https://github.com/owner/e", OutputDir: t.TempDir()},
+	}
+	for _, repo := range repos[:3] {
+		st, _ := state.Load(repo.OutputDir)
+		st.Put(state.Record{Repo: repo.URL, Tag: "v1.0.0", Asset: "app.tar.gz"})
+		if err := st.Save(); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	start := time.Date(2024, 1, 1, 8, 0, 0, 0, time.UTC)
+	r := NewReport(repos)
+	r.start, r.host = start, "build-1"
+	r.now = func() time.Time { return start.Add(90 * time.Second) }
+
+	installed := func(repo config.Repo, tag string) {
+		r.Intercept(context.Background(), installer.Step{Stage: installer.AfterExtract, Repo: repo,
+			Result: &installer.InstallResult{Repo: repo, Tag: tag, Asset: release.Asset{Name: "app.tar.gz"}}})
+		r.Done(repo, nil)
+	}
+	installed(repos[0], "v1.1.0")
+	installed(repos[1], "v1.0.0")
+	r.Done(repos[2], errors.New("failed to download asset: 404 Not Found"))
+	installed(repos[3], "v2.0.0")
+	r.Done(repos[4], installer.ErrNotInstalled)
+
+	var text bytes.Buffer
+	if err := r.WriteText(&text); err != nil {
+		t.Fatal(err)
+	}
+	want := `ghinstall run on build-1 at 2024-01-01 08:00 UTC (took 1m30s)
+Upgraded 2, skipped 2, failed 1.
+
+Upgraded:
+  owner/a: v1.0.0 -> v1.1.0
+  owner/d: v2.0.0 (new)
+
+Skipped:
+  bee: already at v1.0.0
+  owner/e: not installed, other repositories failed to resolve
+
+Failed:
+  owner/c: failed to download asset: 404 Not Found
+`
+	if got := text.String(); got != want {
+		t.Errorf("WriteText() =\n%s\nwant\n%s", got, want)
+	}
+
+	var md bytes.Buffer
+	if err := r.WriteMarkdown(&md); err != nil {
+		t.Fatal(err)
+	}
+	for _, row := range []string{
+		"## ghinstall run on build-1 at 2024-01-01 08:00 UTC (took 1m30s)",
+		"| owner/a | upgraded | v1.0.0 | v1.1.0 |  |",
+		"| bee | skipped | v1.0.0 | v1.0.0 | already at v1.0.0 |",
+		"| owner/c | failed | v1.0.0 |  | failed to download asset: 404 Not Found |",
+		"| owner/d | upgraded |  | v2.0.0 |  |",
+	} {
+		if !strings.Contains(md.String(), row+"\n") {
+			t.Errorf("WriteMarkdown() lacks %q:\n%s", row, md.String())
+		}
+	}
+}
+
+func TestCell(t *testing.T) {
+	if got, want := cell("exit status 1:\n  a | b"), `exit status 1: a \| b`; got != want {
+		t.Errorf("cell() = %q, want %q", got, want)
+	}
+}
+
+func TestReport_UpToDate(t *testing.T) {
+	repo := config.Repo{URL: "https://github.com/owner/a", OutputDir: t.TempDir()}
+	st, _ := state.Load(repo.OutputDir)
+	st.Put(state.Record{Repo: repo.URL, Tag: "v1.0.0", Asset: "app.tar.gz"})
+	if err := st.Save(); err != nil {
+		t.Fatal(err)
+	}
+
+	// Up-to-date repositories finish without being extracted.
+	r := NewReport([]config.Repo{repo})
+	r.Done(repo, nil)
+	l := r.Lines()[0]
+	if l.Outcome != Skipped || l.To != "v1.0.0" || l.Reason != "already at v1.0.0" {
+		t.Errorf("Lines() = %+v, want skipped as already at v1.0.0", l)
+	}
+}
+
+func TestReport_Retries(t *testing.T) {
+	repos := []config.Repo{
+		{URL: "https://github.com/owner/a", OutputDir: t.TempDir()},
+		{URL: "https://github.com/owner/b", OutputDir: t.TempDir()},
+	}
+	stats := installer.DownloadStats{Retries: 1, Sources: []string{"direct", "ghfast.top"}, WastedBytes: 1 << 20}
+	r := NewReport(repos)
+	r.Intercept(context.Background(), installer.Step{Stage: installer.AfterExtract, Repo: repos[0],
+		Result: &installer.InstallResult{Repo: repos[0], Tag: "v1.0.0", Download: stats}})
+	r.Done(repos[0], nil)
+	r.Done(repos[1], fmt.Errorf("failed to download asset: %w", &installer.DownloadError{Stats: stats, Err: errors.New("connection reset by peer")}))
+
+	lines := r.Lines()
+	for _, l := range lines {
+		if l.Download.Retries != 1 || l.Download.WastedBytes != 1<<20 {
+			t.Errorf("%s: Download = %+v, want the retries", l.Repo.URL, l.Download)
+		}
+	}
+
+	var text bytes.Buffer
+	if err := r.WriteText(&text); err != nil {
+		t.Fatal(err)
+	}
+	for _, line := range []string{
+		"  owner/a: v1.0.0 (new) (1 retry via direct, ghfast.top, 1.00 MB wasted)\n",
+		"  owner/b: failed to download asset: connection reset by peer (1 retry via direct, ghfast.top, 1.00 MB wasted)\n",
+	} {
+		if !strings.Contains(text.String(), line) {
+			t.Errorf("WriteText() lacks %q:\n%s", line, text.String())
+		}
+	}
+}
+
+func TestReport_WriteUpgrades(t *testing.T) {
+	repos := []config.Repo{
+		{URL: "https://github.com/owner/a", OutputDir: t.TempDir()},
+		{URL: "https://github.com/owner/b", OutputDir: t.TempDir()},
+		{URL: "https://github.com/owner/c", OutputDir: t.TempDir()},
+	}
+	for _, repo := range repos[:2] {
+		st, _ := state.Load(repo.OutputDir)
+		st.Put(state.Record{Repo: repo.URL, Tag: "v1.0.0", Asset: "app.tar.gz"})
+		if err := st.Save(); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	r := NewReport(repos)
+	var out bytes.Buffer
+	if err := r.WriteUpgrades(&out); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := out.String(), "No repository was upgraded.\n"; got != want {
+		t.Errorf("WriteUpgrades() = %q, want %q", got, want)
+	}
+
+	for _, u := range []struct {
+		repo config.Repo
+		tag  string
+	}{{repos[0], "v1.1.0"}, {repos[2], "v2.0.0"}} {
+		r.Intercept(context.Background(), installer.Step{Stage: installer.AfterExtract, Repo: u.repo,
+			Result: &installer.InstallResult{Repo: u.repo, Tag: u.tag}})
+		r.Done(u.repo, nil)
+	}
+	r.Done(repos[1], nil)
+	out.Reset()
+	if err := r.WriteUpgrades(&out); err != nil {
+		t.Fatal(err)
+	}
+	want := `Upgraded 2 of 3 repositories:
+  owner/a: v1.0.0 -> v1.1.0
+  owner/c: v2.0.0 (new)
+`
+	if got := out.String(); got != want {
+		t.Errorf("WriteUpgrades() =\n%s\nwant\n%s", got, want)
+	}
+}
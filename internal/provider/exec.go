@@ -0,0 +1,28 @@
+package provider
+
+import "github.com/sixban6/ghinstall/internal/release"
+
+// Request is the JSON document written to an exec provider's stdin. The
+// provider is started once per request.
+//
+// For "resolve" it must print a single GitHub-style release object
+// ({"tag_name": ..., "assets": [{"name", "browser_download_url", "size"}]}).
+// For "download" it must write the raw asset content to stdout.
+// A non-zero exit status fails the request; stderr is used as the message.
+type Request struct {
+	Method  string            `json:"method"`
+	URL     string            `json:"url,omitempty"`
+	Options map[string]string `json:"options,omitempty"`
+	Asset   *release.Asset    `json:"asset,omitempty"`
+}
+
+// Exec is a provider implemented by an external executable.
+type Exec struct {
+	command []string
+}
+
+// NewExec returns a provider that runs command for every request. In purego
+// and wasip1 builds, which never start processes, its requests always fail.
+func NewExec(command ...string) *Exec {
+	return &Exec{command: command}
+}
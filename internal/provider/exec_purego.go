@@ -0,0 +1,24 @@
+//go:build purego || wasip1
+
+package provider
+
+import (
+	"context"
+	"fmt"
+	"io"
+
+	"github.com/sixban6/ghinstall/internal/config"
+	"github.com/sixban6/ghinstall/internal/release"
+)
+
+func (e *Exec) Resolve(ctx context.Context, repo config.Repo) (*release.Release, error) {
+	return nil, e.disabled()
+}
+
+func (e *Exec) Download(ctx context.Context, asset release.Asset) (io.ReadCloser, error) {
+	return nil, e.disabled()
+}
+
+func (e *Exec) disabled() error {
+	return fmt.Errorf("provider %v cannot run: this build of ghinstall (purego or wasip1) does not start processes", e.command)
+}
@@ -0,0 +1,21 @@
+//go:build purego || wasip1
+
+package provider
+
+import (
+	"context"
+	"testing"
+
+	"github.com/sixban6/ghinstall/internal/config"
+	"github.com/sixban6/ghinstall/internal/release"
+)
+
+func TestExec_PureGo(t *testing.T) {
+	p := NewExec("/bin/true")
+	if _, err := p.Resolve(context.Background(), config.Repo{URL: "internal://tool"}); err == nil {
+		t.Error("Resolve() should fail in purego and wasip1 builds")
+	}
+	if _, err := p.Download(context.Background(), release.Asset{Name: "tool.tar.gz"}); err == nil {
+		t.Error("Download() should fail in purego and wasip1 builds")
+	}
+}
@@ -0,0 +1,117 @@
+//go:build !purego && !wasip1
+
+package provider
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os/exec"
+	"strings"
+
+	"github.com/sixban6/ghinstall/internal/config"
+	"github.com/sixban6/ghinstall/internal/release"
+)
+
+func (e *Exec) Resolve(ctx context.Context, repo config.Repo) (*release.Release, error) {
+	var stdout, stderr bytes.Buffer
+	cmd, err := e.newCommand(ctx, Request{Method: "resolve", URL: repo.URL, Options: repo.ProviderOptions})
+	if err != nil {
+		return nil, err
+	}
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	if err := cmd.Run(); err != nil {
+		return nil, fmt.Errorf("provider %s failed to resolve %s: %w%s", e.command[0], repo.URL, err, stderrSuffix(&stderr))
+	}
+
+	var rel release.Release
+	if err := json.Unmarshal(stdout.Bytes(), &rel); err != nil {
+		return nil, fmt.Errorf("provider %s returned invalid release: %w", e.command[0], err)
+	}
+	return &rel, nil
+}
+
+func (e *Exec) Download(ctx context.Context, asset release.Asset) (io.ReadCloser, error) {
+	cmd, err := e.newCommand(ctx, Request{Method: "download", Asset: &asset})
+	if err != nil {
+		return nil, err
+	}
+
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, fmt.Errorf("failed to attach to provider output: %w", err)
+	}
+	stderr := &bytes.Buffer{}
+	cmd.Stderr = stderr
+
+	if err := cmd.Start(); err != nil {
+		return nil, fmt.Errorf("failed to start provider %s: %w", e.command[0], err)
+	}
+
+	return &execReader{ReadCloser: stdout, cmd: cmd, stderr: stderr, name: e.command[0]}, nil
+}
+
+func (e *Exec) newCommand(ctx context.Context, req Request) (*exec.Cmd, error) {
+	if len(e.command) == 0 {
+		return nil, fmt.Errorf("provider command is empty")
+	}
+
+	payload, err := json.Marshal(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode provider request: %w", err)
+	}
+
+	cmd := exec.CommandContext(ctx, e.command[0], e.command[1:]...)
+	cmd.Stdin = bytes.NewReader(append(payload, '\n'))
+	return cmd, nil
+}
+
+// execReader streams a download from the provider. A non-zero exit status is
+// reported in place of io.EOF so truncated downloads are never mistaken for
+// complete ones.
+type execReader struct {
+	io.ReadCloser
+	cmd     *exec.Cmd
+	stderr  *bytes.Buffer
+	name    string
+	waited  bool
+	waitErr error
+}
+
+func (r *execReader) Read(p []byte) (int, error) {
+	n, err := r.ReadCloser.Read(p)
+	if err == io.EOF {
+		if werr := r.wait(); werr != nil {
+			return n, werr
+		}
+	}
+	return n, err
+}
+
+func (r *execReader) Close() error {
+	// Drain so the provider is not blocked writing when we stop early.
+	io.Copy(io.Discard, r.ReadCloser)
+	return r.wait()
+}
+
+func (r *execReader) wait() error {
+	if !r.waited {
+		r.waited = true
+		if err := r.cmd.Wait(); err != nil {
+			r.waitErr = fmt.Errorf("provider %s download failed: %w%s", r.name, err, stderrSuffix(r.stderr))
+		}
+	}
+	return r.waitErr
+}
+
+func stderrSuffix(stderr *bytes.Buffer) string {
+	msg := strings.TrimSpace(stderr.String())
+	if msg == "" {
+		return ""
+	}
+	return ": " + msg
+}
@@ -0,0 +1,105 @@
+//go:build !purego && !wasip1
+
+package provider
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/sixban6/ghinstall/internal/config"
+	"github.com/sixban6/ghinstall/internal/release"
+)
+
+// TestHelperProvider is not a real test: it is executed as an exec provider
+// by the tests below.
+func TestHelperProvider(t *testing.T) {
+	if os.Getenv("GHINSTALL_HELPER_PROVIDER") != "1" {
+		return
+	}
+	defer os.Exit(0)
+
+	var req Request
+	if err := json.NewDecoder(os.Stdin).Decode(&req); err != nil {
+		fmt.Fprintln(os.Stderr, "bad request:", err)
+		os.Exit(2)
+	}
+
+	switch req.Method {
+	case "resolve":
+		if req.URL == "internal://missing" {
+			fmt.Fprintln(os.Stderr, "no such project")
+			os.Exit(1)
+		}
+		fmt.Printf(`{"tag_name":"v1.2.3","assets":[{"name":"tool-%s.tar.gz","browser_download_url":"internal://tool","size":7}]}`,
+			req.Options["flavor"])
+	case "download":
+		if req.Asset.Name == "broken" {
+			fmt.Print("partial")
+			os.Exit(3)
+		}
+		fmt.Print("payload")
+	default:
+		os.Exit(2)
+	}
+}
+
+func helperProvider(t *testing.T) *Exec {
+	t.Setenv("GHINSTALL_HELPER_PROVIDER", "1")
+	return NewExec(os.Args[0], "-test.run=^TestHelperProvider$")
+}
+
+func TestExec_Resolve(t *testing.T) {
+	p := helperProvider(t)
+
+	rel, err := p.Resolve(context.Background(), config.Repo{
+		URL:             "internal://tool",
+		ProviderOptions: map[string]string{"flavor": "static"},
+	})
+	if err != nil {
+		t.Fatalf("Resolve() error = %v", err)
+	}
+	if rel.TagName != "v1.2.3" {
+		t.Errorf("Resolve() tag = %s, want v1.2.3", rel.TagName)
+	}
+	if len(rel.Assets) != 1 || rel.Assets[0].Name != "tool-static.tar.gz" {
+		t.Errorf("Resolve() assets = %+v", rel.Assets)
+	}
+
+	_, err = p.Resolve(context.Background(), config.Repo{URL: "internal://missing"})
+	if err == nil || !strings.Contains(err.Error(), "no such project") {
+		t.Errorf("Resolve() error = %v, want provider stderr", err)
+	}
+}
+
+func TestExec_Download(t *testing.T) {
+	p := helperProvider(t)
+
+	rc, err := p.Download(context.Background(), release.Asset{Name: "tool.tar.gz"})
+	if err != nil {
+		t.Fatalf("Download() error = %v", err)
+	}
+	content, err := io.ReadAll(rc)
+	if err != nil {
+		t.Fatalf("reading download error = %v", err)
+	}
+	if err := rc.Close(); err != nil {
+		t.Errorf("Close() error = %v", err)
+	}
+	if string(content) != "payload" {
+		t.Errorf("Download() content = %q, want payload", content)
+	}
+
+	rc, err = p.Download(context.Background(), release.Asset{Name: "broken"})
+	if err != nil {
+		t.Fatalf("Download() error = %v", err)
+	}
+	if _, err := io.ReadAll(rc); err == nil {
+		t.Error("reading failed download should report provider exit status")
+	}
+	rc.Close()
+}
@@ -0,0 +1,66 @@
+// Package provider lets releases come from sources other than the GitHub API,
+// such as company-internal release systems.
+package provider
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"sort"
+	"sync"
+
+	"github.com/sixban6/ghinstall/internal/config"
+	"github.com/sixban6/ghinstall/internal/release"
+)
+
+// Provider resolves the release to install for a repository and downloads its assets.
+type Provider interface {
+	Resolve(ctx context.Context, repo config.Repo) (*release.Release, error)
+	Download(ctx context.Context, asset release.Asset) (io.ReadCloser, error)
+}
+
+var (
+	mu       sync.RWMutex
+	registry = map[string]Provider{}
+)
+
+// Register makes a provider available under name, replacing any previous one.
+func Register(name string, p Provider) {
+	mu.Lock()
+	defer mu.Unlock()
+	registry[name] = p
+}
+
+// Lookup returns the provider registered under name.
+func Lookup(name string) (Provider, bool) {
+	mu.RLock()
+	defer mu.RUnlock()
+	p, ok := registry[name]
+	return p, ok
+}
+
+// Names returns the registered provider names in sorted order.
+func Names() []string {
+	mu.RLock()
+	defer mu.RUnlock()
+	names := make([]string, 0, len(registry))
+	for name := range registry {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// ForRepo returns the provider for repo: a programmatically registered one
+// first, then an executable declared in the configuration's providers list.
+func ForRepo(cfg *config.Config, repo config.Repo) (Provider, error) {
+	if p, ok := Lookup(repo.Provider); ok {
+		return p, nil
+	}
+	for _, pc := range cfg.Providers {
+		if pc.Name == repo.Provider {
+			return NewExec(pc.Command...), nil
+		}
+	}
+	return nil, fmt.Errorf("unknown provider %q", repo.Provider)
+}
@@ -0,0 +1,35 @@
+package provider
+
+import (
+	"testing"
+
+	"github.com/sixban6/ghinstall/internal/config"
+)
+
+func TestForRepo(t *testing.T) {
+	registered := NewExec("registered")
+	Register("test-registered", registered)
+
+	cfg := &config.Config{
+		Providers: []config.ProviderConfig{
+			{Name: "from-config", Command: []string{"/usr/bin/corp-releases"}},
+		},
+	}
+
+	p, err := ForRepo(cfg, config.Repo{Provider: "test-registered"})
+	if err != nil || p != registered {
+		t.Errorf("ForRepo(registered) = %v, %v", p, err)
+	}
+
+	p, err = ForRepo(cfg, config.Repo{Provider: "from-config"})
+	if err != nil {
+		t.Fatalf("ForRepo(from-config) error = %v", err)
+	}
+	if e, ok := p.(*Exec); !ok || e.command[0] != "/usr/bin/corp-releases" {
+		t.Errorf("ForRepo(from-config) = %#v", p)
+	}
+
+	if _, err := ForRepo(cfg, config.Repo{Provider: "nope"}); err == nil {
+		t.Error("ForRepo(unknown) should fail")
+	}
+}
@@ -0,0 +1,99 @@
+package release
+
+import (
+	"os"
+	"regexp"
+	"runtime"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// cpuinfoPath is read to tell ARM variants apart.
+const cpuinfoPath = "/proc/cpuinfo"
+
+var (
+	cpuArchitecture = regexp.MustCompile(`(?m)^CPU architecture\s*:\s*(\d+)`)
+	cpuModelVariant = regexp.MustCompile(`(?im)^(?:model name|processor)\s*:.*\barmv(\d)`)
+	assetArmVersion = regexp.MustCompile(`armv(\d)`)
+)
+
+// HostArmVariant returns the ARM architecture version (5, 6 or 7) of a
+// linux/arm host, read from /proc/cpuinfo, and 0 on other platforms or when
+// it cannot be told. 64-bit CPUs running 32-bit programs count as 7.
+var HostArmVariant = sync.OnceValue(func() int {
+	if runtime.GOOS != "linux" || runtime.GOARCH != "arm" {
+		return 0
+	}
+	data, err := os.ReadFile(cpuinfoPath)
+	if err != nil {
+		return 0
+	}
+	return parseCPUInfo(data)
+})
+
+// parseCPUInfo returns the ARM architecture version of a /proc/cpuinfo.
+func parseCPUInfo(data []byte) int {
+	m := cpuArchitecture.FindSubmatch(data)
+	if m == nil {
+		m = cpuModelVariant.FindSubmatch(data)
+	}
+	if m == nil {
+		return 0
+	}
+	v, _ := strconv.Atoi(string(m[1]))
+	return min(v, 7)
+}
+
+// armVariant returns the ARM version an asset name was built for: 5 to 7 for
+// 32-bit builds naming it, 8 for 64-bit builds, and 0 when the name mentions
+// no version or is not an ARM build.
+func armVariant(name string) int {
+	name = strings.ToLower(name)
+	switch {
+	case strings.Contains(name, "arm64") || strings.Contains(name, "aarch64"):
+		return 8
+	case strings.Contains(name, "armhf"):
+		return 7
+	case strings.Contains(name, "armel"):
+		return 5
+	}
+	if m := assetArmVersion.FindStringSubmatch(name); m != nil {
+		v, _ := strconv.Atoi(m[1])
+		return v
+	}
+	return 0
+}
+
+// PreferArmVariant returns a copy of assets ordered for an ARM host of
+// version host: builds for exactly that version first, then builds for older
+// versions, newest first, then ARM builds naming no version and other assets,
+// and builds for newer versions, which crash with illegal instructions, last.
+// With host 0 the order is kept.
+func PreferArmVariant(assets []Asset, host int) []Asset {
+	sorted := make([]Asset, len(assets))
+	copy(sorted, assets)
+	if host == 0 {
+		return sorted
+	}
+
+	rank := func(a Asset) int {
+		switch v := armVariant(a.Name); {
+		case v == host:
+			return 0
+		case v > host:
+			return 20
+		case v > 0:
+			return 1 + host - v
+		case strings.Contains(strings.ToLower(a.Name), "arm"):
+			return 10
+		default:
+			return 11
+		}
+	}
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return rank(sorted[i]) < rank(sorted[j])
+	})
+	return sorted
+}
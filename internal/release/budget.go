@@ -0,0 +1,50 @@
+package release
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"sync/atomic"
+)
+
+// ErrBudgetExceeded is returned for API requests beyond the budget of a run
+// that cannot be answered from the HTTP cache.
+var ErrBudgetExceeded = errors.New("GitHub API budget exhausted")
+
+// BudgetConfigurer is implemented by finders that can limit the API requests
+// of a run.
+type BudgetConfigurer interface {
+	// SetAPIBudget allows n more API requests, any number when n is 0. Once
+	// they are used, releases are resolved from the HTTP cache, however old.
+	SetAPIBudget(n int)
+}
+
+// budgetTransport refuses requests beyond its limit. It sits below the HTTP
+// cache, so only requests reaching the API count.
+type budgetTransport struct {
+	base  http.RoundTripper
+	limit atomic.Int64
+	used  atomic.Int64
+}
+
+func (t *budgetTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if limit := t.limit.Load(); limit > 0 && t.used.Add(1) > limit {
+		return nil, fmt.Errorf("%w: all %d requests of api_budget are used and %s is not cached", ErrBudgetExceeded, limit, req.URL.Path)
+	}
+	return t.base.RoundTrip(req)
+}
+
+func (t *budgetTransport) set(n int) {
+	t.limit.Store(int64(n))
+	t.used.Store(0)
+}
+
+// exhausted reports whether the budget allows no more requests; clients
+// without a budget never exhaust it.
+func (t *budgetTransport) exhausted() bool {
+	if t == nil {
+		return false
+	}
+	limit := t.limit.Load()
+	return limit > 0 && t.used.Load() >= limit
+}
@@ -2,6 +2,7 @@ package release
 
 import (
 	"fmt"
+	"regexp"
 	"runtime"
 	"sort"
 	"strings"
@@ -28,6 +29,97 @@ func DefaultFilter() AssetFilter {
 	}
 }
 
+// PreferTypes returns a copy of assets ordered by the first suffix of types
+// their name ends with, case-insensitively. Assets ending with none of them
+// keep their order after the others, so first-match filters such as
+// DefaultFilter pick the most preferred archive type the release offers.
+func PreferTypes(assets []Asset, types []string) []Asset {
+	rank := func(a Asset) int {
+		name := strings.ToLower(a.Name)
+		for i, t := range types {
+			if strings.HasSuffix(name, strings.ToLower(t)) {
+				return i
+			}
+		}
+		return len(types)
+	}
+
+	sorted := make([]Asset, len(assets))
+	copy(sorted, assets)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return rank(sorted[i]) < rank(sorted[j])
+	})
+	return sorted
+}
+
+// sidecarSuffixes end the names of the checksums, signatures and SBOMs
+// published next to the archives, which size preferences must not select.
+var sidecarSuffixes = []string{
+	".sha256", ".sha512", ".md5", ".sum", ".txt", ".sig", ".asc", ".minisig",
+	".pem", ".crt", ".json", ".jsonl", ".sbom", ".spdx", ".intoto",
+}
+
+// Prefer returns a copy of assets ordered by pref: the "smallest" or
+// "largest" first, or with "name:<substring>" those whose name contains the
+// substring, case-insensitively. By size, sidecar files such as checksums
+// and signatures, and assets of unknown size, come last. Ties keep their
+// order, so first-match filters pick the preferred asset among those they
+// accept, such as the builds for the platform. Other values of pref keep
+// the order.
+func Prefer(assets []Asset, pref string) []Asset {
+	var less func(a, b Asset) bool
+	switch {
+	case pref == "smallest" || pref == "largest":
+		rank := func(a Asset) int {
+			if a.Size <= 0 || hasSuffix(a.Name, sidecarSuffixes) {
+				return 1
+			}
+			return 0
+		}
+		less = func(a, b Asset) bool {
+			if ra, rb := rank(a), rank(b); ra != rb {
+				return ra < rb
+			}
+			if pref == "smallest" {
+				return a.Size < b.Size
+			}
+			return a.Size > b.Size
+		}
+	case strings.HasPrefix(pref, "name:"):
+		sub := strings.ToLower(strings.TrimPrefix(pref, "name:"))
+		less = func(a, b Asset) bool {
+			return strings.Contains(strings.ToLower(a.Name), sub) && !strings.Contains(strings.ToLower(b.Name), sub)
+		}
+	default:
+		return assets
+	}
+
+	sorted := make([]Asset, len(assets))
+	copy(sorted, assets)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return less(sorted[i], sorted[j])
+	})
+	return sorted
+}
+
+// Preferring creates a filter applying filter to the assets ordered by
+// Prefer, so it selects the preferred one among those it accepts.
+func Preferring(pref string, filter AssetFilter) AssetFilter {
+	return func(assets []Asset) (*Asset, error) {
+		return filter(Prefer(assets, pref))
+	}
+}
+
+func hasSuffix(name string, suffixes []string) bool {
+	name = strings.ToLower(name)
+	for _, s := range suffixes {
+		if strings.HasSuffix(name, s) {
+			return true
+		}
+	}
+	return false
+}
+
 // ByNamePattern creates a filter that matches asset names by patterns
 func ByNamePattern(patterns ...string) AssetFilter {
 	return func(assets []Asset) (*Asset, error) {
@@ -48,6 +140,97 @@ func ByNamePattern(patterns ...string) AssetFilter {
 	}
 }
 
+// ByRegex creates a filter that selects the first asset whose name matches the regular expression
+func ByRegex(pattern string) AssetFilter {
+	re, err := regexp.Compile(pattern)
+	return func(assets []Asset) (*Asset, error) {
+		if err != nil {
+			return nil, fmt.Errorf("invalid asset pattern %q: %w", pattern, err)
+		}
+
+		for _, asset := range assets {
+			if re.MatchString(asset.Name) {
+				return &asset, nil
+			}
+		}
+
+		return nil, fmt.Errorf("no asset matching pattern %q", pattern)
+	}
+}
+
+// MatchRegex returns the assets whose name matches the regular expression, in
+// order; none when it is invalid.
+func MatchRegex(pattern string, assets []Asset) []Asset {
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil
+	}
+	var matches []Asset
+	for _, asset := range assets {
+		if re.MatchString(asset.Name) {
+			matches = append(matches, asset)
+		}
+	}
+	return matches
+}
+
+// osAliases and archAliases are the names release assets use for each GOOS
+// and GOARCH.
+var (
+	osAliases = map[string][]string{
+		"linux":   {"linux"},
+		"darwin":  {"darwin", "macos", "osx"},
+		"windows": {"windows", "win"},
+		"freebsd": {"freebsd"},
+		"openbsd": {"openbsd"},
+		"netbsd":  {"netbsd"},
+		// illumos runs the solaris builds of Go projects, which often ship
+		// only those.
+		"illumos":   {"illumos", "solaris", "sunos"},
+		"solaris":   {"solaris", "sunos"},
+		"dragonfly": {"dragonfly"},
+		"android":   {"android", "termux"},
+	}
+	archAliases = map[string][]string{
+		"amd64": {"amd64", "x86_64", "x64"},
+		"386":   {"386", "i386", "x86"},
+		"arm64": {"arm64", "aarch64"},
+		"arm":   {"arm", "armv7"},
+	}
+)
+
+// ExpandPattern expands the placeholders of an asset pattern for the release
+// tagged tag: {tag}, {version} (the tag without its "v" prefix), and {os} and
+// {arch}, which match any name assets use for the current platform, such as
+// "darwin" or "macos" and "amd64" or "x86_64". A pattern like
+// `tool_{version}_{os}_{arch}\.tar\.gz$` thus keeps selecting the asset of
+// every new release.
+func ExpandPattern(pattern, tag string) string {
+	return ExpandPatternFor(pattern, tag, runtime.GOOS, runtime.GOARCH)
+}
+
+// ExpandPatternFor is ExpandPattern for the platform goos/goarch instead of
+// the current one.
+func ExpandPatternFor(pattern, tag, goos, goarch string) string {
+	alternatives := func(aliases map[string][]string, name string) string {
+		names := aliases[name]
+		if names == nil {
+			names = []string{name}
+		}
+		quoted := make([]string, len(names))
+		for i, n := range names {
+			quoted[i] = regexp.QuoteMeta(n)
+		}
+		return "(?i:" + strings.Join(quoted, "|") + ")"
+	}
+	return strings.NewReplacer(
+		"{tag}", regexp.QuoteMeta(tag),
+		"{version}", regexp.QuoteMeta(strings.TrimPrefix(tag, "v")),
+		"{os}", alternatives(osAliases, goos),
+		"{arch}", alternatives(archAliases, goarch),
+	).Replace(pattern)
+}
+
 // ByOS creates a filter that matches assets by operating system
 func ByOS(os string) AssetFilter {
 	return func(assets []Asset) (*Asset, error) {
@@ -56,13 +239,6 @@ func ByOS(os string) AssetFilter {
 			targetOS = strings.ToLower(runtime.GOOS)
 		}
 		
-		// OS mapping
-		osAliases := map[string][]string{
-			"linux":   {"linux"},
-			"darwin":  {"darwin", "macos", "osx"},
-			"windows": {"windows", "win"},
-		}
-		
 		aliases := osAliases[targetOS]
 		if aliases == nil {
 			aliases = []string{targetOS}
@@ -89,18 +265,13 @@ func ByArch(arch string) AssetFilter {
 			targetArch = strings.ToLower(runtime.GOARCH)
 		}
 		
-		// Architecture mapping
-		archAliases := map[string][]string{
-			"amd64": {"amd64", "x86_64", "x64"},
-			"386":   {"386", "i386", "x86"},
-			"arm64": {"arm64", "aarch64"},
-			"arm":   {"arm", "armv7"},
-		}
-		
 		aliases := archAliases[targetArch]
 		if aliases == nil {
 			aliases = []string{targetArch}
 		}
+		if targetArch == "arm" && runtime.GOARCH == "arm" {
+			assets = PreferArmVariant(assets, HostArmVariant())
+		}
 		
 		for _, asset := range assets {
 			name := strings.ToLower(asset.Name)
@@ -115,6 +286,47 @@ func ByArch(arch string) AssetFilter {
 	}
 }
 
+// ByPlatform creates a filter selecting the first asset naming both goos and
+// goarch, under any of their usual names.
+func ByPlatform(goos, goarch string) AssetFilter {
+	return func(assets []Asset) (*Asset, error) {
+		for _, asset := range assets {
+			if containsAny(asset.Name, osAliases, goos) && containsAny(asset.Name, archAliases, goarch) {
+				return &asset, nil
+			}
+		}
+		return nil, fmt.Errorf("no asset found for %s/%s", goos, goarch)
+	}
+}
+
+// ForPlatform returns the assets naming both goos and goarch, under any of
+// their usual names, in order.
+func ForPlatform(assets []Asset, goos, goarch string) []Asset {
+	var matches []Asset
+	for _, asset := range assets {
+		if containsAny(asset.Name, osAliases, goos) && containsAny(asset.Name, archAliases, goarch) {
+			matches = append(matches, asset)
+		}
+	}
+	return matches
+}
+
+// containsAny reports whether name contains one of the aliases of key,
+// case-insensitively.
+func containsAny(name string, aliases map[string][]string, key string) bool {
+	names := aliases[key]
+	if names == nil {
+		names = []string{key}
+	}
+	name = strings.ToLower(name)
+	for _, alias := range names {
+		if strings.Contains(name, alias) {
+			return true
+		}
+	}
+	return false
+}
+
 // Combined creates a filter that applies multiple filters in sequence
 func Combined(filters ...AssetFilter) AssetFilter {
 	return func(assets []Asset) (*Asset, error) {
@@ -153,6 +365,9 @@ func Combined(filters ...AssetFilter) AssetFilter {
 }
 
 // BySize creates a filter that selects the largest or smallest asset
+//
+// Deprecated: BySize ignores the platform; use Preferring with "smallest" or
+// "largest" around a platform filter instead.
 func BySize(largest bool) AssetFilter {
 	return func(assets []Asset) (*Asset, error) {
 		if len(assets) == 0 {
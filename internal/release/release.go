@@ -3,12 +3,21 @@ package release
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
+	neturl "net/url"
+	"os"
+	"path"
 	"sort"
 	"strings"
 	"time"
 
+	"github.com/sixban6/ghinstall/internal/constraint"
+	"github.com/sixban6/ghinstall/internal/fdlimit"
+	"github.com/sixban6/ghinstall/internal/httpcache"
+	"github.com/sixban6/ghinstall/internal/httplog"
+	"github.com/sixban6/ghinstall/internal/neterr"
 	"golang.org/x/mod/semver"
 )
 
@@ -17,6 +26,11 @@ type Asset struct {
 	URL         string `json:"browser_download_url"`
 	ContentType string `json:"content_type"`
 	Size        int64  `json:"size"`
+	// Digest is the "sha256:<hex>" digest GitHub computes for newer uploads; empty otherwise.
+	Digest string `json:"digest,omitempty"`
+	// APIURL is the asset in the GitHub API, from which the assets of private
+	// repositories are downloaded with a token; empty for other sources.
+	APIURL string `json:"url,omitempty"`
 }
 
 type Release struct {
@@ -26,29 +40,153 @@ type Release struct {
 	Draft       bool      `json:"draft"`
 	Prerelease  bool      `json:"prerelease"`
 	PublishedAt time.Time `json:"published_at"`
+	// Body holds the release notes.
+	Body string `json:"body,omitempty"`
+	// HTMLURL is the page of the release notes.
+	HTMLURL string `json:"html_url,omitempty"`
 }
 
+// ErrNotFound is returned by ByTag when the release does not exist (anymore).
+var ErrNotFound = errors.New("release not found")
+
 type Finder interface {
-	LatestStable(ctx context.Context, owner, repo string) (*Release, error)
+	Latest(ctx context.Context, owner, repo string, policy Policy) (*Release, error)
+	ByTag(ctx context.Context, owner, repo, tag string) (*Release, error)
+}
+
+// Channel selects the releases considered when resolving the latest release.
+type Channel string
+
+const (
+	// ChannelStable considers releases that are not marked as prereleases.
+	ChannelStable Channel = "stable"
+	// ChannelPrerelease also considers prereleases and picks the highest version.
+	ChannelPrerelease Channel = "prerelease"
+	// ChannelNightly picks the most recently published release regardless of
+	// its prerelease flag or tag, for projects publishing rolling nightly builds.
+	ChannelNightly Channel = "nightly"
+)
+
+// Policy decides which release is the latest one. The zero Policy selects the
+// latest stable release.
+type Policy struct {
+	Channel Channel
+	// ExcludeTags are glob patterns (path.Match syntax) of tags never selected.
+	ExcludeTags []string
+	// Constraint limits the releases to the versions satisfying a version
+	// constraint such as ">=1.4.0 <2.0.0" or "~1.6"; see package constraint.
+	Constraint string
+}
+
+func (p Policy) filter(releases []Release) []Release {
+	var candidates []Release
+	switch p.Channel {
+	case ChannelPrerelease, ChannelNightly:
+		candidates = filterPublishedReleases(releases)
+	default:
+		candidates = filterStableReleases(releases)
+	}
+	candidates = filterExcludedTags(candidates, p.ExcludeTags)
+	if p.Constraint == "" {
+		return candidates
+	}
+	var kept []Release
+	for _, release := range candidates {
+		if p.InRange(release.TagName) {
+			kept = append(kept, release)
+		}
+	}
+	return kept
+}
+
+// InRange reports whether tag satisfies the Constraint of p, which every tag
+// does without one. An invalid Constraint is satisfied by none.
+func (p Policy) InRange(tag string) bool {
+	if p.Constraint == "" {
+		return true
+	}
+	c, err := constraint.Parse(p.Constraint)
+	return err == nil && c.Allows(tag)
+}
+
+func (p Policy) latest(releases []Release) Release {
+	if p.Channel == ChannelNightly {
+		return findNewestRelease(releases)
+	}
+	return findLatestRelease(releases)
+}
+
+func (p Policy) String() string {
+	channel := string(p.Channel)
+	if channel == "" {
+		channel = string(ChannelStable)
+	}
+	if p.Constraint != "" {
+		return channel + " " + p.Constraint
+	}
+	return channel
+}
+
+// APIURL is the base URL of the GitHub REST API.
+const APIURL = "https://api.github.com"
+
+// Token returns the GitHub token authenticating API requests, taken from
+// GITHUB_TOKEN or GH_TOKEN. Authenticated requests get a far higher rate limit.
+func Token() string {
+	if token := os.Getenv("GITHUB_TOKEN"); token != "" {
+		return token
+	}
+	return os.Getenv("GH_TOKEN")
 }
 
 type GitHubClient struct {
 	httpClient *http.Client
 	baseURL    string
+	token      string
+	budget     *budgetTransport
 }
 
+// NewGitHubClient returns a client for the GitHub API. Responses are cached
+// and revalidated following their Cache-Control headers; conditional requests
+// answered with 304 do not count against the rate limit.
 func NewGitHubClient() *GitHubClient {
+	return NewGitHubClientWithURL(APIURL)
+}
+
+// NewGitHubClientWithURL returns a client for the GitHub API served at
+// baseURL, such as a GitHub Enterprise server or a fixture server in tests.
+func NewGitHubClientWithURL(baseURL string) *GitHubClient {
+	base := http.DefaultTransport.(*http.Transport).Clone()
+	fdlimit.Limit(base)
+	budget := &budgetTransport{base: httplog.New(base, "api")}
 	return &GitHubClient{
 		httpClient: &http.Client{
-			Timeout: 30 * time.Second,
+			Transport: httpcache.New(budget),
+			Timeout:   30 * time.Second,
 		},
-		baseURL: "https://api.github.com",
+		baseURL: strings.TrimSuffix(baseURL, "/"),
+		token:   Token(),
+		budget:  budget,
 	}
 }
 
-func (c *GitHubClient) LatestStable(ctx context.Context, owner, repo string) (*Release, error) {
-	url := fmt.Sprintf("%s/repos/%s/%s/releases", c.baseURL, owner, repo)
-	
+// SetAPIBudget limits the requests c sends to the API to n, or lifts the
+// limit when n is 0, and resets the count of requests sent.
+func (c *GitHubClient) SetAPIBudget(n int) {
+	if c.budget != nil {
+		c.budget.set(n)
+	}
+}
+
+// SetHTTPCache keeps the API responses in dir so later runs can revalidate
+// them instead of fetching them again.
+func (c *GitHubClient) SetHTTPCache(dir string, shared bool) {
+	if t, ok := c.httpClient.Transport.(*httpcache.Transport); ok {
+		t.SetDir(dir, shared)
+	}
+}
+
+func (c *GitHubClient) newRequest(ctx context.Context, url string) (*http.Request, error) {
 	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create request: %w", err)
@@ -56,33 +194,118 @@ func (c *GitHubClient) LatestStable(ctx context.Context, owner, repo string) (*R
 
 	req.Header.Set("Accept", "application/vnd.github.v3+json")
 	req.Header.Set("User-Agent", "ghinstall/1.0")
+	if c.token != "" {
+		req.Header.Set("Authorization", "Bearer "+c.token)
+	}
+	if c.budget.exhausted() {
+		// Any cached response beats failing.
+		req.Header.Set("Cache-Control", "max-stale")
+	}
+	return req, nil
+}
+
+func (c *GitHubClient) LatestStable(ctx context.Context, owner, repo string) (*Release, error) {
+	return c.Latest(ctx, owner, repo, Policy{})
+}
+
+// Latest returns the newest release of owner/repo selected by policy. With a
+// Constraint, further pages of older releases are fetched until one satisfies
+// it.
+func (c *GitHubClient) Latest(ctx context.Context, owner, repo string, policy Policy) (*Release, error) {
+	url := fmt.Sprintf("%s/repos/%s/%s/releases", c.baseURL, owner, repo)
+	if policy.Constraint != "" {
+		url += "?per_page=100"
+	}
+
+	var releases, candidates []Release
+	for url != "" {
+		page, next, err := c.releasesPage(ctx, url)
+		if err != nil {
+			return nil, err
+		}
+		releases = append(releases, page...)
+		candidates = policy.filter(releases)
+		if len(candidates) > 0 || policy.Constraint == "" {
+			break
+		}
+		url = next
+	}
+
+	if len(releases) == 0 {
+		return nil, fmt.Errorf("no releases found for %s/%s", owner, repo)
+	}
+
+	if len(candidates) == 0 {
+		return nil, fmt.Errorf("no %s releases found for %s/%s", policy, owner, repo)
+	}
+
+	latest := policy.latest(candidates)
+	return &latest, nil
+}
+
+// releasesPage returns the releases listed at url and the URL of the next
+// page, "" on the last one.
+func (c *GitHubClient) releasesPage(ctx context.Context, url string) ([]Release, string, error) {
+	req, err := c.newRequest(ctx, url)
+	if err != nil {
+		return nil, "", err
+	}
 
 	resp, err := c.httpClient.Do(req)
 	if err != nil {
-		return nil, fmt.Errorf("failed to fetch releases: %w", err)
+		return nil, "", fmt.Errorf("failed to fetch releases: %w", err)
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("GitHub API returned status %d", resp.StatusCode)
+		return nil, "", neterr.Status(resp, fmt.Sprintf("GitHub API returned status %d", resp.StatusCode))
 	}
 
 	var releases []Release
 	if err := json.NewDecoder(resp.Body).Decode(&releases); err != nil {
-		return nil, fmt.Errorf("failed to decode releases: %w", err)
+		return nil, "", fmt.Errorf("failed to decode releases: %w", err)
 	}
+	return releases, nextLink(resp.Header.Get("Link")), nil
+}
 
-	if len(releases) == 0 {
-		return nil, fmt.Errorf("no releases found for %s/%s", owner, repo)
+// nextLink returns the rel="next" URL of a Link header, or "".
+func nextLink(header string) string {
+	for _, link := range strings.Split(header, ",") {
+		target, params, ok := strings.Cut(strings.TrimSpace(link), ";")
+		if ok && strings.Contains(params, `rel="next"`) {
+			return strings.Trim(strings.TrimSpace(target), "<>")
+		}
 	}
+	return ""
+}
+
+// ByTag returns the release with the given tag name.
+func (c *GitHubClient) ByTag(ctx context.Context, owner, repo, tag string) (*Release, error) {
+	url := fmt.Sprintf("%s/repos/%s/%s/releases/tags/%s", c.baseURL, owner, repo, neturl.PathEscape(tag))
 
-	stableReleases := filterStableReleases(releases)
-	if len(stableReleases) == 0 {
-		return nil, fmt.Errorf("no stable releases found for %s/%s", owner, repo)
+	req, err := c.newRequest(ctx, url)
+	if err != nil {
+		return nil, err
 	}
 
-	latest := findLatestRelease(stableReleases)
-	return &latest, nil
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch release %s: %w", tag, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound {
+		return nil, fmt.Errorf("%w: %s for %s/%s", ErrNotFound, tag, owner, repo)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, neterr.Status(resp, fmt.Sprintf("GitHub API returned status %d", resp.StatusCode))
+	}
+
+	var rel Release
+	if err := json.NewDecoder(resp.Body).Decode(&rel); err != nil {
+		return nil, fmt.Errorf("failed to decode release: %w", err)
+	}
+	return &rel, nil
 }
 
 func filterStableReleases(releases []Release) []Release {
@@ -95,6 +318,52 @@ func filterStableReleases(releases []Release) []Release {
 	return stable
 }
 
+// filterExcludedTags drops releases whose tag matches one of patterns.
+func filterExcludedTags(releases []Release, patterns []string) []Release {
+	if len(patterns) == 0 {
+		return releases
+	}
+
+	var kept []Release
+	for _, release := range releases {
+		if !matchesAny(release.TagName, patterns) {
+			kept = append(kept, release)
+		}
+	}
+	return kept
+}
+
+func matchesAny(tag string, patterns []string) bool {
+	for _, pattern := range patterns {
+		if ok, _ := path.Match(pattern, tag); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// filterPublishedReleases drops drafts but keeps prereleases.
+func filterPublishedReleases(releases []Release) []Release {
+	var published []Release
+	for _, release := range releases {
+		if !release.Draft {
+			published = append(published, release)
+		}
+	}
+	return published
+}
+
+// findNewestRelease returns the most recently published release.
+func findNewestRelease(releases []Release) Release {
+	newest := releases[0]
+	for _, release := range releases[1:] {
+		if release.PublishedAt.After(newest.PublishedAt) {
+			newest = release
+		}
+	}
+	return newest
+}
+
 func findLatestRelease(releases []Release) Release {
 	if len(releases) == 0 {
 		panic("no releases to compare")
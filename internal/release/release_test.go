@@ -2,9 +2,14 @@ package release
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"net/http"
 	"net/http/httptest"
 	"reflect"
+	"runtime"
+	"strings"
+	"sync/atomic"
 	"testing"
 	"time"
 )
@@ -293,4 +298,385 @@ func TestFilterStableReleases(t *testing.T) {
 	if !reflect.DeepEqual(stable, want) {
 		t.Errorf("filterStableReleases() = %v, want %v", stable, want)
 	}
-}
\ No newline at end of file
+}
+func TestByRegex(t *testing.T) {
+	assets := []Asset{
+		{Name: "tool_1.0_linux_amd64.rpm"},
+		{Name: "tool_1.0_linux_amd64.tar.gz"},
+		{Name: "tool_1.0_darwin_arm64.tar.gz"},
+	}
+
+	got, err := ByRegex(`_linux_amd64\.tar\.gz$`)(assets)
+	if err != nil {
+		t.Fatalf("ByRegex() error = %v", err)
+	}
+	if got.Name != "tool_1.0_linux_amd64.tar.gz" {
+		t.Errorf("ByRegex() = %s, want tool_1.0_linux_amd64.tar.gz", got.Name)
+	}
+
+	if _, err := ByRegex(`windows`)(assets); err == nil {
+		t.Error("ByRegex() should fail when nothing matches")
+	}
+	if _, err := ByRegex(`(`)(assets); err == nil {
+		t.Error("ByRegex() should fail for an invalid pattern")
+	}
+}
+
+func TestExpandPattern(t *testing.T) {
+	goos := runtime.GOOS
+	if goos == "darwin" {
+		goos = "macos"
+	}
+	arch := runtime.GOARCH
+	if arch == "amd64" {
+		arch = "x86_64"
+	}
+	assets := []Asset{
+		{Name: "tool_1.1.0_" + goos + "_" + arch + ".tar.gz"},
+		{Name: "tool_1.2.0_" + goos + "_" + arch + ".tar.gz"},
+		{Name: "tool_1.2.0_plan9_" + arch + ".tar.gz"},
+	}
+
+	pattern := ExpandPattern(`^tool_{version}_{os}_{arch}\.tar\.gz$`, "v1.2.0")
+	got, err := ByRegex(pattern)(assets)
+	if err != nil {
+		t.Fatalf("ByRegex(%s) error = %v", pattern, err)
+	}
+	if got.Name != assets[1].Name {
+		t.Errorf("ByRegex(%s) = %s, want %s", pattern, got.Name, assets[1].Name)
+	}
+
+	if got := ExpandPattern(`^tool-{tag}\.zip$`, "v1.2+3"); got != `^tool-v1\.2\+3\.zip$` {
+		t.Errorf("ExpandPattern() = %s, want the tag quoted", got)
+	}
+}
+
+func TestPlatformAliases(t *testing.T) {
+	assets := []Asset{
+		{Name: "tool_linux_amd64.tar.gz"},
+		{Name: "tool_solaris_amd64.tar.gz"},
+		{Name: "tool_FreeBSD_x86_64.tar.gz"},
+		{Name: "tool_openbsd_arm64.tar.gz"},
+		{Name: "tool_termux_aarch64.tar.gz"},
+	}
+	tests := []struct{ goos, goarch, want string }{
+		{"illumos", "amd64", "tool_solaris_amd64.tar.gz"},
+		{"freebsd", "amd64", "tool_FreeBSD_x86_64.tar.gz"},
+		{"openbsd", "arm64", "tool_openbsd_arm64.tar.gz"},
+		{"android", "arm64", "tool_termux_aarch64.tar.gz"},
+	}
+	for _, tt := range tests {
+		if got, err := ByPlatform(tt.goos, tt.goarch)(assets); err != nil || got.Name != tt.want {
+			t.Errorf("ByPlatform(%s, %s) = %v, %v, want %s", tt.goos, tt.goarch, got, err, tt.want)
+		}
+		if got, err := ByOS(tt.goos)(assets); err != nil || got.Name != tt.want {
+			t.Errorf("ByOS(%s) = %v, %v, want %s", tt.goos, got, err, tt.want)
+		}
+		pattern := ExpandPatternFor(`_{os}_{arch}\.tar\.gz$`, "v1.0.0", tt.goos, tt.goarch)
+		if got, err := ByRegex(pattern)(assets); err != nil || got.Name != tt.want {
+			t.Errorf("ByRegex(%s) = %v, %v, want %s", pattern, got, err, tt.want)
+		}
+	}
+	if _, err := ByPlatform("netbsd", "amd64")(assets); err == nil {
+		t.Error("ByPlatform(netbsd, amd64) should fail")
+	}
+}
+
+func TestPreferTypes(t *testing.T) {
+	assets := []Asset{
+		{Name: "checksums.txt"},
+		{Name: "tool_linux_amd64.zip"},
+		{Name: "tool_linux_amd64.tar.gz"},
+		{Name: "tool_linux_amd64.TGZ"},
+	}
+
+	got := PreferTypes(assets, []string{".tgz", ".tar.gz", ".zip"})
+	var names []string
+	for _, a := range got {
+		names = append(names, a.Name)
+	}
+	want := []string{"tool_linux_amd64.TGZ", "tool_linux_amd64.tar.gz", "tool_linux_amd64.zip", "checksums.txt"}
+	if !reflect.DeepEqual(names, want) {
+		t.Errorf("PreferTypes() = %v, want %v", names, want)
+	}
+	if assets[0].Name != "checksums.txt" {
+		t.Error("PreferTypes() must not reorder its argument")
+	}
+
+	asset, err := DefaultFilter()(PreferTypes(assets, []string{".zip"}))
+	if err != nil || asset.Name != "tool_linux_amd64.zip" {
+		t.Errorf("DefaultFilter() after PreferTypes(.zip) = %v, %v", asset, err)
+	}
+}
+
+func TestPrefer(t *testing.T) {
+	assets := []Asset{
+		{Name: "tool-full-linux-amd64.tar.gz", Size: 9000},
+		{Name: "tool-full-linux-amd64.tar.gz.sha256", Size: 90},
+		{Name: "tool-linux-amd64.tar.gz", Size: 3000},
+		{Name: "tool-darwin-amd64.tar.gz", Size: 1000},
+	}
+	tests := []struct{ pref, want string }{
+		{"smallest", "tool-linux-amd64.tar.gz"},
+		{"largest", "tool-full-linux-amd64.tar.gz"},
+		{"name:FULL", "tool-full-linux-amd64.tar.gz"},
+		{"", "tool-full-linux-amd64.tar.gz"},
+	}
+	for _, tt := range tests {
+		got, err := Preferring(tt.pref, ByPlatform("linux", "amd64"))(assets)
+		if err != nil || got.Name != tt.want {
+			t.Errorf("Preferring(%q) = %v, %v, want %s", tt.pref, got, err, tt.want)
+		}
+	}
+	if names := Prefer(assets, "smallest"); names[0].Name != "tool-darwin-amd64.tar.gz" || names[3].Name != "tool-full-linux-amd64.tar.gz.sha256" {
+		t.Errorf("Prefer(smallest) = %v, want sidecars last", names)
+	}
+}
+
+func TestParseCPUInfo(t *testing.T) {
+	tests := map[string]struct {
+		cpuinfo string
+		want    int
+	}{
+		"pi zero":      {cpuinfo: "processor\t: 0\nmodel name\t: ARMv6-compatible processor rev 7 (v6l)\nCPU architecture: 7\n", want: 7},
+		"pi zero old":  {cpuinfo: "Processor\t: ARMv6-compatible processor rev 7 (v6l)\n", want: 6},
+		"pi 3 32-bit":  {cpuinfo: "model name\t: ARMv7 Processor rev 4 (v7l)\nCPU architecture: 8\n", want: 7},
+		"armv6 kernel": {cpuinfo: "CPU architecture: 6\n", want: 6},
+		"not arm":      {cpuinfo: "model name\t: Intel(R) Xeon(R)\n", want: 0},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			if got := parseCPUInfo([]byte(tt.cpuinfo)); got != tt.want {
+				t.Errorf("parseCPUInfo() = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPreferArmVariant(t *testing.T) {
+	assets := []Asset{
+		{Name: "tool_linux_arm64.tar.gz"},
+		{Name: "tool_linux_amd64.tar.gz"},
+		{Name: "tool_linux_armv7.tar.gz"},
+		{Name: "tool_linux_arm.tar.gz"},
+		{Name: "tool_linux_armv5.tar.gz"},
+		{Name: "tool_linux_armv6.tar.gz"},
+	}
+	names := func(assets []Asset) []string {
+		var names []string
+		for _, a := range assets {
+			names = append(names, a.Name)
+		}
+		return names
+	}
+
+	want := []string{"tool_linux_armv6.tar.gz", "tool_linux_armv5.tar.gz", "tool_linux_arm.tar.gz", "tool_linux_amd64.tar.gz", "tool_linux_arm64.tar.gz", "tool_linux_armv7.tar.gz"}
+	if got := names(PreferArmVariant(assets, 6)); !reflect.DeepEqual(got, want) {
+		t.Errorf("PreferArmVariant(6) = %v, want %v", got, want)
+	}
+	if got := names(PreferArmVariant(assets, 0)); !reflect.DeepEqual(got, names(assets)) {
+		t.Errorf("PreferArmVariant(0) = %v, want the original order", got)
+	}
+	if got := names(PreferArmVariant(assets, 7))[0]; got != "tool_linux_armv7.tar.gz" {
+		t.Errorf("PreferArmVariant(7) starts with %s", got)
+	}
+}
+
+func TestGitHubClient_ByTag(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/repos/owner/repo/releases/tags/v1.5.0" {
+			w.WriteHeader(http.StatusNotFound)
+			w.Write([]byte(`{"message": "Not Found"}`))
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"tag_name": "v1.5.0", "assets": [{"name": "app.tar.gz", "browser_download_url": "u", "size": 1}]}`))
+	}))
+	defer server.Close()
+
+	client := &GitHubClient{
+		httpClient: &http.Client{Timeout: 5 * time.Second},
+		baseURL:    server.URL,
+	}
+
+	got, err := client.ByTag(context.Background(), "owner", "repo", "v1.5.0")
+	if err != nil {
+		t.Fatalf("GitHubClient.ByTag() error = %v", err)
+	}
+	if got.TagName != "v1.5.0" || len(got.Assets) != 1 {
+		t.Errorf("GitHubClient.ByTag() = %v", got)
+	}
+
+	if _, err := client.ByTag(context.Background(), "owner", "repo", "v0.0.1"); err == nil {
+		t.Error("GitHubClient.ByTag() should fail for a missing tag")
+	}
+}
+
+func TestGitHubClient_Token(t *testing.T) {
+	var gotAuth string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotAuth = r.Header.Get("Authorization")
+		w.Write([]byte(`{"tag_name": "v1.0.0"}`))
+	}))
+	defer server.Close()
+
+	t.Setenv("GITHUB_TOKEN", "")
+	t.Setenv("GH_TOKEN", "secret")
+	client := NewGitHubClient()
+	client.baseURL = server.URL
+
+	if _, err := client.ByTag(context.Background(), "owner", "repo", "v1.0.0"); err != nil {
+		t.Fatalf("GitHubClient.ByTag() error = %v", err)
+	}
+	if gotAuth != "Bearer secret" {
+		t.Errorf("Authorization = %q, want %q", gotAuth, "Bearer secret")
+	}
+}
+
+func TestGitHubClient_Latest_Channels(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`[
+			{"tag_name": "nightly", "prerelease": true, "published_at": "2024-03-03T00:00:00Z"},
+			{"tag_name": "v2.0.0-rc1", "prerelease": true, "published_at": "2024-02-01T00:00:00Z"},
+			{"tag_name": "v3.0.0", "draft": true, "published_at": "2024-03-04T00:00:00Z"},
+			{"tag_name": "v1.9.0", "published_at": "2024-01-01T00:00:00Z"}
+		]`))
+	}))
+	defer server.Close()
+
+	client := &GitHubClient{
+		httpClient: &http.Client{Timeout: 5 * time.Second},
+		baseURL:    server.URL,
+	}
+
+	tests := []struct {
+		channel Channel
+		wantTag string
+	}{
+		{channel: "", wantTag: "v1.9.0"},
+		{channel: ChannelStable, wantTag: "v1.9.0"},
+		{channel: ChannelPrerelease, wantTag: "v2.0.0-rc1"},
+		{channel: ChannelNightly, wantTag: "nightly"},
+	}
+
+	for _, tt := range tests {
+		t.Run(string(tt.channel), func(t *testing.T) {
+			got, err := client.Latest(context.Background(), "owner", "repo", Policy{Channel: tt.channel})
+			if err != nil {
+				t.Fatalf("GitHubClient.Latest() error = %v", err)
+			}
+			if got.TagName != tt.wantTag {
+				t.Errorf("GitHubClient.Latest() = %s, want %s", got.TagName, tt.wantTag)
+			}
+		})
+	}
+}
+
+func TestGitHubClient_Latest_Constraint(t *testing.T) {
+	var server *httptest.Server
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		if r.URL.Query().Get("page") == "2" {
+			w.Write([]byte(`[{"tag_name": "v1.6.2"}, {"tag_name": "v1.6.1"}, {"tag_name": "v1.5.0"}]`))
+			return
+		}
+		w.Header().Set("Link", fmt.Sprintf(`<%s%s?per_page=100&page=2>; rel="next", <%s%s?per_page=100&page=2>; rel="last"`, server.URL, r.URL.Path, server.URL, r.URL.Path))
+		w.Write([]byte(`[{"tag_name": "v2.1.0"}, {"tag_name": "v2.0.0"}, {"tag_name": "v1.7.0"}]`))
+	}))
+	defer server.Close()
+
+	client := &GitHubClient{
+		httpClient: &http.Client{Timeout: 5 * time.Second},
+		baseURL:    server.URL,
+	}
+
+	tests := []struct {
+		constraint string
+		wantTag    string
+	}{
+		{constraint: ">=1.4.0 <2.0.0", wantTag: "v1.7.0"},
+		{constraint: "~1.6", wantTag: "v1.6.2"},
+		{constraint: "^1.5 !=1.7.0", wantTag: "v1.6.2"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.constraint, func(t *testing.T) {
+			got, err := client.Latest(context.Background(), "owner", "repo", Policy{Constraint: tt.constraint})
+			if err != nil {
+				t.Fatalf("GitHubClient.Latest() error = %v", err)
+			}
+			if got.TagName != tt.wantTag {
+				t.Errorf("GitHubClient.Latest() = %s, want %s", got.TagName, tt.wantTag)
+			}
+		})
+	}
+
+	if _, err := client.Latest(context.Background(), "owner", "repo", Policy{Constraint: "~3.0"}); err == nil || !strings.Contains(err.Error(), "stable ~3.0") {
+		t.Errorf("GitHubClient.Latest() error = %v, want no stable ~3.0 releases", err)
+	}
+}
+
+func TestPolicy_ExcludeTags(t *testing.T) {
+	releases := []Release{
+		{TagName: "v2.1.0-rc1"},
+		{TagName: "v2.0.1-hotfix"},
+		{TagName: "v2.0.0"},
+		{TagName: "v1.9.0", Prerelease: true},
+	}
+
+	tests := []struct {
+		name    string
+		policy  Policy
+		wantTag string
+	}{
+		{name: "no exclusions", policy: Policy{}, wantTag: "v2.1.0-rc1"},
+		{name: "exclude rc", policy: Policy{ExcludeTags: []string{"*-rc*"}}, wantTag: "v2.0.1-hotfix"},
+		{name: "exclude rc and hotfix", policy: Policy{ExcludeTags: []string{"*-rc*", "*-hotfix*"}}, wantTag: "v2.0.0"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := tt.policy.latest(tt.policy.filter(releases))
+			if got.TagName != tt.wantTag {
+				t.Errorf("latest = %s, want %s", got.TagName, tt.wantTag)
+			}
+		})
+	}
+}
+
+func TestGitHubClient_SetAPIBudget(t *testing.T) {
+	var hits atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits.Add(1)
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Cache-Control", "private, max-age=0")
+		w.Header().Set("ETag", `"v1"`)
+		w.Write([]byte(`{"tag_name": "v1.5.0"}`))
+	}))
+	defer server.Close()
+
+	ctx := context.Background()
+	client := NewGitHubClientWithURL(server.URL)
+	client.SetAPIBudget(2)
+	for _, repo := range []string{"one", "two"} {
+		if _, err := client.ByTag(ctx, "owner", repo, "v1.5.0"); err != nil {
+			t.Fatalf("ByTag(%s) error = %v", repo, err)
+		}
+	}
+
+	// The stale response is used rather than spending more of the budget.
+	if got, err := client.ByTag(ctx, "owner", "one", "v1.5.0"); err != nil || got.TagName != "v1.5.0" {
+		t.Errorf("ByTag() from cache = %v, %v", got, err)
+	}
+	if _, err := client.ByTag(ctx, "owner", "three", "v1.5.0"); !errors.Is(err, ErrBudgetExceeded) {
+		t.Errorf("ByTag() of an uncached release error = %v, want ErrBudgetExceeded", err)
+	}
+	if hits.Load() != 2 {
+		t.Errorf("server saw %d requests, want 2", hits.Load())
+	}
+
+	client.SetAPIBudget(0)
+	if _, err := client.ByTag(ctx, "owner", "three", "v1.5.0"); err != nil {
+		t.Errorf("ByTag() without budget error = %v", err)
+	}
+}
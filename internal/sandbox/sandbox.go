@@ -0,0 +1,31 @@
+// Package sandbox confines archive extraction, which processes untrusted
+// input and often runs as root, to the directories it writes to.
+package sandbox
+
+import (
+	"errors"
+	"runtime"
+)
+
+// ErrUnsupported is returned by Run when the platform or kernel cannot
+// sandbox.
+var ErrUnsupported = errors.New("sandboxing is not supported on this system")
+
+// Run calls fn on a dedicated OS thread that can only create, read and write
+// files below dirs, which must exist, and can neither start programs nor open
+// network connections. The thread is discarded afterwards, so the restrictions
+// never leak into the rest of the process. Goroutines started by fn run
+// elsewhere and are not restricted.
+func Run(dirs []string, fn func() error) error {
+	errc := make(chan error, 1)
+	go func() {
+		// Never unlocked: the thread exits with the goroutine.
+		runtime.LockOSThread()
+		if err := restrict(dirs); err != nil {
+			errc <- err
+			return
+		}
+		errc <- fn()
+	}()
+	return <-errc
+}
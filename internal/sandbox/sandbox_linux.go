@@ -0,0 +1,179 @@
+package sandbox
+
+import (
+	"fmt"
+	"syscall"
+	"unsafe"
+)
+
+// Landlock system calls, numbered alike on every architecture.
+const (
+	sysLandlockCreateRuleset = 444
+	sysLandlockAddRule       = 445
+	sysLandlockRestrictSelf  = 446
+
+	landlockCreateRulesetVersion = 1 << 0
+	landlockRulePathBeneath      = 1
+)
+
+// Landlock filesystem access rights.
+const (
+	accessExecute = 1 << iota
+	accessWriteFile
+	accessReadFile
+	accessReadDir
+	accessRemoveDir
+	accessRemoveFile
+	accessMakeChar
+	accessMakeDir
+	accessMakeReg
+	accessMakeSock
+	accessMakeFifo
+	accessMakeBlock
+	accessMakeSym
+	accessRefer    // ABI 2
+	accessTruncate // ABI 3
+	accessIoctlDev // ABI 5
+)
+
+// Landlock network access rights, ABI 4.
+const (
+	accessBindTCP = 1 << iota
+	accessConnectTCP
+)
+
+// extractAccess is what extraction may do below its directories.
+const extractAccess = accessWriteFile | accessReadFile | accessReadDir | accessRemoveDir | accessRemoveFile |
+	accessMakeDir | accessMakeReg | accessMakeSym | accessRefer | accessTruncate
+
+const (
+	prSetNoNewPrivs   = 38
+	prSetSeccomp      = 22
+	seccompModeFilter = 2
+	oPath             = 0x200000
+)
+
+type rulesetAttr struct {
+	handledAccessFS  uint64
+	handledAccessNet uint64
+}
+
+// pathBeneathAttr is packed in the kernel: parent_fd follows allowed_access
+// at offset 8, as it does here.
+type pathBeneathAttr struct {
+	allowedAccess uint64
+	parentFd      int32
+}
+
+// restrict confines the calling thread with Landlock and, where the system
+// call numbers are known, a seccomp filter denying execve and socket.
+func restrict(dirs []string) error {
+	abi, _, errno := syscall.RawSyscall(sysLandlockCreateRuleset, 0, 0, landlockCreateRulesetVersion)
+	if errno != 0 || abi < 1 {
+		return fmt.Errorf("%w: Landlock is unavailable (Linux 5.13 or later with landlock enabled is required)", ErrUnsupported)
+	}
+
+	attr := rulesetAttr{handledAccessFS: accessExecute | accessWriteFile | accessReadFile | accessReadDir |
+		accessRemoveDir | accessRemoveFile | accessMakeChar | accessMakeDir | accessMakeReg |
+		accessMakeSock | accessMakeFifo | accessMakeBlock | accessMakeSym}
+	size := unsafe.Sizeof(attr.handledAccessFS)
+	if abi >= 2 {
+		attr.handledAccessFS |= accessRefer
+	}
+	if abi >= 3 {
+		attr.handledAccessFS |= accessTruncate
+	}
+	if abi >= 4 {
+		attr.handledAccessNet = accessBindTCP | accessConnectTCP
+		size = unsafe.Sizeof(attr)
+	}
+	if abi >= 5 {
+		attr.handledAccessFS |= accessIoctlDev
+	}
+
+	fd, _, errno := syscall.RawSyscall(sysLandlockCreateRuleset, uintptr(unsafe.Pointer(&attr)), size, 0)
+	if errno != 0 {
+		return fmt.Errorf("failed to create Landlock ruleset: %w", errno)
+	}
+	defer syscall.Close(int(fd))
+
+	for _, dir := range dirs {
+		dirFd, err := syscall.Open(dir, oPath|syscall.O_CLOEXEC, 0)
+		if err != nil {
+			return fmt.Errorf("failed to open %s for the sandbox: %w", dir, err)
+		}
+		rule := pathBeneathAttr{allowedAccess: extractAccess & attr.handledAccessFS, parentFd: int32(dirFd)}
+		_, _, errno := syscall.RawSyscall6(sysLandlockAddRule, fd, landlockRulePathBeneath, uintptr(unsafe.Pointer(&rule)), 0, 0, 0)
+		syscall.Close(dirFd)
+		if errno != 0 {
+			return fmt.Errorf("failed to allow %s in the sandbox: %w", dir, errno)
+		}
+	}
+
+	if _, _, errno := syscall.RawSyscall6(syscall.SYS_PRCTL, prSetNoNewPrivs, 1, 0, 0, 0, 0); errno != 0 {
+		return fmt.Errorf("failed to set no_new_privs: %w", errno)
+	}
+	if err := denySyscalls(); err != nil {
+		return err
+	}
+	if _, _, errno := syscall.RawSyscall(sysLandlockRestrictSelf, fd, 0, 0); errno != 0 {
+		return fmt.Errorf("failed to enforce Landlock ruleset: %w", errno)
+	}
+	return nil
+}
+
+// BPF instructions and seccomp return values used by the filter.
+const (
+	bpfLdWAbs  = 0x20 // BPF_LD | BPF_W | BPF_ABS
+	bpfJeqK    = 0x15 // BPF_JMP | BPF_JEQ | BPF_K
+	bpfJgeK    = 0x35 // BPF_JMP | BPF_JGE | BPF_K
+	x32Bit     = 0x40000000
+	bpfRetK    = 0x06 // BPF_RET | BPF_K
+	retAllow   = 0x7fff0000
+	retErrno   = 0x00050000
+	offsetNr   = 0
+	offsetArch = 4
+)
+
+type sockFilter struct {
+	code uint16
+	jt   uint8
+	jf   uint8
+	k    uint32
+}
+
+type sockFprog struct {
+	len    uint16
+	filter *sockFilter
+}
+
+// denySyscalls installs a seccomp filter failing the system calls that start
+// programs or create sockets with EPERM. Landlock alone does not stop
+// connections over UDP or Unix sockets.
+func denySyscalls() error {
+	if auditArch == 0 {
+		return nil
+	}
+
+	prog := []sockFilter{
+		{code: bpfLdWAbs, k: offsetArch},
+		{code: bpfJeqK, jt: 1, k: auditArch},
+		{code: bpfRetK, k: retAllow},
+		{code: bpfLdWAbs, k: offsetNr},
+		// x32 system calls share the x86-64 architecture with their own numbers.
+		{code: bpfJgeK, jf: 1, k: x32Bit},
+		{code: bpfRetK, k: retErrno | uint32(syscall.EPERM)},
+	}
+	for _, nr := range deniedSyscalls {
+		prog = append(prog,
+			sockFilter{code: bpfJeqK, jf: 1, k: uint32(nr)},
+			sockFilter{code: bpfRetK, k: retErrno | uint32(syscall.EPERM)})
+	}
+	prog = append(prog, sockFilter{code: bpfRetK, k: retAllow})
+
+	fprog := sockFprog{len: uint16(len(prog)), filter: &prog[0]}
+	if _, _, errno := syscall.RawSyscall(syscall.SYS_PRCTL, prSetSeccomp, seccompModeFilter, uintptr(unsafe.Pointer(&fprog))); errno != 0 {
+		return fmt.Errorf("failed to install seccomp filter: %w", errno)
+	}
+	return nil
+}
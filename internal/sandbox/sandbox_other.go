@@ -0,0 +1,7 @@
+//go:build !linux
+
+package sandbox
+
+func restrict(dirs []string) error {
+	return ErrUnsupported
+}
@@ -0,0 +1,48 @@
+package sandbox
+
+import (
+	"errors"
+	"net"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"testing"
+)
+
+func TestRun(t *testing.T) {
+	allowed, denied := t.TempDir(), t.TempDir()
+
+	var writeErr, escapeErr, execErr, dialErr error
+	err := Run([]string{allowed}, func() error {
+		writeErr = os.WriteFile(filepath.Join(allowed, "file"), []byte("ok"), 0644)
+		escapeErr = os.WriteFile(filepath.Join(denied, "file"), []byte("escaped"), 0644)
+		execErr = exec.Command("true").Run()
+		_, dialErr = net.Dial("udp", "127.0.0.1:53")
+		return nil
+	})
+	if errors.Is(err, ErrUnsupported) {
+		t.Skip(err)
+	}
+	if err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+
+	if writeErr != nil {
+		t.Errorf("writing below an allowed directory failed: %v", writeErr)
+	}
+	if escapeErr == nil {
+		t.Error("writing outside the allowed directories succeeded")
+	}
+	if execErr == nil {
+		t.Error("starting a program succeeded")
+	}
+	if dialErr == nil && (runtime.GOARCH == "amd64" || runtime.GOARCH == "arm64") {
+		t.Error("opening a socket succeeded")
+	}
+
+	// The rest of the process is not restricted.
+	if err := os.WriteFile(filepath.Join(denied, "file"), []byte("ok"), 0644); err != nil {
+		t.Errorf("writing after Run() failed: %v", err)
+	}
+}
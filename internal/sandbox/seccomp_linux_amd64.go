@@ -0,0 +1,7 @@
+package sandbox
+
+// auditArch is AUDIT_ARCH_X86_64.
+const auditArch = 0xc000003e
+
+// deniedSyscalls are execve, execveat and socket.
+var deniedSyscalls = []uintptr{59, 322, 41}
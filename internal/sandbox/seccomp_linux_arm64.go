@@ -0,0 +1,7 @@
+package sandbox
+
+// auditArch is AUDIT_ARCH_AARCH64.
+const auditArch = 0xc00000b7
+
+// deniedSyscalls are execve, execveat and socket.
+var deniedSyscalls = []uintptr{221, 281, 198}
@@ -0,0 +1,8 @@
+//go:build linux && !amd64 && !arm64
+
+package sandbox
+
+// auditArch is 0 where the filter is not implemented: only Landlock applies.
+const auditArch = 0
+
+var deniedSyscalls []uintptr
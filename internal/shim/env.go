@@ -0,0 +1,54 @@
+package shim
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+)
+
+// Shells supported by EnvSnippet.
+var Shells = []string{"bash", "zsh", "sh", "fish", "powershell"}
+
+// DefaultDir is the bin dir used when none is configured.
+func DefaultDir() string {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return filepath.Join(".ghinstall", "bin")
+	}
+	return filepath.Join(home, ".ghinstall", "bin")
+}
+
+// DetectShell guesses the user's shell from the environment.
+func DetectShell() string {
+	if runtime.GOOS == "windows" {
+		return "powershell"
+	}
+	if sh := filepath.Base(os.Getenv("SHELL")); sh != "" && sh != "." {
+		return sh
+	}
+	return "sh"
+}
+
+// EnvSnippet returns the shell code that prepends dir to PATH, suitable for
+// eval-ing from a shell profile. It is a no-op when dir is already on PATH.
+func EnvSnippet(shell, dir string) (string, error) {
+	switch strings.ToLower(shell) {
+	case "bash", "zsh", "sh", "dash", "ksh":
+		q := shellQuote(dir)
+		return fmt.Sprintf("case \":${PATH}:\" in\n  *:%s:*) ;;\n  *) export PATH=%s\":${PATH}\" ;;\nesac\n", q, q), nil
+	case "fish":
+		return fmt.Sprintf("fish_add_path --path --prepend %s\n", shellQuote(dir)), nil
+	case "powershell", "pwsh":
+		q := strings.ReplaceAll(dir, "'", "''")
+		return fmt.Sprintf("if (-not ($env:PATH -split [IO.Path]::PathSeparator -contains '%s')) { $env:PATH = '%s' + [IO.Path]::PathSeparator + $env:PATH }\n", q, q), nil
+	default:
+		return "", fmt.Errorf("unsupported shell %q (supported: %s)", shell, strings.Join(Shells, ", "))
+	}
+}
+
+// shellQuote single-quotes s for POSIX shells and fish.
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
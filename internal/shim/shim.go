@@ -0,0 +1,175 @@
+// Package shim manages a directory of links to installed executables, giving
+// every installed tool a single entry on PATH.
+package shim
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+)
+
+// IsExecutable reports whether the file at path is executable on this platform.
+func IsExecutable(path string, info fs.FileInfo) bool {
+	if runtime.GOOS == "windows" {
+		switch strings.ToLower(filepath.Ext(path)) {
+		case ".exe", ".bat", ".cmd", ".ps1":
+			return true
+		}
+		return false
+	}
+	return info.Mode().Perm()&0111 != 0
+}
+
+// Link creates a shim in binDir for every target. Existing shims pointing
+// elsewhere are replaced when they point into one of managed, the output
+// directories of ghinstall; other symlinks and files in binDir are left
+// alone and their targets skipped. It returns the created shim paths and the
+// conflicts skipped.
+func Link(binDir string, targets, managed []string) ([]string, []*Conflict, error) {
+	return LinkWithSuffix(binDir, targets, managed, "")
+}
+
+// LinkWithSuffix is Link with suffix appended to every shim name (before any
+// extension), so several versions of a tool can be linked side by side.
+func LinkWithSuffix(binDir string, targets, managed []string, suffix string) ([]string, []*Conflict, error) {
+	if err := os.MkdirAll(binDir, 0755); err != nil {
+		return nil, nil, fmt.Errorf("failed to create bin dir %s: %w", binDir, err)
+	}
+
+	var (
+		created   []string
+		conflicts []*Conflict
+	)
+	for _, target := range targets {
+		abs, err := filepath.Abs(target)
+		if err != nil {
+			return created, conflicts, err
+		}
+
+		path, err := link(binDir, abs, managed, suffix)
+		var conflict *Conflict
+		if errors.As(err, &conflict) {
+			conflicts = append(conflicts, conflict)
+			continue
+		}
+		if err != nil {
+			return created, conflicts, err
+		}
+		if path != "" {
+			created = append(created, path)
+		}
+	}
+	return created, conflicts, nil
+}
+
+// Conflict is an entry of the bin dir that ghinstall did not create, left
+// alone instead of being replaced by a shim.
+type Conflict struct {
+	Path   string
+	Reason string
+}
+
+func (c *Conflict) Error() string {
+	return fmt.Sprintf("refusing to replace %s: %s", c.Path, c.Reason)
+}
+
+func link(binDir, target string, managed []string, suffix string) (string, error) {
+	if runtime.GOOS == "windows" {
+		return writeWrapper(binDir, target, suffix)
+	}
+
+	path := filepath.Join(binDir, filepath.Base(target)+suffix)
+	if fi, err := os.Lstat(path); err == nil {
+		if fi.Mode()&os.ModeSymlink == 0 {
+			return "", &Conflict{Path: path, Reason: "not a ghinstall shim"}
+		}
+		current, err := os.Readlink(path)
+		if err != nil {
+			return "", fmt.Errorf("failed to read shim %s: %w", path, err)
+		}
+		if current == target {
+			return path, nil
+		}
+		if !filepath.IsAbs(current) {
+			current = filepath.Join(binDir, current)
+		}
+		if !within(current, managed) {
+			return "", &Conflict{Path: path, Reason: fmt.Sprintf("it links to %s, outside the output directories of ghinstall", current)}
+		}
+		if err := os.Remove(path); err != nil {
+			return "", fmt.Errorf("failed to replace shim %s: %w", path, err)
+		}
+	}
+
+	if err := os.Symlink(target, path); err != nil {
+		return "", fmt.Errorf("failed to create shim %s: %w", path, err)
+	}
+	return path, nil
+}
+
+// within reports whether path lies below one of dirs.
+func within(path string, dirs []string) bool {
+	for _, dir := range dirs {
+		abs, err := filepath.Abs(dir)
+		if err != nil {
+			continue
+		}
+		rel, err := filepath.Rel(abs, filepath.Clean(path))
+		if err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+			return true
+		}
+	}
+	return false
+}
+
+// wrapperMarker identifies .cmd files written by ghinstall on Windows, where
+// symlinks need elevated privileges.
+const wrapperMarker = "@rem ghinstall shim"
+
+func writeWrapper(binDir, target, suffix string) (string, error) {
+	name := strings.TrimSuffix(filepath.Base(target), filepath.Ext(target)) + suffix
+	path := filepath.Join(binDir, name+".cmd")
+
+	if data, err := os.ReadFile(path); err == nil && !strings.HasPrefix(string(data), wrapperMarker) {
+		return "", &Conflict{Path: path, Reason: "not a ghinstall shim"}
+	}
+
+	content := fmt.Sprintf("%s\r\n@\"%s\" %%*\r\n", wrapperMarker, target)
+	if err := os.WriteFile(path, []byte(content), 0755); err != nil {
+		return "", fmt.Errorf("failed to create shim %s: %w", path, err)
+	}
+	return path, nil
+}
+
+// OnPath reports whether dir is listed in the PATH environment variable.
+func OnPath(dir string) bool {
+	want, err := filepath.Abs(dir)
+	if err != nil {
+		return false
+	}
+	for _, entry := range filepath.SplitList(os.Getenv("PATH")) {
+		if entry == "" {
+			continue
+		}
+		abs, err := filepath.Abs(entry)
+		if err != nil {
+			continue
+		}
+		if samePath(abs, want) {
+			return true
+		}
+	}
+	return false
+}
+
+func samePath(a, b string) bool {
+	a, b = filepath.Clean(a), filepath.Clean(b)
+	if runtime.GOOS == "windows" {
+		return strings.EqualFold(a, b)
+	}
+	return a == b
+}
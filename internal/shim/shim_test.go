@@ -0,0 +1,125 @@
+package shim
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+func TestLink(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("symlink shims are not used on Windows")
+	}
+
+	out := t.TempDir()
+	targets := []string{filepath.Join(out, "tool"), filepath.Join(out, "sub", "helper")}
+	for _, target := range targets {
+		mustWrite(t, target, 0755)
+	}
+	managed := []string{out}
+
+	bin := filepath.Join(t.TempDir(), "bin")
+	created, _, err := Link(bin, targets, managed)
+	if err != nil {
+		t.Fatalf("Link() error = %v", err)
+	}
+	if len(created) != 2 {
+		t.Errorf("Link() created %v, want 2 shims", created)
+	}
+
+	target, err := os.Readlink(filepath.Join(bin, "tool"))
+	if err != nil || target != filepath.Join(out, "tool") {
+		t.Errorf("shim tool -> %s (%v), want %s", target, err, filepath.Join(out, "tool"))
+	}
+
+	// Relinking is idempotent.
+	if _, _, err := Link(bin, targets, managed); err != nil {
+		t.Errorf("second Link() error = %v", err)
+	}
+
+	// Shims into another output directory are replaced.
+	other := t.TempDir()
+	mustWrite(t, filepath.Join(other, "tool"), 0755)
+	if _, _, err := Link(bin, []string{filepath.Join(other, "tool")}, []string{out, other}); err != nil {
+		t.Errorf("Link() error = %v replacing a shim", err)
+	}
+
+	// Files that are not shims are never replaced.
+	mustWrite(t, filepath.Join(bin, "other"), 0755)
+	mustWrite(t, filepath.Join(out, "other"), 0755)
+	created, conflicts, err := Link(bin, []string{filepath.Join(out, "other"), filepath.Join(out, "sub", "helper")}, managed)
+	if err != nil || len(created) != 1 || len(conflicts) != 1 || conflicts[0].Path != filepath.Join(bin, "other") {
+		t.Errorf("Link() = %v, %v, %v, want the regular file skipped as a conflict", created, conflicts, err)
+	}
+
+	// Nor are the user's own symlinks.
+	own := filepath.Join(t.TempDir(), "mine")
+	mustWrite(t, own, 0755)
+	if err := os.Symlink(own, filepath.Join(bin, "mine")); err != nil {
+		t.Fatal(err)
+	}
+	mustWrite(t, filepath.Join(out, "mine"), 0755)
+	if _, conflicts, err := Link(bin, []string{filepath.Join(out, "mine")}, managed); err != nil || len(conflicts) != 1 {
+		t.Errorf("Link() = %v, %v, want a symlink outside the output directories skipped", conflicts, err)
+	}
+	if got, _ := os.Readlink(filepath.Join(bin, "mine")); got != own {
+		t.Errorf("symlink mine -> %s, want it kept at %s", got, own)
+	}
+}
+
+func TestOnPath(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv("PATH", "/usr/bin"+string(os.PathListSeparator)+dir+string(os.PathSeparator))
+
+	if !OnPath(dir) {
+		t.Errorf("OnPath(%s) = false, want true", dir)
+	}
+	if OnPath(filepath.Join(dir, "nested")) {
+		t.Error("OnPath(nested) = true, want false")
+	}
+}
+
+func mustWrite(t *testing.T, path string, mode os.FileMode) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte("x"), mode); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestEnvSnippet(t *testing.T) {
+	tests := []struct {
+		shell   string
+		want    string
+		wantErr bool
+	}{
+		{shell: "bash", want: "*) export PATH='/home/u/.ghinstall/bin'\":${PATH}\" ;;"},
+		{shell: "zsh", want: "*:'/home/u/.ghinstall/bin':*) ;;"},
+		{shell: "fish", want: "fish_add_path --path --prepend '/home/u/.ghinstall/bin'"},
+		{shell: "powershell", want: "$env:PATH = '/home/u/.ghinstall/bin' + [IO.Path]::PathSeparator + $env:PATH"},
+		{shell: "tcsh", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.shell, func(t *testing.T) {
+			got, err := EnvSnippet(tt.shell, "/home/u/.ghinstall/bin")
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("EnvSnippet() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !strings.Contains(got, tt.want) {
+				t.Errorf("EnvSnippet() = %q, want it to contain %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestEnvSnippet_Quoting(t *testing.T) {
+	got, _ := EnvSnippet("sh", "/tmp/it's here")
+	if !strings.Contains(got, `'/tmp/it'\''s here'`) {
+		t.Errorf("EnvSnippet() did not quote path: %q", got)
+	}
+}
@@ -0,0 +1,46 @@
+// Package signature verifies release assets against the signatures their
+// maintainers publish with them, with gpg or cosign.
+package signature
+
+import (
+	"strings"
+
+	"github.com/sixban6/ghinstall/internal/config"
+)
+
+// Candidates returns the names the signature of the asset named asset may be
+// published under, in order of preference: the configured name, or the ones
+// gpg and cosign users commonly choose.
+func Candidates(opts config.SignatureOptions, asset string) []string {
+	if opts.Asset != "" {
+		return []string{strings.ReplaceAll(opts.Asset, "{asset}", asset)}
+	}
+	var suffixes []string
+	switch opts.Type {
+	case "gpg":
+		suffixes = []string{".asc", ".sig", ".gpg"}
+	case "cosign":
+		suffixes = []string{".sigstore.json", ".sigstore", ".bundle", ".sig"}
+	}
+	names := make([]string, len(suffixes))
+	for n, suffix := range suffixes {
+		names[n] = asset + suffix
+	}
+	return names
+}
+
+// IsBundle reports whether the cosign signature named name is a Sigstore
+// bundle, which carries its certificate, rather than a bare signature.
+func IsBundle(name string) bool {
+	return strings.HasSuffix(name, ".sigstore.json") || strings.HasSuffix(name, ".sigstore") || strings.HasSuffix(name, ".bundle")
+}
+
+// Certificates returns the names the signing certificate of a keyless cosign
+// signature, named sig, of the asset named asset may be published under.
+func Certificates(asset, sig string) []string {
+	names := []string{asset + ".pem", asset + ".crt"}
+	if base, ok := strings.CutSuffix(sig, ".sig"); ok && base != asset {
+		names = append(names, base+".pem")
+	}
+	return names
+}
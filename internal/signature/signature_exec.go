@@ -0,0 +1,72 @@
+//go:build !purego && !wasip1
+
+package signature
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+
+	"github.com/sixban6/ghinstall/internal/config"
+	"github.com/sixban6/ghinstall/internal/tmpdir"
+)
+
+// Verify checks the signature at sig of the file at path as opts require.
+// bundle tells whether sig is a Sigstore bundle, as IsBundle reports for the
+// name it was published under. cert is the signing certificate of a keyless
+// cosign signature that is not a bundle, and empty otherwise.
+func Verify(ctx context.Context, opts config.SignatureOptions, path, sig string, bundle bool, cert string) error {
+	switch opts.Type {
+	case "gpg":
+		return verifyGPG(ctx, opts.Key, path, sig)
+	case "cosign":
+		args := []string{"verify-blob"}
+		if bundle {
+			args = append(args, "--bundle", sig)
+		} else {
+			args = append(args, "--signature", sig)
+			if cert != "" {
+				args = append(args, "--certificate", cert)
+			}
+		}
+		if opts.Key != "" {
+			args = append(args, "--key", opts.Key)
+		} else {
+			args = append(args, "--certificate-identity-regexp", opts.Identity, "--certificate-oidc-issuer", opts.Issuer)
+		}
+		return run(ctx, "cosign", append(args, path)...)
+	default:
+		return fmt.Errorf("unknown signature type %q", opts.Type)
+	}
+}
+
+// verifyGPG verifies with a keyring holding only the key at key, in a
+// temporary home directory, so the keys the user trusts play no part.
+func verifyGPG(ctx context.Context, key, path, sig string) error {
+	home, err := os.MkdirTemp(tmpdir.Dir(), "ghinstall-gnupg-*")
+	if err != nil {
+		return fmt.Errorf("failed to create gpg home directory: %w", err)
+	}
+	defer os.RemoveAll(home)
+
+	if err := run(ctx, "gpg", "--batch", "--quiet", "--homedir", home, "--import", key); err != nil {
+		return fmt.Errorf("failed to import %s: %w", key, err)
+	}
+	return run(ctx, "gpg", "--batch", "--quiet", "--homedir", home, "--verify", sig, path)
+}
+
+func run(ctx context.Context, name string, args ...string) error {
+	var stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, name, args...)
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return fmt.Errorf("%s: %w: %s", name, err, msg)
+		}
+		return fmt.Errorf("%s: %w", name, err)
+	}
+	return nil
+}
@@ -0,0 +1,56 @@
+//go:build !purego && !wasip1 && !windows
+
+package signature
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/sixban6/ghinstall/internal/config"
+)
+
+// fakeCosign puts a cosign on PATH that records its arguments and returns
+// where they are written.
+func fakeCosign(t *testing.T) string {
+	t.Helper()
+	dir := t.TempDir()
+	args := filepath.Join(dir, "args")
+	script := "#!/bin/sh\necho \"$@\" > " + args + "\n"
+	if err := os.WriteFile(filepath.Join(dir, "cosign"), []byte(script), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("PATH", dir+string(os.PathListSeparator)+os.Getenv("PATH"))
+	return args
+}
+
+func TestVerify_Cosign(t *testing.T) {
+	keyless := config.SignatureOptions{Type: "cosign", Identity: "^https://github.com/owner/", Issuer: "https://token.actions.githubusercontent.com"}
+	tests := []struct {
+		name   string
+		bundle bool
+		cert   string
+		want   string
+	}{
+		// Downloaded signatures are temporary files without their published name.
+		{name: "bundle", bundle: true, want: "verify-blob --bundle /tmp/ghinstall-sig-1 --certificate-identity-regexp"},
+		{name: "signature", cert: "/tmp/ghinstall-sig-2", want: "verify-blob --signature /tmp/ghinstall-sig-1 --certificate /tmp/ghinstall-sig-2 --certificate-identity-regexp"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			args := fakeCosign(t)
+			if err := Verify(context.Background(), keyless, "/tmp/app.tar.gz", "/tmp/ghinstall-sig-1", tt.bundle, tt.cert); err != nil {
+				t.Fatalf("Verify() error = %v", err)
+			}
+			got, err := os.ReadFile(args)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !strings.HasPrefix(string(got), tt.want) || !strings.HasSuffix(strings.TrimSpace(string(got)), "/tmp/app.tar.gz") {
+				t.Errorf("cosign %s, want %s ... /tmp/app.tar.gz", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,16 @@
+//go:build purego || wasip1
+
+package signature
+
+import (
+	"context"
+	"errors"
+
+	"github.com/sixban6/ghinstall/internal/config"
+)
+
+var errDisabled = errors.New("signatures cannot be verified: this build of ghinstall (purego or wasip1) does not start processes")
+
+func Verify(ctx context.Context, opts config.SignatureOptions, path, sig string, bundle bool, cert string) error {
+	return errDisabled
+}
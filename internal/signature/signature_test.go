@@ -0,0 +1,34 @@
+package signature
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/sixban6/ghinstall/internal/config"
+)
+
+func TestCandidates(t *testing.T) {
+	tests := []struct {
+		opts config.SignatureOptions
+		want []string
+	}{
+		{config.SignatureOptions{Type: "gpg"}, []string{"app.tar.gz.asc", "app.tar.gz.sig", "app.tar.gz.gpg"}},
+		{config.SignatureOptions{Type: "cosign"}, []string{"app.tar.gz.sigstore.json", "app.tar.gz.sigstore", "app.tar.gz.bundle", "app.tar.gz.sig"}},
+		{config.SignatureOptions{Type: "cosign", Asset: "{asset}-keyless.sig"}, []string{"app.tar.gz-keyless.sig"}},
+	}
+	for _, tt := range tests {
+		if got := Candidates(tt.opts, "app.tar.gz"); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("Candidates(%+v) = %v, want %v", tt.opts, got, tt.want)
+		}
+	}
+}
+
+func TestCertificates(t *testing.T) {
+	want := []string{"app.tar.gz.pem", "app.tar.gz.crt", "app.tar.gz-keyless.pem"}
+	if got := Certificates("app.tar.gz", "app.tar.gz-keyless.sig"); !reflect.DeepEqual(got, want) {
+		t.Errorf("Certificates() = %v, want %v", got, want)
+	}
+	if IsBundle("app.tar.gz.sig") || !IsBundle("app.tar.gz.sigstore.json") {
+		t.Error("IsBundle() misclassified a signature")
+	}
+}
@@ -0,0 +1,159 @@
+package state
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"time"
+
+	"github.com/sixban6/ghinstall/internal/filelock"
+)
+
+// indexLockTimeout bounds how long registering waits for another process.
+const indexLockTimeout = 30 * time.Second
+
+// IndexPath returns the per-user file listing every output directory
+// ghinstall has installed into, which lets the state of all of them be found
+// without a config file.
+func IndexPath() string {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return filepath.Join(".ghinstall", "installs.json")
+	}
+	return filepath.Join(home, ".ghinstall", "installs.json")
+}
+
+type index struct {
+	Dirs []string `json:"output_dirs"`
+}
+
+// Dirs returns the registered output directories.
+func Dirs() ([]string, error) {
+	idx, err := readIndex(IndexPath())
+	if err != nil {
+		return nil, err
+	}
+	return idx.Dirs, nil
+}
+
+// Register adds dir to the index of output directories.
+func Register(ctx context.Context, dir string) error {
+	abs, err := filepath.Abs(dir)
+	if err != nil {
+		return err
+	}
+
+	path := IndexPath()
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("failed to create state index directory: %w", err)
+	}
+
+	lock, err := filelock.Acquire(ctx, path+".lock", indexLockTimeout)
+	if err != nil {
+		return fmt.Errorf("failed to lock state index: %w", err)
+	}
+	defer lock.Release()
+
+	idx, err := readIndex(path)
+	if err != nil {
+		return err
+	}
+	if slices.Contains(idx.Dirs, abs) {
+		return nil
+	}
+	idx.Dirs = append(idx.Dirs, abs)
+
+	data, err := json.MarshalIndent(idx, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode state index: %w", err)
+	}
+	return writeFile(path, append(data, '\n'))
+}
+
+func readIndex(path string) (*index, error) {
+	var idx index
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return &idx, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read state index: %w", err)
+	}
+	if err := json.Unmarshal(data, &idx); err != nil {
+		return nil, fmt.Errorf("failed to parse state index %s: %w", path, err)
+	}
+	return &idx, nil
+}
+
+// Install is a record together with the output directory it belongs to.
+type Install struct {
+	OutputDir string `json:"output_dir"`
+	Record
+}
+
+// Export is the machine-independent list of installs written by
+// "ghinstall state export" and replayed on another machine.
+type Export struct {
+	Installs []Install `json:"installs"`
+}
+
+// ExportAll collects the records of every registered output directory,
+// sorted by directory and repository so exports of the same installs are
+// identical.
+func ExportAll() (*Export, error) {
+	dirs, err := Dirs()
+	if err != nil {
+		return nil, err
+	}
+	dirs = slices.Sorted(slices.Values(dirs))
+
+	exp := &Export{Installs: []Install{}}
+	for _, dir := range dirs {
+		f, err := Load(dir)
+		if err != nil {
+			return nil, err
+		}
+		sortRecords(f.Records)
+		for _, rec := range f.Records {
+			exp.Installs = append(exp.Installs, Install{OutputDir: dir, Record: rec})
+		}
+	}
+	return exp, nil
+}
+
+// Import writes the installs of exp into the state files of their output
+// directories and registers them, without installing anything. Every record
+// is validated before any directory is created.
+func Import(ctx context.Context, exp *Export) error {
+	for _, in := range exp.Installs {
+		if in.OutputDir == "" || in.Repo == "" {
+			return fmt.Errorf("invalid install record: output_dir and repo are required")
+		}
+		if !filepath.IsAbs(in.OutputDir) {
+			return fmt.Errorf("invalid install record of %s: output_dir %s is not absolute", in.Repo, in.OutputDir)
+		}
+	}
+
+	for _, in := range exp.Installs {
+		if err := os.MkdirAll(in.OutputDir, 0755); err != nil {
+			return fmt.Errorf("failed to create output directory %s: %w", in.OutputDir, err)
+		}
+
+		f, err := Load(in.OutputDir)
+		if err != nil {
+			return err
+		}
+		f.Put(in.Record)
+		if err := f.Save(); err != nil {
+			return err
+		}
+		if err := Register(ctx, in.OutputDir); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,132 @@
+// Package state records what ghinstall installed into an output directory.
+//
+// Each output directory holds one state file listing the repositories
+// installed into it, so installs are tracked by repository, pinned version and
+// output directory: the same repository can be installed in several versions
+// side by side as long as each goes to its own directory. The file is only
+// modified while the directory's install lock is held.
+package state
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+)
+
+// FileName is the state file kept in every output directory.
+const FileName = ".ghinstall.state.json"
+
+// Record describes the last successful install of a repository.
+type Record struct {
+	Repo string `json:"repo"`
+	// Name is the configured alias of the repository, if any.
+	Name string `json:"name,omitempty"`
+	// Version is the version pin the repository was installed with, if any.
+	Version string `json:"version,omitempty"`
+	Tag     string `json:"tag"`
+	Asset   string `json:"asset"`
+	// SHA256 is the hex digest of the installed asset, when it is known.
+	SHA256 string `json:"sha256,omitempty"`
+	// Source is the URL the asset was downloaded from and ETag the entity
+	// tag it was served with, used to notice assets replaced under the same tag.
+	Source      string    `json:"source,omitempty"`
+	ETag        string    `json:"etag,omitempty"`
+	InstalledAt time.Time `json:"installed_at"`
+	// Completions are the completion scripts and man pages the install
+	// copied to the per-user directories, which later installs may replace.
+	Completions []string `json:"completions,omitempty"`
+}
+
+// File is the state of one output directory.
+type File struct {
+	path    string
+	Records []Record `json:"records"`
+}
+
+// Load reads the state of dir. A missing state file yields an empty state.
+func Load(dir string) (*File, error) {
+	f := &File{path: filepath.Join(dir, FileName)}
+
+	data, err := os.ReadFile(f.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return f, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read state file: %w", err)
+	}
+	if err := json.Unmarshal(data, f); err != nil {
+		return nil, fmt.Errorf("failed to parse state file %s: %w", f.path, err)
+	}
+	return f, nil
+}
+
+// Get returns the record of repo.
+func (f *File) Get(repo string) (Record, bool) {
+	for _, r := range f.Records {
+		if r.Repo == repo {
+			return r, true
+		}
+	}
+	return Record{}, false
+}
+
+// Put adds rec, replacing the previous record of the same repository. An
+// output directory holds a single install of a repository, so a record with a
+// changed version pin replaces the old one.
+func (f *File) Put(rec Record) {
+	for i, r := range f.Records {
+		if r.Repo == rec.Repo {
+			f.Records[i] = rec
+			return
+		}
+	}
+	f.Records = append(f.Records, rec)
+}
+
+// Save atomically writes the state file. Records are sorted by repository, so
+// the file does not depend on the order parallel installs finished in.
+func (f *File) Save() error {
+	sortRecords(f.Records)
+	data, err := json.MarshalIndent(f, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode state: %w", err)
+	}
+
+	return writeFile(f.path, append(data, '\n'))
+}
+
+// sortRecords sorts records by repository.
+func sortRecords(records []Record) {
+	slices.SortStableFunc(records, func(a, b Record) int {
+		return strings.Compare(a.Repo, b.Repo)
+	})
+}
+
+// writeFile atomically replaces path with data.
+func writeFile(path string, data []byte) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*")
+	if err != nil {
+		return fmt.Errorf("failed to write %s: %w", path, err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write %s: %w", path, err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to write %s: %w", path, err)
+	}
+	if err := os.Chmod(tmp.Name(), 0644); err != nil {
+		return fmt.Errorf("failed to write %s: %w", path, err)
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return fmt.Errorf("failed to write %s: %w", path, err)
+	}
+	return nil
+}
@@ -0,0 +1,154 @@
+package state
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestFile_SaveLoad(t *testing.T) {
+	dir := t.TempDir()
+
+	f, err := Load(dir)
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if len(f.Records) != 0 {
+		t.Fatalf("Load() of a fresh dir = %v, want no records", f.Records)
+	}
+
+	installed := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	f.Put(Record{Repo: "https://github.com/owner/a", Tag: "v1.0.0", Asset: "a.tar.gz", InstalledAt: installed})
+	f.Put(Record{Repo: "https://github.com/owner/b", Tag: "v2.0.0", Asset: "b.tar.gz", InstalledAt: installed})
+	f.Put(Record{Repo: "https://github.com/owner/a", Tag: "v1.1.0", Asset: "a.tar.gz", SHA256: "abc", InstalledAt: installed})
+	if err := f.Save(); err != nil {
+		t.Fatalf("Save() error = %v", err)
+	}
+
+	got, err := Load(dir)
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if len(got.Records) != 2 {
+		t.Fatalf("Load() = %v, want 2 records", got.Records)
+	}
+	rec, ok := got.Get("https://github.com/owner/a")
+	if !ok || rec.Tag != "v1.1.0" || rec.SHA256 != "abc" || !rec.InstalledAt.Equal(installed) {
+		t.Errorf("Get() = %+v, %v", rec, ok)
+	}
+	if _, ok := got.Get("https://github.com/owner/c"); ok {
+		t.Error("Get() found an unknown repository")
+	}
+}
+
+func TestFile_SaveSorted(t *testing.T) {
+	dir := t.TempDir()
+	f, err := Load(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Parallel installs record their repositories in any order.
+	f.Put(Record{Repo: "https://github.com/owner/b", Tag: "v2.0.0"})
+	f.Put(Record{Repo: "https://github.com/owner/a", Tag: "v1.0.0"})
+	if err := f.Save(); err != nil {
+		t.Fatalf("Save() error = %v", err)
+	}
+
+	got, err := Load(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got.Records) != 2 || got.Records[0].Repo != "https://github.com/owner/a" {
+		t.Errorf("Load() = %+v, want the records sorted by repository", got.Records)
+	}
+}
+
+func TestExportImport(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	t.Setenv("USERPROFILE", os.Getenv("HOME"))
+
+	dirA, dirB := t.TempDir(), t.TempDir()
+	installed := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	for _, in := range []Install{
+		{OutputDir: dirA, Record: Record{Repo: "https://github.com/owner/a", Tag: "v1.0.0", Asset: "a.tar.gz", InstalledAt: installed}},
+		{OutputDir: dirB, Record: Record{Repo: "https://github.com/owner/b", Version: "v2.0.0", Tag: "v2.0.0", Asset: "b.zip", InstalledAt: installed}},
+	} {
+		f, err := Load(in.OutputDir)
+		if err != nil {
+			t.Fatal(err)
+		}
+		f.Put(in.Record)
+		if err := f.Save(); err != nil {
+			t.Fatal(err)
+		}
+		if err := Register(context.Background(), in.OutputDir); err != nil {
+			t.Fatalf("Register() error = %v", err)
+		}
+	}
+	// Registering twice is a no-op.
+	if err := Register(context.Background(), dirA); err != nil {
+		t.Fatalf("Register() error = %v", err)
+	}
+
+	exp, err := ExportAll()
+	if err != nil {
+		t.Fatalf("ExportAll() error = %v", err)
+	}
+	if len(exp.Installs) != 2 {
+		t.Fatalf("ExportAll() = %+v, want 2 installs", exp.Installs)
+	}
+
+	data, err := json.Marshal(exp)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Replay on a "new machine" with an empty home and other output dirs.
+	t.Setenv("HOME", t.TempDir())
+	t.Setenv("USERPROFILE", os.Getenv("HOME"))
+	var imported Export
+	if err := json.Unmarshal(data, &imported); err != nil {
+		t.Fatal(err)
+	}
+	target := t.TempDir()
+	for i := range imported.Installs {
+		imported.Installs[i].OutputDir = filepath.Join(target, filepath.Base(imported.Installs[i].OutputDir))
+	}
+	if err := Import(context.Background(), &imported); err != nil {
+		t.Fatalf("Import() error = %v", err)
+	}
+
+	got, err := ExportAll()
+	if err != nil {
+		t.Fatalf("ExportAll() error = %v", err)
+	}
+	if len(got.Installs) != 2 {
+		t.Fatalf("ExportAll() after import = %+v, want 2 installs", got.Installs)
+	}
+	for i, in := range got.Installs {
+		want := imported.Installs[i]
+		if in.OutputDir != want.OutputDir || in.Repo != want.Repo || in.Version != want.Version || in.Tag != want.Tag || in.Asset != want.Asset {
+			t.Errorf("install %d = %+v, want %+v", i, in, want)
+		}
+	}
+}
+
+func TestImport_Invalid(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	t.Setenv("USERPROFILE", os.Getenv("HOME"))
+
+	dir := t.TempDir()
+	exp := &Export{Installs: []Install{
+		{OutputDir: filepath.Join(dir, "a"), Record: Record{Repo: "https://github.com/owner/a"}},
+		{OutputDir: "relative/b", Record: Record{Repo: "https://github.com/owner/b"}},
+	}}
+	if err := Import(context.Background(), exp); err == nil {
+		t.Fatal("Import() accepted a relative output_dir")
+	}
+	if _, err := os.Stat(filepath.Join(dir, "a")); !os.IsNotExist(err) {
+		t.Errorf("Import() created an output_dir before validating all records: %v", err)
+	}
+}
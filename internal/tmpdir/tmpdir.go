@@ -0,0 +1,43 @@
+// Package tmpdir chooses where ghinstall spools downloads and extracts
+// archives. Termux on Android has no writable /tmp; its temporary directory
+// lives under $PREFIX, which environments that drop $TMPDIR, such as cron
+// jobs and proot sessions, would otherwise miss.
+package tmpdir
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Dir returns the directory for temporary files: $TMPDIR when set, $PREFIX/tmp
+// under Termux, and os.TempDir otherwise.
+func Dir() string {
+	if dir := os.Getenv("TMPDIR"); dir != "" {
+		return dir
+	}
+	if prefix := termuxPrefix(); prefix != "" {
+		dir := filepath.Join(prefix, "tmp")
+		if err := os.MkdirAll(dir, 0o700); err == nil {
+			return dir
+		}
+	}
+	return os.TempDir()
+}
+
+// Termux reports whether ghinstall runs inside Termux.
+func Termux() bool {
+	return termuxPrefix() != ""
+}
+
+// termuxPrefix returns the Termux installation prefix, "" outside Termux.
+func termuxPrefix() string {
+	prefix := os.Getenv("PREFIX")
+	if prefix == "" {
+		return ""
+	}
+	if os.Getenv("TERMUX_VERSION") != "" || strings.Contains(prefix, "/com.termux/") {
+		return prefix
+	}
+	return ""
+}
@@ -0,0 +1,64 @@
+package tmpdir
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestDir(t *testing.T) {
+	scratch := t.TempDir()
+	prefix := filepath.Join(t.TempDir(), "data", "data", "com.termux", "files", "usr")
+
+	tests := []struct {
+		name   string
+		env    map[string]string
+		want   string
+		termux bool
+	}{
+		{
+			name:   "tmpdir wins",
+			env:    map[string]string{"TMPDIR": scratch, "PREFIX": prefix, "TERMUX_VERSION": "0.118.0"},
+			want:   scratch,
+			termux: true,
+		},
+		{
+			name:   "termux prefix",
+			env:    map[string]string{"TMPDIR": "", "PREFIX": prefix, "TERMUX_VERSION": ""},
+			want:   filepath.Join(prefix, "tmp"),
+			termux: true,
+		},
+		{
+			name:   "termux version",
+			env:    map[string]string{"TMPDIR": "", "PREFIX": "/opt/termux", "TERMUX_VERSION": "0.118.0"},
+			want:   "",
+			termux: true,
+		},
+		{
+			name: "prefix outside termux",
+			env:  map[string]string{"TMPDIR": "", "PREFIX": "/usr/local", "TERMUX_VERSION": ""},
+			want: os.TempDir(),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for k, v := range tt.env {
+				t.Setenv(k, v)
+			}
+			if got := Termux(); got != tt.termux {
+				t.Errorf("Termux() = %v, want %v", got, tt.termux)
+			}
+			if tt.want == "" {
+				return
+			}
+			got := Dir()
+			if got != tt.want {
+				t.Errorf("Dir() = %q, want %q", got, tt.want)
+			}
+			if info, err := os.Stat(got); err != nil || !info.IsDir() {
+				t.Errorf("Dir() = %q is not a directory: %v", got, err)
+			}
+		})
+	}
+}